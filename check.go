@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/lucas-varjao/gohtmx/internal/config"
+
+	"gorm.io/gorm"
+)
+
+// runCheck validates cfg and the database behind it without starting the server (the --check flag):
+// config values, TLS certificates, connectivity and whether the schema is up to date. Nothing is
+// migrated or seeded. Each step is reported to w; the returned error is non-nil when any step failed.
+// Missing tables or columns are only reported, since the next startup migrates them.
+func runCheck(w io.Writer, cfg *config.Config, open func(dsn string) (*gorm.DB, error)) error {
+	var failed []error
+	report := func(step string, err error) {
+		if err != nil {
+			failed = append(failed, fmt.Errorf("%s: %w", step, err))
+			fmt.Fprintf(w, "FALHA %s:\n  %v\n", step, err)
+			return
+		}
+		fmt.Fprintf(w, "ok    %s\n", step)
+	}
+
+	report("configuração", cfg.Validate())
+	if cfg.Server.TLS.Enabled {
+		_, err := buildTLSConfig(cfg.Server.TLS)
+		report("certificados TLS", err)
+	}
+
+	if cfg.Database.DSN == "" {
+		return errors.Join(failed...)
+	}
+	db, err := open(cfg.Database.DSN)
+	if err == nil {
+		err = pingDatabase(db)
+	}
+	report("conexão com o banco de dados", err)
+	if err != nil {
+		return errors.Join(failed...)
+	}
+
+	pending, err := pendingMigrations(db)
+	report("esquema do banco de dados", err)
+	for _, p := range pending {
+		fmt.Fprintf(w, "      pendente (criado na próxima inicialização): %s\n", p)
+	}
+	return errors.Join(failed...)
+}
+
+func pingDatabase(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Ping()
+}
+
+// pendingMigrations lists the tables and columns of migratedModels that AutoMigrate would still create.
+func pendingMigrations(db *gorm.DB) ([]string, error) {
+	migrator := db.Migrator()
+	var pending []string
+	for _, model := range migratedModels {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, err
+		}
+		table := stmt.Schema.Table
+		if !migrator.HasTable(model) {
+			pending = append(pending, "tabela "+table)
+			continue
+		}
+		for _, column := range stmt.Schema.DBNames {
+			if !migrator.HasColumn(model, column) {
+				pending = append(pending, "coluna "+table+"."+column)
+			}
+		}
+	}
+	return pending, nil
+}
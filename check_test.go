@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lucas-varjao/gohtmx/internal/config"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func loadCheckConfig(t *testing.T, yml string) *config.Config {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "app.yml"), []byte(yml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadConfigFromPath(dir)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	return cfg
+}
+
+func openMemoryDB(db *gorm.DB) func(string) (*gorm.DB, error) {
+	return func(string) (*gorm.DB, error) { return db, nil }
+}
+
+func TestRunCheck(t *testing.T) {
+	cfg := loadCheckConfig(t, "server:\n  port: 8080\ndatabase:\n  dsn: \"file::memory:\"\n")
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runCheck(&out, cfg, openMemoryDB(db)); err != nil {
+		t.Fatalf("empty database should only report pending migrations, got %v\n%s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "pendente (criado na próxima inicialização): tabela users") {
+		t.Errorf("missing tables should be reported:\n%s", out.String())
+	}
+	if db.Migrator().HasTable("users") {
+		t.Error("check must not migrate")
+	}
+
+	if err := db.AutoMigrate(migratedModels...); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := runCheck(&out, cfg, openMemoryDB(db)); err != nil {
+		t.Fatalf("migrated database should pass, got %v", err)
+	}
+	if strings.Contains(out.String(), "pendente") || strings.Contains(out.String(), "FALHA") {
+		t.Errorf("nothing should be pending:\n%s", out.String())
+	}
+	var users int64
+	db.Table("users").Count(&users)
+	if users != 0 {
+		t.Errorf("check must not seed, found %d users", users)
+	}
+}
+
+func TestRunCheck_Failures(t *testing.T) {
+	cfg := loadCheckConfig(t, "server:\n  port: 8080\ndatabase:\n  dsn: \"postgres://nowhere\"\nlog:\n  level: verbose\n")
+
+	var out bytes.Buffer
+	err := runCheck(&out, cfg, func(string) (*gorm.DB, error) { return nil, errors.New("connection refused") })
+	if err == nil {
+		t.Fatal("expected the check to fail")
+	}
+	for _, want := range []string{"FALHA configuração", "log.level", "FALHA conexão com o banco de dados", "connection refused"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("report should mention %q:\n%s", want, out.String())
+		}
+	}
+}
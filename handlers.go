@@ -1,10 +1,14 @@
 package main
 
 import (
+	"cmp"
 	"context"
 	"errors"
+	"fmt"
+	"maps"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -13,76 +17,115 @@ import (
 	"github.com/angelofallars/htmx-go"
 
 	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/avatar"
+	"github.com/lucas-varjao/gohtmx/internal/basepath"
+	"github.com/lucas-varjao/gohtmx/internal/config"
+	"github.com/lucas-varjao/gohtmx/internal/email"
+	"github.com/lucas-varjao/gohtmx/internal/handlers"
 	"github.com/lucas-varjao/gohtmx/internal/icons"
+	"github.com/lucas-varjao/gohtmx/internal/logger"
 	"github.com/lucas-varjao/gohtmx/internal/middleware"
 	"github.com/lucas-varjao/gohtmx/internal/models"
+	"github.com/lucas-varjao/gohtmx/internal/service"
+	"github.com/lucas-varjao/gohtmx/internal/useragent"
 	"github.com/lucas-varjao/gohtmx/internal/validation"
 	"github.com/lucas-varjao/gohtmx/templates/components"
 	"github.com/lucas-varjao/gohtmx/templates/layouts"
 	"github.com/lucas-varjao/gohtmx/templates/pages"
 	"github.com/lucas-varjao/gohtmx/templates/pages/admin"
+	"github.com/lucas-varjao/gohtmx/templates/render"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 // AppVersion is shown in the footer. Set via ldflags on release or use "dev".
 var AppVersion = "dev"
 
-// Role constants keep role comparisons consistent.
+// Avatar sizes in pixels (requested at 2x for high-DPI screens).
 const (
-	roleAdmin = "admin"
-	roleUser  = "user"
+	navAvatarSize  = 48
+	listAvatarSize = 48
 )
 
-// getNavData returns displayName and loggedIn for the navbar from the current request.
-func getNavData(c *gin.Context, authManager *auth.AuthManager) (displayName string, loggedIn bool) {
-	sessionID := middleware.ExtractSessionID(c)
-	if sessionID == "" {
-		return "", false
+// renderContext returns the request context carrying the render.Context that layouts.Layout reads:
+// the logged-in user for the navbar (nil when the session is missing or invalid) and the notice of a login
+// from another device, the request ID set by the proxy, the feature flags, the admin read-only mode, an
+// upcoming maintenance window and the footer data.
+func renderContext(c *gin.Context, authManager *auth.AuthManager) context.Context {
+	rc := render.Context{
+		RequestID:     c.GetHeader("X-Request-ID"),
+		Locale:        render.DefaultLocale,
+		Features:      config.GetConfig().Features(),
+		AdminReadOnly: middleware.AdminReadOnly(),
+		AppVersion:    AppVersion,
+		Year:          time.Now().Year(),
+		Brand:         appBrand(),
 	}
-	_, user, err := authManager.ValidateSession(sessionID)
-	if err != nil || user == nil {
-		return "", false
+	if window := middleware.ScheduledMaintenance(); window.Announced(time.Now()) {
+		rc.Maintenance = &render.Maintenance{Start: window.Start, End: window.End}
 	}
-	loggedIn = true
-	if user.DisplayName != "" {
-		displayName = user.DisplayName
-	} else {
-		displayName = user.Identifier
+	if sessionID := middleware.ExtractSessionID(c); sessionID != "" {
+		if session, user, err := authManager.ValidateSession(sessionID); err == nil && user != nil {
+			rc.User = &render.User{
+				DisplayName:   cmp.Or(user.DisplayName, user.Identifier),
+				Impersonating: session.ImpersonatedBy != "",
+			}
+			if session.NewLoginAt != nil && session.ImpersonatedBy == "" {
+				rc.User.NewLogin = &render.NewLogin{At: *session.NewLoginAt, From: session.NewLoginFrom}
+			}
+			if avatarsEnabled() {
+				stored, _ := user.Attributes["avatar_url"].(string)
+				rc.User.AvatarURL = avatar.URL(stored, user.Email, navAvatarSize)
+			}
+		}
 	}
-	return displayName, loggedIn
+	return render.WithContext(c.Request.Context(), rc)
+}
+
+// appBrand returns the branding in config app; render.From fills the fields left empty.
+func appBrand() render.Brand {
+	cfg := config.GetConfig()
+	if cfg == nil {
+		return render.Brand{}
+	}
+	return render.Brand{
+		Name:         cfg.App.Name,
+		Tagline:      cfg.App.Tagline,
+		LogoURL:      cfg.App.LogoURL,
+		FaviconURL:   cfg.App.FaviconURL,
+		PrimaryColor: cfg.App.PrimaryColor,
+	}
+}
+
+// avatarsEnabled reports whether avatars should be rendered (config avatar.enabled).
+func avatarsEnabled() bool {
+	return config.GetConfig().Features().Avatars
 }
 
 // indexViewHandler handles the index page; shows user name + logout when logged in.
 func indexViewHandler(c *gin.Context, authManager *auth.AuthManager) {
-	displayName, loggedIn := getNavData(c, authManager)
 	generatedAt := time.Now().Format("02/01/2006 15:04:05")
 
 	metaTags := pages.MetaTags(
-		"GoHTMX, Go, TEMPL, HTMX, Alpine.js, Tailwind, DaisyUI, demo, stack",
+		"Go, TEMPL, HTMX, Alpine.js, Tailwind, DaisyUI, demo, stack",
 		"Página de demonstração da stack: Go, TEMPL, HTMX, Alpine.js, Tailwind e DaisyUI.",
 	)
 
 	bodyContent := pages.IndexPage(generatedAt)
 
 	indexTemplate := layouts.Layout(
-		"GoHTMX — Stack demo",
+		"", // home page: app name and tagline
 		metaTags,
 		bodyContent,
-		displayName,
-		loggedIn,
 		false, // isAdmin
 		icons.LogIn(),
 		icons.UserPlus(),
 		icons.LogOut(),
 		icons.Menu(),
-		AppVersion,
-		time.Now().Year(),
 	)
 
-	if err := htmx.NewResponse().RenderTempl(c.Request.Context(), c.Writer, indexTemplate); err != nil {
+	if err := htmx.NewResponse().RenderTempl(renderContext(c, authManager), c.Writer, indexTemplate); err != nil {
 		c.AbortWithStatus(http.StatusInternalServerError)
 		return
 	}
@@ -92,10 +135,13 @@ func indexViewHandler(c *gin.Context, authManager *auth.AuthManager) {
 func logoutViewHandler(c *gin.Context, authManager *auth.AuthManager) {
 	sessionID := middleware.ExtractSessionID(c)
 	if sessionID != "" {
-		_ = authManager.Logout(sessionID)
+		_ = authManager.Logout(sessionID, auth.LogoutReasonUser)
 		middleware.ClearSessionCookie(c)
+		if cfg := config.GetConfig(); cfg != nil && cfg.Login.RememberUsername.ClearOnLogout {
+			middleware.ClearRememberedUsername(c)
+		}
 	}
-	c.Redirect(http.StatusFound, "/")
+	c.Redirect(http.StatusFound, basepath.URL("/"))
 }
 
 // showContentAPIHandler handles an API endpoint to show content.
@@ -116,14 +162,18 @@ func showContentAPIHandler(c *gin.Context) {
 }
 
 // loginViewHandler handles a view for the login page.
-func loginViewHandler(c *gin.Context, authManager *auth.AuthManager) {
+// captchaSlot is the login CAPTCHA container (see handlers.AuthHandler.LoginCaptcha).
+func loginViewHandler(c *gin.Context, authManager *auth.AuthManager, captchaSlot templ.Component) {
+	// Only local paths are kept; anything else falls back to the default destination
+	next := validation.SafeRedirectPath(c.Query("next"), "")
+
 	sessionID := middleware.ExtractSessionID(c)
 	if sessionID != "" {
 		// Validate session - if invalid, clear cookie and allow access
 		_, _, err := authManager.ValidateSession(sessionID)
 		if err == nil {
-			// Valid session - redirect to home
-			c.Redirect(http.StatusFound, "/")
+			// Valid session - redirect to the intended page or home
+			c.Redirect(http.StatusFound, basepath.URL(validation.SafeRedirectPath(next, "/")))
 			return
 		}
 		// Invalid session - clear cookie and continue to show login page
@@ -135,40 +185,125 @@ func loginViewHandler(c *gin.Context, authManager *auth.AuthManager) {
 		errorMsg = c.GetString("error")
 	}
 
-	displayName, loggedIn := getNavData(c, authManager)
+	// Pre-filled from the last successful login in this browser (config login.remember_username)
+	var username string
+	if cfg := config.GetConfig(); cfg != nil && cfg.Login.RememberUsername.Enabled {
+		username = middleware.RememberedUsername(c)
+	}
+
 	metaTags := pages.MetaTags("login, autenticação, entrar", "Faça login na sua conta")
-	bodyContent := layouts.AuthContentWrap(pages.LoginPage(errorMsg, icons.Error(), icons.LogIn(), icons.User(), icons.Lock()))
+	bodyContent := layouts.AuthContentWrap(pages.LoginPage(errorMsg, next, username, registrationOpen(), captchaSlot, icons.Error(), icons.LogIn(), icons.User(), icons.Lock()))
 
 	loginTemplate := layouts.Layout(
-		"Entrar - GoHTMX",
+		"Entrar",
 		metaTags,
 		bodyContent,
-		displayName,
-		loggedIn,
 		false, // isAdmin
 		icons.LogIn(),
 		icons.UserPlus(),
 		icons.LogOut(),
 		icons.Menu(),
-		AppVersion,
-		time.Now().Year(),
 	)
 
-	if err := htmx.NewResponse().RenderTempl(c.Request.Context(), c.Writer, loginTemplate); err != nil {
+	if err := htmx.NewResponse().RenderTempl(renderContext(c, authManager), c.Writer, loginTemplate); err != nil {
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+}
+
+// loginChallengeViewHandler renders the page asking for the code emailed for a login held back by the
+// new country challenge (?challenge=, from the login response). Without a challenge it sends to the login.
+// rememberDevice > 0 offers to remember the device for that long.
+func loginChallengeViewHandler(c *gin.Context, authManager *auth.AuthManager, rememberDevice time.Duration) {
+	challenge := c.Query("challenge")
+	if challenge == "" {
+		c.Redirect(http.StatusFound, basepath.URL("/login"))
+		return
+	}
+	next := validation.SafeRedirectPath(c.Query("next"), "")
+	// Whole days, rounded up so a lifetime under a day still shows the option
+	rememberDays := int((rememberDevice + 24*time.Hour - 1) / (24 * time.Hour))
+
+	metaTags := pages.MetaTags("login, confirmação, código", "Confirme o login com o código enviado por email")
+	challengeTemplate := layouts.Layout(
+		"Confirme o login",
+		metaTags,
+		layouts.AuthContentWrap(pages.LoginChallengePage(challenge, next, rememberDays, icons.Mail(), icons.LogIn())),
+		false, // isAdmin
+		icons.LogIn(),
+		icons.UserPlus(),
+		icons.LogOut(),
+		icons.Menu(),
+	)
+
+	c.Header("Cache-Control", "no-store")
+	if err := htmx.NewResponse().RenderTempl(renderContext(c, authManager), c.Writer, challengeTemplate); err != nil {
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+}
+
+// cancelDeletionViewHandler renders the page of the account deletion email's link (?token=), which
+// cancels the deletion. Without a token it sends to the login.
+func cancelDeletionViewHandler(c *gin.Context, authManager *auth.AuthManager) {
+	token := c.Query("token")
+	if token == "" {
+		c.Redirect(http.StatusFound, basepath.URL("/login"))
+		return
+	}
+
+	metaTags := pages.MetaTags("conta, exclusão, cancelar", "Cancele a exclusão da sua conta")
+	cancelTemplate := layouts.Layout(
+		"Cancelar exclusão da conta",
+		metaTags,
+		layouts.AuthContentWrap(pages.CancelDeletionPage(token, icons.UserCheck())),
+		false, // isAdmin
+		icons.LogIn(),
+		icons.UserPlus(),
+		icons.LogOut(),
+		icons.Menu(),
+	)
+
+	c.Header("Cache-Control", "no-store")
+	if err := htmx.NewResponse().RenderTempl(renderContext(c, authManager), c.Writer, cancelTemplate); err != nil {
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+}
+
+// recoverViewHandler renders the page where users set a new password with an admin's recovery code.
+func recoverViewHandler(c *gin.Context, authManager *auth.AuthManager) {
+	metaTags := pages.MetaTags("conta, recuperar, senha, código", "Recupere sua conta com o código de recuperação")
+	recoverTemplate := layouts.Layout(
+		"Recuperar conta",
+		metaTags,
+		layouts.AuthContentWrap(pages.RecoverPage(icons.KeyRound(), icons.User(), icons.KeyRound(), icons.Lock())),
+		false, // isAdmin
+		icons.LogIn(),
+		icons.UserPlus(),
+		icons.LogOut(),
+		icons.Menu(),
+	)
+
+	c.Header("Cache-Control", "no-store")
+	if err := htmx.NewResponse().RenderTempl(renderContext(c, authManager), c.Writer, recoverTemplate); err != nil {
 		c.AbortWithStatus(http.StatusInternalServerError)
 		return
 	}
 }
 
 // registerViewHandler handles a view for the registration page.
-func registerViewHandler(c *gin.Context, authManager *auth.AuthManager) {
+// captchaSlot is the registration CAPTCHA container (see handlers.AuthHandler.RegisterCaptcha).
+// With ?invite=... the form registers through that invite (see service.InviteService), which also works
+// while registration is disabled; invites may be nil when they aren't enabled.
+func registerViewHandler(c *gin.Context, authManager *auth.AuthManager, invites *service.InviteService, captchaSlot templ.Component) {
 	sessionID := middleware.ExtractSessionID(c)
 	if sessionID != "" {
 		// Validate session - if invalid, clear cookie and allow access
 		_, _, err := authManager.ValidateSession(sessionID)
 		if err == nil {
-			// Valid session - redirect to home
-			c.Redirect(http.StatusFound, "/")
+			// Valid session - redirect to the intended page (local paths only) or home
+			c.Redirect(http.StatusFound, basepath.URL(validation.SafeRedirectPath(c.Query("next"), "/")))
 			return
 		}
 		// Invalid session - clear cookie and continue to show register page
@@ -180,38 +315,306 @@ func registerViewHandler(c *gin.Context, authManager *auth.AuthManager) {
 		errorMsg = c.GetString("error")
 	}
 
-	displayName, loggedIn := getNavData(c, authManager)
-	metaTags := pages.MetaTags("registro, criar conta, cadastro", "Crie uma nova conta")
-	bodyContent := layouts.AuthContentWrap(pages.RegisterPage(errorMsg, icons.Error(), icons.UserPlus(), icons.User(), icons.Mail(), icons.UserCircle(), icons.Lock(), icons.ValidationSuccess(), icons.ValidationFail()))
+	inviteToken := c.Query("invite")
+	inviteEmail := ""
+	switch {
+	case inviteToken != "":
+		invite, err := lookupInvite(invites, inviteToken)
+		if message, unusable := inviteUnusableMessage(err); unusable {
+			renderRegisterPage(c, authManager, http.StatusForbidden, pages.RegistrationClosedPage("Convite indisponível", message, icons.LogIn()))
+			return
+		}
+		if err != nil {
+			renderErrorPage(c, http.StatusInternalServerError)
+			return
+		}
+		inviteEmail = invite.Email
+	case !registrationOpen():
+		renderRegisterPage(c, authManager, http.StatusForbidden, pages.RegistrationClosedPage("Cadastro desativado", "Novas contas são criadas por um administrador. Se você já tem uma conta, entre normalmente.", icons.LogIn()))
+		return
+	}
 
+	checkEmail, termsURL := false, ""
+	if cfg := config.GetConfig(); cfg != nil {
+		checkEmail = cfg.Features().EmailAvailabilityCheck
+		if cfg.Features().Terms {
+			termsURL = cfg.Terms.URL
+		}
+	}
+	renderRegisterPage(c, authManager, http.StatusOK, pages.RegisterPage(errorMsg, checkEmail, inviteToken, inviteEmail, captchaSlot, termsURL, icons.Error(), icons.UserPlus(), icons.User(), icons.Mail(), icons.UserCircle(), icons.Lock(), icons.ValidationSuccess(), icons.ValidationFail()))
+}
+
+// renderRegisterPage renders content (the form or why it isn't available) in the registration page layout.
+func renderRegisterPage(c *gin.Context, authManager *auth.AuthManager, status int, content templ.Component) {
+	metaTags := pages.MetaTags("registro, criar conta, cadastro", "Crie uma nova conta")
 	registerTemplate := layouts.Layout(
-		"Criar Conta - GoHTMX",
+		"Criar Conta",
 		metaTags,
-		bodyContent,
-		displayName,
-		loggedIn,
+		layouts.AuthContentWrap(content),
 		false, // isAdmin
 		icons.LogIn(),
 		icons.UserPlus(),
 		icons.LogOut(),
 		icons.Menu(),
-		AppVersion,
-		time.Now().Year(),
 	)
 
-	if err := htmx.NewResponse().RenderTempl(c.Request.Context(), c.Writer, registerTemplate); err != nil {
+	if err := htmx.NewResponse().StatusCode(status).RenderTempl(renderContext(c, authManager), c.Writer, registerTemplate); err != nil {
 		c.AbortWithStatus(http.StatusInternalServerError)
 		return
 	}
 }
 
-// wantsHTML returns true when the request prefers an HTML response (browser navigation).
-func wantsHTML(c *gin.Context) bool {
-	accept := c.GetHeader("Accept")
-	if accept == "" {
-		return true
+// verifyEmailViewHandler renders the "verify your email" page that protected routes redirect to while
+// login.verified_email_gate is on. Without a session it sends to the login; verified users go home.
+func verifyEmailViewHandler(c *gin.Context, authManager *auth.AuthManager) {
+	loginURL := basepath.URL("/login?next=" + url.QueryEscape(middleware.VerifyEmailPath))
+	sessionID := middleware.ExtractSessionID(c)
+	if sessionID == "" {
+		c.Redirect(http.StatusFound, loginURL)
+		return
+	}
+	_, user, err := authManager.ValidateSession(sessionID)
+	if err != nil || user == nil {
+		middleware.ClearSessionCookie(c)
+		c.Redirect(http.StatusFound, loginURL)
+		return
+	}
+	if user.EmailVerified {
+		c.Redirect(http.StatusFound, basepath.URL("/"))
+		return
+	}
+
+	metaTags := pages.MetaTags("verificar email, confirmação", "Confirme seu email para continuar")
+	verifyTemplate := layouts.Layout(
+		"Verifique seu email",
+		metaTags,
+		layouts.AuthContentWrap(pages.VerifyEmailPage(user.Email, icons.Mail(), icons.LogOut())),
+		false, // isAdmin
+		icons.LogIn(),
+		icons.UserPlus(),
+		icons.LogOut(),
+		icons.Menu(),
+	)
+
+	if err := htmx.NewResponse().RenderTempl(renderContext(c, authManager), c.Writer, verifyTemplate); err != nil {
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+}
+
+// termsViewHandler renders the page that protected routes redirect to while the user hasn't accepted the
+// current terms of use (config terms.version). Without a session it sends to the login; users who are up
+// to date, or all users when the feature is off, go home.
+func termsViewHandler(c *gin.Context, authManager *auth.AuthManager, terms config.TermsConfig) {
+	loginURL := basepath.URL("/login?next=" + url.QueryEscape(middleware.TermsPath))
+	sessionID := middleware.ExtractSessionID(c)
+	if sessionID == "" {
+		c.Redirect(http.StatusFound, loginURL)
+		return
+	}
+	_, user, err := authManager.ValidateSession(sessionID)
+	if err != nil || user == nil {
+		middleware.ClearSessionCookie(c)
+		c.Redirect(http.StatusFound, loginURL)
+		return
+	}
+	if terms.Version == "" || user.TermsVersion == terms.Version {
+		c.Redirect(http.StatusFound, basepath.URL("/"))
+		return
+	}
+
+	metaTags := pages.MetaTags("termos de uso, aceite", "Aceite os termos de uso atualizados")
+	termsTemplate := layouts.Layout(
+		"Termos de uso",
+		metaTags,
+		layouts.AuthContentWrap(pages.TermsPage(terms.URL, icons.Success(), icons.LogOut())),
+		false, // isAdmin
+		icons.LogIn(),
+		icons.UserPlus(),
+		icons.LogOut(),
+		icons.Menu(),
+	)
+
+	if err := htmx.NewResponse().RenderTempl(renderContext(c, authManager), c.Writer, termsTemplate); err != nil {
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+}
+
+// notificationLabels holds the profile page label and description of each optional email type.
+var notificationLabels = map[string][2]string{
+	email.TypeAccountLocked:      {"Aviso de conta bloqueada", "Email quando a conta é bloqueada após várias tentativas de login erradas."},
+	email.TypeAccountDeactivated: {"Aviso de desativação por inatividade", "Email quando a conta é desativada por ficar muito tempo sem uso."},
+	email.TypeNewLogin:           {"Aviso de novo login", "Email quando alguém entra na conta enquanto ela está aberta em outro dispositivo."},
+}
+
+// profileView renders the logged-in user's profile page with their phone and notification preferences.
+// Runs behind middleware.WebAuthMiddleware, which stores the user in the context.
+func profileView(c *gin.Context, notifications *service.NotificationService, accounts *service.AccountService, authManager *auth.AuthManager) {
+	user := c.MustGet("user").(*auth.UserData)
+	userID, _ := strconv.ParseUint(user.ID, 10, 64)
+	enabled, err := notifications.Preferences(uint(userID))
+	if err != nil {
+		renderErrorPage(c, http.StatusInternalServerError)
+		return
+	}
+	phone, err := accounts.Phone(user.ID)
+	if err != nil {
+		renderErrorPage(c, http.StatusInternalServerError)
+		return
+	}
+
+	// The current session counts too; the profile only lists how many others there are
+	otherSessions, err := authManager.CountUserSessions(user.ID)
+	if err != nil {
+		renderErrorPage(c, http.StatusInternalServerError)
+		return
+	}
+	device := "dispositivo desconhecido"
+	if session, ok := c.Get("session"); ok {
+		device = useragent.Parse(session.(*auth.Session).UserAgent).Describe()
+	}
+
+	toggles := make([]pages.NotificationToggle, 0, len(email.OptionalTypes))
+	for _, t := range email.OptionalTypes {
+		label := notificationLabels[t]
+		toggles = append(toggles, pages.NotificationToggle{Type: t, Label: cmp.Or(label[0], t), Description: label[1], Enabled: enabled[t]})
+	}
+
+	ctx := renderContext(c, authManager)
+	metaTags := pages.MetaTags("perfil, notificações", "Seu perfil e preferências de notificação")
+	profileTemplate := layouts.Layout(
+		"Perfil",
+		metaTags,
+		layouts.AuthContentWrap(pages.ProfilePage(render.From(ctx).DisplayName(), user.Email, phone, c.Query("error"), toggles, deletionNotice(), device, max(otherSessions-1, 0))),
+		false, // isAdmin
+		icons.LogIn(),
+		icons.UserPlus(),
+		icons.LogOut(),
+		icons.Menu(),
+	)
+
+	if err := htmx.NewResponse().RenderTempl(ctx, c.Writer, profileTemplate); err != nil {
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+}
+
+// deletionNotice tells, on the profile page, what deleting the account does under config account.deletion_grace_period.
+func deletionNotice() string {
+	cfg := config.GetConfig()
+	if cfg == nil || cfg.Account.DeletionGracePeriod <= 0 {
+		return "A conta e seus dados são excluídos na hora, sem volta. Confirme com sua senha."
+	}
+	days := int(cfg.Account.DeletionGracePeriod.Hours() / 24)
+	return fmt.Sprintf("A conta é desativada e excluída de vez após %d dia(s). Até lá, você pode cancelar pelo link enviado por email ou entrando de novo.", max(days, 1))
+}
+
+// profileNotificationsPost stores which optional emails the user receives; the checked "notifications"
+// form values are the enabled types. HTMX gets an alert for #notifications-result, other clients go back to /profile.
+func profileNotificationsPost(c *gin.Context, notifications *service.NotificationService) {
+	userID, _ := strconv.ParseUint(c.GetString("userID"), 10, 64)
+	enabled := map[string]bool{}
+	for _, t := range c.PostFormArray("notifications") {
+		enabled[t] = true
+	}
+
+	err := notifications.SetPreferences(uint(userID), enabled)
+	if c.GetHeader("HX-Request") == "" {
+		if err != nil {
+			renderErrorPage(c, http.StatusInternalServerError)
+			return
+		}
+		c.Redirect(http.StatusSeeOther, basepath.URL("/profile"))
+		return
+	}
+
+	alert := components.SuccessAlert("Preferências salvas.", icons.Success())
+	if err != nil {
+		alert = components.ErrorAlert("Não foi possível salvar as preferências. Tente novamente.", icons.Error())
+	}
+	if err := htmx.NewResponse().RenderTempl(c.Request.Context(), c.Writer, alert); err != nil {
+		c.AbortWithStatus(http.StatusInternalServerError)
 	}
-	return strings.Contains(accept, "text/html")
+}
+
+// profilePhonePost saves or (when empty) removes the user's phone number. HTMX gets an alert for
+// #phone-result, other clients go back to /profile.
+func profilePhonePost(c *gin.Context, accounts *service.AccountService) {
+	phone, err := accounts.SetPhone(c.GetString("userID"), c.PostForm("phone"))
+	var validationErr *service.ValidationError
+	if c.GetHeader("HX-Request") == "" {
+		switch {
+		case errors.As(err, &validationErr):
+			c.Redirect(http.StatusSeeOther, basepath.URL("/profile?error="+url.QueryEscape(err.Error())))
+		case err != nil:
+			renderErrorPage(c, http.StatusInternalServerError)
+		default:
+			c.Redirect(http.StatusSeeOther, basepath.URL("/profile"))
+		}
+		return
+	}
+
+	var alert templ.Component
+	switch {
+	case errors.As(err, &validationErr):
+		alert = components.ErrorAlert(err.Error(), icons.Error())
+	case err != nil:
+		alert = components.ErrorAlert("Não foi possível salvar o telefone. Tente novamente.", icons.Error())
+	case phone == "":
+		alert = components.SuccessAlert("Telefone removido.", icons.Success())
+	default:
+		alert = components.SuccessAlert("Telefone salvo: "+phone, icons.Success())
+	}
+	if err := htmx.NewResponse().RenderTempl(c.Request.Context(), c.Writer, alert); err != nil {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}
+}
+
+// lookupInvite finds a usable invite; without an invite service every token is unknown.
+func lookupInvite(invites *service.InviteService, token string) (*models.Invite, error) {
+	if invites == nil {
+		return nil, service.ErrInviteNotFound
+	}
+	return invites.Lookup(token)
+}
+
+// inviteUnusableMessage explains why an invite link can't be used; ok is false when err isn't one of
+// those reasons (nil or a storage error).
+func inviteUnusableMessage(err error) (message string, ok bool) {
+	switch {
+	case errors.Is(err, service.ErrInviteNotFound):
+		return "Este link de convite não é válido. Confira o endereço ou peça um novo convite.", true
+	case errors.Is(err, service.ErrInviteExpired):
+		return "Este convite expirou. Peça um novo convite a um administrador.", true
+	case errors.Is(err, service.ErrInviteUsed):
+		return "Este convite já foi usado. Se a conta é sua, entre normalmente.", true
+	}
+	return "", false
+}
+
+// registrationOpen reports whether public sign-up is enabled (config registration.enabled, on by default).
+func registrationOpen() bool {
+	return config.GetConfig().Features().Registration
+}
+
+// readinessHandler answers GET /health/ready: 503 when the database doesn't respond, otherwise 200 with
+// "ok", or "degraded" while emails are only logged because the SMTP config is invalid (emailDegraded).
+// The endpoint is public, so the reason only goes to the log.
+func readinessHandler(c *gin.Context, db *gorm.DB, emailDegraded error) {
+	checks := gin.H{"database": "ok", "email": "ok"}
+	status, code := "ok", http.StatusOK
+	if emailDegraded != nil {
+		logger.Warn("Emails só registrados no log: configuração SMTP inválida", "error", emailDegraded)
+		checks["email"] = "degraded"
+		status = "degraded"
+	}
+	if err := pingDatabase(db); err != nil {
+		logger.Error("Banco de dados indisponível na verificação de prontidão", "error", err)
+		checks["database"] = "unavailable"
+		status, code = "unavailable", http.StatusServiceUnavailable
+	}
+	c.JSON(code, gin.H{"status": status, "checks": checks})
 }
 
 // renderErrorPage writes an HTTP error page (404, 403, 500, 503) using the error layout and template.
@@ -221,28 +624,28 @@ func renderErrorPage(c *gin.Context, code int) {
 	var content templ.Component
 	switch code {
 	case http.StatusNotFound:
-		title = "Página não encontrada - GoHTMX"
+		title = "Página não encontrada"
 		metaKeywords = "erro 404, não encontrado"
 		metaDesc = "Página não encontrada."
 		content = pages.Error404Content()
 	case http.StatusForbidden:
-		title = "Acesso negado - GoHTMX"
+		title = "Acesso negado"
 		metaKeywords = "erro 403, acesso negado"
 		metaDesc = "Acesso negado."
 		content = pages.Error403Content()
 	case http.StatusInternalServerError:
-		title = "Erro interno - GoHTMX"
+		title = "Erro interno"
 		metaKeywords = "erro 500, erro interno"
 		metaDesc = "Erro interno do servidor."
 		content = pages.Error500Content()
 	case http.StatusServiceUnavailable:
-		title = "Em manutenção - GoHTMX"
+		title = "Em manutenção"
 		metaKeywords = "erro 503, manutenção"
 		metaDesc = "Serviço temporariamente indisponível."
 		content = pages.Error503Content()
 	default:
 		code = http.StatusInternalServerError
-		title = "Erro - GoHTMX"
+		title = "Erro"
 		metaKeywords = "erro"
 		metaDesc = "Ocorreu um erro."
 		content = pages.Error500Content()
@@ -251,7 +654,8 @@ func renderErrorPage(c *gin.Context, code int) {
 	tmpl := layouts.ErrorLayout(title, metaTags, content)
 	c.Status(code)
 	c.Header("Content-Type", "text/html; charset=utf-8")
-	if err := tmpl.Render(context.Background(), c.Writer); err != nil {
+	ctx := render.WithContext(c.Request.Context(), render.Context{Brand: appBrand()})
+	if err := tmpl.Render(ctx, c.Writer); err != nil {
 		c.AbortWithStatus(http.StatusInternalServerError)
 	}
 }
@@ -263,8 +667,8 @@ func adminDashboardView(c *gin.Context, db *gorm.DB, authManager *auth.AuthManag
 	db.Model(&models.User{}).Count(&totalUsers)
 	db.Model(&models.User{}).Where("active = ?", true).Count(&activeUsers)
 	db.Model(&models.User{}).Where("active = ?", false).Count(&inactiveUsers)
-	db.Model(&models.User{}).Where("role = ?", roleAdmin).Count(&adminUsers)
-	db.Model(&models.User{}).Where("role = ?", roleUser).Count(&regularUsers)
+	db.Model(&models.User{}).Where("role = ?", service.RoleAdmin).Count(&adminUsers)
+	db.Model(&models.User{}).Where("role = ?", service.RoleUser).Count(&regularUsers)
 
 	stats := admin.DashboardStats{
 		TotalUsers:    int(totalUsers),
@@ -274,62 +678,161 @@ func adminDashboardView(c *gin.Context, db *gorm.DB, authManager *auth.AuthManag
 		RegularUsers:  int(regularUsers),
 	}
 
-	displayName, loggedIn := getNavData(c, authManager)
 	metaTags := pages.MetaTags("admin, dashboard, estatísticas", "Dashboard administration")
 	pageContent := admin.DashboardPage(stats, icons.Users(), icons.UsersRound(), icons.UserCheck(), icons.UserX(), icons.Shield(), icons.User())
-	bodyContent := layouts.AdminBody("", icons.LayoutDashboard(), icons.Users(), icons.LogOut(), icons.Home(), pageContent)
+	bodyContent := layouts.AdminBody("", icons.LayoutDashboard(), icons.Users(), icons.KeyRound(), icons.LogOut(), icons.Home(), pageContent)
 
 	tmpl := layouts.Layout(
-		"Dashboard - Admin - GoHTMX",
+		"Dashboard - Admin",
 		metaTags,
 		bodyContent,
-		displayName,
-		loggedIn,
 		true, // isAdmin
 		icons.LogIn(),
 		icons.UserPlus(),
 		icons.LogOut(),
 		icons.Menu(),
-		AppVersion,
-		time.Now().Year(),
 	)
 
-	if err := htmx.NewResponse().RenderTempl(c.Request.Context(), c.Writer, tmpl); err != nil {
+	if err := htmx.NewResponse().RenderTempl(renderContext(c, authManager), c.Writer, tmpl); err != nil {
 		c.AbortWithStatus(http.StatusInternalServerError)
 	}
 }
 
-// adminUsersView renders the admin users list inside the app Layout (navbar + AdminBody + footer).
-func adminUsersView(c *gin.Context, db *gorm.DB, authManager *auth.AuthManager) {
-	var users []models.User
-	if err := db.Order("created_at DESC").Find(&users).Error; err != nil {
+// Limits for the signups chart range (days).
+const (
+	defaultSignupDays = 30
+	maxSignupDays     = 365
+)
+
+// signupPoint is one day of the signups series.
+type signupPoint struct {
+	Date  string `json:"date"` // YYYY-MM-DD (UTC)
+	Count int64  `json:"count"`
+}
+
+// adminEmailPreview renders the transactional email ?type= (one of email.PreviewTypes) with sample data, as
+// the recipient would see it, so templates can be checked without SMTP. Unknown types get the 404 page.
+func adminEmailPreview(c *gin.Context, cfg config.EmailConfig) {
+	_, body, err := email.Preview(cfg, c.Query("type"))
+	if errors.Is(err, email.ErrUnknownType) {
+		renderErrorPage(c, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.Error("Erro ao gerar a prévia do email", "error", err, "type", c.Query("type"))
 		renderErrorPage(c, http.StatusInternalServerError)
 		return
 	}
-	views := make([]admin.UserView, 0, len(users))
-	for i := range users {
-		view := userViewFromModel(&users[i])
-		views = append(views, view)
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(body))
+}
+
+// adminSignupStatsJSON returns daily registration counts for the last ?days= days (default 30, max 365).
+func adminSignupStatsJSON(c *gin.Context, db *gorm.DB) {
+	days := defaultSignupDays
+	if raw := c.Query("days"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days deve ser um número positivo"})
+			return
+		}
+		days = min(n, maxSignupDays)
+	}
+
+	series, err := signupSeries(db, days, time.Now())
+	if err != nil {
+		logger.Error("Erro ao calcular cadastros por dia", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "falha ao carregar estatísticas"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"days": days, "series": series})
+}
+
+// signupSeries counts users created per UTC day over the last days days (ending today),
+// filling days without registrations with zero so the series always has len == days.
+func signupSeries(db *gorm.DB, days int, now time.Time) ([]signupPoint, error) {
+	today := now.UTC().Truncate(24 * time.Hour)
+	start := today.AddDate(0, 0, -(days - 1))
+
+	// PostgreSQL would take the day in the session's time zone; SQLite's date() already converts to UTC
+	day := "date(created_at)"
+	var args []any
+	if db.Dialector.Name() == "postgres" {
+		day, args = "date(created_at AT TIME ZONE ?)", []any{"UTC"}
+	}
+	var rows []signupPoint
+	err := db.Model(&models.User{}).
+		Select("CAST("+day+" AS TEXT) AS date, COUNT(*) AS count", args...).
+		Where("created_at >= ?", start).
+		Group("date"). // the alias, as Group takes no placeholders
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, r := range rows {
+		counts[r.Date] = r.Count
+	}
+
+	series := make([]signupPoint, 0, days)
+	for d := start; !d.After(today); d = d.AddDate(0, 0, 1) {
+		key := d.Format(time.DateOnly)
+		series = append(series, signupPoint{Date: key, Count: counts[key]})
+	}
+	return series, nil
+}
+
+// A user is flagged in the session stats with at least sessionAnomalyThreshold sessions created within
+// sessionAnomalyWindow, or as many still active.
+const (
+	sessionAnomalyWindow    = 24 * time.Hour
+	sessionAnomalyThreshold = 20
+	sessionStatsLimit       = 20
+)
+
+// adminSessionStatsJSON returns the users with the most sessions created in the last sessionAnomalyWindow,
+// flagging those past sessionAnomalyThreshold (see service.SessionStatsService).
+func adminSessionStatsJSON(c *gin.Context, stats *service.SessionStatsService) {
+	activity, err := stats.Activity(time.Now().Add(-sessionAnomalyWindow), sessionAnomalyThreshold, sessionStatsLimit)
+	if err != nil {
+		logger.Error("Erro ao calcular sessões por usuário", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "falha ao carregar estatísticas"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"window_hours": int(sessionAnomalyWindow.Hours()),
+		"threshold":    sessionAnomalyThreshold,
+		"users":        activity,
+	})
+}
+
+// adminUsersView renders the admin users list inside the app Layout (navbar + AdminBody + footer), filtered by ?q=.
+func adminUsersView(c *gin.Context, users service.UserAdminServiceInterface, authManager *auth.AuthManager) {
+	query := strings.TrimSpace(c.Query("q"))
+	page, err := users.List(service.UserFilter{Query: query})
+	if err != nil {
+		renderErrorPage(c, http.StatusInternalServerError)
+		return
+	}
+	views := make([]admin.UserView, 0, len(page.Users))
+	for i := range page.Users {
+		views = append(views, userRowView(&page.Users[i], users))
 	}
-	displayName, loggedIn := getNavData(c, authManager)
 	metaTags := pages.MetaTags("admin, usuários, gestão", "Gerencie usuários do sistema.")
-	pageContent := admin.UsersPage(views, icons.CircleCheckForStatus(), icons.ValidationFail(), icons.Trash2(), icons.Error())
-	bodyContent := layouts.AdminBody("users", icons.LayoutDashboard(), icons.Users(), icons.LogOut(), icons.Home(), pageContent)
+	pageContent := admin.UsersPage(views, query, icons.CircleCheckForStatus(), icons.ValidationFail(), icons.Trash2(), icons.Error(), icons.Users(), newUserAccountFields())
+	bodyContent := layouts.AdminBody("users", icons.LayoutDashboard(), icons.Users(), icons.KeyRound(), icons.LogOut(), icons.Home(), pageContent)
 	tmpl := layouts.Layout(
-		"Usuários - Admin - GoHTMX",
+		"Usuários - Admin",
 		metaTags,
 		bodyContent,
-		displayName,
-		loggedIn,
 		true, // isAdmin
 		icons.LogIn(),
 		icons.UserPlus(),
 		icons.LogOut(),
 		icons.Menu(),
-		AppVersion,
-		time.Now().Year(),
 	)
-	if err := htmx.NewResponse().RenderTempl(c.Request.Context(), c.Writer, tmpl); err != nil {
+	if err := htmx.NewResponse().RenderTempl(renderContext(c, authManager), c.Writer, tmpl); err != nil {
 		c.AbortWithStatus(http.StatusInternalServerError)
 	}
 }
@@ -340,23 +843,96 @@ func userViewFromModel(u *models.User) admin.UserView {
 	if !u.LastLogin.IsZero() {
 		lastLogin = u.LastLogin.Format("02/01/2006 15:04")
 	}
+	avatarURL := ""
+	if avatarsEnabled() {
+		avatarURL = avatar.URL(u.AvatarURL, u.Email, listAvatarSize)
+	}
 	return admin.UserView{
 		ID:          strconv.FormatUint(uint64(u.ID), 10),
 		Username:    u.Username,
 		Email:       u.Email,
 		DisplayName: u.DisplayName,
+		AvatarURL:   avatarURL,
 		Role:        u.Role,
 		Active:      u.Active,
 		LastLogin:   lastLogin,
+		PasswordAge: passwordAge(u, time.Now()),
+	}
+}
+
+// passwordAge formats how long ago u's password was set, in whole days.
+func passwordAge(u *models.User, now time.Time) string {
+	changedAt := u.CreatedAt
+	if u.PasswordChangedAt != nil {
+		changedAt = *u.PasswordChangedAt
 	}
+	switch days := int(now.Sub(changedAt).Hours() / 24); days {
+	case 0:
+		return "hoje"
+	case 1:
+		return "1 dia"
+	default:
+		return strconv.Itoa(days) + " dias"
+	}
+}
+
+// userRowView is userViewFromModel plus the login status badge shown in the users table row.
+func userRowView(u *models.User, users service.UserAdminServiceInterface) admin.UserView {
+	view := userViewFromModel(u)
+	view.LoginStatus = string(users.LoginStatus(u))
+	return view
+}
+
+// abortUserError maps user service errors to a bare status for HTMX fragment endpoints.
+func abortUserError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrUserNotFound) {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	c.AbortWithStatus(http.StatusInternalServerError)
+}
+
+// adminReadOnlyBlocked answers admin mutations refused by middleware.AdminReadOnlyMiddleware: HTMX gets
+// the error alert in the admin area's #admin-alert slot, plain form posts go back to the dashboard, where
+// the read-only notice is shown.
+func adminReadOnlyBlocked(c *gin.Context) {
+	if c.GetHeader("HX-Request") == "" {
+		c.Redirect(http.StatusSeeOther, basepath.URL("/admin"))
+		return
+	}
+	renderAdminAlert(c, middleware.MsgAdminReadOnly)
+}
+
+// renderAdminAlert answers an HTMX request with message in the admin area's #admin-alert slot.
+func renderAdminAlert(c *gin.Context, message string) {
+	// HTMX não faz swap em 4xx; retornar 200 para o alerta aparecer em #admin-alert
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Header("HX-Retarget", "#admin-alert")
+	c.Header("HX-Reswap", "innerHTML")
+	c.Status(http.StatusOK)
+	_ = components.ErrorAlert(message, icons.Error()).Render(context.Background(), c.Writer)
 }
 
-// normalizeUserRole ensures only supported roles are persisted.
-func normalizeUserRole(role string) string {
-	if role != roleAdmin && role != roleUser {
-		return roleUser
+// confirmField is the form field of a typed confirmation (components.TypedConfirmation).
+const confirmField = "confirm"
+
+// typedConfirmation is the server-side check behind the "type X to confirm" prompt of destructive admin
+// actions: it reports whether the form's confirm field is exactly expected (the target's username, or a
+// fixed phrase for actions without a single target), so a direct or cross-site POST without it does
+// nothing. Otherwise the request is answered (the alert in #admin-alert for HTMX, 400 JSON otherwise)
+// and false is returned.
+func typedConfirmation(c *gin.Context, expected string) bool {
+	if expected != "" && strings.TrimSpace(c.PostForm(confirmField)) == expected {
+		return true
+	}
+	message := fmt.Sprintf("confirmação incorreta: digite %q para confirmar; nada foi alterado", expected)
+	if c.GetHeader("HX-Request") != "" {
+		renderAdminAlert(c, message)
+	} else {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
 	}
-	return role
+	c.Abort()
+	return false
 }
 
 // parseBoolFormValue treats common form truthy values as true.
@@ -364,93 +940,249 @@ func parseBoolFormValue(value string) bool {
 	return value == "true" || value == "1"
 }
 
-// renderNewUserHTMXError writes the error fragment for the new-user form.
-func renderNewUserHTMXError(c *gin.Context, message string) {
+// newUserAccountFields returns the inputs of the admin new-user form, shared with the registration form.
+func newUserAccountFields() templ.Component {
+	return components.AccountFields(basepath.URL("/admin/users/available"), true, "",
+		icons.User(), icons.Mail(), icons.UserCircle(), icons.Lock(), icons.ValidationSuccess(), icons.ValidationFail())
+}
+
+// newUserFields lists the admin new-user form fields with an error slot: the registration ones plus the phone.
+var newUserFields = append(slices.Clone(validation.RegistrationFields), validation.FieldPhone)
+
+// renderNewUserHTMXError writes the error fragment for the new-user form, plus out-of-band swaps that
+// fill (or clear) each field's error slot.
+func renderNewUserHTMXError(c *gin.Context, message string, fieldErrs validation.FieldErrors) {
 	// HTMX não faz swap em 4xx; retornar 200 para o conteúdo de erro ser colocado em #new-user-error
 	alert := components.ErrorAlert(message, icons.Error())
 	c.Header("Content-Type", "text/html; charset=utf-8")
 	c.Header("HX-Retarget", "#new-user-error")
 	c.Header("HX-Reswap", "innerHTML")
 	c.Status(http.StatusOK)
-	_ = alert.Render(context.Background(), c.Writer)
+	_ = templ.Join(alert, components.FieldErrorsOOB(newUserFields, fieldErrs)).Render(context.Background(), c.Writer)
 }
 
 // respondNewUserError sends an HTMX fragment or redirects with a query error.
-func respondNewUserError(c *gin.Context, message string) {
+// fieldErrs only reach HTMX clients; the redirect carries the summary message.
+func respondNewUserError(c *gin.Context, message string, fieldErrs validation.FieldErrors) {
 	if c.GetHeader("HX-Request") != "" {
-		renderNewUserHTMXError(c, message)
+		renderNewUserHTMXError(c, message, fieldErrs)
 		return
 	}
-	c.Redirect(http.StatusSeeOther, "/admin/users/new?error="+url.QueryEscape(message))
+	c.Redirect(http.StatusSeeOther, basepath.URL("/admin/users/new?error="+url.QueryEscape(message)))
 }
 
 // adminUserRolePost updates a user's role and returns the updated table row HTML for HTMX swap.
-func adminUserRolePost(c *gin.Context, db *gorm.DB) {
-	idStr := c.Param("id")
+func adminUserRolePost(c *gin.Context, users service.UserAdminServiceInterface) {
 	// PostForm reads from both URL query and body; form from HTMX is in body as application/x-www-form-urlencoded
 	role := c.PostForm("role")
 	if role == "" {
 		// Fallback to PostFormValue for clients that send role in the query string.
 		role = c.Request.PostFormValue("role")
 	}
-	role = normalizeUserRole(role)
-	var u models.User
-	if err := db.First(&u, idStr).Error; err != nil {
-		c.AbortWithStatus(http.StatusNotFound)
+	u, err := users.UpdateRole(c.Param("id"), role)
+	var validationErr *service.ValidationError
+	if errors.As(err, &validationErr) {
+		// e.g. service.ErrAccountTooNewForAdmin
+		if c.GetHeader("HX-Request") != "" {
+			renderAdminAlert(c, validationErr.Error())
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+		}
+		c.Abort()
 		return
 	}
-	u.Role = role
-	if err := db.Model(&u).Update("role", role).Error; err != nil {
-		c.AbortWithStatus(http.StatusInternalServerError)
+	if err != nil {
+		abortUserError(c, err)
 		return
 	}
-	view := userViewFromModel(&u)
-	row := admin.UserRow(view, icons.CircleCheckForStatus(), icons.ValidationFail(), icons.Trash2())
+	row := admin.UserRow(userRowView(u, users), icons.CircleCheckForStatus(), icons.ValidationFail(), icons.Trash2())
 	c.Header("Content-Type", "text/html; charset=utf-8")
 	_ = row.Render(context.Background(), c.Writer)
 }
 
 // adminUserActivePost toggles a user's active status and returns the updated table row HTML for HTMX swap.
-func adminUserActivePost(c *gin.Context, db *gorm.DB) {
-	idStr := c.Param("id")
-	activeStr := c.PostForm("active")
-	active := activeStr == "true" || activeStr == "1"
-	var u models.User
-	if err := db.First(&u, idStr).Error; err != nil {
-		c.AbortWithStatus(http.StatusNotFound)
+func adminUserActivePost(c *gin.Context, users service.UserAdminServiceInterface) {
+	u, err := users.SetActive(c.Param("id"), parseBoolFormValue(c.PostForm("active")))
+	if err != nil {
+		abortUserError(c, err)
 		return
 	}
-	u.Active = active
-	if err := db.Save(&u).Error; err != nil {
-		c.AbortWithStatus(http.StatusInternalServerError)
+	row := admin.UserRow(userRowView(u, users), icons.CircleCheckForStatus(), icons.ValidationFail(), icons.Trash2())
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	_ = row.Render(context.Background(), c.Writer)
+}
+
+// adminDisplayNameCell returns the read-only display-name cell (used to cancel an inline edit).
+func adminDisplayNameCell(c *gin.Context, users service.UserAdminServiceInterface) {
+	u, err := users.Get(c.Param("id"))
+	if err != nil {
+		abortUserError(c, err)
+		return
+	}
+	renderFragment(c, admin.DisplayNameCell(userViewFromModel(u)))
+}
+
+// adminDisplayNameEditView returns the inline edit form for a user's display name.
+func adminDisplayNameEditView(c *gin.Context, users service.UserAdminServiceInterface) {
+	u, err := users.Get(c.Param("id"))
+	if err != nil {
+		abortUserError(c, err)
+		return
+	}
+	renderFragment(c, admin.DisplayNameEdit(userViewFromModel(u), ""))
+}
+
+// adminDisplayNamePost validates and saves a new display name, returning the updated cell,
+// or the edit form with the validation error (200 so HTMX swaps it in).
+func adminDisplayNamePost(c *gin.Context, users service.UserAdminServiceInterface) {
+	id := c.Param("id")
+	displayName := c.PostForm("display_name")
+	u, err := users.UpdateDisplayName(id, displayName)
+	var validationErr *service.ValidationError
+	if errors.As(err, &validationErr) {
+		current, getErr := users.Get(id)
+		if getErr != nil {
+			abortUserError(c, getErr)
+			return
+		}
+		view := userViewFromModel(current)
+		view.DisplayName = strings.TrimSpace(displayName)
+		renderFragment(c, admin.DisplayNameEdit(view, validationErr.Error()))
+		return
+	}
+	if err != nil {
+		abortUserError(c, err)
 		return
 	}
-	view := userViewFromModel(&u)
-	row := admin.UserRow(view, icons.CircleCheckForStatus(), icons.ValidationFail(), icons.Trash2())
+	renderFragment(c, admin.DisplayNameCell(userViewFromModel(u)))
+}
+
+// renderFragment writes an HTML fragment for HTMX swaps.
+func renderFragment(c *gin.Context, component templ.Component) {
 	c.Header("Content-Type", "text/html; charset=utf-8")
-	_ = row.Render(context.Background(), c.Writer)
+	c.Status(http.StatusOK)
+	_ = component.Render(context.Background(), c.Writer)
+}
+
+// adminImpersonatePost replaces the admin's session with one acting as the user, then opens the home page.
+// Admins can't be impersonated (403).
+func adminImpersonatePost(c *gin.Context, impersonation *service.ImpersonationService) {
+	adminSession, _ := c.Get("session")
+	admin, _ := c.Get("user")
+	session, ok1 := adminSession.(*auth.Session)
+	user, ok2 := admin.(*auth.UserData)
+	if !ok1 || !ok2 {
+		renderErrorPage(c, http.StatusForbidden)
+		return
+	}
+
+	newSession, _, err := impersonation.Start(session, user, c.Param("id"), sessionMetadata(c))
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		renderErrorPage(c, http.StatusNotFound)
+		return
+	case errors.Is(err, service.ErrCannotImpersonateAdmin), errors.Is(err, service.ErrImpersonatorNotAdmin):
+		renderErrorPage(c, http.StatusForbidden)
+		return
+	case err != nil:
+		renderErrorPage(c, http.StatusInternalServerError)
+		return
+	}
+
+	middleware.SetSessionCookie(c, newSession.ID)
+	c.Redirect(http.StatusSeeOther, basepath.URL("/"))
+}
+
+// adminRecoveryCodePost creates a recovery code for a user who lost access to their email. The code
+// can't be shown again (only its hash is kept): HTMX gets it in the admin area's alert slot, other
+// clients as JSON with 201.
+func adminRecoveryCodePost(c *gin.Context, recovery *service.RecoveryCodeService) {
+	issued, err := recovery.Issue(c.Param("id"), c.GetString("userID"), c.ClientIP())
+	if err != nil {
+		abortUserError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	if c.GetHeader("HX-Request") == "" {
+		c.JSON(http.StatusCreated, issued)
+		return
+	}
+	renderFragment(c, admin.RecoveryCodeAlert(issued.Username, issued.Code, issued.ExpiresAt.Local().Format("02/01/2006 15:04"),
+		absoluteURL(c, handlers.RecoverPath), icons.KeyRound()))
+}
+
+// impersonateStopPost ends an impersonation and restores a session for the admin who started it.
+func impersonateStopPost(c *gin.Context, authManager *auth.AuthManager, impersonation *service.ImpersonationService) {
+	sessionID := middleware.ExtractSessionID(c)
+	session, _, err := authManager.ValidateSession(sessionID)
+	if sessionID == "" || err != nil {
+		c.Redirect(http.StatusSeeOther, basepath.URL("/login"))
+		return
+	}
+
+	adminSession, _, err := impersonation.Stop(session, sessionMetadata(c))
+	switch {
+	case errors.Is(err, service.ErrNotImpersonating):
+		c.Redirect(http.StatusSeeOther, basepath.URL("/"))
+		return
+	case err != nil:
+		// The impersonation session is already gone; the admin must sign in again
+		middleware.ClearSessionCookie(c)
+		c.Redirect(http.StatusSeeOther, basepath.URL("/login"))
+		return
+	}
+
+	middleware.SetSessionCookie(c, adminSession.ID)
+	c.Redirect(http.StatusSeeOther, basepath.URL("/admin/users"))
+}
+
+// adminResendVerificationPost queues verification emails for unverified users and returns the batch
+// counts as JSON. Form fields: dry_run ("true"/"1" only counts) and limit (optional, capped by the service).
+func adminResendVerificationPost(c *gin.Context, verification *service.VerificationService) {
+	limit := 0
+	if raw := c.PostForm("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit deve ser um número positivo"})
+			return
+		}
+		limit = n
+	}
+
+	batch, err := verification.ResendToUnverified(c.GetString("userID"), limit, parseBoolFormValue(c.PostForm("dry_run")), c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "falha ao reenviar emails de verificação"})
+		return
+	}
+	c.JSON(http.StatusOK, batch)
+}
+
+// sessionMetadata captures the client details stored with a new session.
+func sessionMetadata(c *gin.Context) auth.SessionMetadata {
+	return auth.SessionMetadata{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
 }
 
 // adminUserDeletePost permanently deletes a user (hard delete), clears their sessions, then redirects to /admin/users.
-func adminUserDeletePost(c *gin.Context, db *gorm.DB, authManager *auth.AuthManager) {
-	idStr := c.Param("id")
-	var u models.User
-	if err := db.First(&u, idStr).Error; err != nil {
-		c.AbortWithStatus(http.StatusNotFound)
+// The form must confirm with the user's username (see typedConfirmation).
+func adminUserDeletePost(c *gin.Context, users service.UserAdminServiceInterface) {
+	u, err := users.Get(c.Param("id"))
+	if err != nil {
+		abortUserError(c, err)
 		return
 	}
-	userID := strconv.FormatUint(uint64(u.ID), 10)
-	_ = authManager.LogoutAll(userID)
-	if err := db.Unscoped().Delete(&u).Error; err != nil {
-		c.AbortWithStatus(http.StatusInternalServerError)
+	if !typedConfirmation(c, u.Username) {
+		return
+	}
+	if err := users.Delete(c.Param("id")); err != nil {
+		abortUserError(c, err)
 		return
 	}
 	if c.GetHeader("HX-Request") != "" {
-		c.Header("HX-Redirect", "/admin/users")
+		c.Header("HX-Redirect", basepath.URL("/admin/users"))
 		c.Status(http.StatusOK)
 		return
 	}
-	c.Redirect(http.StatusFound, "/admin/users")
+	c.Redirect(http.StatusFound, basepath.URL("/admin/users"))
 }
 
 // adminUsersNewView renders the new-user form inside the app Layout (navbar + AdminBody + footer).
@@ -459,64 +1191,385 @@ func adminUsersNewView(c *gin.Context, authManager *auth.AuthManager) {
 	if errorMsg == "" {
 		errorMsg = c.GetString("error")
 	}
-	displayName, loggedIn := getNavData(c, authManager)
 	metaTags := pages.MetaTags("admin, novo usuário, criar conta", "Criar novo usuário")
-	pageContent := admin.UsersNewPage(errorMsg, icons.Error())
-	bodyContent := layouts.AdminBody("users", icons.LayoutDashboard(), icons.Users(), icons.LogOut(), icons.Home(), pageContent)
+	pageContent := admin.UsersNewPage(errorMsg, icons.Error(), newUserAccountFields())
+	bodyContent := layouts.AdminBody("users", icons.LayoutDashboard(), icons.Users(), icons.KeyRound(), icons.LogOut(), icons.Home(), pageContent)
 	tmpl := layouts.Layout(
-		"Novo usuário - Admin - GoHTMX",
+		"Novo usuário - Admin",
 		metaTags,
 		bodyContent,
-		displayName,
-		loggedIn,
 		true, // isAdmin
 		icons.LogIn(),
 		icons.UserPlus(),
 		icons.LogOut(),
 		icons.Menu(),
-		AppVersion,
-		time.Now().Year(),
 	)
-	if err := htmx.NewResponse().RenderTempl(c.Request.Context(), c.Writer, tmpl); err != nil {
+	if err := htmx.NewResponse().RenderTempl(renderContext(c, authManager), c.Writer, tmpl); err != nil {
 		c.AbortWithStatus(http.StatusInternalServerError)
 	}
 }
 
 // adminUsersCreatePost creates a user from the form and redirects to /admin/users (or returns error fragment for HTMX).
-func adminUsersCreatePost(c *gin.Context, db *gorm.DB) {
-	username := c.PostForm("username")
-	email := c.PostForm("email")
-	displayName := c.PostForm("display_name")
-	password := c.PostForm("password")
-	role := normalizeUserRole(c.PostForm("role"))
-	active := parseBoolFormValue(c.PostForm("active"))
+func adminUsersCreatePost(c *gin.Context, users service.UserAdminServiceInterface) {
+	_, err := users.Create(service.NewUserInput{
+		Username:    c.PostForm("username"),
+		Email:       c.PostForm("email"),
+		DisplayName: c.PostForm("display_name"),
+		Password:    c.PostForm("password"),
+		Role:        c.PostForm("role"),
+		Active:      parseBoolFormValue(c.PostForm("active")),
+		Phone:       c.PostForm("phone"),
+		ActorID:     c.GetString("userID"),
+		IP:          c.ClientIP(),
+	})
+	var validationErr *service.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		respondNewUserError(c, err.Error(), validationErr.Fields)
+		return
+	case errors.Is(err, service.ErrUserExists):
+		respondNewUserError(c, err.Error(), nil)
+		return
+	case err != nil:
+		renderErrorPage(c, http.StatusInternalServerError)
+		return
+	}
+	if c.GetHeader("HX-Request") != "" {
+		c.Header("HX-Redirect", basepath.URL("/admin/users"))
+		c.Status(http.StatusOK)
+		return
+	}
+	c.Redirect(http.StatusFound, basepath.URL("/admin/users"))
+}
+
+// Page size of the admin login attempts log; ?per_page= (remembered per admin) changes it up to the max.
+const (
+	loginAttemptsPerPage    = 50
+	maxLoginAttemptsPerPage = 200
+)
+
+// A burst is at least attemptBurstThreshold failed logins from one IP or for one account within attemptBurstWindow.
+const (
+	attemptBurstWindow      = 15 * time.Minute
+	attemptBurstWindowLabel = "15 minutos"
+	attemptBurstThreshold   = 5
+)
 
-	if err := validation.ValidateRegistrationRequest(username, email, password, displayName); err != nil {
-		respondNewUserError(c, err.Error())
+// loginAttemptReasonLabels maps stored attempt reasons to labels shown to admins.
+var loginAttemptReasonLabels = map[string]string{
+	service.AttemptReasonSuccess:            "Sucesso",
+	service.AttemptReasonInvalidCredentials: "Credenciais inválidas",
+	service.AttemptReasonInactive:           "Usuário inativo",
+	service.AttemptReasonLocked:             "Conta bloqueada",
+	service.AttemptReasonUnverified:         "Email não verificado",
+	service.AttemptReasonError:              "Erro interno",
+	service.AttemptReasonChallenged:         "Código pedido (país novo)",
+	service.AttemptReasonPasskeyCloned:      "Passkey possivelmente copiada",
+}
+
+// adminLoginAttemptsView renders the login attempts log filtered by ?identifier=, ?ip=, ?outcome= (success|failure), paginated by ?page=
+// and ?per_page= (the default is the page size the admin last picked, see service.PageSizeService).
+// IPs and accounts with a recent burst of failures are listed on top (with a quick action to block the IP) and their rows highlighted.
+func adminLoginAttemptsView(c *gin.Context, attempts *service.LoginAttemptService, denylist *service.IPDenylistService, pageSizes *service.PageSizeService, authManager *auth.AuthManager) {
+	filter := admin.LoginAttemptsFilter{
+		Identifier: strings.TrimSpace(c.Query("identifier")),
+		IP:         strings.TrimSpace(c.Query("ip")),
+		Outcome:    c.Query("outcome"),
+	}
+	query := service.LoginAttemptFilter{
+		Identifier: filter.Identifier,
+		IP:         filter.IP,
+	}
+	query.Page, _ = strconv.Atoi(c.Query("page"))
+	requested, _ := strconv.Atoi(c.Query("per_page"))
+	query.PerPage = pageSizes.Resolve(c.GetString("userID"), requested, loginAttemptsPerPage, maxLoginAttemptsPerPage)
+	switch filter.Outcome {
+	case "success", "failure":
+		success := filter.Outcome == "success"
+		query.Success = &success
+	default:
+		filter.Outcome = ""
+	}
+
+	page, err := attempts.List(query)
+	if err != nil {
+		renderErrorPage(c, http.StatusInternalServerError)
+		return
+	}
+	since := time.Now().Add(-attemptBurstWindow)
+	bursts, err := attempts.Bursts(since, attemptBurstThreshold)
+	if err != nil {
+		renderErrorPage(c, http.StatusInternalServerError)
+		return
+	}
+	denied, err := deniedIPSet(denylist)
+	if err != nil {
+		renderErrorPage(c, http.StatusInternalServerError)
 		return
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	views := make([]admin.LoginAttemptView, 0, len(page.Attempts))
+	for _, a := range page.Attempts {
+		reason, ok := loginAttemptReasonLabels[a.Reason]
+		if !ok {
+			reason = a.Reason
+		}
+		_, burstIP := bursts.IPs[a.IP]
+		_, burstAccount := bursts.Identifiers[strings.ToLower(a.Identifier)]
+		views = append(views, admin.LoginAttemptView{
+			Identifier: a.Identifier,
+			IP:         a.IP,
+			UserAgent:  a.UserAgent,
+			Success:    a.Success,
+			Reason:     reason,
+			CreatedAt:  a.CreatedAt.Format("02/01/2006 15:04:05"),
+			Burst:      !a.Success && !a.CreatedAt.Before(since) && (burstIP || burstAccount),
+			IPDenied:   denied[a.IP],
+		})
+	}
+
+	metaTags := pages.MetaTags("admin, login, segurança", "Histórico de tentativas de login.")
+	pageContent := admin.LoginAttemptsPage(views, failureBurstViews(bursts, denied), attemptBurstWindowLabel, filter,
+		loginAttemptsPagination(filter, page), icons.KeyRound())
+	bodyContent := layouts.AdminBody("login-attempts", icons.LayoutDashboard(), icons.Users(), icons.KeyRound(), icons.LogOut(), icons.Home(), pageContent)
+	tmpl := layouts.Layout(
+		"Tentativas de login - Admin",
+		metaTags,
+		bodyContent,
+		true, // isAdmin
+		icons.LogIn(),
+		icons.UserPlus(),
+		icons.LogOut(),
+		icons.Menu(),
+	)
+	if err := htmx.NewResponse().RenderTempl(renderContext(c, authManager), c.Writer, tmpl); err != nil {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}
+}
+
+// deniedIPSet returns the denylist entries as a set, for marking IPs that are already blocked.
+func deniedIPSet(denylist *service.IPDenylistService) (map[string]bool, error) {
+	entries, err := denylist.List()
 	if err != nil {
+		return nil, err
+	}
+	denied := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		denied[e.Value] = true
+	}
+	return denied, nil
+}
+
+// failureBurstViews lists IP bursts then account bursts, each by failure count (highest first).
+func failureBurstViews(bursts *service.FailureBursts, denied map[string]bool) []admin.FailureBurstView {
+	attemptsURL := func(key, value string) string {
+		return basepath.URL("/admin/security/attempts?" + url.Values{key: {value}, "outcome": {"failure"}}.Encode())
+	}
+	byFailures := func(counts map[string]int64) []string {
+		keys := slices.Collect(maps.Keys(counts))
+		slices.SortFunc(keys, func(a, b string) int { return cmp.Or(cmp.Compare(counts[b], counts[a]), cmp.Compare(a, b)) })
+		return keys
+	}
+
+	views := make([]admin.FailureBurstView, 0, len(bursts.IPs)+len(bursts.Identifiers))
+	for _, ip := range byFailures(bursts.IPs) {
+		views = append(views, admin.FailureBurstView{IP: ip, Failures: bursts.IPs[ip], IPDenied: denied[ip], FilterURL: attemptsURL("ip", ip)})
+	}
+	for _, identifier := range byFailures(bursts.Identifiers) {
+		views = append(views, admin.FailureBurstView{Identifier: identifier, Failures: bursts.Identifiers[identifier],
+			FilterURL: attemptsURL("identifier", identifier)})
+	}
+	return views
+}
+
+// defaultDenyReason is stored for IPs blocked from the login attempts page.
+const defaultDenyReason = "bloqueado pelo histórico de tentativas de login"
+
+// adminDenyIPPost adds the form's ip (an IP or CIDR range) to the denylist, with an optional reason. HTMX requests
+// (the quick action on the attempts page) get the "blocked" badge that replaces the button; plain form posts are
+// redirected to the denylist page, with ?error= when the entry is invalid.
+func adminDenyIPPost(c *gin.Context, denylist *service.IPDenylistService) {
+	// The service refuses catch-all ranges and entries containing the admin's own IP
+	entry, err := denylist.Add(c.PostForm("ip"), cmp.Or(strings.TrimSpace(c.PostForm("reason")), defaultDenyReason), c.GetString("userID"), c.ClientIP())
+
+	isHTMX := c.GetHeader("HX-Request") != ""
+	var validationErr *service.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		if isHTMX {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		c.Redirect(http.StatusSeeOther, basepath.URL("/admin/security/denylist?"+url.Values{"error": {err.Error()}}.Encode()))
+		return
+	case err != nil:
 		renderErrorPage(c, http.StatusInternalServerError)
 		return
 	}
-	u := models.User{
-		Username:     username,
-		Email:        email,
-		DisplayName:  displayName,
-		PasswordHash: string(hashedPassword),
-		Role:         role,
-		Active:       active,
+	if isHTMX {
+		if err := admin.DenyIPButton(entry.Value, true).Render(c.Request.Context(), c.Writer); err != nil {
+			c.AbortWithStatus(http.StatusInternalServerError)
+		}
+		return
 	}
-	if err := db.Create(&u).Error; err != nil {
-		respondNewUserError(c, "usuário ou email já existe")
+	c.Redirect(http.StatusSeeOther, basepath.URL("/admin/security/denylist"))
+}
+
+// adminDenylistDeletePost removes a runtime denylist entry; the IP is unblocked immediately.
+func adminDenylistDeletePost(c *gin.Context, denylist *service.IPDenylistService) {
+	err := denylist.Remove(c.Param("id"), c.GetString("userID"), c.ClientIP())
+	switch {
+	case errors.Is(err, service.ErrDeniedIPNotFound):
+		renderErrorPage(c, http.StatusNotFound)
+		return
+	case err != nil:
+		renderErrorPage(c, http.StatusInternalServerError)
 		return
 	}
-	if c.GetHeader("HX-Request") != "" {
-		c.Header("HX-Redirect", "/admin/users")
-		c.Status(http.StatusOK)
+	c.Redirect(http.StatusSeeOther, basepath.URL("/admin/security/denylist"))
+}
+
+// adminDenylistView renders the denylist: the fixed entries from config (staticEntries) first, then the stored ones.
+func adminDenylistView(c *gin.Context, denylist *service.IPDenylistService, staticEntries []string, authManager *auth.AuthManager) {
+	entries, err := denylist.List()
+	if err != nil {
+		renderErrorPage(c, http.StatusInternalServerError)
+		return
+	}
+	views := make([]admin.DeniedIPView, 0, len(staticEntries)+len(entries))
+	for _, value := range staticEntries {
+		views = append(views, admin.DeniedIPView{Value: value, Static: true})
+	}
+	for _, e := range entries {
+		views = append(views, admin.DeniedIPView{
+			ID:        strconv.FormatUint(uint64(e.ID), 10),
+			Value:     e.Value,
+			Reason:    e.Reason,
+			CreatedAt: e.CreatedAt.Format("02/01/2006 15:04"),
+		})
+	}
+
+	metaTags := pages.MetaTags("admin, segurança, bloqueio de IP", "IPs bloqueados.")
+	pageContent := admin.DenylistPage(views, c.Query("error"), icons.Error(), icons.Shield())
+	bodyContent := layouts.AdminBody("login-attempts", icons.LayoutDashboard(), icons.Users(), icons.KeyRound(), icons.LogOut(), icons.Home(), pageContent)
+	tmpl := layouts.Layout(
+		"Lista de bloqueio - Admin",
+		metaTags,
+		bodyContent,
+		true, // isAdmin
+		icons.LogIn(),
+		icons.UserPlus(),
+		icons.LogOut(),
+		icons.Menu(),
+	)
+	if err := htmx.NewResponse().RenderTempl(renderContext(c, authManager), c.Writer, tmpl); err != nil {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}
+}
+
+// adminInvitesView renders the registration invites page.
+func adminInvitesView(c *gin.Context, invites *service.InviteService, authManager *auth.AuthManager) {
+	renderInvitesPage(c, invites, authManager, http.StatusOK, "", "")
+}
+
+// adminInvitesCreatePost creates an invite and shows its link on the invites page. The page is rendered
+// instead of redirecting because the link can't be rebuilt later (only the token hash is stored).
+func adminInvitesCreatePost(c *gin.Context, invites *service.InviteService, authManager *auth.AuthManager) {
+	_, token, err := invites.Create(c.PostForm("email"), c.PostForm("role"), c.GetString("userID"), c.ClientIP())
+	var validationErr *service.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		renderInvitesPage(c, invites, authManager, http.StatusBadRequest, "", err.Error())
+		return
+	case err != nil:
+		renderErrorPage(c, http.StatusInternalServerError)
 		return
 	}
-	c.Redirect(http.StatusFound, "/admin/users")
+	renderInvitesPage(c, invites, authManager, http.StatusCreated, inviteLink(c, token), "")
+}
+
+// renderInvitesPage renders the invites list; createdLink and errorMessage are shown above it when set.
+func renderInvitesPage(c *gin.Context, invites *service.InviteService, authManager *auth.AuthManager, status int, createdLink, errorMessage string) {
+	list, err := invites.List()
+	if err != nil {
+		renderErrorPage(c, http.StatusInternalServerError)
+		return
+	}
+	now := time.Now()
+	views := make([]admin.InviteView, 0, len(list))
+	for _, inv := range list {
+		view := admin.InviteView{
+			Email:     inv.Email,
+			Role:      inv.Role,
+			Status:    "pending",
+			ExpiresAt: inv.ExpiresAt.Format("02/01/2006 15:04"),
+			CreatedAt: inv.CreatedAt.Format("02/01/2006 15:04"),
+		}
+		switch {
+		case inv.UsedAt != nil:
+			view.Status = "used"
+		case now.After(inv.ExpiresAt):
+			view.Status = "expired"
+		}
+		views = append(views, view)
+	}
+
+	metaTags := pages.MetaTags("admin, convites, cadastro", "Convites de cadastro.")
+	pageContent := admin.InvitesPage(views, createdLink, errorMessage, icons.Error(), icons.UserPlus())
+	bodyContent := layouts.AdminBody("users", icons.LayoutDashboard(), icons.Users(), icons.KeyRound(), icons.LogOut(), icons.Home(), pageContent)
+	tmpl := layouts.Layout(
+		"Convites - Admin",
+		metaTags,
+		bodyContent,
+		true, // isAdmin
+		icons.LogIn(),
+		icons.UserPlus(),
+		icons.LogOut(),
+		icons.Menu(),
+	)
+	if err := htmx.NewResponse().StatusCode(status).RenderTempl(renderContext(c, authManager), c.Writer, tmpl); err != nil {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}
+}
+
+// inviteLink is the absolute registration link for an invite token, on the host the admin is using.
+func inviteLink(c *gin.Context, token string) string {
+	return absoluteURL(c, "/register?"+url.Values{"invite": {token}}.Encode())
+}
+
+// absoluteURL returns the full URL of the app path on the host the request came in on.
+func absoluteURL(c *gin.Context, path string) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host + basepath.URL(path)
+}
+
+// loginAttemptsPagination builds the prev/next links, keeping the current filters in the query string.
+func loginAttemptsPagination(filter admin.LoginAttemptsFilter, page *service.LoginAttemptPage) admin.Pagination {
+	totalPages := int((page.Total + int64(page.PerPage) - 1) / int64(page.PerPage))
+	pageURL := func(n int) string {
+		q := url.Values{}
+		if filter.Identifier != "" {
+			q.Set("identifier", filter.Identifier)
+		}
+		if filter.IP != "" {
+			q.Set("ip", filter.IP)
+		}
+		if filter.Outcome != "" {
+			q.Set("outcome", filter.Outcome)
+		}
+		q.Set("page", strconv.Itoa(n))
+		return basepath.URL("/admin/security/attempts?" + q.Encode())
+	}
+
+	p := admin.Pagination{Page: page.Page, TotalPages: totalPages, Total: page.Total}
+	if page.Page > 1 {
+		p.PrevURL = pageURL(page.Page - 1)
+	}
+	if page.Page < totalPages {
+		p.NextURL = pageURL(page.Page + 1)
+	}
+	return p
 }
@@ -0,0 +1,868 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+	gormadapter "github.com/lucas-varjao/gohtmx/internal/auth/adapter/gorm"
+	"github.com/lucas-varjao/gohtmx/internal/config"
+	"github.com/lucas-varjao/gohtmx/internal/email"
+	"github.com/lucas-varjao/gohtmx/internal/middleware"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+	"github.com/lucas-varjao/gohtmx/internal/service"
+	"github.com/lucas-varjao/gohtmx/internal/validation"
+
+	"github.com/a-h/templ"
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	if err := db.AutoMigrate(migratedModels...); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return db
+}
+
+func createUserAt(t *testing.T, db *gorm.DB, username string, createdAt time.Time) {
+	t.Helper()
+	user := &models.User{
+		Username:     username,
+		Email:        username + "@example.com",
+		DisplayName:  username,
+		PasswordHash: "hash",
+	}
+	user.CreatedAt = createdAt
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+}
+
+func TestSignupSeries(t *testing.T) {
+	db := setupTestDB(t)
+	now := time.Date(2025, 6, 10, 15, 0, 0, 0, time.UTC)
+
+	createUserAt(t, db, "today1", now.Add(-time.Hour))
+	createUserAt(t, db, "today2", now.Add(-2*time.Hour))
+	createUserAt(t, db, "threedays", now.AddDate(0, 0, -3))
+	// Evening of June 8 in São Paulo, already June 9 in UTC
+	createUserAt(t, db, "otherzone", time.Date(2025, 6, 8, 22, 30, 0, 0, time.FixedZone("BRT", -3*60*60)))
+	createUserAt(t, db, "outofrange", now.AddDate(0, 0, -30))
+
+	series, err := signupSeries(db, 7, now)
+	if err != nil {
+		t.Fatalf("signupSeries() error = %v", err)
+	}
+
+	if len(series) != 7 {
+		t.Fatalf("expected 7 points, got %d", len(series))
+	}
+	if series[0].Date != "2025-06-04" || series[6].Date != "2025-06-10" {
+		t.Errorf("unexpected range %s..%s", series[0].Date, series[6].Date)
+	}
+
+	want := map[string]int64{"2025-06-10": 2, "2025-06-09": 1, "2025-06-07": 1}
+	var total int64
+	for _, p := range series {
+		if p.Count != want[p.Date] {
+			t.Errorf("%s: expected %d, got %d", p.Date, want[p.Date], p.Count)
+		}
+		total += p.Count
+	}
+	if total != 4 {
+		t.Errorf("expected 4 signups in range, got %d", total)
+	}
+}
+
+func TestAdminSignupStatsJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantDays   int
+	}{
+		{"Default range", "", http.StatusOK, 30},
+		{"Custom range", "?days=7", http.StatusOK, 7},
+		{"Capped range", "?days=1000", http.StatusOK, maxSignupDays},
+		{"Invalid range", "?days=abc", http.StatusBadRequest, 0},
+		{"Zero range", "?days=0", http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/admin/stats/signups"+tt.query, nil)
+
+			adminSignupStatsJSON(c, db)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var body struct {
+				Days   int           `json:"days"`
+				Series []signupPoint `json:"series"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to unmarshal: %v", err)
+			}
+			if body.Days != tt.wantDays || len(body.Series) != tt.wantDays {
+				t.Errorf("expected %d days, got days=%d len=%d", tt.wantDays, body.Days, len(body.Series))
+			}
+		})
+	}
+}
+
+func TestAdminDisplayNamePost(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+	createUserAt(t, db, "alice", time.Now())
+
+	var user models.User
+	if err := db.Where("username = ?", "alice").First(&user).Error; err != nil {
+		t.Fatalf("failed to load user: %v", err)
+	}
+	id := strconv.FormatUint(uint64(user.ID), 10)
+
+	tests := []struct {
+		name        string
+		displayName string
+		wantSaved   string
+		wantInBody  string
+	}{
+		{"Valid name", "  Alice Souza  ", "Alice Souza", `hx-get="/admin/users/` + id + `/display-name/edit"`},
+		{"Empty name", "   ", "Alice Souza", validation.ErrDisplayNameInvalid.Error()},
+		{"Too long", strings.Repeat("a", 101), "Alice Souza", validation.ErrDisplayNameTooLong.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			form := url.Values{"display_name": {tt.displayName}}
+			c.Request = httptest.NewRequest(http.MethodPost, "/admin/users/"+id+"/display-name", strings.NewReader(form.Encode()))
+			c.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			c.Params = gin.Params{{Key: "id", Value: id}}
+
+			adminDisplayNamePost(c, service.NewUserAdminService(db, nil))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", w.Code)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantInBody) {
+				t.Errorf("expected body to contain %q, got %s", tt.wantInBody, w.Body.String())
+			}
+			var saved models.User
+			db.First(&saved, user.ID)
+			if saved.DisplayName != tt.wantSaved {
+				t.Errorf("expected saved display name %q, got %q", tt.wantSaved, saved.DisplayName)
+			}
+		})
+	}
+}
+
+func TestAdminLoginAttemptsView(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+	attempts := service.NewLoginAttemptService(db)
+	denylist := service.NewIPDenylistService(db, nil)
+
+	now := time.Now()
+	for i := range attemptBurstThreshold {
+		_ = attempts.Record(&models.LoginAttempt{Identifier: "victim", IP: "203.0.113.7", Reason: service.AttemptReasonInvalidCredentials,
+			CreatedAt: now.Add(-time.Duration(i) * time.Minute)})
+	}
+	_ = attempts.Record(&models.LoginAttempt{Identifier: "alice", IP: "10.0.0.1", Success: true, Reason: service.AttemptReasonSuccess,
+		CreatedAt: now})
+	_ = attempts.Record(&models.LoginAttempt{Identifier: "bob", IP: "10.0.0.2", Reason: service.AttemptReasonInvalidCredentials,
+		CreatedAt: now.Add(-time.Hour)})
+
+	render := func(query string) string {
+		t.Helper()
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/admin/security/attempts"+query, nil)
+		adminLoginAttemptsView(c, attempts, denylist, nil, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		return w.Body.String()
+	}
+
+	t.Run("Default view lists everything and highlights bursts", func(t *testing.T) {
+		body := render("")
+		for _, want := range []string{"victim", "alice", "bob", `id="login-attempt-bursts"`, "5 falhas", "Bloquear IP"} {
+			if !strings.Contains(body, want) {
+				t.Errorf("expected %q in the page", want)
+			}
+		}
+		if got := strings.Count(body, `class="bg-error/10"`); got != attemptBurstThreshold {
+			t.Errorf("expected the %d burst rows highlighted, got %d", attemptBurstThreshold, got)
+		}
+		if strings.Contains(body, "?ip=10.0.0.2") {
+			t.Errorf("a single old failure is not a burst")
+		}
+	})
+
+	t.Run("Filters", func(t *testing.T) {
+		tests := []struct {
+			query string
+			want  []string
+		}{
+			{"?ip=10.0.0.1", []string{"alice"}},
+			{"?outcome=success", []string{"alice"}},
+			{"?identifier=BOB&outcome=failure", []string{"bob"}},
+			{"?ip=203.0.113.7&page=2", nil},
+		}
+		for _, tt := range tests {
+			body := render(tt.query)
+			for _, identifier := range []string{"alice", "bob", "victim"} {
+				row := "<td>" + identifier + "</td>"
+				if got, want := strings.Contains(body, row), slices.Contains(tt.want, identifier); got != want {
+					t.Errorf("%s: row of %s shown = %v, want %v", tt.query, identifier, got, want)
+				}
+			}
+		}
+	})
+
+	t.Run("Block IP", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/admin/security/denylist", strings.NewReader(url.Values{"ip": {"203.0.113.7"}}.Encode()))
+		c.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		c.Request.Header.Set("HX-Request", "true")
+		adminDenyIPPost(c, denylist)
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Bloqueado") {
+			t.Fatalf("expected the blocked badge, got %d %q", w.Code, w.Body.String())
+		}
+		if body := render(""); strings.Contains(body, `value="203.0.113.7"`) {
+			t.Errorf("a blocked IP should not offer the block action again")
+		}
+
+		post := func(form url.Values, htmx bool) *gin.Context {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/admin/security/denylist", strings.NewReader(form.Encode()))
+			c.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			c.Request.RemoteAddr = "192.0.2.10:4000"
+			if htmx {
+				c.Request.Header.Set("HX-Request", "true")
+			}
+			adminDenyIPPost(c, denylist)
+			return c
+		}
+		if c := post(url.Values{"ip": {"nope"}}, true); c.Writer.Status() != http.StatusBadRequest {
+			t.Errorf("invalid IP: expected 400, got %d", c.Writer.Status())
+		}
+		if c := post(url.Values{"ip": {"192.0.2.0/24"}}, false); !strings.Contains(c.Writer.Header().Get("Location"), "error=") {
+			t.Errorf("blocking your own IP should be refused, got Location %q", c.Writer.Header().Get("Location"))
+		}
+		if c := post(url.Values{"ip": {"0.0.0.0/0"}}, true); c.Writer.Status() != http.StatusBadRequest {
+			t.Errorf("blocking every IP should be refused, got %d", c.Writer.Status())
+		}
+		c = post(url.Values{"ip": {"198.51.100.0/24"}, "reason": {"scanner"}}, false)
+		if c.Writer.Status() != http.StatusSeeOther || c.Writer.Header().Get("Location") != "/admin/security/denylist" {
+			t.Errorf("form post should redirect to the denylist, got %d %q", c.Writer.Status(), c.Writer.Header().Get("Location"))
+		}
+		entries, _ := denylist.List()
+		if len(entries) != 2 || entries[1].Reason != "scanner" {
+			t.Errorf("unexpected denylist %+v", entries)
+		}
+	})
+}
+
+func TestAdminUsersView_EmptyState(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+	createUserAt(t, db, "alice", time.Now())
+	users := service.NewUserAdminService(db, auth.NewAuthManager(gormadapter.NewUserAdapter(db), gormadapter.NewSessionAdapter(db), nil))
+
+	render := func(query string) string {
+		t.Helper()
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/admin/users"+query, nil)
+		adminUsersView(c, users, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		return w.Body.String()
+	}
+
+	body := render("?q=nobody")
+	if !strings.Contains(body, "data-empty-state") || !strings.Contains(body, "Nenhum usuário corresponde à busca.") {
+		t.Errorf("expected the empty state when the search matches no one")
+	}
+	if !strings.Contains(body, `href="/admin/users" class="btn btn-ghost btn-sm">Limpar filtros</a>`) {
+		t.Errorf("expected a clear-filters action")
+	}
+
+	body = render("?q=ali")
+	if strings.Contains(body, "data-empty-state") || !strings.Contains(body, "alice@example.com") {
+		t.Errorf("matching users should be listed without the empty state")
+	}
+
+	if err := db.Exec("DELETE FROM users").Error; err != nil {
+		t.Fatal(err)
+	}
+	body = render("")
+	if !strings.Contains(body, "Nenhum usuário cadastrado.") || strings.Contains(body, "Limpar filtros") {
+		t.Errorf("without a search the empty state has no clear-filters action")
+	}
+}
+
+func TestAdminUsersCreatePost_FieldErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+	createUserAt(t, db, "alice", time.Now())
+	users := service.NewUserAdminService(db, nil)
+
+	post := func(form url.Values, htmx bool) *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/admin/users", strings.NewReader(form.Encode()))
+		c.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if htmx {
+			c.Request.Header.Set("HX-Request", "true")
+		}
+		adminUsersCreatePost(c, users)
+		c.Writer.WriteHeaderNow() // redirects to POST carry no body, so nothing flushes the status
+		return w
+	}
+
+	t.Run("Invalid fields get their own slots", func(t *testing.T) {
+		form := url.Values{"username": {"ab"}, "email": {"bad"}, "display_name": {"Bob"}, "password": {"weakpass"}}
+		w := post(form, true)
+		if w.Code != http.StatusOK || w.Header().Get("HX-Retarget") != "#new-user-error" {
+			t.Fatalf("expected 200 retargeted to #new-user-error, got %d %q", w.Code, w.Header().Get("HX-Retarget"))
+		}
+		body := w.Body.String()
+		for field, msg := range map[string]string{
+			validation.FieldUsername: validation.ErrUsernameTooShort.Error(),
+			validation.FieldEmail:    validation.ErrEmailInvalid.Error(),
+			validation.FieldPassword: validation.ErrPasswordNoUppercase.Error(),
+		} {
+			slot := `<div id="` + field + `-error" class="field-error text-error text-xs mt-1" hx-swap-oob="true" aria-live="polite">` + msg + `</div>`
+			if !strings.Contains(body, slot) {
+				t.Errorf("expected %s slot with %q, got %s", field, msg, body)
+			}
+		}
+		if !strings.Contains(body, `<div id="display_name-error" class="field-error text-error text-xs mt-1" hx-swap-oob="true" aria-live="polite"></div>`) {
+			t.Errorf("expected the valid display_name slot to be cleared, got %s", body)
+		}
+	})
+
+	t.Run("Duplicate user clears field slots", func(t *testing.T) {
+		form := url.Values{"username": {"alice"}, "email": {"alice2@example.com"}, "display_name": {"Alice"}, "password": {"Test123!@#"}}
+		body := post(form, true).Body.String()
+		if !strings.Contains(body, service.ErrUserExists.Error()) {
+			t.Errorf("expected duplicate message, got %s", body)
+		}
+		if !strings.Contains(body, `<div id="username-error" class="field-error text-error text-xs mt-1" hx-swap-oob="true" aria-live="polite"></div>`) {
+			t.Errorf("expected stale field errors to be cleared, got %s", body)
+		}
+	})
+
+	t.Run("Plain form post redirects with the first error", func(t *testing.T) {
+		form := url.Values{"username": {"ab"}, "email": {"bad"}, "display_name": {"Bob"}, "password": {"weakpass"}}
+		w := post(form, false)
+		if w.Code != http.StatusSeeOther {
+			t.Fatalf("expected 303, got %d", w.Code)
+		}
+		want := "/admin/users/new?error=" + url.QueryEscape(validation.ErrUsernameTooShort.Error())
+		if got := w.Header().Get("Location"); got != want {
+			t.Errorf("expected redirect to %q, got %q", want, got)
+		}
+	})
+}
+
+func TestRegisterViewHandler_RegistrationDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	loadCheckConfig(t, "registration:\n  enabled: false\n")
+	t.Cleanup(func() { loadCheckConfig(t, "registration:\n  enabled: true\n") })
+
+	render := func(handler func(c *gin.Context), path string) *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, path, nil)
+		handler(c)
+		return w
+	}
+
+	w := render(func(c *gin.Context) { registerViewHandler(c, nil, nil, templ.NopComponent) }, "/register")
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected status 403, got %d", w.Code)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "data-registration-closed") || strings.Contains(body, `name="password"`) {
+		t.Errorf("expected the disabled page instead of the form, got %s", body)
+	}
+
+	// Neither the navbar nor the login page links to /register
+	for _, body := range []string{body, render(func(c *gin.Context) { loginViewHandler(c, nil, templ.NopComponent) }, "/login").Body.String()} {
+		if strings.Contains(body, `href="/register"`) {
+			t.Errorf("expected no register link while registration is disabled")
+		}
+	}
+}
+
+func TestRegisterViewHandler_Invite(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	loadCheckConfig(t, "registration:\n  enabled: false\n")
+	t.Cleanup(func() { loadCheckConfig(t, "registration:\n  enabled: true\n") })
+	db := setupTestDB(t)
+	invites := service.NewInviteService(db, nil, time.Hour)
+
+	_, valid, err := invites.Create("invited@example.com", service.RoleAdmin, "1", "")
+	if err != nil {
+		t.Fatalf("failed to create invite: %v", err)
+	}
+	expired, expiredToken, _ := invites.Create("", service.RoleUser, "1", "")
+	db.Model(expired).Update("expires_at", time.Now().Add(-time.Minute))
+	used, usedToken, _ := invites.Create("", service.RoleUser, "1", "")
+	db.Model(used).Update("used_at", time.Now())
+
+	tests := []struct {
+		name       string
+		token      string
+		wantStatus int
+		wantInBody string
+	}{
+		{"Valid", valid, http.StatusOK, `value="invited@example.com" readonly`},
+		{"Expired", expiredToken, http.StatusForbidden, "Este convite expirou"},
+		{"Used", usedToken, http.StatusForbidden, "Este convite já foi usado"},
+		{"Unknown", "deadbeef", http.StatusForbidden, "Este link de convite não é válido"},
+		{"Missing", "", http.StatusForbidden, "Cadastro desativado"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/register?"+url.Values{"invite": {tt.token}}.Encode(), nil)
+
+			registerViewHandler(c, nil, invites, templ.NopComponent)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			body := w.Body.String()
+			if !strings.Contains(body, tt.wantInBody) {
+				t.Errorf("expected body to contain %q, got %s", tt.wantInBody, body)
+			}
+			hasForm := strings.Contains(body, `name="invite" value="`+tt.token+`"`)
+			if hasForm != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("expected the form with the invite token only for a usable invite")
+			}
+		})
+	}
+}
+
+func TestAdminInvitesCreatePost(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+	invites := service.NewInviteService(db, nil, 0)
+
+	post := func(form url.Values) *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "http://app.example.com/admin/invites", strings.NewReader(form.Encode()))
+		c.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		adminInvitesCreatePost(c, invites, nil)
+		return w
+	}
+
+	w := post(url.Values{"email": {"new@example.com"}, "role": {"admin"}})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `value="http://app.example.com/register?invite=`) {
+		t.Errorf("expected the invite link on the page, got %s", w.Body.String())
+	}
+	list, _ := invites.List()
+	if len(list) != 1 || list[0].Role != service.RoleAdmin || list[0].Email != "new@example.com" {
+		t.Errorf("unexpected invites: %+v", list)
+	}
+
+	if w := post(url.Values{"email": {"not-an-email"}}); w.Code != http.StatusBadRequest || strings.Contains(w.Body.String(), "data-invite-link") {
+		t.Errorf("expected 400 without a link for an invalid email, got %d", w.Code)
+	}
+}
+
+func TestReadinessHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+
+	ready := func(emailDegraded error) (int, map[string]any) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/health/ready", nil)
+		readinessHandler(c, db, emailDegraded)
+		var body map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		return w.Code, body
+	}
+
+	if code, body := ready(nil); code != http.StatusOK || body["status"] != "ok" {
+		t.Errorf("healthy: got %d %v", code, body)
+	}
+
+	code, body := ready(errors.New("email.smtp_host não definido"))
+	if code != http.StatusOK || body["status"] != "degraded" {
+		t.Errorf("bad SMTP config should degrade without failing readiness: got %d %v", code, body)
+	}
+	if checks, _ := body["checks"].(map[string]any); checks["email"] != "degraded" {
+		t.Errorf("email check = %v, want degraded without the config error", checks["email"])
+	}
+}
+
+func TestProfileNotificationsPost(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+	createUserAt(t, db, "alice", time.Now())
+	notifications := service.NewNotificationService(db)
+
+	post := func(form url.Values, htmxRequest bool) *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/profile/notifications", strings.NewReader(form.Encode()))
+		c.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if htmxRequest {
+			c.Request.Header.Set("HX-Request", "true")
+		}
+		c.Set("userID", "1")
+		profileNotificationsPost(c, notifications)
+		c.Writer.WriteHeaderNow()
+		return w
+	}
+
+	w := post(url.Values{"notifications": {"account_deactivated"}}, true)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Preferências salvas") {
+		t.Fatalf("expected a success alert, got %d: %s", w.Code, w.Body.String())
+	}
+	enabled, err := notifications.Preferences(1)
+	if err != nil {
+		t.Fatalf("Preferences: %v", err)
+	}
+	if enabled["account_locked"] || !enabled["account_deactivated"] {
+		t.Errorf("expected only account_deactivated on, got %v", enabled)
+	}
+
+	if w := post(url.Values{}, false); w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/profile" {
+		t.Errorf("expected a redirect to /profile without HTMX, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+	if enabled, _ := notifications.Preferences(1); enabled["account_deactivated"] {
+		t.Errorf("expected every optional email off, got %v", enabled)
+	}
+}
+
+func TestProfilePhonePost(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+	createUserAt(t, db, "alice", time.Now())
+	accounts := service.NewAccountService(db)
+
+	post := func(phone string, htmxRequest bool) *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		form := url.Values{"phone": {phone}}
+		c.Request = httptest.NewRequest(http.MethodPost, "/profile/phone", strings.NewReader(form.Encode()))
+		c.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if htmxRequest {
+			c.Request.Header.Set("HX-Request", "true")
+		}
+		c.Set("userID", "1")
+		profilePhonePost(c, accounts)
+		c.Writer.WriteHeaderNow()
+		return w
+	}
+
+	w := post("+1 415 555 2671", true)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Telefone salvo: +14155552671") {
+		t.Fatalf("expected a success alert with the normalized number, got %d: %s", w.Code, w.Body.String())
+	}
+	w = post("555-2671", true)
+	if !strings.Contains(w.Body.String(), "telefone inválido") {
+		t.Errorf("expected the validation message, got %s", w.Body.String())
+	}
+	if phone, _ := accounts.Phone("1"); phone != "+14155552671" {
+		t.Errorf("expected the previous phone to stay, got %q", phone)
+	}
+
+	if w := post("555-2671", false); w.Code != http.StatusSeeOther || !strings.HasPrefix(w.Header().Get("Location"), "/profile?error=") {
+		t.Errorf("expected a redirect back with the error, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+	if w := post("", false); w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/profile" {
+		t.Errorf("expected a redirect to /profile, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+	if phone, _ := accounts.Phone("1"); phone != "" {
+		t.Errorf("expected the phone removed, got %q", phone)
+	}
+}
+
+func TestLayout_Branding(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	render := func(handler func(c *gin.Context)) string {
+		t.Helper()
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		handler(c)
+		return w.Body.String()
+	}
+
+	loadCheckConfig(t, "app:\n  name: Acme\n  tagline: Ferramentas\n  primary_color: '#123456'\n")
+	t.Cleanup(func() { loadCheckConfig(t, "") })
+
+	login := render(func(c *gin.Context) { loginViewHandler(c, nil, templ.NopComponent) })
+	if !strings.Contains(login, "<title>Entrar - Acme</title>") {
+		t.Errorf("expected the configured app name in the title, got %s", login)
+	}
+	if !strings.Contains(login, `content="#123456"`) || strings.Contains(login, "GoHTMX") {
+		t.Errorf("expected only the configured branding, got %s", login)
+	}
+	if index := render(func(c *gin.Context) { indexViewHandler(c, nil) }); !strings.Contains(index, "<title>Acme — Ferramentas</title>") {
+		t.Errorf("expected the home page title from name and tagline, got %s", index)
+	}
+
+	loadCheckConfig(t, "")
+	if login := render(func(c *gin.Context) { loginViewHandler(c, nil, templ.NopComponent) }); !strings.Contains(login, "<title>Entrar - GoHTMX</title>") {
+		t.Errorf("expected the default app name without config app, got %s", login)
+	}
+}
+
+func TestLoginView_RememberedUsername(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	render := func() string {
+		t.Helper()
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/login", nil)
+		c.Request.AddCookie(&http.Cookie{Name: middleware.RememberedUsernameCookie, Value: "alice"})
+		loginViewHandler(c, nil, templ.NopComponent)
+		return w.Body.String()
+	}
+
+	loadCheckConfig(t, "login:\n  remember_username:\n    enabled: true\n")
+	t.Cleanup(func() { loadCheckConfig(t, "") })
+	if login := render(); !strings.Contains(login, `name="username" value="alice"`) || !strings.Contains(login, "autofocus") {
+		t.Errorf("expected the username pre-filled and the password focused, got %s", login)
+	}
+
+	loadCheckConfig(t, "")
+	if login := render(); strings.Contains(login, "alice") {
+		t.Errorf("expected no pre-filled username while disabled, got %s", login)
+	}
+}
+
+func TestAdminReadOnlyMode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+	createUserAt(t, db, "alice", time.Now())
+	var alice models.User
+	if err := db.Where("username = ?", "alice").First(&alice).Error; err != nil {
+		t.Fatalf("failed to load user: %v", err)
+	}
+	id := strconv.FormatUint(uint64(alice.ID), 10)
+	users := service.NewUserAdminService(db, auth.NewAuthManager(gormadapter.NewUserAdapter(db), gormadapter.NewSessionAdapter(db), nil))
+
+	r := gin.New()
+	adminGroup := r.Group("/admin", middleware.AdminReadOnlyMiddleware(adminReadOnlyBlocked))
+	adminGroup.GET("/users", func(c *gin.Context) { adminUsersView(c, users, nil) })
+	adminGroup.POST("/users", func(c *gin.Context) { adminUsersCreatePost(c, users) })
+	adminGroup.POST("/users/:id/role", func(c *gin.Context) { adminUserRolePost(c, users) })
+	adminGroup.POST("/users/:id/active", func(c *gin.Context) { adminUserActivePost(c, users) })
+	adminGroup.POST("/users/:id/delete", func(c *gin.Context) { adminUserDeletePost(c, users) })
+
+	middleware.SetAdminReadOnly(true)
+	t.Cleanup(func() { middleware.SetAdminReadOnly(false) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/users", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the users page to load, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "Modo somente leitura.") || !strings.Contains(w.Body.String(), "alice@example.com") {
+		t.Errorf("expected the users list with the read-only notice")
+	}
+
+	mutations := []struct {
+		path string
+		form url.Values
+	}{
+		{"/admin/users", url.Values{"username": {"bob"}, "email": {"bob@example.com"}, "display_name": {"Bob"}, "password": {"Password123!"}, "password_confirm": {"Password123!"}}},
+		{"/admin/users/" + id + "/role", url.Values{"role": {"admin"}}},
+		{"/admin/users/" + id + "/active", url.Values{"active": {"false"}}},
+		{"/admin/users/" + id + "/delete", nil},
+	}
+	for _, m := range mutations {
+		t.Run(m.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, m.path, strings.NewReader(m.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("HX-Request", "true")
+			r.ServeHTTP(w, req)
+
+			if w.Header().Get("HX-Retarget") != "#admin-alert" || !strings.Contains(w.Body.String(), middleware.MsgAdminReadOnly) {
+				t.Errorf("expected the read-only alert, got %d %s", w.Code, w.Body.String())
+			}
+		})
+	}
+
+	var saved []models.User
+	db.Find(&saved)
+	if len(saved) != 1 || saved[0].Role != alice.Role || saved[0].Active != alice.Active {
+		t.Errorf("read-only mode must not change users, got %+v", saved)
+	}
+}
+
+func TestAdminUserDeletePost_TypedConfirmation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+	createUserAt(t, db, "alice", time.Now())
+	var alice models.User
+	if err := db.Where("username = ?", "alice").First(&alice).Error; err != nil {
+		t.Fatalf("failed to load user: %v", err)
+	}
+	id := strconv.FormatUint(uint64(alice.ID), 10)
+	users := service.NewUserAdminService(db, auth.NewAuthManager(gormadapter.NewUserAdapter(db), gormadapter.NewSessionAdapter(db), nil))
+
+	r := gin.New()
+	r.POST("/admin/users/:id/delete", func(c *gin.Context) { adminUserDeletePost(c, users) })
+	post := func(form url.Values, htmxRequest bool) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/admin/users/"+id+"/delete", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if htmxRequest {
+			req.Header.Set("HX-Request", "true")
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+	exists := func() bool {
+		var count int64
+		db.Unscoped().Model(&models.User{}).Where("id = ?", alice.ID).Count(&count)
+		return count == 1
+	}
+
+	if w := post(nil, false); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "confirmação incorreta") {
+		t.Errorf("expected 400 without the confirmation, got %d %s", w.Code, w.Body.String())
+	}
+	if w := post(url.Values{"confirm": {"Alice"}}, true); w.Header().Get("HX-Retarget") != "#admin-alert" || !strings.Contains(w.Body.String(), "confirmação incorreta") {
+		t.Errorf("expected the alert for a wrong confirmation, got %d %s", w.Code, w.Body.String())
+	}
+	if !exists() {
+		t.Fatal("the user must not be deleted without the right confirmation")
+	}
+
+	if w := post(url.Values{"confirm": {"alice"}}, true); w.Code != http.StatusOK || w.Header().Get("HX-Redirect") != "/admin/users" {
+		t.Errorf("expected a redirect to the users list, got %d %v", w.Code, w.Header())
+	}
+	if exists() {
+		t.Error("expected the user to be deleted once confirmed")
+	}
+}
+
+func TestAdminUserRolePost_MinAccountAge(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+	createUserAt(t, db, "fresh", time.Now().Add(-time.Hour))
+	createUserAt(t, db, "veteran", time.Now().Add(-4*24*time.Hour))
+	users := service.NewUserAdminService(db, auth.NewAuthManager(gormadapter.NewUserAdapter(db), gormadapter.NewSessionAdapter(db), nil))
+	users.RequireAccountAgeForAdmin(72 * time.Hour)
+
+	r := gin.New()
+	r.POST("/admin/users/:id/role", func(c *gin.Context) { adminUserRolePost(c, users) })
+	promote := func(username string) (*httptest.ResponseRecorder, models.User) {
+		var u models.User
+		if err := db.Where("username = ?", username).First(&u).Error; err != nil {
+			t.Fatalf("failed to load user: %v", err)
+		}
+		w := httptest.NewRecorder()
+		form := url.Values{"role": {service.RoleAdmin}}
+		req := httptest.NewRequest(http.MethodPost, "/admin/users/"+strconv.FormatUint(uint64(u.ID), 10)+"/role", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("HX-Request", "true")
+		r.ServeHTTP(w, req)
+		var saved models.User
+		db.First(&saved, u.ID)
+		return w, saved
+	}
+
+	w, fresh := promote("fresh")
+	if w.Header().Get("HX-Retarget") != "#admin-alert" || !strings.Contains(w.Body.String(), service.ErrAccountTooNewForAdmin.Error()) {
+		t.Errorf("expected the account age alert, got %d %s", w.Code, w.Body.String())
+	}
+	if fresh.Role == service.RoleAdmin {
+		t.Error("an account younger than the minimum must not become admin")
+	}
+
+	w, veteran := promote("veteran")
+	if w.Code != http.StatusOK || w.Header().Get("HX-Retarget") != "" {
+		t.Errorf("expected the updated row, got %d %s", w.Code, w.Body.String())
+	}
+	if veteran.Role != service.RoleAdmin {
+		t.Errorf("expected an old enough account to become admin, got role %q", veteran.Role)
+	}
+}
+
+func TestAdminEmailPreview(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := config.EmailConfig{ResetURL: "https://app.example.com/reset-password?token="}
+
+	preview := func(emailType string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/admin/emails/preview?type="+emailType, nil)
+		adminEmailPreview(c, cfg)
+		return w
+	}
+
+	for _, emailType := range email.PreviewTypes {
+		t.Run(emailType, func(t *testing.T) {
+			w := preview(emailType)
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", w.Code)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+				t.Errorf("expected HTML, got %q", ct)
+			}
+			if !strings.Contains(w.Body.String(), "Maria Silva") {
+				t.Errorf("expected the sample data in the email, got %s", w.Body.String())
+			}
+		})
+	}
+
+	for _, emailType := range []string{"", "welcome"} {
+		if w := preview(emailType); w.Code != http.StatusNotFound {
+			t.Errorf("type %q: expected status 404, got %d", emailType, w.Code)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package gorm
 
 import (
+	"context"
 	"errors"
 	"strconv"
 	"time"
@@ -22,6 +23,11 @@ func NewSessionAdapter(db *gorm.DB) *SessionAdapter {
 	return &SessionAdapter{db: db}
 }
 
+// WithContext returns a copy of the adapter whose queries run with ctx, so they join its trace
+func (a *SessionAdapter) WithContext(ctx context.Context) auth.SessionAdapter {
+	return &SessionAdapter{db: a.db.WithContext(ctx)}
+}
+
 // CreateSession creates a new session for a user
 func (a *SessionAdapter) CreateSession(userID string, expiresAt time.Time, metadata auth.SessionMetadata) (*auth.Session, error) {
 	// Parse userID as uint for GORM model
@@ -48,6 +54,13 @@ func (a *SessionAdapter) CreateSession(userID string, expiresAt time.Time, metad
 		UserAgent: metadata.UserAgent,
 		IP:        metadata.IP,
 	}
+	if metadata.ImpersonatedBy != "" {
+		adminID, err := strconv.ParseUint(metadata.ImpersonatedBy, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		session.ImpersonatedBy = uint(adminID)
+	}
 
 	if err := a.db.Create(session).Error; err != nil {
 		logger.Error("Erro ao criar sessão no banco de dados", "error", err, "user_id", userID, "session_id", sessionID)
@@ -104,18 +117,53 @@ func (a *SessionAdapter) DeleteUserSessions(userID string) error {
 	return nil
 }
 
+// DeleteUserSessionsExcept removes all sessions for a user but keepSessionID
+func (a *SessionAdapter) DeleteUserSessionsExcept(userID, keepSessionID string) error {
+	uid, err := strconv.ParseUint(userID, 10, 64)
+	if err != nil {
+		logger.Error("Erro ao parsear userID para deletar sessões", "error", err, "user_id", userID)
+		return err
+	}
+	if err := a.db.Where("user_id = ? AND id <> ?", uid, keepSessionID).Delete(&models.Session{}).Error; err != nil {
+		logger.Error("Erro ao deletar as outras sessões do usuário", "error", err, "user_id", userID)
+		return err
+	}
+	return nil
+}
+
+// CountUserSessions counts the user's sessions that have not expired yet
+func (a *SessionAdapter) CountUserSessions(userID string) (int64, error) {
+	uid, err := strconv.ParseUint(userID, 10, 64)
+	if err != nil {
+		logger.Error("Erro ao parsear userID para contar sessões", "error", err, "user_id", userID)
+		return 0, err
+	}
+	var count int64
+	if err := a.db.Model(&models.Session{}).Where("user_id = ? AND expires_at > ?", uid, time.Now()).Count(&count).Error; err != nil {
+		logger.Error("Erro ao contar sessões do usuário", "error", err, "user_id", userID)
+		return 0, err
+	}
+	return count, nil
+}
+
 // DeleteExpiredSessions cleans up expired sessions
 func (a *SessionAdapter) DeleteExpiredSessions() error {
 	return a.db.Where("expires_at < ?", time.Now()).Delete(&models.Session{}).Error
 }
 
 func (a *SessionAdapter) toAuthSession(session *models.Session) *auth.Session {
-	return &auth.Session{
-		ID:        session.ID,
-		UserID:    strconv.FormatUint(uint64(session.UserID), 10),
-		ExpiresAt: session.ExpiresAt,
-		CreatedAt: session.CreatedAt,
-		UserAgent: session.UserAgent,
-		IP:        session.IP,
+	s := &auth.Session{
+		ID:           session.ID,
+		UserID:       strconv.FormatUint(uint64(session.UserID), 10),
+		ExpiresAt:    session.ExpiresAt,
+		CreatedAt:    session.CreatedAt,
+		UserAgent:    session.UserAgent,
+		IP:           session.IP,
+		NewLoginAt:   session.NewLoginAt,
+		NewLoginFrom: session.NewLoginFrom,
+	}
+	if session.ImpersonatedBy != 0 {
+		s.ImpersonatedBy = strconv.FormatUint(uint64(session.ImpersonatedBy), 10)
 	}
+	return s
 }
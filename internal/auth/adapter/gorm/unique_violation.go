@@ -0,0 +1,57 @@
+package gorm
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// pgUniqueViolation is PostgreSQL's SQLSTATE for a unique constraint violation.
+const pgUniqueViolation = "23505"
+
+// sqliteUniqueViolation starts the message of a SQLite unique constraint violation. It is matched as text
+// so this package doesn't import the cgo SQLite driver, which production builds (CGO_ENABLED=0) leave out.
+const sqliteUniqueViolation = "UNIQUE constraint failed: "
+
+// uniqueColumns are the users columns with a unique constraint, as named in the driver errors.
+var uniqueColumns = []string{"username", "email"}
+
+// uniqueViolation reports whether err is a unique constraint violation from one of the supported drivers,
+// and on which users column ("username", "email", or "" when the driver doesn't say). Only the constraint
+// and column names are looked at, never the duplicated value, which may itself contain "email".
+func uniqueViolation(err error) (field string, ok bool) {
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation:
+		return pgUniqueColumn(pgErr), true
+	case strings.Contains(err.Error(), sqliteUniqueViolation):
+		// "UNIQUE constraint failed: users.username"
+		_, target, _ := strings.Cut(err.Error(), sqliteUniqueViolation)
+		_, column, _ := strings.Cut(target, ".")
+		column, _, _ = strings.Cut(column, ",")
+		if slices.Contains(uniqueColumns, column) {
+			return column, true
+		}
+		return "", true
+	default:
+		return "", false
+	}
+}
+
+// pgUniqueColumn returns the column behind a PostgreSQL unique violation: from the constraint name (e.g.
+// "uni_users_username"), else from the key list of Detail ("Key (username)=(...) already exists.").
+func pgUniqueColumn(pgErr *pgconn.PgError) string {
+	for _, column := range uniqueColumns {
+		if strings.HasSuffix(pgErr.ConstraintName, "_"+column) {
+			return column
+		}
+	}
+	if rest, found := strings.CutPrefix(pgErr.Detail, "Key ("); found {
+		if column, _, found := strings.Cut(rest, ")="); found && slices.Contains(uniqueColumns, column) {
+			return column
+		}
+	}
+	return ""
+}
@@ -0,0 +1,53 @@
+package gorm
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestUniqueViolation_Postgres(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantField string
+		wantOK    bool
+	}{
+		{"By constraint", &pgconn.PgError{Code: pgUniqueViolation, ConstraintName: "uni_users_email"}, "email", true},
+		{"By key", &pgconn.PgError{Code: pgUniqueViolation, Detail: "Key (username)=(bob) already exists."}, "username", true},
+		{"Value is not read", &pgconn.PgError{Code: pgUniqueViolation, ConstraintName: "users_pkey", Detail: "Key (id)=(email) already exists."}, "", true},
+		{"Username that looks like an email column", &pgconn.PgError{Code: pgUniqueViolation, ConstraintName: "uni_users_username", Detail: "Key (username)=(email) already exists."}, "username", true},
+		{"Wrapped", fmt.Errorf("create: %w", &pgconn.PgError{Code: pgUniqueViolation, ConstraintName: "uni_users_username"}), "username", true},
+		{"Other error", &pgconn.PgError{Code: "23502"}, "", false},
+		{"Not a driver error", errors.New("boom"), "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			field, ok := uniqueViolation(tt.err)
+			if field != tt.wantField || ok != tt.wantOK {
+				t.Errorf("got (%q, %v), want (%q, %v)", field, ok, tt.wantField, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestUniqueViolation_SQLite(t *testing.T) {
+	tests := []struct {
+		message   string
+		wantField string
+		wantOK    bool
+	}{
+		{"UNIQUE constraint failed: users.username", "username", true},
+		{"UNIQUE constraint failed: users.email", "email", true},
+		{"UNIQUE constraint failed: sessions.id", "", true},
+		{"NOT NULL constraint failed: users.email", "", false},
+	}
+	for _, tt := range tests {
+		field, ok := uniqueViolation(fmt.Errorf("insert: %w", errors.New(tt.message)))
+		if field != tt.wantField || ok != tt.wantOK {
+			t.Errorf("%s: got (%q, %v), want (%q, %v)", tt.message, field, ok, tt.wantField, tt.wantOK)
+		}
+	}
+}
@@ -2,6 +2,7 @@
 package gorm
 
 import (
+	"context"
 	"errors"
 	"strconv"
 	"time"
@@ -24,6 +25,11 @@ func NewUserAdapter(db *gorm.DB) *UserAdapter {
 	return &UserAdapter{db: db}
 }
 
+// WithContext returns a copy of the adapter whose queries run with ctx, so they join its trace
+func (a *UserAdapter) WithContext(ctx context.Context) auth.UserAdapter {
+	return &UserAdapter{db: a.db.WithContext(ctx)}
+}
+
 // FindUserByIdentifier looks up user by username or email
 func (a *UserAdapter) FindUserByIdentifier(identifier string) (*auth.UserData, error) {
 	var user models.User
@@ -70,14 +76,19 @@ func (a *UserAdapter) ValidateCredentials(identifier, password string) (*auth.Us
 		return nil, auth.ErrInvalidCredentials
 	}
 
-	// Update last login time
-	user.LastLogin = time.Now()
-	if err := a.db.Save(&user).Error; err != nil {
-		logger.Error("Erro ao atualizar último login", "error", err, "user_id", user.ID)
-		// Não retornar erro, apenas logar
-	}
+	// A zero LastLogin means this is the user's first login; it is stamped by UpdateLastLogin
+	data := a.toUserData(&user)
+	data.FirstLogin = user.LastLogin.IsZero()
+	return data, nil
+}
 
-	return a.toUserData(&user), nil
+// UpdateLastLogin stamps the user's last login time
+func (a *UserAdapter) UpdateLastLogin(userID string) error {
+	id, err := strconv.ParseUint(userID, 10, 64)
+	if err != nil {
+		return err
+	}
+	return a.db.Model(&models.User{}).Where("id = ?", id).Update("last_login", time.Now()).Error
 }
 
 // CreateUser creates a new user
@@ -89,16 +100,28 @@ func (a *UserAdapter) CreateUser(data auth.CreateUserInput) (*auth.UserData, err
 		return nil, err
 	}
 
+	now := time.Now()
 	user := &models.User{
-		Username:     data.Identifier,
-		Email:        data.Email,
-		DisplayName:  data.DisplayName,
-		PasswordHash: string(hashedPassword),
-		Active:       true,
-		Role:         "user",
+		Username:          data.Identifier,
+		Email:             data.Email,
+		DisplayName:       data.DisplayName,
+		PasswordHash:      string(hashedPassword),
+		PasswordChangedAt: &now,
+		Active:            true,
+		Role:              "user",
 	}
 
 	if err := a.db.Create(user).Error; err != nil {
+		// The caller's availability checks can't see a concurrent sign-up; the unique constraints can
+		if field, ok := uniqueViolation(err); ok {
+			logger.Warn("Registro em conflito com conta existente", "field", field, "identifier", data.Identifier, "email", data.Email)
+			switch field {
+			case "username":
+				return nil, auth.ErrUsernameExists
+			case "email":
+				return nil, auth.ErrEmailExists
+			}
+		}
 		logger.Error("Erro ao criar usuário no banco de dados", "error", err, "identifier", data.Identifier, "email", data.Email)
 		return nil, err
 	}
@@ -118,7 +141,10 @@ func (a *UserAdapter) UpdatePassword(userID, newPassword string) error {
 		return err
 	}
 
-	return a.db.Model(&models.User{}).Where("id = ?", id).Update("password_hash", string(hashedPassword)).Error
+	return a.db.Model(&models.User{}).Where("id = ?", id).Updates(map[string]any{
+		"password_hash":       string(hashedPassword),
+		"password_changed_at": time.Now(),
+	}).Error
 }
 
 // GetUserModel returns the underlying GORM user model (for advanced queries)
@@ -156,6 +182,18 @@ func (a *UserAdapter) FindByResetToken(hashedToken string) (*models.User, error)
 	return &user, nil
 }
 
+// FindByEmailChangeToken finds a user by hashed email change token. Caller must check EmailChangeExpiry for expiry.
+func (a *UserAdapter) FindByEmailChangeToken(hashedToken string) (*models.User, error) {
+	if hashedToken == "" {
+		return nil, gorm.ErrRecordNotFound
+	}
+	var user models.User
+	if err := a.db.Where("email_change_token = ?", hashedToken).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
 // UpdateUser saves changes to user model
 func (a *UserAdapter) UpdateUser(user *models.User) error {
 	if err := a.db.Save(user).Error; err != nil {
@@ -165,19 +203,59 @@ func (a *UserAdapter) UpdateUser(user *models.User) error {
 	return nil
 }
 
+// RecentPasswordHashes returns up to limit previous password hashes of the user, newest first.
+func (a *UserAdapter) RecentPasswordHashes(userID uint, limit int) ([]string, error) {
+	var hashes []string
+	err := a.db.Model(&models.PasswordHistory{}).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").Order("id DESC").
+		Limit(limit).
+		Pluck("password_hash", &hashes).Error
+	if err != nil {
+		logger.Error("Erro ao buscar histórico de senhas", "error", err, "user_id", userID)
+		return nil, err
+	}
+	return hashes, nil
+}
+
+// AddPasswordHistory stores a replaced password hash and keeps only the newest keep entries of the user.
+func (a *UserAdapter) AddPasswordHistory(userID uint, passwordHash string, keep int) error {
+	if keep < 1 {
+		return nil
+	}
+	return a.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&models.PasswordHistory{UserID: userID, PasswordHash: passwordHash}).Error; err != nil {
+			return err
+		}
+		var keepIDs []uint
+		if err := tx.Model(&models.PasswordHistory{}).
+			Where("user_id = ?", userID).
+			Order("created_at DESC").Order("id DESC").
+			Limit(keep).
+			Pluck("id", &keepIDs).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ? AND id NOT IN ?", userID, keepIDs).Delete(&models.PasswordHistory{}).Error
+	})
+}
+
 func (a *UserAdapter) toUserData(user *models.User) *auth.UserData {
 	return &auth.UserData{
-		ID:          strconv.FormatUint(uint64(user.ID), 10),
-		Identifier:  user.Username,
-		Email:       user.Email,
-		DisplayName: user.DisplayName,
-		Role:        user.Role,
-		Active:      user.Active,
+		ID:                 strconv.FormatUint(uint64(user.ID), 10),
+		Identifier:         user.Username,
+		Email:              user.Email,
+		DisplayName:        user.DisplayName,
+		Role:               user.Role,
+		Active:             user.Active,
+		EmailVerified:      user.EmailVerified,
+		MustChangePassword: user.MustChangePassword,
+		TermsVersion:       user.TermsVersion,
 		Attributes: map[string]any{
 			"first_name":     user.FirstName,
 			"last_name":      user.LastName,
 			"email_verified": user.EmailVerified,
 			"last_login":     user.LastLogin,
+			"avatar_url":     user.AvatarURL,
 		},
 	}
 }
@@ -1,10 +1,10 @@
 package auth
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/base64"
 	"errors"
-	"sync"
 	"time"
 
 	"github.com/lucas-varjao/gohtmx/internal/logger"
@@ -14,10 +14,21 @@ import (
 type AuthConfig struct {
 	SessionDuration   time.Duration // Default: 30 days
 	RefreshThreshold  time.Duration // Refresh if less than this remaining (default: 15 days)
-	MaxFailedAttempts int           // Max failed login attempts before lockout
-	LockoutDuration   time.Duration // How long to lock account after max attempts
+	MaxFailedAttempts int           // Max failed login attempts within LockoutDuration before lockout
+	LockoutDuration   time.Duration // Window in which failures are counted, and so how long a lock lasts
+	// ImpersonationDuration is the fixed lifetime of an impersonation session (never refreshed)
+	ImpersonationDuration time.Duration
+	// RequireVerifiedEmail refuses logins until the user's email is verified (default: false)
+	RequireVerifiedEmail bool
+	// MaxSessionLifetime caps how long a session can live since login, however often it is
+	// refreshed or extended (0 = no cap). Sessions older than this are refused even if their
+	// stored expiry is later, e.g. after the cap was lowered.
+	MaxSessionLifetime time.Duration
 }
 
+// DefaultMaxSessionLifetime is the absolute session cap of DefaultAuthConfig, so no session lives forever.
+const DefaultMaxSessionLifetime = 90 * 24 * time.Hour
+
 // DefaultAuthConfig returns sensible defaults
 func DefaultAuthConfig() *AuthConfig {
 	return &AuthConfig{
@@ -25,6 +36,9 @@ func DefaultAuthConfig() *AuthConfig {
 		RefreshThreshold:  15 * 24 * time.Hour, // 15 days
 		MaxFailedAttempts: 5,
 		LockoutDuration:   30 * time.Minute,
+
+		ImpersonationDuration: time.Hour,
+		MaxSessionLifetime:    DefaultMaxSessionLifetime,
 	}
 }
 
@@ -34,16 +48,21 @@ type AuthManager struct {
 	sessionAdapter SessionAdapter
 	config         *AuthConfig
 
-	// Rate limiting for failed attempts
-	failedAttempts      map[string]failedAttemptInfo
-	failedAttemptsMutex sync.RWMutex
-}
-
-type failedAttemptInfo struct {
-	count    int
-	lastTry  time.Time
-	lockedAt time.Time
-	isLocked bool
+	// failures holds the failed logins behind account lockout (see UseLoginFailures); nil disables lockout
+	failures LoginFailureStore
+
+	// onAccountLocked is called once each time an identifier gets locked (see OnAccountLocked)
+	onAccountLocked func(identifier string, until time.Time)
+	// onLogout is called after Logout and LogoutAll end sessions (see OnLogout)
+	onLogout func(userID, sessionID string, reason LogoutReason)
+	// onFirstLogin is called after a user's first successful login (see OnFirstLogin)
+	onFirstLogin func(user *UserData, session *Session)
+	// onSessionCreated are called after Login and CreateSessionForUser open a session (see OnSessionCreated)
+	onSessionCreated []func(user *UserData, session *Session)
+	// onLogoutAll are called after LogoutAll ends every session of a user (see OnLogoutAll)
+	onLogoutAll []func(userID string, reason LogoutReason)
+	// onCredentialsVerified may hold a password login back before its session (see OnCredentialsVerified)
+	onCredentialsVerified func(user *UserData, metadata SessionMetadata) error
 }
 
 // NewAuthManager creates a new AuthManager instance
@@ -56,10 +75,29 @@ func NewAuthManager(userAdapter UserAdapter, sessionAdapter SessionAdapter, conf
 		userAdapter:    userAdapter,
 		sessionAdapter: sessionAdapter,
 		config:         config,
-		failedAttempts: make(map[string]failedAttemptInfo),
 	}
 }
 
+// UseLoginFailures locks identifiers with MaxFailedAttempts failed logins within LockoutDuration, as
+// recorded in store. Without it there is no lockout. Call it during setup, before serving requests.
+func (m *AuthManager) UseLoginFailures(store LoginFailureStore) {
+	m.failures = store
+}
+
+// WithContext returns a copy of the manager whose adapter queries run with ctx (when the adapters
+// implement ContextUserAdapter and ContextSessionAdapter), e.g. so they show up in the request's trace.
+// The copy shares the configuration, lockout store and hooks with m.
+func (m *AuthManager) WithContext(ctx context.Context) *AuthManager {
+	scoped := *m
+	if users, ok := m.userAdapter.(ContextUserAdapter); ok {
+		scoped.userAdapter = users.WithContext(ctx)
+	}
+	if sessions, ok := m.sessionAdapter.(ContextSessionAdapter); ok {
+		scoped.sessionAdapter = sessions.WithContext(ctx)
+	}
+	return &scoped
+}
+
 // Login authenticates a user and creates a session
 func (m *AuthManager) Login(identifier, password string, metadata SessionMetadata) (*Session, *UserData, error) {
 	// Check if account is locked
@@ -70,21 +108,35 @@ func (m *AuthManager) Login(identifier, password string, metadata SessionMetadat
 	// Validate credentials
 	user, err := m.userAdapter.ValidateCredentials(identifier, password)
 	if err != nil {
-		m.recordFailedAttempt(identifier)
+		if until := m.recordFailedAttempt(identifier); !until.IsZero() && m.onAccountLocked != nil {
+			m.onAccountLocked(identifier, until)
+		}
 
 		return nil, nil, err
 	}
 
-	// Check if user is active
-	if !user.Active {
+	switch m.LoginStatus(user) {
+	case LoginStatusInactive:
 		return nil, nil, ErrUserNotActive
+	case LoginStatusLocked:
+		// Locked under the other identifier (username vs email)
+		return nil, nil, ErrAccountLocked
+	case LoginStatusUnverified:
+		return nil, nil, ErrEmailNotVerified
 	}
 
 	// Clear failed attempts on successful login
 	m.clearFailedAttempts(identifier)
 
+	if m.onCredentialsVerified != nil {
+		if err := m.onCredentialsVerified(user, metadata); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	// Create session
-	expiresAt := time.Now().Add(m.config.SessionDuration)
+	now := time.Now()
+	expiresAt := m.capExpiry(now, now.Add(m.config.SessionDuration))
 	session, err := m.sessionAdapter.CreateSession(user.ID, expiresAt, metadata)
 	if err != nil {
 		logger.Error("Erro ao criar sessão após login", "error", err, "user_id", user.ID)
@@ -93,10 +145,39 @@ func (m *AuthManager) Login(identifier, password string, metadata SessionMetadat
 	}
 
 	session.Fresh = true
+	// Only a login that got its session counts as one
+	if err := m.userAdapter.UpdateLastLogin(user.ID); err != nil {
+		logger.Error("Erro ao atualizar último login", "error", err, "user_id", user.ID)
+	}
+	m.sessionCreated(user, session)
+	if user.FirstLogin {
+		logger.Info("Primeiro login do usuário", "user_id", user.ID)
+		if m.onFirstLogin != nil {
+			m.onFirstLogin(user, session)
+		}
+	}
 
 	return session, user, nil
 }
 
+// LoginStatus decides whether user may log in. The first matching status wins, in this order:
+// inactive, locked (under the username or the email), unverified (only with RequireVerifiedEmail),
+// must_change, ok. Credentials are not checked here.
+func (m *AuthManager) LoginStatus(user *UserData) LoginStatus {
+	switch {
+	case !user.Active:
+		return LoginStatusInactive
+	case m.isAccountLocked(user.Identifier) || (user.Email != "" && m.isAccountLocked(user.Email)):
+		return LoginStatusLocked
+	case m.config.RequireVerifiedEmail && !user.EmailVerified:
+		return LoginStatusUnverified
+	case user.MustChangePassword:
+		return LoginStatusMustChange
+	default:
+		return LoginStatusOK
+	}
+}
+
 // ValidateSession validates a session and returns user data
 func (m *AuthManager) ValidateSession(sessionID string) (*Session, *UserData, error) {
 	session, err := m.sessionAdapter.GetSession(sessionID)
@@ -111,6 +192,13 @@ func (m *AuthManager) ValidateSession(sessionID string) (*Session, *UserData, er
 
 		return nil, nil, ErrSessionExpired
 	}
+	if m.config.MaxSessionLifetime > 0 && time.Since(session.CreatedAt) > m.config.MaxSessionLifetime {
+		// Past the absolute cap, however recently it was used
+		logger.Info("Sessão encerrada pelo limite absoluto de duração", "session_id", sessionID, "user_id", session.UserID)
+		_ = m.sessionAdapter.DeleteSession(sessionID)
+
+		return nil, nil, ErrSessionExpired
+	}
 
 	// Get user data
 	user, err := m.userAdapter.FindUserByID(session.UserID)
@@ -131,11 +219,15 @@ func (m *AuthManager) ValidateSession(sessionID string) (*Session, *UserData, er
 		return nil, nil, ErrUserNotActive
 	}
 
-	// Refresh session if needed
+	// Refresh session if needed; impersonation sessions keep their short fixed lifetime
 	session.Fresh = false
 	timeRemaining := time.Until(session.ExpiresAt)
-	if timeRemaining < m.config.RefreshThreshold {
-		newExpiresAt := time.Now().Add(m.config.SessionDuration)
+	if timeRemaining < m.config.RefreshThreshold && session.ImpersonatedBy == "" {
+		newExpiresAt := m.capExpiry(session.CreatedAt, time.Now().Add(m.config.SessionDuration))
+		if !newExpiresAt.After(session.ExpiresAt) {
+			// Already at the absolute cap
+			return session, user, nil
+		}
 		if err := m.sessionAdapter.UpdateSessionExpiry(sessionID, newExpiresAt); err == nil {
 			session.ExpiresAt = newExpiresAt
 			session.Fresh = true
@@ -148,29 +240,181 @@ func (m *AuthManager) ValidateSession(sessionID string) (*Session, *UserData, er
 	return session, user, nil
 }
 
-// Logout invalidates a session
-func (m *AuthManager) Logout(sessionID string) error {
+// ExtendSession validates the session and pushes its expiry to a full SessionDuration from now,
+// without going past MaxSessionLifetime. Impersonation sessions keep their fixed expiry.
+// Used by clients that warn about an idle session before it expires (keep-alive).
+func (m *AuthManager) ExtendSession(sessionID string) (*Session, error) {
+	session, user, err := m.ValidateSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.ImpersonatedBy != "" {
+		return session, nil
+	}
+
+	newExpiresAt := m.capExpiry(session.CreatedAt, time.Now().Add(m.config.SessionDuration))
+	if !newExpiresAt.After(session.ExpiresAt) {
+		return session, nil
+	}
+	if err := m.sessionAdapter.UpdateSessionExpiry(sessionID, newExpiresAt); err != nil {
+		logger.Error("Erro ao estender sessão", "error", err, "session_id", sessionID)
+
+		return nil, err
+	}
+	session.ExpiresAt = newExpiresAt
+	session.Fresh = true
+	logger.Debug("Sessão estendida", "session_id", sessionID, "user_id", user.ID)
+
+	return session, nil
+}
+
+// capExpiry limits expiresAt to createdAt + MaxSessionLifetime when a cap is configured.
+func (m *AuthManager) capExpiry(createdAt, expiresAt time.Time) time.Time {
+	if m.config.MaxSessionLifetime <= 0 {
+		return expiresAt
+	}
+	if limit := createdAt.Add(m.config.MaxSessionLifetime); expiresAt.After(limit) {
+		return limit
+	}
+	return expiresAt
+}
+
+// CreateSessionForUser opens a session for an active user without checking credentials.
+// Callers must have authorized the user by other means (e.g. returning an admin from impersonation).
+func (m *AuthManager) CreateSessionForUser(userID string, metadata SessionMetadata) (*Session, *UserData, error) {
+	user, err := m.userAdapter.FindUserByID(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !user.Active {
+		return nil, nil, ErrUserNotActive
+	}
+
+	now := time.Now()
+	expiresAt := m.capExpiry(now, now.Add(m.config.SessionDuration))
+	if metadata.ImpersonatedBy != "" {
+		expiresAt = now.Add(m.config.ImpersonationDuration)
+	}
+	session, err := m.sessionAdapter.CreateSession(user.ID, expiresAt, metadata)
+	if err != nil {
+		logger.Error("Erro ao criar sessão", "error", err, "user_id", user.ID)
+
+		return nil, nil, err
+	}
+	session.Fresh = true
+	m.sessionCreated(user, session)
+
+	return session, user, nil
+}
+
+// Logout invalidates a session; reason ("" means LogoutReasonUser) is passed on to the OnLogout hook
+func (m *AuthManager) Logout(sessionID string, reason LogoutReason) error {
+	var userID string
+	if m.onLogout != nil {
+		if session, err := m.sessionAdapter.GetSession(sessionID); err == nil {
+			userID = session.UserID
+		}
+	}
 	if err := m.sessionAdapter.DeleteSession(sessionID); err != nil {
 		logger.Error("Erro ao fazer logout", "error", err, "session_id", sessionID)
 
 		return err
 	}
+	if m.onLogout != nil && userID != "" {
+		m.onLogout(userID, sessionID, reason.orDefault())
+	}
 
 	return nil
 }
 
-// LogoutAll invalidates all sessions for a user
-func (m *AuthManager) LogoutAll(userID string) error {
+// LogoutAll invalidates all sessions for a user; reason ("" means LogoutReasonUser) is passed on to the OnLogout hook
+func (m *AuthManager) LogoutAll(userID string, reason LogoutReason) error {
 	if err := m.sessionAdapter.DeleteUserSessions(userID); err != nil {
 		logger.Error("Erro ao fazer logout de todas as sessões", "error", err, "user_id", userID)
 
 		return err
 	}
-	logger.Info("Todas as sessões do usuário foram invalidadas", "user_id", userID)
+	logger.Info("Todas as sessões do usuário foram invalidadas", "user_id", userID, "reason", reason.orDefault())
+	if m.onLogout != nil {
+		m.onLogout(userID, "", reason.orDefault())
+	}
+	for _, fn := range m.onLogoutAll {
+		fn(userID, reason.orDefault())
+	}
+
+	return nil
+}
+
+// LogoutOthers invalidates every session of a user but keepSessionID (usually the caller's own), for
+// "sign out everywhere else"; the OnLogout hook gets an empty session ID and LogoutReasonOtherDevices
+func (m *AuthManager) LogoutOthers(userID, keepSessionID string) error {
+	if err := m.sessionAdapter.DeleteUserSessionsExcept(userID, keepSessionID); err != nil {
+		logger.Error("Erro ao fazer logout das outras sessões", "error", err, "user_id", userID)
+
+		return err
+	}
+	logger.Info("Sessões dos outros dispositivos do usuário foram invalidadas", "user_id", userID)
+	if m.onLogout != nil {
+		m.onLogout(userID, "", LogoutReasonOtherDevices)
+	}
 
 	return nil
 }
 
+// CountUserSessions returns how many active (not expired) sessions the user has
+func (m *AuthManager) CountUserSessions(userID string) (int64, error) {
+	return m.sessionAdapter.CountUserSessions(userID)
+}
+
+// OnLogout registers fn to be called after Logout (with the session ID) and LogoutAll or LogoutOthers
+// (with an empty session ID) end sessions, e.g. to audit why. It runs on the caller's goroutine, so fn must not block.
+// Call it during setup, before serving requests.
+func (m *AuthManager) OnLogout(fn func(userID, sessionID string, reason LogoutReason)) {
+	m.onLogout = fn
+}
+
+// OnAccountLocked registers fn to be called when a failed login locks identifier, once per lock,
+// with the time the lock ends. It runs on the login request, so fn must not block (queue slow work).
+// Call it during setup, before serving requests.
+func (m *AuthManager) OnAccountLocked(fn func(identifier string, until time.Time)) {
+	m.onAccountLocked = fn
+}
+
+// OnFirstLogin registers fn to be called after the first successful login of a user (UserData.FirstLogin),
+// e.g. to start onboarding. It runs on the login request, so fn must not block.
+// Call it during setup, before serving requests.
+func (m *AuthManager) OnFirstLogin(fn func(user *UserData, session *Session)) {
+	m.onFirstLogin = fn
+}
+
+// OnSessionCreated adds fn to the functions called, in the order added, after Login or CreateSessionForUser
+// opens a session for user, e.g. to count them. It runs on the caller's goroutine, so fn must not block.
+// Call it during setup, before serving requests.
+func (m *AuthManager) OnSessionCreated(fn func(user *UserData, session *Session)) {
+	m.onSessionCreated = append(m.onSessionCreated, fn)
+}
+
+// OnLogoutAll adds fn to the functions called, in the order added, after LogoutAll ends every session
+// of userID, e.g. to forget the user's remembered devices. It runs on the caller's goroutine, so fn must not block.
+// Call it during setup, before serving requests.
+func (m *AuthManager) OnLogoutAll(fn func(userID string, reason LogoutReason)) {
+	m.onLogoutAll = append(m.onLogoutAll, fn)
+}
+
+func (m *AuthManager) sessionCreated(user *UserData, session *Session) {
+	for _, fn := range m.onSessionCreated {
+		fn(user, session)
+	}
+}
+
+// OnCredentialsVerified registers fn to be called by Login once the password is right and the user may
+// log in, before the session is created. An error from fn ends the login without a session and is
+// returned as is, e.g. to ask for a step-up verification first. Sessions opened by CreateSessionForUser
+// skip it. Call it during setup, before serving requests.
+func (m *AuthManager) OnCredentialsVerified(fn func(user *UserData, metadata SessionMetadata) error) {
+	m.onCredentialsVerified = fn
+}
+
 // GetUserAdapter returns the user adapter (useful for registration, etc)
 func (m *AuthManager) GetUserAdapter() UserAdapter {
 	return m.userAdapter
@@ -198,49 +442,58 @@ func GenerateRandomBytes(b []byte) (int, error) {
 	return rand.Read(b)
 }
 
-// --- Rate limiting helpers ---
+// --- Lockout helpers ---
 
+// isAccountLocked reports whether identifier has MaxFailedAttempts failed logins within LockoutDuration.
+// A store error is logged and leaves the account unlocked, like the login it can't count.
 func (m *AuthManager) isAccountLocked(identifier string) bool {
-	m.failedAttemptsMutex.RLock()
-	defer m.failedAttemptsMutex.RUnlock()
+	count, _ := m.countFailures(identifier)
+	return m.config.MaxFailedAttempts > 0 && count >= m.config.MaxFailedAttempts
+}
 
-	info, exists := m.failedAttempts[identifier]
-	if !exists {
-		return false
+// recordFailedAttempt accounts for a failed login that its caller records in the store, and returns
+// when the lock ends if this attempt locked the identifier (zero time otherwise).
+func (m *AuthManager) recordFailedAttempt(identifier string) time.Time {
+	count, oldest := m.countFailures(identifier)
+	if m.failures == nil || m.config.MaxFailedAttempts <= 0 || count+1 != m.config.MaxFailedAttempts {
+		return time.Time{}
 	}
-
-	if !info.isLocked {
-		return false
+	if count == 0 {
+		oldest = time.Now()
 	}
+	// The lock lifts once the oldest counted failure leaves the window
+	return oldest.Add(m.config.LockoutDuration)
+}
 
-	// Check if lockout has expired
-	if time.Since(info.lockedAt) > m.config.LockoutDuration {
-		return false
+func (m *AuthManager) countFailures(identifier string) (int, time.Time) {
+	if m.failures == nil {
+		return 0, time.Time{}
 	}
-
-	return true
+	count, oldest, err := m.failures.LoginFailures(identifier, time.Now().Add(-m.config.LockoutDuration))
+	if err != nil {
+		logger.Error("Erro ao contar falhas de login", "error", err, "identifier", identifier)
+		return 0, time.Time{}
+	}
+	return count, oldest
 }
 
-func (m *AuthManager) recordFailedAttempt(identifier string) {
-	m.failedAttemptsMutex.Lock()
-	defer m.failedAttemptsMutex.Unlock()
-
-	info := m.failedAttempts[identifier]
-	info.count++
-	info.lastTry = time.Now()
-
-	if info.count >= m.config.MaxFailedAttempts {
-		info.isLocked = true
-		info.lockedAt = time.Now()
+func (m *AuthManager) clearFailedAttempts(identifier string) {
+	if m.failures == nil {
+		return
+	}
+	if err := m.failures.ClearLoginFailures(identifier); err != nil {
+		logger.Error("Erro ao limpar falhas de login", "error", err, "identifier", identifier)
 	}
-
-	m.failedAttempts[identifier] = info
 }
 
-func (m *AuthManager) clearFailedAttempts(identifier string) {
-	m.failedAttemptsMutex.Lock()
-	defer m.failedAttemptsMutex.Unlock()
-	delete(m.failedAttempts, identifier)
+// ClearLockout forgets the failed logins, and lifts the lock, of each identifier (username and email,
+// since either can be locked).
+func (m *AuthManager) ClearLockout(identifiers ...string) {
+	for _, identifier := range identifiers {
+		if identifier != "" {
+			m.clearFailedAttempts(identifier)
+		}
+	}
 }
 
 // ErrAccountLocked is returned when an account is temporarily locked
@@ -8,6 +8,7 @@
 package auth
 
 import (
+	"context"
 	"errors"
 	"time"
 )
@@ -19,17 +20,65 @@ var (
 	ErrUserNotFound       = errors.New("user not found")
 	ErrSessionNotFound    = errors.New("session not found")
 	ErrSessionExpired     = errors.New("session expired")
+	ErrEmailNotVerified   = errors.New("email not verified")
+	// ErrUsernameExists and ErrEmailExists mean CreateUser hit an account that already uses the username or email
+	ErrUsernameExists = errors.New("username already exists")
+	ErrEmailExists    = errors.New("email already exists")
 )
 
+// LoginStatus is the outcome of the "can this user log in?" decision made by AuthManager.LoginStatus
+type LoginStatus string
+
+// Login statuses, in the order AuthManager.LoginStatus checks them
+const (
+	LoginStatusInactive   LoginStatus = "inactive"    // deactivated by an admin or the inactivity job
+	LoginStatusLocked     LoginStatus = "locked"      // too many failed attempts, temporarily
+	LoginStatusUnverified LoginStatus = "unverified"  // email not verified while AuthConfig.RequireVerifiedEmail is on
+	LoginStatusMustChange LoginStatus = "must_change" // may log in, but has to change the password first
+	LoginStatusOK         LoginStatus = "ok"
+)
+
+// CanLogin reports whether a session may be created for a user in this status
+func (s LoginStatus) CanLogin() bool {
+	return s == LoginStatusOK || s == LoginStatusMustChange
+}
+
+// LogoutReason says why sessions ended, for the audit log and security dashboards
+type LogoutReason string
+
+// Logout reasons passed to AuthManager.Logout, LogoutAll and LogoutOthers
+const (
+	LogoutReasonUser          LogoutReason = "user"           // the user logged out (the default)
+	LogoutReasonAdminRevoked  LogoutReason = "admin_revoked"  // an admin ended the sessions, e.g. by deleting the account
+	LogoutReasonPasswordReset LogoutReason = "password_reset" // the password was reset through the emailed link
+	LogoutReasonDeactivated   LogoutReason = "deactivated"    // the account was deactivated
+	LogoutReasonImpersonation LogoutReason = "impersonation"  // replaced by a new session when impersonation started or stopped
+	LogoutReasonRoleChanged   LogoutReason = "role_changed"   // an admin changed the user's role (session.logout_on_role_change)
+	LogoutReasonOtherDevices  LogoutReason = "other_devices"  // the user signed out everywhere but the current session (LogoutOthers)
+)
+
+// orDefault returns the reason, or LogoutReasonUser when none was given
+func (r LogoutReason) orDefault() LogoutReason {
+	if r == "" {
+		return LogoutReasonUser
+	}
+	return r
+}
+
 // UserData represents generic user data (database-agnostic)
 type UserData struct {
-	ID          string         `json:"id"`
-	Identifier  string         `json:"identifier"` // username, email, etc
-	DisplayName string         `json:"display_name"`
-	Email       string         `json:"email"`
-	Role        string         `json:"role"`
-	Active      bool           `json:"active"`
-	Attributes  map[string]any `json:"attributes,omitempty"` // extra fields
+	ID                 string         `json:"id"`
+	Identifier         string         `json:"identifier"` // username, email, etc
+	DisplayName        string         `json:"display_name"`
+	Email              string         `json:"email"`
+	Role               string         `json:"role"`
+	Active             bool           `json:"active"`
+	EmailVerified      bool           `json:"email_verified"`
+	MustChangePassword bool           `json:"must_change_password"`
+	TermsVersion       string         `json:"terms_version,omitempty"` // version of the terms of use last accepted
+	Attributes         map[string]any `json:"attributes,omitempty"`    // extra fields
+	// FirstLogin is set by ValidateCredentials when the user has no recorded login yet (not stored)
+	FirstLogin bool `json:"-"`
 }
 
 // Session represents an authentication session
@@ -41,12 +90,21 @@ type Session struct {
 	UserAgent string    `json:"user_agent,omitempty"`
 	IP        string    `json:"ip,omitempty"`
 	Fresh     bool      `json:"fresh"` // true if just created or refreshed
+	// ImpersonatedBy is the ID of the admin acting as this user ("" for regular sessions)
+	ImpersonatedBy string `json:"impersonated_by,omitempty"`
+	// NewLoginAt and NewLoginFrom tell of a login from another device made while this session was open
+	// and not yet dismissed (nil and "" for none)
+	NewLoginAt   *time.Time `json:"new_login_at,omitempty"`
+	NewLoginFrom string     `json:"new_login_from,omitempty"`
 }
 
 // SessionMetadata contains metadata for session creation
 type SessionMetadata struct {
-	UserAgent string
-	IP        string
+	UserAgent      string
+	IP             string
+	ImpersonatedBy string // admin user ID when the session is an impersonation
+	Country        string // ISO 3166-1 alpha-2 code of the request's origin, when known (not stored)
+	DeviceToken    string // remembered device token from the client's cookie, when any (not stored)
 }
 
 // CreateUserInput contains data for creating a new user
@@ -66,10 +124,15 @@ type UserAdapter interface {
 	// FindUserByID looks up user by ID
 	FindUserByID(id string) (*UserData, error)
 
-	// ValidateCredentials validates credentials and returns user if valid
+	// ValidateCredentials validates credentials and returns user if valid; it doesn't record the login,
+	// since the user may still be refused (see UpdateLastLogin)
 	ValidateCredentials(identifier, password string) (*UserData, error)
 
-	// CreateUser creates a new user (optional for legacy systems)
+	// UpdateLastLogin records that userID just logged in; called once the session exists
+	UpdateLastLogin(userID string) error
+
+	// CreateUser creates a new user (optional for legacy systems); ErrUsernameExists or ErrEmailExists
+	// when another account already has the username or email, including one created concurrently
 	CreateUser(data CreateUserInput) (*UserData, error)
 
 	// UpdatePassword updates the password (optional for legacy systems)
@@ -93,10 +156,41 @@ type SessionAdapter interface {
 	// DeleteUserSessions removes all sessions for a user
 	DeleteUserSessions(userID string) error
 
+	// DeleteUserSessionsExcept removes all sessions for a user but keepSessionID
+	DeleteUserSessionsExcept(userID, keepSessionID string) error
+
+	// CountUserSessions counts the user's sessions that have not expired yet
+	CountUserSessions(userID string) (int64, error)
+
 	// DeleteExpiredSessions cleans up expired sessions
 	DeleteExpiredSessions() error
 }
 
+// LoginFailureStore keeps the failed logins that drive account lockout, so a lock survives restarts and
+// holds on every instance sharing the store
+type LoginFailureStore interface {
+	// LoginFailures counts the failed logins of identifier (case-insensitive) since the given time that were
+	// not cleared, and returns when the oldest of them happened
+	LoginFailures(identifier string, since time.Time) (count int, oldest time.Time, err error)
+
+	// ClearLoginFailures stops counting the failed logins of identifier (successful login, password reset)
+	ClearLoginFailures(identifier string) error
+}
+
+// ContextUserAdapter optional interface for user adapters whose queries can run with a request context
+// (e.g. to join the request's trace); see AuthManager.WithContext
+type ContextUserAdapter interface {
+	// WithContext returns a copy of the adapter that runs its queries with ctx
+	WithContext(ctx context.Context) UserAdapter
+}
+
+// ContextSessionAdapter optional interface for session adapters whose queries can run with a request
+// context; see AuthManager.WithContext
+type ContextSessionAdapter interface {
+	// WithContext returns a copy of the adapter that runs its queries with ctx
+	WithContext(ctx context.Context) SessionAdapter
+}
+
 // PasswordResetAdapter optional interface for password reset functionality
 type PasswordResetAdapter interface {
 	// SetResetToken stores a password reset token for a user
@@ -0,0 +1,73 @@
+// Package avatar resolves user avatar image URLs (stored URL or Gravatar).
+package avatar
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// gravatarBaseURL is the Gravatar image endpoint; the hash is appended to it.
+const gravatarBaseURL = "https://www.gravatar.com/avatar/"
+
+// gravatarOrigin is where Gravatar images load from, as listed in the page's Content-Security-Policy.
+const gravatarOrigin = "https://www.gravatar.com"
+
+// allowedHosts is set at startup from config avatar.hosts.
+var allowedHosts atomic.Pointer[[]string]
+
+// AllowHosts sets the hosts (e.g. "cdn.example.com") a stored avatar URL may point to; URL replaces a
+// stored URL on any other host with the Gravatar. Without a call only Gravatar is used.
+func AllowHosts(hosts ...string) {
+	lower := make([]string, len(hosts))
+	for i, host := range hosts {
+		lower[i] = strings.ToLower(host)
+	}
+	allowedHosts.Store(&lower)
+}
+
+// ImageSources returns the origins avatar images load from, space separated, for the img-src directive
+// of the Content-Security-Policy: Gravatar and each host given to AllowHosts.
+func ImageSources() string {
+	sources := []string{gravatarOrigin}
+	if hosts := allowedHosts.Load(); hosts != nil {
+		for _, host := range *hosts {
+			sources = append(sources, "https://"+host)
+		}
+	}
+	return strings.Join(sources, " ")
+}
+
+// hostAllowed reports whether host was given to AllowHosts.
+func hostAllowed(host string) bool {
+	hosts := allowedHosts.Load()
+	return hosts != nil && slices.Contains(*hosts, strings.ToLower(host))
+}
+
+// GravatarHash returns the SHA-256 hex hash Gravatar expects: computed over the trimmed, lowercased email.
+func GravatarHash(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return hex.EncodeToString(sum[:])
+}
+
+// GravatarURL returns the Gravatar image URL for email at size pixels.
+// Users without a Gravatar get a generated identicon instead of the default logo.
+func GravatarURL(email string, size int) string {
+	q := url.Values{}
+	q.Set("s", strconv.Itoa(size))
+	q.Set("d", "identicon")
+	return gravatarBaseURL + GravatarHash(email) + "?" + q.Encode()
+}
+
+// URL returns the avatar to show for a user: the stored avatarURL when it is a valid https URL on a host
+// given to AllowHosts, otherwise the Gravatar derived from email.
+func URL(avatarURL, email string, size int) string {
+	if u, err := url.Parse(avatarURL); err == nil && u.Scheme == "https" && hostAllowed(u.Host) {
+		return avatarURL
+	}
+	return GravatarURL(email, size)
+}
@@ -0,0 +1,57 @@
+// Package avatar tests
+package avatar
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGravatarHash(t *testing.T) {
+	// Reference value from the Gravatar docs: sha256("myemailaddress@example.com")
+	const want = "84059b07d4be67b806386c0aad8070a23f18836bbaae342275dc0a83414c32ee"
+
+	tests := []string{
+		"myemailaddress@example.com",
+		"MyEmailAddress@example.com",
+		"  MyEmailAddress@Example.com \n",
+	}
+	for _, email := range tests {
+		if got := GravatarHash(email); got != want {
+			t.Errorf("GravatarHash(%q) = %s, want %s", email, got, want)
+		}
+	}
+}
+
+func TestGravatarURL(t *testing.T) {
+	got := GravatarURL("MyEmailAddress@example.com", 64)
+	want := "https://www.gravatar.com/avatar/84059b07d4be67b806386c0aad8070a23f18836bbaae342275dc0a83414c32ee?d=identicon&s=64"
+	if got != want {
+		t.Errorf("GravatarURL() = %s, want %s", got, want)
+	}
+}
+
+func TestURL(t *testing.T) {
+	AllowHosts("CDN.example.com")
+	defer AllowHosts()
+
+	stored := "https://cdn.example.com/a.png"
+	if got := URL(stored, "a@example.com", 32); got != stored {
+		t.Errorf("URL() = %s, want stored %s", got, stored)
+	}
+	for _, bad := range []string{"", "http://cdn.example.com/a.png", "https://other.example.com/a.png", "javascript:alert(1)", "/local.png"} {
+		if got := URL(bad, "a@example.com", 32); !strings.HasPrefix(got, gravatarBaseURL) {
+			t.Errorf("URL(%q) = %s, want Gravatar fallback", bad, got)
+		}
+	}
+}
+
+func TestImageSources(t *testing.T) {
+	if got := ImageSources(); got != "https://www.gravatar.com" {
+		t.Errorf("ImageSources() = %s, want only Gravatar", got)
+	}
+	AllowHosts("cdn.example.com")
+	defer AllowHosts()
+	if got, want := ImageSources(), "https://www.gravatar.com https://cdn.example.com"; got != want {
+		t.Errorf("ImageSources() = %s, want %s", got, want)
+	}
+}
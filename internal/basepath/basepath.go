@@ -0,0 +1,97 @@
+// Package basepath lets the whole app be mounted under a URL prefix (e.g. "/myapp" behind a reverse proxy).
+//
+// Routes are always registered and matched without the prefix: Handler strips it from incoming
+// requests. Everything that goes back to the browser (links, redirects, HX-Redirect, cookie paths)
+// must pass through URL so the prefix is added again.
+package basepath
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+)
+
+var prefix atomic.Value // string
+
+// Set configures the prefix. "", "/" and surrounding slashes are normalized, so "myapp/" becomes "/myapp".
+func Set(p string) {
+	p = strings.Trim(strings.TrimSpace(p), "/")
+	if p != "" {
+		p = "/" + p
+	}
+	prefix.Store(p)
+}
+
+// Get returns the normalized prefix ("" when the app is served from the root).
+func Get() string {
+	p, _ := prefix.Load().(string)
+	return p
+}
+
+// URL prefixes an app-local path such as "/login?next=%2Fadmin". Other values (absolute URLs,
+// fragments, empty strings) are returned unchanged.
+func URL(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") {
+		return path
+	}
+	return Get() + path
+}
+
+// Strip removes the prefix from a browser-facing path, e.g. the path of HX-Current-URL.
+// Paths outside the prefix are returned unchanged.
+func Strip(path string) string {
+	p := Get()
+	if p == "" {
+		return path
+	}
+	rest, ok := strings.CutPrefix(path, p)
+	if !ok || (rest != "" && rest[0] != '/' && rest[0] != '?') {
+		return path
+	}
+	if rest == "" || rest[0] == '?' {
+		return "/" + rest
+	}
+	return rest
+}
+
+// CookiePath is the Path attribute for cookies, so apps sharing a host under different prefixes
+// don't see each other's cookies.
+func CookiePath() string {
+	if p := Get(); p != "" {
+		return p
+	}
+	return "/"
+}
+
+// Handler serves h under the prefix: the prefix is stripped before routing, "/myapp" redirects to
+// "/myapp/", and anything outside the prefix is 404. With no prefix, h is returned as is.
+func Handler(h http.Handler) http.Handler {
+	p := Get()
+	if p == "" {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest, ok := strings.CutPrefix(r.URL.Path, p)
+		if !ok || (rest != "" && rest[0] != '/') {
+			http.NotFound(w, r)
+			return
+		}
+		if rest == "" {
+			target := p + "/"
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+			return
+		}
+
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path = rest
+		r2.URL.RawPath = strings.TrimPrefix(r.URL.RawPath, p)
+		h.ServeHTTP(w, r2)
+	})
+}
@@ -0,0 +1,70 @@
+package basepath
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestURLAndStrip(t *testing.T) {
+	t.Cleanup(func() { Set("") })
+
+	Set("")
+	assert.Equal(t, "/login", URL("/login"))
+	assert.Equal(t, "/", CookiePath())
+
+	Set(" myapp/ ")
+	assert.Equal(t, "/myapp", Get())
+	assert.Equal(t, "/myapp", CookiePath())
+
+	tests := []struct {
+		in, url, strip string
+	}{
+		{"/", "/myapp/", "/"},
+		{"/login?next=%2Fadmin", "/myapp/login?next=%2Fadmin", "/login?next=%2Fadmin"},
+		{"https://example.com/x", "https://example.com/x", "https://example.com/x"},
+		{"//evil.com", "//evil.com", "//evil.com"},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.url, URL(tt.in), "URL(%q)", tt.in)
+		assert.Equal(t, tt.strip, Strip(tt.url), "Strip(%q)", tt.url)
+	}
+	assert.Equal(t, "/", Strip("/myapp"))
+	assert.Equal(t, "/myappother", Strip("/myappother"), "only whole segments are stripped")
+}
+
+func TestHandler(t *testing.T) {
+	t.Cleanup(func() { Set("") })
+	Set("/myapp")
+
+	h := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.Path))
+	}))
+
+	tests := []struct {
+		path         string
+		wantStatus   int
+		wantBody     string
+		wantLocation string
+	}{
+		{"/myapp/admin/users", http.StatusOK, "/admin/users", ""},
+		{"/myapp/", http.StatusOK, "/", ""},
+		{"/myapp?x=1", http.StatusMovedPermanently, "", "/myapp/?x=1"},
+		{"/admin/users", http.StatusNotFound, "", ""},
+		{"/myappother", http.StatusNotFound, "", ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		assert.Equal(t, tt.wantStatus, w.Code, tt.path)
+		if tt.wantBody != "" {
+			assert.Equal(t, tt.wantBody, w.Body.String(), tt.path)
+		}
+		if tt.wantLocation != "" {
+			assert.Equal(t, tt.wantLocation, w.Header().Get("Location"), tt.path)
+		}
+	}
+}
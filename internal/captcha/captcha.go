@@ -0,0 +1,118 @@
+// Package captcha verifies CAPTCHA tokens (Cloudflare Turnstile or Google reCAPTCHA v2) server-side
+// and decides when the login and registration forms must show the challenge.
+package captcha
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+)
+
+// Supported providers (config captcha.provider).
+const (
+	ProviderTurnstile = "turnstile"
+	ProviderReCaptcha = "recaptcha"
+)
+
+// Siteverify endpoints and widget scripts of each provider.
+const (
+	turnstileVerifyURL = "https://challenges.cloudflare.com/turnstile/v0/siteverify"
+	turnstileScriptURL = "https://challenges.cloudflare.com/turnstile/v0/api.js"
+	recaptchaVerifyURL = "https://www.google.com/recaptcha/api/siteverify"
+	recaptchaScriptURL = "https://www.google.com/recaptcha/api.js"
+)
+
+// TokenField is the form/JSON field the widget writes its token to. reCAPTCHA always uses
+// g-recaptcha-response, which handlers accept as a fallback (see ResponseFields).
+const TokenField = "captcha_token"
+
+// ResponseFields are the providers' default form fields, checked when TokenField is empty.
+var ResponseFields = []string{"cf-turnstile-response", "g-recaptcha-response"}
+
+var (
+	ErrCaptchaRequired = errors.New("confirme que você não é um robô")
+	ErrCaptchaInvalid  = errors.New("verificação anti-robô falhou, tente novamente")
+)
+
+// verifyTimeout bounds the call to the provider's siteverify endpoint.
+const verifyTimeout = 5 * time.Second
+
+// CaptchaVerifier validates a CAPTCHA token produced by the widget in the browser.
+type CaptchaVerifier interface {
+	Verify(ctx context.Context, token, remoteIP string) error
+}
+
+// NoopVerifier accepts every token. Used when CAPTCHA is disabled and in tests.
+type NoopVerifier struct{}
+
+// Verify always succeeds.
+func (NoopVerifier) Verify(context.Context, string, string) error { return nil }
+
+// SiteVerifyVerifier validates tokens against a siteverify endpoint. Turnstile and reCAPTCHA share
+// the same protocol: POST secret/response/remoteip as a form, receive {"success": bool, ...}.
+type SiteVerifyVerifier struct {
+	Endpoint string
+	Secret   string
+	Client   *http.Client
+}
+
+// NewTurnstileVerifier creates a verifier for Cloudflare Turnstile
+func NewTurnstileVerifier(secret string) *SiteVerifyVerifier {
+	return &SiteVerifyVerifier{Endpoint: turnstileVerifyURL, Secret: secret, Client: &http.Client{Timeout: verifyTimeout}}
+}
+
+// NewReCaptchaVerifier creates a verifier for Google reCAPTCHA v2
+func NewReCaptchaVerifier(secret string) *SiteVerifyVerifier {
+	return &SiteVerifyVerifier{Endpoint: recaptchaVerifyURL, Secret: secret, Client: &http.Client{Timeout: verifyTimeout}}
+}
+
+type siteVerifyResponse struct {
+	Success    bool     `json:"success"`
+	ErrorCodes []string `json:"error-codes"`
+}
+
+// Verify returns ErrCaptchaRequired for an empty token and ErrCaptchaInvalid when the provider
+// rejects it. Transport errors are returned as-is (the request is treated as not verified).
+func (v *SiteVerifyVerifier) Verify(ctx context.Context, token, remoteIP string) error {
+	if strings.TrimSpace(token) == "" {
+		return ErrCaptchaRequired
+	}
+
+	form := url.Values{"secret": {v.Secret}, "response": {token}}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.Endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	client := v.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		logger.Error("Erro ao verificar CAPTCHA", "error", err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result siteVerifyResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		logger.Error("Resposta inválida da verificação de CAPTCHA", "error", err, "status", resp.StatusCode)
+		return err
+	}
+	if !result.Success {
+		logger.Debug("CAPTCHA rejeitado", "error_codes", result.ErrorCodes, "ip", remoteIP)
+		return ErrCaptchaInvalid
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package captcha
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSiteVerifyVerifier_Verify(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "secret", r.PostForm.Get("secret"))
+		assert.Equal(t, "198.51.100.1", r.PostForm.Get("remoteip"))
+		ok := r.PostForm.Get("response") == "good-token"
+		_ = json.NewEncoder(w).Encode(map[string]any{"success": ok, "error-codes": []string{}})
+	}))
+	defer server.Close()
+
+	v := &SiteVerifyVerifier{Endpoint: server.URL, Secret: "secret", Client: server.Client()}
+
+	assert.NoError(t, v.Verify(context.Background(), "good-token", "198.51.100.1"))
+	assert.ErrorIs(t, v.Verify(context.Background(), "bad-token", "198.51.100.1"), ErrCaptchaInvalid)
+	assert.ErrorIs(t, v.Verify(context.Background(), "  ", "198.51.100.1"), ErrCaptchaRequired)
+}
+
+func TestSiteVerifyVerifier_TransportError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+
+	v := &SiteVerifyVerifier{Endpoint: server.URL, Secret: "secret"}
+	err := v.Verify(context.Background(), "token", "")
+	require.Error(t, err)
+	assert.False(t, errors.Is(err, ErrCaptchaInvalid))
+}
+
+func TestNewGuard(t *testing.T) {
+	disabled := NewGuard(config.CaptchaConfig{Provider: "unknown", OnRegister: true, LoginFailures: 1})
+	assert.False(t, disabled.Enabled())
+	assert.IsType(t, NoopVerifier{}, disabled.Verifier)
+	assert.False(t, disabled.RequiredForRegister())
+	assert.Equal(t, Widget{}, disabled.Widget())
+	assert.NoError(t, disabled.Check(context.Background(), true, "", "1.2.3.4"))
+
+	turnstile := NewGuard(config.CaptchaConfig{Provider: ProviderTurnstile, SiteKey: "site", SecretKey: "secret", OnRegister: true})
+	assert.True(t, turnstile.RequiredForRegister())
+	assert.Equal(t, turnstileVerifyURL, turnstile.Verifier.(*SiteVerifyVerifier).Endpoint)
+	assert.Equal(t, Widget{Provider: ProviderTurnstile, SiteKey: "site", ScriptURL: turnstileScriptURL}, turnstile.Widget())
+
+	recaptcha := NewGuard(config.CaptchaConfig{Provider: ProviderReCaptcha})
+	assert.Equal(t, recaptchaVerifyURL, recaptcha.Verifier.(*SiteVerifyVerifier).Endpoint)
+	assert.False(t, recaptcha.RequiredForRegister())
+}
+
+func TestGuard_RegisterBypass(t *testing.T) {
+	g := NewGuard(config.CaptchaConfig{
+		Provider:       ProviderTurnstile,
+		OnRegister:     true,
+		RegisterBypass: config.CaptchaBypassConfig{IPs: []string{"10.0.0.0/8", "2001:db8::1", "not-an-ip"}, Secret: "s3cret"},
+	})
+
+	via, ok := g.RegisterBypass("10.20.30.40", "")
+	assert.True(t, ok)
+	assert.Equal(t, BypassViaIP, via)
+	_, ok = g.RegisterBypass("::ffff:10.0.0.1", "")
+	assert.True(t, ok, "IPv4-mapped addresses match IPv4 ranges")
+	_, ok = g.RegisterBypass("2001:db8::1", "")
+	assert.True(t, ok)
+	via, ok = g.RegisterBypass("203.0.113.7", "s3cret")
+	assert.True(t, ok)
+	assert.Equal(t, BypassViaSecret, via)
+
+	_, ok = g.RegisterBypass("203.0.113.7", "")
+	assert.False(t, ok)
+	_, ok = g.RegisterBypass("203.0.113.7", "wrong")
+	assert.False(t, ok)
+
+	var nilGuard *Guard
+	_, ok = nilGuard.RegisterBypass("10.0.0.1", "s3cret")
+	assert.False(t, ok)
+}
+
+func TestGuard_LoginFailures(t *testing.T) {
+	g := &Guard{Verifier: NoopVerifier{}, Provider: ProviderTurnstile, LoginFailures: 3, FailureWindow: time.Minute}
+	ip := "203.0.113.9"
+
+	for range 2 {
+		g.LoginFailed(ip)
+	}
+	assert.False(t, g.RequiredForLogin(ip), "below the threshold")
+
+	g.LoginFailed(ip)
+	assert.True(t, g.RequiredForLogin(ip))
+	assert.False(t, g.RequiredForLogin("203.0.113.10"), "other IPs are not affected")
+	assert.ErrorIs(t, g.Check(context.Background(), g.RequiredForLogin(ip), "", ip), ErrCaptchaRequired)
+	assert.NoError(t, g.Check(context.Background(), g.RequiredForLogin(ip), "token", ip))
+
+	g.LoginSucceeded(ip)
+	assert.False(t, g.RequiredForLogin(ip), "success resets the count")
+
+	// Failures older than the window no longer count
+	g.failures[ip] = failureInfo{count: 5, first: time.Now().Add(-2 * time.Minute)}
+	assert.False(t, g.RequiredForLogin(ip))
+	g.LoginFailed(ip)
+	assert.False(t, g.RequiredForLogin(ip), "an expired window starts over")
+}
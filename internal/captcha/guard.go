@@ -0,0 +1,219 @@
+package captcha
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/config"
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+)
+
+// defaultFailureWindow is used when captcha.failure_window is not set.
+const defaultFailureWindow = 15 * time.Minute
+
+// BypassHeader carries the shared secret that exempts a registration from the challenge (captcha.register_bypass.secret).
+const BypassHeader = "X-Captcha-Bypass"
+
+// Ways a registration can skip the challenge, as returned by Guard.RegisterBypass.
+const (
+	BypassViaIP     = "ip"
+	BypassViaSecret = "secret"
+)
+
+// Widget describes the challenge to render in a form. The zero value means no challenge.
+type Widget struct {
+	Provider  string
+	SiteKey   string
+	ScriptURL string
+}
+
+// Guard decides when a form requires the CAPTCHA and verifies the submitted token.
+// Registration requires it always (when OnRegister is set); login only after LoginFailures
+// failed attempts from the same IP within FailureWindow.
+type Guard struct {
+	Verifier      CaptchaVerifier
+	Provider      string
+	SiteKey       string
+	OnRegister    bool
+	LoginFailures int
+	FailureWindow time.Duration
+	// BypassIPs and BypassSecret exempt trusted callers from the registration challenge
+	BypassIPs    []netip.Prefix
+	BypassSecret string
+
+	mu       sync.Mutex
+	failures map[string]failureInfo
+}
+
+type failureInfo struct {
+	count int
+	first time.Time
+}
+
+// NewGuard builds a Guard from config. An empty or unknown provider yields a disabled guard
+// that never requires the challenge and uses NoopVerifier.
+func NewGuard(cfg config.CaptchaConfig) *Guard {
+	g := &Guard{
+		Verifier:      NoopVerifier{},
+		Provider:      cfg.Provider,
+		SiteKey:       cfg.SiteKey,
+		OnRegister:    cfg.OnRegister,
+		LoginFailures: cfg.LoginFailures,
+		FailureWindow: cfg.FailureWindow,
+		BypassIPs:     parseBypassIPs(cfg.RegisterBypass.IPs),
+		BypassSecret:  cfg.RegisterBypass.Secret,
+	}
+	switch cfg.Provider {
+	case ProviderTurnstile:
+		g.Verifier = NewTurnstileVerifier(cfg.SecretKey)
+	case ProviderReCaptcha:
+		g.Verifier = NewReCaptchaVerifier(cfg.SecretKey)
+	default:
+		g.Provider = ""
+	}
+	return g
+}
+
+// Enabled reports whether a CAPTCHA provider is configured.
+func (g *Guard) Enabled() bool {
+	return g != nil && g.Provider != ""
+}
+
+// Widget returns what the templates need to render the challenge.
+func (g *Guard) Widget() Widget {
+	if !g.Enabled() {
+		return Widget{}
+	}
+	script := turnstileScriptURL
+	if g.Provider == ProviderReCaptcha {
+		script = recaptchaScriptURL
+	}
+	return Widget{Provider: g.Provider, SiteKey: g.SiteKey, ScriptURL: script}
+}
+
+// RequiredForRegister reports whether registration must pass the challenge.
+func (g *Guard) RequiredForRegister() bool {
+	return g.Enabled() && g.OnRegister
+}
+
+// RegisterBypass reports whether a registration from ip, sending secret in BypassHeader, is exempt from
+// the challenge, and why (BypassViaIP or BypassViaSecret). Callers should log every exemption.
+func (g *Guard) RegisterBypass(ip, secret string) (via string, ok bool) {
+	if g == nil {
+		return "", false
+	}
+	if g.BypassSecret != "" && secret != "" && subtle.ConstantTimeCompare([]byte(secret), []byte(g.BypassSecret)) == 1 {
+		return BypassViaSecret, true
+	}
+	if addr, err := netip.ParseAddr(ip); err == nil {
+		addr = addr.Unmap().WithZone("")
+		for _, prefix := range g.BypassIPs {
+			if prefix.Contains(addr) {
+				return BypassViaIP, true
+			}
+		}
+	}
+	return "", false
+}
+
+// RequiredForLogin reports whether a login from ip must pass the challenge.
+func (g *Guard) RequiredForLogin(ip string) bool {
+	if !g.Enabled() || g.LoginFailures <= 0 {
+		return false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	info, ok := g.failures[ip]
+	return ok && !g.expired(info, time.Now()) && info.count >= g.LoginFailures
+}
+
+// LoginFailed counts a failed login from ip.
+func (g *Guard) LoginFailed(ip string) {
+	if !g.Enabled() || g.LoginFailures <= 0 {
+		return
+	}
+	now := time.Now()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.failures == nil {
+		g.failures = make(map[string]failureInfo)
+	}
+	info, ok := g.failures[ip]
+	if !ok || g.expired(info, now) {
+		info = failureInfo{first: now}
+	}
+	info.count++
+	g.failures[ip] = info
+	g.pruneLocked(now)
+}
+
+// LoginSucceeded clears the failure count of ip.
+func (g *Guard) LoginSucceeded(ip string) {
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.failures, ip)
+}
+
+// Check verifies token when required is true. It returns ErrCaptchaRequired or ErrCaptchaInvalid
+// (or a transport error) when the challenge was not passed; a nil Guard or required=false always passes.
+func (g *Guard) Check(ctx context.Context, required bool, token, ip string) error {
+	if !required || !g.Enabled() {
+		return nil
+	}
+	if token == "" {
+		return ErrCaptchaRequired
+	}
+	return g.Verifier.Verify(ctx, token, ip)
+}
+
+func (g *Guard) window() time.Duration {
+	if g.FailureWindow > 0 {
+		return g.FailureWindow
+	}
+	return defaultFailureWindow
+}
+
+func (g *Guard) expired(info failureInfo, now time.Time) bool {
+	return now.Sub(info.first) > g.window()
+}
+
+// pruneLocked drops expired entries so the map doesn't grow with every IP ever seen. Caller holds mu.
+func (g *Guard) pruneLocked(now time.Time) {
+	const pruneThreshold = 1000
+	if len(g.failures) < pruneThreshold {
+		return
+	}
+	for ip, info := range g.failures {
+		if g.expired(info, now) {
+			delete(g.failures, ip)
+		}
+	}
+}
+
+// parseBypassIPs turns IPs and CIDR ranges into prefixes, skipping (and logging) invalid entries;
+// config.Validate reports them at startup.
+func parseBypassIPs(entries []string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			logger.Warn("Entrada inválida em captcha.register_bypass.ips ignorada", "entry", entry)
+			continue
+		}
+		addr = addr.Unmap().WithZone("")
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes
+}
@@ -3,14 +3,54 @@
 package config
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
 	"time"
+	// Zone data for maintenance.timezone on hosts without it (e.g. scratch or distroless images)
+	_ "time/tzdata"
 
 	"github.com/spf13/viper"
 )
 
 type ServerConfig struct {
-	Port int `mapstructure:"port"`
+	Port int       `mapstructure:"port"`
+	TLS  TLSConfig `mapstructure:"tls"`
+	// BasePath mounts the whole app under a URL prefix (e.g. "/myapp" behind a reverse proxy); empty serves from the root
+	BasePath string `mapstructure:"base_path"`
+	// H2C serves cleartext HTTP/2 alongside HTTP/1.1, for when a proxy in front terminates TLS.
+	// With TLS enabled, HTTP/2 is always negotiated via ALPN.
+	H2C bool `mapstructure:"h2c"`
+	// http.Server timeouts; zero keeps the defaults (5s header/read, 10s write, 120s idle).
+	// Routes using middleware.NoTimeoutMiddleware are exempt from read/write deadlines.
+	ReadHeaderTimeout time.Duration `mapstructure:"read_header_timeout"`
+	ReadTimeout       time.Duration `mapstructure:"read_timeout"`
+	WriteTimeout      time.Duration `mapstructure:"write_timeout"`
+	IdleTimeout       time.Duration `mapstructure:"idle_timeout"`
+	// Compression gzips responses for clients that accept it
+	Compression CompressionConfig `mapstructure:"compression"`
+}
+
+// CompressionConfig controla a compressão gzip das respostas (middleware.CompressionMiddleware)
+type CompressionConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// MinSize is the smallest body, in bytes, that gets compressed; zero uses 1024 (middleware.DefaultCompressionMinSize)
+	MinSize int `mapstructure:"min_size"`
+	// SkipContentTypes are sent uncompressed on top of the built-in list of already compressed types
+	// (images, video, audio, fonts, archives, PDF, SSE); "type/" matches the whole family
+	SkipContentTypes []string `mapstructure:"skip_content_types"`
+}
+
+// TLSConfig habilita HTTPS no próprio processo (sem proxy reverso na frente)
+type TLSConfig struct {
+	Enabled  bool   `mapstructure:"enabled"`
+	CertFile string `mapstructure:"cert_file"`
+	KeyFile  string `mapstructure:"key_file"`
+	// MinVersion is "1.2" or "1.3"; empty means 1.2
+	MinVersion string `mapstructure:"min_version"`
+	// CipherSuites lists TLS 1.2 suite names (crypto/tls names); empty uses the built-in ECDHE+AEAD list.
+	// TLS 1.3 suites are not configurable.
+	CipherSuites []string `mapstructure:"cipher_suites"`
 }
 
 type DatabaseConfig struct {
@@ -34,20 +74,432 @@ type EmailConfig struct {
 	FromEmail    string `mapstructure:"from_email"`
 	FromName     string `mapstructure:"from_name"`
 	ResetURL     string `mapstructure:"reset_url"`
+	// EmailChangeURL is the base of the link sent to confirm a new email address (token appended)
+	EmailChangeURL string `mapstructure:"email_change_url"`
+	// CancelDeletionURL is the base of the link that cancels a scheduled account deletion (token appended)
+	CancelDeletionURL string `mapstructure:"cancel_deletion_url"`
+	// BulkRatePerSecond paces admin bulk sends (verification batch) to stay under SMTP throttling; 0 = 2/s
+	BulkRatePerSecond float64 `mapstructure:"bulk_rate_per_second"`
+	// VerificationBatchCap is the most users one verification batch emails; 0 = 500
+	VerificationBatchCap int `mapstructure:"verification_batch_cap"`
+	// SMTPServers are tried in order for each email: the first is the primary, the next ones are fallbacks
+	// used when the previous can't connect or refuses the email. Empty uses smtp_host, smtp_port,
+	// smtp_username and smtp_password as the only server
+	SMTPServers []SMTPServerConfig `mapstructure:"smtp_servers"`
+}
+
+// SMTPServerConfig é um servidor SMTP de email.smtp_servers
+type SMTPServerConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+}
+
+// Servers returns the SMTP servers to try, in order: SMTPServers, or the smtp_host server alone.
+func (c EmailConfig) Servers() []SMTPServerConfig {
+	if len(c.SMTPServers) > 0 {
+		return c.SMTPServers
+	}
+	return []SMTPServerConfig{{Host: c.SMTPHost, Port: c.SMTPPort, Username: c.SMTPUsername, Password: c.SMTPPassword}}
 }
 
 // LogConfig contém configurações de logging
 type LogConfig struct {
 	Level  string `mapstructure:"level"`  // debug, info, warn, error
 	Format string `mapstructure:"format"` // json, text
+	// SlowRequestThreshold logs a slow_request warning for requests that take longer (0 = 2s)
+	SlowRequestThreshold time.Duration `mapstructure:"slow_request_threshold"`
+	// RequestBodies logs the bodies of /auth and /admin posts at debug level, with passwords and tokens
+	// redacted; for debugging forms in development
+	RequestBodies bool `mapstructure:"request_bodies"`
+}
+
+// RegistrationConfig contém configurações do cadastro público
+type RegistrationConfig struct {
+	// Enabled opens public sign-up (/register and POST /auth/register); nil (unset) means enabled.
+	// Admins can still create users when it is false.
+	Enabled *bool `mapstructure:"enabled"`
+	// InviteTTL is how long admin invite links work (0 = 7 days); invites register users even when Enabled is false
+	InviteTTL time.Duration `mapstructure:"invite_ttl"`
+	// EmailAvailabilityCheck enables GET /auth/available?email=... (off by default to avoid email enumeration)
+	EmailAvailabilityCheck bool `mapstructure:"email_availability_check"`
+	// AllowedEmailDomains restricts sign-up and self-service email changes to these domains (empty = any).
+	// Users created by an admin or through an invite are exempt.
+	AllowedEmailDomains []string `mapstructure:"allowed_email_domains"`
+	// GenerateUsername makes the username optional on sign-up: an empty one is generated from the display
+	// name or email (service.UsernameGenerator)
+	GenerateUsername bool `mapstructure:"generate_username"`
+}
+
+// IsEnabled reports whether public sign-up is open (true unless registration.enabled is false).
+func (r RegistrationConfig) IsEnabled() bool {
+	return r.Enabled == nil || *r.Enabled
+}
+
+// LoginConfig contém configurações do fluxo de login
+type LoginConfig struct {
+	// LandingPaths maps role → local path used after login when there is no valid ?next=
+	// (roles not listed fall back to /admin for admins and / for everyone else)
+	LandingPaths map[string]string `mapstructure:"landing_paths"`
+	// RequireVerifiedEmail refuses logins of users whose email is not verified yet
+	RequireVerifiedEmail bool `mapstructure:"require_verified_email"`
+	// VerifiedEmailGate lets unverified users log in but keeps them out of protected routes until they verify
+	VerifiedEmailGate VerifiedEmailGateConfig `mapstructure:"verified_email_gate"`
+	// LockoutEmail warns the account owner (with a password reset link) when failed logins lock the account
+	LockoutEmail bool `mapstructure:"lockout_email"`
+	// KeepLockoutOnPasswordChange leaves a lock from failed logins in place after the owner resets or changes
+	// the password (by default the new password lifts it)
+	KeepLockoutOnPasswordChange bool `mapstructure:"keep_lockout_on_password_change"`
+	// NewCountryChallenge asks for an emailed code when a user logs in from a country they never logged in from
+	NewCountryChallenge NewCountryChallengeConfig `mapstructure:"new_country_challenge"`
+	// ConcurrentLoginNotice tells the user about a login made while other sessions of the account are open
+	ConcurrentLoginNotice ConcurrentLoginNoticeConfig `mapstructure:"concurrent_login_notice"`
+	// RememberUsername pre-fills the login form with the username of the browser's last successful login
+	RememberUsername RememberUsernameConfig `mapstructure:"remember_username"`
+}
+
+// RememberUsernameConfig controla o cookie que guarda o último usuário que entrou no navegador
+type RememberUsernameConfig struct {
+	// Enabled sets the cookie on login and reads it on the login page; off forgets usernames remembered before
+	Enabled bool `mapstructure:"enabled"`
+	// MaxAge is how long the username is remembered; zero uses 30 days (middleware.DefaultRememberedUsernameMaxAge)
+	MaxAge time.Duration `mapstructure:"max_age"`
+	// ClearOnLogout forgets the username when the user logs out, for devices shared with other people
+	ClearOnLogout bool `mapstructure:"clear_on_logout"`
+}
+
+// ConcurrentLoginNoticeConfig controla o aviso de login feito enquanto outras sessões da conta estão abertas
+type ConcurrentLoginNoticeConfig struct {
+	// Email warns the account owner by email (an optional email, which users can turn off in their profile)
+	Email bool `mapstructure:"email"`
+	// Banner shows the account's other open sessions a notice on their next page load, until dismissed
+	Banner bool `mapstructure:"banner"`
+}
+
+// NewCountryChallengeConfig controla a verificação extra de logins vindos de um país novo para o usuário
+type NewCountryChallengeConfig struct {
+	// Enabled turns the challenge on; the country must come from a trusted proxy (CountryHeader)
+	Enabled bool `mapstructure:"enabled"`
+	// CountryHeader is the request header with the client's ISO country code, set by the proxy or CDN
+	// in front of the app (default: CF-IPCountry). Only enable this when clients can't set it themselves.
+	CountryHeader string `mapstructure:"country_header"`
+	// CodeTTL is how long the emailed code can be used (default: 10m)
+	CodeTTL time.Duration `mapstructure:"code_ttl"`
+	// RememberDevice is how long a browser the user chose to remember at the code step skips it; zero
+	// turns the option off. Needs security.cookie_secret, which signs the device cookie.
+	RememberDevice time.Duration `mapstructure:"remember_device"`
+}
+
+// VerifiedEmailGateConfig controla o bloqueio de rotas protegidas para usuários com email não verificado
+type VerifiedEmailGateConfig struct {
+	// Enabled sends unverified users to /verify-email (403 for API clients); logout, session, /api/me and the
+	// verification routes stay open
+	Enabled bool `mapstructure:"enabled"`
+	// ExemptAdmins lets admins through even with an unverified email
+	ExemptAdmins bool `mapstructure:"exempt_admins"`
+}
+
+// SessionConfig controla a duração das sessões e o aviso de expiração no navegador
+type SessionConfig struct {
+	// IdleTimeout logs out sessions without activity for this long (0 keeps the 30-day sliding session)
+	IdleTimeout time.Duration `mapstructure:"idle_timeout"`
+	// MaxLifetime is the absolute limit since login; activity and keep-alives never go past it and older
+	// sessions are refused (0 = auth.DefaultMaxSessionLifetime, 90 days)
+	MaxLifetime time.Duration `mapstructure:"max_lifetime"`
+	// WarnBefore is how long before expiry the browser offers to extend the session (0 disables the prompt)
+	WarnBefore time.Duration `mapstructure:"warn_before"`
+	// KeepOnDeactivate leaves a deactivated user's sessions alive until their next request is refused,
+	// instead of ending them as soon as an admin deactivates the account
+	KeepOnDeactivate bool `mapstructure:"keep_on_deactivate"`
+	// LogoutOnRoleChange ends a user's sessions when an admin changes their role, forcing a new login
+	// (the role is re-read on every request either way; this makes the switch explicit to the user)
+	LogoutOnRoleChange bool `mapstructure:"logout_on_role_change"`
+	// MaxInFlight caps the simultaneous /api requests of one session, answering 429 beyond it (0 = no limit)
+	MaxInFlight int `mapstructure:"max_in_flight"`
+	// CookiePartitioned sends the session cookie with Partitioned and SameSite=None (CHIPS), for apps
+	// embedded in a cross-site iframe; off keeps the browser's default SameSite
+	CookiePartitioned bool `mapstructure:"cookie_partitioned"`
+}
+
+// OriginCheckConfig controla a verificação de Origin/Referer em requisições que alteram estado (CSRF leve)
+type OriginCheckConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// TrustedOrigins lists allowed "scheme://host[:port]" values; empty means same host as the request
+	TrustedOrigins []string `mapstructure:"trusted_origins"`
+	// RequireHeader rejects state-changing requests that carry neither Origin nor Referer
+	RequireHeader bool `mapstructure:"require_header"`
+}
+
+// SecurityConfig contém configurações de proteção das requisições
+type SecurityConfig struct {
+	OriginCheck OriginCheckConfig `mapstructure:"origin_check"`
+	// CookieSecret signs (and encrypts) small client-side values such as flash messages; kept apart from the JWT secret.
+	// At least 32 bytes. Set COOKIE_SECRET in production.
+	CookieSecret string `mapstructure:"cookie_secret"`
+	// IPDenylist lists IPs and CIDR ranges refused with 403 before any other processing. Admins can add more
+	// at runtime (stored in the database); these fixed entries can't be removed from the admin page.
+	IPDenylist []string `mapstructure:"ip_denylist"`
+}
+
+// InactivityConfig controla a desativação automática de contas sem login
+type InactivityConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Threshold is how long without login before an account is deactivated (e.g. 8760h = 1 year)
+	Threshold time.Duration `mapstructure:"threshold"`
+	// NotifyEmail sends the user a notice when the account is deactivated
+	NotifyEmail bool `mapstructure:"notify_email"`
+}
+
+// RetentionConfig defines how long the janitor keeps each kind of record; each one is independent
+type RetentionConfig struct {
+	// Sessions is how long expired sessions are kept after expiring (0 = deleted as soon as they expire)
+	Sessions time.Duration `mapstructure:"sessions"`
+	// LoginAttempts deletes login attempt records older than this (0 = kept forever)
+	LoginAttempts time.Duration `mapstructure:"login_attempts"`
+	// AuditLogs deletes audit log entries older than this (0 = kept forever)
+	AuditLogs time.Duration `mapstructure:"audit_logs"`
+}
+
+// JobsConfig contém configurações das tarefas periódicas
+type JobsConfig struct {
+	// Interval between job runs (janitor and inactivity check); defaults to 1h when zero
+	Interval   time.Duration    `mapstructure:"interval"`
+	Inactivity InactivityConfig `mapstructure:"inactivity"`
+	Retention  RetentionConfig  `mapstructure:"retention"`
+	// Deprecated: use Retention.LoginAttempts. Still honored (in days) while that is zero
+	LoginAttemptRetentionDays int `mapstructure:"login_attempt_retention_days"`
+}
+
+// LoginAttemptRetention returns Retention.LoginAttempts, falling back to the deprecated LoginAttemptRetentionDays.
+func (j JobsConfig) LoginAttemptRetention() time.Duration {
+	if j.Retention.LoginAttempts > 0 {
+		return j.Retention.LoginAttempts
+	}
+	return time.Duration(j.LoginAttemptRetentionDays) * 24 * time.Hour
+}
+
+// AvatarConfig controla a exibição de avatares (URL salva ou Gravatar)
+type AvatarConfig struct {
+	// Enabled shows avatars in the navbar and admin list; off by default since Gravatar leaks email hashes to a third party
+	Enabled bool `mapstructure:"enabled"`
+	// Hosts a stored avatar_url may point to (e.g. "cdn.example.com"); the page CSP allows images only from
+	// these and Gravatar, so a stored URL on any other host shows the Gravatar instead
+	Hosts []string `mapstructure:"hosts"`
+}
+
+// CaptchaConfig configura o desafio CAPTCHA (Cloudflare Turnstile ou Google reCAPTCHA v2) no login e no registro
+type CaptchaConfig struct {
+	// Provider is "turnstile" or "recaptcha"; empty disables the challenge everywhere
+	Provider  string `mapstructure:"provider"`
+	SiteKey   string `mapstructure:"site_key"`
+	SecretKey string `mapstructure:"secret_key"`
+	// OnRegister requires the challenge on every registration
+	OnRegister bool `mapstructure:"on_register"`
+	// LoginFailures is how many failed logins from one IP within FailureWindow make login require the challenge; 0 never does
+	LoginFailures int           `mapstructure:"login_failures"`
+	FailureWindow time.Duration `mapstructure:"failure_window"`
+	// RegisterBypass lets trusted internal callers register without the challenge
+	RegisterBypass CaptchaBypassConfig `mapstructure:"register_bypass"`
+}
+
+// CaptchaBypassConfig exempts registrations from the CAPTCHA (e.g. provisioning from an internal tool)
+type CaptchaBypassConfig struct {
+	// IPs and CIDR ranges whose registrations skip the challenge
+	IPs []string `mapstructure:"ips"`
+	// Secret, when set, exempts requests carrying it in the X-Captcha-Bypass header
+	Secret string `mapstructure:"secret"`
+}
+
+// PasswordConfig contém regras de troca de senha
+type PasswordConfig struct {
+	// HistorySize is how many previous passwords are remembered and can't be reused; 0 only blocks the current one
+	HistorySize int `mapstructure:"history_size"`
+	// ResetBinding ties reset links to where they were requested: "ip" (same client IP) or "cookie"
+	// (same browser, via a device cookie); empty leaves links usable from any device
+	ResetBinding string `mapstructure:"reset_binding"`
+	// ResetCooldown is the minimum time between reset emails to one account; requests inside it are
+	// silently ignored (0 = no limit besides the per-IP rate limiter)
+	ResetCooldown time.Duration `mapstructure:"reset_cooldown"`
+	// MaxAge forces a password change (must_change_password) once a password is this old; 0 never expires passwords
+	MaxAge time.Duration `mapstructure:"max_age"`
+	// AllowPersonalInfo accepts passwords equal to the email's local part or to the display name (refused by default)
+	AllowPersonalInfo bool `mapstructure:"allow_personal_info"`
+	// ChangeConfirmation emails the owner after a password reset or change ("if this wasn't you...");
+	// nil (unset) means on
+	ChangeConfirmation *bool `mapstructure:"change_confirmation"`
+}
+
+// SendsChangeConfirmation reports whether password changes are confirmed by email (true unless
+// password.change_confirmation is false).
+func (p PasswordConfig) SendsChangeConfirmation() bool {
+	return p.ChangeConfirmation == nil || *p.ChangeConfirmation
+}
+
+// TracingConfig configura o tracing OpenTelemetry (desligado sem endpoint)
+type TracingConfig struct {
+	// Endpoint is the OTLP/HTTP collector URL (e.g. http://localhost:4318); empty keeps tracing a no-op
+	Endpoint    string `mapstructure:"endpoint"`
+	ServiceName string `mapstructure:"service_name"`
+}
+
+// APIConfig controla a formatação das respostas JSON da API e os limites de requisições
+type APIConfig struct {
+	// PrettyJSON indents every API response (handy in development; keep it off in production)
+	PrettyJSON bool `mapstructure:"pretty_json"`
+	// AllowPrettyParam lets a request ask for indented JSON with ?pretty=1. Development only
+	AllowPrettyParam bool `mapstructure:"allow_pretty_param"`
+	// RateLimit sets the /api limits per client IP, with its own tier for requests with a valid session
+	RateLimit APIRateLimitConfig `mapstructure:"rate_limit"`
+}
+
+// APIRateLimitConfig separa os limites de requisições da API de anônimos e de usuários autenticados
+type APIRateLimitConfig struct {
+	// Anonymous applies to requests without a valid session (default 10/s, burst 20, the former single API limit)
+	Anonymous RateLimitTier `mapstructure:"anonymous"`
+	// Authenticated applies to requests with a valid session
+	Authenticated RateLimitTier `mapstructure:"authenticated"`
+}
+
+// RateLimitTier is a token bucket: RatePerSecond requests per second on average, in bursts of up to
+// Burst. Zero fields use the router's defaults.
+type RateLimitTier struct {
+	RatePerSecond float64 `mapstructure:"rate_per_second"`
+	Burst         int     `mapstructure:"burst"`
+}
+
+// WebAuthnConfig habilita passkeys (WebAuthn) como alternativa ao login por senha
+type WebAuthnConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// RPID is the relying party ID: the site's domain, without scheme or port (e.g. example.com)
+	RPID string `mapstructure:"rp_id"`
+	// RPDisplayName is shown by the authenticator; empty uses app.name
+	RPDisplayName string `mapstructure:"rp_display_name"`
+	// RPOrigins are the full origins the ceremonies may run on (e.g. https://example.com)
+	RPOrigins []string `mapstructure:"rp_origins"`
+	// ChallengeTTL is how long a begun registration or login can be finished (0 = 5 minutes)
+	ChallengeTTL time.Duration `mapstructure:"challenge_ttl"`
+}
+
+// TermsConfig exige aceitar os termos de uso; Version vazio desliga o recurso
+type TermsConfig struct {
+	// Version names the current terms (e.g. "2025-01"). Registration requires accepting it, and users who
+	// accepted another version must accept again before reaching protected routes
+	Version string `mapstructure:"version"`
+	// URL is the page with the terms, linked from the registration checkbox and the re-acceptance page
+	URL string `mapstructure:"url"`
+}
+
+// AccountConfig controla o que o usuário faz com a própria conta
+type AccountConfig struct {
+	// DeletionGracePeriod keeps an account whose owner asked to delete it deactivated but recoverable for
+	// this long (cancel link by email, or a prompt on login); the janitor deletes it afterwards.
+	// 0 deletes it right away.
+	DeletionGracePeriod time.Duration `mapstructure:"deletion_grace_period"`
+	// RecoveryCodeTTL is how long a recovery code created by an admin (for users who lost access to their
+	// email) can be used at /recover; 0 uses one hour
+	RecoveryCodeTTL time.Duration `mapstructure:"recovery_code_ttl"`
+}
+
+// AdminConfig controla o painel admin
+type AdminConfig struct {
+	// ReadOnly keeps the admin views available but rejects every admin mutation (role, active, create,
+	// delete...) without touching the data, e.g. during an audit or a data migration
+	ReadOnly bool `mapstructure:"read_only"`
+	// PerPage is the page size of the admin lists for users who never picked one with ?per_page=
+	// (the last one picked is kept in their preferences); 0 keeps each list's own default
+	PerPage int `mapstructure:"per_page"`
+	// MinAccountAgeForAdmin is how long an account must exist before an admin can change its role to admin;
+	// 0 = no minimum. The admin panel and API then refuse to create admins directly; the seed and setup
+	// wizard admins are not affected
+	MinAccountAgeForAdmin time.Duration `mapstructure:"min_account_age_for_admin"`
+}
+
+// MaintenanceLayout is how maintenance.start and maintenance.end are written, in maintenance.timezone.
+const MaintenanceLayout = "2006-01-02 15:04"
+
+// MaintenanceConfig agenda uma janela de manutenção; Start e End vazios desligam o recurso
+type MaintenanceConfig struct {
+	// Start and End bound the window (MaintenanceLayout, e.g. "2025-03-01 02:00"); inside it the app answers 503
+	Start string `mapstructure:"start"`
+	End   string `mapstructure:"end"`
+	// Timezone is the IANA zone Start and End are in (e.g. "America/Sao_Paulo"); defaults to UTC
+	Timezone string `mapstructure:"timezone"`
+	// AnnounceBefore is how long before Start every page shows a banner announcing the window
+	AnnounceBefore time.Duration `mapstructure:"announce_before"`
+}
+
+// Window parses Start and End in Timezone. Both are zero when no window is scheduled.
+func (m MaintenanceConfig) Window() (start, end time.Time, err error) {
+	if m.Start == "" && m.End == "" {
+		return time.Time{}, time.Time{}, nil
+	}
+	loc, err := time.LoadLocation(cmp.Or(m.Timezone, "UTC"))
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("maintenance.timezone inválido: %w", err)
+	}
+	if start, err = time.ParseInLocation(MaintenanceLayout, m.Start, loc); err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("maintenance.start deve estar no formato %q", MaintenanceLayout)
+	}
+	if end, err = time.ParseInLocation(MaintenanceLayout, m.End, loc); err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("maintenance.end deve estar no formato %q", MaintenanceLayout)
+	}
+	if !end.After(start) {
+		return time.Time{}, time.Time{}, errors.New("maintenance.end deve ser depois de maintenance.start")
+	}
+	return start, end, nil
+}
+
+// SeedUser é uma conta criada na inicialização quando ainda não existe
+type SeedUser struct {
+	Username    string `mapstructure:"username"`
+	Email       string `mapstructure:"email"`
+	DisplayName string `mapstructure:"display_name"` // empty uses the username
+	Role        string `mapstructure:"role"`         // "admin" or "user" (anything else becomes "user")
+	// Password is the initial password; empty generates a random one, logged once, that must be changed at first login
+	Password string `mapstructure:"password"`
+}
+
+// AppConfig contém a identidade visual da aplicação; campos vazios usam os valores do GoHTMX
+type AppConfig struct {
+	Name    string `mapstructure:"name"`
+	Tagline string `mapstructure:"tagline"`  // subtitle in the home page title
+	LogoURL string `mapstructure:"logo_url"` // replaces the name in the navbar
+	// FaviconURL replaces the bundled favicon set under /static
+	FaviconURL   string `mapstructure:"favicon_url"`
+	PrimaryColor string `mapstructure:"primary_color"` // browser theme-color
+}
+
+// SeedConfig lista os usuários iniciais
+type SeedConfig struct {
+	// Users are created at startup when no account has the same username or email; existing accounts are never changed.
+	// Empty seeds the default admin (admin/admin)
+	Users []SeedUser `mapstructure:"users"`
 }
 
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	JWT      JWTConfig      `mapstructure:"jwt"`
-	Email    EmailConfig    `mapstructure:"email"`
-	Log      LogConfig      `mapstructure:"log"`
+	App          AppConfig          `mapstructure:"app"`
+	Server       ServerConfig       `mapstructure:"server"`
+	Database     DatabaseConfig     `mapstructure:"database"`
+	JWT          JWTConfig          `mapstructure:"jwt"`
+	Email        EmailConfig        `mapstructure:"email"`
+	Log          LogConfig          `mapstructure:"log"`
+	Registration RegistrationConfig `mapstructure:"registration"`
+	Login        LoginConfig        `mapstructure:"login"`
+	Session      SessionConfig      `mapstructure:"session"`
+	Security     SecurityConfig     `mapstructure:"security"`
+	Jobs         JobsConfig         `mapstructure:"jobs"`
+	Avatar       AvatarConfig       `mapstructure:"avatar"`
+	Captcha      CaptchaConfig      `mapstructure:"captcha"`
+	Password     PasswordConfig     `mapstructure:"password"`
+	Tracing      TracingConfig      `mapstructure:"tracing"`
+	API          APIConfig          `mapstructure:"api"`
+	Seed         SeedConfig         `mapstructure:"seed"`
+	WebAuthn     WebAuthnConfig     `mapstructure:"webauthn"`
+	Terms        TermsConfig        `mapstructure:"terms"`
+	Admin        AdminConfig        `mapstructure:"admin"`
+	Account      AccountConfig      `mapstructure:"account"`
+	Maintenance  MaintenanceConfig  `mapstructure:"maintenance"`
 }
 
 var cfg *Config
@@ -80,6 +532,10 @@ func LoadConfigFromPath(configPath string) (*Config, error) {
 	// DATABASE_DSN env overrides config file when set
 	viper.AutomaticEnv()
 	_ = viper.BindEnv("database.dsn", "DATABASE_DSN")
+	_ = viper.BindEnv("captcha.secret_key", "CAPTCHA_SECRET_KEY")
+	_ = viper.BindEnv("captcha.register_bypass.secret", "CAPTCHA_BYPASS_SECRET")
+	_ = viper.BindEnv("security.cookie_secret", "COOKIE_SECRET")
+	_ = viper.BindEnv("tracing.endpoint", "OTEL_EXPORTER_OTLP_ENDPOINT")
 
 	cfg = &Config{}
 	if err := viper.Unmarshal(cfg); err != nil {
@@ -92,3 +92,138 @@ func TestGetConfigBeforeLoad(t *testing.T) {
 
 	assert.Nil(t, GetConfig())
 }
+
+func TestConfig_Redacted(t *testing.T) {
+	c := Config{
+		Server:   ServerConfig{Port: 8080},
+		Database: DatabaseConfig{DSN: "host=db user=app password=s3cret dbname=app sslmode=disable"},
+		JWT:      JWTConfig{SecretKey: "jwt-secret", AccessTokenTTL: 15 * time.Minute},
+		Email: EmailConfig{
+			SMTPHost:     "smtp.example.com",
+			SMTPUsername: "mailer",
+			SMTPPassword: "smtp-secret",
+			SMTPServers:  []SMTPServerConfig{{Host: "fallback.example.com", Port: 587, Password: "fallback-secret"}},
+		},
+		Security: SecurityConfig{CookieSecret: "cookie-secret"},
+		Seed:     SeedConfig{Users: []SeedUser{{Username: "admin", Password: "seed-secret"}}},
+	}
+
+	r := c.Redacted()
+
+	assert.Equal(t, "host=db user=app password=*** dbname=app sslmode=disable", r.Database.DSN)
+	assert.Equal(t, "***", r.JWT.SecretKey)
+	assert.Equal(t, "***", r.Email.SMTPPassword)
+	assert.Equal(t, "***", r.Email.SMTPServers[0].Password)
+	assert.Equal(t, "***", r.Security.CookieSecret)
+	assert.Equal(t, "***", r.Seed.Users[0].Password)
+	assert.Empty(t, r.Captcha.SecretKey, "an unset secret stays empty")
+
+	assert.Equal(t, 8080, r.Server.Port)
+	assert.Equal(t, 15*time.Minute, r.JWT.AccessTokenTTL)
+	assert.Equal(t, "smtp.example.com", r.Email.SMTPHost)
+	assert.Equal(t, "mailer", r.Email.SMTPUsername)
+	assert.Equal(t, "fallback.example.com", r.Email.SMTPServers[0].Host)
+	assert.Equal(t, "admin", r.Seed.Users[0].Username)
+
+	assert.Equal(t, "smtp-secret", c.Email.SMTPPassword, "the original is left untouched")
+	assert.Equal(t, "fallback-secret", c.Email.SMTPServers[0].Password)
+	assert.Equal(t, "seed-secret", c.Seed.Users[0].Password)
+
+	assert.Equal(t, "postgres://app:xxxxx@db:5432/app", RedactDSN("postgres://app:s3cret@db:5432/app"))
+	assert.Equal(t, "host=db password=*** user=app", RedactDSN("host=db password='s3 cret' user=app"))
+	assert.Equal(t, "test.db", RedactDSN("test.db"))
+}
+
+func TestConfig_Features(t *testing.T) {
+	var unloaded *Config
+	assert.Equal(t, Features{Registration: true}, unloaded.Features(), "registration is on by default")
+
+	disabled := false
+	cfg := &Config{
+		Registration: RegistrationConfig{Enabled: &disabled, GenerateUsername: true},
+		WebAuthn:     WebAuthnConfig{Enabled: true},
+		Captcha:      CaptchaConfig{Provider: "turnstile"},
+		Login:        LoginConfig{NewCountryChallenge: NewCountryChallengeConfig{Enabled: true}},
+		Terms:        TermsConfig{Version: "2025-01"},
+		Maintenance:  MaintenanceConfig{Start: "2025-03-01 02:00", End: "2025-03-01 04:00"},
+		Admin:        AdminConfig{ReadOnly: true},
+	}
+	assert.Equal(t, Features{
+		UsernameGeneration: true, Passkeys: true, Captcha: true,
+		NewCountryChallenge: true, Terms: true, Maintenance: true, AdminReadOnly: true,
+	}, cfg.Features())
+}
+
+func TestValidate(t *testing.T) {
+	dir, cleanup := setupTestConfigDir(t)
+	defer cleanup()
+
+	c, err := LoadConfigFromPath(dir)
+	require.NoError(t, err)
+	assert.NoError(t, c.Validate())
+
+	c.Server.Port = 70000
+	c.Database.DSN = ""
+	c.Log.Level = "verbose"
+	c.Security.CookieSecret = "short"
+	c.Captcha.Provider = "hcaptcha"
+	c.Password.ResetBinding = "device"
+	c.Tracing.Endpoint = "localhost:4318"
+	c.Seed.Users = []SeedUser{{Username: "admin"}}
+	c.Security.IPDenylist = []string{"10.0.0.0/8", "2001:db8::1", "10.0.0.0/40"}
+	c.Jobs.Retention.AuditLogs = -time.Hour
+	c.Captcha.RegisterBypass.IPs = []string{"10.0.0.0/8", "intranet"}
+	c.WebAuthn.Enabled = true
+	c.Terms.Version = "2025-01"
+	c.Admin.PerPage = 500
+	c.Maintenance.Start = "2025-03-01 02:00"
+	c.Login.NewCountryChallenge.CodeTTL = -time.Minute
+	c.Login.NewCountryChallenge.RememberDevice = -time.Hour
+	c.Account.DeletionGracePeriod = -time.Hour
+	c.API.RateLimit.Authenticated.Burst = -1
+	c.Server.Compression.MinSize = -1
+	c.Login.RememberUsername.MaxAge = -time.Hour
+	c.Avatar.Hosts = []string{"cdn.example.com", "https://img.example.com"}
+
+	err = c.Validate()
+	require.Error(t, err)
+	for _, key := range []string{"server.port", "database.dsn", "log.level", "security.cookie_secret",
+		"captcha.provider", "password.reset_binding", "tracing.endpoint", "seed.users[0]", `"10.0.0.0/40"`, "jobs.retention", `"intranet"`, "webauthn", "terms.url",
+		"admin.per_page", "maintenance.end", "login.new_country_challenge.code_ttl", "login.new_country_challenge.remember_device", "account.deletion_grace_period", "api.rate_limit",
+		"server.compression.min_size", "login.remember_username.max_age", `"https://img.example.com"`} {
+		assert.Contains(t, err.Error(), key)
+	}
+
+	c, err = LoadConfigFromPath(dir)
+	require.NoError(t, err)
+	c.Login.NewCountryChallenge.Enabled = true
+	c.Login.NewCountryChallenge.RememberDevice = 720 * time.Hour
+	c.Security.CookieSecret = ""
+	err = c.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "login.new_country_challenge.remember_device exige security.cookie_secret")
+}
+
+func TestMaintenanceConfig_Window(t *testing.T) {
+	start, end, err := MaintenanceConfig{}.Window()
+	require.NoError(t, err)
+	assert.True(t, start.IsZero() && end.IsZero(), "no window scheduled")
+
+	start, end, err = MaintenanceConfig{Start: "2025-03-01 02:00", End: "2025-03-01 04:30", Timezone: "America/Sao_Paulo"}.Window()
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 3, 1, 5, 0, 0, 0, time.UTC), start.UTC(), "São Paulo is UTC-3")
+	assert.Equal(t, time.Date(2025, 3, 1, 7, 30, 0, 0, time.UTC), end.UTC())
+
+	start, _, err = MaintenanceConfig{Start: "2025-03-01 02:00", End: "2025-03-01 04:30"}.Window()
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 3, 1, 2, 0, 0, 0, time.UTC), start, "defaults to UTC")
+
+	for _, bad := range []MaintenanceConfig{
+		{Start: "2025-03-01 02:00", End: "2025-03-01 04:30", Timezone: "Mars/Olympus"},
+		{Start: "2025-03-01T02:00:00Z", End: "2025-03-01 04:30"},
+		{Start: "2025-03-01 04:30", End: "2025-03-01 02:00"},
+	} {
+		_, _, err := bad.Window()
+		assert.Error(t, err, "%+v", bad)
+	}
+}
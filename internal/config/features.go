@@ -0,0 +1,51 @@
+package config
+
+// Features são os recursos que a configuração liga ou desliga, reunidos num só lugar para a aplicação
+// (handlers e templates) e para os clientes (GET /api/features)
+//
+// Each flag is derived from the section that configures the feature, so there is a single source of
+// truth per toggle. Every flag is public: nothing here is a secret, and the client uses them to show
+// only what the server will accept.
+type Features struct {
+	// Registration is config registration.enabled; when false only invited users can sign up
+	Registration bool `json:"registration"`
+	// UsernameGeneration is config registration.generate_username; sign-ups may leave the username empty
+	UsernameGeneration bool `json:"username_generation"`
+	// EmailAvailabilityCheck is config registration.email_availability_check
+	EmailAvailabilityCheck bool `json:"email_availability_check"`
+	// Passkeys is config webauthn.enabled
+	Passkeys bool `json:"passkeys"`
+	// Captcha is set when config captcha.provider is; login and registration may require the challenge
+	Captcha bool `json:"captcha"`
+	// Avatars is config avatar.enabled
+	Avatars bool `json:"avatars"`
+	// NewCountryChallenge is config login.new_country_challenge.enabled; logins from a new country ask for
+	// an emailed code (or a backup code)
+	NewCountryChallenge bool `json:"new_country_challenge"`
+	// Terms is set when config terms.version is; registration requires accepting the terms of use
+	Terms bool `json:"terms"`
+	// Maintenance is set when config maintenance schedules a window (start and end)
+	Maintenance bool `json:"maintenance"`
+	// AdminReadOnly is config admin.read_only; the admin panel only shows data
+	AdminReadOnly bool `json:"admin_read_only"`
+}
+
+// Features returns the feature flags of c. A nil c (no config loaded) has only the features that are
+// on by default.
+func (c *Config) Features() Features {
+	if c == nil {
+		return Features{Registration: true}
+	}
+	return Features{
+		Registration:           c.Registration.IsEnabled(),
+		UsernameGeneration:     c.Registration.GenerateUsername,
+		EmailAvailabilityCheck: c.Registration.EmailAvailabilityCheck,
+		Passkeys:               c.WebAuthn.Enabled,
+		Captcha:                c.Captcha.Provider != "",
+		Avatars:                c.Avatar.Enabled,
+		NewCountryChallenge:    c.Login.NewCountryChallenge.Enabled,
+		Terms:                  c.Terms.Version != "",
+		Maintenance:            c.Maintenance.Start != "" && c.Maintenance.End != "",
+		AdminReadOnly:          c.Admin.ReadOnly,
+	}
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// minCookieSecretLength mirrors securecookie.MinSecretLength (config can't import it).
+const minCookieSecretLength = 32
+
+// Validate checks the settings that would otherwise only fail at runtime (or silently fall back to a
+// default) and returns every problem found, joined. Files such as TLS certificates are not opened here.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Port >= 0 && c.Server.Port <= 65535, "server.port fora do intervalo 0-65535: %d", c.Server.Port)
+	if c.Server.TLS.Enabled {
+		check(c.Server.TLS.CertFile != "" && c.Server.TLS.KeyFile != "", "server.tls habilitado sem cert_file e key_file")
+	}
+	check(c.Server.Compression.MinSize >= 0, "server.compression.min_size não pode ser negativo")
+	check(c.Database.DSN != "", "database.dsn não definido (use DATABASE_DSN em produção)")
+	check(slices.Contains([]string{"", "debug", "info", "warn", "error"}, c.Log.Level), "log.level inválido: %q", c.Log.Level)
+	check(slices.Contains([]string{"", "json", "text"}, c.Log.Format), "log.format inválido: %q", c.Log.Format)
+	check(c.Log.SlowRequestThreshold >= 0, "log.slow_request_threshold não pode ser negativo")
+
+	check(c.Session.IdleTimeout >= 0 && c.Session.MaxLifetime >= 0 && c.Session.WarnBefore >= 0,
+		"session.idle_timeout, max_lifetime e warn_before não podem ser negativos")
+	check(c.Session.MaxInFlight >= 0, "session.max_in_flight não pode ser negativo")
+	anonymous, authenticated := c.API.RateLimit.Anonymous, c.API.RateLimit.Authenticated
+	check(anonymous.RatePerSecond >= 0 && anonymous.Burst >= 0 && authenticated.RatePerSecond >= 0 && authenticated.Burst >= 0,
+		"api.rate_limit: rate_per_second e burst não podem ser negativos")
+	retention := c.Jobs.Retention
+	check(retention.Sessions >= 0 && retention.LoginAttempts >= 0 && retention.AuditLogs >= 0 && c.Jobs.LoginAttemptRetentionDays >= 0,
+		"jobs.retention: sessions, login_attempts e audit_logs não podem ser negativos")
+	check(c.Registration.InviteTTL >= 0, "registration.invite_ttl não pode ser negativo")
+	if secret := c.Security.CookieSecret; secret != "" {
+		check(len(secret) >= minCookieSecretLength, "security.cookie_secret deve ter pelo menos %d bytes", minCookieSecretLength)
+	}
+	for _, entry := range c.Security.IPDenylist {
+		check(validIPOrPrefix(entry), "security.ip_denylist: IP ou faixa CIDR inválida: %q", entry)
+	}
+
+	switch c.Captcha.Provider {
+	case "":
+	case "turnstile", "recaptcha":
+		check(c.Captcha.SiteKey != "" && c.Captcha.SecretKey != "", "captcha.%s exige site_key e secret_key", c.Captcha.Provider)
+	default:
+		check(false, "captcha.provider inválido: %q (use 'turnstile' ou 'recaptcha')", c.Captcha.Provider)
+	}
+	for _, host := range c.Avatar.Hosts {
+		// Each host goes into the Content-Security-Policy as is
+		check(host != "" && !strings.ContainsAny(host, "/:;*' \t"), "avatar.hosts: host inválido: %q (use só o nome, ex.: cdn.example.com)", host)
+	}
+	for _, entry := range c.Captcha.RegisterBypass.IPs {
+		check(validIPOrPrefix(entry), "captcha.register_bypass.ips: IP ou faixa CIDR inválida: %q", entry)
+	}
+	if secret := c.Captcha.RegisterBypass.Secret; secret != "" {
+		check(len(secret) >= minCookieSecretLength, "captcha.register_bypass.secret deve ter pelo menos %d bytes", minCookieSecretLength)
+	}
+	check(slices.Contains([]string{"", "ip", "cookie"}, c.Password.ResetBinding),
+		"password.reset_binding inválido: %q (use 'ip' ou 'cookie')", c.Password.ResetBinding)
+	check(c.Password.HistorySize >= 0, "password.history_size não pode ser negativo")
+	check(c.Password.MaxAge >= 0, "password.max_age não pode ser negativo")
+	check(c.Password.ResetCooldown >= 0, "password.reset_cooldown não pode ser negativo")
+	check(c.Login.NewCountryChallenge.CodeTTL >= 0, "login.new_country_challenge.code_ttl não pode ser negativo")
+	check(c.Login.NewCountryChallenge.RememberDevice >= 0, "login.new_country_challenge.remember_device não pode ser negativo")
+	if challenge := c.Login.NewCountryChallenge; challenge.Enabled && challenge.RememberDevice > 0 {
+		check(c.Security.CookieSecret != "", "login.new_country_challenge.remember_device exige security.cookie_secret")
+	}
+	check(c.Login.RememberUsername.MaxAge >= 0, "login.remember_username.max_age não pode ser negativo")
+	check(c.Email.BulkRatePerSecond >= 0 && c.Email.VerificationBatchCap >= 0,
+		"email.bulk_rate_per_second e verification_batch_cap não podem ser negativos")
+
+	if endpoint := c.Tracing.Endpoint; endpoint != "" {
+		u, err := url.Parse(endpoint)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+			"tracing.endpoint deve ser uma URL http(s): %q", endpoint)
+	}
+	if c.WebAuthn.Enabled {
+		check(c.WebAuthn.RPID != "" && len(c.WebAuthn.RPOrigins) > 0, "webauthn habilitado sem rp_id e rp_origins")
+	}
+	check(c.WebAuthn.ChallengeTTL >= 0, "webauthn.challenge_ttl não pode ser negativo")
+	if c.Terms.Version != "" {
+		check(c.Terms.URL != "", "terms.version definido sem terms.url")
+	}
+	check(c.Account.DeletionGracePeriod >= 0, "account.deletion_grace_period não pode ser negativo")
+	check(c.Account.RecoveryCodeTTL >= 0, "account.recovery_code_ttl não pode ser negativo")
+	check(c.Admin.PerPage >= 0 && c.Admin.PerPage <= 100, "admin.per_page deve estar entre 0 e 100")
+	check(c.Admin.MinAccountAgeForAdmin >= 0, "admin.min_account_age_for_admin não pode ser negativo")
+	if _, _, err := c.Maintenance.Window(); err != nil {
+		errs = append(errs, err)
+	}
+	check(c.Maintenance.AnnounceBefore >= 0, "maintenance.announce_before não pode ser negativo")
+	for i, user := range c.Seed.Users {
+		check(user.Username != "" && user.Email != "", "seed.users[%d] precisa de username e email", i)
+	}
+
+	return errors.Join(errs...)
+}
+
+// validIPOrPrefix reports whether entry is an IP or a CIDR range.
+func validIPOrPrefix(entry string) bool {
+	_, prefixErr := netip.ParsePrefix(strings.TrimSpace(entry))
+	_, addrErr := netip.ParseAddr(strings.TrimSpace(entry))
+	return prefixErr == nil || addrErr == nil
+}
@@ -11,9 +11,13 @@ package email
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"html/template"
+	"net/mail"
 	"net/smtp"
+	"slices"
+	"strings"
 
 	"github.com/lucas-varjao/gohtmx/internal/config"
 	"github.com/lucas-varjao/gohtmx/internal/logger"
@@ -22,27 +26,88 @@ import (
 // EmailServiceInterface defines the interface for email services
 type EmailServiceInterface interface {
 	SendPasswordResetEmail(to, token, username, displayName string) error
+	SendAccountDeactivatedEmail(to, username, displayName string) error
+	SendEmailChangeConfirmation(to, token, username, displayName string) error
+	SendAccountLockedEmail(to, token, username, displayName string) error
+	SendEmailVerification(to, token, username, displayName string) error
+	SendLoginCode(to, code, username, displayName string) error
+	SendAccountDeletionScheduled(to, token, username, displayName string) error
+	SendNewLoginEmail(to, device, ip, username, displayName string) error
+	SendPasswordChangedEmail(to, username, displayName string) error
+}
+
+// Email types: the kinds given to Queue.Enqueue and the keys of the users' notification preferences
+const (
+	TypePasswordReset      = "password_reset"
+	TypeAccountDeactivated = "account_deactivated"
+	TypeEmailChange        = "email_change"
+	TypeAccountLocked      = "account_locked"
+	TypeEmailVerification  = "email_verification"
+	TypeLoginCode          = "login_code"
+	TypeAccountDeletion    = "account_deletion"
+	TypeNewLogin           = "new_login"
+	TypePasswordChanged    = "password_changed"
+)
+
+// OptionalTypes are the emails a user can turn off. The others (password reset, email change,
+// verification, login code and account deletion) answer something the user asked for and always send.
+var OptionalTypes = []string{TypeAccountLocked, TypeAccountDeactivated, TypeNewLogin}
+
+// IsOptional reports whether users may turn emailType off.
+func IsOptional(emailType string) bool {
+	return slices.Contains(OptionalTypes, emailType)
+}
+
+// Gate tells whether a user wants to receive an email type (service.NotificationService). Senders of
+// optional emails consult it before sending.
+type Gate interface {
+	ShouldSend(userID uint, emailType string) bool
 }
 
 // EmailService é o serviço responsável pelo envio de emails
 type EmailService struct {
-	config *config.EmailConfig
+	config    *config.EmailConfig
+	transport Transport
+	degraded  error // why the SMTP config was rejected; nil when sending through SMTP
 }
 
-// NewEmailService cria uma nova instância do serviço de email
+// NewEmailService cria uma nova instância do serviço de email. The SMTP config is validated up front:
+// when it is unusable the service falls back to LogTransport (see Degraded) and logs a loud warning,
+// so the app keeps serving with email misconfigured.
 func NewEmailService(cfg *config.Config) *EmailService {
-	return &EmailService{
-		config: &cfg.Email,
+	s := &EmailService{
+		config:    &cfg.Email,
+		transport: smtpTransport{config: &cfg.Email},
+	}
+	if err := ValidateConfig(cfg.Email); err != nil {
+		logger.Error("CONFIGURAÇÃO DE EMAIL INVÁLIDA: nenhum email será enviado, apenas registrado no log", "error", err)
+		s.transport = LogTransport{}
+		s.degraded = err
 	}
+	return s
+}
+
+// Transport returns the transport emails go through (LogTransport when degraded).
+func (s *EmailService) Transport() Transport {
+	return s.transport
+}
+
+// Degraded returns why emails are not being sent (invalid SMTP config), or nil when SMTP is in use.
+func (s *EmailService) Degraded() error {
+	return s.degraded
 }
 
 // EmailData contém dados dinâmicos para templates de email
 type EmailData struct {
 	Username     string
 	ResetLink    string
+	ConfirmLink  string
 	DisplayName  string
 	AppName      string
 	SupportEmail string
+	Code         string
+	Device       string
+	IP           string
 }
 
 // SendPasswordResetEmail envia um email de recuperação de senha com um link contendo o token
@@ -130,15 +195,462 @@ func (s *EmailService) SendPasswordResetEmail(to, token, username, displayName s
 	return nil
 }
 
-// sendEmail é uma função auxiliar que envia um email usando SMTP
+// SendAccountDeactivatedEmail avisa o usuário de que a conta foi desativada por inatividade
+func (s *EmailService) SendAccountDeactivatedEmail(to, username, displayName string) error {
+	subject := "Conta desativada por inatividade"
+
+	data := EmailData{
+		Username:     username,
+		DisplayName:  displayName,
+		AppName:      "GoHTMX",
+		SupportEmail: s.config.FromEmail,
+	}
+
+	htmlBody := `
+	<!DOCTYPE html>
+	<html>
+	<head>
+		<meta charset="UTF-8">
+		<title>Conta desativada</title>
+	</head>
+	<body style="font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif; color: #333;">
+		<p>Olá {{.DisplayName}},</p>
+		<p>Sua conta <strong>{{.Username}}</strong> no {{.AppName}} foi desativada por ficar muito tempo sem acesso.</p>
+		<p>Se quiser voltar a usá-la, entre em contato com {{.SupportEmail}} para reativá-la.</p>
+		<p>Atenciosamente,<br>Equipe {{.AppName}}</p>
+	</body>
+	</html>
+	`
+
+	t, err := template.New("deactivated_email").Parse(htmlBody)
+	if err != nil {
+		logger.Error("Erro ao analisar template de email", "error", err, "email", to)
+
+		return fmt.Errorf("erro ao analisar template: %w", err)
+	}
+
+	var body bytes.Buffer
+	if err := t.Execute(&body, data); err != nil {
+		logger.Error("Erro ao executar template de email", "error", err, "email", to)
+
+		return fmt.Errorf("erro ao executar template: %w", err)
+	}
+
+	if err := s.sendEmail(to, subject, body.String()); err != nil {
+		return err
+	}
+
+	logger.Debug("Email de conta desativada enviado com sucesso", "email", to)
+
+	return nil
+}
+
+// SendEmailChangeConfirmation envia para o novo endereço o link que confirma a troca de email
+func (s *EmailService) SendEmailChangeConfirmation(to, token, username, displayName string) error {
+	subject := "Confirme seu novo email"
+
+	data := EmailData{
+		Username:     username,
+		ConfirmLink:  s.config.EmailChangeURL + token,
+		DisplayName:  displayName,
+		AppName:      "GoHTMX",
+		SupportEmail: s.config.FromEmail,
+	}
+
+	htmlBody := `
+	<!DOCTYPE html>
+	<html>
+	<head>
+		<meta charset="UTF-8">
+		<title>Confirme seu novo email</title>
+	</head>
+	<body style="font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif; color: #333;">
+		<p>Olá {{.DisplayName}},</p>
+		<p>Recebemos um pedido para usar este endereço como o novo email da conta <strong>{{.Username}}</strong> no {{.AppName}}.</p>
+		<p>Para confirmar a troca, acesse o link abaixo:</p>
+		<p><a href="{{.ConfirmLink}}">{{.ConfirmLink}}</a></p>
+		<p>Este link expirará em 24 horas. Até lá, o email antigo continua valendo.</p>
+		<p>Se você não fez esse pedido, ignore este email.</p>
+		<p>Atenciosamente,<br>Equipe {{.AppName}}</p>
+	</body>
+	</html>
+	`
+
+	t, err := template.New("email_change").Parse(htmlBody)
+	if err != nil {
+		logger.Error("Erro ao analisar template de email", "error", err, "email", to)
+
+		return fmt.Errorf("erro ao analisar template: %w", err)
+	}
+
+	var body bytes.Buffer
+	if err := t.Execute(&body, data); err != nil {
+		logger.Error("Erro ao executar template de email", "error", err, "email", to)
+
+		return fmt.Errorf("erro ao executar template: %w", err)
+	}
+
+	if err := s.sendEmail(to, subject, body.String()); err != nil {
+		return err
+	}
+
+	logger.Debug("Email de confirmação de troca de email enviado com sucesso", "email", to)
+
+	return nil
+}
+
+// SendAccountLockedEmail avisa o dono da conta de que ela foi bloqueada por tentativas de login
+// falhas, com um link de redefinição de senha (token) caso ele não reconheça as tentativas. Sem token
+// (já há uma redefinição pendente), o email indica o link já enviado em vez de um novo
+func (s *EmailService) SendAccountLockedEmail(to, token, username, displayName string) error {
+	subject := "Conta bloqueada temporariamente"
+
+	var resetLink string
+	if token != "" {
+		resetLink = s.config.ResetURL + token
+	}
+	data := EmailData{
+		Username:     username,
+		ResetLink:    resetLink,
+		DisplayName:  displayName,
+		AppName:      "GoHTMX",
+		SupportEmail: s.config.FromEmail,
+	}
+
+	htmlBody := `
+	<!DOCTYPE html>
+	<html>
+	<head>
+		<meta charset="UTF-8">
+		<title>Conta bloqueada</title>
+	</head>
+	<body style="font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif; color: #333;">
+		<p>Olá {{.DisplayName}},</p>
+		<p>Sua conta <strong>{{.Username}}</strong> no {{.AppName}} foi bloqueada temporariamente após várias tentativas de login sem sucesso.</p>
+		<p>Se foi você, basta aguardar alguns minutos e tentar de novo.</p>
+		{{if .ResetLink}}
+		<p>Se você não reconhece essas tentativas, alguém pode estar tentando acessar sua conta. Recomendamos redefinir sua senha:</p>
+		<p><a href="{{.ResetLink}}">{{.ResetLink}}</a></p>
+		<p>Este link expirará em 1 hora. Em caso de dúvidas, entre em contato com {{.SupportEmail}}.</p>
+		{{else}}
+		<p>Se você não reconhece essas tentativas, alguém pode estar tentando acessar sua conta. Recomendamos redefinir sua senha com o link de redefinição que já enviamos para este email.</p>
+		<p>Em caso de dúvidas, entre em contato com {{.SupportEmail}}.</p>
+		{{end}}
+		<p>Atenciosamente,<br>Equipe {{.AppName}}</p>
+	</body>
+	</html>
+	`
+
+	t, err := template.New("account_locked").Parse(htmlBody)
+	if err != nil {
+		logger.Error("Erro ao analisar template de email", "error", err, "email", to)
+
+		return fmt.Errorf("erro ao analisar template: %w", err)
+	}
+
+	var body bytes.Buffer
+	if err := t.Execute(&body, data); err != nil {
+		logger.Error("Erro ao executar template de email", "error", err, "email", to)
+
+		return fmt.Errorf("erro ao executar template: %w", err)
+	}
+
+	if err := s.sendEmail(to, subject, body.String()); err != nil {
+		return err
+	}
+
+	logger.Debug("Email de conta bloqueada enviado com sucesso", "email", to)
+
+	return nil
+}
+
+// SendEmailVerification envia o link que confirma o email atual da conta (mesmo link da troca de email)
+func (s *EmailService) SendEmailVerification(to, token, username, displayName string) error {
+	subject := "Confirme seu email"
+
+	data := EmailData{
+		Username:     username,
+		ConfirmLink:  s.config.EmailChangeURL + token,
+		DisplayName:  displayName,
+		AppName:      "GoHTMX",
+		SupportEmail: s.config.FromEmail,
+	}
+
+	htmlBody := `
+	<!DOCTYPE html>
+	<html>
+	<head>
+		<meta charset="UTF-8">
+		<title>Confirme seu email</title>
+	</head>
+	<body style="font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif; color: #333;">
+		<p>Olá {{.DisplayName}},</p>
+		<p>O {{.AppName}} passou a confirmar os endereços de email das contas. Para confirmar que este é o email da conta <strong>{{.Username}}</strong>, acesse o link abaixo:</p>
+		<p><a href="{{.ConfirmLink}}">{{.ConfirmLink}}</a></p>
+		<p>Este link expirará em 24 horas. Em caso de dúvidas, entre em contato com {{.SupportEmail}}.</p>
+		<p>Se você não tem conta no {{.AppName}}, ignore este email.</p>
+		<p>Atenciosamente,<br>Equipe {{.AppName}}</p>
+	</body>
+	</html>
+	`
+
+	t, err := template.New("email_verification").Parse(htmlBody)
+	if err != nil {
+		logger.Error("Erro ao analisar template de email", "error", err, "email", to)
+
+		return fmt.Errorf("erro ao analisar template: %w", err)
+	}
+
+	var body bytes.Buffer
+	if err := t.Execute(&body, data); err != nil {
+		logger.Error("Erro ao executar template de email", "error", err, "email", to)
+
+		return fmt.Errorf("erro ao executar template: %w", err)
+	}
+
+	if err := s.sendEmail(to, subject, body.String()); err != nil {
+		return err
+	}
+
+	logger.Debug("Email de verificação enviado com sucesso", "email", to)
+
+	return nil
+}
+
+// SendLoginCode envia o código que confirma um login vindo de um país em que a conta nunca entrou
+func (s *EmailService) SendLoginCode(to, code, username, displayName string) error {
+	subject := "Código para confirmar seu login"
+
+	data := EmailData{
+		Username:     username,
+		DisplayName:  displayName,
+		AppName:      "GoHTMX",
+		SupportEmail: s.config.FromEmail,
+		Code:         code,
+	}
+
+	htmlBody := `
+	<!DOCTYPE html>
+	<html>
+	<head>
+		<meta charset="UTF-8">
+		<title>Confirme seu login</title>
+	</head>
+	<body style="font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif; color: #333;">
+		<p>Olá {{.DisplayName}},</p>
+		<p>Recebemos um login na conta <strong>{{.Username}}</strong> no {{.AppName}} a partir de um país de onde você ainda não tinha entrado. Para concluir o login, informe o código abaixo:</p>
+		<p style="font-size: 24px; font-weight: bold; letter-spacing: 4px;">{{.Code}}</p>
+		<p>Este código expirará em poucos minutos.</p>
+		<p>Se não foi você, alguém sabe a sua senha: troque-a assim que possível. Em caso de dúvidas, entre em contato com {{.SupportEmail}}.</p>
+		<p>Atenciosamente,<br>Equipe {{.AppName}}</p>
+	</body>
+	</html>
+	`
+
+	t, err := template.New("login_code").Parse(htmlBody)
+	if err != nil {
+		logger.Error("Erro ao analisar template de email", "error", err, "email", to)
+
+		return fmt.Errorf("erro ao analisar template: %w", err)
+	}
+
+	var body bytes.Buffer
+	if err := t.Execute(&body, data); err != nil {
+		logger.Error("Erro ao executar template de email", "error", err, "email", to)
+
+		return fmt.Errorf("erro ao executar template: %w", err)
+	}
+
+	if err := s.sendEmail(to, subject, body.String()); err != nil {
+		return err
+	}
+
+	logger.Debug("Email com código de login enviado com sucesso", "email", to)
+
+	return nil
+}
+
+// SendAccountDeletionScheduled confirma o pedido de exclusão da conta, com o link (token) que a cancela
+// enquanto o prazo de carência não acaba
+func (s *EmailService) SendAccountDeletionScheduled(to, token, username, displayName string) error {
+	subject := "Exclusão da conta agendada"
+
+	data := EmailData{
+		Username:     username,
+		ConfirmLink:  s.config.CancelDeletionURL + token,
+		DisplayName:  displayName,
+		AppName:      "GoHTMX",
+		SupportEmail: s.config.FromEmail,
+	}
+
+	htmlBody := `
+	<!DOCTYPE html>
+	<html>
+	<head>
+		<meta charset="UTF-8">
+		<title>Exclusão da conta agendada</title>
+	</head>
+	<body style="font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif; color: #333;">
+		<p>Olá {{.DisplayName}},</p>
+		<p>Recebemos o pedido para excluir a conta <strong>{{.Username}}</strong> no {{.AppName}}. A conta foi desativada e será apagada definitivamente ao fim do prazo de carência.</p>
+		<p>Mudou de ideia? Até lá, você pode cancelar a exclusão pelo link abaixo:</p>
+		<p><a href="{{.ConfirmLink}}">{{.ConfirmLink}}</a></p>
+		<p>Se não foi você quem pediu, cancele a exclusão e troque sua senha. Em caso de dúvidas, entre em contato com {{.SupportEmail}}.</p>
+		<p>Atenciosamente,<br>Equipe {{.AppName}}</p>
+	</body>
+	</html>
+	`
+
+	t, err := template.New("account_deletion").Parse(htmlBody)
+	if err != nil {
+		logger.Error("Erro ao analisar template de email", "error", err, "email", to)
+
+		return fmt.Errorf("erro ao analisar template: %w", err)
+	}
+
+	var body bytes.Buffer
+	if err := t.Execute(&body, data); err != nil {
+		logger.Error("Erro ao executar template de email", "error", err, "email", to)
+
+		return fmt.Errorf("erro ao executar template: %w", err)
+	}
+
+	if err := s.sendEmail(to, subject, body.String()); err != nil {
+		return err
+	}
+
+	logger.Debug("Email de exclusão agendada enviado com sucesso", "email", to)
+
+	return nil
+}
+
+// SendNewLoginEmail avisa o dono da conta de um login feito enquanto outras sessões estavam abertas,
+// com o dispositivo e o IP de onde veio
+func (s *EmailService) SendNewLoginEmail(to, device, ip, username, displayName string) error {
+	subject := "Novo login na sua conta"
+
+	data := EmailData{
+		Username:     username,
+		DisplayName:  displayName,
+		AppName:      "GoHTMX",
+		SupportEmail: s.config.FromEmail,
+		Device:       device,
+		IP:           ip,
+	}
+
+	htmlBody := `
+	<!DOCTYPE html>
+	<html>
+	<head>
+		<meta charset="UTF-8">
+		<title>Novo login na sua conta</title>
+	</head>
+	<body style="font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif; color: #333;">
+		<p>Olá {{.DisplayName}},</p>
+		<p>Alguém entrou na conta <strong>{{.Username}}</strong> no {{.AppName}} enquanto ela já estava aberta em outro dispositivo.</p>
+		<p>Dispositivo: <strong>{{.Device}}</strong>{{if .IP}}<br>IP: <strong>{{.IP}}</strong>{{end}}</p>
+		<p>Se foi você, ignore este email. Se não foi, troque sua senha e encerre as outras sessões assim que possível. Em caso de dúvidas, entre em contato com {{.SupportEmail}}.</p>
+		<p>Atenciosamente,<br>Equipe {{.AppName}}</p>
+	</body>
+	</html>
+	`
+
+	t, err := template.New("new_login").Parse(htmlBody)
+	if err != nil {
+		logger.Error("Erro ao analisar template de email", "error", err, "email", to)
+
+		return fmt.Errorf("erro ao analisar template: %w", err)
+	}
+
+	var body bytes.Buffer
+	if err := t.Execute(&body, data); err != nil {
+		logger.Error("Erro ao executar template de email", "error", err, "email", to)
+
+		return fmt.Errorf("erro ao executar template: %w", err)
+	}
+
+	if err := s.sendEmail(to, subject, body.String()); err != nil {
+		return err
+	}
+
+	logger.Debug("Email de novo login enviado com sucesso", "email", to)
+
+	return nil
+}
+
+// SendPasswordChangedEmail confirma ao dono da conta que a senha foi redefinida ou trocada, para que
+// perceba uma troca que não fez
+func (s *EmailService) SendPasswordChangedEmail(to, username, displayName string) error {
+	subject := "Sua senha foi alterada"
+
+	data := EmailData{
+		Username:     username,
+		DisplayName:  displayName,
+		AppName:      "GoHTMX",
+		SupportEmail: s.config.FromEmail,
+	}
+
+	htmlBody := `
+	<!DOCTYPE html>
+	<html>
+	<head>
+		<meta charset="UTF-8">
+		<title>Sua senha foi alterada</title>
+	</head>
+	<body style="font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif; color: #333;">
+		<p>Olá {{.DisplayName}},</p>
+		<p>A senha da conta <strong>{{.Username}}</strong> no {{.AppName}} acabou de ser alterada.</p>
+		<p>Se foi você, ignore este email. Se não foi, entre em contato com {{.SupportEmail}} imediatamente: alguém pode ter acesso à sua conta.</p>
+		<p>Atenciosamente,<br>Equipe {{.AppName}}</p>
+	</body>
+	</html>
+	`
+
+	t, err := template.New("password_changed").Parse(htmlBody)
+	if err != nil {
+		logger.Error("Erro ao analisar template de email", "error", err, "email", to)
+
+		return fmt.Errorf("erro ao analisar template: %w", err)
+	}
+
+	var body bytes.Buffer
+	if err := t.Execute(&body, data); err != nil {
+		logger.Error("Erro ao executar template de email", "error", err, "email", to)
+
+		return fmt.Errorf("erro ao executar template: %w", err)
+	}
+
+	if err := s.sendEmail(to, subject, body.String()); err != nil {
+		return err
+	}
+
+	logger.Debug("Email de senha alterada enviado com sucesso", "email", to)
+
+	return nil
+}
+
+// sendEmail entrega o email pelo transporte configurado (SMTP, ou o LogTransport de fallback)
 func (s *EmailService) sendEmail(to, subject, htmlBody string) error {
-	// Configurações de SMTP
-	host := s.config.SMTPHost
-	port := s.config.SMTPPort
-	username := s.config.SMTPUsername
-	password := s.config.SMTPPassword
-	fromEmail := s.config.FromEmail
-	fromName := s.config.FromName
+	return s.transport.Send(to, subject, htmlBody)
+}
+
+// Transport delivers a rendered email.
+type Transport interface {
+	Send(to, subject, htmlBody string) error
+}
+
+// smtpTransport sends through the SMTP servers in the email config, trying each one in order until one
+// accepts the email (config.EmailConfig.Servers).
+type smtpTransport struct {
+	config *config.EmailConfig
+	// sendMail is smtp.SendMail unless replaced in tests
+	sendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// Send envia um email usando SMTP, passando para o próximo servidor quando um falha
+func (t smtpTransport) Send(to, subject, htmlBody string) error {
+	fromEmail := t.config.FromEmail
+	fromName := t.config.FromName
 
 	// Construir o cabeçalho do email
 	headers := make(map[string]string)
@@ -156,24 +668,68 @@ func (s *EmailService) sendEmail(to, subject, htmlBody string) error {
 	message.WriteString("\r\n")
 	message.WriteString(htmlBody)
 
-	// Autenticação SMTP
-	auth := smtp.PlainAuth("", username, password, host)
-
-	// Endereço do servidor SMTP
-	addr := fmt.Sprintf("%s:%d", host, port)
+	sendMail := t.sendMail
+	if sendMail == nil {
+		sendMail = smtp.SendMail
+	}
+	var failures []error
+	for i, server := range t.config.Servers() {
+		// Autenticação SMTP
+		auth := smtp.PlainAuth("", server.Username, server.Password, server.Host)
 
-	// Enviamos o email
-	if err := smtp.SendMail(
-		addr,
-		auth,
-		fromEmail,
-		[]string{to},
-		message.Bytes(),
-	); err != nil {
-		logger.Error("Erro ao enviar email via SMTP", "error", err, "to", to, "addr", addr)
+		// Endereço do servidor SMTP
+		addr := fmt.Sprintf("%s:%d", server.Host, server.Port)
 
-		return err
+		// Enviamos o email
+		if err := sendMail(addr, auth, fromEmail, []string{to}, message.Bytes()); err != nil {
+			logger.Error("Erro ao enviar email via SMTP", "error", err, "to", to, "addr", addr)
+			failures = append(failures, fmt.Errorf("%s: %w", addr, err))
+			continue
+		}
+		if i > 0 {
+			logger.Info("Email enviado pelo servidor SMTP reserva", "to", to, "addr", addr, "failed_servers", i)
+		} else {
+			logger.Debug("Email enviado via SMTP", "to", to, "addr", addr)
+		}
+		return nil
 	}
 
+	return errors.Join(failures...)
+}
+
+// LogTransport is the fallback when the SMTP config is unusable: it only logs that an email was dropped
+// (recipient and subject; never the body, which carries tokens) and reports success, so flows such as
+// password reset keep answering instead of failing on every send.
+type LogTransport struct{}
+
+// Send logs the dropped email.
+func (LogTransport) Send(to, subject, _ string) error {
+	logger.Warn("Email não enviado: serviço de email indisponível (configuração SMTP inválida)", "to", to, "subject", subject)
 	return nil
 }
+
+// ValidateConfig reports why cfg can't be used to send emails: no SMTP host, a port out of range or an
+// invalid sender address. Credentials are optional (relays without authentication).
+func ValidateConfig(cfg config.EmailConfig) error {
+	var problems []error
+	if len(cfg.SMTPServers) == 0 {
+		if strings.TrimSpace(cfg.SMTPHost) == "" {
+			problems = append(problems, errors.New("email.smtp_host não definido"))
+		}
+		if cfg.SMTPPort < 1 || cfg.SMTPPort > 65535 {
+			problems = append(problems, fmt.Errorf("email.smtp_port inválida: %d", cfg.SMTPPort))
+		}
+	}
+	for i, server := range cfg.SMTPServers {
+		if strings.TrimSpace(server.Host) == "" {
+			problems = append(problems, fmt.Errorf("email.smtp_servers[%d].host não definido", i))
+		}
+		if server.Port < 1 || server.Port > 65535 {
+			problems = append(problems, fmt.Errorf("email.smtp_servers[%d].port inválida: %d", i, server.Port))
+		}
+	}
+	if _, err := mail.ParseAddress(cfg.FromEmail); err != nil {
+		problems = append(problems, fmt.Errorf("email.from_email inválido: %q", cfg.FromEmail))
+	}
+	return errors.Join(problems...)
+}
@@ -0,0 +1,122 @@
+package email
+
+import (
+	"errors"
+	"net/smtp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/lucas-varjao/gohtmx/internal/config"
+)
+
+func TestNewEmailService_FallsBackOnBadSMTPConfig(t *testing.T) {
+	valid := config.EmailConfig{SMTPHost: "smtp.example.com", SMTPPort: 587, FromEmail: "no-reply@example.com"}
+
+	svc := NewEmailService(&config.Config{Email: valid})
+	if err := svc.Degraded(); err != nil {
+		t.Fatalf("Degraded() = %v for a valid config", err)
+	}
+	if _, ok := svc.Transport().(smtpTransport); !ok {
+		t.Fatalf("Transport() = %T, want smtpTransport", svc.Transport())
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(*config.EmailConfig)
+	}{
+		{"Missing host", func(c *config.EmailConfig) { c.SMTPHost = " " }},
+		{"Port out of range", func(c *config.EmailConfig) { c.SMTPPort = 0 }},
+		{"Invalid sender", func(c *config.EmailConfig) { c.FromEmail = "not an address" }},
+		{"Fallback without host", func(c *config.EmailConfig) {
+			c.SMTPServers = []config.SMTPServerConfig{{Host: "smtp.example.com", Port: 587}, {Port: 587}}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bad := valid
+			tt.mutate(&bad)
+
+			svc := NewEmailService(&config.Config{Email: bad})
+			if svc.Degraded() == nil {
+				t.Fatal("Degraded() = nil, want the config error")
+			}
+			if _, ok := svc.Transport().(LogTransport); !ok {
+				t.Fatalf("Transport() = %T, want LogTransport", svc.Transport())
+			}
+			// Sending keeps working (nothing leaves the process), so password reset doesn't break
+			if err := svc.SendPasswordResetEmail("user@example.com", "token", "user", "User"); err != nil {
+				t.Errorf("SendPasswordResetEmail() = %v, want nil with the fallback transport", err)
+			}
+		})
+	}
+}
+
+func TestSMTPTransport_Fallback(t *testing.T) {
+	cfg := config.EmailConfig{
+		FromEmail: "no-reply@example.com",
+		SMTPServers: []config.SMTPServerConfig{
+			{Host: "primary.example.com", Port: 587},
+			{Host: "fallback.example.com", Port: 2525},
+		},
+	}
+	var dialed []string
+	refuse := map[string]bool{"primary.example.com:587": true}
+	transport := smtpTransport{config: &cfg, sendMail: func(addr string, _ smtp.Auth, _ string, _ []string, _ []byte) error {
+		dialed = append(dialed, addr)
+		if refuse[addr] {
+			return errors.New("connection refused")
+		}
+		return nil
+	}}
+
+	if err := transport.Send("user@example.com", "Assunto", "<p>corpo</p>"); err != nil {
+		t.Fatalf("Send() = %v, want the fallback to deliver", err)
+	}
+	if want := []string{"primary.example.com:587", "fallback.example.com:2525"}; !slices.Equal(dialed, want) {
+		t.Errorf("dialed %v, want %v", dialed, want)
+	}
+
+	dialed = nil
+	refuse["fallback.example.com:2525"] = true
+	err := transport.Send("user@example.com", "Assunto", "<p>corpo</p>")
+	if err == nil || !strings.Contains(err.Error(), "primary.example.com:587") || !strings.Contains(err.Error(), "fallback.example.com:2525") {
+		t.Errorf("Send() = %v, want the error of every server", err)
+	}
+	if len(dialed) != 2 {
+		t.Errorf("dialed %v, want both servers tried", dialed)
+	}
+}
+
+func TestEmailConfig_SingleServer(t *testing.T) {
+	cfg := config.EmailConfig{SMTPHost: "smtp.example.com", SMTPPort: 587, SMTPUsername: "app", SMTPPassword: "secret"}
+	want := []config.SMTPServerConfig{{Host: "smtp.example.com", Port: 587, Username: "app", Password: "secret"}}
+	if got := cfg.Servers(); !slices.Equal(got, want) {
+		t.Errorf("Servers() = %v, want %v", got, want)
+	}
+}
+
+func TestPreview(t *testing.T) {
+	cfg := config.EmailConfig{ResetURL: "https://app.example.com/reset-password?token=", FromEmail: "suporte@example.com"}
+
+	for _, emailType := range PreviewTypes {
+		t.Run(emailType, func(t *testing.T) {
+			subject, body, err := Preview(cfg, emailType)
+			if err != nil {
+				t.Fatalf("Preview() error = %v", err)
+			}
+			if subject == "" || !strings.Contains(body, "<html>") || !strings.Contains(body, "Maria Silva") {
+				t.Errorf("Preview() = %q, %q; want a subject and the body with the sample data", subject, body)
+			}
+		})
+	}
+
+	_, body, _ := Preview(cfg, TypePasswordReset)
+	if !strings.Contains(body, "https://app.example.com/reset-password?token=token-de-exemplo") {
+		t.Errorf("reset preview should link with the configured URL, got %s", body)
+	}
+
+	if _, _, err := Preview(cfg, "welcome"); !errors.Is(err, ErrUnknownType) {
+		t.Errorf("Preview(welcome) error = %v, want ErrUnknownType", err)
+	}
+}
@@ -13,8 +13,22 @@ type MockEmailService struct {
 	mu             sync.Mutex
 }
 
+// Kinds of emails recorded by MockEmailService
+const (
+	MockKindPasswordReset      = TypePasswordReset
+	MockKindAccountDeactivated = TypeAccountDeactivated
+	MockKindEmailChange        = TypeEmailChange
+	MockKindAccountLocked      = TypeAccountLocked
+	MockKindEmailVerification  = TypeEmailVerification
+	MockKindLoginCode          = TypeLoginCode
+	MockKindAccountDeletion    = TypeAccountDeletion
+	MockKindNewLogin           = TypeNewLogin
+	MockKindPasswordChanged    = TypePasswordChanged
+)
+
 // MockEmail represents a sent email for testing
 type MockEmail struct {
+	Kind        string
 	To          string
 	Token       string
 	Username    string
@@ -34,6 +48,70 @@ func (m *MockEmailService) SendPasswordResetEmail(to, token, username, displayNa
 	defer m.mu.Unlock()
 
 	m.sentEmails = append(m.sentEmails, MockEmail{
+		Kind:        MockKindPasswordReset,
+		To:          to,
+		Token:       token,
+		Username:    username,
+		DisplayName: displayName,
+	})
+
+	return m.sendEmailError
+}
+
+// SendAccountDeactivatedEmail records the deactivation notice that would be sent
+func (m *MockEmailService) SendAccountDeactivatedEmail(to, username, displayName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sentEmails = append(m.sentEmails, MockEmail{
+		Kind:        MockKindAccountDeactivated,
+		To:          to,
+		Username:    username,
+		DisplayName: displayName,
+	})
+
+	return m.sendEmailError
+}
+
+// SendEmailChangeConfirmation records the confirmation link email that would be sent to the new address
+func (m *MockEmailService) SendEmailChangeConfirmation(to, token, username, displayName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sentEmails = append(m.sentEmails, MockEmail{
+		Kind:        MockKindEmailChange,
+		To:          to,
+		Token:       token,
+		Username:    username,
+		DisplayName: displayName,
+	})
+
+	return m.sendEmailError
+}
+
+// SendAccountLockedEmail records the lockout notice (with its reset token, if any) that would be sent
+func (m *MockEmailService) SendAccountLockedEmail(to, token, username, displayName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sentEmails = append(m.sentEmails, MockEmail{
+		Kind:        MockKindAccountLocked,
+		To:          to,
+		Token:       token,
+		Username:    username,
+		DisplayName: displayName,
+	})
+
+	return m.sendEmailError
+}
+
+// SendEmailVerification records the verification email (with its token) that would be sent
+func (m *MockEmailService) SendEmailVerification(to, token, username, displayName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sentEmails = append(m.sentEmails, MockEmail{
+		Kind:        MockKindEmailVerification,
 		To:          to,
 		Token:       token,
 		Username:    username,
@@ -43,7 +121,70 @@ func (m *MockEmailService) SendPasswordResetEmail(to, token, username, displayNa
 	return m.sendEmailError
 }
 
-// SetSendEmailError sets an error to be returned by SendPasswordResetEmail
+// SendLoginCode records the login code email that would be sent; Token holds the code
+func (m *MockEmailService) SendLoginCode(to, code, username, displayName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sentEmails = append(m.sentEmails, MockEmail{
+		Kind:        MockKindLoginCode,
+		To:          to,
+		Token:       code,
+		Username:    username,
+		DisplayName: displayName,
+	})
+
+	return m.sendEmailError
+}
+
+// SendAccountDeletionScheduled records the scheduled deletion notice (with its cancel token) that would be sent
+func (m *MockEmailService) SendAccountDeletionScheduled(to, token, username, displayName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sentEmails = append(m.sentEmails, MockEmail{
+		Kind:        MockKindAccountDeletion,
+		To:          to,
+		Token:       token,
+		Username:    username,
+		DisplayName: displayName,
+	})
+
+	return m.sendEmailError
+}
+
+// SendNewLoginEmail records the concurrent login notice that would be sent; Token holds the device
+func (m *MockEmailService) SendNewLoginEmail(to, device, ip, username, displayName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sentEmails = append(m.sentEmails, MockEmail{
+		Kind:        MockKindNewLogin,
+		To:          to,
+		Token:       device,
+		Username:    username,
+		DisplayName: displayName,
+	})
+
+	return m.sendEmailError
+}
+
+// SendPasswordChangedEmail records the password changed confirmation that would be sent
+func (m *MockEmailService) SendPasswordChangedEmail(to, username, displayName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sentEmails = append(m.sentEmails, MockEmail{
+		Kind:        MockKindPasswordChanged,
+		To:          to,
+		Username:    username,
+		DisplayName: displayName,
+	})
+
+	return m.sendEmailError
+}
+
+// SetSendEmailError sets an error to be returned by the Send* methods
 func (m *MockEmailService) SetSendEmailError(err error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -0,0 +1,65 @@
+package email
+
+import (
+	"errors"
+
+	"github.com/lucas-varjao/gohtmx/internal/config"
+)
+
+// ErrUnknownType is returned by Preview for a type that isn't one of the Type constants.
+var ErrUnknownType = errors.New("tipo de email desconhecido")
+
+// PreviewTypes lists the email types Preview renders, in the order the admin sees them.
+var PreviewTypes = []string{TypeEmailVerification, TypePasswordReset, TypePasswordChanged, TypeEmailChange, TypeAccountLocked, TypeLoginCode, TypeAccountDeletion, TypeNewLogin, TypeAccountDeactivated}
+
+// Sample recipient and token shown in previews.
+const (
+	previewTo          = "maria@exemplo.com"
+	previewToken       = "token-de-exemplo"
+	previewUsername    = "maria"
+	previewDisplayName = "Maria Silva"
+	previewCode        = "482913"
+	previewDevice      = "Firefox 128 em Windows"
+	previewIP          = "203.0.113.7"
+)
+
+// capturedEmail is a Transport that keeps the email instead of delivering it.
+type capturedEmail struct {
+	subject, htmlBody string
+}
+
+// Send keeps the email.
+func (e *capturedEmail) Send(_, subject, htmlBody string) error {
+	e.subject, e.htmlBody = subject, htmlBody
+	return nil
+}
+
+// Preview renders emailType with sample data through the same templates and links (cfg) the real
+// sends use, without delivering anything. It returns ErrUnknownType for a type not in PreviewTypes.
+func Preview(cfg config.EmailConfig, emailType string) (subject, htmlBody string, err error) {
+	captured := &capturedEmail{}
+	s := &EmailService{config: &cfg, transport: captured}
+	switch emailType {
+	case TypePasswordReset:
+		err = s.SendPasswordResetEmail(previewTo, previewToken, previewUsername, previewDisplayName)
+	case TypeAccountDeactivated:
+		err = s.SendAccountDeactivatedEmail(previewTo, previewUsername, previewDisplayName)
+	case TypeEmailChange:
+		err = s.SendEmailChangeConfirmation(previewTo, previewToken, previewUsername, previewDisplayName)
+	case TypeAccountLocked:
+		err = s.SendAccountLockedEmail(previewTo, previewToken, previewUsername, previewDisplayName)
+	case TypeEmailVerification:
+		err = s.SendEmailVerification(previewTo, previewToken, previewUsername, previewDisplayName)
+	case TypeLoginCode:
+		err = s.SendLoginCode(previewTo, previewCode, previewUsername, previewDisplayName)
+	case TypeAccountDeletion:
+		err = s.SendAccountDeletionScheduled(previewTo, previewToken, previewUsername, previewDisplayName)
+	case TypePasswordChanged:
+		err = s.SendPasswordChangedEmail(previewTo, previewUsername, previewDisplayName)
+	case TypeNewLogin:
+		err = s.SendNewLoginEmail(previewTo, previewDevice, previewIP, previewUsername, previewDisplayName)
+	default:
+		return "", "", ErrUnknownType
+	}
+	return captured.subject, captured.htmlBody, err
+}
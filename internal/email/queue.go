@@ -0,0 +1,100 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+	"github.com/lucas-varjao/gohtmx/internal/worker"
+
+	"golang.org/x/time/rate"
+)
+
+// DefaultQueueSize is the buffer used by NewQueue when size <= 0.
+const DefaultQueueSize = 100
+
+// SendFunc sends one email through the service wrapped by a Queue.
+type SendFunc func(svc EmailServiceInterface) error
+
+type queuedEmail struct {
+	kind string
+	to   string
+	send SendFunc
+}
+
+// Queue sends emails from a background worker so that callers (request handlers) never wait on SMTP.
+// Delivery is best effort: failures are logged, and an email is dropped with a warning when the buffer
+// is full or the queue is closed.
+type Queue struct {
+	svc  EmailServiceInterface
+	pool *worker.Pool
+
+	// Throttled queues only (see NewThrottledQueue); stop cancels the wait for the next send slot
+	limiter *rate.Limiter
+	stop    context.Context
+	cancel  context.CancelFunc
+	dropped atomic.Int64
+}
+
+// NewQueue creates a Queue that sends through svc, buffering up to size emails, and starts its worker.
+func NewQueue(svc EmailServiceInterface, size int) *Queue {
+	return newQueue(svc, size, nil)
+}
+
+// NewThrottledQueue is like NewQueue but sends at most perSecond emails per second, for bulk
+// sends that would otherwise hit the SMTP provider's throttling (perSecond must be positive). Since draining a large backlog
+// at that pace could outlast shutdown, Close drops (and logs) whatever is still waiting.
+func NewThrottledQueue(svc EmailServiceInterface, size int, perSecond rate.Limit) *Queue {
+	return newQueue(svc, size, rate.NewLimiter(perSecond, 1))
+}
+
+func newQueue(svc EmailServiceInterface, size int, limiter *rate.Limiter) *Queue {
+	if size <= 0 {
+		size = DefaultQueueSize
+	}
+	q := &Queue{
+		svc:     svc,
+		pool:    worker.NewPool("email", 1, size),
+		limiter: limiter,
+	}
+	q.stop, q.cancel = context.WithCancel(context.Background())
+	return q
+}
+
+// Enqueue schedules send without blocking. kind and to only label the logs.
+// It returns false when the email was dropped.
+func (q *Queue) Enqueue(kind, to string, send SendFunc) bool {
+	err := q.pool.Submit(kind, func(context.Context) error {
+		if q.limiter != nil && q.limiter.Wait(q.stop) != nil {
+			q.dropped.Add(1)
+			return nil
+		}
+		if err := send(q.svc); err != nil {
+			logger.Error("Erro ao enviar email da fila", "error", err, "kind", kind, "email", to)
+			return err
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, worker.ErrClosed):
+		logger.Warn("Fila de emails encerrada, email descartado", "kind", kind, "email", to)
+		return false
+	case err != nil:
+		logger.Warn("Fila de emails cheia, email descartado", "kind", kind, "email", to)
+		return false
+	}
+	return true
+}
+
+// Close stops accepting emails, sends the ones already queued (throttled queues drop them instead)
+// and waits for the worker to finish. When ctx ends first, the emails not sent yet are dropped and
+// ctx.Err() is returned without waiting any longer.
+func (q *Queue) Close(ctx context.Context) error {
+	q.cancel()
+	err := q.pool.Shutdown(ctx)
+	if n := q.dropped.Swap(0); n > 0 {
+		logger.Warn("Fila de emails encerrada antes do envio, emails descartados", "count", n)
+	}
+	return err
+}
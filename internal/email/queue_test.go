@@ -0,0 +1,105 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestQueue_SendsQueuedEmailsBeforeClose(t *testing.T) {
+	mock := NewMockEmailService()
+	q := NewQueue(mock, 10)
+
+	for _, to := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		if !q.Enqueue(MockKindAccountLocked, to, func(svc EmailServiceInterface) error {
+			return svc.SendAccountLockedEmail(to, "token", "user", "User")
+		}) {
+			t.Fatalf("Enqueue(%s) = false, want true", to)
+		}
+	}
+	// A failing email is logged and doesn't stop the worker
+	q.Enqueue("broken", "x@example.com", func(EmailServiceInterface) error { return errors.New("smtp down") })
+	if err := q.Close(t.Context()); err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+
+	if sent := mock.GetSentEmails(); len(sent) != 3 {
+		t.Fatalf("expected 3 emails sent, got %d", len(sent))
+	}
+	if q.Enqueue(MockKindAccountLocked, "late@example.com", func(EmailServiceInterface) error { return nil }) {
+		t.Error("Enqueue after Close should drop the email")
+	}
+	_ = q.Close(t.Context()) // closing twice is harmless
+}
+
+func TestQueue_DropsWhenFull(t *testing.T) {
+	block := make(chan struct{})
+	q := NewQueue(NewMockEmailService(), 1)
+
+	// The worker picks up the first email and blocks; the second fills the buffer
+	started := make(chan struct{})
+	q.Enqueue("slow", "a@example.com", func(EmailServiceInterface) error {
+		close(started)
+		<-block
+		return nil
+	})
+	<-started
+	if !q.Enqueue("buffered", "b@example.com", func(EmailServiceInterface) error { return nil }) {
+		t.Fatal("second email should fit in the buffer")
+	}
+	if q.Enqueue("dropped", "c@example.com", func(EmailServiceInterface) error { return nil }) {
+		t.Error("Enqueue on a full queue should not block and should report the drop")
+	}
+	close(block)
+	_ = q.Close(t.Context())
+}
+
+func TestThrottledQueue_PacesAndDropsOnClose(t *testing.T) {
+	mock := NewMockEmailService()
+	// One email now, the next only after 10 minutes
+	q := NewThrottledQueue(mock, 10, rate.Every(10*time.Minute))
+
+	sent := make(chan struct{}, 3)
+	for _, to := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		q.Enqueue(MockKindEmailVerification, to, func(svc EmailServiceInterface) error {
+			defer func() { sent <- struct{}{} }()
+			return svc.SendEmailVerification(to, "token", "user", "User")
+		})
+	}
+	<-sent
+	_ = q.Close(t.Context())
+
+	if got := len(mock.GetSentEmails()); got != 1 {
+		t.Fatalf("expected only the first email before Close, got %d", got)
+	}
+}
+
+func TestQueue_CloseGivesUpWhenContextEnds(t *testing.T) {
+	mock := NewMockEmailService()
+	q := NewQueue(mock, 10)
+	block := make(chan struct{})
+	defer close(block)
+
+	started := make(chan struct{})
+	q.Enqueue("slow", "a@example.com", func(EmailServiceInterface) error {
+		close(started)
+		<-block
+		return nil
+	})
+	<-started
+	q.Enqueue(MockKindAccountLocked, "b@example.com", func(svc EmailServiceInterface) error {
+		return svc.SendAccountLockedEmail("b@example.com", "token", "user", "User")
+	})
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	if err := q.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Close() = %v, want context.DeadlineExceeded", err)
+	}
+	if sent := mock.GetSentEmails(); len(sent) != 0 {
+		t.Errorf("expected the queued email to be dropped, got %d sent", len(sent))
+	}
+}
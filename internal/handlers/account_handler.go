@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+	"github.com/lucas-varjao/gohtmx/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccountHandler serves account data operations for the logged-in user and, on the admin routes, for any user.
+type AccountHandler struct {
+	accounts service.AccountServiceInterface
+}
+
+// NewAccountHandler creates a new AccountHandler instance
+func NewAccountHandler(accounts service.AccountServiceInterface) *AccountHandler {
+	return &AccountHandler{accounts: accounts}
+}
+
+// ExportOwn handles GET /api/account/export: downloads the current user's data as JSON.
+func (h *AccountHandler) ExportOwn(c *gin.Context) {
+	h.writeExport(c, c.GetString("userID"))
+}
+
+// ExportUser handles GET /api/admin/users/:id/export (admin only).
+func (h *AccountHandler) ExportUser(c *gin.Context) {
+	logger.Info("Exportação de dados solicitada pelo admin", "user_id", c.Param("id"), "admin_id", c.GetString("userID"))
+	h.writeExport(c, c.Param("id"))
+}
+
+// writeExport streams the export as a JSON attachment.
+func (h *AccountHandler) writeExport(c *gin.Context, userID string) {
+	export, err := h.accounts.Export(userID)
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			respondJSON(c, http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		respondJSON(c, http.StatusInternalServerError, gin.H{"error": "falha ao exportar dados"})
+		return
+	}
+
+	filename := "account-" + strconv.FormatUint(uint64(export.Profile.ID), 10) + "-" + export.GeneratedAt.Format("20060102") + ".json"
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Header("Cache-Control", "no-store")
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	enc := json.NewEncoder(c.Writer)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(export); err != nil {
+		logger.Error("Erro ao escrever exportação de dados", "error", err, "user_id", userID)
+	}
+}
@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+	"github.com/lucas-varjao/gohtmx/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Pagination limits for GET /api/admin/users.
+const (
+	defaultUsersPerPage = 20
+	maxUsersPerPage     = 100
+)
+
+// AdminUserHandler exposes the admin user management as a JSON API (/api/admin/users).
+// It shares service.UserAdminServiceInterface with the HTML admin pages.
+type AdminUserHandler struct {
+	users     service.UserAdminServiceInterface
+	pageSizes *service.PageSizeService // nil: no per-user page size
+}
+
+// NewAdminUserHandler creates a new AdminUserHandler instance
+func NewAdminUserHandler(users service.UserAdminServiceInterface) *AdminUserHandler {
+	return &AdminUserHandler{users: users}
+}
+
+// UsePageSizes makes the list default to the page size each admin last asked for with ?per_page=.
+// Call it during setup, before serving requests.
+func (h *AdminUserHandler) UsePageSizes(pageSizes *service.PageSizeService) {
+	h.pageSizes = pageSizes
+}
+
+// AdminUserResponse is the JSON representation of a user for the admin API
+type AdminUserResponse struct {
+	ID                 uint      `json:"id"`
+	Username           string    `json:"username"`
+	Email              string    `json:"email"`
+	DisplayName        string    `json:"display_name"`
+	Role               string    `json:"role"`
+	Active             bool      `json:"active"`
+	EmailVerified      bool      `json:"email_verified"`
+	MustChangePassword bool      `json:"must_change_password"`
+	LoginStatus        string    `json:"login_status"` // see auth.LoginStatus
+	Version            uint      `json:"version"`
+	LastLogin          time.Time `json:"last_login"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+	// PasswordChangedAt is omitted for accounts created before it was tracked
+	PasswordChangedAt *time.Time `json:"password_changed_at,omitempty"`
+	// Phone is personal data only admins get through this API (models.User never serializes it)
+	Phone string `json:"phone,omitempty"`
+}
+
+// AdminUserListResponse is one page of users
+type AdminUserListResponse struct {
+	Users   []AdminUserResponse `json:"users"`
+	Total   int64               `json:"total"`
+	Page    int                 `json:"page"`
+	PerPage int                 `json:"per_page"`
+}
+
+// AdminCreateUserRequest represents the body of POST /api/admin/users
+type AdminCreateUserRequest struct {
+	Username    string `json:"username"     binding:"required"`
+	Email       string `json:"email"        binding:"required"`
+	DisplayName string `json:"display_name" binding:"required"`
+	Password    string `json:"password"     binding:"required"`
+	Role        string `json:"role"`
+	Active      *bool  `json:"active"` // defaults to true when omitted
+	Phone       string `json:"phone"`  // optional, E.164
+}
+
+// AdminUpdateUserRequest represents the body of PATCH /api/admin/users/:id; omitted fields are left unchanged
+type AdminUpdateUserRequest struct {
+	Role               *string `json:"role"`
+	Active             *bool   `json:"active"`
+	DisplayName        *string `json:"display_name"`
+	MustChangePassword *bool   `json:"must_change_password"`
+	Phone              *string `json:"phone"` // "" removes it
+}
+
+func (h *AdminUserHandler) newAdminUserResponse(u *models.User) AdminUserResponse {
+	return AdminUserResponse{
+		ID:                 u.ID,
+		Username:           u.Username,
+		Email:              u.Email,
+		DisplayName:        u.DisplayName,
+		Role:               u.Role,
+		Active:             u.Active,
+		EmailVerified:      u.EmailVerified,
+		MustChangePassword: u.MustChangePassword,
+		LoginStatus:        string(h.users.LoginStatus(u)),
+		Version:            u.Version,
+		LastLogin:          u.LastLogin,
+		CreatedAt:          u.CreatedAt,
+		UpdatedAt:          u.UpdatedAt,
+		PasswordChangedAt:  u.PasswordChangedAt,
+		Phone:              u.Phone,
+	}
+}
+
+// ListUsers handles GET /api/admin/users?page=&per_page=&q=&role=&active=
+// Without per_page the page size is the one the admin last asked for (see UsePageSizes).
+// ?style=envelope|array picks another response shape (see respondList).
+func (h *AdminUserHandler) ListUsers(c *gin.Context) {
+	filter := service.UserFilter{
+		Query:   c.Query("q"),
+		Role:    c.Query("role"),
+		Page:    queryInt(c, "page", 1),
+		PerPage: h.pageSizes.Resolve(c.GetString("userID"), queryInt(c, "per_page", 0), defaultUsersPerPage, maxUsersPerPage),
+	}
+	if raw := c.Query("active"); raw != "" {
+		active, err := strconv.ParseBool(raw)
+		if err != nil {
+			respondJSON(c, http.StatusBadRequest, gin.H{"error": "active deve ser true ou false"})
+			return
+		}
+		filter.Active = &active
+	}
+
+	page, err := h.users.List(filter)
+	if err != nil {
+		respondJSON(c, http.StatusInternalServerError, gin.H{"error": "falha ao listar usuários"})
+		return
+	}
+
+	resp := AdminUserListResponse{
+		Users:   make([]AdminUserResponse, 0, len(page.Users)),
+		Total:   page.Total,
+		Page:    page.Page,
+		PerPage: page.PerPage,
+	}
+	for i := range page.Users {
+		resp.Users = append(resp.Users, h.newAdminUserResponse(&page.Users[i]))
+	}
+	respondList(c, resp, resp.Users, ListMeta{Total: resp.Total, Page: resp.Page, PerPage: resp.PerPage})
+}
+
+// GetUser handles GET /api/admin/users/:id. The response carries an ETag for conditional updates.
+func (h *AdminUserHandler) GetUser(c *gin.Context) {
+	u, err := h.users.Get(c.Param("id"))
+	if err != nil {
+		respondAdminUserError(c, err)
+		return
+	}
+	etag := userETag(u)
+	c.Header("ETag", etag)
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	respondJSON(c, http.StatusOK, h.newAdminUserResponse(u))
+}
+
+// CreateUser handles POST /api/admin/users
+func (h *AdminUserHandler) CreateUser(c *gin.Context) {
+	var req AdminCreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondJSON(c, http.StatusBadRequest, gin.H{"error": "dados inválidos"})
+		return
+	}
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+
+	u, err := h.users.Create(service.NewUserInput{
+		Username:    req.Username,
+		Email:       req.Email,
+		DisplayName: req.DisplayName,
+		Password:    req.Password,
+		Role:        req.Role,
+		Active:      active,
+		Phone:       req.Phone,
+		ActorID:     c.GetString("userID"),
+		IP:          c.ClientIP(),
+	})
+	if err != nil {
+		respondAdminUserError(c, err)
+		return
+	}
+	logger.Info("Usuário criado via API admin", "user_id", u.ID, "admin_id", c.GetString("userID"))
+	respondJSON(c, http.StatusCreated, h.newAdminUserResponse(u))
+}
+
+// UpdateUser handles PATCH /api/admin/users/:id (role, active, display_name and/or must_change_password).
+// Requires If-Match with the ETag from GetUser (428 when missing, 412 when stale); "*" skips the check.
+func (h *AdminUserHandler) UpdateUser(c *gin.Context) {
+	ifMatch := c.GetHeader("If-Match")
+	if ifMatch == "" {
+		respondJSON(c, http.StatusPreconditionRequired, gin.H{"error": "cabeçalho If-Match obrigatório"})
+		return
+	}
+	expectedVersion := service.AnyVersion
+	if ifMatch != "*" {
+		v, ok := parseUserETag(ifMatch)
+		if !ok {
+			respondJSON(c, http.StatusPreconditionFailed, gin.H{"error": "ETag inválido"})
+			return
+		}
+		expectedVersion = v
+	}
+
+	var req AdminUpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondJSON(c, http.StatusBadRequest, gin.H{"error": "dados inválidos"})
+		return
+	}
+
+	u, err := h.users.Update(c.Param("id"), service.UserUpdate{
+		Role:               req.Role,
+		Active:             req.Active,
+		DisplayName:        req.DisplayName,
+		MustChangePassword: req.MustChangePassword,
+		Phone:              req.Phone,
+	}, expectedVersion)
+	if err != nil {
+		respondAdminUserError(c, err)
+		return
+	}
+	c.Header("ETag", userETag(u))
+	respondJSON(c, http.StatusOK, h.newAdminUserResponse(u))
+}
+
+// DeleteUser handles DELETE /api/admin/users/:id
+func (h *AdminUserHandler) DeleteUser(c *gin.Context) {
+	if err := h.users.Delete(c.Param("id")); err != nil {
+		respondAdminUserError(c, err)
+		return
+	}
+	logger.Info("Usuário excluído via API admin", "user_id", c.Param("id"), "admin_id", c.GetString("userID"))
+	c.Status(http.StatusNoContent)
+}
+
+// respondAdminUserError maps user service errors to JSON responses.
+func respondAdminUserError(c *gin.Context, err error) {
+	var validationErr *service.ValidationError
+	switch {
+	case errors.As(err, &validationErr) && validationErr.Fields.HasErrors():
+		respondJSON(c, http.StatusBadRequest, gin.H{"error": validationErr.Error(), "fields": validationErr.Fields})
+	case errors.As(err, &validationErr):
+		respondJSON(c, http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+	case errors.Is(err, service.ErrUserNotFound):
+		respondJSON(c, http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrUserExists):
+		respondJSON(c, http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrVersionConflict):
+		respondJSON(c, http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+	default:
+		respondJSON(c, http.StatusInternalServerError, gin.H{"error": "erro interno"})
+	}
+}
+
+// userETag builds the strong ETag for a user from its version column, e.g. "v3".
+func userETag(u *models.User) string {
+	return `"v` + strconv.FormatUint(uint64(u.Version), 10) + `"`
+}
+
+// parseUserETag extracts the version from an ETag produced by userETag (weak W/ prefix tolerated).
+func parseUserETag(etag string) (uint, bool) {
+	etag = strings.TrimPrefix(strings.TrimSpace(etag), "W/")
+	if !strings.HasPrefix(etag, `"v`) || !strings.HasSuffix(etag, `"`) || len(etag) < 4 {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(etag[2:len(etag)-1], 10, 64)
+	if err != nil || v == 0 {
+		return 0, false
+	}
+	return uint(v), true
+}
+
+// queryInt reads a positive integer query parameter, returning def when missing or invalid.
+func queryInt(c *gin.Context, key string, def int) int {
+	n, err := strconv.Atoi(c.Query(key))
+	if err != nil || n < 1 {
+		return def
+	}
+	return n
+}
@@ -4,15 +4,26 @@ package handlers
 import (
 	"bytes"
 	"context"
+	"encoding/hex"
 	"errors"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/a-h/templ"
 	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/basepath"
+	"github.com/lucas-varjao/gohtmx/internal/captcha"
+	"github.com/lucas-varjao/gohtmx/internal/config"
 	"github.com/lucas-varjao/gohtmx/internal/icons"
 	"github.com/lucas-varjao/gohtmx/internal/logger"
 	"github.com/lucas-varjao/gohtmx/internal/middleware"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+	"github.com/lucas-varjao/gohtmx/internal/securecookie"
 	"github.com/lucas-varjao/gohtmx/internal/service"
+	"github.com/lucas-varjao/gohtmx/internal/useragent"
 	"github.com/lucas-varjao/gohtmx/internal/validation"
 	"github.com/lucas-varjao/gohtmx/templates/components"
 
@@ -22,6 +33,48 @@ import (
 // AuthHandler handles authentication-related HTTP requests
 type AuthHandler struct {
 	authService service.AuthServiceInterface
+	cfg         *config.Config
+	captcha     *captcha.Guard
+	invites     InviteRedeemer             // nil rejects every invite token
+	audit       service.AuditRecorder      // nil only logs CAPTCHA exemptions
+	passkeys    Passkeys                   // nil answers the passkey routes with 404
+	challenges  LoginChallenges            // nil skips the new country challenge
+	devices     TrustedDevices             // nil hides "remember this device" at the new country challenge
+	deviceJar   *securecookie.SecureCookie // signs the device cookie; set along with devices
+	deletions   AccountDeletions           // nil answers the account deletion routes with 404
+	newLogins   NewLoginNotices            // nil answers the new login notice dismissal with 404
+	recovery    AccountRecovery            // nil answers POST /auth/recover with 404
+	backupCodes BackupCodes                // nil answers POST /api/2fa/backup-codes with 404
+}
+
+// InviteRedeemer checks and consumes registration invites (service.InviteService).
+type InviteRedeemer interface {
+	Lookup(token string) (*models.Invite, error)
+	Redeem(token, email string, register func() (*models.User, error)) (*models.User, error)
+}
+
+// UseInvites enables invite links on registration. Call it during setup, before serving requests.
+func (h *AuthHandler) UseInvites(invites InviteRedeemer) {
+	h.invites = invites
+}
+
+// UseAudit stores CAPTCHA exemptions of registrations in the audit log. Call it during setup, before serving requests.
+func (h *AuthHandler) UseAudit(audit service.AuditRecorder) {
+	h.audit = audit
+}
+
+// redeemInvite registers through the invite behind token.
+func (h *AuthHandler) redeemInvite(token, email string, register func() (*models.User, error)) (*models.User, error) {
+	if h.invites == nil {
+		return nil, service.ErrInviteNotFound
+	}
+	return h.invites.Redeem(token, email, register)
+}
+
+// isInviteError reports whether err means the invite can't be used (as opposed to the registration failing).
+func isInviteError(err error) bool {
+	return errors.Is(err, service.ErrInviteNotFound) || errors.Is(err, service.ErrInviteExpired) ||
+		errors.Is(err, service.ErrInviteUsed) || errors.Is(err, service.ErrInviteEmailMismatch)
 }
 
 // renderTemplError renders a templ component as HTML for HTMX error responses.
@@ -35,8 +88,11 @@ func renderTemplError(c *gin.Context, component templ.Component) {
 	}
 	// Determine target based on request path
 	target := "#login-error"
-	if c.Request.URL.Path == "/auth/register" {
+	switch c.Request.URL.Path {
+	case "/auth/register":
 		target = "#register-error"
+	case "/auth/login/verify":
+		target = "#login-challenge-error"
 	}
 	c.Header("HX-Retarget", target)
 	c.Header("HX-Reswap", "innerHTML")
@@ -44,20 +100,35 @@ func renderTemplError(c *gin.Context, component templ.Component) {
 	c.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
 }
 
-// renderHTMXError wraps a message with the standard error component.
-func renderHTMXError(c *gin.Context, message string) {
+// renderHTMXError wraps a message with the standard error component, followed by any out-of-band fragments.
+func renderHTMXError(c *gin.Context, message string, oob ...templ.Component) {
+	errorAlert := components.ErrorAlert(message, icons.Error())
+	renderTemplError(c, templ.Join(append([]templ.Component{errorAlert}, oob...)...))
+}
+
+// renderHTMXFieldErrors renders the summary alert plus out-of-band swaps for each of the form's field slots.
+func renderHTMXFieldErrors(c *gin.Context, message string, fields []string, fieldErrs validation.FieldErrors) {
 	errorAlert := components.ErrorAlert(message, icons.Error())
-	renderTemplError(c, errorAlert)
+	renderTemplError(c, templ.Join(errorAlert, components.FieldErrorsOOB(fields, fieldErrs)))
+}
+
+// registerFields are the registration form's field slots: the terms checkbox only exists with terms.version set.
+func (h *AuthHandler) registerFields() []string {
+	if !h.cfg.Features().Terms {
+		return validation.RegistrationFields
+	}
+	return append(slices.Clone(validation.RegistrationFields), validation.FieldTerms)
 }
 
 // handleLoginBindError logs and responds for binding errors (JSON or HTMX).
 func handleLoginBindError(c *gin.Context, err error) {
 	logger.Debug("Requisição de login com dados inválidos", "error", err, "ip", getClientIP(c))
+	message := bindErrorMessage(err)
 	if c.GetHeader("HX-Request") != "" {
-		renderHTMXError(c, err.Error())
+		renderHTMXError(c, message)
 		return
 	}
-	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	respondJSON(c, http.StatusBadRequest, gin.H{"error": message})
 }
 
 // handleLoginValidationError logs and responds for validation errors (JSON or HTMX).
@@ -67,26 +138,77 @@ func handleLoginValidationError(c *gin.Context, req LoginRequest, err error) {
 		renderHTMXError(c, err.Error())
 		return
 	}
-	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	respondJSON(c, http.StatusBadRequest, gin.H{"error": err.Error()})
 }
 
 // handleLoginAuthError maps service errors into user-facing responses.
-func handleLoginAuthError(c *gin.Context, err error) {
+func (h *AuthHandler) handleLoginAuthError(c *gin.Context, err error) {
 	status := http.StatusUnauthorized
 	message := "credenciais inválidas"
-	if errors.Is(err, service.ErrUserNotActive) {
+	switch {
+	case errors.Is(err, service.ErrUserNotActive):
 		message = "usuário inativo"
-	} else if err.Error() == "conta temporariamente bloqueada, tente novamente mais tarde" {
+	case errors.Is(err, service.ErrAccountLocked), errors.Is(err, service.ErrEmailNotVerified):
 		message = err.Error()
 	}
 
 	// HTMX: return 200 so the error fragment is swapped into #login-error (HTMX ignores body on 4xx/5xx)
 	if c.GetHeader("HX-Request") != "" {
-		renderHTMXError(c, message)
+		renderHTMXError(c, message, h.LoginCaptcha(c, true))
+		return
+	}
+
+	respondJSON(c, status, gin.H{"error": message})
+}
+
+// handleCaptchaError responds to a missing or rejected CAPTCHA as a validation error (400 JSON or
+// HTMX alert). refresh is the out-of-band CAPTCHA slot that replaces the used widget.
+func handleCaptchaError(c *gin.Context, err error, refresh templ.Component) {
+	message := captcha.ErrCaptchaInvalid.Error()
+	if errors.Is(err, captcha.ErrCaptchaRequired) {
+		message = err.Error()
+	}
+	logger.Debug("Requisição com CAPTCHA ausente ou inválido", "error", err, "path", c.Request.URL.Path, "ip", getClientIP(c))
+	if c.GetHeader("HX-Request") != "" {
+		renderHTMXError(c, message, refresh)
 		return
 	}
+	respondJSON(c, http.StatusBadRequest, gin.H{"error": message})
+}
+
+// captchaToken returns the widget token: the captcha_token field, else the provider's default form field.
+func captchaToken(c *gin.Context, token string) string {
+	if token != "" {
+		return token
+	}
+	if c.Request == nil {
+		return ""
+	}
+	for _, field := range captcha.ResponseFields {
+		if v := c.PostForm(field); v != "" {
+			return v
+		}
+	}
+	return ""
+}
 
-	c.JSON(status, gin.H{"error": message})
+// LoginCaptcha returns the login form's CAPTCHA slot (#login-captcha), holding the widget only when
+// logins from the client's IP currently require the challenge. oob marks it for an out-of-band swap.
+func (h *AuthHandler) LoginCaptcha(c *gin.Context, oob bool) templ.Component {
+	return captchaSlot("login-captcha", h.captcha, h.captcha.RequiredForLogin(getClientIP(c)), oob)
+}
+
+// RegisterCaptcha returns the registration form's CAPTCHA slot (#register-captcha).
+func (h *AuthHandler) RegisterCaptcha(oob bool) templ.Component {
+	return captchaSlot("register-captcha", h.captcha, h.captcha.RequiredForRegister(), oob)
+}
+
+func captchaSlot(slotID string, guard *captcha.Guard, required, oob bool) templ.Component {
+	var widget captcha.Widget
+	if required {
+		widget = guard.Widget()
+	}
+	return components.CaptchaSlot(slotID, widget.Provider, widget.SiteKey, widget.ScriptURL, oob)
 }
 
 // getUserAgent safely gets the user agent string from the request.
@@ -97,38 +219,72 @@ func getUserAgent(c *gin.Context) string {
 	return c.Request.UserAgent()
 }
 
-// setSessionCookie sets the session cookie with consistent flags.
+// setSessionCookie sets the session cookie with the same flags as the auth middleware.
 func setSessionCookie(c *gin.Context, sessionID string) {
-	// 30 days in seconds.
-	const cookieMaxAgeSec = 30 * 24 * 60 * 60
-	c.SetCookie(
-		middleware.SessionCookieName,
-		sessionID,
-		cookieMaxAgeSec,
-		"/",
-		"",
-		true, // secure
-		true, // httpOnly
-	)
-}
-
-// NewAuthHandler creates a new AuthHandler instance
+	middleware.SetSessionCookie(c, sessionID)
+}
+
+// rememberUsername pre-fills the next login form of this browser with username (config
+// login.remember_username); with the option off, a username remembered before is forgotten.
+func (h *AuthHandler) rememberUsername(c *gin.Context, username string) {
+	remember := h.cfg.Login.RememberUsername
+	if !remember.Enabled {
+		middleware.ClearRememberedUsername(c)
+		return
+	}
+	middleware.SetRememberedUsername(c, username, remember.MaxAge)
+}
+
+// msgRegistrationDisabled answers sign-up attempts when config registration.enabled is false.
+const msgRegistrationDisabled = "o cadastro de novas contas está desativado"
+
+// msgUsernameRequired answers sign-ups without a username unless config registration.generate_username is set
+// (same wording as the binding errors).
+const msgUsernameRequired = "usuário é obrigatório"
+
+// NewAuthHandler creates a new AuthHandler instance using the loaded app config (if any)
 func NewAuthHandler(authService service.AuthServiceInterface) *AuthHandler {
-	return &AuthHandler{authService: authService}
+	return NewAuthHandlerWithConfig(authService, config.GetConfig())
+}
+
+// NewAuthHandlerWithConfig creates a new AuthHandler with an explicit config (nil uses zero-value defaults)
+func NewAuthHandlerWithConfig(authService service.AuthServiceInterface, cfg *config.Config) *AuthHandler {
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	return &AuthHandler{authService: authService, cfg: cfg, captcha: captcha.NewGuard(cfg.Captcha)}
 }
 
 // LoginRequest represents the login request body (supports both JSON and form data)
 type LoginRequest struct {
 	Username string `json:"username" binding:"required" form:"username"`
 	Password string `json:"password" binding:"required" form:"password"`
+	// Next is the page the user was trying to reach before being sent to login (local paths only)
+	Next string `json:"next" form:"next"`
+	// CaptchaToken is only checked after repeated failures from the client's IP (config captcha.login_failures)
+	CaptchaToken string `json:"captcha_token" form:"captcha_token"`
 }
 
 // RegistrationRequest represents the registration request body (supports both JSON and form data)
 type RegistrationRequest struct {
-	Username    string `json:"username"     binding:"required" form:"username"`
+	// Username may be left empty when config registration.generate_username is set (one is generated)
+	Username    string `json:"username"     form:"username"`
 	Email       string `json:"email"        binding:"required" form:"email"`
 	Password    string `json:"password"     binding:"required" form:"password"`
 	DisplayName string `json:"display_name" binding:"required" form:"display_name"`
+	// CaptchaToken is required when config captcha.on_register is set
+	CaptchaToken string `json:"captcha_token" form:"captcha_token"`
+	// Invite is the token of an admin invite link (GET /register?invite=...); required when registration is disabled
+	Invite string `json:"invite" form:"invite"`
+	// AcceptTerms is the terms of use checkbox; required when config terms.version is set
+	AcceptTerms bool `json:"accept_terms" form:"accept_terms"`
+}
+
+// ChangePasswordRequest represents the change password request body (supports both JSON and form data)
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required" form:"current_password"`
+	NewPassword     string `json:"new_password"     binding:"required" form:"new_password"`
+	ConfirmPassword string `json:"confirm_password" binding:"required" form:"confirm_password"`
 }
 
 // PasswordResetRequest represents the password reset request body
@@ -157,27 +313,102 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	ip := getClientIP(c)
 	userAgent := getUserAgent(c)
 
-	response, err := h.authService.Login(req.Username, req.Password, ip, userAgent)
+	// After repeated failures from this IP, a CAPTCHA is required before the credentials are even checked
+	if err := h.captcha.Check(c.Request.Context(), h.captcha.RequiredForLogin(ip), captchaToken(c, req.CaptchaToken), ip); err != nil {
+		handleCaptchaError(c, err, h.LoginCaptcha(c, true))
+		return
+	}
+
+	metadata := auth.SessionMetadata{UserAgent: userAgent, IP: ip, Country: h.requestCountry(c), DeviceToken: h.deviceToken(c)}
+	response, err := h.authService.LoginFrom(req.Username, req.Password, metadata)
 	if err != nil {
-		handleLoginAuthError(c, err)
+		var challenge *service.LoginChallengeError
+		if errors.As(err, &challenge) {
+			h.respondLoginChallenge(c, challenge, req.Next)
+			return
+		}
+		var pending *service.DeletionPendingError
+		if errors.As(err, &pending) {
+			h.respondDeletionPending(c, pending)
+			return
+		}
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			h.captcha.LoginFailed(ip)
+		}
+		h.handleLoginAuthError(c, err)
 		return
 	}
+	h.captcha.LoginSucceeded(ip)
+	// For middleware running after the handler (e.g. onboarding); JSON clients get first_login in the body
+	c.Set("firstLogin", response.FirstLogin)
 
 	// Set session cookie for browser sessions.
 	setSessionCookie(c, response.SessionID)
+	h.rememberUsername(c, response.User.Identifier)
 
-	// Check if HTMX request - redirect by role (admin → dashboard, others → home)
+	// Check if HTMX request - redirect to the intended page, falling back by role (admin → dashboard, others → home)
 	if c.GetHeader("HX-Request") != "" {
-		redirectTo := "/"
-		if response.User.Role == "admin" {
-			redirectTo = "/admin"
-		}
-		c.Header("HX-Redirect", redirectTo)
+		c.Header("HX-Redirect", basepath.URL(h.postLoginRedirect(c, req.Next, response.User.Role)))
 		c.Status(http.StatusOK)
 		return
 	}
 
-	c.JSON(http.StatusOK, response)
+	respondJSON(c, http.StatusOK, response)
+}
+
+// disallowedDomain adds ErrEmailDomainNotAllowed to fieldErrs when a sign-up without invite uses an email
+// outside registration.allowed_email_domains, and reports whether it did. Invites are admin-approved, so exempt.
+func (h *AuthHandler) disallowedDomain(invite, email string, fieldErrs validation.FieldErrors) bool {
+	if invite != "" || fieldErrs[validation.FieldEmail] != "" {
+		return false
+	}
+	if err := validation.ValidateEmailDomain(email, h.cfg.Registration.AllowedEmailDomains); err != nil {
+		fieldErrs[validation.FieldEmail] = err.Error()
+		return true
+	}
+	return false
+}
+
+// termsMissing adds ErrTermsNotAccepted to fieldErrs when config terms.version requires accepting the
+// terms of use and the checkbox wasn't ticked, and reports whether it did.
+func (h *AuthHandler) termsMissing(accepted bool, fieldErrs validation.FieldErrors) bool {
+	if !h.cfg.Features().Terms || accepted {
+		return false
+	}
+	fieldErrs[validation.FieldTerms] = validation.ErrTermsNotAccepted.Error()
+	return true
+}
+
+// postLoginRedirect picks where to send the user after login. The form field takes precedence over
+// the ?next= query parameter; anything that isn't a local path is ignored to prevent open redirects.
+// Without a valid next, the role's landing page is used.
+func (h *AuthHandler) postLoginRedirect(c *gin.Context, next, role string) string {
+	fallback := h.landingPath(role)
+	if next == "" {
+		next = c.Query("next")
+	}
+	if next != "" && validation.ValidateRedirectPath(next) != nil {
+		logger.Warn("Destino de redirecionamento rejeitado", "next", next, "ip", getClientIP(c))
+	}
+	return validation.SafeRedirectPath(next, fallback)
+}
+
+// landingPath returns the configured landing page for role (login.landing_paths), falling back to
+// /admin for admins and / for everyone else. Misconfigured non-local paths are ignored.
+func (h *AuthHandler) landingPath(role string) string {
+	fallback := "/"
+	if role == "admin" {
+		fallback = "/admin"
+	}
+	path, ok := h.cfg.Login.LandingPaths[role]
+	if !ok {
+		return fallback
+	}
+	if validation.ValidateRedirectPath(path) != nil {
+		logger.Warn("Página inicial configurada para a role não é um caminho local", "role", role, "path", path)
+		return fallback
+	}
+	return path
 }
 
 // Logout handles user logout
@@ -186,15 +417,15 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 	if !exists {
 		ip := getClientIP(c)
 		logger.Debug("Tentativa de logout sem sessão", "ip", ip)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "não autenticado"})
+		respondJSON(c, http.StatusUnauthorized, gin.H{"error": "não autenticado"})
 		return
 	}
 
 	sessionIDStr := sessionID.(string)
-	if err := h.authService.Logout(sessionIDStr); err != nil {
+	if err := h.authService.Logout(sessionIDStr, auth.LogoutReasonUser); err != nil {
 		ip := getClientIP(c)
 		logger.Error("Erro ao fazer logout", "error", err, "session_id", sessionIDStr, "ip", ip)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "falha ao fazer logout"})
+		respondJSON(c, http.StatusInternalServerError, gin.H{"error": "falha ao fazer logout"})
 		return
 	}
 
@@ -203,8 +434,11 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 
 	// Clear session cookie
 	middleware.ClearSessionCookie(c)
+	if h.cfg.Login.RememberUsername.ClearOnLogout {
+		middleware.ClearRememberedUsername(c)
+	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "logout realizado com sucesso"})
+	respondJSON(c, http.StatusOK, gin.H{"message": "logout realizado com sucesso"})
 }
 
 // Register handles new user registration with comprehensive validation
@@ -213,56 +447,114 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	// Support both JSON and form data (for HTMX forms)
 	if err := c.ShouldBind(&req); err != nil {
 		logger.Debug("Requisição de registro com dados inválidos", "error", err, "ip", getClientIP(c))
+		message := bindErrorMessage(err)
 		if c.GetHeader("HX-Request") != "" {
-			errorAlert := components.ErrorAlert(err.Error(), icons.Error())
-			renderTemplError(c, errorAlert)
+			renderHTMXFieldErrors(c, message, h.registerFields(), bindFieldErrors(err))
 			return
 		}
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondJSON(c, http.StatusBadRequest, gin.H{"error": message})
 		return
 	}
 
-	// Validate all registration data
-	if err := validation.ValidateRegistrationRequest(
-		req.Username,
-		req.Email,
-		req.Password,
-		req.DisplayName,
-	); err != nil {
-		logger.Debug("Requisição de registro com validação falhada", "error", err, "username", req.Username, "email", req.Email, "ip", getClientIP(c))
+	// With registration closed, only invited users can sign up
+	if req.Invite == "" && !h.cfg.Features().Registration {
+		logger.Debug("Tentativa de registro com cadastro desativado", "ip", getClientIP(c))
 		if c.GetHeader("HX-Request") != "" {
-			errorAlert := components.ErrorAlert(err.Error(), icons.Error())
-			renderTemplError(c, errorAlert)
+			renderHTMXError(c, msgRegistrationDisabled)
 			return
 		}
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondJSON(c, http.StatusForbidden, gin.H{"error": msgRegistrationDisabled})
 		return
 	}
 
-	// Forward to service layer
-	user, err := h.authService.Register(req.Username, req.Email, req.Password, req.DisplayName)
+	// Validate all registration data, collecting one message per field
+	fieldErrs := validation.ValidateRegistrationFields(req.Username, req.Email, req.Password, req.DisplayName)
+	if req.Username == "" {
+		if h.cfg.Features().UsernameGeneration {
+			// AuthService.Register picks a valid one
+			delete(fieldErrs, validation.FieldUsername)
+		} else {
+			fieldErrs[validation.FieldUsername] = msgUsernameRequired
+		}
+	}
+	if fieldErrs.HasErrors() || h.disallowedDomain(req.Invite, req.Email, fieldErrs) || h.termsMissing(req.AcceptTerms, fieldErrs) {
+		message := fieldErrs.First(h.registerFields())
+		logger.Debug("Requisição de registro com validação falhada", "fields", fieldErrs, "username", req.Username, "email", req.Email, "ip", getClientIP(c))
+		if c.GetHeader("HX-Request") != "" {
+			renderHTMXFieldErrors(c, message, h.registerFields(), fieldErrs)
+			return
+		}
+		resp := gin.H{"error": message, "fields": fieldErrs}
+		if _, ok := fieldErrs[validation.FieldPassword]; ok {
+			// The whole checklist, so API clients can mark every unmet requirement like the form does
+			resp["password_requirements"] = validation.ValidatePasswordDetailed(req.Password, req.Username, req.Email, req.DisplayName)
+		}
+		respondJSON(c, http.StatusBadRequest, resp)
+		return
+	}
+
+	ip := getClientIP(c)
+	captchaRequired := h.captcha.RequiredForRegister()
+	bypass := ""
+	if captchaRequired {
+		if via, ok := h.captcha.RegisterBypass(ip, c.GetHeader(captcha.BypassHeader)); ok {
+			captchaRequired, bypass = false, via
+			logger.Info("CAPTCHA dispensado no registro", "via", via, "username", req.Username, "ip", ip)
+		}
+	}
+	if err := h.captcha.Check(c.Request.Context(), captchaRequired, captchaToken(c, req.CaptchaToken), ip); err != nil {
+		handleCaptchaError(c, err, h.RegisterCaptcha(true))
+		return
+	}
+
+	// Forward to service layer; an invite is claimed around the registration and decides the role
+	register := func() (*models.User, error) {
+		return h.authService.Register(req.Username, req.Email, req.Password, req.DisplayName)
+	}
+	var user *models.User
+	var err error
+	if req.Invite != "" {
+		user, err = h.redeemInvite(req.Invite, req.Email, register)
+	} else {
+		user, err = register()
+	}
 	if err != nil {
-		logger.Debug("Erro ao registrar usuário", "error", err, "username", req.Username, "email", req.Email, "ip", getClientIP(c))
+		logger.Debug("Erro ao registrar usuário", "error", err, "username", req.Username, "email", req.Email, "ip", ip)
 		if c.GetHeader("HX-Request") != "" {
-			errorAlert := components.ErrorAlert(err.Error(), icons.Error())
-			renderTemplError(c, errorAlert)
+			// The CAPTCHA token was consumed; refresh the widget for the next try
+			renderHTMXError(c, err.Error(), h.RegisterCaptcha(true))
 			return
 		}
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		status := http.StatusBadRequest
+		if isInviteError(err) {
+			status = http.StatusForbidden
+		}
+		respondJSON(c, status, gin.H{"error": err.Error()})
 		return
 	}
 
+	// Not fatal: without the record, the terms gate asks for the acceptance again on the first visit
+	if h.cfg.Features().Terms {
+		if err := h.authService.AcceptTerms(strconv.FormatUint(uint64(user.ID), 10), h.cfg.Terms.Version); err != nil {
+			logger.Error("Erro ao registrar aceite dos termos no cadastro", "error", err, "user_id", user.ID)
+		}
+	}
+
+	if bypass != "" && h.audit != nil {
+		_ = h.audit.Record(&models.AuditLog{Action: service.AuditActionCaptchaBypass, TargetID: user.ID, IP: ip, Details: "via=" + bypass})
+	}
+
 	// Strip sensitive data
 	user.PasswordHash = ""
 
 	// Check if HTMX request - redirect to login
 	if c.GetHeader("HX-Request") != "" {
-		c.Header("HX-Redirect", "/login")
+		c.Header("HX-Redirect", basepath.URL("/login"))
 		c.Status(http.StatusOK)
 		return
 	}
 
-	c.JSON(http.StatusOK, user)
+	respondJSON(c, http.StatusOK, user)
 }
 
 // RequestPasswordReset handles password reset requests
@@ -273,28 +565,66 @@ func (h *AuthHandler) RequestPasswordReset(c *gin.Context) {
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		logger.Debug("Requisição de reset de senha com JSON inválido", "error", err, "ip", getClientIP(c))
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondJSON(c, http.StatusBadRequest, gin.H{"error": bindErrorMessage(err)})
 		return
 	}
 
 	// Validate email
 	if err := validation.ValidateEmail(req.Email); err != nil {
 		logger.Debug("Requisição de reset de senha com email inválido", "error", err, "email", req.Email, "ip", getClientIP(c))
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondJSON(c, http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := h.authService.RequestPasswordReset(req.Email); err != nil {
+	if err := h.authService.RequestPasswordReset(req.Email, h.resetBinding(c, true)); err != nil {
 		if err.Error() == "invalid email format" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			respondJSON(c, http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 		// Don't reveal if email exists for security reasons
-		c.JSON(http.StatusOK, gin.H{"message": "se o email existir, um link de recuperação será enviado"})
+		respondJSON(c, http.StatusOK, gin.H{"message": "se o email existir, um link de recuperação será enviado"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "se o email existir, um link de recuperação será enviado"})
+	respondJSON(c, http.StatusOK, gin.H{"message": "se o email existir, um link de recuperação será enviado"})
+}
+
+// Values of password.reset_binding
+const (
+	resetBindingIP     = "ip"
+	resetBindingCookie = "cookie"
+)
+
+// resetDeviceCookie identifies the browser that asked for a password reset (password.reset_binding: cookie).
+const resetDeviceCookie = "reset_device"
+
+// resetBinding returns the context a reset token is bound to under password.reset_binding: the client
+// IP, or the device cookie (created when issue is set and the browser has none yet). Empty when off.
+func (h *AuthHandler) resetBinding(c *gin.Context, issue bool) string {
+	switch h.cfg.Password.ResetBinding {
+	case resetBindingIP:
+		return getClientIP(c)
+	case resetBindingCookie:
+		if device, err := c.Cookie(resetDeviceCookie); err == nil && device != "" {
+			return device
+		}
+		if !issue {
+			return ""
+		}
+		const deviceByteSize = 32
+		deviceBytes := make([]byte, deviceByteSize)
+		if _, err := auth.GenerateRandomBytes(deviceBytes); err != nil {
+			logger.Error("Erro ao gerar cookie de dispositivo", "error", err)
+			return ""
+		}
+		device := hex.EncodeToString(deviceBytes)
+		// Outlives the 1h token so requesting again from the same browser keeps the same binding
+		const deviceMaxAgeSec = 30 * 24 * 60 * 60
+		c.SetCookie(resetDeviceCookie, device, deviceMaxAgeSec, basepath.CookiePath(), "", true, true)
+		return device
+	default:
+		return ""
+	}
 }
 
 // ResetPassword handles password reset with token validation
@@ -302,48 +632,401 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 	var req PasswordResetRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		logger.Debug("Requisição de reset de senha com JSON inválido", "error", err, "ip", getClientIP(c))
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondJSON(c, http.StatusBadRequest, gin.H{"error": bindErrorMessage(err)})
 		return
 	}
 
 	// Validate password reset request
 	if err := validation.ValidatePasswordReset(req.Token, req.NewPassword, req.ConfirmPassword); err != nil {
 		logger.Debug("Requisição de reset de senha com validação falhada", "error", err, "ip", getClientIP(c))
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondJSON(c, http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := h.authService.ResetPassword(req.Token, req.NewPassword); err != nil {
+	if err := h.authService.ResetPassword(req.Token, req.NewPassword, h.resetBinding(c, false)); err != nil {
 		status := http.StatusBadRequest
 		ip := getClientIP(c)
 		var message string
+		var validationErr *service.ValidationError
 		switch {
+		case errors.As(err, &validationErr):
+			message = err.Error()
 		case errors.Is(err, service.ErrInvalidToken):
 			message = "token inválido"
 			logger.Warn("Tentativa de reset de senha com token inválido", "ip", ip)
 		case errors.Is(err, service.ErrExpiredToken):
 			message = "token expirado"
 			logger.Warn("Tentativa de reset de senha com token expirado", "ip", ip)
+		case errors.Is(err, service.ErrPasswordReused):
+			message = err.Error()
+		case errors.Is(err, service.ErrResetContextMismatch):
+			status = http.StatusForbidden
+			message = err.Error()
 		default:
 			message = "falha ao redefinir senha"
 			logger.Error("Erro ao resetar senha", "error", err, "ip", ip)
 		}
-		c.JSON(status, gin.H{"error": message})
+		respondJSON(c, status, gin.H{"error": message})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "senha redefinida com sucesso"})
+	respondJSON(c, http.StatusOK, gin.H{"message": "senha redefinida com sucesso"})
+}
+
+// ChangePassword handles a password change by the logged-in user (current password required)
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		respondJSON(c, http.StatusUnauthorized, gin.H{"error": "não autenticado"})
+		return
+	}
+	userData := user.(*auth.UserData)
+
+	var req ChangePasswordRequest
+	if err := c.ShouldBind(&req); err != nil {
+		logger.Debug("Requisição de troca de senha com dados inválidos", "error", err, "ip", getClientIP(c))
+		respondJSON(c, http.StatusBadRequest, gin.H{"error": bindErrorMessage(err)})
+		return
+	}
+
+	if err := validation.ValidatePasswordChange(req.NewPassword, req.ConfirmPassword, userData.Identifier, userData.Email, userData.DisplayName); err != nil {
+		logger.Debug("Requisição de troca de senha com validação falhada", "error", err, "user_id", userData.ID)
+		respondJSON(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.ChangePassword(userData.ID, req.CurrentPassword, req.NewPassword); err != nil {
+		if errors.Is(err, service.ErrWrongPassword) || errors.Is(err, service.ErrPasswordReused) {
+			respondJSON(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		logger.Error("Erro ao trocar senha", "error", err, "user_id", userData.ID)
+		respondJSON(c, http.StatusInternalServerError, gin.H{"error": "falha ao trocar senha"})
+		return
+	}
+
+	respondJSON(c, http.StatusOK, gin.H{"message": "senha alterada com sucesso"})
+}
+
+// EmailChangeRequest represents the body of POST /api/account/email
+type EmailChangeRequest struct {
+	Email string `json:"email" binding:"required" form:"email"`
+}
+
+// RequestEmailChange starts an email change: the new address must be confirmed through the emailed link
+func (h *AuthHandler) RequestEmailChange(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		respondJSON(c, http.StatusUnauthorized, gin.H{"error": "não autenticado"})
+		return
+	}
+	userData := user.(*auth.UserData)
+
+	var req EmailChangeRequest
+	if err := c.ShouldBind(&req); err != nil {
+		respondJSON(c, http.StatusBadRequest, gin.H{"error": bindErrorMessage(err)})
+		return
+	}
+	newEmail := strings.TrimSpace(req.Email)
+	if err := validation.ValidateEmail(newEmail); err != nil {
+		respondJSON(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// Otherwise a sign-up with an allowed domain could move to any email afterwards
+	if err := validation.ValidateEmailDomain(newEmail, h.cfg.Registration.AllowedEmailDomains); err != nil {
+		respondJSON(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.RequestEmailChange(userData.ID, newEmail); err != nil {
+		switch {
+		case errors.Is(err, service.ErrEmailTaken):
+			respondJSON(c, http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrSameEmail):
+			respondJSON(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			logger.Error("Erro ao solicitar troca de email", "error", err, "user_id", userData.ID)
+			respondJSON(c, http.StatusInternalServerError, gin.H{"error": "falha ao solicitar troca de email"})
+		}
+		return
+	}
+
+	respondJSON(c, http.StatusAccepted, gin.H{"message": "enviamos um link de confirmação para o novo email"})
+}
+
+// ResendVerification emails a new verification link to the logged-in user (POST /api/account/verify-email).
+// HTMX requests (the /verify-email page) get an alert fragment; errors are swapped in with status 200 too.
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		respondJSON(c, http.StatusUnauthorized, gin.H{"error": "não autenticado"})
+		return
+	}
+	userData := user.(*auth.UserData)
+
+	status, message := http.StatusAccepted, "enviamos um novo link de verificação para seu email"
+	err := h.authService.ResendVerification(userData.ID)
+	switch {
+	case err == nil:
+	case errors.Is(err, service.ErrAlreadyVerified):
+		status, message = http.StatusConflict, err.Error()
+	default:
+		logger.Error("Erro ao reenviar verificação de email", "error", err, "user_id", userData.ID)
+		status, message = http.StatusInternalServerError, "falha ao reenviar o email de verificação"
+	}
+
+	if c.GetHeader("HX-Request") != "" {
+		alert := components.SuccessAlert(message, icons.Success())
+		if err != nil {
+			alert = components.ErrorAlert(message, icons.Error())
+		}
+		var buf bytes.Buffer
+		if renderErr := alert.Render(c.Request.Context(), &buf); renderErr != nil {
+			c.String(http.StatusInternalServerError, "Erro ao processar resposta")
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
+		return
+	}
+	if err != nil {
+		respondJSON(c, status, gin.H{"error": message})
+		return
+	}
+	respondJSON(c, status, gin.H{"message": message})
+}
+
+// AcceptTerms handles POST /api/account/terms: the current user accepts the current terms of use
+// (config terms.version). HTMX requests are sent on to the home page.
+func (h *AuthHandler) AcceptTerms(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		respondJSON(c, http.StatusUnauthorized, gin.H{"error": "não autenticado"})
+		return
+	}
+	userData := user.(*auth.UserData)
+	version := h.cfg.Terms.Version
+	if version == "" {
+		respondJSON(c, http.StatusNotFound, gin.H{"error": "não há termos de uso para aceitar"})
+		return
+	}
+
+	if err := h.authService.AcceptTerms(userData.ID, version); err != nil {
+		logger.Error("Erro ao registrar aceite dos termos", "error", err, "user_id", userData.ID)
+		const message = "falha ao registrar o aceite dos termos"
+		if c.GetHeader("HX-Request") != "" {
+			// Swapped into the page's result slot (HTMX ignores the body of 5xx responses)
+			var buf bytes.Buffer
+			if renderErr := components.ErrorAlert(message, icons.Error()).Render(c.Request.Context(), &buf); renderErr != nil {
+				c.String(http.StatusInternalServerError, "Erro ao processar resposta")
+				return
+			}
+			c.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
+			return
+		}
+		respondJSON(c, http.StatusInternalServerError, gin.H{"error": message})
+		return
+	}
+	if c.GetHeader("HX-Request") != "" {
+		c.Header("HX-Redirect", basepath.URL("/"))
+		c.Status(http.StatusOK)
+		return
+	}
+	respondJSON(c, http.StatusOK, gin.H{"message": "termos de uso aceitos", "terms_version": version})
+}
+
+// ConfirmEmailChange handles the link sent to the new address, or to the current one to verify it
+// (GET /auth/confirm-email?token=...)
+func (h *AuthHandler) ConfirmEmailChange(c *gin.Context) {
+	token := c.Query("token")
+	if err := validation.ValidateResetToken(token); err != nil {
+		respondJSON(c, http.StatusBadRequest, gin.H{"error": "token inválido"})
+		return
+	}
+
+	if _, err := h.authService.ConfirmEmailChange(token); err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidToken):
+			respondJSON(c, http.StatusBadRequest, gin.H{"error": "token inválido"})
+		case errors.Is(err, service.ErrExpiredToken):
+			respondJSON(c, http.StatusBadRequest, gin.H{"error": "token expirado"})
+		case errors.Is(err, service.ErrEmailTaken):
+			respondJSON(c, http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			logger.Error("Erro ao confirmar troca de email", "error", err, "ip", getClientIP(c))
+			respondJSON(c, http.StatusInternalServerError, gin.H{"error": "falha ao confirmar troca de email"})
+		}
+		return
+	}
+
+	respondJSON(c, http.StatusOK, gin.H{"message": "email confirmado com sucesso"})
 }
 
 // GetCurrentUser returns the currently authenticated user
 func (h *AuthHandler) GetCurrentUser(c *gin.Context) {
 	user, exists := c.Get("user")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "não autenticado"})
+		respondJSON(c, http.StatusUnauthorized, gin.H{"error": "não autenticado"})
 		return
 	}
 
-	c.JSON(http.StatusOK, user.(*auth.UserData))
+	respondJSON(c, http.StatusOK, user.(*auth.UserData))
+}
+
+// CountSessions handles GET /api/me/sessions/count: how many active sessions the current user has,
+// e.g. for a "logged in on N devices" hint in the navbar. Only the caller's own sessions are counted.
+func (h *AuthHandler) CountSessions(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		respondJSON(c, http.StatusUnauthorized, gin.H{"error": "não autenticado"})
+		return
+	}
+	userData := user.(*auth.UserData)
+
+	count, err := h.authService.CountSessions(userData.ID)
+	if err != nil {
+		logger.Error("Erro ao contar sessões do usuário", "error", err, "user_id", userData.ID)
+		respondJSON(c, http.StatusInternalServerError, gin.H{"error": "falha ao contar as sessões"})
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	respondJSON(c, http.StatusOK, gin.H{"count": count})
+}
+
+// RevokeOtherSessions handles POST /api/sessions/revoke-others: "sign out everywhere else". Every session
+// of the caller but the current one ends, so a lost or shared device loses access while this one stays in.
+func (h *AuthHandler) RevokeOtherSessions(c *gin.Context) {
+	userID, sessionID := c.GetString("userID"), c.GetString("sessionID")
+	if userID == "" || sessionID == "" {
+		respondJSON(c, http.StatusUnauthorized, gin.H{"error": "não autenticado"})
+		return
+	}
+	if err := h.authService.LogoutOthers(userID, sessionID); err != nil {
+		logger.Error("Erro ao encerrar as outras sessões", "error", err, "user_id", userID, "ip", getClientIP(c))
+		respondJSON(c, http.StatusInternalServerError, gin.H{"error": "falha ao encerrar as outras sessões"})
+		return
+	}
+	logger.Info("Outras sessões encerradas pelo usuário", "user_id", userID, "ip", getClientIP(c))
+	respondJSON(c, http.StatusOK, gin.H{"message": "sessões dos outros dispositivos encerradas"})
+}
+
+// SessionStatusResponse describes the current session's expiry for the idle-logout warning.
+// ExpiresIn and WarnBefore are in seconds; WarnBefore 0 means the prompt is disabled (config session.warn_before).
+type SessionStatusResponse struct {
+	ExpiresAt  time.Time `json:"expires_at"`
+	ExpiresIn  int       `json:"expires_in"`
+	WarnBefore int       `json:"warn_before"`
+}
+
+func (h *AuthHandler) sessionStatus(c *gin.Context, session *auth.Session) {
+	middleware.SetSessionExpiresIn(c, session)
+	respondJSON(c, http.StatusOK, SessionStatusResponse{
+		ExpiresAt:  session.ExpiresAt,
+		ExpiresIn:  max(int(time.Until(session.ExpiresAt).Seconds()), 0),
+		WarnBefore: int(h.cfg.Session.WarnBefore.Seconds()),
+	})
+}
+
+// SessionStatus handles GET /api/session: how long until the current session expires
+func (h *AuthHandler) SessionStatus(c *gin.Context) {
+	session, exists := c.Get("session")
+	if !exists {
+		respondJSON(c, http.StatusUnauthorized, gin.H{"error": "não autenticado"})
+		return
+	}
+	h.sessionStatus(c, session.(*auth.Session))
+}
+
+// SessionDetailsResponse describes the caller's current session for a "this device" card. ExpiresIn is
+// in seconds; Device is parsed from UserAgent.
+type SessionDetailsResponse struct {
+	CreatedAt time.Time       `json:"created_at"`
+	ExpiresAt time.Time       `json:"expires_at"`
+	ExpiresIn int             `json:"expires_in"`
+	IP        string          `json:"ip,omitempty"`
+	UserAgent string          `json:"user_agent,omitempty"`
+	Device    useragent.Agent `json:"device"`
+}
+
+// SessionDetails handles GET /api/me/session: when the current session started and expires, and the IP
+// and device it was opened from. Only the caller's current session is shown.
+func (h *AuthHandler) SessionDetails(c *gin.Context) {
+	value, exists := c.Get("session")
+	if !exists {
+		respondJSON(c, http.StatusUnauthorized, gin.H{"error": "não autenticado"})
+		return
+	}
+	session := value.(*auth.Session)
+	c.Header("Cache-Control", "no-store")
+	respondJSON(c, http.StatusOK, SessionDetailsResponse{
+		CreatedAt: session.CreatedAt,
+		ExpiresAt: session.ExpiresAt,
+		ExpiresIn: max(int(time.Until(session.ExpiresAt).Seconds()), 0),
+		IP:        session.IP,
+		UserAgent: session.UserAgent,
+		Device:    useragent.Parse(session.UserAgent),
+	})
+}
+
+// NewLoginNotices clears the notice a session shows about a login made from another device
+// (service.ConcurrentLoginService).
+type NewLoginNotices interface {
+	Dismiss(sessionID string) error
+}
+
+// UseNewLoginNotices enables POST /api/session/new-login/dismiss. Call it during setup, before serving requests.
+func (h *AuthHandler) UseNewLoginNotices(notices NewLoginNotices) {
+	h.newLogins = notices
+}
+
+// DismissNewLogin handles POST /api/session/new-login/dismiss: the current session stops showing the
+// notice of a concurrent login (config login.concurrent_login_notice.banner). 204 on success.
+func (h *AuthHandler) DismissNewLogin(c *gin.Context) {
+	if h.newLogins == nil {
+		respondJSON(c, http.StatusNotFound, gin.H{"error": "avisos de novo login desativados"})
+		return
+	}
+	sessionID := c.GetString("sessionID")
+	if sessionID == "" {
+		respondJSON(c, http.StatusUnauthorized, gin.H{"error": "não autenticado"})
+		return
+	}
+	if err := h.newLogins.Dismiss(sessionID); err != nil {
+		logger.Error("Erro ao dispensar aviso de novo login", "error", err)
+		respondJSON(c, http.StatusInternalServerError, gin.H{"error": "falha ao dispensar aviso"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// PingSession handles GET /api/session/ping, the keep-alive sent while the user is active on a page.
+// AuthMiddleware already validated the session and slid its expiry (up to the absolute limit) and set
+// X-Session-Expires-In; the response is an empty 204 so it costs nothing and reveals nothing else.
+func (h *AuthHandler) PingSession(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusNoContent)
+}
+
+// ExtendSession handles POST /api/session/extend (keep-alive): refreshes the session expiry,
+// never beyond the absolute limit (config session.max_lifetime)
+func (h *AuthHandler) ExtendSession(c *gin.Context) {
+	sessionID := c.GetString("sessionID")
+	if sessionID == "" {
+		respondJSON(c, http.StatusUnauthorized, gin.H{"error": "não autenticado"})
+		return
+	}
+
+	session, err := h.authService.ExtendSession(sessionID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidToken), errors.Is(err, service.ErrExpiredToken), errors.Is(err, service.ErrUserNotActive):
+			middleware.ClearSessionCookie(c)
+			respondJSON(c, http.StatusUnauthorized, gin.H{"error": "sessão inválida"})
+		default:
+			respondJSON(c, http.StatusInternalServerError, gin.H{"error": "falha ao estender sessão"})
+		}
+		return
+	}
+	h.sessionStatus(c, session)
 }
 
 // getClientIP safely gets the client IP from the context
@@ -3,17 +3,23 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/captcha"
+	"github.com/lucas-varjao/gohtmx/internal/config"
+	"github.com/lucas-varjao/gohtmx/internal/middleware"
 	"github.com/lucas-varjao/gohtmx/internal/models"
 	"github.com/lucas-varjao/gohtmx/internal/service"
+	"github.com/lucas-varjao/gohtmx/internal/validation"
 
 	"github.com/gin-gonic/gin"
 )
@@ -21,40 +27,99 @@ import (
 // MockAuthService implements the service.AuthServiceInterface interface
 type MockAuthService struct {
 	LoginFunc                func(username, password, ip, userAgent string) (*service.LoginResponse, error)
+	LoginFromFunc            func(username, password string, metadata auth.SessionMetadata) (*service.LoginResponse, error)
 	ValidateSessionFunc      func(sessionID string) (*auth.Session, *auth.UserData, error)
-	LogoutFunc               func(sessionID string) error
-	LogoutAllFunc            func(userID string) error
+	ExtendSessionFunc        func(sessionID string) (*auth.Session, error)
+	LogoutFunc               func(sessionID string, reason auth.LogoutReason) error
+	LogoutAllFunc            func(userID string, reason auth.LogoutReason) error
+	LogoutOthersFunc         func(userID, keepSessionID string) error
+	CountSessionsFunc        func(userID string) (int64, error)
 	RegisterFunc             func(username, email, password, displayName string) (*models.User, error)
-	RequestPasswordResetFunc func(email string) error
-	ResetPasswordFunc        func(token, newPassword string) error
+	RequestPasswordResetFunc func(email, binding string) error
+	ResetPasswordFunc        func(token, newPassword, binding string) error
+	ChangePasswordFunc       func(userID, currentPassword, newPassword string) error
+	RequestEmailChangeFunc   func(userID, newEmail string) error
+	ConfirmEmailChangeFunc   func(token string) (*models.User, error)
+	ResendVerificationFunc   func(userID string) error
+	AcceptTermsFunc          func(userID, version string) error
+	IsUsernameAvailableFunc  func(username string) (bool, error)
+	IsEmailAvailableFunc     func(email string) (bool, error)
 }
 
 func (m *MockAuthService) Login(username, password, ip, userAgent string) (*service.LoginResponse, error) {
 	return m.LoginFunc(username, password, ip, userAgent)
 }
 
+// LoginFrom falls back to LoginFunc, so tests that don't care about the metadata only set that one.
+func (m *MockAuthService) LoginFrom(username, password string, metadata auth.SessionMetadata) (*service.LoginResponse, error) {
+	if m.LoginFromFunc != nil {
+		return m.LoginFromFunc(username, password, metadata)
+	}
+	return m.LoginFunc(username, password, metadata.IP, metadata.UserAgent)
+}
+
 func (m *MockAuthService) ValidateSession(sessionID string) (*auth.Session, *auth.UserData, error) {
 	return m.ValidateSessionFunc(sessionID)
 }
 
-func (m *MockAuthService) Logout(sessionID string) error {
-	return m.LogoutFunc(sessionID)
+func (m *MockAuthService) ExtendSession(sessionID string) (*auth.Session, error) {
+	return m.ExtendSessionFunc(sessionID)
+}
+
+func (m *MockAuthService) Logout(sessionID string, reason auth.LogoutReason) error {
+	return m.LogoutFunc(sessionID, reason)
+}
+
+func (m *MockAuthService) LogoutAll(userID string, reason auth.LogoutReason) error {
+	return m.LogoutAllFunc(userID, reason)
 }
 
-func (m *MockAuthService) LogoutAll(userID string) error {
-	return m.LogoutAllFunc(userID)
+func (m *MockAuthService) LogoutOthers(userID, keepSessionID string) error {
+	return m.LogoutOthersFunc(userID, keepSessionID)
+}
+
+func (m *MockAuthService) CountSessions(userID string) (int64, error) {
+	return m.CountSessionsFunc(userID)
 }
 
 func (m *MockAuthService) Register(username, email, password, displayName string) (*models.User, error) {
 	return m.RegisterFunc(username, email, password, displayName)
 }
 
-func (m *MockAuthService) RequestPasswordReset(email string) error {
-	return m.RequestPasswordResetFunc(email)
+func (m *MockAuthService) RequestPasswordReset(email, binding string) error {
+	return m.RequestPasswordResetFunc(email, binding)
+}
+
+func (m *MockAuthService) ResetPassword(token, newPassword, binding string) error {
+	return m.ResetPasswordFunc(token, newPassword, binding)
+}
+
+func (m *MockAuthService) ChangePassword(userID, currentPassword, newPassword string) error {
+	return m.ChangePasswordFunc(userID, currentPassword, newPassword)
+}
+
+func (m *MockAuthService) RequestEmailChange(userID, newEmail string) error {
+	return m.RequestEmailChangeFunc(userID, newEmail)
+}
+
+func (m *MockAuthService) ConfirmEmailChange(token string) (*models.User, error) {
+	return m.ConfirmEmailChangeFunc(token)
+}
+
+func (m *MockAuthService) ResendVerification(userID string) error {
+	return m.ResendVerificationFunc(userID)
+}
+
+func (m *MockAuthService) AcceptTerms(userID, version string) error {
+	return m.AcceptTermsFunc(userID, version)
 }
 
-func (m *MockAuthService) ResetPassword(token, newPassword string) error {
-	return m.ResetPasswordFunc(token, newPassword)
+func (m *MockAuthService) IsUsernameAvailable(username string) (bool, error) {
+	return m.IsUsernameAvailableFunc(username)
+}
+
+func (m *MockAuthService) IsEmailAvailable(email string) (bool, error) {
+	return m.IsEmailAvailableFunc(email)
 }
 
 func setupTestRouter() (*gin.Context, *httptest.ResponseRecorder) {
@@ -152,7 +217,7 @@ func TestAuthHandler_Login(t *testing.T) {
 			},
 			setupMock: func(m *MockAuthService) {
 				m.LoginFunc = func(username, password, ip, userAgent string) (*service.LoginResponse, error) {
-					return nil, errors.New("conta temporariamente bloqueada, tente novamente mais tarde")
+					return nil, service.ErrAccountLocked
 				}
 			},
 			expectedStatus: http.StatusUnauthorized,
@@ -203,6 +268,663 @@ func TestAuthHandler_Login(t *testing.T) {
 	}
 }
 
+func TestAuthHandler_Login_HTMXRedirect(t *testing.T) {
+	tests := []struct {
+		name     string
+		role     string
+		next     string
+		query    string
+		expected string
+	}{
+		{"Default for user", "user", "", "", "/"},
+		{"Default for admin", "admin", "", "", "/admin"},
+		{"Local next from form", "user", "/admin/users?page=2", "", "/admin/users?page=2"},
+		{"Local next from query", "user", "", "/profile", "/profile"},
+		{"Absolute URL rejected", "user", "https://evil.example.com/", "", "/"},
+		{"Protocol-relative rejected", "admin", "//evil.example.com", "", "/admin"},
+		{"Protocol-relative query rejected", "user", "", "//evil.example.com", "/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := setupTestRouter()
+			mockService := &MockAuthService{
+				LoginFunc: func(username, password, ip, userAgent string) (*service.LoginResponse, error) {
+					return &service.LoginResponse{
+						SessionID: "test-session-id",
+						ExpiresAt: time.Now().Add(time.Hour),
+						User:      auth.UserData{ID: "1", Identifier: "testuser", Role: tt.role},
+					}, nil
+				},
+			}
+			handler := NewAuthHandler(mockService)
+
+			form := url.Values{"username": {"testuser"}, "password": {"password123"}}
+			if tt.next != "" {
+				form.Set("next", tt.next)
+			}
+			target := "/auth/login"
+			if tt.query != "" {
+				target += "?next=" + url.QueryEscape(tt.query)
+			}
+			req, _ := http.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("HX-Request", "true")
+			c.Request = req
+
+			handler.Login(c)
+
+			if w.Code != http.StatusOK {
+				t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+			}
+			if got := w.Header().Get("HX-Redirect"); got != tt.expected {
+				t.Errorf("expected HX-Redirect %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestAuthHandler_Login_RoleLandingPaths(t *testing.T) {
+	cfg := &config.Config{Login: config.LoginConfig{LandingPaths: map[string]string{
+		"admin":     "/admin/users",
+		"moderator": "/moderation",
+		"user":      "/",
+		"broken":    "https://evil.example.com",
+	}}}
+
+	tests := []struct {
+		name     string
+		role     string
+		next     string
+		expected string
+	}{
+		{"Admin landing", "admin", "", "/admin/users"},
+		{"Moderator landing", "moderator", "", "/moderation"},
+		{"User landing", "user", "", "/"},
+		{"Unconfigured role", "guest", "", "/"},
+		{"Non-local landing ignored", "broken", "", "/"},
+		{"Next overrides landing", "moderator", "/profile", "/profile"},
+		{"Invalid next falls back to landing", "moderator", "//evil.example.com", "/moderation"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := setupTestRouter()
+			mockService := &MockAuthService{
+				LoginFunc: func(username, password, ip, userAgent string) (*service.LoginResponse, error) {
+					return &service.LoginResponse{
+						SessionID: "test-session-id",
+						ExpiresAt: time.Now().Add(time.Hour),
+						User:      auth.UserData{ID: "1", Identifier: "testuser", Role: tt.role},
+					}, nil
+				},
+			}
+			handler := NewAuthHandlerWithConfig(mockService, cfg)
+
+			form := url.Values{"username": {"testuser"}, "password": {"password123"}, "next": {tt.next}}
+			req, _ := http.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("HX-Request", "true")
+			c.Request = req
+
+			handler.Login(c)
+
+			if got := w.Header().Get("HX-Redirect"); got != tt.expected {
+				t.Errorf("expected HX-Redirect %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// stubCaptchaVerifier accepts only the token "valid" and counts calls.
+type stubCaptchaVerifier struct {
+	calls int
+}
+
+func (v *stubCaptchaVerifier) Verify(_ context.Context, token, _ string) error {
+	v.calls++
+	if token != "valid" {
+		return captcha.ErrCaptchaInvalid
+	}
+	return nil
+}
+
+func TestAuthHandler_Login_RememberUsername(t *testing.T) {
+	mockService := &MockAuthService{
+		LoginFunc: func(username, password, ip, userAgent string) (*service.LoginResponse, error) {
+			return &service.LoginResponse{
+				SessionID: "test-session-id",
+				ExpiresAt: time.Now().Add(time.Hour),
+				User:      auth.UserData{ID: "1", Identifier: "testuser", Role: "user"},
+			}, nil
+		},
+		LogoutFunc: func(sessionID string, reason auth.LogoutReason) error { return nil },
+	}
+	remembered := func(w *httptest.ResponseRecorder) *http.Cookie {
+		for _, cookie := range w.Result().Cookies() {
+			if cookie.Name == middleware.RememberedUsernameCookie {
+				return cookie
+			}
+		}
+		return nil
+	}
+	login := func(handler *AuthHandler, cookie *http.Cookie) *httptest.ResponseRecorder {
+		c, w := setupTestRouter()
+		form := url.Values{"username": {"testuser"}, "password": {"password123"}}
+		c.Request, _ = http.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(form.Encode()))
+		c.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if cookie != nil {
+			c.Request.AddCookie(cookie)
+		}
+		handler.Login(c)
+		return w
+	}
+	logout := func(handler *AuthHandler) *httptest.ResponseRecorder {
+		c, w := setupTestRouter()
+		c.Request, _ = http.NewRequest(http.MethodPost, "/auth/logout", nil)
+		c.Request.AddCookie(&http.Cookie{Name: middleware.RememberedUsernameCookie, Value: "testuser"})
+		c.Set("sessionID", "test-session-id")
+		handler.Logout(c)
+		return w
+	}
+
+	t.Run("Login remembers the username, never the password", func(t *testing.T) {
+		handler := NewAuthHandlerWithConfig(mockService, &config.Config{Login: config.LoginConfig{
+			RememberUsername: config.RememberUsernameConfig{Enabled: true, MaxAge: time.Hour},
+		}})
+		w := login(handler, nil)
+		cookie := remembered(w)
+		if cookie == nil {
+			t.Fatalf("expected the %s cookie, got %d %s", middleware.RememberedUsernameCookie, w.Code, w.Body.String())
+		}
+		if cookie.Value != "testuser" || cookie.MaxAge != 3600 || !cookie.Secure || !cookie.HttpOnly {
+			t.Errorf("unexpected cookie %+v", cookie)
+		}
+		if strings.Contains(strings.Join(w.Header().Values("Set-Cookie"), "\n"), "password123") {
+			t.Error("the password must never be stored in a cookie")
+		}
+
+		// Logout keeps it unless clear_on_logout is set
+		if cookie := remembered(logout(handler)); cookie != nil {
+			t.Errorf("expected the username kept on logout, got %+v", cookie)
+		}
+		handler.cfg.Login.RememberUsername.ClearOnLogout = true
+		if cookie := remembered(logout(handler)); cookie == nil || cookie.MaxAge >= 0 {
+			t.Errorf("expected the username cleared on logout, got %+v", cookie)
+		}
+	})
+
+	t.Run("Disabled forgets a username remembered before", func(t *testing.T) {
+		handler := NewAuthHandlerWithConfig(mockService, &config.Config{})
+		if cookie := remembered(login(handler, nil)); cookie != nil {
+			t.Errorf("expected no cookie while disabled, got %+v", cookie)
+		}
+		cookie := remembered(login(handler, &http.Cookie{Name: middleware.RememberedUsernameCookie, Value: "testuser"}))
+		if cookie == nil || cookie.MaxAge >= 0 {
+			t.Errorf("expected the old cookie deleted, got %+v", cookie)
+		}
+	})
+}
+
+func TestAuthHandler_Login_Captcha(t *testing.T) {
+	verifier := &stubCaptchaVerifier{}
+	loginCalls := 0
+	mockService := &MockAuthService{
+		LoginFunc: func(username, password, ip, userAgent string) (*service.LoginResponse, error) {
+			loginCalls++
+			if password != "password123" {
+				return nil, service.ErrInvalidCredentials
+			}
+			return &service.LoginResponse{SessionID: "sid", User: auth.UserData{ID: "1", Role: "user"}}, nil
+		},
+	}
+	handler := NewAuthHandlerWithConfig(mockService, &config.Config{})
+	handler.captcha = &captcha.Guard{Verifier: verifier, Provider: captcha.ProviderTurnstile, SiteKey: "site", LoginFailures: 2}
+
+	login := func(password, token string) *httptest.ResponseRecorder {
+		c, w := setupTestRouter()
+		jsonData, _ := json.Marshal(LoginRequest{Username: "testuser", Password: password, CaptchaToken: token})
+		req, _ := http.NewRequest(http.MethodPost, "/auth/login", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = "203.0.113.7:1234"
+		c.Request = req
+		handler.Login(c)
+		return w
+	}
+
+	// Not required yet: failures go straight to the service without a token
+	for range 2 {
+		if w := login("wrongpass", ""); w.Code != http.StatusUnauthorized {
+			t.Fatalf("expected 401 before the threshold, got %d", w.Code)
+		}
+	}
+	if verifier.calls != 0 {
+		t.Errorf("verifier should not be called while not required, got %d calls", verifier.calls)
+	}
+
+	// Required now: missing or invalid tokens are rejected before checking credentials
+	loginCalls = 0
+	if w := login("password123", ""); w.Code != http.StatusBadRequest || !contains(w.Body.String(), captcha.ErrCaptchaRequired.Error()) {
+		t.Errorf("expected 400 with required message, got %d %s", w.Code, w.Body.String())
+	}
+	if w := login("password123", "forged"); w.Code != http.StatusBadRequest || !contains(w.Body.String(), captcha.ErrCaptchaInvalid.Error()) {
+		t.Errorf("expected 400 with invalid message, got %d %s", w.Code, w.Body.String())
+	}
+	if loginCalls != 0 {
+		t.Errorf("service should not be called without a valid captcha, got %d calls", loginCalls)
+	}
+
+	// A valid token lets the login through and resets the failure count
+	if w := login("password123", "valid"); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 with valid captcha, got %d", w.Code)
+	}
+	if w := login("password123", ""); w.Code != http.StatusOK {
+		t.Errorf("expected captcha to no longer be required after success, got %d", w.Code)
+	}
+}
+
+func TestAuthHandler_Register_Captcha(t *testing.T) {
+	tests := []struct {
+		name           string
+		guard          *captcha.Guard
+		token          string
+		expectedStatus int
+	}{
+		{"Disabled", captcha.NewGuard(config.CaptchaConfig{}), "", http.StatusOK},
+		{"Provider set but not required on register", &captcha.Guard{Verifier: &stubCaptchaVerifier{}, Provider: captcha.ProviderTurnstile}, "", http.StatusOK},
+		{"Required and missing", &captcha.Guard{Verifier: &stubCaptchaVerifier{}, Provider: captcha.ProviderTurnstile, OnRegister: true}, "", http.StatusBadRequest},
+		{"Required and invalid", &captcha.Guard{Verifier: &stubCaptchaVerifier{}, Provider: captcha.ProviderReCaptcha, OnRegister: true}, "forged", http.StatusBadRequest},
+		{"Required and valid", &captcha.Guard{Verifier: &stubCaptchaVerifier{}, Provider: captcha.ProviderReCaptcha, OnRegister: true}, "valid", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := setupTestRouter()
+			registered := false
+			handler := NewAuthHandler(&MockAuthService{
+				RegisterFunc: func(username, email, password, displayName string) (*models.User, error) {
+					registered = true
+					return &models.User{Username: username, Email: email, DisplayName: displayName}, nil
+				},
+			})
+			handler.captcha = tt.guard
+
+			form := url.Values{
+				"username":     {"newuser"},
+				"email":        {"new@example.com"},
+				"password":     {"Padasdasdasdd123!"},
+				"display_name": {"New User"},
+			}
+			if tt.token != "" {
+				// reCAPTCHA's default field name is accepted as well as captcha_token
+				form.Set("g-recaptcha-response", tt.token)
+			}
+			req, _ := http.NewRequest(http.MethodPost, "/auth/register", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			c.Request = req
+
+			handler.Register(c)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if registered != (tt.expectedStatus == http.StatusOK) {
+				t.Errorf("expected registered=%v", tt.expectedStatus == http.StatusOK)
+			}
+		})
+	}
+}
+
+// auditSpy collects the entries passed to Record.
+type auditSpy struct{ entries []*models.AuditLog }
+
+func (a *auditSpy) Record(entry *models.AuditLog) error {
+	a.entries = append(a.entries, entry)
+	return nil
+}
+
+func TestAuthHandler_Register_CaptchaBypass(t *testing.T) {
+	const secret = "internal-provisioning-secret-0123456789"
+	guard := captcha.NewGuard(config.CaptchaConfig{
+		Provider:       captcha.ProviderTurnstile,
+		SiteKey:        "site",
+		SecretKey:      "secret",
+		OnRegister:     true,
+		RegisterBypass: config.CaptchaBypassConfig{IPs: []string{"10.0.0.0/8", "192.0.2.10"}, Secret: secret},
+	})
+	guard.Verifier = &stubCaptchaVerifier{}
+
+	tests := []struct {
+		name           string
+		remoteAddr     string
+		header         string
+		expectedStatus int
+		expectedAudit  string
+	}{
+		{"Allowlisted range", "10.1.2.3:5000", "", http.StatusOK, "via=ip"},
+		{"Allowlisted IP", "192.0.2.10:5000", "", http.StatusOK, "via=ip"},
+		{"Shared secret", "203.0.113.7:5000", secret, http.StatusOK, "via=secret"},
+		{"Not allowlisted", "203.0.113.7:5000", "", http.StatusBadRequest, ""},
+		{"Wrong secret", "203.0.113.7:5000", "guess", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := setupTestRouter()
+			audit := &auditSpy{}
+			handler := NewAuthHandler(&MockAuthService{
+				RegisterFunc: func(username, email, password, displayName string) (*models.User, error) {
+					user := &models.User{Username: username, Email: email, DisplayName: displayName}
+					user.ID = 7
+					return user, nil
+				},
+			})
+			handler.captcha = guard
+			handler.UseAudit(audit)
+
+			form := url.Values{
+				"username":     {"provisioned"},
+				"email":        {"provisioned@example.com"},
+				"password":     {"Padasdasdasdd123!"},
+				"display_name": {"Provisioned"},
+			}
+			req, _ := http.NewRequest(http.MethodPost, "/auth/register", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.RemoteAddr = tt.remoteAddr
+			if tt.header != "" {
+				req.Header.Set(captcha.BypassHeader, tt.header)
+			}
+			c.Request = req
+
+			handler.Register(c)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedAudit == "" {
+				if len(audit.entries) != 0 {
+					t.Errorf("expected no audit entry, got %+v", audit.entries)
+				}
+				return
+			}
+			if len(audit.entries) != 1 || audit.entries[0].Action != service.AuditActionCaptchaBypass ||
+				audit.entries[0].Details != tt.expectedAudit || audit.entries[0].TargetID != 7 {
+				t.Errorf("expected a %s audit entry for user 7, got %+v", tt.expectedAudit, audit.entries)
+			}
+		})
+	}
+}
+
+func TestAuthHandler_EmailDomainAllowlist(t *testing.T) {
+	cfg := &config.Config{Registration: config.RegistrationConfig{AllowedEmailDomains: []string{"corp.example"}}}
+
+	register := func(email string) (*httptest.ResponseRecorder, bool) {
+		c, w := setupTestRouter()
+		registered := false
+		handler := NewAuthHandlerWithConfig(&MockAuthService{
+			RegisterFunc: func(username, email, password, displayName string) (*models.User, error) {
+				registered = true
+				return &models.User{Username: username, Email: email}, nil
+			},
+		}, cfg)
+		jsonData, _ := json.Marshal(RegistrationRequest{Username: "newuser", Email: email, Password: "Padasdasdasdd123!", DisplayName: "New User"})
+		req, _ := http.NewRequest(http.MethodPost, "/auth/register", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		c.Request = req
+		handler.Register(c)
+		return w, registered
+	}
+
+	if w, registered := register("new@Corp.Example"); w.Code != http.StatusOK || !registered {
+		t.Errorf("expected an allowed domain (any case) to register, got %d: %s", w.Code, w.Body.String())
+	}
+	w, registered := register("new@gmail.com")
+	if w.Code != http.StatusBadRequest || registered {
+		t.Fatalf("expected 400 without registering, got %d", w.Code)
+	}
+	var body struct {
+		Fields map[string]string `json:"fields"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &body)
+	if body.Fields["email"] != validation.ErrEmailDomainNotAllowed.Error() {
+		t.Errorf("expected the domain error on the email field, got %s", w.Body.String())
+	}
+
+	// An allowed sign-up can't switch to another domain afterwards
+	c, w := setupTestRouter()
+	handler := NewAuthHandlerWithConfig(&MockAuthService{
+		RequestEmailChangeFunc: func(userID, newEmail string) error {
+			t.Error("the service must not be called for a disallowed domain")
+			return nil
+		},
+	}, cfg)
+	jsonData, _ := json.Marshal(EmailChangeRequest{Email: "me@gmail.com"})
+	req, _ := http.NewRequest(http.MethodPost, "/api/account/email", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	c.Request = req
+	c.Set("user", &auth.UserData{ID: "1", Identifier: "testuser"})
+	handler.RequestEmailChange(c)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an email change to a disallowed domain, got %d", w.Code)
+	}
+}
+
+func TestAuthHandler_Register_Terms(t *testing.T) {
+	cfg := &config.Config{Terms: config.TermsConfig{Version: "2025-01", URL: "https://example.com/termos"}}
+
+	register := func(accept bool) (*httptest.ResponseRecorder, bool, string) {
+		c, w := setupTestRouter()
+		registered, acceptedVersion := false, ""
+		handler := NewAuthHandlerWithConfig(&MockAuthService{
+			RegisterFunc: func(username, email, password, displayName string) (*models.User, error) {
+				registered = true
+				user := &models.User{Username: username, Email: email}
+				user.ID = 7
+				return user, nil
+			},
+			AcceptTermsFunc: func(userID, version string) error {
+				if userID == "7" {
+					acceptedVersion = version
+				}
+				return nil
+			},
+		}, cfg)
+		form := url.Values{
+			"username":     {"newuser"},
+			"email":        {"new@example.com"},
+			"password":     {"Padasdasdasdd123!"},
+			"display_name": {"New User"},
+		}
+		if accept {
+			form.Set("accept_terms", "true")
+		}
+		req, _ := http.NewRequest(http.MethodPost, "/auth/register", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		c.Request = req
+		handler.Register(c)
+		return w, registered, acceptedVersion
+	}
+
+	w, registered, _ := register(false)
+	if w.Code != http.StatusBadRequest || registered {
+		t.Fatalf("expected 400 without registering when the terms aren't accepted, got %d", w.Code)
+	}
+	var body struct {
+		Fields map[string]string `json:"fields"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &body)
+	if body.Fields[validation.FieldTerms] != validation.ErrTermsNotAccepted.Error() {
+		t.Errorf("expected the terms error on the terms field, got %s", w.Body.String())
+	}
+
+	w, registered, acceptedVersion := register(true)
+	if w.Code != http.StatusOK || !registered {
+		t.Fatalf("expected the registration to succeed with the terms accepted, got %d: %s", w.Code, w.Body.String())
+	}
+	if acceptedVersion != "2025-01" {
+		t.Errorf("expected the acceptance of version 2025-01 to be recorded, got %q", acceptedVersion)
+	}
+}
+
+func TestAuthHandler_AcceptTerms(t *testing.T) {
+	accept := func(cfg *config.Config) (*httptest.ResponseRecorder, string) {
+		c, w := setupTestRouter()
+		acceptedVersion := ""
+		handler := NewAuthHandlerWithConfig(&MockAuthService{
+			AcceptTermsFunc: func(userID, version string) error {
+				acceptedVersion = version
+				return nil
+			},
+		}, cfg)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/api/account/terms", nil)
+		c.Set("user", &auth.UserData{ID: "1", TermsVersion: "2025-01"})
+		handler.AcceptTerms(c)
+		return w, acceptedVersion
+	}
+
+	w, acceptedVersion := accept(&config.Config{Terms: config.TermsConfig{Version: "2025-02", URL: "https://example.com/termos"}})
+	if w.Code != http.StatusOK || acceptedVersion != "2025-02" {
+		t.Errorf("expected the current version to be accepted, got %d and %q", w.Code, acceptedVersion)
+	}
+	if w, acceptedVersion := accept(&config.Config{}); w.Code != http.StatusNotFound || acceptedVersion != "" {
+		t.Errorf("expected 404 with terms disabled, got %d", w.Code)
+	}
+}
+
+func TestAuthHandler_Register_Disabled(t *testing.T) {
+	disabled := false
+	cfg := &config.Config{Registration: config.RegistrationConfig{Enabled: &disabled}}
+
+	for _, htmx := range []bool{false, true} {
+		c, w := setupTestRouter()
+		registered := false
+		handler := NewAuthHandlerWithConfig(&MockAuthService{
+			RegisterFunc: func(username, email, password, displayName string) (*models.User, error) {
+				registered = true
+				return &models.User{}, nil
+			},
+		}, cfg)
+
+		form := url.Values{
+			"username":     {"newuser"},
+			"email":        {"new@example.com"},
+			"password":     {"Padasdasdasdd123!"},
+			"display_name": {"New User"},
+		}
+		req, _ := http.NewRequest(http.MethodPost, "/auth/register", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if htmx {
+			req.Header.Set("HX-Request", "true")
+		}
+		c.Request = req
+
+		handler.Register(c)
+
+		wantStatus := http.StatusForbidden
+		if htmx {
+			wantStatus = http.StatusOK // HTMX only swaps 2xx responses
+		}
+		if w.Code != wantStatus {
+			t.Errorf("htmx=%v: expected status %d, got %d", htmx, wantStatus, w.Code)
+		}
+		if !strings.Contains(w.Body.String(), msgRegistrationDisabled) {
+			t.Errorf("htmx=%v: expected disabled message, got %s", htmx, w.Body.String())
+		}
+		if registered {
+			t.Errorf("htmx=%v: the service must not be called while registration is disabled", htmx)
+		}
+	}
+}
+
+func TestAuthHandler_Register_GeneratedUsername(t *testing.T) {
+	for _, generate := range []bool{false, true} {
+		c, w := setupTestRouter()
+		registered := false
+		handler := NewAuthHandlerWithConfig(&MockAuthService{
+			RegisterFunc: func(username, email, password, displayName string) (*models.User, error) {
+				registered = username == ""
+				return &models.User{Username: "new.user", Email: email, DisplayName: displayName}, nil
+			},
+		}, &config.Config{Registration: config.RegistrationConfig{GenerateUsername: generate}})
+
+		body := `{"email":"new@example.com","password":"Padasdasdasdd123!","display_name":"New User"}`
+		c.Request, _ = http.NewRequest(http.MethodPost, "/auth/register", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		handler.Register(c)
+
+		if generate && (w.Code != http.StatusOK || !registered) {
+			t.Errorf("expected the service to generate the username, got %d %s", w.Code, w.Body.String())
+		}
+		if !generate && (w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), msgUsernameRequired)) {
+			t.Errorf("expected the username to be required, got %d %s", w.Code, w.Body.String())
+		}
+	}
+}
+
+// fakeInvites accepts only the "good" token, registering through the handler's callback.
+type fakeInvites struct{ redeemed bool }
+
+func (f *fakeInvites) Lookup(token string) (*models.Invite, error) {
+	if token != "good" {
+		return nil, service.ErrInviteNotFound
+	}
+	return &models.Invite{Role: "admin"}, nil
+}
+
+func (f *fakeInvites) Redeem(token, email string, register func() (*models.User, error)) (*models.User, error) {
+	if _, err := f.Lookup(token); err != nil {
+		return nil, err
+	}
+	f.redeemed = true
+	return register()
+}
+
+func TestAuthHandler_Register_Invite(t *testing.T) {
+	disabled := false
+	cfg := &config.Config{Registration: config.RegistrationConfig{Enabled: &disabled}}
+
+	tests := []struct {
+		name           string
+		invite         string
+		useInvites     bool
+		expectedStatus int
+	}{
+		{"Valid invite while registration is disabled", "good", true, http.StatusOK},
+		{"Unknown invite", "forged", true, http.StatusForbidden},
+		{"Invites not enabled", "good", false, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := setupTestRouter()
+			invites := &fakeInvites{}
+			handler := NewAuthHandlerWithConfig(&MockAuthService{
+				RegisterFunc: func(username, email, password, displayName string) (*models.User, error) {
+					return &models.User{Username: username, Email: email}, nil
+				},
+			}, cfg)
+			if tt.useInvites {
+				handler.UseInvites(invites)
+			}
+
+			body, _ := json.Marshal(RegistrationRequest{
+				Username: "invited", Email: "invited@example.com", Password: "Padasdasdasdd123!", DisplayName: "Invited", Invite: tt.invite,
+			})
+			c.Request, _ = http.NewRequest(http.MethodPost, "/auth/register", bytes.NewBuffer(body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			handler.Register(c)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if invites.redeemed != (tt.expectedStatus == http.StatusOK) {
+				t.Errorf("expected redeemed=%v", tt.expectedStatus == http.StatusOK)
+			}
+		})
+	}
+}
+
 func TestAuthHandler_Logout(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -218,7 +940,7 @@ func TestAuthHandler_Logout(t *testing.T) {
 				c.Set("sessionID", "valid-session")
 			},
 			setupMock: func(m *MockAuthService) {
-				m.LogoutFunc = func(sessionID string) error {
+				m.LogoutFunc = func(sessionID string, reason auth.LogoutReason) error {
 					return nil
 				}
 			},
@@ -233,7 +955,7 @@ func TestAuthHandler_Logout(t *testing.T) {
 				// Don't set sessionID
 			},
 			setupMock: func(m *MockAuthService) {
-				m.LogoutFunc = func(sessionID string) error {
+				m.LogoutFunc = func(sessionID string, reason auth.LogoutReason) error {
 					return nil
 				}
 			},
@@ -372,6 +1094,112 @@ func TestAuthHandler_Register(t *testing.T) {
 	}
 }
 
+func TestAuthHandler_Register_FieldErrors(t *testing.T) {
+	t.Run("JSON returns fields map", func(t *testing.T) {
+		c, w := setupTestRouter()
+		handler := NewAuthHandler(&MockAuthService{})
+
+		jsonData, _ := json.Marshal(RegistrationRequest{
+			Username:    "jo",
+			Email:       "invalid",
+			Password:    "Secure123!",
+			DisplayName: "Jo",
+		})
+		req, _ := http.NewRequest(http.MethodPost, "/auth/register", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		c.Request = req
+
+		handler.Register(c)
+
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+		}
+		var response struct {
+			Error  string            `json:"error"`
+			Fields map[string]string `json:"fields"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("unmarshal response: %v", err)
+		}
+		if _, ok := response.Fields["username"]; !ok {
+			t.Errorf("expected username field error, got %v", response.Fields)
+		}
+		if _, ok := response.Fields["email"]; !ok {
+			t.Errorf("expected email field error, got %v", response.Fields)
+		}
+		if _, ok := response.Fields["password"]; ok {
+			t.Errorf("did not expect password field error, got %v", response.Fields)
+		}
+		if response.Error != response.Fields["username"] {
+			t.Errorf("expected summary error to be the first field error, got %q", response.Error)
+		}
+		if strings.Contains(w.Body.String(), "password_requirements") {
+			t.Errorf("password checklist only comes with a password error, got %s", w.Body.String())
+		}
+	})
+
+	t.Run("JSON lists every unmet password requirement", func(t *testing.T) {
+		c, w := setupTestRouter()
+		handler := NewAuthHandler(&MockAuthService{})
+
+		jsonData, _ := json.Marshal(RegistrationRequest{
+			Username:    "joana",
+			Email:       "joana@example.com",
+			Password:    "joana",
+			DisplayName: "Joana",
+		})
+		req, _ := http.NewRequest(http.MethodPost, "/auth/register", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		c.Request = req
+
+		handler.Register(c)
+
+		var response struct {
+			Error                string                          `json:"error"`
+			PasswordRequirements validation.PasswordRequirements `json:"password_requirements"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("unmarshal response: %v", err)
+		}
+		want := validation.PasswordRequirements{Lower: true, NotCommon: true}
+		if response.PasswordRequirements != want {
+			t.Errorf("expected password requirements %+v, got %+v", want, response.PasswordRequirements)
+		}
+		if response.Error != validation.ErrPasswordTooShort.Error() {
+			t.Errorf("expected the first unmet requirement as the error, got %q", response.Error)
+		}
+	})
+
+	t.Run("HTMX returns out-of-band field fragments", func(t *testing.T) {
+		c, w := setupTestRouter()
+		handler := NewAuthHandler(&MockAuthService{})
+
+		form := "username=jo&email=jo%40example.com&password=Secure123!&display_name=Jo"
+		req, _ := http.NewRequest(http.MethodPost, "/auth/register", strings.NewReader(form))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("HX-Request", "true")
+		c.Request = req
+
+		handler.Register(c)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+		}
+		if got := w.Header().Get("HX-Retarget"); got != "#register-error" {
+			t.Errorf("expected HX-Retarget #register-error, got %q", got)
+		}
+		body := w.Body.String()
+		for _, id := range []string{"username-error", "email-error", "display_name-error", "password-error"} {
+			if !strings.Contains(body, `id="`+id+`"`) {
+				t.Errorf("expected fragment with id %q in body", id)
+			}
+		}
+		if !strings.Contains(body, `hx-swap-oob="true"`) {
+			t.Error("expected out-of-band swap attribute in body")
+		}
+	})
+}
+
 func TestAuthHandler_RequestPasswordReset(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -387,7 +1215,7 @@ func TestAuthHandler_RequestPasswordReset(t *testing.T) {
 				"email": "test@example.com",
 			},
 			setupMock: func(m *MockAuthService) {
-				m.RequestPasswordResetFunc = func(email string) error {
+				m.RequestPasswordResetFunc = func(email, binding string) error {
 					return nil
 				}
 			},
@@ -402,7 +1230,7 @@ func TestAuthHandler_RequestPasswordReset(t *testing.T) {
 				"email": "invalid-email",
 			},
 			setupMock: func(m *MockAuthService) {
-				m.RequestPasswordResetFunc = func(email string) error {
+				m.RequestPasswordResetFunc = func(email, binding string) error {
 					return errors.New("should not be called")
 				}
 			},
@@ -483,7 +1311,7 @@ func TestAuthHandler_ResetPassword(t *testing.T) {
 				ConfirmPassword: "NewPgdfgdfgd123!",
 			},
 			setupMock: func(m *MockAuthService) {
-				m.ResetPasswordFunc = func(token, newPassword string) error {
+				m.ResetPasswordFunc = func(token, newPassword, binding string) error {
 					return nil
 				}
 			},
@@ -500,7 +1328,7 @@ func TestAuthHandler_ResetPassword(t *testing.T) {
 				ConfirmPassword: "NewPgdfgdfgd123!",
 			},
 			setupMock: func(m *MockAuthService) {
-				m.ResetPasswordFunc = func(token, newPassword string) error {
+				m.ResetPasswordFunc = func(token, newPassword, binding string) error {
 					return service.ErrInvalidToken
 				}
 			},
@@ -517,7 +1345,7 @@ func TestAuthHandler_ResetPassword(t *testing.T) {
 				ConfirmPassword: "NewPgdfgdfgd123!",
 			},
 			setupMock: func(m *MockAuthService) {
-				m.ResetPasswordFunc = func(token, newPassword string) error {
+				m.ResetPasswordFunc = func(token, newPassword, binding string) error {
 					return service.ErrExpiredToken
 				}
 			},
@@ -568,6 +1396,108 @@ func TestAuthHandler_ResetPassword(t *testing.T) {
 	}
 }
 
+func TestAuthHandler_ChangePassword(t *testing.T) {
+	tests := []struct {
+		name           string
+		request        ChangePasswordRequest
+		serviceErr     error
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "Success",
+			request:        ChangePasswordRequest{CurrentPassword: "old", NewPassword: "NewSecurePass123!", ConfirmPassword: "NewSecurePass123!"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Confirmation mismatch",
+			request:        ChangePasswordRequest{CurrentPassword: "old", NewPassword: "NewSecurePass123!", ConfirmPassword: "Other123!"},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "as senhas não coincidem",
+		},
+		{
+			name:           "Wrong current password",
+			request:        ChangePasswordRequest{CurrentPassword: "bad", NewPassword: "NewSecurePass123!", ConfirmPassword: "NewSecurePass123!"},
+			serviceErr:     service.ErrWrongPassword,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  service.ErrWrongPassword.Error(),
+		},
+		{
+			name:           "Password reused",
+			request:        ChangePasswordRequest{CurrentPassword: "old", NewPassword: "NewSecurePass123!", ConfirmPassword: "NewSecurePass123!"},
+			serviceErr:     service.ErrPasswordReused,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  service.ErrPasswordReused.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := setupTestRouter()
+			handler := NewAuthHandler(&MockAuthService{
+				ChangePasswordFunc: func(userID, currentPassword, newPassword string) error {
+					if userID != "1" {
+						t.Errorf("expected user ID 1, got %s", userID)
+					}
+					return tt.serviceErr
+				},
+			})
+
+			jsonData, _ := json.Marshal(tt.request)
+			req, _ := http.NewRequest(http.MethodPost, "/api/change-password", bytes.NewBuffer(jsonData))
+			req.Header.Set("Content-Type", "application/json")
+			c.Request = req
+			c.Set("user", &auth.UserData{ID: "1", Identifier: "testuser"})
+
+			handler.ChangePassword(c)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedError != "" && !contains(w.Body.String(), tt.expectedError) {
+				t.Errorf("expected error %q, got %s", tt.expectedError, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthHandler_RequestEmailChange(t *testing.T) {
+	tests := []struct {
+		name           string
+		email          string
+		serviceErr     error
+		expectedStatus int
+	}{
+		{"Pending change created", "new@example.com", nil, http.StatusAccepted},
+		{"Invalid email", "not-an-email", nil, http.StatusBadRequest},
+		{"Email taken", "taken@example.com", service.ErrEmailTaken, http.StatusConflict},
+		{"Same email", "test@example.com", service.ErrSameEmail, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := setupTestRouter()
+			handler := NewAuthHandler(&MockAuthService{
+				RequestEmailChangeFunc: func(userID, newEmail string) error {
+					return tt.serviceErr
+				},
+			})
+
+			jsonData, _ := json.Marshal(EmailChangeRequest{Email: tt.email})
+			req, _ := http.NewRequest(http.MethodPost, "/api/account/email", bytes.NewBuffer(jsonData))
+			req.Header.Set("Content-Type", "application/json")
+			c.Request = req
+			c.Set("user", &auth.UserData{ID: "1", Identifier: "testuser"})
+
+			handler.RequestEmailChange(c)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
 func TestAuthHandler_GetCurrentUser(t *testing.T) {
 	t.Run("success when user in context", func(t *testing.T) {
 		c, w := setupTestRouter()
@@ -621,3 +1551,193 @@ func TestAuthHandler_GetCurrentUser(t *testing.T) {
 		}
 	})
 }
+
+func TestAuthHandler_CountSessions(t *testing.T) {
+	t.Run("counts the caller's sessions", func(t *testing.T) {
+		c, w := setupTestRouter()
+		var countedFor string
+		handler := NewAuthHandler(&MockAuthService{
+			CountSessionsFunc: func(userID string) (int64, error) {
+				countedFor = userID
+				return 3, nil
+			},
+		})
+		c.Set("user", &auth.UserData{ID: "7", Identifier: "testuser"})
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/me/sessions/count", nil)
+
+		handler.CountSessions(c)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+		}
+		if countedFor != "7" {
+			t.Errorf("expected sessions of user 7 to be counted, got %q", countedFor)
+		}
+		var response map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("unmarshal response: %v", err)
+		}
+		if response["count"] != float64(3) {
+			t.Errorf("expected count 3, got %v", response["count"])
+		}
+	})
+
+	t.Run("unauthorized when user not in context", func(t *testing.T) {
+		c, w := setupTestRouter()
+		handler := NewAuthHandler(&MockAuthService{})
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/me/sessions/count", nil)
+
+		handler.CountSessions(c)
+
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
+		}
+	})
+}
+
+func TestAuthHandler_SessionDetails(t *testing.T) {
+	c, w := setupTestRouter()
+	handler := NewAuthHandler(&MockAuthService{})
+	c.Set("session", &auth.Session{
+		CreatedAt: time.Now().Add(-time.Hour),
+		ExpiresAt: time.Now().Add(time.Hour),
+		IP:        "203.0.113.7",
+		UserAgent: "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.6478.122 Mobile Safari/537.36",
+	})
+	c.Request, _ = http.NewRequest(http.MethodGet, "/api/me/session", nil)
+
+	handler.SessionDetails(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var response SessionDetailsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	if response.IP != "203.0.113.7" || response.ExpiresIn <= 0 {
+		t.Errorf("unexpected session details: %+v", response)
+	}
+	if response.Device.Browser != "Chrome" || response.Device.OS != "Android 14" || response.Device.Device != "mobile" {
+		t.Errorf("unexpected device: %+v", response.Device)
+	}
+	if w.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("expected Cache-Control no-store, got %q", w.Header().Get("Cache-Control"))
+	}
+}
+
+func TestAuthHandler_RevokeOtherSessions(t *testing.T) {
+	c, w := setupTestRouter()
+	var revokedFor, kept string
+	handler := NewAuthHandler(&MockAuthService{
+		LogoutOthersFunc: func(userID, keepSessionID string) error {
+			revokedFor, kept = userID, keepSessionID
+			return nil
+		},
+	})
+	c.Set("userID", "7")
+	c.Set("sessionID", "current-session")
+	c.Request, _ = http.NewRequest(http.MethodPost, "/api/sessions/revoke-others", nil)
+
+	handler.RevokeOtherSessions(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if revokedFor != "7" || kept != "current-session" {
+		t.Errorf("expected the other sessions of user 7 to end and the current one kept, got user %q keeping %q", revokedFor, kept)
+	}
+}
+
+func TestAuthHandler_PasswordResetBinding(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const body = `{"token":"valid-token","new_password":"NewPgdfgdfgd123!","confirm_password":"NewPgdfgdfgd123!"}`
+
+	var requested, used string
+	mockService := &MockAuthService{
+		RequestPasswordResetFunc: func(email, binding string) error {
+			requested = binding
+			return nil
+		},
+		ResetPasswordFunc: func(token, newPassword, binding string) error {
+			used = binding
+			if binding != requested {
+				return service.ErrResetContextMismatch
+			}
+			return nil
+		},
+	}
+	serve := func(handler *AuthHandler, path, payload string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		r := gin.New()
+		r.POST("/auth/password-reset-request", handler.RequestPasswordReset)
+		r.POST("/auth/password-reset", handler.ResetPassword)
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = "203.0.113.7:1234"
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Cookie binding", func(t *testing.T) {
+		cfg := &config.Config{Password: config.PasswordConfig{ResetBinding: "cookie"}}
+		handler := NewAuthHandlerWithConfig(mockService, cfg)
+
+		w := serve(handler, "/auth/password-reset-request", `{"email":"test@example.com"}`)
+		cookies := w.Result().Cookies()
+		if w.Code != http.StatusOK || len(cookies) != 1 || cookies[0].Name != resetDeviceCookie {
+			t.Fatalf("expected 200 with a %s cookie, got %d and %v", resetDeviceCookie, w.Code, cookies)
+		}
+		device := cookies[0]
+		if !device.HttpOnly || device.Value == "" || requested != device.Value {
+			t.Fatalf("token must be bound to the HttpOnly device cookie, got binding %q for cookie %+v", requested, device)
+		}
+
+		// Asking again from the same browser keeps the device
+		serve(handler, "/auth/password-reset-request", `{"email":"test@example.com"}`, device)
+		if requested != device.Value {
+			t.Errorf("expected the existing device cookie to be reused, got binding %q", requested)
+		}
+
+		if w := serve(handler, "/auth/password-reset", body, device); w.Code != http.StatusOK {
+			t.Errorf("same device: expected 200, got %d", w.Code)
+		}
+
+		// Another device has no cookie and gets the specific message
+		w = serve(handler, "/auth/password-reset", body)
+		if w.Code != http.StatusForbidden || used != "" {
+			t.Errorf("other device: expected 403 with empty binding, got %d with %q", w.Code, used)
+		}
+		if !contains(w.Body.String(), "mesmo dispositivo") {
+			t.Errorf("expected the same-device message, got %s", w.Body.String())
+		}
+		if len(w.Result().Cookies()) != 0 {
+			t.Error("resetting must never issue a device cookie")
+		}
+	})
+
+	t.Run("IP binding", func(t *testing.T) {
+		cfg := &config.Config{Password: config.PasswordConfig{ResetBinding: "ip"}}
+		handler := NewAuthHandlerWithConfig(mockService, cfg)
+
+		serve(handler, "/auth/password-reset-request", `{"email":"test@example.com"}`)
+		if requested != "203.0.113.7" {
+			t.Errorf("expected the client IP as binding, got %q", requested)
+		}
+		if w := serve(handler, "/auth/password-reset", body); w.Code != http.StatusOK {
+			t.Errorf("same IP: expected 200, got %d", w.Code)
+		}
+	})
+
+	t.Run("Off by default", func(t *testing.T) {
+		handler := NewAuthHandlerWithConfig(mockService, &config.Config{})
+
+		w := serve(handler, "/auth/password-reset-request", `{"email":"test@example.com"}`)
+		if requested != "" || len(w.Result().Cookies()) != 0 {
+			t.Errorf("expected no binding and no cookie, got %q and %v", requested, w.Result().Cookies())
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/lucas-varjao/gohtmx/internal/icons"
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+	"github.com/lucas-varjao/gohtmx/internal/validation"
+	"github.com/lucas-varjao/gohtmx/templates/components"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Availability messages only say yes/no; they never reveal anything about the existing account.
+const (
+	msgUsernameAvailable = "nome de usuário disponível"
+	msgUsernameTaken     = "nome de usuário já está em uso"
+	msgEmailAvailable    = "email disponível"
+	msgEmailTaken        = "email já está em uso"
+)
+
+// CheckAvailability answers whether a username (or, when enabled in config, an email) is free.
+// GET /auth/available?username=... or ?email=...
+// HTMX requests get a small fragment for #<field>-availability; other clients get JSON.
+func (h *AuthHandler) CheckAvailability(c *gin.Context) {
+	h.checkAvailability(c, h.cfg.Features().EmailAvailabilityCheck)
+}
+
+// CheckAvailabilityAdmin is CheckAvailability for the admin new-user form, mounted behind the admin
+// middleware. Email checks are always on there: admins can already list every account.
+func (h *AuthHandler) CheckAvailabilityAdmin(c *gin.Context) {
+	h.checkAvailability(c, true)
+}
+
+// checkAvailability answers for the username or email query parameter; emailEnabled gates the email lookup.
+func (h *AuthHandler) checkAvailability(c *gin.Context, emailEnabled bool) {
+	var (
+		field     string
+		value     string
+		validate  func(string) error
+		available func(string) (bool, error)
+		okMsg     string
+		takenMsg  string
+	)
+
+	switch {
+	case c.Query(validation.FieldUsername) != "":
+		field = validation.FieldUsername
+		value = c.Query(validation.FieldUsername)
+		validate = validation.ValidateUsername
+		available = h.authService.IsUsernameAvailable
+		okMsg, takenMsg = msgUsernameAvailable, msgUsernameTaken
+	case c.Query(validation.FieldEmail) != "" && emailEnabled:
+		field = validation.FieldEmail
+		value = c.Query(validation.FieldEmail)
+		validate = validation.ValidateEmail
+		available = h.authService.IsEmailAvailable
+		okMsg, takenMsg = msgEmailAvailable, msgEmailTaken
+	default:
+		respondJSON(c, http.StatusBadRequest, gin.H{"error": "informe username ou email"})
+		return
+	}
+
+	if err := validate(value); err != nil {
+		respondAvailability(c, field, false, false, err.Error())
+		return
+	}
+
+	free, err := available(value)
+	if err != nil {
+		logger.Error("Erro ao verificar disponibilidade", "error", err, "field", field, "ip", getClientIP(c))
+		respondJSON(c, http.StatusInternalServerError, gin.H{"error": "falha ao verificar disponibilidade"})
+		return
+	}
+
+	message := takenMsg
+	if free {
+		message = okMsg
+	}
+	respondAvailability(c, field, true, free, message)
+}
+
+// respondAvailability writes the availability result as an HTMX fragment or JSON.
+func respondAvailability(c *gin.Context, field string, valid, available bool, message string) {
+	if c.GetHeader("HX-Request") != "" {
+		icon := icons.ValidationFail()
+		if available {
+			icon = icons.ValidationSuccess()
+		}
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		c.Status(http.StatusOK)
+		_ = components.AvailabilityStatus(field, available, message, icon).Render(context.Background(), c.Writer)
+		return
+	}
+
+	status := http.StatusOK
+	if !valid {
+		status = http.StatusBadRequest
+	}
+	respondJSON(c, status, gin.H{
+		"field":     field,
+		"valid":     valid,
+		"available": available,
+		"message":   message,
+	})
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/lucas-varjao/gohtmx/internal/config"
+)
+
+func newAvailabilityMock() *MockAuthService {
+	return &MockAuthService{
+		IsUsernameAvailableFunc: func(username string) (bool, error) {
+			return username != "taken_user", nil
+		},
+		IsEmailAvailableFunc: func(email string) (bool, error) {
+			return email != "taken@example.com", nil
+		},
+	}
+}
+
+func TestAuthHandler_CheckAvailability(t *testing.T) {
+	emailEnabled := &config.Config{Registration: config.RegistrationConfig{EmailAvailabilityCheck: true}}
+
+	tests := []struct {
+		name          string
+		query         string
+		cfg           *config.Config
+		expectedCode  int
+		wantAvailable bool
+		wantValid     bool
+	}{
+		{"username available", "username=free_user", nil, http.StatusOK, true, true},
+		{"username taken", "username=taken_user", nil, http.StatusOK, false, true},
+		{"username invalid", "username=a!", nil, http.StatusBadRequest, false, false},
+		{"email available", "email=free%40example.com", emailEnabled, http.StatusOK, true, true},
+		{"email taken", "email=taken%40example.com", emailEnabled, http.StatusOK, false, true},
+		{"email invalid", "email=not-an-email", emailEnabled, http.StatusBadRequest, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := setupTestRouter()
+			handler := NewAuthHandlerWithConfig(newAvailabilityMock(), tt.cfg)
+
+			req, _ := http.NewRequest(http.MethodGet, "/auth/available?"+tt.query, nil)
+			c.Request = req
+
+			handler.CheckAvailability(c)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("expected status %d, got %d", tt.expectedCode, w.Code)
+			}
+			var response map[string]any
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("unmarshal response: %v", err)
+			}
+			if response["available"] != tt.wantAvailable {
+				t.Errorf("expected available=%v, got %v", tt.wantAvailable, response["available"])
+			}
+			if response["valid"] != tt.wantValid {
+				t.Errorf("expected valid=%v, got %v", tt.wantValid, response["valid"])
+			}
+		})
+	}
+}
+
+func TestAuthHandler_CheckAvailability_EmailDisabledByDefault(t *testing.T) {
+	c, w := setupTestRouter()
+	handler := NewAuthHandlerWithConfig(newAvailabilityMock(), nil)
+
+	req, _ := http.NewRequest(http.MethodGet, "/auth/available?email=free%40example.com", nil)
+	c.Request = req
+
+	handler.CheckAvailability(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d when email check is disabled, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestAuthHandler_CheckAvailabilityAdmin_EmailAlwaysOn(t *testing.T) {
+	c, w := setupTestRouter()
+	handler := NewAuthHandlerWithConfig(newAvailabilityMock(), nil)
+
+	req, _ := http.NewRequest(http.MethodGet, "/admin/users/available?email=free%40example.com", nil)
+	c.Request = req
+
+	handler.CheckAvailabilityAdmin(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if !strings.Contains(w.Body.String(), msgEmailAvailable) {
+		t.Errorf("expected email availability answer, got %s", w.Body.String())
+	}
+}
+
+func TestAuthHandler_CheckAvailability_HTMXFragment(t *testing.T) {
+	c, w := setupTestRouter()
+	handler := NewAuthHandlerWithConfig(newAvailabilityMock(), nil)
+
+	req, _ := http.NewRequest(http.MethodGet, "/auth/available?username=taken_user", nil)
+	req.Header.Set("HX-Request", "true")
+	c.Request = req
+
+	handler.CheckAvailability(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, `id="username-availability"`) {
+		t.Errorf("expected fragment id username-availability, got %s", body)
+	}
+	if !strings.Contains(body, msgUsernameTaken) || !strings.Contains(body, "text-error") {
+		t.Errorf("expected taken message with error styling, got %s", body)
+	}
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// msgBackupCodesDisabled answers POST /api/2fa/backup-codes when no login verification step accepts them.
+const msgBackupCodesDisabled = "os códigos de backup estão desativados"
+
+// BackupCodes hands out the one-time codes accepted by the login verification step in place of the
+// emailed code (service.BackupCodeService).
+type BackupCodes interface {
+	Regenerate(userID, ip string) ([]string, error)
+}
+
+// UseBackupCodes enables POST /api/2fa/backup-codes. Call it during setup, before serving requests.
+func (h *AuthHandler) UseBackupCodes(codes BackupCodes) {
+	h.backupCodes = codes
+}
+
+// RegenerateBackupCodes handles POST /api/2fa/backup-codes: a fresh set of one-time codes for the
+// current user, shown this once; every code of the previous set stops working.
+func (h *AuthHandler) RegenerateBackupCodes(c *gin.Context) {
+	if h.backupCodes == nil {
+		respondJSON(c, http.StatusNotFound, gin.H{"error": msgBackupCodesDisabled})
+		return
+	}
+	codes, err := h.backupCodes.Regenerate(c.GetString("userID"), getClientIP(c))
+	if err != nil {
+		logger.Error("Erro ao gerar códigos de backup", "error", err, "user_id", c.GetString("userID"))
+		respondJSON(c, http.StatusInternalServerError, gin.H{"error": "erro interno do servidor"})
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	respondJSON(c, http.StatusOK, gin.H{"codes": codes})
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// stubBackupCodes hands out two codes to user "1" and fails for anyone else.
+type stubBackupCodes struct{}
+
+func (stubBackupCodes) Regenerate(userID, _ string) ([]string, error) {
+	if userID != "1" {
+		return nil, errors.New("boom")
+	}
+	return []string{"AAAA-BBBB-CCCC", "DDDD-EEEE-FFFF"}, nil
+}
+
+func TestAuthHandler_RegenerateBackupCodes(t *testing.T) {
+	tests := []struct {
+		name   string
+		codes  BackupCodes
+		userID string
+		want   int
+	}{
+		{"Not wired", nil, "1", http.StatusNotFound},
+		{"New set", stubBackupCodes{}, "1", http.StatusOK},
+		{"Storage failure", stubBackupCodes{}, "2", http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewAuthHandler(&MockAuthService{})
+			if tt.codes != nil {
+				handler.UseBackupCodes(tt.codes)
+			}
+			c, w := setupTestRouter()
+			c.Request, _ = http.NewRequest(http.MethodPost, "/api/2fa/backup-codes", nil)
+			c.Set("userID", tt.userID)
+			handler.RegenerateBackupCodes(c)
+			if w.Code != tt.want {
+				t.Fatalf("expected status %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+			if w.Code == http.StatusOK {
+				if !strings.Contains(w.Body.String(), `"codes":["AAAA-BBBB-CCCC","DDDD-EEEE-FFFF"]`) {
+					t.Errorf("expected the codes in the body, got %s", w.Body.String())
+				}
+				if w.Header().Get("Cache-Control") != "no-store" {
+					t.Errorf("codes must not be cached, got %q", w.Header().Get("Cache-Control"))
+				}
+			}
+		})
+	}
+}
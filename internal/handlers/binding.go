@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lucas-varjao/gohtmx/internal/validation"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// errBindInvalid is the message for bodies that can't be decoded at all (malformed JSON, empty body).
+const errBindInvalid = "dados inválidos"
+
+// bindField is how a request struct field is named to the user. Key is the form field the message
+// belongs to (validation.Field*); feminine picks the adjective ending ("obrigatória").
+type bindField struct {
+	key      string
+	label    string
+	feminine bool
+}
+
+// bindFields maps the struct fields of the request types bound in this package, by Go field name.
+var bindFields = map[string]bindField{
+	"Username":        {validation.FieldUsername, "usuário", false},
+	"Email":           {validation.FieldEmail, "email", false},
+	"Password":        {validation.FieldPassword, "senha", true},
+	"DisplayName":     {validation.FieldDisplayName, "nome de exibição", false},
+	"CurrentPassword": {"current_password", "senha atual", true},
+	"NewPassword":     {"new_password", "nova senha", true},
+	"ConfirmPassword": {"confirm_password", "confirmação da senha", true},
+	"Token":           {"token", "token", false},
+}
+
+// bindErrorMessage turns a ShouldBind error into a message fit for the user, e.g. "usuário é obrigatório"
+// instead of validator's "Key: 'LoginRequest.Username' Error:Field validation...". Only the first
+// invalid field is described; errors other than validation failures become a generic message.
+func bindErrorMessage(err error) string {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) || len(verrs) == 0 {
+		return errBindInvalid
+	}
+	_, message := describeFieldError(verrs[0])
+	return message
+}
+
+// bindFieldErrors returns one message per invalid field, keyed by form field name, for inline errors.
+// It is empty when err is not a validation failure.
+func bindFieldErrors(err error) validation.FieldErrors {
+	fieldErrs := validation.FieldErrors{}
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return fieldErrs
+	}
+	for _, fe := range verrs {
+		key, message := describeFieldError(fe)
+		if _, seen := fieldErrs[key]; !seen {
+			fieldErrs[key] = message
+		}
+	}
+	return fieldErrs
+}
+
+func describeFieldError(fe validator.FieldError) (key, message string) {
+	field, ok := bindFields[fe.StructField()]
+	if !ok {
+		field = bindField{key: strings.ToLower(fe.Field()), label: strings.ToLower(fe.Field())}
+	}
+	ending := "o"
+	if field.feminine {
+		ending = "a"
+	}
+
+	switch fe.Tag() {
+	case "required":
+		message = fmt.Sprintf("%s é obrigatóri%s", field.label, ending)
+	case "min":
+		message = fmt.Sprintf("%s deve ter pelo menos %s caracteres", field.label, fe.Param())
+	case "max":
+		message = fmt.Sprintf("%s não pode ter mais de %s caracteres", field.label, fe.Param())
+	default: // email and other format checks
+		message = fmt.Sprintf("%s inválid%s", field.label, ending)
+	}
+	return field.key, message
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/lucas-varjao/gohtmx/internal/validation"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+func TestBindErrorMessage(t *testing.T) {
+	var resetRequest struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	resetRequest.Email = "not-an-email"
+	var lengthRequest struct {
+		Username string `binding:"min=3"`
+		Password string `binding:"max=4"`
+	}
+	lengthRequest.Username, lengthRequest.Password = "ab", "12345"
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"required field", binding.Validator.ValidateStruct(&LoginRequest{Password: "x"}), "usuário é obrigatório"},
+		{"feminine label", binding.Validator.ValidateStruct(&LoginRequest{Username: "x"}), "senha é obrigatória"},
+		{"first invalid field wins", binding.Validator.ValidateStruct(&ChangePasswordRequest{}), "senha atual é obrigatória"},
+		{"email format", binding.Validator.ValidateStruct(&resetRequest), "email inválido"},
+		{"min length", binding.Validator.ValidateStruct(&lengthRequest), "usuário deve ter pelo menos 3 caracteres"},
+		{"malformed JSON", json.Unmarshal([]byte("{"), &LoginRequest{}), errBindInvalid},
+		{"other errors", errors.New("EOF"), errBindInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.err == nil {
+				t.Fatal("expected a binding error to translate")
+			}
+			if got := bindErrorMessage(tt.err); got != tt.want {
+				t.Errorf("bindErrorMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindFieldErrors(t *testing.T) {
+	err := binding.Validator.ValidateStruct(&RegistrationRequest{Username: "ana"})
+	got := bindFieldErrors(err)
+
+	want := validation.FieldErrors{
+		validation.FieldEmail:       "email é obrigatório",
+		validation.FieldPassword:    "senha é obrigatória",
+		validation.FieldDisplayName: "nome de exibição é obrigatório",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d field errors, got %v", len(want), got)
+	}
+	for field, message := range want {
+		if got[field] != message {
+			t.Errorf("field %s: got %q, want %q", field, got[field], message)
+		}
+	}
+
+	if fieldErrs := bindFieldErrors(errors.New("EOF")); fieldErrs.HasErrors() {
+		t.Errorf("expected no field errors for a non-validation error, got %v", fieldErrs)
+	}
+}
+
+func TestAuthHandler_Login_BindErrorIsReadable(t *testing.T) {
+	c, w := setupTestRouter()
+	handler := NewAuthHandler(&MockAuthService{})
+	c.Request, _ = http.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"password":"secret"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handler.Login(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	var response map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	if response["error"] != "usuário é obrigatório" {
+		t.Errorf("expected a readable message, got %v", response["error"])
+	}
+}
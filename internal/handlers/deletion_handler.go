@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/basepath"
+	"github.com/lucas-varjao/gohtmx/internal/icons"
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+	"github.com/lucas-varjao/gohtmx/internal/middleware"
+	"github.com/lucas-varjao/gohtmx/internal/service"
+	"github.com/lucas-varjao/gohtmx/templates/components"
+
+	"github.com/a-h/templ"
+	"github.com/gin-gonic/gin"
+)
+
+// CancelDeletionPath is the page the account deletion email links to, with ?token=.
+const CancelDeletionPath = "/cancel-deletion"
+
+// msgDeletionsDisabled answers the account deletion routes when they aren't wired.
+const msgDeletionsDisabled = "a exclusão de conta está desativada"
+
+// AccountDeletions deletes accounts at their owner's request (service.DeletionService).
+type AccountDeletions interface {
+	Request(userID, password string) (time.Time, error)
+	Cancel(token string) error
+}
+
+// UseDeletions lets users delete their own account and cancel a deletion still in its grace period.
+// Call it during setup, before serving requests.
+func (h *AuthHandler) UseDeletions(deletions AccountDeletions) {
+	h.deletions = deletions
+}
+
+// DeleteAccountRequest is the body of POST /api/account/delete (JSON or form data).
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required" form:"password"`
+}
+
+// CancelDeletionRequest is the body of POST /auth/cancel-deletion (JSON or form data).
+type CancelDeletionRequest struct {
+	Token string `json:"token" binding:"required" form:"token"`
+}
+
+// RequestAccountDeletion deletes the logged-in user's account, confirmed with their password, and ends
+// their sessions. With a grace period it answers 202 with deletion_scheduled_at, otherwise 200. HTMX
+// (the profile page) goes to the home page, or gets an alert when the password is wrong.
+func (h *AuthHandler) RequestAccountDeletion(c *gin.Context) {
+	if h.deletions == nil {
+		respondJSON(c, http.StatusNotFound, gin.H{"error": msgDeletionsDisabled})
+		return
+	}
+	user, exists := c.Get("user")
+	if !exists {
+		respondJSON(c, http.StatusUnauthorized, gin.H{"error": "não autenticado"})
+		return
+	}
+	userData := user.(*auth.UserData)
+
+	var req DeleteAccountRequest
+	if err := c.ShouldBind(&req); err != nil {
+		h.respondFormError(c, http.StatusBadRequest, "informe sua senha para excluir a conta")
+		return
+	}
+
+	scheduledAt, err := h.deletions.Request(userData.ID, req.Password)
+	if err != nil {
+		if errors.Is(err, service.ErrWrongPassword) {
+			h.respondFormError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		logger.Error("Erro ao excluir conta", "error", err, "user_id", userData.ID)
+		h.respondFormError(c, http.StatusInternalServerError, "falha ao excluir conta")
+		return
+	}
+	// Request already ended the sessions
+	middleware.ClearSessionCookie(c)
+
+	if c.GetHeader("HX-Request") != "" {
+		c.Header("HX-Redirect", basepath.URL("/"))
+		c.Status(http.StatusOK)
+		return
+	}
+	if scheduledAt.IsZero() {
+		respondJSON(c, http.StatusOK, gin.H{"message": "conta excluída"})
+		return
+	}
+	respondJSON(c, http.StatusAccepted, gin.H{
+		"message":               "conta desativada e agendada para exclusão",
+		"deletion_scheduled_at": scheduledAt,
+	})
+}
+
+// CancelAccountDeletion handles POST /auth/cancel-deletion: the token from the deletion email or from the
+// login of an account waiting to be deleted. The account is active again; the user logs in as usual.
+func (h *AuthHandler) CancelAccountDeletion(c *gin.Context) {
+	if h.deletions == nil {
+		respondJSON(c, http.StatusNotFound, gin.H{"error": msgDeletionsDisabled})
+		return
+	}
+	var req CancelDeletionRequest
+	if err := c.ShouldBind(&req); err != nil {
+		h.respondFormError(c, http.StatusBadRequest, "token de cancelamento ausente")
+		return
+	}
+
+	if err := h.deletions.Cancel(req.Token); err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidToken):
+			h.respondFormError(c, http.StatusBadRequest, "link de cancelamento inválido")
+		case errors.Is(err, service.ErrExpiredToken):
+			h.respondFormError(c, http.StatusBadRequest, "o prazo para cancelar a exclusão terminou")
+		default:
+			logger.Error("Erro ao cancelar exclusão de conta", "error", err, "ip", getClientIP(c))
+			h.respondFormError(c, http.StatusInternalServerError, "erro interno do servidor")
+		}
+		return
+	}
+
+	message := "exclusão cancelada; entre novamente para usar sua conta"
+	if c.GetHeader("HX-Request") != "" {
+		renderAlert(c, components.SuccessAlert(message, icons.Success()))
+		return
+	}
+	respondJSON(c, http.StatusOK, gin.H{"message": message})
+}
+
+// respondDeletionPending answers the login of an account waiting to be deleted: HTMX gets the alert with
+// the cancel button (200, so it is swapped into #login-error), API clients 403 with the cancel token.
+func (h *AuthHandler) respondDeletionPending(c *gin.Context, pending *service.DeletionPendingError) {
+	c.Header("Cache-Control", "no-store")
+	if c.GetHeader("HX-Request") != "" {
+		scheduledAt := pending.ScheduledAt.Local().Format("02/01/2006 15:04")
+		renderTemplError(c, templ.Join(components.DeletionPendingAlert(scheduledAt, pending.CancelToken, icons.Trash2()), h.LoginCaptcha(c, true)))
+		return
+	}
+	respondJSON(c, http.StatusForbidden, gin.H{
+		"error":                 pending.Error(),
+		"deletion_scheduled_at": pending.ScheduledAt,
+		"cancel_token":          pending.CancelToken,
+	})
+}
+
+// respondFormError answers a refused form post (account deletion, its cancellation, account recovery):
+// an alert for HTMX (200, so it is swapped into the form's own target), status and JSON error otherwise.
+func (h *AuthHandler) respondFormError(c *gin.Context, status int, message string) {
+	if c.GetHeader("HX-Request") != "" {
+		renderAlert(c, components.ErrorAlert(message, icons.Error()))
+		return
+	}
+	respondJSON(c, status, gin.H{"error": message})
+}
+
+// renderAlert writes alert with status 200 for the requesting form's hx-target, unlike renderTemplError,
+// which retargets to the login and registration forms.
+func renderAlert(c *gin.Context, alert templ.Component) {
+	var buf bytes.Buffer
+	if err := alert.Render(c.Request.Context(), &buf); err != nil {
+		c.String(http.StatusInternalServerError, "Erro ao processar resposta")
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
+}
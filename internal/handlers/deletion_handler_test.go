@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/middleware"
+	"github.com/lucas-varjao/gohtmx/internal/service"
+)
+
+// stubDeletions schedules deletions with password "password123" and cancels only token "good".
+type stubDeletions struct{ grace time.Duration }
+
+func (s stubDeletions) Request(_, password string) (time.Time, error) {
+	if password != "password123" {
+		return time.Time{}, service.ErrWrongPassword
+	}
+	if s.grace == 0 {
+		return time.Time{}, nil
+	}
+	return time.Now().Add(s.grace), nil
+}
+
+func (stubDeletions) Cancel(token string) error {
+	if token != "good" {
+		return service.ErrInvalidToken
+	}
+	return nil
+}
+
+func TestAuthHandler_Login_DeletionPending(t *testing.T) {
+	handler := NewAuthHandler(&MockAuthService{
+		LoginFromFunc: func(_, _ string, _ auth.SessionMetadata) (*service.LoginResponse, error) {
+			return nil, &service.DeletionPendingError{ScheduledAt: time.Now().Add(time.Hour), CancelToken: "good"}
+		},
+	})
+
+	login := func(htmx bool) (int, http.Header, string) {
+		c, w := setupTestRouter()
+		c.Request, _ = http.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"username":"testuser","password":"password123"}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		if htmx {
+			c.Request.Header.Set("HX-Request", "true")
+		}
+		handler.Login(c)
+		return w.Code, w.Header(), w.Body.String()
+	}
+
+	code, header, body := login(false)
+	if code != http.StatusForbidden || !strings.Contains(body, `"cancel_token":"good"`) || !strings.Contains(body, `"deletion_scheduled_at"`) {
+		t.Errorf("expected 403 with the cancel token, got %d %s", code, body)
+	}
+	if header.Get("Set-Cookie") != "" {
+		t.Errorf("no session cookie for a pending deletion, got %q", header.Get("Set-Cookie"))
+	}
+
+	code, _, body = login(true)
+	if code != http.StatusOK || !strings.Contains(body, "data-deletion-pending") || !strings.Contains(body, `value="good"`) {
+		t.Errorf("expected the cancel prompt, got %d %s", code, body)
+	}
+}
+
+func TestAuthHandler_RequestAccountDeletion(t *testing.T) {
+	request := func(handler *AuthHandler, body string) (int, http.Header, string) {
+		c, w := setupTestRouter()
+		c.Request, _ = http.NewRequest(http.MethodPost, "/api/account/delete", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Set("user", &auth.UserData{ID: "1", Role: "user"})
+		handler.RequestAccountDeletion(c)
+		return w.Code, w.Header(), w.Body.String()
+	}
+
+	if code, _, _ := request(NewAuthHandler(&MockAuthService{}), `{"password":"password123"}`); code != http.StatusNotFound {
+		t.Errorf("expected 404 while disabled, got %d", code)
+	}
+
+	withGrace := NewAuthHandler(&MockAuthService{})
+	withGrace.UseDeletions(stubDeletions{grace: time.Hour})
+	immediate := NewAuthHandler(&MockAuthService{})
+	immediate.UseDeletions(stubDeletions{})
+	tests := []struct {
+		name       string
+		handler    *AuthHandler
+		body       string
+		wantStatus int
+	}{
+		{"Missing password", withGrace, `{}`, http.StatusBadRequest},
+		{"Wrong password", withGrace, `{"password":"wrong"}`, http.StatusBadRequest},
+		{"Scheduled", withGrace, `{"password":"password123"}`, http.StatusAccepted},
+		{"Deleted right away", immediate, `{"password":"password123"}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, header, body := request(tt.handler, tt.body)
+			if code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, code, body)
+			}
+			cleared := strings.Contains(header.Get("Set-Cookie"), middleware.SessionCookieName+"=;")
+			if cleared != (code < http.StatusBadRequest) {
+				t.Errorf("session cookie cleared = %v for status %d", cleared, code)
+			}
+		})
+	}
+}
+
+func TestAuthHandler_CancelAccountDeletion(t *testing.T) {
+	handler := NewAuthHandler(&MockAuthService{})
+	handler.UseDeletions(stubDeletions{grace: time.Hour})
+
+	for token, want := range map[string]int{"good": http.StatusOK, "stale": http.StatusBadRequest, "": http.StatusBadRequest} {
+		c, w := setupTestRouter()
+		c.Request, _ = http.NewRequest(http.MethodPost, "/auth/cancel-deletion", strings.NewReader(`{"token":"`+token+`"}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.CancelAccountDeletion(c)
+		if w.Code != want {
+			t.Errorf("token %q: expected status %d, got %d: %s", token, want, w.Code, w.Body.String())
+		}
+	}
+}
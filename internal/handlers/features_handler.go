@@ -0,0 +1,13 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Features returns the public feature flags (config.Features), so clients only offer what the server
+// accepts: e.g. no sign-up link when registration is closed. GET /api/features, no login required.
+func (h *AuthHandler) Features(c *gin.Context) {
+	respondJSON(c, http.StatusOK, h.cfg.Features())
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/lucas-varjao/gohtmx/internal/config"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+)
+
+func TestAuthHandler_Features(t *testing.T) {
+	disabled := false
+	tests := []struct {
+		name         string
+		cfg          *config.Config
+		registration bool
+		passkeys     bool
+		terms        bool
+	}{
+		{"Defaults", &config.Config{}, true, false, false},
+		{"Registration disabled", &config.Config{Registration: config.RegistrationConfig{Enabled: &disabled}}, false, false, false},
+		{"Passkeys enabled", &config.Config{WebAuthn: config.WebAuthnConfig{Enabled: true}}, true, true, false},
+		{"Terms required", &config.Config{Terms: config.TermsConfig{Version: "2025-01"}}, true, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewAuthHandlerWithConfig(&MockAuthService{
+				RegisterFunc: func(username, email, password, displayName string) (*models.User, error) {
+					return &models.User{Username: username, Email: email}, nil
+				},
+			}, tt.cfg)
+
+			c, w := setupTestRouter()
+			c.Request, _ = http.NewRequest(http.MethodGet, "/api/features", nil)
+			handler.Features(c)
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", w.Code)
+			}
+			var flags map[string]bool
+			if err := json.Unmarshal(w.Body.Bytes(), &flags); err != nil {
+				t.Fatalf("invalid JSON: %v", err)
+			}
+			if flags["registration"] != tt.registration || flags["passkeys"] != tt.passkeys || flags["terms"] != tt.terms {
+				t.Errorf("expected registration=%v passkeys=%v terms=%v, got %s", tt.registration, tt.passkeys, tt.terms, w.Body.String())
+			}
+
+			// The flag matches what Register enforces
+			c, w = setupTestRouter()
+			body := `{"username":"newuser","email":"new@example.com","password":"Padasdasdasdd123!","display_name":"New User"}`
+			c.Request, _ = http.NewRequest(http.MethodPost, "/auth/register", strings.NewReader(body))
+			c.Request.Header.Set("Content-Type", "application/json")
+			handler.Register(c)
+			if open := w.Code != http.StatusForbidden; open != flags["registration"] {
+				t.Errorf("registration flag is %v but register answered %d", flags["registration"], w.Code)
+			}
+			// Without the terms checkbox, only a terms flag makes the registration fail validation
+			if rejected := w.Code == http.StatusBadRequest; rejected != flags["terms"] {
+				t.Errorf("terms flag is %v but register answered %d", flags["terms"], w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"cmp"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/basepath"
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+	"github.com/lucas-varjao/gohtmx/internal/securecookie"
+	"github.com/lucas-varjao/gohtmx/internal/service"
+	"github.com/lucas-varjao/gohtmx/internal/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultCountryHeader is where the client's country is read from when config
+// login.new_country_challenge.country_header is unset (Cloudflare's header).
+const DefaultCountryHeader = "CF-IPCountry"
+
+// LoginChallengePath is the page asking for the code of a login held back by the new country challenge.
+const LoginChallengePath = "/login/verify"
+
+// TrustedDeviceCookie holds the signed token of a device remembered at the new country challenge.
+const TrustedDeviceCookie = "trusted_device"
+
+// msgLoginChallengesDisabled answers POST /auth/login/verify when the new country challenge is off.
+const msgLoginChallengesDisabled = "a confirmação de login por código está desativada"
+
+// LoginChallenges confirms logins held back by the new country challenge (service.LoginChallengeService).
+type LoginChallenges interface {
+	Verify(challengeID, code string, metadata auth.SessionMetadata) (*service.LoginResponse, error)
+}
+
+// UseLoginChallenges turns on the new country challenge: Login passes the client's country (from the
+// configured proxy header) to the auth service, and VerifyLoginChallenge finishes the logins it holds
+// back. Call it during setup, before serving requests.
+func (h *AuthHandler) UseLoginChallenges(challenges LoginChallenges) {
+	h.challenges = challenges
+}
+
+// TrustedDevices remembers the devices that skip the new country challenge (service.TrustedDeviceService).
+type TrustedDevices interface {
+	Remember(userID string) (string, error)
+	TTL() time.Duration
+}
+
+// UseTrustedDevices offers "remember this device" when confirming a login from a new country: the device
+// gets a token in a cookie signed by jar, which Login passes on so the next logins skip the code. Call it
+// during setup, before serving requests.
+func (h *AuthHandler) UseTrustedDevices(devices TrustedDevices, jar *securecookie.SecureCookie) {
+	h.devices = devices
+	h.deviceJar = jar
+}
+
+// LoginChallengeRequest is the body of POST /auth/login/verify (JSON or form data).
+type LoginChallengeRequest struct {
+	Challenge string `json:"challenge" binding:"required" form:"challenge"`
+	Code      string `json:"code"      binding:"required" form:"code"`
+	// Next is the page to go to after login, as in LoginRequest
+	Next string `json:"next" form:"next"`
+	// Remember skips the code on later logins from this device (when UseTrustedDevices is set)
+	Remember bool `json:"remember" form:"remember"`
+}
+
+// requestCountry returns the client's ISO country code from the trusted proxy header, or "" when the
+// challenge is off or the country is not known: a missing header, or one the proxy couldn't resolve
+// (Cloudflare sends "XX" for unknown and "T1" for Tor). With the challenge on, the auth service treats
+// "" as a country the user never logged in from, so these logins are challenged rather than let through.
+func (h *AuthHandler) requestCountry(c *gin.Context) string {
+	if h.challenges == nil {
+		return ""
+	}
+	header := cmp.Or(h.cfg.Login.NewCountryChallenge.CountryHeader, DefaultCountryHeader)
+	country := strings.ToUpper(strings.TrimSpace(c.GetHeader(header)))
+	if len(country) != 2 || country == "XX" || country == "T1" {
+		return ""
+	}
+	for _, r := range country {
+		if r < 'A' || r > 'Z' {
+			return ""
+		}
+	}
+	return country
+}
+
+// respondLoginChallenge answers a login held back for the emailed code: HTMX goes to the page asking for
+// it, API clients get 202 with the challenge to send to POST /auth/login/verify.
+func (h *AuthHandler) respondLoginChallenge(c *gin.Context, challenge *service.LoginChallengeError, next string) {
+	if c.GetHeader("HX-Request") != "" {
+		query := url.Values{"challenge": {challenge.ChallengeID}}
+		if next = cmp.Or(next, c.Query("next")); validation.ValidateRedirectPath(next) == nil {
+			query.Set("next", next)
+		}
+		c.Header("HX-Redirect", basepath.URL(LoginChallengePath+"?"+query.Encode()))
+		c.Status(http.StatusOK)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	respondJSON(c, http.StatusAccepted, gin.H{
+		"step_up":   "email_code",
+		"challenge": challenge.ChallengeID,
+		"message":   challenge.Error(),
+	})
+}
+
+// VerifyLoginChallenge handles POST /auth/login/verify: the code emailed for a login from a new country.
+// On success it sets the session cookie and answers like Login.
+func (h *AuthHandler) VerifyLoginChallenge(c *gin.Context) {
+	if h.challenges == nil {
+		respondJSON(c, http.StatusNotFound, gin.H{"error": msgLoginChallengesDisabled})
+		return
+	}
+	var req LoginChallengeRequest
+	if err := c.ShouldBind(&req); err != nil {
+		h.respondLoginChallengeError(c, http.StatusBadRequest, "informe o código recebido por email")
+		return
+	}
+
+	metadata := auth.SessionMetadata{UserAgent: getUserAgent(c), IP: getClientIP(c)}
+	response, err := h.challenges.Verify(req.Challenge, req.Code, metadata)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrLoginChallengeNotFound):
+			h.respondLoginChallengeError(c, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrLoginCodeInvalid):
+			h.respondLoginChallengeError(c, http.StatusUnauthorized, err.Error())
+		case errors.Is(err, service.ErrUserNotActive):
+			h.respondLoginChallengeError(c, http.StatusUnauthorized, "usuário inativo")
+		case errors.Is(err, service.ErrAccountLocked), errors.Is(err, service.ErrEmailNotVerified):
+			h.respondLoginChallengeError(c, http.StatusUnauthorized, err.Error())
+		default:
+			logger.Error("Erro ao confirmar login por código", "error", err, "ip", metadata.IP)
+			h.respondLoginChallengeError(c, http.StatusInternalServerError, "erro interno do servidor")
+		}
+		return
+	}
+	setSessionCookie(c, response.SessionID)
+	h.rememberUsername(c, response.User.Identifier)
+	if req.Remember {
+		h.rememberDevice(c, response.User.ID)
+	}
+
+	if c.GetHeader("HX-Request") != "" {
+		c.Header("HX-Redirect", basepath.URL(h.postLoginRedirect(c, req.Next, response.User.Role)))
+		c.Status(http.StatusOK)
+		return
+	}
+	respondJSON(c, http.StatusOK, response)
+}
+
+// rememberDevice trusts the client's device for userID's next logins. A failure only costs the user a
+// code next time, so it is logged and the login goes on.
+func (h *AuthHandler) rememberDevice(c *gin.Context, userID string) {
+	if h.devices == nil {
+		return
+	}
+	token, err := h.devices.Remember(userID)
+	if err == nil {
+		err = h.deviceJar.SetCookie(c.Writer, TrustedDeviceCookie, token, h.devices.TTL())
+	}
+	if err != nil {
+		logger.Error("Erro ao lembrar dispositivo", "error", err, "user_id", userID)
+	}
+}
+
+// deviceToken returns the remembered device token from the client's cookie, or "" without a valid one.
+func (h *AuthHandler) deviceToken(c *gin.Context) string {
+	if h.devices == nil {
+		return ""
+	}
+	var token string
+	if err := h.deviceJar.ReadCookie(c.Request, TrustedDeviceCookie, &token); err != nil {
+		return ""
+	}
+	return token
+}
+
+// respondLoginChallengeError answers a rejected code: an alert in #login-challenge-error for HTMX (200, so
+// it is swapped in), status and JSON error otherwise.
+func (h *AuthHandler) respondLoginChallengeError(c *gin.Context, status int, message string) {
+	if c.GetHeader("HX-Request") != "" {
+		renderHTMXError(c, message)
+		return
+	}
+	respondJSON(c, status, gin.H{"error": message})
+}
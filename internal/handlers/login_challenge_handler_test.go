@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/config"
+	"github.com/lucas-varjao/gohtmx/internal/middleware"
+	"github.com/lucas-varjao/gohtmx/internal/securecookie"
+	"github.com/lucas-varjao/gohtmx/internal/service"
+)
+
+// stubLoginChallenges accepts only code "123456" for the "good" challenge.
+type stubLoginChallenges struct{}
+
+func (stubLoginChallenges) Verify(challengeID, code string, _ auth.SessionMetadata) (*service.LoginResponse, error) {
+	if challengeID != "good" {
+		return nil, service.ErrLoginChallengeNotFound
+	}
+	if code != "123456" {
+		return nil, service.ErrLoginCodeInvalid
+	}
+	return &service.LoginResponse{SessionID: "challenge-session", ExpiresAt: time.Now().Add(time.Hour), User: auth.UserData{ID: "1", Role: "user"}}, nil
+}
+
+func TestAuthHandler_Login_NewCountryChallenge(t *testing.T) {
+	var gotCountry string
+	mockService := &MockAuthService{
+		LoginFromFunc: func(_, _ string, metadata auth.SessionMetadata) (*service.LoginResponse, error) {
+			gotCountry = metadata.Country
+			if metadata.Country == "US" {
+				return nil, &service.LoginChallengeError{ChallengeID: "good"}
+			}
+			return &service.LoginResponse{SessionID: "sid", User: auth.UserData{ID: "1", Role: "user"}}, nil
+		},
+	}
+	cfg := &config.Config{}
+	cfg.Login.NewCountryChallenge.CountryHeader = "X-Country"
+
+	login := func(handler *AuthHandler, country string, htmx bool) (int, http.Header, string) {
+		c, w := setupTestRouter()
+		c.Request, _ = http.NewRequest(http.MethodPost, "/auth/login?next=/profile", strings.NewReader(`{"username":"testuser","password":"password123"}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Request.Header.Set("X-Country", country)
+		if htmx {
+			c.Request.Header.Set("HX-Request", "true")
+		}
+		handler.Login(c)
+		return w.Code, w.Header(), w.Body.String()
+	}
+
+	t.Run("Disabled ignores the country header", func(t *testing.T) {
+		code, _, _ := login(NewAuthHandlerWithConfig(mockService, cfg), "us", false)
+		if code != http.StatusOK || gotCountry != "" {
+			t.Errorf("expected 200 without a country, got %d with %q", code, gotCountry)
+		}
+	})
+
+	handler := NewAuthHandlerWithConfig(mockService, cfg)
+	handler.UseLoginChallenges(stubLoginChallenges{})
+
+	t.Run("Known country logs in normally", func(t *testing.T) {
+		code, header, _ := login(handler, "br", false)
+		if code != http.StatusOK || gotCountry != "BR" {
+			t.Errorf("expected 200 with country BR, got %d with %q", code, gotCountry)
+		}
+		if !strings.Contains(header.Get("Set-Cookie"), middleware.SessionCookieName+"=sid") {
+			t.Errorf("expected the session cookie, got %q", header.Get("Set-Cookie"))
+		}
+	})
+
+	t.Run("Unknown country from the proxy is passed as unknown", func(t *testing.T) {
+		for _, country := range []string{"XX", "T1", "", "B1"} {
+			gotCountry = "set"
+			login(handler, country, false)
+			if gotCountry != "" {
+				t.Errorf("header %q: expected no country, got %q", country, gotCountry)
+			}
+		}
+	})
+
+	t.Run("New country asks for the code", func(t *testing.T) {
+		code, header, body := login(handler, "US", false)
+		if code != http.StatusAccepted || !strings.Contains(body, `"challenge":"good"`) || !strings.Contains(body, `"step_up":"email_code"`) {
+			t.Errorf("expected 202 with the challenge, got %d %s", code, body)
+		}
+		if header.Get("Set-Cookie") != "" {
+			t.Errorf("no session cookie before the code, got %q", header.Get("Set-Cookie"))
+		}
+
+		code, header, _ = login(handler, "US", true)
+		if want := LoginChallengePath + "?challenge=good&next=%2Fprofile"; code != http.StatusOK || header.Get("HX-Redirect") != want {
+			t.Errorf("expected HX-Redirect to %s, got %d %q", want, code, header.Get("HX-Redirect"))
+		}
+	})
+}
+
+func TestAuthHandler_VerifyLoginChallenge(t *testing.T) {
+	verify := func(handler *AuthHandler, body string) (int, http.Header, string) {
+		c, w := setupTestRouter()
+		c.Request, _ = http.NewRequest(http.MethodPost, "/auth/login/verify", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.VerifyLoginChallenge(c)
+		return w.Code, w.Header(), w.Body.String()
+	}
+
+	if code, _, _ := verify(NewAuthHandler(&MockAuthService{}), `{"challenge":"good","code":"123456"}`); code != http.StatusNotFound {
+		t.Errorf("expected 404 while disabled, got %d", code)
+	}
+
+	handler := NewAuthHandler(&MockAuthService{})
+	handler.UseLoginChallenges(stubLoginChallenges{})
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"Missing code", `{"challenge":"good"}`, http.StatusBadRequest},
+		{"Wrong code", `{"challenge":"good","code":"654321"}`, http.StatusUnauthorized},
+		{"Expired challenge", `{"challenge":"gone","code":"123456"}`, http.StatusBadRequest},
+		{"Right code", `{"challenge":"good","code":"123456"}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, header, body := verify(handler, tt.body)
+			if code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, code, body)
+			}
+			gotCookie := strings.Contains(header.Get("Set-Cookie"), middleware.SessionCookieName+"=challenge-session")
+			if gotCookie != (code == http.StatusOK) {
+				t.Errorf("session cookie set = %v for status %d", gotCookie, code)
+			}
+		})
+	}
+	t.Run("HTMX errors go to the code page's alert", func(t *testing.T) {
+		c, w := setupTestRouter()
+		c.Request, _ = http.NewRequest(http.MethodPost, "/auth/login/verify", strings.NewReader(`{"challenge":"good","code":"654321"}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Request.Header.Set("HX-Request", "true")
+		handler.VerifyLoginChallenge(c)
+		if w.Code != http.StatusOK || w.Header().Get("HX-Retarget") != "#login-challenge-error" {
+			t.Errorf("expected 200 retargeted to #login-challenge-error, got %d %q", w.Code, w.Header().Get("HX-Retarget"))
+		}
+	})
+}
+
+// stubTrustedDevices hands out "device-token" for every remembered device.
+type stubTrustedDevices struct{ remembered []string }
+
+func (s *stubTrustedDevices) Remember(userID string) (string, error) {
+	s.remembered = append(s.remembered, userID)
+	return "device-token", nil
+}
+
+func (*stubTrustedDevices) TTL() time.Duration { return 24 * time.Hour }
+
+func TestAuthHandler_RememberDevice(t *testing.T) {
+	jar, err := securecookie.New(strings.Repeat("k", securecookie.MinSecretLength), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	devices := &stubTrustedDevices{}
+	var gotToken string
+	mockService := &MockAuthService{
+		LoginFromFunc: func(_, _ string, metadata auth.SessionMetadata) (*service.LoginResponse, error) {
+			gotToken = metadata.DeviceToken
+			return &service.LoginResponse{SessionID: "sid", User: auth.UserData{ID: "1", Role: "user"}}, nil
+		},
+	}
+	handler := NewAuthHandler(mockService)
+	handler.UseLoginChallenges(stubLoginChallenges{})
+	handler.UseTrustedDevices(devices, jar)
+
+	verify := func(body string) *http.Cookie {
+		c, w := setupTestRouter()
+		c.Request, _ = http.NewRequest(http.MethodPost, "/auth/login/verify", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.VerifyLoginChallenge(c)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		for _, cookie := range w.Result().Cookies() {
+			if cookie.Name == TrustedDeviceCookie {
+				return cookie
+			}
+		}
+		return nil
+	}
+	login := func(cookie *http.Cookie) {
+		c, _ := setupTestRouter()
+		c.Request, _ = http.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"username":"testuser","password":"password123"}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		if cookie != nil {
+			c.Request.AddCookie(cookie)
+		}
+		gotToken = "unset"
+		handler.Login(c)
+	}
+
+	if cookie := verify(`{"challenge":"good","code":"123456"}`); cookie != nil || len(devices.remembered) != 0 {
+		t.Errorf("expected no device cookie without remember, got %v", cookie)
+	}
+	cookie := verify(`{"challenge":"good","code":"123456","remember":true}`)
+	if cookie == nil || cookie.Value == "device-token" || !cookie.HttpOnly || cookie.MaxAge <= 0 {
+		t.Fatalf("expected a signed, HttpOnly, persistent device cookie, got %+v", cookie)
+	}
+	if len(devices.remembered) != 1 || devices.remembered[0] != "1" {
+		t.Errorf("expected the device remembered for user 1, got %v", devices.remembered)
+	}
+
+	login(cookie)
+	if gotToken != "device-token" {
+		t.Errorf("expected the login to carry the device token, got %q", gotToken)
+	}
+	login(&http.Cookie{Name: TrustedDeviceCookie, Value: "device-token"})
+	if gotToken != "" {
+		t.Errorf("expected an unsigned cookie to be ignored, got %q", gotToken)
+	}
+	login(nil)
+	if gotToken != "" {
+		t.Errorf("expected no device token without the cookie, got %q", gotToken)
+	}
+}
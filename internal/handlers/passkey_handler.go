@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+	"github.com/lucas-varjao/gohtmx/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/protocol"
+)
+
+// msgPasskeysDisabled answers the passkey routes when config webauthn.enabled is false.
+const msgPasskeysDisabled = "o login com passkey está desativado"
+
+// Passkeys registers passkeys and logs in with them (service.PasskeyService).
+type Passkeys interface {
+	BeginRegistration(userID string) (*protocol.CredentialCreation, string, error)
+	FinishRegistration(userID, ceremonyID, name string, r *http.Request) (*models.WebAuthnCredential, error)
+	BeginLogin() (*protocol.CredentialAssertion, string, error)
+	FinishLogin(ceremonyID string, r *http.Request, metadata auth.SessionMetadata) (*service.LoginResponse, error)
+	List(userID string) ([]models.WebAuthnCredential, error)
+	Delete(userID, credentialID string) error
+}
+
+// UsePasskeys enables passkey registration and login next to passwords. Call it during setup, before serving requests.
+func (h *AuthHandler) UsePasskeys(passkeys Passkeys) {
+	h.passkeys = passkeys
+}
+
+// passkeysEnabled answers 404 and reports false when no passkey service is configured.
+func (h *AuthHandler) passkeysEnabled(c *gin.Context) bool {
+	if h.passkeys == nil {
+		respondJSON(c, http.StatusNotFound, gin.H{"error": msgPasskeysDisabled})
+		return false
+	}
+	return true
+}
+
+// respondPasskeyError maps passkey service errors to a status and a user-facing message.
+func respondPasskeyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrPasskeyCeremonyNotFound):
+		respondJSON(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrPasskeyInvalid), errors.Is(err, service.ErrPasskeyCloned):
+		respondJSON(c, http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrPasskeyNotFound):
+		respondJSON(c, http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrPasskeyBusy):
+		respondJSON(c, http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrUserNotActive):
+		respondJSON(c, http.StatusUnauthorized, gin.H{"error": "usuário inativo"})
+	case errors.Is(err, service.ErrAccountLocked), errors.Is(err, service.ErrEmailNotVerified):
+		respondJSON(c, http.StatusUnauthorized, gin.H{"error": err.Error()})
+	default:
+		logger.Error("Erro na operação com passkey", "error", err, "path", c.Request.URL.Path, "ip", getClientIP(c))
+		respondJSON(c, http.StatusInternalServerError, gin.H{"error": "falha na operação com a passkey"})
+	}
+}
+
+// BeginPasskeyLogin handles POST /auth/passkey/login/begin: the options for navigator.credentials.get()
+// and the ceremony ID to send back to FinishPasskeyLogin.
+func (h *AuthHandler) BeginPasskeyLogin(c *gin.Context) {
+	if !h.passkeysEnabled(c) {
+		return
+	}
+	options, ceremonyID, err := h.passkeys.BeginLogin()
+	if err != nil {
+		respondPasskeyError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	respondJSON(c, http.StatusOK, gin.H{"ceremony": ceremonyID, "options": options})
+}
+
+// FinishPasskeyLogin handles POST /auth/passkey/login/finish?ceremony=...: the body is the credential
+// returned by navigator.credentials.get(). On success it sets the session cookie like Login.
+func (h *AuthHandler) FinishPasskeyLogin(c *gin.Context) {
+	if !h.passkeysEnabled(c) {
+		return
+	}
+	metadata := auth.SessionMetadata{UserAgent: getUserAgent(c), IP: getClientIP(c)}
+	response, err := h.passkeys.FinishLogin(c.Query("ceremony"), c.Request, metadata)
+	if err != nil {
+		respondPasskeyError(c, err)
+		return
+	}
+	c.Set("firstLogin", response.FirstLogin)
+	setSessionCookie(c, response.SessionID)
+	respondJSON(c, http.StatusOK, response)
+}
+
+// BeginPasskeyRegistration handles POST /api/passkeys/register/begin: the options for
+// navigator.credentials.create() for the current user and the ceremony ID.
+func (h *AuthHandler) BeginPasskeyRegistration(c *gin.Context) {
+	if !h.passkeysEnabled(c) {
+		return
+	}
+	options, ceremonyID, err := h.passkeys.BeginRegistration(c.GetString("userID"))
+	if err != nil {
+		respondPasskeyError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	respondJSON(c, http.StatusOK, gin.H{"ceremony": ceremonyID, "options": options})
+}
+
+// FinishPasskeyRegistration handles POST /api/passkeys/register/finish?ceremony=...&name=...: the body
+// is the credential returned by navigator.credentials.create(). Only the user who began can finish.
+func (h *AuthHandler) FinishPasskeyRegistration(c *gin.Context) {
+	if !h.passkeysEnabled(c) {
+		return
+	}
+	credential, err := h.passkeys.FinishRegistration(c.GetString("userID"), c.Query("ceremony"), c.Query("name"), c.Request)
+	if err != nil {
+		respondPasskeyError(c, err)
+		return
+	}
+	respondJSON(c, http.StatusCreated, credential)
+}
+
+// ListPasskeys handles GET /api/passkeys: the current user's passkeys. ?style=envelope|array picks
+// another response shape (see respondList).
+func (h *AuthHandler) ListPasskeys(c *gin.Context) {
+	if !h.passkeysEnabled(c) {
+		return
+	}
+	credentials, err := h.passkeys.List(c.GetString("userID"))
+	if err != nil {
+		respondPasskeyError(c, err)
+		return
+	}
+	if credentials == nil {
+		credentials = []models.WebAuthnCredential{}
+	}
+	respondList(c, gin.H{"passkeys": credentials}, credentials, ListMeta{Total: int64(len(credentials))})
+}
+
+// DeletePasskey handles DELETE /api/passkeys/:id for one of the current user's passkeys.
+func (h *AuthHandler) DeletePasskey(c *gin.Context) {
+	if !h.passkeysEnabled(c) {
+		return
+	}
+	if err := h.passkeys.Delete(c.GetString("userID"), c.Param("id")); err != nil {
+		respondPasskeyError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
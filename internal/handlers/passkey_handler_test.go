@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/middleware"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+	"github.com/lucas-varjao/gohtmx/internal/service"
+
+	"github.com/go-webauthn/webauthn/protocol"
+)
+
+// stubPasskeys accepts only the "good" ceremony.
+type stubPasskeys struct{}
+
+func (stubPasskeys) BeginRegistration(string) (*protocol.CredentialCreation, string, error) {
+	return &protocol.CredentialCreation{}, "good", nil
+}
+
+func (stubPasskeys) FinishRegistration(_, ceremonyID, name string, _ *http.Request) (*models.WebAuthnCredential, error) {
+	if ceremonyID != "good" {
+		return nil, service.ErrPasskeyCeremonyNotFound
+	}
+	return &models.WebAuthnCredential{ID: 1, Name: name}, nil
+}
+
+func (stubPasskeys) BeginLogin() (*protocol.CredentialAssertion, string, error) {
+	return &protocol.CredentialAssertion{}, "good", nil
+}
+
+func (stubPasskeys) FinishLogin(ceremonyID string, _ *http.Request, _ auth.SessionMetadata) (*service.LoginResponse, error) {
+	switch ceremonyID {
+	case "good":
+		return &service.LoginResponse{SessionID: "passkey-session", ExpiresAt: time.Now().Add(time.Hour), User: auth.UserData{ID: "1"}}, nil
+	case "forged":
+		return nil, service.ErrPasskeyInvalid
+	}
+	return nil, service.ErrPasskeyCeremonyNotFound
+}
+
+func (stubPasskeys) List(string) ([]models.WebAuthnCredential, error) { return nil, nil }
+
+func (stubPasskeys) Delete(string, string) error { return service.ErrPasskeyNotFound }
+
+func TestAuthHandler_Passkeys(t *testing.T) {
+	t.Run("Disabled passkeys answer 404", func(t *testing.T) {
+		c, w := setupTestRouter()
+		c.Request, _ = http.NewRequest(http.MethodPost, "/auth/passkey/login/begin", nil)
+
+		NewAuthHandler(&MockAuthService{}).BeginPasskeyLogin(c)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status %d, got %d", http.StatusNotFound, w.Code)
+		}
+	})
+
+	tests := []struct {
+		name       string
+		ceremony   string
+		wantStatus int
+		wantCookie bool
+	}{
+		{"Valid login sets the session cookie", "good", http.StatusOK, true},
+		{"Rejected signature", "forged", http.StatusUnauthorized, false},
+		{"Expired or reused ceremony", "used", http.StatusBadRequest, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := setupTestRouter()
+			c.Request, _ = http.NewRequest(http.MethodPost, "/auth/passkey/login/finish?ceremony="+tt.ceremony, strings.NewReader("{}"))
+			handler := NewAuthHandler(&MockAuthService{})
+			handler.UsePasskeys(stubPasskeys{})
+
+			handler.FinishPasskeyLogin(c)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			setCookie := w.Header().Get("Set-Cookie")
+			if got := strings.Contains(setCookie, middleware.SessionCookieName+"=passkey-session"); got != tt.wantCookie {
+				t.Errorf("session cookie set = %v, want %v (Set-Cookie: %q)", got, tt.wantCookie, setCookie)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/lucas-varjao/gohtmx/internal/icons"
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+	"github.com/lucas-varjao/gohtmx/internal/service"
+	"github.com/lucas-varjao/gohtmx/internal/validation"
+	"github.com/lucas-varjao/gohtmx/templates/components"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RecoverPath is the page where users who lost access to their email type the recovery code an admin
+// created for them.
+const RecoverPath = "/recover"
+
+// msgRecoveryDisabled answers POST /auth/recover when account recovery isn't wired.
+const msgRecoveryDisabled = "a recuperação de conta por código está desativada"
+
+// AccountRecovery sets a new password with a recovery code created by an admin (service.RecoveryCodeService).
+type AccountRecovery interface {
+	Redeem(identifier, code, newPassword, ip string) error
+}
+
+// UseRecoveryCodes lets users set a new password with a recovery code created by an admin.
+// Call it during setup, before serving requests.
+func (h *AuthHandler) UseRecoveryCodes(recovery AccountRecovery) {
+	h.recovery = recovery
+}
+
+// RecoverAccountRequest is the body of POST /auth/recover (JSON or form data).
+type RecoverAccountRequest struct {
+	Identifier      string `json:"identifier"       binding:"required" form:"identifier"`
+	Code            string `json:"code"             binding:"required" form:"code"`
+	NewPassword     string `json:"new_password"     binding:"required" form:"new_password"`
+	ConfirmPassword string `json:"confirm_password" binding:"required" form:"confirm_password"`
+}
+
+// RecoverAccount handles POST /auth/recover: the username or email, the recovery code and the new
+// password. Every session of the account ends; the user logs in with the new password.
+func (h *AuthHandler) RecoverAccount(c *gin.Context) {
+	if h.recovery == nil {
+		respondJSON(c, http.StatusNotFound, gin.H{"error": msgRecoveryDisabled})
+		return
+	}
+	var req RecoverAccountRequest
+	if err := c.ShouldBind(&req); err != nil {
+		h.respondFormError(c, http.StatusBadRequest, bindErrorMessage(err))
+		return
+	}
+	// The account is only known from the code; Redeem checks the password against its personal info
+	if err := validation.ValidatePasswordChange(req.NewPassword, req.ConfirmPassword, "", "", ""); err != nil {
+		h.respondFormError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ip := getClientIP(c)
+	if err := h.recovery.Redeem(req.Identifier, req.Code, req.NewPassword, ip); err != nil {
+		var validationErr *service.ValidationError
+		switch {
+		case errors.Is(err, service.ErrRecoveryCodeInvalid), errors.Is(err, service.ErrPasswordReused),
+			errors.As(err, &validationErr):
+			h.respondFormError(c, http.StatusBadRequest, err.Error())
+		default:
+			logger.Error("Erro ao recuperar conta com código", "error", err, "ip", ip)
+			h.respondFormError(c, http.StatusInternalServerError, "erro interno do servidor")
+		}
+		return
+	}
+
+	message := "senha redefinida; entre com a nova senha"
+	if c.GetHeader("HX-Request") != "" {
+		renderAlert(c, components.SuccessAlert(message, icons.Success()))
+		return
+	}
+	respondJSON(c, http.StatusOK, gin.H{"message": message})
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/lucas-varjao/gohtmx/internal/service"
+)
+
+// stubRecovery accepts only code "GOOD-CODE-1234" for user "alice".
+type stubRecovery struct{}
+
+func (stubRecovery) Redeem(identifier, code, _, _ string) error {
+	if identifier != "alice" || code != "GOOD-CODE-1234" {
+		return service.ErrRecoveryCodeInvalid
+	}
+	return nil
+}
+
+func TestAuthHandler_RecoverAccount(t *testing.T) {
+	tests := []struct {
+		name     string
+		recovery AccountRecovery
+		body     string
+		want     int
+	}{
+		{"Not wired", nil, `{"identifier":"alice","code":"GOOD-CODE-1234","new_password":"N3w!Passw0rd","confirm_password":"N3w!Passw0rd"}`, http.StatusNotFound},
+		{"Valid code", stubRecovery{}, `{"identifier":"alice","code":"GOOD-CODE-1234","new_password":"N3w!Passw0rd","confirm_password":"N3w!Passw0rd"}`, http.StatusOK},
+		{"Wrong code", stubRecovery{}, `{"identifier":"alice","code":"BAD","new_password":"N3w!Passw0rd","confirm_password":"N3w!Passw0rd"}`, http.StatusBadRequest},
+		{"Mismatched passwords", stubRecovery{}, `{"identifier":"alice","code":"GOOD-CODE-1234","new_password":"N3w!Passw0rd","confirm_password":"Other!Passw0rd"}`, http.StatusBadRequest},
+		{"Missing fields", stubRecovery{}, `{"identifier":"alice"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewAuthHandler(&MockAuthService{})
+			if tt.recovery != nil {
+				handler.UseRecoveryCodes(tt.recovery)
+			}
+			c, w := setupTestRouter()
+			c.Request, _ = http.NewRequest(http.MethodPost, "/auth/recover", strings.NewReader(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+			handler.RecoverAccount(c)
+			if w.Code != tt.want {
+				t.Errorf("expected status %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/lucas-varjao/gohtmx/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondJSON writes obj as JSON: indented when config api.pretty_json is set or, only with
+// api.allow_pretty_param (meant for development), when the request has ?pretty=1. Compact otherwise.
+func respondJSON(c *gin.Context, status int, obj any) {
+	writeJSON(c, config.GetConfig(), status, obj)
+}
+
+// writeJSON is respondJSON with an explicit config (nil = compact).
+func writeJSON(c *gin.Context, cfg *config.Config, status int, obj any) {
+	if wantsPrettyJSON(c, cfg) {
+		c.IndentedJSON(status, obj)
+		return
+	}
+	c.JSON(status, obj)
+}
+
+func wantsPrettyJSON(c *gin.Context, cfg *config.Config) bool {
+	if cfg == nil {
+		return false
+	}
+	if cfg.API.PrettyJSON {
+		return true
+	}
+	if !cfg.API.AllowPrettyParam {
+		return false
+	}
+	switch c.Query("pretty") {
+	case "1", "true":
+		return true
+	}
+	return false
+}
+
+// List response styles a client can opt into with ?style= or an Accept profile
+// (Accept: application/json; profile="envelope"). Without one, list endpoints keep their own shape.
+const (
+	listStyleEnvelope = "envelope" // {"data": [...], "meta": {...}}
+	listStyleArray    = "array"    // [...], with meta in the X-Total-Count, X-Page and X-Per-Page headers
+)
+
+// ListMeta describes a list response: the total of matches and, for paginated lists, the page.
+type ListMeta struct {
+	Total   int64 `json:"total"`
+	Page    int   `json:"page,omitempty"`
+	PerPage int   `json:"per_page,omitempty"`
+}
+
+// ListEnvelope is the list response in the "envelope" style.
+type ListEnvelope struct {
+	Data any      `json:"data"`
+	Meta ListMeta `json:"meta"`
+}
+
+// respondList writes a list in the style the client asked for (see listStyle): items and meta for the
+// envelope and array styles, and body, the endpoint's own shape carrying the same data, otherwise.
+func respondList(c *gin.Context, body, items any, meta ListMeta) {
+	switch listStyle(c) {
+	case listStyleEnvelope:
+		respondJSON(c, http.StatusOK, ListEnvelope{Data: items, Meta: meta})
+	case listStyleArray:
+		c.Header("X-Total-Count", strconv.FormatInt(meta.Total, 10))
+		if meta.PerPage > 0 {
+			c.Header("X-Page", strconv.Itoa(meta.Page))
+			c.Header("X-Per-Page", strconv.Itoa(meta.PerPage))
+		}
+		respondJSON(c, http.StatusOK, items)
+	default:
+		respondJSON(c, http.StatusOK, body)
+	}
+}
+
+// listStyle returns the list style from ?style=, else from the profile parameter of an application/json
+// range in the Accept header. Unknown styles are ignored.
+func listStyle(c *gin.Context) string {
+	style := c.Query("style")
+	if style == "" {
+		for part := range strings.SplitSeq(c.GetHeader("Accept"), ",") {
+			params := strings.Split(part, ";")
+			if !strings.EqualFold(strings.TrimSpace(params[0]), "application/json") {
+				continue
+			}
+			for _, param := range params[1:] {
+				if key, value, _ := strings.Cut(strings.TrimSpace(param), "="); strings.EqualFold(key, "profile") {
+					style = strings.Trim(value, `"`)
+				}
+			}
+		}
+	}
+	switch style {
+	case listStyleEnvelope, listStyleArray:
+		return style
+	}
+	return ""
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lucas-varjao/gohtmx/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestWriteJSON_PrettyToggle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const compact = `{"status":"ok"}`
+	const pretty = "{\n    \"status\": \"ok\"\n}"
+
+	tests := []struct {
+		name  string
+		cfg   *config.Config
+		query string
+		want  string
+	}{
+		{"default is compact", nil, "", compact},
+		{"query ignored without the dev flag", &config.Config{}, "?pretty=1", compact},
+		{"config pretty-prints everything", &config.Config{API: config.APIConfig{PrettyJSON: true}}, "", pretty},
+		{"dev flag honors the query", &config.Config{API: config.APIConfig{AllowPrettyParam: true}}, "?pretty=1", pretty},
+		{"dev flag without the query", &config.Config{API: config.APIConfig{AllowPrettyParam: true}}, "?pretty=0", compact},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/api/me"+tt.query, nil)
+
+			writeJSON(c, tt.cfg, http.StatusOK, gin.H{"status": "ok"})
+
+			if w.Code != http.StatusOK || w.Body.String() != tt.want {
+				t.Errorf("expected %d %q, got %d %q", http.StatusOK, tt.want, w.Code, w.Body.String())
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+				t.Errorf("unexpected content type %q", ct)
+			}
+		})
+	}
+}
+
+func TestRespondList_Styles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	items := []string{"ana", "bia"}
+	meta := ListMeta{Total: 12, Page: 2, PerPage: 2}
+	body := gin.H{"users": items, "total": 12, "page": 2, "per_page": 2}
+
+	tests := []struct {
+		name       string
+		query      string
+		accept     string
+		want       string
+		wantHeader string // X-Total-Count
+	}{
+		{"default keeps the endpoint's shape", "", "", `{"page":2,"per_page":2,"total":12,"users":["ana","bia"]}`, ""},
+		{"envelope by query", "?style=envelope", "", `{"data":["ana","bia"],"meta":{"total":12,"page":2,"per_page":2}}`, ""},
+		{"envelope by Accept profile", "", `application/json; profile="envelope"`, `{"data":["ana","bia"],"meta":{"total":12,"page":2,"per_page":2}}`, ""},
+		{"array by query", "?style=array", "", `["ana","bia"]`, "12"},
+		{"array by Accept profile", "", "text/html;q=0.5, application/json;profile=array", `["ana","bia"]`, "12"},
+		{"unknown style falls back to the default", "?style=jsonapi", "", `{"page":2,"per_page":2,"total":12,"users":["ana","bia"]}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/api/admin/users"+tt.query, nil)
+			c.Request.Header.Set("Accept", tt.accept)
+
+			respondList(c, body, items, meta)
+
+			if w.Code != http.StatusOK || w.Body.String() != tt.want {
+				t.Errorf("expected %d %s, got %d %s", http.StatusOK, tt.want, w.Code, w.Body.String())
+			}
+			if got := w.Header().Get("X-Total-Count"); got != tt.wantHeader {
+				t.Errorf("expected X-Total-Count %q, got %q", tt.wantHeader, got)
+			}
+		})
+	}
+}
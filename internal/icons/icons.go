@@ -26,6 +26,11 @@ func Error() template.HTML {
 	return lucide.CircleX(lucide.Options{Color: colorCurrent, Class: classAlert})
 }
 
+// Success returns the CircleCheck icon for success alerts (DaisyUI alert-success).
+func Success() template.HTML {
+	return lucide.CircleCheck(lucide.Options{Color: colorCurrent, Class: classAlert})
+}
+
 // LogIn returns the log-in icon for the "Entrar" navbar link and login submit button.
 func LogIn() template.HTML {
 	return lucide.LogIn(lucide.Options{Color: colorCurrent, Class: classButton})
@@ -122,3 +127,8 @@ func Shield() template.HTML {
 func UsersRound() template.HTML {
 	return lucide.UsersRound(lucide.Options{Color: colorCurrent, Class: classButton})
 }
+
+// KeyRound returns the key-round icon for the login attempts link in admin sidebar.
+func KeyRound() template.HTML {
+	return lucide.KeyRound(lucide.Options{Color: colorCurrent, Class: classButton})
+}
@@ -0,0 +1,99 @@
+package jobs
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/email"
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// InactivityDeactivator sets Active=false on accounts without a login for longer than Threshold.
+// Accounts in ProtectedUsernames (e.g. the seeded admin) are never touched, and an admin is only
+// deactivated while another active admin remains.
+type InactivityDeactivator struct {
+	DB                 *gorm.DB
+	Threshold          time.Duration
+	ProtectedUsernames []string
+	// Email, when non-nil, is used to notify each user before the account is deactivated
+	Email email.EmailServiceInterface
+	// Notifications, when non-nil, skips the notice for users who turned it off
+	Notifications email.Gate
+	// Now defaults to time.Now; overridable in tests
+	Now func() time.Time
+}
+
+// Job adapts Run to the Func signature used by Every.
+func (d *InactivityDeactivator) Job() Func {
+	return func(ctx context.Context) error {
+		_, err := d.Run(ctx)
+		return err
+	}
+}
+
+// Run deactivates inactive accounts and returns how many were deactivated.
+func (d *InactivityDeactivator) Run(ctx context.Context) (int, error) {
+	now := time.Now
+	if d.Now != nil {
+		now = d.Now
+	}
+	cutoff := now().Add(-d.Threshold)
+
+	// Users that never logged in have a zero LastLogin, so created_at keeps new accounts safe
+	var candidates []models.User
+	query := d.DB.WithContext(ctx).
+		Where("active = ? AND last_login < ? AND created_at < ?", true, cutoff, cutoff)
+	if len(d.ProtectedUsernames) > 0 {
+		query = query.Where("username NOT IN ?", d.ProtectedUsernames)
+	}
+	if err := query.Order("last_login ASC").Find(&candidates).Error; err != nil {
+		logger.Error("Erro ao buscar contas inativas", "error", err)
+		return 0, err
+	}
+
+	deactivated := 0
+	for i := range candidates {
+		user := &candidates[i]
+		if slices.Contains(d.ProtectedUsernames, user.Username) {
+			continue
+		}
+		if user.Role == "admin" {
+			var activeAdmins int64
+			if err := d.DB.WithContext(ctx).Model(&models.User{}).
+				Where("role = ? AND active = ?", "admin", true).Count(&activeAdmins).Error; err != nil {
+				return deactivated, err
+			}
+			if activeAdmins <= 1 {
+				logger.Warn("Último admin ativo não será desativado por inatividade", "user_id", user.ID, "username", user.Username)
+				continue
+			}
+		}
+
+		if d.Email != nil && (d.Notifications == nil || d.Notifications.ShouldSend(user.ID, email.TypeAccountDeactivated)) {
+			if err := d.Email.SendAccountDeactivatedEmail(user.Email, user.Username, user.DisplayName); err != nil {
+				// The notice is best effort; deactivation still proceeds
+				logger.Warn("Falha ao enviar aviso de desativação", "error", err, "user_id", user.ID)
+			}
+		}
+
+		if err := d.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(user).Update("active", false).Error; err != nil {
+				return err
+			}
+			return tx.Where("user_id = ?", user.ID).Delete(&models.Session{}).Error
+		}); err != nil {
+			logger.Error("Erro ao desativar conta inativa", "error", err, "user_id", user.ID)
+			return deactivated, err
+		}
+
+		deactivated++
+		logger.Info("Conta desativada por inatividade",
+			"user_id", user.ID, "username", user.Username, "last_login", user.LastLogin)
+	}
+
+	return deactivated, nil
+}
@@ -0,0 +1,114 @@
+// Package jobs tests
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/email"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupJobsTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Session{}, &models.LoginAttempt{}, &models.AuditLog{},
+		&models.WebAuthnCredential{}, &models.BackupCode{}, &models.PasswordHistory{}, &models.TrustedDevice{}))
+	return db
+}
+
+func seedUser(t *testing.T, db *gorm.DB, username, role string, createdAt, lastLogin time.Time) *models.User {
+	t.Helper()
+	user := &models.User{
+		Username:     username,
+		Email:        username + "@example.com",
+		DisplayName:  username,
+		PasswordHash: "hash",
+		Active:       true,
+		Role:         role,
+		LastLogin:    lastLogin,
+	}
+	user.CreatedAt = createdAt
+	require.NoError(t, db.Create(user).Error)
+	return user
+}
+
+func isActive(t *testing.T, db *gorm.DB, username string) bool {
+	t.Helper()
+	var user models.User
+	require.NoError(t, db.Where("username = ?", username).First(&user).Error)
+	return user.Active
+}
+
+func TestInactivityDeactivator_Run(t *testing.T) {
+	db := setupJobsTestDB(t)
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	threshold := 90 * 24 * time.Hour
+	longAgo := now.Add(-365 * 24 * time.Hour)
+
+	seedUser(t, db, "recent", "user", longAgo, now.Add(-24*time.Hour))
+	seedUser(t, db, "borderline", "user", longAgo, now.Add(-threshold+time.Hour))
+	stale := seedUser(t, db, "stale", "user", longAgo, now.Add(-threshold-time.Hour))
+	seedUser(t, db, "never-old", "user", longAgo, time.Time{})
+	seedUser(t, db, "never-new", "user", now.Add(-24*time.Hour), time.Time{})
+	seedUser(t, db, "admin", "admin", longAgo, longAgo)
+	seedUser(t, db, "stale-admin", "admin", longAgo, longAgo)
+	require.NoError(t, db.Create(&models.Session{ID: "stale-session", UserID: stale.ID, ExpiresAt: now.Add(time.Hour)}).Error)
+
+	mockEmail := email.NewMockEmailService()
+	d := &InactivityDeactivator{
+		DB:                 db,
+		Threshold:          threshold,
+		ProtectedUsernames: []string{"admin"},
+		Email:              mockEmail,
+		Now:                func() time.Time { return now },
+	}
+
+	count, err := d.Run(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	assert.True(t, isActive(t, db, "recent"))
+	assert.True(t, isActive(t, db, "borderline"))
+	assert.False(t, isActive(t, db, "stale"))
+	assert.False(t, isActive(t, db, "never-old"))
+	assert.True(t, isActive(t, db, "never-new"))
+	assert.True(t, isActive(t, db, "admin"), "seeded admin must never be deactivated")
+	assert.False(t, isActive(t, db, "stale-admin"), "the seeded admin remains active, so other stale admins can go")
+
+	var sessions int64
+	db.Model(&models.Session{}).Where("user_id = ?", stale.ID).Count(&sessions)
+	assert.Zero(t, sessions, "sessions of deactivated users should be removed")
+
+	sent := mockEmail.GetSentEmails()
+	require.Len(t, sent, 3)
+	for _, e := range sent {
+		assert.Equal(t, email.MockKindAccountDeactivated, e.Kind)
+	}
+}
+
+func TestInactivityDeactivator_KeepsLastAdmin(t *testing.T) {
+	db := setupJobsTestDB(t)
+	now := time.Now()
+	longAgo := now.Add(-365 * 24 * time.Hour)
+
+	seedUser(t, db, "only-admin", "admin", longAgo, longAgo)
+	seedUser(t, db, "other-admin", "admin", longAgo, longAgo)
+
+	d := &InactivityDeactivator{DB: db, Threshold: 30 * 24 * time.Hour}
+
+	count, err := d.Run(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, count, "one of two stale admins may go, the last one stays")
+
+	var activeAdmins int64
+	db.Model(&models.User{}).Where("role = ? AND active = ?", "admin", true).Count(&activeAdmins)
+	assert.Equal(t, int64(1), activeAdmins)
+}
@@ -0,0 +1,116 @@
+// Package jobs runs periodic background tasks (session cleanup, account inactivity checks, record retention).
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+	"github.com/lucas-varjao/gohtmx/internal/service"
+
+	"gorm.io/gorm"
+)
+
+// DefaultInterval is used when a job is scheduled with a zero interval.
+const DefaultInterval = time.Hour
+
+// Func is a unit of periodic work. Returning an error only logs it; the job keeps running.
+type Func func(ctx context.Context) error
+
+// Every runs fn immediately and then on every interval until ctx is cancelled.
+// It returns right away; the job runs in its own goroutine.
+func Every(ctx context.Context, name string, interval time.Duration, fn Func) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := fn(ctx); err != nil {
+				logger.Error("Erro ao executar tarefa periódica", "job", name, "error", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				logger.Debug("Tarefa periódica encerrada", "job", name)
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// RetentionJanitor deletes records past their retention window, each kind independently: sessions
+// SessionsAfterExpiry after they expire, login attempts older than LoginAttempts and audit log entries
+// older than AuditLogs. A zero LoginAttempts or AuditLogs keeps those records forever. Accounts whose
+// owner asked to delete them (service.DeletionService) are deleted for good once their date passes.
+type RetentionJanitor struct {
+	DB                  *gorm.DB
+	SessionsAfterExpiry time.Duration
+	LoginAttempts       time.Duration
+	AuditLogs           time.Duration
+	// Now defaults to time.Now; overridable in tests
+	Now func() time.Time
+}
+
+// Job adapts Run to the Func signature used by Every.
+func (j *RetentionJanitor) Job() Func {
+	return func(ctx context.Context) error {
+		_, err := j.Run(ctx)
+		return err
+	}
+}
+
+// Run deletes the expired records and returns how many were deleted per kind ("sessions",
+// "login_attempts", "audit_logs", "accounts"). A failing kind doesn't stop the others; the errors are joined.
+func (j *RetentionJanitor) Run(ctx context.Context) (map[string]int64, error) {
+	now := time.Now
+	if j.Now != nil {
+		now = j.Now
+	}
+	deleted := map[string]int64{}
+	var errs []error
+	purge := func(kind string, model any, column string, cutoff time.Time) {
+		result := j.DB.WithContext(ctx).Where(column+" < ?", cutoff).Delete(model)
+		if result.Error != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", kind, result.Error))
+			return
+		}
+		deleted[kind] = result.RowsAffected
+	}
+
+	purge("sessions", &models.Session{}, "expires_at", now().Add(-j.SessionsAfterExpiry))
+	if j.LoginAttempts > 0 {
+		purge("login_attempts", &models.LoginAttempt{}, "created_at", now().Add(-j.LoginAttempts))
+	}
+	if j.AuditLogs > 0 {
+		purge("audit_logs", &models.AuditLog{}, "created_at", now().Add(-j.AuditLogs))
+	}
+	// Hard delete, with the rows the accounts own; a reactivated account is spared even if its date was left behind
+	var ids []uint
+	err := j.DB.WithContext(ctx).Unscoped().Model(&models.User{}).
+		Where("active = ? AND deletion_scheduled_at < ?", false, now()).
+		Pluck("id", &ids).Error
+	if err == nil && len(ids) > 0 {
+		err = service.WithTransaction(j.DB.WithContext(ctx), func(tx *gorm.DB) error {
+			return service.HardDeleteUsers(tx, ids...)
+		})
+	}
+	if err != nil {
+		errs = append(errs, fmt.Errorf("accounts: %w", err))
+	} else {
+		deleted["accounts"] = int64(len(ids))
+	}
+
+	if deleted["sessions"]+deleted["login_attempts"]+deleted["audit_logs"]+deleted["accounts"] > 0 {
+		logger.Info("Registros antigos removidos", "sessions", deleted["sessions"],
+			"login_attempts", deleted["login_attempts"], "audit_logs", deleted["audit_logs"], "accounts", deleted["accounts"])
+	}
+	return deleted, errors.Join(errs...)
+}
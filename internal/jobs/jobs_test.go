@@ -0,0 +1,84 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetentionJanitor(t *testing.T) {
+	db := setupJobsTestDB(t)
+	now := time.Now()
+	day := 24 * time.Hour
+
+	require.NoError(t, db.Create(&models.Session{ID: "expired-long-ago", UserID: 1, ExpiresAt: now.Add(-8 * day)}).Error)
+	require.NoError(t, db.Create(&models.Session{ID: "expired-recently", UserID: 1, ExpiresAt: now.Add(-day)}).Error)
+	require.NoError(t, db.Create(&models.Session{ID: "active", UserID: 1, ExpiresAt: now.Add(day)}).Error)
+	require.NoError(t, db.Create(&models.LoginAttempt{Identifier: "old", CreatedAt: now.Add(-31 * day)}).Error)
+	require.NoError(t, db.Create(&models.LoginAttempt{Identifier: "recent", CreatedAt: now.Add(-day)}).Error)
+	require.NoError(t, db.Create(&models.AuditLog{Action: "old", CreatedAt: now.Add(-400 * day)}).Error)
+	require.NoError(t, db.Create(&models.AuditLog{Action: "recent", CreatedAt: now.Add(-10 * day)}).Error)
+	due := seedUser(t, db, "due", "user", now.Add(-60*day), now.Add(-40*day))
+	waiting := seedUser(t, db, "waiting", "user", now.Add(-60*day), now.Add(-day))
+	cancelled := seedUser(t, db, "cancelled", "user", now.Add(-60*day), now.Add(-day))
+	require.NoError(t, db.Model(due).Updates(map[string]any{"active": false, "deletion_scheduled_at": now.Add(-day)}).Error)
+	require.NoError(t, db.Model(waiting).Updates(map[string]any{"active": false, "deletion_scheduled_at": now.Add(day)}).Error)
+	require.NoError(t, db.Model(cancelled).Update("deletion_scheduled_at", now.Add(-day)).Error)
+	for _, user := range []*models.User{due, waiting} {
+		require.NoError(t, db.Create(&models.WebAuthnCredential{UserID: user.ID, CredentialID: user.Username, Data: "{}"}).Error)
+		require.NoError(t, db.Create(&models.BackupCode{UserID: user.ID, CodeHash: user.Username}).Error)
+		require.NoError(t, db.Create(&models.PasswordHistory{UserID: user.ID, PasswordHash: "old"}).Error)
+		require.NoError(t, db.Create(&models.TrustedDevice{UserID: user.ID, TokenHash: user.Username, ExpiresAt: now.Add(time.Hour)}).Error)
+	}
+
+	janitor := &RetentionJanitor{
+		DB:                  db,
+		SessionsAfterExpiry: 7 * day,
+		LoginAttempts:       30 * day,
+		AuditLogs:           365 * day,
+		Now:                 func() time.Time { return now },
+	}
+	deleted, err := janitor.Run(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"sessions": 1, "login_attempts": 1, "audit_logs": 1, "accounts": 1}, deleted)
+
+	var sessions []models.Session
+	require.NoError(t, db.Order("id").Find(&sessions).Error)
+	require.Len(t, sessions, 2)
+	assert.Equal(t, "active", sessions[0].ID)
+	assert.Equal(t, "expired-recently", sessions[1].ID, "expired sessions stay for the retention window")
+	var attempts []models.LoginAttempt
+	require.NoError(t, db.Find(&attempts).Error)
+	require.Len(t, attempts, 1)
+	assert.Equal(t, "recent", attempts[0].Identifier)
+	var audits []models.AuditLog
+	require.NoError(t, db.Find(&audits).Error)
+	require.Len(t, audits, 1)
+	assert.Equal(t, "recent", audits[0].Action)
+	var usernames []string
+	require.NoError(t, db.Unscoped().Model(&models.User{}).Order("username").Pluck("username", &usernames).Error)
+	assert.Equal(t, []string{"cancelled", "waiting"}, usernames, "only inactive accounts past their date are deleted, for good")
+	for _, model := range []any{&models.WebAuthnCredential{}, &models.BackupCode{}, &models.PasswordHistory{}, &models.TrustedDevice{}} {
+		var owners []uint
+		require.NoError(t, db.Model(model).Pluck("user_id", &owners).Error)
+		assert.Equal(t, []uint{waiting.ID}, owners, "the deleted account's rows go with it")
+	}
+
+	t.Run("Zero retention", func(t *testing.T) {
+		janitor := &RetentionJanitor{DB: db, Now: func() time.Time { return now }}
+		deleted, err := janitor.Run(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int64{"sessions": 1, "accounts": 0}, deleted, "sessions go at expiry; attempts and audit logs are kept")
+
+		var attempts, audits int64
+		db.Model(&models.LoginAttempt{}).Count(&attempts)
+		db.Model(&models.AuditLog{}).Count(&audits)
+		assert.Equal(t, int64(1), attempts)
+		assert.Equal(t, int64(1), audits)
+	})
+}
@@ -0,0 +1,51 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordExpirer_Run(t *testing.T) {
+	db := setupJobsTestDB(t)
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	maxAge := 90 * 24 * time.Hour
+	longAgo := now.Add(-365 * 24 * time.Hour)
+
+	withPasswordAge := func(username string, changedAt *time.Time) {
+		user := seedUser(t, db, username, "user", longAgo, time.Time{})
+		require.NoError(t, db.Model(user).Update("password_changed_at", changedAt).Error)
+	}
+	at := func(d time.Duration) *time.Time { ts := now.Add(-d); return &ts }
+	withPasswordAge("fresh", at(24*time.Hour))
+	withPasswordAge("borderline", at(maxAge-time.Hour))
+	withPasswordAge("expired", at(maxAge+time.Hour))
+	withPasswordAge("untracked-old", nil)
+	seedUser(t, db, "untracked-new", "user", now.Add(-time.Hour), time.Time{})
+
+	e := &PasswordExpirer{DB: db, MaxAge: maxAge, Now: func() time.Time { return now }}
+	count, err := e.Run(context.Background())
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, count)
+
+	mustChange := func(username string) bool {
+		t.Helper()
+		var user models.User
+		require.NoError(t, db.Where("username = ?", username).First(&user).Error)
+		return user.MustChangePassword
+	}
+	assert.False(t, mustChange("fresh"))
+	assert.False(t, mustChange("borderline"))
+	assert.True(t, mustChange("expired"), "an over-age password must be changed")
+	assert.True(t, mustChange("untracked-old"), "without a change date the age counts from the account creation")
+	assert.False(t, mustChange("untracked-new"))
+
+	count, err = e.Run(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, count, "users already flagged are left alone")
+}
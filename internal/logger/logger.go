@@ -2,16 +2,25 @@
 package logger
 
 import (
+	"context"
+	"io"
 	"log/slog"
 	"os"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 var defaultLogger *slog.Logger
 
-// Init initializes the logger with the specified level and format.
+// Init initializes the logger with the specified level and format, writing to stdout.
 // level: "debug", "info", "warn", "error"
 // format: "json" or "text"
 func Init(level, format string) {
+	InitWithWriter(os.Stdout, level, format)
+}
+
+// InitWithWriter is Init writing to w instead of stdout (e.g. a buffer, for tests that check the logs).
+func InitWithWriter(w io.Writer, level, format string) {
 	var logLevel slog.Level
 	switch level {
 	case "debug":
@@ -32,12 +41,12 @@ func Init(level, format string) {
 
 	var handler slog.Handler
 	if format == "json" {
-		handler = slog.NewJSONHandler(os.Stdout, opts)
+		handler = slog.NewJSONHandler(w, opts)
 	} else {
-		handler = slog.NewTextHandler(os.Stdout, opts)
+		handler = slog.NewTextHandler(w, opts)
 	}
 
-	defaultLogger = slog.New(handler)
+	defaultLogger = slog.New(traceHandler{handler})
 	slog.SetDefault(defaultLogger)
 }
 
@@ -70,3 +79,43 @@ func Warn(msg string, args ...any) {
 func Debug(msg string, args ...any) {
 	Get().Debug(msg, args...)
 }
+
+// InfoContext logs an info message; lines logged inside a traced request carry trace_id and span_id.
+func InfoContext(ctx context.Context, msg string, args ...any) {
+	Get().InfoContext(ctx, msg, args...)
+}
+
+// ErrorContext logs an error message; lines logged inside a traced request carry trace_id and span_id.
+func ErrorContext(ctx context.Context, msg string, args ...any) {
+	Get().ErrorContext(ctx, msg, args...)
+}
+
+// WarnContext logs a warning message; lines logged inside a traced request carry trace_id and span_id.
+func WarnContext(ctx context.Context, msg string, args ...any) {
+	Get().WarnContext(ctx, msg, args...)
+}
+
+// DebugContext logs a debug message; lines logged inside a traced request carry trace_id and span_id.
+func DebugContext(ctx context.Context, msg string, args ...any) {
+	Get().DebugContext(ctx, msg, args...)
+}
+
+// traceHandler adds the OpenTelemetry trace and span IDs found in the record's context.
+type traceHandler struct {
+	slog.Handler
+}
+
+func (h traceHandler) Handle(ctx context.Context, r slog.Record) error {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h traceHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return traceHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h traceHandler) WithGroup(name string) slog.Handler {
+	return traceHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MsgAdminReadOnly answers admin mutations blocked by AdminReadOnlyMiddleware.
+const MsgAdminReadOnly = "o painel admin está em modo somente leitura; nenhuma alteração foi feita"
+
+// adminReadOnly is set at startup from config admin.read_only and may be flipped while serving.
+var adminReadOnly atomic.Bool
+
+// SetAdminReadOnly turns the admin read-only mode on or off. It takes effect on the next request.
+func SetAdminReadOnly(readOnly bool) {
+	adminReadOnly.Store(readOnly)
+}
+
+// AdminReadOnly reports whether the admin area is in read-only mode.
+func AdminReadOnly() bool {
+	return adminReadOnly.Load()
+}
+
+// AdminReadOnlyMiddleware rejects every request to the routes after it that isn't GET, HEAD or OPTIONS
+// while the admin read-only mode is on, before the handler can change anything. Use it after
+// AdminWebMiddleware or RoleMiddleware("admin").
+//
+// API clients get a 403 JSON error with "admin_read_only": true. Other requests call onBlocked(c)
+// (e.g. to render an error fragment for HTMX); if onBlocked is nil they get the JSON error too.
+func AdminReadOnlyMiddleware(onBlocked func(*gin.Context)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		if !adminReadOnly.Load() {
+			c.Next()
+			return
+		}
+
+		logger.InfoContext(c.Request.Context(), "Alteração no admin recusada: modo somente leitura", "method", c.Request.Method,
+			"path", c.Request.URL.Path, "user_id", c.GetString("userID"))
+		c.Abort()
+		if onBlocked != nil && !wantsJSON(c) {
+			onBlocked(c)
+			return
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": MsgAdminReadOnly, "admin_read_only": true})
+	}
+}
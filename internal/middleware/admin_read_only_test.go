@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminReadOnlyMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Cleanup(func() { SetAdminReadOnly(false) })
+
+	mutated := false
+	router := gin.New()
+	admin := router.Group("/admin", AdminReadOnlyMiddleware(func(c *gin.Context) { c.String(http.StatusOK, "blocked") }))
+	admin.GET("/users", func(c *gin.Context) { c.Status(http.StatusOK) })
+	admin.POST("/users/:id/role", func(c *gin.Context) {
+		mutated = true
+		c.Status(http.StatusOK)
+	})
+
+	serve := func(method, path, accept string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Off lets mutations through", func(t *testing.T) {
+		SetAdminReadOnly(false)
+		mutated = false
+		assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/admin/users/1/role", "").Code)
+		assert.True(t, mutated)
+	})
+
+	t.Run("On keeps reads and blocks mutations", func(t *testing.T) {
+		SetAdminReadOnly(true)
+		mutated = false
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/admin/users", "").Code)
+
+		w := serve(http.MethodPost, "/admin/users/1/role", "")
+		assert.Equal(t, "blocked", w.Body.String())
+
+		w = serve(http.MethodPost, "/admin/users/1/role", "application/json")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), `"admin_read_only":true`)
+		assert.False(t, mutated)
+	})
+}
@@ -3,31 +3,23 @@ package middleware
 
 import (
 	"net/http"
+	"net/url"
 
 	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/basepath"
+	"github.com/lucas-varjao/gohtmx/internal/validation"
 
 	"github.com/gin-gonic/gin"
 )
 
 // AdminWebMiddleware validates session and admin role for HTML admin routes.
-// If there is no valid session, it redirects to /login.
+// If there is no valid session, it redirects to /login, carrying the requested page as ?next=.
 // If the user is not an admin, it calls onForbidden(c) and aborts (e.g. to render 403 HTML).
 // If onForbidden is nil, it responds with 403 status only.
 func AdminWebMiddleware(authManager *auth.AuthManager, onForbidden func(*gin.Context)) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		sessionID := ExtractSessionID(c)
-		if sessionID == "" {
-			c.Redirect(http.StatusFound, "/login")
-			c.Abort()
-			return
-		}
-
-		_, user, err := authManager.ValidateSession(sessionID)
-		if err != nil || user == nil {
-			// Clear invalid session cookie
-			ClearSessionCookie(c)
-			c.Redirect(http.StatusFound, "/login")
-			c.Abort()
+		user, ok := webSession(c, authManager)
+		if !ok {
 			return
 		}
 
@@ -41,9 +33,58 @@ func AdminWebMiddleware(authManager *auth.AuthManager, onForbidden func(*gin.Con
 			return
 		}
 
-		c.Set("user", user)
-		c.Set("userID", user.ID)
-		c.Set("role", user.Role)
 		c.Next()
 	}
 }
+
+// WebAuthMiddleware validates the session for HTML pages of any logged-in user (e.g. /profile).
+// Without a valid session it redirects to /login, carrying the requested page as ?next=.
+func WebAuthMiddleware(authManager *auth.AuthManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := webSession(c, authManager); ok {
+			c.Next()
+		}
+	}
+}
+
+// webSession validates the request's session and stores the user in the context like AuthMiddleware.
+// Without a valid session it redirects to the login, aborts and returns false.
+func webSession(c *gin.Context, authManager *auth.AuthManager) (*auth.UserData, bool) {
+	sessionID := ExtractSessionID(c)
+	if sessionID == "" {
+		c.Redirect(http.StatusFound, loginRedirectURL(c))
+		c.Abort()
+		return nil, false
+	}
+
+	session, user, err := authManager.WithContext(c.Request.Context()).ValidateSession(sessionID)
+	if err != nil || user == nil {
+		// Clear invalid session cookie
+		ClearSessionCookie(c)
+		c.Redirect(http.StatusFound, loginRedirectURL(c))
+		c.Abort()
+		return nil, false
+	}
+
+	c.Set("user", user)
+	c.Set("userID", user.ID)
+	c.Set("role", user.Role)
+	c.Set("session", session)
+	c.Set("sessionID", sessionID)
+	SetSessionExpiresIn(c, session)
+	return user, true
+}
+
+// loginRedirectURL builds the login URL, preserving the original target as ?next= so the user
+// returns there after signing in. Only GET requests to local paths are captured; following a
+// redirect back to a form submission would not make sense.
+func loginRedirectURL(c *gin.Context) string {
+	if c.Request.Method != http.MethodGet {
+		return basepath.URL("/login")
+	}
+	target := c.Request.URL.RequestURI()
+	if validation.ValidateRedirectPath(target) != nil {
+		return basepath.URL("/login")
+	}
+	return basepath.URL("/login?next=" + url.QueryEscape(target))
+}
@@ -0,0 +1,48 @@
+// Package middleware tests
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminWebMiddleware_RedirectsToLogin(t *testing.T) {
+	authManager, _ := createTestAuthManager()
+
+	router := gin.New()
+	admin := router.Group("/admin", AdminWebMiddleware(authManager, nil))
+	admin.GET("/users/:id/edit", func(c *gin.Context) { c.Status(http.StatusOK) })
+	admin.POST("/users/:id/delete", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	t.Run("Preserves target without session", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/admin/users/42/edit?tab=roles", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, "/login?next=%2Fadmin%2Fusers%2F42%2Fedit%3Ftab%3Droles", w.Header().Get("Location"))
+	})
+
+	t.Run("Preserves target with invalid session", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/admin/users/42/edit", nil)
+		req.Header.Set("Authorization", "Bearer invalid-session")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, "/login?next=%2Fadmin%2Fusers%2F42%2Fedit", w.Header().Get("Location"))
+	})
+
+	t.Run("Does not capture non-GET requests", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, "/admin/users/42/delete", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, "/login", w.Header().Get("Location"))
+	})
+}
@@ -4,10 +4,16 @@ package middleware
 import (
 	"errors"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/basepath"
 	"github.com/lucas-varjao/gohtmx/internal/logger"
+	"github.com/lucas-varjao/gohtmx/internal/validation"
 
 	"github.com/gin-gonic/gin"
 )
@@ -17,6 +23,9 @@ const (
 	SessionCookieName = "session_id"
 	// SessionHeaderName is the name of the session header (for API clients)
 	SessionHeaderName = "X-Session-ID"
+	// SessionExpiresInHeader carries the seconds left before the session expires, so the browser
+	// can warn about an idle logout and offer to extend the session (POST /api/session/extend)
+	SessionExpiresInHeader = "X-Session-Expires-In"
 )
 
 // AuthMiddleware creates a Gin middleware for session-based authentication.
@@ -31,34 +40,33 @@ func AuthMiddleware(authManager *auth.AuthManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		sessionID := extractSessionID(c)
 		if sessionID == "" {
-			logger.Debug("Requisição sem sessão", "path", c.Request.URL.Path, "ip", c.ClientIP())
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "autorização necessária"})
+			logger.DebugContext(c.Request.Context(), "Requisição sem sessão", "path", c.Request.URL.Path, "ip", c.ClientIP())
+			abortUnauthorized(c, "autorização necessária")
 
 			return
 		}
 
-		session, user, err := authManager.ValidateSession(sessionID)
+		session, user, err := validateSession(c, authManager, sessionID)
 		if err != nil {
 			// Clear invalid session cookie (for web requests)
 			ClearSessionCookie(c)
 
-			status := http.StatusUnauthorized
 			var message string
 			switch {
 			case errors.Is(err, auth.ErrSessionExpired):
 				message = "sessão expirada"
-				logger.Debug("Sessão expirada", "session_id", sessionID, "ip", c.ClientIP())
+				logger.DebugContext(c.Request.Context(), "Sessão expirada", "session_id", sessionID, "ip", c.ClientIP())
 			case errors.Is(err, auth.ErrSessionNotFound):
 				message = "sessão não encontrada"
-				logger.Warn("Sessão não encontrada", "session_id", sessionID, "ip", c.ClientIP())
+				logger.WarnContext(c.Request.Context(), "Sessão não encontrada", "session_id", sessionID, "ip", c.ClientIP())
 			case errors.Is(err, auth.ErrUserNotActive):
 				message = "usuário inativo"
-				logger.Warn("Tentativa de acesso com usuário inativo", "session_id", sessionID, "ip", c.ClientIP())
+				logger.WarnContext(c.Request.Context(), "Tentativa de acesso com usuário inativo", "session_id", sessionID, "ip", c.ClientIP())
 			default:
 				message = "sessão inválida"
-				logger.Error("Erro ao validar sessão", "error", err, "session_id", sessionID, "ip", c.ClientIP())
+				logger.ErrorContext(c.Request.Context(), "Erro ao validar sessão", "error", err, "session_id", sessionID, "ip", c.ClientIP())
 			}
-			c.AbortWithStatusJSON(status, gin.H{"error": message})
+			abortUnauthorized(c, message)
 
 			return
 		}
@@ -69,6 +77,7 @@ func AuthMiddleware(authManager *auth.AuthManager) gin.HandlerFunc {
 		c.Set("user", user)
 		c.Set("session", session)
 		c.Set("sessionID", sessionID)
+		SetSessionExpiresIn(c, session)
 
 		// If session was refreshed, update the cookie
 		if session.Fresh && c.Request.Method != http.MethodOptions {
@@ -79,6 +88,56 @@ func AuthMiddleware(authManager *auth.AuthManager) gin.HandlerFunc {
 	}
 }
 
+// sessionCheckKey holds the sessionCheck of the request once validateSession has run.
+const sessionCheckKey = "middleware.sessionCheck"
+
+// sessionCheck is the outcome of validating the request's session.
+type sessionCheck struct {
+	sessionID string
+	session   *auth.Session
+	user      *auth.UserData
+	err       error
+}
+
+// validateSession validates sessionID once per request: a middleware that needs the auth state before
+// AuthMiddleware (e.g. TieredRateLimitMiddleware) leaves the outcome in the context for the next. Validating
+// twice would cost another query and lose session.Fresh, so the refreshed cookie would never be sent.
+func validateSession(c *gin.Context, authManager *auth.AuthManager, sessionID string) (*auth.Session, *auth.UserData, error) {
+	if v, ok := c.Get(sessionCheckKey); ok {
+		if check, ok := v.(sessionCheck); ok && check.sessionID == sessionID {
+			return check.session, check.user, check.err
+		}
+	}
+	session, user, err := authManager.WithContext(c.Request.Context()).ValidateSession(sessionID)
+	c.Set(sessionCheckKey, sessionCheck{sessionID: sessionID, session: session, user: user, err: err})
+	return session, user, err
+}
+
+// abortUnauthorized aborts with 401. For HTMX requests it also sets HX-Redirect so the browser is
+// sent to the login page (returning to the current page afterwards) instead of failing silently;
+// HTMX follows HX-Redirect regardless of the status code. API clients only see the JSON error.
+func abortUnauthorized(c *gin.Context, message string) {
+	if c.GetHeader("HX-Request") != "" {
+		c.Header("HX-Redirect", htmxLoginURL(c))
+	}
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": message})
+}
+
+// htmxLoginURL builds the login URL for an HTMX request, using HX-Current-URL (the page the user
+// is on, not the fragment endpoint) as ?next= when it is a local path.
+func htmxLoginURL(c *gin.Context) string {
+	current, err := url.Parse(c.GetHeader("HX-Current-URL"))
+	if err != nil || current.Path == "" {
+		return basepath.URL("/login")
+	}
+	// The browser URL carries the base path; next= holds app paths, like the rest of the routing
+	target := basepath.Strip(current.RequestURI())
+	if validation.ValidateRedirectPath(target) != nil {
+		return basepath.URL("/login")
+	}
+	return basepath.URL("/login?next=" + url.QueryEscape(target))
+}
+
 // RoleMiddleware creates a middleware to verify user roles.
 //
 // It expects the user's role to be set in the context by AuthMiddleware.
@@ -135,32 +194,83 @@ func extractSessionID(c *gin.Context) string {
 	return ""
 }
 
+// SetSessionCookie sets the session cookie for a newly issued session (e.g. when an admin starts or
+// stops impersonating a user from an HTML page).
+func SetSessionCookie(c *gin.Context, sessionID string) {
+	setSessionCookie(c, sessionID, nil)
+}
+
+// SetSessionExpiresIn writes SessionExpiresInHeader for session (whole seconds, never negative).
+func SetSessionExpiresIn(c *gin.Context, session *auth.Session) {
+	seconds := max(int(time.Until(session.ExpiresAt).Seconds()), 0)
+	c.Header(SessionExpiresInHeader, strconv.Itoa(seconds))
+}
+
+// ForbidImpersonationMiddleware rejects the request with 403 when the session is an admin impersonating
+// a user. Use it after AuthMiddleware on account-sensitive routes (password, email, data export).
+func ForbidImpersonationMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if session, ok := c.Get("session"); ok {
+			if s, ok := session.(*auth.Session); ok && s.ImpersonatedBy != "" {
+				logger.WarnContext(c.Request.Context(), "Ação bloqueada durante personificação", "path", c.Request.URL.Path,
+					"user_id", c.GetString("userID"), "admin_id", s.ImpersonatedBy)
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "ação não permitida durante personificação"})
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
 // setSessionCookie sets the session cookie in the response
 func setSessionCookie(c *gin.Context, sessionID string, expiresAt any) {
 	_ = expiresAt // unused but required by caller signature
 	// Calculate max age in seconds
 	maxAge := 30 * 24 * 60 * 60 // 30 days default
 
-	c.SetCookie(
-		SessionCookieName,
-		sessionID,
-		maxAge,
-		"/",
-		"",   // domain - empty means current domain
-		true, // secure - only send over HTTPS
-		true, // httpOnly - not accessible via JavaScript
-	)
+	writeCookie(c, SessionCookieName, sessionID, maxAge)
 }
 
 // ClearSessionCookie removes the session cookie
 func ClearSessionCookie(c *gin.Context) {
-	c.SetCookie(
-		SessionCookieName,
-		"",
-		-1, // negative max age deletes the cookie
-		"/",
-		"",
-		true,
-		true,
-	)
+	writeCookie(c, SessionCookieName, "", -1) // negative max age deletes the cookie
+}
+
+// sessionCookiePartitioned is set once at startup by SetSessionCookiePartitioned.
+var sessionCookiePartitioned atomic.Bool
+
+// SetSessionCookiePartitioned makes the session cookie Partitioned (CHIPS) with SameSite=None, so the app
+// keeps its session when embedded in a cross-site iframe of browsers that block third-party cookies.
+// The cookie is then tied to the embedding site. Call it during setup, before serving requests.
+func SetSessionCookiePartitioned(partitioned bool) {
+	sessionCookiePartitioned.Store(partitioned)
+}
+
+// writeCookie writes the session cookie, or another one sent along with it, with the session cookie's flags
+// (Secure, HttpOnly, scoped to the base path, partitioned when configured). A partitioned cookie can only
+// be replaced or deleted by another partitioned one, so clearing goes through here too.
+func writeCookie(c *gin.Context, name, value string, maxAge int) {
+	if !sessionCookiePartitioned.Load() {
+		c.SetCookie(
+			name,
+			value,
+			maxAge,
+			basepath.CookiePath(),
+			"",   // domain - empty means current domain
+			true, // secure - only send over HTTPS
+			true, // httpOnly - not accessible via JavaScript
+		)
+		return
+	}
+	// gin's SetCookie has no Partitioned option
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:        name,
+		Value:       url.QueryEscape(value),
+		MaxAge:      maxAge,
+		Path:        basepath.CookiePath(),
+		Secure:      true,
+		HttpOnly:    true,
+		SameSite:    http.SameSiteNoneMode,
+		Partitioned: true,
+	})
 }
@@ -142,6 +142,83 @@ func TestAuthMiddleware(t *testing.T) {
 
 		assert.Equal(t, http.StatusOK, w.Code)
 	})
+
+	t.Run("Expired Session via HTMX", func(t *testing.T) {
+		authManager, db := createTestAuthManager()
+
+		db.Create(&models.Session{
+			ID:        "expired-session-id",
+			UserID:    1,
+			ExpiresAt: time.Now().Add(-time.Hour),
+			CreatedAt: time.Now().Add(-2 * time.Hour),
+		})
+		db.Create(&models.User{
+			Username:     "expireduser",
+			Email:        "expired@example.com",
+			PasswordHash: "hash",
+			Active:       true,
+		})
+
+		r := gin.New()
+		r.Use(AuthMiddleware(authManager))
+		r.GET("/test", func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.AddCookie(&http.Cookie{Name: SessionCookieName, Value: "expired-session-id"})
+		req.Header.Set("HX-Request", "true")
+		req.Header.Set("HX-Current-URL", "http://localhost:8080/admin/users?page=2")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "sessão expirada")
+		assert.Equal(t, "/login?next=%2Fadmin%2Fusers%3Fpage%3D2", w.Header().Get("HX-Redirect"))
+	})
+
+	t.Run("Expired Session via API keeps JSON only", func(t *testing.T) {
+		authManager, db := createTestAuthManager()
+
+		db.Create(&models.Session{
+			ID:        "expired-api-session",
+			UserID:    1,
+			ExpiresAt: time.Now().Add(-time.Hour),
+			CreatedAt: time.Now().Add(-2 * time.Hour),
+		})
+
+		r := gin.New()
+		r.Use(AuthMiddleware(authManager))
+		r.GET("/test", func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("Authorization", "Bearer expired-api-session")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Empty(t, w.Header().Get("HX-Redirect"))
+	})
+
+	t.Run("Missing Session via HTMX without current URL", func(t *testing.T) {
+		authManager, _ := createTestAuthManager()
+
+		r := gin.New()
+		r.Use(AuthMiddleware(authManager))
+		r.GET("/test", func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("HX-Request", "true")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, "/login", w.Header().Get("HX-Redirect"))
+	})
 }
 
 // Test cases for RoleMiddleware
@@ -235,3 +312,38 @@ func TestRoleMiddleware(t *testing.T) {
 		assert.Contains(t, w.Body.String(), "acesso negado")
 	})
 }
+
+func TestSessionCookie_Partitioned(t *testing.T) {
+	setCookie := func(write func(c *gin.Context)) string {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		write(c)
+		return w.Header().Get("Set-Cookie")
+	}
+	issue := func(c *gin.Context) { SetSessionCookie(c, "abc123") }
+
+	t.Run("Default cookie is unchanged", func(t *testing.T) {
+		header := setCookie(issue)
+		assert.Contains(t, header, "session_id=abc123")
+		assert.Contains(t, header, "HttpOnly")
+		assert.Contains(t, header, "Secure")
+		assert.NotContains(t, header, "Partitioned")
+		assert.NotContains(t, header, "SameSite")
+	})
+
+	t.Run("Partitioned when enabled", func(t *testing.T) {
+		SetSessionCookiePartitioned(true)
+		t.Cleanup(func() { SetSessionCookiePartitioned(false) })
+
+		header := setCookie(issue)
+		assert.Contains(t, header, "session_id=abc123")
+		assert.Contains(t, header, "Partitioned")
+		assert.Contains(t, header, "SameSite=None")
+		assert.Contains(t, header, "Secure")
+		assert.Contains(t, header, "HttpOnly")
+
+		cleared := setCookie(ClearSessionCookie)
+		assert.Contains(t, cleared, "Max-Age=0")
+		assert.Contains(t, cleared, "Partitioned", "only a partitioned cookie can delete a partitioned one")
+	})
+}
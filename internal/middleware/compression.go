@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultCompressionMinSize is the smallest body CompressionMiddleware compresses when given minSize <= 0.
+// Below about 1 KiB the gzip header and the CPU cost outweigh the bytes saved, which covers most HTMX
+// fragments (a table row, an alert).
+const DefaultCompressionMinSize = 1024
+
+// CompressedContentTypes are media types whose bodies are already compressed, so gzip only costs CPU.
+// A type ending in "/" matches the whole family (e.g. "video/").
+var CompressedContentTypes = []string{
+	"image/png", "image/jpeg", "image/gif", "image/webp", "image/avif",
+	"video/", "audio/",
+	"font/woff", "font/woff2",
+	"application/zip", "application/gzip", "application/x-gzip", "application/zstd", "application/pdf",
+	// Streams must reach the client as they are flushed, not when a gzip block fills up
+	"text/event-stream",
+}
+
+var gzipWriters = sync.Pool{New: func() any { return gzip.NewWriter(nil) }}
+
+// CompressionMiddleware gzips responses for clients that accept it, once the body reaches minSize bytes
+// (DefaultCompressionMinSize when minSize <= 0); smaller bodies go out as they are. Bodies whose
+// Content-Type is in CompressedContentTypes or skipTypes (same matching rules), already encoded, or
+// partial (206) are never compressed. Register it before the recovery middleware, so error pages go
+// through it too.
+func CompressionMiddleware(minSize int, skipTypes ...string) gin.HandlerFunc {
+	if minSize <= 0 {
+		minSize = DefaultCompressionMinSize
+	}
+	skip := append(append([]string{}, CompressedContentTypes...), skipTypes...)
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodHead || !acceptsGzip(c.GetHeader("Accept-Encoding")) {
+			c.Next()
+			return
+		}
+		original := c.Writer
+		w := &compressWriter{ResponseWriter: original, minSize: minSize, skip: skip}
+		c.Writer = w
+		defer func() {
+			w.finish()
+			c.Writer = original
+		}()
+		c.Next()
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip (or "*") with a non-zero q.
+func acceptsGzip(header string) bool {
+	for part := range strings.SplitSeq(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.TrimSpace(coding)
+		if !strings.EqualFold(coding, "gzip") && coding != "*" {
+			continue
+		}
+		name, value, _ := strings.Cut(strings.TrimSpace(params), "=")
+		if strings.TrimSpace(name) == "q" {
+			if q, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil && q == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// compressWriter holds the body back until it reaches minSize bytes, then picks plain or gzip output for
+// the rest of the response; finish sends what is left.
+type compressWriter struct {
+	gin.ResponseWriter
+	minSize int
+	skip    []string
+	buf     []byte
+	decided bool
+	gz      *gzip.Writer
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, data...)
+		if len(w.buf) < w.minSize {
+			return len(data), nil
+		}
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+	if w.gz != nil {
+		return w.gz.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// WriteHeaderNow keeps the status pending until the body decides the encoding.
+func (w *compressWriter) WriteHeaderNow() {
+	if w.decided {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+// Written reports held-back bytes as written, so gin doesn't render a default body over them.
+func (w *compressWriter) Written() bool {
+	return len(w.buf) > 0 || w.ResponseWriter.Written()
+}
+
+// Flush sends what is held back (compressed only if it already reaches minSize), then flushes.
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		_ = w.decide()
+	}
+	if w.gz != nil {
+		_ = w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// Unwrap lets http.ResponseController reach the connection (e.g. NoTimeoutMiddleware's deadlines).
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// decide sets the headers for plain or gzip output and writes the held-back bytes.
+func (w *compressWriter) decide() error {
+	w.decided = true
+	buf := w.buf
+	w.buf = nil
+	header := w.Header()
+	if header.Get("Content-Type") == "" && len(buf) > 0 {
+		// Sniff before compressing; net/http would otherwise sniff the gzip bytes
+		header.Set("Content-Type", http.DetectContentType(buf))
+	}
+	if len(buf) >= w.minSize && w.compressible(header) {
+		header.Set("Content-Encoding", "gzip")
+		header.Add("Vary", "Accept-Encoding")
+		header.Del("Content-Length")
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+		w.gz = gzipWriters.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+		_, err := w.gz.Write(buf)
+		return err
+	}
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+func (w *compressWriter) compressible(header http.Header) bool {
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" || w.Status() == http.StatusPartialContent {
+		return false
+	}
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	for _, skip := range w.skip {
+		skip = strings.ToLower(skip)
+		if mediaType == skip || (strings.HasSuffix(skip, "/") && strings.HasPrefix(mediaType, skip)) {
+			return false
+		}
+	}
+	return true
+}
+
+// finish sends the rest of the response: a body smaller than minSize goes out plain, a gzip stream is closed.
+func (w *compressWriter) finish() {
+	if !w.decided {
+		_ = w.decide()
+	}
+	if w.gz != nil {
+		_ = w.gz.Close()
+		w.gz.Reset(nil)
+		gzipWriters.Put(w.gz)
+		w.gz = nil
+	}
+}
@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressionMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	page := "<!DOCTYPE html><html><body>" + strings.Repeat("<p>Olá, mundo</p>", 200) + "</body></html>"
+	fragment := `<tr id="user-row-1"><td>alice</td></tr>`
+
+	router := gin.New()
+	router.Use(CompressionMiddleware(0, "application/x-custom"))
+	router.GET("/page", func(c *gin.Context) { c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(page)) })
+	router.GET("/fragment", func(c *gin.Context) { c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(fragment)) })
+	router.GET("/image", func(c *gin.Context) { c.Data(http.StatusOK, "image/png", []byte(page)) })
+	router.GET("/custom", func(c *gin.Context) { c.Data(http.StatusOK, "application/x-custom", []byte(page)) })
+	router.GET("/chunks", func(c *gin.Context) {
+		c.Header("ETag", `"v1"`)
+		for range 100 {
+			_, _ = c.Writer.WriteString("<p>Olá, mundo</p>")
+		}
+	})
+	router.GET("/missing", func(c *gin.Context) { c.AbortWithStatus(http.StatusNotFound) })
+
+	get := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	gunzip := func(t *testing.T, w *httptest.ResponseRecorder) string {
+		t.Helper()
+		reader, err := gzip.NewReader(w.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	t.Run("Large HTML page is compressed", func(t *testing.T) {
+		w := get("/page", "gzip, deflate, br")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+		assert.Contains(t, w.Header().Values("Vary"), "Accept-Encoding")
+		assert.Less(t, w.Body.Len(), len(page))
+		assert.Equal(t, page, gunzip(t, w))
+	})
+
+	t.Run("Small fragment is sent uncompressed", func(t *testing.T) {
+		w := get("/fragment", "gzip")
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+		assert.Equal(t, fragment, w.Body.String())
+	})
+
+	t.Run("Already compressed and skipped types are sent uncompressed", func(t *testing.T) {
+		for _, path := range []string{"/image", "/custom"} {
+			w := get(path, "gzip")
+			assert.Empty(t, w.Header().Get("Content-Encoding"), path)
+			assert.Equal(t, page, w.Body.String(), path)
+		}
+	})
+
+	t.Run("Clients that don't accept gzip get the plain body", func(t *testing.T) {
+		for _, acceptEncoding := range []string{"", "br", "gzip;q=0"} {
+			w := get("/page", acceptEncoding)
+			assert.Empty(t, w.Header().Get("Content-Encoding"), acceptEncoding)
+			assert.Equal(t, page, w.Body.String(), acceptEncoding)
+		}
+	})
+
+	t.Run("Body written in small pieces is compressed once it reaches the threshold", func(t *testing.T) {
+		w := get("/chunks", "gzip")
+		assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+		assert.Equal(t, `W/"v1"`, w.Header().Get("ETag"))
+		assert.True(t, strings.HasPrefix(w.Header().Get("Content-Type"), "text/html"), "type is sniffed from the plain body")
+		assert.Equal(t, strings.Repeat("<p>Olá, mundo</p>", 100), gunzip(t, w))
+	})
+
+	t.Run("Status without body is kept", func(t *testing.T) {
+		w := get("/missing", "gzip")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+		assert.Zero(t, w.Body.Len())
+	})
+}
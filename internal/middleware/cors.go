@@ -29,7 +29,7 @@ func CorsMiddleware() gin.HandlerFunc {
 		},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		ExposeHeaders:    []string{"Content-Length", "X-Total-Count", "X-Page", "X-Per-Page"}, // pagination of ?style=array lists
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	})
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// msgIPDenied is the response body for requests from a denied IP.
+const msgIPDenied = "acesso bloqueado para este endereço IP"
+
+// IPDenylist matches client IPs against blocked IPs and CIDR ranges. Static entries come from config and
+// never change; dynamic entries are replaced as a whole whenever admins edit the stored denylist.
+// Safe for concurrent use.
+type IPDenylist struct {
+	mu      sync.RWMutex
+	static  []netip.Prefix
+	dynamic []netip.Prefix
+}
+
+// NewIPDenylist creates a denylist with the given static entries (IPs or CIDR ranges).
+func NewIPDenylist(static []string) (*IPDenylist, error) {
+	prefixes, err := parsePrefixes(static)
+	if err != nil {
+		return nil, err
+	}
+	return &IPDenylist{static: prefixes}, nil
+}
+
+// SetDynamic replaces the runtime entries; on error the previous entries are kept.
+func (d *IPDenylist) SetDynamic(entries []string) error {
+	prefixes, err := parsePrefixes(entries)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	d.dynamic = prefixes
+	d.mu.Unlock()
+	return nil
+}
+
+// Contains reports whether ip is denied. Unparseable IPs are never denied.
+func (d *IPDenylist) Contains(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap().WithZone("")
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, list := range [][]netip.Prefix{d.static, d.dynamic} {
+		for _, prefix := range list {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parsePrefixes turns IPs (as single-address prefixes) and CIDR ranges into prefixes.
+func parsePrefixes(entries []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("faixa CIDR inválida na lista de bloqueio: %q", entry)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("IP inválido na lista de bloqueio: %q", entry)
+		}
+		addr = addr.Unmap().WithZone("")
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// IPDenylistMiddleware refuses requests from denied client IPs with 403. Register it before any rate
+// limiter so blocked clients don't consume tokens. The client IP is c.ClientIP(), as everywhere else,
+// so the engine's trusted proxies decide whether X-Forwarded-For is honored.
+func IPDenylistMiddleware(denylist *IPDenylist) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ip := c.ClientIP()
+		if !denylist.Contains(ip) {
+			c.Next()
+
+			return
+		}
+
+		logger.WarnContext(c.Request.Context(), "Requisição de IP bloqueado", "ip", ip, "path", c.Request.URL.Path)
+		if wantsJSON(c) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": msgIPDenied})
+			return
+		}
+		c.String(http.StatusForbidden, msgIPDenied)
+		c.Abort()
+	}
+}
@@ -0,0 +1,81 @@
+// Package middleware tests
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIPDenylist_Contains(t *testing.T) {
+	denylist, err := NewIPDenylist([]string{"203.0.113.7", "198.51.100.77/24", "2001:db8::/32"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		ip     string
+		denied bool
+	}{
+		{"203.0.113.7", true},
+		{"::ffff:203.0.113.7", true},
+		{"203.0.113.8", false},
+		{"198.51.100.1", true},
+		{"198.51.100.255", true},
+		{"198.51.101.1", false},
+		{"2001:db8:1::5", true},
+		{"2001:db9::1", false},
+		{"not-an-ip", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.denied, denylist.Contains(tt.ip), tt.ip)
+	}
+
+	require.NoError(t, denylist.SetDynamic([]string{"192.0.2.1"}))
+	assert.True(t, denylist.Contains("192.0.2.1"))
+	require.NoError(t, denylist.SetDynamic(nil))
+	assert.False(t, denylist.Contains("192.0.2.1"))
+	assert.True(t, denylist.Contains("203.0.113.7"), "static entries are kept")
+
+	assert.Error(t, denylist.SetDynamic([]string{"192.0.2.1", "bad"}))
+	assert.False(t, denylist.Contains("192.0.2.1"), "a bad update keeps the previous entries")
+
+	_, err = NewIPDenylist([]string{"10.0.0.0/33"})
+	assert.Error(t, err)
+}
+
+func TestIPDenylistMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	denylist, err := NewIPDenylist([]string{"10.1.0.0/16"})
+	require.NoError(t, err)
+
+	limited := 0
+	r := gin.New()
+	r.Use(IPDenylistMiddleware(denylist))
+	r.Use(func(c *gin.Context) { limited++ }) // stands in for the rate limiter
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	request := func(remoteAddr, accept string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("Accept", accept)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request("10.1.2.3:5000", "text/html")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, msgIPDenied, w.Body.String())
+	w = request("10.1.2.3:5000", "application/json")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.JSONEq(t, `{"error":"`+msgIPDenied+`"}`, w.Body.String())
+	assert.Zero(t, limited, "blocked requests never reach the next middleware")
+
+	w = request("10.2.0.1:5000", "text/html")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, limited)
+}
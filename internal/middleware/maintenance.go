@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// msgMaintenance answers requests blocked by MaintenanceMiddleware.
+const msgMaintenance = "serviço em manutenção; tente de novo mais tarde"
+
+// MaintenanceWindow is a scheduled maintenance: from Start until End the app answers 503, and during
+// AnnounceBefore before Start pages announce it. The zero value schedules nothing.
+type MaintenanceWindow struct {
+	Start, End     time.Time
+	AnnounceBefore time.Duration
+}
+
+// Active reports whether now is inside the window.
+func (w MaintenanceWindow) Active(now time.Time) bool {
+	return !w.Start.IsZero() && !now.Before(w.Start) && now.Before(w.End)
+}
+
+// Announced reports whether now is within AnnounceBefore of the window's start.
+func (w MaintenanceWindow) Announced(now time.Time) bool {
+	return !w.Start.IsZero() && now.Before(w.Start) && !now.Before(w.Start.Add(-w.AnnounceBefore))
+}
+
+// maintenanceWindow is set at startup from config maintenance by SetMaintenanceWindow.
+var maintenanceWindow atomic.Pointer[MaintenanceWindow]
+
+// SetMaintenanceWindow schedules the maintenance window; the zero MaintenanceWindow clears it. It takes
+// effect on the next request.
+func SetMaintenanceWindow(w MaintenanceWindow) {
+	maintenanceWindow.Store(&w)
+}
+
+// ScheduledMaintenance returns the window set by SetMaintenanceWindow, or the zero value.
+func ScheduledMaintenance() MaintenanceWindow {
+	if w := maintenanceWindow.Load(); w != nil {
+		return *w
+	}
+	return MaintenanceWindow{}
+}
+
+// MaintenanceMiddleware answers 503 while the scheduled maintenance window is active, checking the
+// clock on every request so the mode starts and ends on time without a restart. Paths starting with
+// one of exempt (e.g. "/health", "/static") keep working.
+//
+// API clients get a JSON error with "maintenance_until"; other requests call onActive(c) (e.g. to render
+// the 503 page), or get the JSON error too when it is nil. Retry-After carries the seconds left.
+func MaintenanceMiddleware(onActive func(*gin.Context), exempt ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		window := ScheduledMaintenance()
+		now := time.Now()
+		if !window.Active(now) {
+			c.Next()
+			return
+		}
+		for _, prefix := range exempt {
+			if strings.HasPrefix(c.Request.URL.Path, prefix) {
+				c.Next()
+				return
+			}
+		}
+
+		logger.DebugContext(c.Request.Context(), "Requisição recusada: janela de manutenção", "path", c.Request.URL.Path,
+			"until", window.End)
+		c.Header("Retry-After", strconv.Itoa(int(window.End.Sub(now).Seconds())+1))
+		c.Abort()
+		if onActive != nil && WantsHTML(c) {
+			onActive(c)
+			return
+		}
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": msgMaintenance, "maintenance_until": window.End.UTC().Format(time.RFC3339)})
+	}
+}
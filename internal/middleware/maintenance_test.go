@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaintenanceWindow(t *testing.T) {
+	saoPaulo, err := time.LoadLocation("America/Sao_Paulo")
+	assert.NoError(t, err)
+	window := MaintenanceWindow{
+		Start:          time.Date(2025, 3, 1, 2, 0, 0, 0, saoPaulo),
+		End:            time.Date(2025, 3, 1, 4, 0, 0, 0, saoPaulo),
+		AnnounceBefore: 24 * time.Hour,
+	}
+
+	tests := []struct {
+		name          string
+		now           time.Time
+		wantActive    bool
+		wantAnnounced bool
+	}{
+		{"Days before", time.Date(2025, 2, 25, 12, 0, 0, 0, time.UTC), false, false},
+		{"Within the announcement", time.Date(2025, 2, 28, 12, 0, 0, 0, time.UTC), false, true},
+		{"Start, in another zone", time.Date(2025, 3, 1, 5, 0, 0, 0, time.UTC), true, false},
+		{"02:00 UTC is still 23:00 in São Paulo", time.Date(2025, 3, 1, 2, 0, 0, 0, time.UTC), false, true},
+		{"Inside", time.Date(2025, 3, 1, 3, 30, 0, 0, saoPaulo), true, false},
+		{"End", time.Date(2025, 3, 1, 4, 0, 0, 0, saoPaulo), false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantActive, window.Active(tt.now))
+			assert.Equal(t, tt.wantAnnounced, window.Announced(tt.now))
+		})
+	}
+
+	assert.False(t, MaintenanceWindow{}.Active(time.Now()), "nothing scheduled")
+	assert.False(t, MaintenanceWindow{}.Announced(time.Now()))
+}
+
+func TestMaintenanceMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Cleanup(func() { SetMaintenanceWindow(MaintenanceWindow{}) })
+
+	router := gin.New()
+	router.Use(MaintenanceMiddleware(func(c *gin.Context) { c.String(http.StatusServiceUnavailable, "maintenance page") }, "/health"))
+	router.GET("/", func(c *gin.Context) { c.String(http.StatusOK, "home") })
+	router.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	serve := func(path, accept string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Outside the window", func(t *testing.T) {
+		SetMaintenanceWindow(MaintenanceWindow{Start: time.Now().Add(time.Hour), End: time.Now().Add(2 * time.Hour)})
+		assert.Equal(t, http.StatusOK, serve("/", "text/html").Code)
+
+		SetMaintenanceWindow(MaintenanceWindow{Start: time.Now().Add(-2 * time.Hour), End: time.Now().Add(-time.Hour)})
+		assert.Equal(t, http.StatusOK, serve("/", "text/html").Code)
+	})
+
+	t.Run("Inside the window", func(t *testing.T) {
+		SetMaintenanceWindow(MaintenanceWindow{Start: time.Now().Add(-time.Minute), End: time.Now().Add(time.Hour)})
+
+		w := serve("/", "text/html")
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, "maintenance page", w.Body.String())
+		assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+		w = serve("/", "application/json")
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Contains(t, w.Body.String(), `"maintenance_until"`)
+
+		assert.Equal(t, http.StatusOK, serve("/health", "application/json").Code, "exempt paths stay up")
+	})
+}
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Media types the app answers with.
+const (
+	MIMEHTML = "text/html"
+	MIMEJSON = "application/json"
+)
+
+// Negotiate picks the offer the Accept header prefers: the highest quality (q), ties going to the
+// earlier offer. Each offer takes the q of the most specific range matching it (type/subtype, then
+// type/*, then */*). An empty header accepts anything, so the first offer wins; ok is false when the
+// header rules out every offer.
+func Negotiate(accept string, offers ...string) (offer string, ok bool) {
+	if strings.TrimSpace(accept) == "" {
+		if len(offers) == 0 {
+			return "", false
+		}
+		return offers[0], true
+	}
+	ranges := parseAccept(accept)
+
+	bestQ := 0.0
+	for _, candidate := range offers {
+		if q := acceptQuality(ranges, candidate); q > bestQ {
+			offer, bestQ = candidate, q
+		}
+	}
+	return offer, bestQ > 0
+}
+
+// WantsHTML reports whether the response should be an HTML page rather than JSON. Browser navigation
+// stays lenient: no Accept header, an Accept that prefers text/html, or one accepting neither type gets
+// HTML; "*/*" (curl, fetch) and JSON clients get JSON.
+func WantsHTML(c *gin.Context) bool {
+	accept := c.GetHeader("Accept")
+	if accept == "" {
+		return true
+	}
+	offer, ok := Negotiate(accept, MIMEJSON, MIMEHTML)
+	return !ok || offer == MIMEHTML
+}
+
+// AcceptMiddleware answers 406 Not Acceptable to requests whose Accept header rules out every offer,
+// for API routes that only speak those types. A missing header accepts anything.
+func AcceptMiddleware(offers ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := Negotiate(c.GetHeader("Accept"), offers...); !ok {
+			c.AbortWithStatusJSON(http.StatusNotAcceptable, gin.H{
+				"error":   "formato de resposta não suportado",
+				"accepts": offers,
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+// parseAccept splits an Accept header into media ranges; malformed ranges are skipped and a malformed q counts as 1.
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for part := range strings.SplitSeq(accept, ",") {
+		fields := strings.Split(part, ";")
+		typ, subtype, found := strings.Cut(strings.ToLower(strings.TrimSpace(fields[0])), "/")
+		if !found || typ == "" || subtype == "" {
+			continue
+		}
+		r := mediaRange{typ: typ, subtype: subtype, q: 1}
+		for _, param := range fields[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(key, "q") {
+				if q, err := strconv.ParseFloat(value, 64); err == nil && q >= 0 && q <= 1 {
+					r.q = q
+				}
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+// acceptQuality returns the q of the most specific range matching mediaType, or 0 when none does.
+func acceptQuality(ranges []mediaRange, mediaType string) float64 {
+	typ, subtype, _ := strings.Cut(strings.ToLower(mediaType), "/")
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		s := -1
+		switch {
+		case r.typ == typ && r.subtype == subtype:
+			s = 2
+		case r.typ == typ && r.subtype == "*":
+			s = 1
+		case r.typ == "*" && r.subtype == "*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept string
+		want   string
+		wantOK bool
+	}{
+		{"Empty accepts anything", "", MIMEJSON, true},
+		{"Wildcard takes the first offer", "*/*", MIMEJSON, true},
+		{"HTML", "text/html", MIMEHTML, true},
+		{"JSON", "application/json", MIMEJSON, true},
+		{"Type wildcard", "text/*", MIMEHTML, true},
+		{"Browser navigation", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", MIMEHTML, true},
+		{"Quality decides", "text/html;q=0.5, application/json", MIMEJSON, true},
+		{"Specific range wins over wildcard", "*/*, application/json;q=0", MIMEHTML, true},
+		{"Case and spaces", " Application/JSON ; Q=1 ", MIMEJSON, true},
+		{"Unsupported", "application/xml", "", false},
+		{"Refused", "application/json;q=0, text/html;q=0", "", false},
+		{"Malformed ranges are ignored", "garbage, text/html", MIMEHTML, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Negotiate(tt.accept, MIMEJSON, MIMEHTML)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestWantsHTML(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		accept string
+		want   bool
+	}{
+		{"", true},
+		{"*/*", false},
+		{"text/html", true},
+		{"application/json", false},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", true},
+		{"application/xml", true}, // lenient: nothing we serve fits, so browsers still get the page
+	}
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		c.Request.Header.Set("Accept", tt.accept)
+		assert.Equal(t, tt.want, WantsHTML(c), "Accept %q", tt.accept)
+	}
+}
+
+func TestAcceptMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(AcceptMiddleware(MIMEJSON))
+	router.GET("/api", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"ok": true}) })
+
+	tests := []struct {
+		accept string
+		want   int
+	}{
+		{"", http.StatusOK},
+		{"*/*", http.StatusOK},
+		{"application/json", http.StatusOK},
+		{"text/html", http.StatusNotAcceptable},
+		{"application/xml", http.StatusNotAcceptable},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api", nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, tt.want, w.Code, "Accept %q", tt.accept)
+		if tt.want == http.StatusNotAcceptable {
+			assert.JSONEq(t, `{"error":"formato de resposta não suportado","accepts":["application/json"]}`, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OriginCheckMiddleware is a lightweight CSRF defense: state-changing requests (POST, PUT, PATCH,
+// DELETE) must come from a trusted origin, taken from the Origin header or, when absent, the Referer.
+//
+// trustedOrigins are "scheme://host[:port]" values; when empty, only the request's own host is trusted.
+// Requests authenticated with a bearer token or X-Session-ID header are skipped, since browsers never
+// attach those automatically. When neither Origin nor Referer is present the request passes unless
+// requireHeader is set (non-browser clients usually send neither).
+func OriginCheckMiddleware(trustedOrigins []string, requireHeader bool) gin.HandlerFunc {
+	trusted := make(map[string]struct{}, len(trustedOrigins))
+	for _, o := range trustedOrigins {
+		if normalized := normalizeOrigin(o); normalized != "" {
+			trusted[normalized] = struct{}{}
+		}
+	}
+
+	return func(c *gin.Context) {
+		if isSafeMethod(c.Request.Method) || hasHeaderCredentials(c) {
+			c.Next()
+
+			return
+		}
+
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			origin = c.GetHeader("Referer")
+		}
+		if origin == "" {
+			if requireHeader {
+				logger.WarnContext(c.Request.Context(), "Requisição sem Origin/Referer bloqueada", "path", c.Request.URL.Path, "ip", c.ClientIP())
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "origem não permitida"})
+
+				return
+			}
+			c.Next()
+
+			return
+		}
+
+		if !isTrustedOrigin(normalizeOrigin(origin), c.Request.Host, trusted) {
+			logger.WarnContext(c.Request.Context(), "Requisição de origem não confiável bloqueada", "origin", origin, "path", c.Request.URL.Path, "ip", c.ClientIP())
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "origem não permitida"})
+
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// isSafeMethod reports whether the method is read-only per RFC 9110.
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// hasHeaderCredentials reports whether the request authenticates via headers instead of the cookie.
+func hasHeaderCredentials(c *gin.Context) bool {
+	return strings.HasPrefix(c.GetHeader("Authorization"), "Bearer ") || c.GetHeader(SessionHeaderName) != ""
+}
+
+// normalizeOrigin reduces an Origin or Referer value to lowercase "scheme://host[:port]".
+// Returns "" for opaque origins ("null") and unparsable values.
+func normalizeOrigin(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return ""
+	}
+	return strings.ToLower(u.Scheme + "://" + u.Host)
+}
+
+// isTrustedOrigin checks origin against the allowlist, or against the request host when the list is empty.
+func isTrustedOrigin(origin, requestHost string, trusted map[string]struct{}) bool {
+	if origin == "" {
+		return false
+	}
+	if len(trusted) > 0 {
+		_, ok := trusted[origin]
+		return ok
+	}
+	host := origin[strings.Index(origin, "://")+len("://"):]
+	return strings.EqualFold(host, requestHost)
+}
@@ -0,0 +1,62 @@
+// Package middleware tests
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newOriginCheckRouter(trusted []string, requireHeader bool) *gin.Engine {
+	r := gin.New()
+	r.Use(OriginCheckMiddleware(trusted, requireHeader))
+	r.GET("/form", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.POST("/form", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+func TestOriginCheckMiddleware(t *testing.T) {
+	trusted := []string{"https://app.example.com"}
+
+	tests := []struct {
+		name           string
+		trusted        []string
+		requireHeader  bool
+		method         string
+		headers        map[string]string
+		expectedStatus int
+	}{
+		{"Same origin allowed", trusted, false, http.MethodPost, map[string]string{"Origin": "https://app.example.com"}, http.StatusOK},
+		{"Origin match is case-insensitive", trusted, false, http.MethodPost, map[string]string{"Origin": "HTTPS://App.Example.com"}, http.StatusOK},
+		{"Cross origin blocked", trusted, false, http.MethodPost, map[string]string{"Origin": "https://evil.example.com"}, http.StatusForbidden},
+		{"Different scheme blocked", trusted, false, http.MethodPost, map[string]string{"Origin": "http://app.example.com"}, http.StatusForbidden},
+		{"Opaque origin blocked", trusted, false, http.MethodPost, map[string]string{"Origin": "null"}, http.StatusForbidden},
+		{"Referer fallback allowed", trusted, false, http.MethodPost, map[string]string{"Referer": "https://app.example.com/admin/users"}, http.StatusOK},
+		{"Referer fallback blocked", trusted, false, http.MethodPost, map[string]string{"Referer": "https://evil.example.com/page"}, http.StatusForbidden},
+		{"Missing headers allowed by default", trusted, false, http.MethodPost, nil, http.StatusOK},
+		{"Missing headers rejected when required", trusted, true, http.MethodPost, nil, http.StatusForbidden},
+		{"Safe method not checked", trusted, true, http.MethodGet, map[string]string{"Origin": "https://evil.example.com"}, http.StatusOK},
+		{"Bearer token skipped", trusted, true, http.MethodPost, map[string]string{"Origin": "https://evil.example.com", "Authorization": "Bearer abc"}, http.StatusOK},
+		{"Session header skipped", trusted, true, http.MethodPost, map[string]string{SessionHeaderName: "abc"}, http.StatusOK},
+		{"Empty allowlist trusts request host", nil, false, http.MethodPost, map[string]string{"Origin": "http://example.com"}, http.StatusOK},
+		{"Empty allowlist blocks other hosts", nil, false, http.MethodPost, map[string]string{"Origin": "http://evil.example.com"}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newOriginCheckRouter(tt.trusted, tt.requireHeader)
+
+			req := httptest.NewRequest(tt.method, "http://example.com/form", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
@@ -4,66 +4,140 @@ package middleware
 
 import (
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/icons"
 	"github.com/lucas-varjao/gohtmx/internal/logger"
+	"github.com/lucas-varjao/gohtmx/templates/components"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/time/rate"
 )
 
+// IPRateLimiter hands out one token bucket per client IP. Buckets idle for longer than expiry
+// are pruned lazily (at most once per expiry window), so an idle entry lives between one and
+// two windows.
 type IPRateLimiter struct {
-	ips    map[string]*rate.Limiter
-	mu     *sync.RWMutex
-	rate   rate.Limit
-	burst  int
-	expiry time.Duration
+	store     RateLimitStore
+	mu        sync.Mutex // serializes bucket creation and pruning
+	rate      rate.Limit
+	burst     int
+	expiry    time.Duration
+	now       func() time.Time
+	nextPrune time.Time
 }
 
+// NewIPRateLimiter creates a limiter backed by a MemoryRateLimitStore and the wall clock
 func NewIPRateLimiter(r rate.Limit, b int, expiry time.Duration) *IPRateLimiter {
+	return NewIPRateLimiterWithStore(r, b, expiry, NewMemoryRateLimitStore(), nil)
+}
+
+// NewIPRateLimiterWithStore creates a limiter with a custom store and clock (nil now = time.Now)
+func NewIPRateLimiterWithStore(r rate.Limit, b int, expiry time.Duration, store RateLimitStore, now func() time.Time) *IPRateLimiter {
+	if now == nil {
+		now = time.Now
+	}
 	return &IPRateLimiter{
-		ips:    make(map[string]*rate.Limiter),
-		mu:     &sync.RWMutex{},
-		rate:   r,
-		burst:  b,
-		expiry: expiry,
+		store:     store,
+		rate:      r,
+		burst:     b,
+		expiry:    expiry,
+		now:       now,
+		nextPrune: now().Add(expiry),
 	}
 }
 
 func (i *IPRateLimiter) GetLimiter(ip string) *rate.Limiter {
-	i.mu.RLock()
-	limiter, exists := i.ips[ip]
-	i.mu.RUnlock()
-
-	if !exists {
-		i.mu.Lock()
-		limiter = rate.NewLimiter(i.rate, i.burst)
-		i.ips[ip] = limiter
-		i.mu.Unlock()
-
-		// Limpar IPs expirados para evitar vazamento de memória
-		go func(ip string) {
-			time.Sleep(i.expiry)
-			i.mu.Lock()
-			delete(i.ips, ip)
-			i.mu.Unlock()
-		}(ip)
+	now := i.now()
+	i.pruneIfDue(now)
+
+	if limiter, ok := i.store.Get(ip, now); ok {
+		return limiter
 	}
 
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	// Another request may have created it while we waited for the lock
+	if limiter, ok := i.store.Get(ip, now); ok {
+		return limiter
+	}
+	limiter := rate.NewLimiter(i.rate, i.burst)
+	i.store.Set(ip, limiter, now)
 	return limiter
 }
 
+// Allow reports whether a request from ip may proceed now (per the limiter's clock).
+func (i *IPRateLimiter) Allow(ip string) bool {
+	return i.GetLimiter(ip).AllowN(i.now(), 1)
+}
+
+// Prune drops buckets idle for longer than expiry and returns how many were removed.
+func (i *IPRateLimiter) Prune() int {
+	now := i.now()
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.nextPrune = now.Add(i.expiry)
+	return i.store.Prune(now.Add(-i.expiry))
+}
+
+// pruneIfDue evicts idle buckets to avoid leaking memory, at most once per expiry window.
+func (i *IPRateLimiter) pruneIfDue(now time.Time) {
+	i.mu.Lock()
+	due := !now.Before(i.nextPrune)
+	i.mu.Unlock()
+	if due {
+		i.Prune()
+	}
+}
+
+// msgRateLimited is the user-facing message for every rate-limit response format.
+const msgRateLimited = "limite de requisições excedido, aguarde alguns segundos e tente novamente"
+
 func RateLimitMiddleware(limiter *IPRateLimiter) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ip := c.ClientIP()
-		l := limiter.GetLimiter(ip)
 
-		if !l.Allow() {
-			logger.Warn("Rate limit excedido", "ip", ip, "path", c.Request.URL.Path)
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error": "limite de requisições excedido",
-			})
+		if !limiter.Allow(ip) {
+			logger.WarnContext(c.Request.Context(), "Rate limit excedido", "ip", ip, "path", c.Request.URL.Path)
+			respondRateLimited(c, msgRateLimited)
+			c.Abort()
+
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// TieredRateLimitMiddleware is RateLimitMiddleware with a tier per auth state: requests with a valid
+// session are limited by authenticated, all others (no session, or an unknown or expired one) by
+// anonymous, so logged-in users can get a more generous limit without relaxing it for everyone. Both
+// tiers count per client IP.
+//
+// A request carrying a session ID is first charged to authenticated, so the session lookup itself is
+// rate limited; one whose session turns out invalid is then charged to anonymous as well. Requests
+// without one never reach the database here. AuthMiddleware reuses the validation instead of repeating it.
+func TieredRateLimitMiddleware(anonymous, authenticated *IPRateLimiter, authManager *auth.AuthManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ip := c.ClientIP()
+		tier, allowed := "anonymous", false
+		if sessionID := extractSessionID(c); sessionID != "" {
+			tier, allowed = "authenticated", authenticated.Allow(ip)
+			if allowed {
+				if _, _, err := validateSession(c, authManager, sessionID); err != nil {
+					tier, allowed = "anonymous", anonymous.Allow(ip)
+				}
+			}
+		} else {
+			allowed = anonymous.Allow(ip)
+		}
+
+		if !allowed {
+			logger.WarnContext(c.Request.Context(), "Rate limit excedido", "ip", ip, "path", c.Request.URL.Path, "tier", tier)
+			respondRateLimited(c, msgRateLimited)
 			c.Abort()
 
 			return
@@ -72,3 +146,37 @@ func RateLimitMiddleware(limiter *IPRateLimiter) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// respondRateLimited negotiates the 429 response format for message:
+//   - HTMX: error alert fragment with status 200 (HTMX ignores 4xx bodies), retargeted to the
+//     element the request was targeting (HX-Target), e.g. the form's error div
+//   - JSON/API clients: {"error": "..."} with 429
+//   - anything else: plain text with 429
+func respondRateLimited(c *gin.Context, message string) {
+	switch {
+	case c.GetHeader("HX-Request") != "":
+		if target := c.GetHeader("HX-Target"); target != "" {
+			c.Header("HX-Retarget", "#"+target)
+			c.Header("HX-Reswap", "innerHTML")
+		}
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		c.Status(http.StatusOK)
+		_ = components.ErrorAlert(message, icons.Error()).Render(c.Request.Context(), c.Writer)
+	case wantsJSON(c):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": message})
+	default:
+		c.String(http.StatusTooManyRequests, message)
+	}
+}
+
+// wantsJSON reports whether the client is an API/JSON client: its Accept header prefers JSON over HTML
+// (a bare "*/*" doesn't), or it sent a JSON body or a bearer token.
+func wantsJSON(c *gin.Context) bool {
+	if accept := c.GetHeader("Accept"); accept != "" {
+		if offer, ok := Negotiate(accept, MIMEHTML, MIMEJSON); ok && offer == MIMEJSON {
+			return true
+		}
+	}
+	return strings.HasPrefix(c.ContentType(), "application/json") ||
+		strings.HasPrefix(c.GetHeader("Authorization"), "Bearer ")
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimitStore keeps the per-key limiters of an IPRateLimiter. Times are passed in by the
+// limiter (its injected clock), so stores stay deterministic and easy to test.
+type RateLimitStore interface {
+	// Get returns the limiter for key and marks it as seen at now.
+	Get(key string, now time.Time) (*rate.Limiter, bool)
+	// Set stores limiter for key, seen at now.
+	Set(key string, limiter *rate.Limiter, now time.Time)
+	// Prune removes keys not seen since before and returns how many were removed.
+	Prune(before time.Time) int
+	// Len returns the number of keys currently stored.
+	Len() int
+}
+
+type memoryRateLimitEntry struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// MemoryRateLimitStore is the default in-process RateLimitStore.
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryRateLimitEntry
+}
+
+// NewMemoryRateLimitStore creates an empty in-memory store
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{entries: make(map[string]*memoryRateLimitEntry)}
+}
+
+func (s *MemoryRateLimitStore) Get(key string, now time.Time) (*rate.Limiter, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e.lastSeen = now
+	return e.limiter, true
+}
+
+func (s *MemoryRateLimitStore) Set(key string, limiter *rate.Limiter, now time.Time) {
+	s.mu.Lock()
+	s.entries[key] = &memoryRateLimitEntry{limiter: limiter, lastSeen: now}
+	s.mu.Unlock()
+}
+
+func (s *MemoryRateLimitStore) Prune(before time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := 0
+	for key, e := range s.entries {
+		if e.lastSeen.Before(before) {
+			delete(s.entries, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+func (s *MemoryRateLimitStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
@@ -0,0 +1,102 @@
+// Package middleware tests
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+// fakeClock is a manually advanced clock for time-based rate-limit tests.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) Now() time.Time          { return c.t }
+func (c *fakeClock) Advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{t: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func TestMemoryRateLimitStore_Prune(t *testing.T) {
+	clock := newFakeClock()
+	store := NewMemoryRateLimitStore()
+
+	store.Set("old", rate.NewLimiter(1, 1), clock.Now())
+	clock.Advance(time.Minute)
+	store.Set("recent", rate.NewLimiter(1, 1), clock.Now())
+
+	assert.Equal(t, 0, store.Prune(clock.Now().Add(-2*time.Minute)), "nothing is older than the cutoff")
+	assert.Equal(t, 1, store.Prune(clock.Now().Add(-30*time.Second)))
+	_, ok := store.Get("old", clock.Now())
+	assert.False(t, ok)
+	assert.Equal(t, 1, store.Len())
+
+	// Get refreshes lastSeen, so a busy key survives
+	clock.Advance(time.Hour)
+	_, ok = store.Get("recent", clock.Now())
+	assert.True(t, ok)
+	assert.Equal(t, 0, store.Prune(clock.Now().Add(-time.Second)))
+	clock.Advance(2 * time.Second)
+	assert.Equal(t, 1, store.Prune(clock.Now().Add(-time.Second)))
+	assert.Zero(t, store.Len())
+}
+
+func TestIPRateLimiter_EvictsIdleBuckets(t *testing.T) {
+	clock := newFakeClock()
+	store := NewMemoryRateLimitStore()
+	limiter := NewIPRateLimiterWithStore(rate.Every(time.Hour), 1, 10*time.Minute, store, clock.Now)
+
+	limiter.GetLimiter("10.0.0.1")
+	clock.Advance(9 * time.Minute)
+	limiter.GetLimiter("10.0.0.2")
+	assert.Equal(t, 2, store.Len())
+
+	// First prune is due one window after creation; 10.0.0.1 has been idle for just over 10m
+	clock.Advance(time.Minute + time.Second)
+	limiter.GetLimiter("10.0.0.2")
+	assert.Equal(t, 1, store.Len())
+
+	// 10.0.0.2 keeps being seen, so it outlives several windows
+	for range 3 {
+		clock.Advance(9 * time.Minute)
+		limiter.GetLimiter("10.0.0.2")
+	}
+	assert.Equal(t, 1, store.Len())
+
+	clock.Advance(11 * time.Minute)
+	assert.Equal(t, 1, limiter.Prune())
+	assert.Zero(t, store.Len())
+}
+
+func TestRateLimitMiddleware_FreshBucketAfterEviction(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	clock := newFakeClock()
+	// Tokens never refill, so only eviction can let the client through again
+	limiter := NewIPRateLimiterWithStore(0, 1, time.Hour, NewMemoryRateLimitStore(), clock.Now)
+
+	r := gin.New()
+	r.Use(RateLimitMiddleware(limiter))
+	r.GET("/test", func(c *gin.Context) { c.Status(http.StatusOK) })
+	request := func() int {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("X-Forwarded-For", "192.168.1.60")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request())
+	assert.Equal(t, http.StatusTooManyRequests, request())
+
+	clock.Advance(59 * time.Minute)
+	assert.Equal(t, http.StatusTooManyRequests, request(), "blocked requests keep the bucket alive")
+
+	clock.Advance(61 * time.Minute)
+	limiter.Prune()
+	assert.Equal(t, http.StatusOK, request())
+}
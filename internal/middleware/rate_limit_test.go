@@ -5,13 +5,18 @@ package middleware
 import (
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"golang.org/x/time/rate"
+	"gorm.io/gorm"
 )
 
 // Tests only verify observable behavior via HTTP. No inspection of limiter.ips/mu or assert.Same.
@@ -158,3 +163,134 @@ func TestRateLimitMiddleware(t *testing.T) {
 		assert.LessOrEqual(t, allowed, 5)
 	})
 }
+
+func TestRateLimitMiddleware_ResponseFormat(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// exhaustedRouter returns a router whose limiter has no tokens left for ip.
+	exhaustedRouter := func(ip string) *gin.Engine {
+		ipLimiter := NewIPRateLimiter(0.1, 1, time.Minute)
+		ipLimiter.GetLimiter(ip).Allow()
+		r := gin.New()
+		r.Use(RateLimitMiddleware(ipLimiter))
+		r.POST("/auth/login", func(c *gin.Context) { c.Status(http.StatusOK) })
+		return r
+	}
+
+	t.Run("JSON for API clients", func(t *testing.T) {
+		r := exhaustedRouter("10.0.0.1")
+		req := httptest.NewRequest("POST", "/auth/login", nil)
+		req.Header.Set("X-Forwarded-For", "10.0.0.1")
+		req.Header.Set("Accept", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+		assert.JSONEq(t, `{"error": "`+msgRateLimited+`"}`, w.Body.String())
+	})
+
+	t.Run("HTMX fragment retargeted to form error div", func(t *testing.T) {
+		r := exhaustedRouter("10.0.0.2")
+		req := httptest.NewRequest("POST", "/auth/login", nil)
+		req.Header.Set("X-Forwarded-For", "10.0.0.2")
+		req.Header.Set("HX-Request", "true")
+		req.Header.Set("HX-Target", "login-error")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+		assert.Equal(t, "#login-error", w.Header().Get("HX-Retarget"))
+		assert.Equal(t, "innerHTML", w.Header().Get("HX-Reswap"))
+		assert.Contains(t, w.Body.String(), "alert-error")
+		assert.Contains(t, w.Body.String(), msgRateLimited)
+	})
+
+	t.Run("Plain text otherwise", func(t *testing.T) {
+		for i, accept := range []string{
+			"text/html",
+			"*/*",
+			// JSON is mentioned but refused or less preferred
+			"text/html, application/json;q=0",
+			"text/html, application/json;q=0.5",
+		} {
+			ip := "10.0.1." + strconv.Itoa(i)
+			r := exhaustedRouter(ip)
+			req := httptest.NewRequest("POST", "/auth/login", nil)
+			req.Header.Set("X-Forwarded-For", ip)
+			req.Header.Set("Accept", accept)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusTooManyRequests, w.Code, accept)
+			assert.Contains(t, w.Header().Get("Content-Type"), "text/plain", accept)
+			assert.Equal(t, msgRateLimited, w.Body.String(), accept)
+		}
+	})
+}
+
+func TestTieredRateLimitMiddleware(t *testing.T) {
+	authManager, db := createTestAuthManager()
+	user := &models.User{Username: "tiered", Email: "tiered@example.com", PasswordHash: "hash", Active: true, Role: "user"}
+	db.Create(user)
+	db.Create(&models.Session{ID: "tiered-session", UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour), CreatedAt: time.Now()})
+
+	// allowed counts how many of n requests from ip (with sessionID, if any) pass before the limit
+	allowed := func(ip, sessionID string, n int) int {
+		r := gin.New()
+		r.Use(TieredRateLimitMiddleware(NewIPRateLimiter(0.1, 2, time.Minute), NewIPRateLimiter(0.1, 5, time.Minute), authManager))
+		r.Use(AuthMiddleware(authManager))
+		r.GET("/api/me", func(c *gin.Context) { c.String(http.StatusOK, c.GetString("userID")) })
+
+		passed := 0
+		for range n {
+			req := httptest.NewRequest("GET", "/api/me", nil)
+			req.Header.Set("X-Forwarded-For", ip)
+			if sessionID != "" {
+				req.Header.Set("Authorization", "Bearer "+sessionID)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != http.StatusTooManyRequests {
+				passed++
+			}
+		}
+		return passed
+	}
+
+	assert.Equal(t, 5, allowed("10.1.0.1", "tiered-session", 8), "a valid session gets the authenticated limit")
+	assert.Equal(t, 2, allowed("10.1.0.2", "", 8), "no session gets the anonymous limit")
+	assert.Equal(t, 2, allowed("10.1.0.3", "forged-session", 8), "an unknown session is anonymous")
+
+	// Session lookups only happen within the authenticated limit, so made-up session IDs can't hammer the database
+	lookups := 0
+	require.NoError(t, db.Callback().Query().After("gorm:query").Register("count_session_lookups", func(tx *gorm.DB) {
+		if tx.Statement.Table == "sessions" {
+			lookups++
+		}
+	}))
+	r := gin.New()
+	r.Use(TieredRateLimitMiddleware(NewIPRateLimiter(0.1, 100, time.Minute), NewIPRateLimiter(0.1, 3, time.Minute), authManager))
+	r.GET("/api/me", func(c *gin.Context) { c.Status(http.StatusOK) })
+	for i := range 10 {
+		req := httptest.NewRequest("GET", "/api/me", nil)
+		req.Header.Set("X-Forwarded-For", "10.1.0.4")
+		req.Header.Set("Authorization", "Bearer forged-"+strconv.Itoa(i))
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	assert.Equal(t, 3, lookups)
+
+	// AuthMiddleware reuses the validation, so a session refreshed by it still gets its new cookie
+	db.Create(&models.Session{ID: "refreshed-session", UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour), CreatedAt: time.Now()})
+	r = gin.New()
+	r.Use(TieredRateLimitMiddleware(NewIPRateLimiter(1, 1, time.Minute), NewIPRateLimiter(1, 1, time.Minute), authManager))
+	r.Use(AuthMiddleware(authManager))
+	r.GET("/api/me", func(c *gin.Context) { c.Status(http.StatusOK) })
+	req := httptest.NewRequest("GET", "/api/me", nil)
+	req.AddCookie(&http.Cookie{Name: SessionCookieName, Value: "refreshed-session"})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Set-Cookie"), SessionCookieName+"=refreshed-session")
+}
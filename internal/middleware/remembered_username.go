@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RememberedUsernameCookie holds the username of the last successful login in this browser, to pre-fill
+// the login form (config login.remember_username). It never holds the password or anything else secret.
+const RememberedUsernameCookie = "last_username"
+
+// DefaultRememberedUsernameMaxAge is how long the username is remembered when config
+// login.remember_username.max_age is unset.
+const DefaultRememberedUsernameMaxAge = 30 * 24 * time.Hour
+
+// maxRememberedUsernameLength bounds what RememberedUsername accepts back from the browser.
+const maxRememberedUsernameLength = 254
+
+// SetRememberedUsername remembers username in this browser for maxAge (DefaultRememberedUsernameMaxAge
+// when maxAge <= 0), with the same Secure, HttpOnly and SameSite flags as the session cookie.
+func SetRememberedUsername(c *gin.Context, username string, maxAge time.Duration) {
+	if maxAge <= 0 {
+		maxAge = DefaultRememberedUsernameMaxAge
+	}
+	writeCookie(c, RememberedUsernameCookie, username, int(maxAge.Seconds()))
+}
+
+// RememberedUsername returns the username remembered in this browser ("" for none).
+func RememberedUsername(c *gin.Context) string {
+	username, err := c.Cookie(RememberedUsernameCookie)
+	if err != nil || len(username) > maxRememberedUsernameLength {
+		return ""
+	}
+	return username
+}
+
+// ClearRememberedUsername forgets the username remembered in this browser, if any.
+func ClearRememberedUsername(c *gin.Context) {
+	if _, err := c.Cookie(RememberedUsernameCookie); err == nil {
+		writeCookie(c, RememberedUsernameCookie, "", -1)
+	}
+}
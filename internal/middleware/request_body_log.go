@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxLoggedBodySize is the largest body RequestBodyLogMiddleware logs; bigger ones are noted, not read.
+const maxLoggedBodySize = 64 << 10
+
+// redacted replaces the value of sensitive fields in logged bodies.
+const redacted = "***"
+
+// sensitiveFieldParts mark a body field as sensitive when its name contains one of them
+// (password, new_password, token, captcha_token, ...).
+var sensitiveFieldParts = []string{"password", "token", "secret", "captcha", "recaptcha", "turnstile"}
+
+// sensitiveFields are sensitive field names that don't contain one of sensitiveFieldParts.
+var sensitiveFields = map[string]bool{"code": true} // emailed login code
+
+// isSensitiveField reports whether the value of the body field name must not be logged.
+func isSensitiveField(name string) bool {
+	name = strings.ToLower(name)
+	if sensitiveFields[name] {
+		return true
+	}
+	for _, part := range sensitiveFieldParts {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequestBodyLogMiddleware logs, at debug level, the body of POST, PUT and PATCH requests whose path
+// starts with one of prefixes (e.g. "/auth", "/admin"), with passwords, tokens and codes replaced by
+// "***". JSON objects and form posts are redacted field by field; other bodies are only described.
+// It is meant for debugging forms in development (config log.request_bodies) and does nothing unless the
+// log level is debug. The body is re-buffered, so handlers read it as sent.
+func RequestBodyLogMiddleware(prefixes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+		default:
+			c.Next()
+			return
+		}
+		ctx := c.Request.Context()
+		if c.Request.Body == nil || !logger.Get().Enabled(ctx, slog.LevelDebug) || !hasAnyPrefix(c.Request.URL.Path, prefixes) {
+			c.Next()
+			return
+		}
+
+		read, err := io.ReadAll(io.LimitReader(c.Request.Body, maxLoggedBodySize+1))
+		// What was read goes back in front of the rest, so the handler gets the whole body
+		c.Request.Body = readCloser{io.MultiReader(bytes.NewReader(read), c.Request.Body), c.Request.Body}
+		if err != nil {
+			logger.DebugContext(ctx, "request_body", "method", c.Request.Method, "path", c.Request.URL.Path, "error", err)
+			c.Next()
+			return
+		}
+
+		contentType := c.ContentType()
+		var body string
+		if len(read) > maxLoggedBodySize {
+			body = "[omitido: maior que 64 KiB]"
+		} else {
+			body = redactBody(contentType, read)
+		}
+		logger.DebugContext(ctx, "request_body", "method", c.Request.Method, "path", c.Request.URL.Path,
+			"content_type", contentType, "body", body)
+		c.Next()
+	}
+}
+
+// readCloser reads from the re-buffered body and closes the original one.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func hasAnyPrefix(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactBody returns raw, as sent with contentType, with the values of sensitive fields redacted.
+// Bodies it can't parse field by field are described instead of logged, since they may hold anything.
+func redactBody(contentType string, raw []byte) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		var value any
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return "[JSON inválido omitido]"
+		}
+		out, err := json.Marshal(redactJSON(value))
+		if err != nil {
+			return "[JSON omitido]"
+		}
+		return string(out)
+	case mediaType == "application/x-www-form-urlencoded":
+		form, err := url.ParseQuery(string(raw))
+		if err != nil {
+			return "[formulário inválido omitido]"
+		}
+		for name, values := range form {
+			if isSensitiveField(name) {
+				for i := range values {
+					values[i] = redacted
+				}
+			}
+		}
+		// As JSON: form encoding would turn *** into %2A%2A%2A
+		out, err := json.Marshal(form)
+		if err != nil {
+			return "[formulário omitido]"
+		}
+		return string(out)
+	default:
+		return "[corpo omitido: " + cmp.Or(mediaType, "sem Content-Type") + "]"
+	}
+}
+
+// redactJSON redacts the sensitive fields of value, in nested objects and arrays too.
+func redactJSON(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for name, field := range v {
+			if isSensitiveField(name) {
+				v[name] = redacted
+			} else {
+				v[name] = redactJSON(field)
+			}
+		}
+	case []any:
+		for i := range v {
+			v[i] = redactJSON(v[i])
+		}
+	}
+	return value
+}
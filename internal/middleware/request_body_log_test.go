@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestBodyLogMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var logs bytes.Buffer
+	logger.InitWithWriter(&logs, "debug", "json")
+	t.Cleanup(func() { logger.Init("info", "text") })
+
+	var seen string
+	router := gin.New()
+	router.Use(RequestBodyLogMiddleware("/auth", "/admin"))
+	echo := func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		seen = string(body)
+		c.Status(http.StatusOK)
+	}
+	router.POST("/auth/login", echo)
+	router.POST("/api/items", echo)
+
+	post := func(path, contentType, body string) {
+		logs.Reset()
+		req, _ := http.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	t.Run("JSON passwords are redacted and the handler gets the original body", func(t *testing.T) {
+		body := `{"username":"maria","password":"hunter2","profile":{"new_password":"s3cret!","token":"abc"}}`
+		post("/auth/login", "application/json", body)
+
+		assert.Equal(t, body, seen)
+		require.Contains(t, logs.String(), `"msg":"request_body"`)
+		assert.Contains(t, logs.String(), "maria")
+		for _, secret := range []string{"hunter2", "s3cret!", "abc"} {
+			assert.NotContains(t, logs.String(), secret)
+		}
+		assert.Contains(t, logs.String(), `***`)
+	})
+
+	t.Run("Form passwords are redacted", func(t *testing.T) {
+		body := "username=maria&password=hunter2&cf-turnstile-response=xyz"
+		post("/auth/login", "application/x-www-form-urlencoded", body)
+
+		assert.Equal(t, body, seen)
+		assert.Contains(t, logs.String(), "maria")
+		assert.NotContains(t, logs.String(), "hunter2")
+		assert.NotContains(t, logs.String(), "xyz")
+	})
+
+	t.Run("Other paths are not logged", func(t *testing.T) {
+		post("/api/items", "application/json", `{"name":"x"}`)
+		assert.Equal(t, `{"name":"x"}`, seen)
+		assert.Empty(t, logs.String())
+	})
+
+	t.Run("Nothing is logged above debug level", func(t *testing.T) {
+		logger.InitWithWriter(&logs, "info", "json")
+		post("/auth/login", "application/json", `{"password":"hunter2"}`)
+		assert.Equal(t, `{"password":"hunter2"}`, seen)
+		assert.Empty(t, logs.String())
+	})
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"sync"
+
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// msgSessionConcurrency answers requests over the per-session in-flight limit.
+const msgSessionConcurrency = "muitas requisições simultâneas nesta sessão, aguarde as anteriores terminarem"
+
+// SessionConcurrencyLimiter counts the requests each session has in flight. Unlike the IP rate limit it
+// catches a single (possibly stolen) session token hammering the API, wherever the requests come from.
+type SessionConcurrencyLimiter struct {
+	mu       sync.Mutex
+	limit    int
+	inFlight map[string]int
+}
+
+// NewSessionConcurrencyLimiter creates a limiter allowing up to limit simultaneous requests per session.
+func NewSessionConcurrencyLimiter(limit int) *SessionConcurrencyLimiter {
+	return &SessionConcurrencyLimiter{limit: limit, inFlight: make(map[string]int)}
+}
+
+// Acquire takes an in-flight slot for sessionID; false means the session is at the limit.
+// Every successful Acquire must be paired with Release.
+func (l *SessionConcurrencyLimiter) Acquire(sessionID string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inFlight[sessionID] >= l.limit {
+		return false
+	}
+	l.inFlight[sessionID]++
+	return true
+}
+
+// Release frees a slot taken by Acquire; idle sessions are dropped from the map.
+func (l *SessionConcurrencyLimiter) Release(sessionID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inFlight[sessionID] <= 1 {
+		delete(l.inFlight, sessionID)
+		return
+	}
+	l.inFlight[sessionID]--
+}
+
+// InFlight returns how many requests sessionID has in flight.
+func (l *SessionConcurrencyLimiter) InFlight(sessionID string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inFlight[sessionID]
+}
+
+// SessionConcurrencyMiddleware rejects with 429 (same formats as the rate limit) a request whose session
+// already has the limiter's maximum in flight. Use it after AuthMiddleware, which sets "sessionID";
+// requests without a session pass through. The slot is released even when a later handler panics.
+func SessionConcurrencyMiddleware(limiter *SessionConcurrencyLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionID := c.GetString("sessionID")
+		if sessionID == "" {
+			c.Next()
+			return
+		}
+		if !limiter.Acquire(sessionID) {
+			logger.WarnContext(c.Request.Context(), "Limite de requisições simultâneas da sessão excedido",
+				"user_id", c.GetString("userID"), "path", c.Request.URL.Path, "ip", c.ClientIP())
+			respondRateLimited(c, msgSessionConcurrency)
+			c.Abort()
+			return
+		}
+		defer limiter.Release(sessionID)
+		c.Next()
+	}
+}
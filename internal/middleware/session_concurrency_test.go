@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionConcurrencyMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(limiter *SessionConcurrencyLimiter, handler gin.HandlerFunc) *gin.Engine {
+		r := gin.New()
+		r.Use(gin.RecoveryWithWriter(io.Discard))
+		r.Use(func(c *gin.Context) {
+			c.Set("sessionID", c.GetHeader(SessionHeaderName))
+			c.Next()
+		})
+		r.Use(SessionConcurrencyMiddleware(limiter))
+		r.GET("/test", handler)
+		return r
+	}
+	request := func(r *gin.Engine, sessionID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set(SessionHeaderName, sessionID)
+		req.Header.Set("Accept", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Concurrent requests over the limit get 429", func(t *testing.T) {
+		const limit, total = 3, 8
+		limiter := NewSessionConcurrencyLimiter(limit)
+		arrived := make(chan struct{}, total)
+		release := make(chan struct{})
+		r := newRouter(limiter, func(c *gin.Context) {
+			arrived <- struct{}{}
+			<-release
+			c.Status(http.StatusOK)
+		})
+
+		codes := make(chan int, total)
+		var wg sync.WaitGroup
+		for range total {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				codes <- request(r, "session-a").Code
+			}()
+		}
+		// While the first requests hold every slot, the others come back refused
+		for range limit {
+			<-arrived
+		}
+		for range total - limit {
+			assert.Equal(t, http.StatusTooManyRequests, <-codes)
+		}
+		assert.Equal(t, limit, limiter.InFlight("session-a"))
+
+		// Other sessions are not affected
+		otherCode := make(chan int, 1)
+		go func() { otherCode <- request(r, "session-b").Code }()
+		<-arrived
+		close(release)
+		wg.Wait()
+		assert.Equal(t, http.StatusOK, <-otherCode)
+		for range limit {
+			assert.Equal(t, http.StatusOK, <-codes)
+		}
+		assert.Zero(t, limiter.InFlight("session-a"), "finished requests release their slots")
+	})
+
+	t.Run("Panicking handler releases its slot", func(t *testing.T) {
+		limiter := NewSessionConcurrencyLimiter(1)
+		r := newRouter(limiter, func(c *gin.Context) { panic("boom") })
+
+		assert.Equal(t, http.StatusInternalServerError, request(r, "session-p").Code)
+		assert.Zero(t, limiter.InFlight("session-p"))
+		assert.Equal(t, http.StatusInternalServerError, request(r, "session-p").Code, "not 429: the slot was released")
+	})
+
+	t.Run("Requests without a session pass through", func(t *testing.T) {
+		limiter := NewSessionConcurrencyLimiter(0)
+		r := newRouter(limiter, func(c *gin.Context) { c.Status(http.StatusOK) })
+		assert.Equal(t, http.StatusOK, request(r, "").Code)
+	})
+}
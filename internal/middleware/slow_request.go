@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultSlowRequestThreshold is the latency above which a request is logged as slow when config
+// log.slow_request_threshold is unset: high enough that only real outliers show up.
+const DefaultSlowRequestThreshold = 2 * time.Second
+
+// SlowRequestMiddleware logs a "slow_request" warning, with the route, status and duration, for every
+// request that takes longer than threshold (DefaultSlowRequestThreshold when threshold <= 0). Register
+// it first, next to the access log, so the time covers all the other middleware.
+func SlowRequestMiddleware(threshold time.Duration) gin.HandlerFunc {
+	if threshold <= 0 {
+		threshold = DefaultSlowRequestThreshold
+	}
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		duration := time.Since(start)
+		if duration <= threshold {
+			return
+		}
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		logger.WarnContext(c.Request.Context(), "slow_request",
+			"method", c.Request.Method,
+			"route", route,
+			"path", c.Request.URL.Path,
+			"status", c.Writer.Status(),
+			"duration_ms", duration.Milliseconds(),
+			"threshold_ms", threshold.Milliseconds(),
+			"ip", c.ClientIP())
+	}
+}
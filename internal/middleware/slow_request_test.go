@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlowRequestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var logs bytes.Buffer
+	logger.InitWithWriter(&logs, "info", "json")
+	t.Cleanup(func() { logger.Init("info", "text") })
+
+	router := gin.New()
+	router.Use(SlowRequestMiddleware(20 * time.Millisecond))
+	router.GET("/fast", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/slow/:id", func(c *gin.Context) {
+		time.Sleep(40 * time.Millisecond)
+		c.Status(http.StatusAccepted)
+	})
+	get := func(path string) {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	t.Run("Fast requests are not logged", func(t *testing.T) {
+		logs.Reset()
+		get("/fast")
+		assert.NotContains(t, logs.String(), "slow_request")
+	})
+
+	t.Run("Slow requests log route, status and duration", func(t *testing.T) {
+		logs.Reset()
+		get("/slow/42")
+
+		lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+		require.Len(t, lines, 1)
+		var entry struct {
+			Level      string `json:"level"`
+			Msg        string `json:"msg"`
+			Route      string `json:"route"`
+			Path       string `json:"path"`
+			Status     int    `json:"status"`
+			DurationMS int64  `json:"duration_ms"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+		assert.Equal(t, "WARN", entry.Level)
+		assert.Equal(t, "slow_request", entry.Msg)
+		assert.Equal(t, "/slow/:id", entry.Route)
+		assert.Equal(t, "/slow/42", entry.Path)
+		assert.Equal(t, http.StatusAccepted, entry.Status)
+		assert.GreaterOrEqual(t, entry.DurationMS, int64(40))
+	})
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/basepath"
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TermsPath is the page that asks the user to accept the current terms of use.
+const TermsPath = "/terms"
+
+// msgTermsNotAccepted answers requests blocked by TermsMiddleware.
+const msgTermsNotAccepted = "aceite os termos de uso atualizados para continuar"
+
+// TermsMiddleware keeps users who haven't accepted version of the terms of use out of the routes after
+// it, admins included. Use it after AuthMiddleware or AdminWebMiddleware, and register logout, session
+// and the acceptance route outside it.
+//
+// Blocked browser requests are redirected to TermsPath; HTMX requests get HX-Redirect to it and API
+// clients a 403 JSON error carrying the page as "terms_url".
+func TermsMiddleware(version string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, _ := c.Get("user")
+		user, ok := value.(*auth.UserData)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "usuário não autenticado"})
+			return
+		}
+		if user.TermsVersion == version {
+			c.Next()
+			return
+		}
+
+		logger.DebugContext(c.Request.Context(), "Acesso bloqueado até o aceite dos termos de uso", "path", c.Request.URL.Path,
+			"user_id", user.ID, "accepted", user.TermsVersion, "current", version)
+		termsURL := basepath.URL(TermsPath)
+		switch {
+		case c.GetHeader("HX-Request") != "":
+			c.Header("HX-Redirect", termsURL)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": msgTermsNotAccepted, "terms_url": termsURL})
+		case wantsJSON(c):
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": msgTermsNotAccepted, "terms_url": termsURL})
+		default:
+			c.Redirect(http.StatusFound, termsURL)
+			c.Abort()
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTermsMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	get := func(user *auth.UserData, header, value string) *httptest.ResponseRecorder {
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", user)
+			c.Next()
+		})
+		router.Use(TermsMiddleware("2025-02"))
+		router.GET("/protected", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+		req, _ := http.NewRequest(http.MethodGet, "/protected", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	current := &auth.UserData{ID: "1", Role: "user", TermsVersion: "2025-02"}
+	stale := &auth.UserData{ID: "2", Role: "user", TermsVersion: "2025-01"}
+	never := &auth.UserData{ID: "3", Role: "admin"}
+
+	t.Run("Current version passes", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, get(current, "Accept", "application/json").Code)
+	})
+
+	t.Run("Stale version must re-accept", func(t *testing.T) {
+		w := get(stale, "Accept", "application/json")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), `"terms_url":"/terms"`)
+
+		w = get(stale, "Accept", "text/html")
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, TermsPath, w.Header().Get("Location"))
+
+		w = get(stale, "HX-Request", "true")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Equal(t, TermsPath, w.Header().Get("HX-Redirect"))
+	})
+
+	t.Run("Admins who never accepted are not exempt", func(t *testing.T) {
+		assert.Equal(t, http.StatusFound, get(never, "", "").Code)
+	})
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NoTimeoutMiddleware lifts the server's read and write deadlines for the current request, so long-lived
+// responses (SSE, streaming downloads) are not cut off by http.Server's ReadTimeout/WriteTimeout.
+// Register it only on the routes that need it; every other route keeps the global timeouts.
+func NoTimeoutMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rc := http.NewResponseController(c.Writer)
+		// A zero time means no deadline
+		if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			logger.WarnContext(c.Request.Context(), "Falha ao remover prazo de escrita", "error", err, "path", c.Request.URL.Path)
+		}
+		if err := rc.SetReadDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			logger.WarnContext(c.Request.Context(), "Falha ao remover prazo de leitura", "error", err, "path", c.Request.URL.Path)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoTimeoutMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const writeTimeout = 100 * time.Millisecond
+
+	slow := func(c *gin.Context) {
+		time.Sleep(3 * writeTimeout)
+		c.String(http.StatusOK, "done")
+	}
+	r := gin.New()
+	r.GET("/normal", slow)
+	r.GET("/stream", NoTimeoutMiddleware(), slow)
+
+	srv := httptest.NewUnstartedServer(r)
+	srv.Config.WriteTimeout = writeTimeout
+	srv.Start()
+	defer srv.Close()
+
+	t.Run("Normal route times out", func(t *testing.T) {
+		resp, err := http.Get(srv.URL + "/normal")
+		if err == nil {
+			resp.Body.Close()
+		}
+		assert.Error(t, err, "write past the deadline should drop the connection")
+	})
+
+	t.Run("Exempt route runs to completion", func(t *testing.T) {
+		resp, err := http.Get(srv.URL + "/stream")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "done", string(body))
+	})
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/basepath"
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VerifyEmailPath is the page that asks the user to verify their email, with a resend button.
+const VerifyEmailPath = "/verify-email"
+
+// msgEmailNotVerified answers requests blocked by VerifiedEmailMiddleware.
+const msgEmailNotVerified = "verifique seu email para continuar"
+
+// VerifiedEmailMiddleware keeps users whose email is not verified out of the routes after it, except for
+// the given exempt roles (e.g. "admin"). Use it after AuthMiddleware or AdminWebMiddleware, and register
+// logout, session and verification routes outside it.
+//
+// Blocked browser requests are redirected to VerifyEmailPath; HTMX requests get HX-Redirect to it and
+// API clients a 403 JSON error carrying the page as "verify_url".
+func VerifiedEmailMiddleware(exemptRoles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, _ := c.Get("user")
+		user, ok := value.(*auth.UserData)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "usuário não autenticado"})
+			return
+		}
+		if user.EmailVerified || slices.Contains(exemptRoles, user.Role) {
+			c.Next()
+			return
+		}
+
+		logger.DebugContext(c.Request.Context(), "Acesso bloqueado até a verificação do email", "path", c.Request.URL.Path, "user_id", user.ID)
+		verifyURL := basepath.URL(VerifyEmailPath)
+		switch {
+		case c.GetHeader("HX-Request") != "":
+			c.Header("HX-Redirect", verifyURL)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": msgEmailNotVerified, "verify_url": verifyURL})
+		case wantsJSON(c):
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": msgEmailNotVerified, "verify_url": verifyURL})
+		default:
+			c.Redirect(http.StatusFound, verifyURL)
+			c.Abort()
+		}
+	}
+}
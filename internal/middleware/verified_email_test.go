@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifiedEmailMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(user *auth.UserData, exemptRoles ...string) *gin.Engine {
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", user)
+			c.Next()
+		})
+		router.Use(VerifiedEmailMiddleware(exemptRoles...))
+		router.GET("/protected", func(c *gin.Context) { c.Status(http.StatusOK) })
+		return router
+	}
+	get := func(router *gin.Engine, header, value string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, "/protected", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	verified := &auth.UserData{ID: "1", Role: "user", EmailVerified: true}
+	unverified := &auth.UserData{ID: "2", Role: "user"}
+	unverifiedAdmin := &auth.UserData{ID: "3", Role: "admin"}
+
+	t.Run("Verified user passes", func(t *testing.T) {
+		w := get(newRouter(verified), "Accept", "application/json")
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Unverified API client gets 403", func(t *testing.T) {
+		w := get(newRouter(unverified), "Accept", "application/json")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), `"verify_url":"/verify-email"`)
+	})
+
+	t.Run("Unverified browser is redirected", func(t *testing.T) {
+		w := get(newRouter(unverified), "Accept", "text/html")
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, VerifyEmailPath, w.Header().Get("Location"))
+	})
+
+	t.Run("Unverified HTMX request gets HX-Redirect", func(t *testing.T) {
+		w := get(newRouter(unverified), "HX-Request", "true")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Equal(t, VerifyEmailPath, w.Header().Get("HX-Redirect"))
+	})
+
+	t.Run("Exempt roles pass", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, get(newRouter(unverifiedAdmin, "admin"), "", "").Code)
+		assert.Equal(t, http.StatusFound, get(newRouter(unverifiedAdmin), "", "").Code, "admins are only exempt when listed")
+	})
+}
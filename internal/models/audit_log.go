@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// AuditLog records a sensitive action: who did it (ActorID), to whom (TargetID) and from where
+type AuditLog struct {
+	ID        uint      `json:"id"                  gorm:"primaryKey"`
+	ActorID   uint      `json:"actor_id"            gorm:"not null;index"`
+	Action    string    `json:"action"              gorm:"type:varchar(64);not null;index"` // e.g. impersonate.start
+	TargetID  uint      `json:"target_id,omitempty" gorm:"index"`                           // 0 when the action has no target user
+	IP        string    `json:"ip,omitempty"        gorm:"type:varchar(45)"`
+	Details   string    `json:"details,omitempty"   gorm:"type:text"`
+	CreatedAt time.Time `json:"created_at"          gorm:"not null;index"`
+}
+
+// TableName specifies the table name for GORM
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
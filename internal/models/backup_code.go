@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// BackupCode is one of a user's one-time codes for the login verification step, used in place of the
+// code sent by email when it can't be received. Only the code's hash is stored.
+type BackupCode struct {
+	ID        uint       `json:"id"                gorm:"primaryKey"`
+	UserID    uint       `json:"-"                 gorm:"not null;index"`
+	CodeHash  string     `json:"-"                 gorm:"type:varchar(64);not null"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"        gorm:"not null"`
+}
+
+// TableName specifies the table name for GORM
+func (BackupCode) TableName() string {
+	return "backup_codes"
+}
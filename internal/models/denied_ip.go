@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// DeniedIP is a denylist entry: a single IP or a CIDR range whose requests are refused
+type DeniedIP struct {
+	ID        uint      `json:"id"                gorm:"primaryKey"`
+	Value     string    `json:"value"             gorm:"type:varchar(49);not null;uniqueIndex"` // normalized IP or CIDR
+	Reason    string    `json:"reason,omitempty"  gorm:"type:varchar(255)"`
+	CreatedBy uint      `json:"created_by"        gorm:"index"` // admin who added it; 0 when added from config
+	CreatedAt time.Time `json:"created_at"        gorm:"not null"`
+}
+
+// TableName specifies the table name for GORM
+func (DeniedIP) TableName() string {
+	return "denied_ips"
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// Invite lets one person register, even with public registration disabled, and sets the role they get
+type Invite struct {
+	ID        uint       `json:"id"                gorm:"primaryKey"`
+	TokenHash string     `json:"-"                 gorm:"type:varchar(64);not null;uniqueIndex"` // SHA-256 of the link token
+	Email     string     `json:"email,omitempty"   gorm:"type:varchar(255)"`                     // when set, only this address can use it
+	Role      string     `json:"role"              gorm:"not null;default:user"`
+	CreatedBy uint       `json:"created_by"        gorm:"index"`
+	ExpiresAt time.Time  `json:"expires_at"        gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	UsedBy    uint       `json:"used_by,omitempty"` // the user who registered with it
+	CreatedAt time.Time  `json:"created_at"        gorm:"not null"`
+}
+
+// TableName specifies the table name for GORM
+func (Invite) TableName() string {
+	return "invites"
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// LoginAttempt records a single login attempt (successful or not) for auditing and lockout decisions
+type LoginAttempt struct {
+	ID         uint      `json:"id"                   gorm:"primaryKey"`
+	Identifier string    `json:"identifier"           gorm:"type:varchar(255);not null;index"` // username or email as typed
+	IP         string    `json:"ip,omitempty"         gorm:"type:varchar(45);index"`
+	UserAgent  string    `json:"user_agent,omitempty" gorm:"type:varchar(500)"`
+	Success    bool      `json:"success"              gorm:"not null;index"`
+	Reason     string    `json:"reason,omitempty"     gorm:"type:varchar(50)"` // e.g. invalid_credentials, inactive, locked
+	CreatedAt  time.Time `json:"created_at"           gorm:"not null;index"`
+	// Cleared failures no longer count toward the lockout (a later successful login or password reset)
+	Cleared bool `json:"cleared,omitempty" gorm:"not null;default:false"`
+}
+
+// TableName specifies the table name for GORM
+func (LoginAttempt) TableName() string {
+	return "login_attempts"
+}
@@ -19,18 +19,60 @@ type User struct {
 	// Profile information
 	FirstName string `json:"first_name,omitempty"`
 	LastName  string `json:"last_name,omitempty"`
+	AvatarURL string `json:"avatar_url,omitempty"` // optional; Gravatar is used when empty
+	// Phone is an optional E.164 number (validation.ValidatePhone) for account recovery. It is personal
+	// data, so it never goes out with the user's JSON; only the owner and admins see it
+	Phone string `json:"-" gorm:"type:varchar(16)"`
+	// Preferences is a JSON object of user settings, e.g. {"notifications": {"account_locked": false}}
+	Preferences string `json:"-" gorm:"type:text"`
 
 	// Account status
 	Active        bool      `json:"active"         gorm:"default:true"`
 	EmailVerified bool      `json:"email_verified" gorm:"default:false"`
 	LastLogin     time.Time `json:"last_login"`
 	LastActive    time.Time `json:"last_active"`
+	// KnownCountries lists, comma-separated, the ISO country codes the user has logged in from (config
+	// login.new_country_challenge), e.g. "BR,PT"
+	KnownCountries string `json:"-" gorm:"type:varchar(255)"`
+
+	// TermsVersion is the version of the terms of use (config terms.version) the user last accepted, at TermsAcceptedAt
+	TermsVersion    string     `json:"terms_version,omitempty" gorm:"type:varchar(64)"`
+	TermsAcceptedAt *time.Time `json:"terms_accepted_at,omitempty"`
+
+	// MustChangePassword sends the user to change the password right after login; cleared by any password change
+	MustChangePassword bool `json:"must_change_password" gorm:"default:false"`
+	// PasswordChangedAt is when the password was last set (registration, reset or change); nil for accounts
+	// created before it was tracked, whose password age counts from CreatedAt
+	PasswordChangedAt *time.Time `json:"password_changed_at,omitempty"`
 
 	// Access control
 	Role        string `json:"role"                  gorm:"default:user"`
 	Permissions string `json:"permissions,omitempty" gorm:"type:text"` // JSON string of permissions
 
+	// Optimistic locking: bumped on every admin edit, exposed to API clients as the ETag
+	Version uint `json:"version" gorm:"not null;default:1"`
+
 	// Password reset (kept separate from session management)
 	ResetToken       string    `json:"-"`
 	ResetTokenExpiry time.Time `json:"-"`
+	// ResetTokenBinding is the hash of the requester's IP or device cookie when reset links are bound (password.reset_binding)
+	ResetTokenBinding string `json:"-"`
+	// LastResetRequestAt is when the user last asked for a reset email, for password.reset_cooldown
+	LastResetRequestAt time.Time `json:"-"`
+
+	// Pending email change: Email stays in use until the token sent to PendingEmail is confirmed
+	PendingEmail      string    `json:"-"`
+	EmailChangeToken  string    `json:"-"`
+	EmailChangeExpiry time.Time `json:"-"`
+
+	// Scheduled self-deletion (config account.deletion_grace_period): the account is inactive until
+	// DeletionScheduledAt, when the janitor deletes it, unless cancelled with the token (hashed) first
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+	DeletionCancelToken string     `json:"-"`
+
+	// Account recovery by an admin, for users who lost access to their email: the hash of the one-time
+	// code, when it expires and how many wrong codes were typed for it
+	RecoveryCodeHash     string    `json:"-"`
+	RecoveryCodeExpiry   time.Time `json:"-"`
+	RecoveryCodeAttempts int       `json:"-"`
 }
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// PasswordHistory keeps a previous password hash of a user, so recent passwords can't be reused
+type PasswordHistory struct {
+	ID           uint      `json:"-" gorm:"primaryKey"`
+	UserID       uint      `json:"-" gorm:"not null;index"`
+	PasswordHash string    `json:"-" gorm:"not null"`
+	CreatedAt    time.Time `json:"-"`
+}
+
+// TableName specifies the table name for GORM
+func (PasswordHistory) TableName() string {
+	return "password_history"
+}
@@ -12,6 +12,13 @@ type Session struct {
 	CreatedAt time.Time `json:"created_at"`
 	UserAgent string    `json:"user_agent,omitempty" gorm:"type:varchar(500)"`
 	IP        string    `json:"ip,omitempty"         gorm:"type:varchar(45)"` // Supports IPv6
+	// ImpersonatedBy is the admin who opened this session as the user; 0 for regular sessions
+	ImpersonatedBy uint `json:"impersonated_by,omitempty" gorm:"index"`
+	// NewLoginAt is when the account was logged into from another device while this session was open,
+	// shown to this session until dismissed (config login.concurrent_login_notice.banner); nil for none
+	NewLoginAt *time.Time `json:"new_login_at,omitempty"`
+	// NewLoginFrom describes that login's device and IP
+	NewLoginFrom string `json:"new_login_from,omitempty" gorm:"type:varchar(200)"`
 }
 
 // TableName specifies the table name for GORM
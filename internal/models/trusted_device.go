@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// TrustedDevice is a browser a user chose to remember at the login verification step, so logins from it
+// skip that step until ExpiresAt. Only the hash of the token kept in the browser's cookie is stored.
+type TrustedDevice struct {
+	ID        uint      `json:"id"         gorm:"primaryKey"`
+	UserID    uint      `json:"-"          gorm:"not null;index"`
+	TokenHash string    `json:"-"          gorm:"type:varchar(64);not null;uniqueIndex"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+}
+
+// TableName specifies the table name for GORM
+func (TrustedDevice) TableName() string {
+	return "trusted_devices"
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// WebAuthnCredential is a passkey a user registered to log in without a password
+type WebAuthnCredential struct {
+	ID           uint       `json:"id"                     gorm:"primaryKey"`
+	UserID       uint       `json:"-"                      gorm:"not null;index"`
+	CredentialID string     `json:"-"                      gorm:"type:varchar(255);not null;uniqueIndex"` // base64url of the authenticator's credential ID
+	Name         string     `json:"name"                   gorm:"type:varchar(100)"`                      // chosen by the user, e.g. "Notebook"
+	Data         string     `json:"-"                      gorm:"type:text;not null"`                     // webauthn.Credential as JSON (public key, sign counter, flags)
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"             gorm:"not null"`
+}
+
+// TableName specifies the table name for GORM
+func (WebAuthnCredential) TableName() string {
+	return "webauthn_credentials"
+}
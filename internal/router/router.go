@@ -2,35 +2,94 @@
 package router
 
 import (
+	"cmp"
+	_ "embed"
 	"net/http"
+	"runtime"
 	"time"
 
 	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/config"
 	"github.com/lucas-varjao/gohtmx/internal/handlers"
 	"github.com/lucas-varjao/gohtmx/internal/middleware"
+	"github.com/lucas-varjao/gohtmx/internal/tracing"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/time/rate"
 )
 
+// Version is reported by GET /health; main sets it to its AppVersion (set via ldflags on release).
+var Version = "dev"
+
+// MaintenancePage renders the 503 page for browsers during a scheduled maintenance window; main sets it.
+// When nil every blocked request gets the JSON error.
+var MaintenancePage func(*gin.Context)
+
+// openAPISpec is the hand-maintained OpenAPI 3 description of the auth API, served at GET /api/openapi.json.
+// TestOpenAPISpec checks its status codes against the handlers.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// startedAt approximates the process start time for the uptime reported by GET /health.
+var startedAt = time.Now()
+
 // SetupRouter configures all routes for the application.
 // If recoveryFn is non-nil, it is used as custom recovery (e.g. to render HTML error pages for 500).
+// adminUserHandler may be nil to skip the /api/admin/users routes; accountHandler may be nil to skip
+// the account data export routes. denylist may be nil when no IP is ever blocked.
 func SetupRouter(
 	authHandler *handlers.AuthHandler,
+	adminUserHandler *handlers.AdminUserHandler,
+	accountHandler *handlers.AccountHandler,
 	authManager *auth.AuthManager,
+	denylist *middleware.IPDenylist,
 	recoveryFn gin.RecoveryFunc,
 ) *gin.Engine {
 	r := gin.New()
 	r.Use(gin.Logger())
+	// Requests slower than log.slow_request_threshold also get a slow_request warning in the app log
+	var slowThreshold time.Duration
+	if cfg := config.GetConfig(); cfg != nil {
+		slowThreshold = cfg.Log.SlowRequestThreshold
+	}
+	r.Use(middleware.SlowRequestMiddleware(slowThreshold))
+	// Before recovery, so error pages are compressed too; small fragments and compressed types go out as they are
+	if cfg := config.GetConfig(); cfg != nil && cfg.Server.Compression.Enabled {
+		r.Use(middleware.CompressionMiddleware(cfg.Server.Compression.MinSize, cfg.Server.Compression.SkipContentTypes...))
+	}
 	if recoveryFn != nil {
 		r.Use(gin.CustomRecovery(recoveryFn))
 	} else {
 		r.Use(gin.Recovery())
 	}
+	// Development aid: the bodies of auth and admin posts in the debug log, secrets redacted
+	if cfg := config.GetConfig(); cfg != nil && cfg.Log.RequestBodies {
+		r.Use(middleware.RequestBodyLogMiddleware("/auth", "/admin", "/api/admin"))
+	}
+
+	// One span per request when an OTLP exporter is configured; otherwise no middleware at all
+	if cfg := config.GetConfig(); cfg != nil && cfg.Tracing.Endpoint != "" {
+		r.Use(tracing.Middleware())
+	}
+
+	// Blocked IPs stop here, before CORS, rate limiting and every handler
+	if denylist != nil {
+		r.Use(middleware.IPDenylistMiddleware(denylist))
+	}
 
 	// Add CORS middleware
 	r.Use(middleware.CorsMiddleware())
 
+	// Lightweight CSRF: reject cross-site state-changing requests when enabled
+	if cfg := config.GetConfig(); cfg != nil && cfg.Security.OriginCheck.Enabled {
+		originCheck := cfg.Security.OriginCheck
+		r.Use(middleware.OriginCheckMiddleware(originCheck.TrustedOrigins, originCheck.RequireHeader))
+	}
+
+	// 503 during the maintenance window (config maintenance); probes and static files stay up
+	r.Use(middleware.MaintenanceMiddleware(MaintenancePage, "/ping", "/health", "/static/", "/maintenance"))
+
 	// Health check routes
 	r.GET("/ping", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
@@ -38,12 +97,25 @@ func SetupRouter(
 		})
 	})
 
+	// Liveness only: never touches the database, so it stays cheap enough to poll often
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
-			"status": "ok",
+			"status":         "ok",
+			"version":        Version,
+			"go_version":     runtime.Version(),
+			"started_at":     startedAt.UTC().Format(time.RFC3339),
+			"uptime_seconds": time.Since(startedAt).Seconds(),
 		})
 	})
 
+	// Machine-readable contract of the auth API for client codegen; public, like the docs it feeds
+	r.GET("/api/openapi.json", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", openAPISpec)
+	})
+
+	// Public feature flags, read by clients before login (e.g. whether to offer sign-up)
+	r.GET("/api/features", authHandler.Features)
+
 	// Rate limiter for auth routes (brute force prevention)
 	const authBurst = 3
 	authLimiter := middleware.NewIPRateLimiter(rate.Limit(1), authBurst, time.Hour)
@@ -52,31 +124,129 @@ func SetupRouter(
 	authRoutes := r.Group("/auth")
 	authRoutes.Use(middleware.RateLimitMiddleware(authLimiter))
 	authRoutes.POST("/login", authHandler.Login)
+	authRoutes.POST("/login/verify", authHandler.VerifyLoginChallenge)
 	authRoutes.POST("/register", authHandler.Register)
 	authRoutes.POST("/password-reset-request", authHandler.RequestPasswordReset)
 	authRoutes.POST("/password-reset", authHandler.ResetPassword)
+	authRoutes.GET("/confirm-email", authHandler.ConfirmEmailChange)
+	authRoutes.POST("/cancel-deletion", authHandler.CancelAccountDeletion)
+	authRoutes.POST("/recover", authHandler.RecoverAccount)
+	authRoutes.POST("/passkey/login/begin", authHandler.BeginPasskeyLogin)
+	authRoutes.POST("/passkey/login/finish", authHandler.FinishPasskeyLogin)
 
-	// Rate limiter for API (more permissive)
-	const apiBurst = 20
-	const apiRatePerSec = 10
-	apiLimiter := middleware.NewIPRateLimiter(rate.Limit(apiRatePerSec), apiBurst, time.Hour)
+	// Availability check (register form, on blur): own limiter so it doesn't consume login/register tokens
+	const availabilityBurst = 10
+	const availabilityRatePerSec = 2
+	availabilityLimiter := middleware.NewIPRateLimiter(rate.Limit(availabilityRatePerSec), availabilityBurst, time.Hour)
+	r.GET("/auth/available", middleware.RateLimitMiddleware(availabilityLimiter), authHandler.CheckAvailability)
+
+	// Rate limiters for API (more permissive): requests with a valid session get a more generous tier
+	// than anonymous ones (config api.rate_limit)
+	const (
+		apiAnonymousBurst          = 20
+		apiAnonymousRatePerSec     = 10
+		apiAuthenticatedBurst      = 40
+		apiAuthenticatedRatePerSec = 20
+	)
+	var apiLimits config.APIRateLimitConfig
+	if cfg := config.GetConfig(); cfg != nil {
+		apiLimits = cfg.API.RateLimit
+	}
+	anonymousLimiter := middleware.NewIPRateLimiter(rate.Limit(cmp.Or(apiLimits.Anonymous.RatePerSecond, apiAnonymousRatePerSec)),
+		cmp.Or(apiLimits.Anonymous.Burst, apiAnonymousBurst), time.Hour)
+	authenticatedLimiter := middleware.NewIPRateLimiter(rate.Limit(cmp.Or(apiLimits.Authenticated.RatePerSecond, apiAuthenticatedRatePerSec)),
+		cmp.Or(apiLimits.Authenticated.Burst, apiAuthenticatedBurst), time.Hour)
 
 	// Protected routes
 	api := r.Group("/api")
-	api.Use(middleware.RateLimitMiddleware(apiLimiter))
+	api.Use(middleware.TieredRateLimitMiddleware(anonymousLimiter, authenticatedLimiter, authManager))
+	// The API only answers JSON: clients asking for anything else get 406 instead of a JSON body they can't read
+	api.Use(middleware.AcceptMiddleware(middleware.MIMEJSON))
 	api.Use(middleware.AuthMiddleware(authManager))
-	api.GET("/protected", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"message": "Esta é uma rota protegida"})
-	})
+	// One session can only have so many requests in flight, wherever they come from
+	if cfg := config.GetConfig(); cfg != nil && cfg.Session.MaxInFlight > 0 {
+		api.Use(middleware.SessionConcurrencyMiddleware(middleware.NewSessionConcurrencyLimiter(cfg.Session.MaxInFlight)))
+	}
+	// Open to users with an unverified email even when the verified email gate is on: who they are,
+	// logging out, the session, and the two ways to verify (resend the link, fix a mistyped email)
 	api.GET("/me", authHandler.GetCurrentUser)
+	api.GET("/me/sessions/count", authHandler.CountSessions)
+	api.GET("/me/session", authHandler.SessionDetails)
 	api.POST("/logout", authHandler.Logout)
+	api.GET("/session", authHandler.SessionStatus)
+	api.GET("/session/ping", authHandler.PingSession)
+	api.POST("/session/extend", authHandler.ExtendSession)
+	api.POST("/session/new-login/dismiss", authHandler.DismissNewLogin)
+	api.POST("/account/verify-email", authHandler.ResendVerification)
+	// Account-sensitive actions are off limits to an admin impersonating the user
+	noImpersonation := middleware.ForbidImpersonationMiddleware()
+	api.POST("/account/email", noImpersonation, authHandler.RequestEmailChange)
+	api.POST("/account/terms", noImpersonation, authHandler.AcceptTerms)
+	api.POST("/account/delete", noImpersonation, authHandler.RequestAccountDeletion)
+	api.POST("/sessions/revoke-others", noImpersonation, authHandler.RevokeOtherSessions)
+	api.GET("/passkeys", authHandler.ListPasskeys)
+	api.POST("/passkeys/register/begin", noImpersonation, authHandler.BeginPasskeyRegistration)
+	api.POST("/passkeys/register/finish", noImpersonation, authHandler.FinishPasskeyRegistration)
+	api.DELETE("/passkeys/:id", noImpersonation, authHandler.DeletePasskey)
+	api.POST("/2fa/backup-codes", noImpersonation, authHandler.RegenerateBackupCodes)
+
+	// Everything below requires a verified email when login.verified_email_gate is enabled,
+	// and the current terms of use accepted when terms.version is set
+	verified := api.Group("")
+	if gate := VerifiedEmailGate(); gate != nil {
+		verified.Use(gate)
+	}
+	if gate := TermsGate(); gate != nil {
+		verified.Use(gate)
+	}
+	verified.GET("/protected", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "Esta é uma rota protegida"})
+	})
+	verified.POST("/change-password", noImpersonation, authHandler.ChangePassword)
+	if accountHandler != nil {
+		verified.GET("/account/export", noImpersonation, accountHandler.ExportOwn)
+	}
 
 	// Admin only routes
-	admin := api.Group("/admin")
-	admin.Use(middleware.RoleMiddleware("admin"))
+	admin := verified.Group("/admin")
+	admin.Use(middleware.RoleMiddleware("admin"), middleware.AdminReadOnlyMiddleware(nil))
 	admin.GET("/dashboard", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "Admin Dashboard"})
 	})
+	if adminUserHandler != nil {
+		admin.GET("/users", adminUserHandler.ListUsers)
+		admin.POST("/users", adminUserHandler.CreateUser)
+		admin.GET("/users/:id", adminUserHandler.GetUser)
+		admin.PATCH("/users/:id", adminUserHandler.UpdateUser)
+		admin.DELETE("/users/:id", adminUserHandler.DeleteUser)
+	}
+	if accountHandler != nil {
+		admin.GET("/users/:id/export", accountHandler.ExportUser)
+	}
 
 	return r
 }
+
+// VerifiedEmailGate returns the middleware that keeps unverified users out of protected routes, or nil
+// when config login.verified_email_gate is off. Admins are exempt with exempt_admins.
+func VerifiedEmailGate() gin.HandlerFunc {
+	cfg := config.GetConfig()
+	if cfg == nil || !cfg.Login.VerifiedEmailGate.Enabled {
+		return nil
+	}
+	var exempt []string
+	if cfg.Login.VerifiedEmailGate.ExemptAdmins {
+		exempt = append(exempt, "admin")
+	}
+	return middleware.VerifiedEmailMiddleware(exempt...)
+}
+
+// TermsGate returns the middleware that sends users who haven't accepted the current terms of use to
+// the acceptance page, or nil when config terms.version is empty.
+func TermsGate() gin.HandlerFunc {
+	cfg := config.GetConfig()
+	if !cfg.Features().Terms {
+		return nil
+	}
+	return middleware.TermsMiddleware(cfg.Terms.Version)
+}
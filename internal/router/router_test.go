@@ -3,13 +3,19 @@ package router
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/lucas-varjao/gohtmx/internal/auth"
 	gormadapter "github.com/lucas-varjao/gohtmx/internal/auth/adapter/gorm"
+	"github.com/lucas-varjao/gohtmx/internal/config"
 	"github.com/lucas-varjao/gohtmx/internal/handlers"
 	"github.com/lucas-varjao/gohtmx/internal/models"
 	"github.com/lucas-varjao/gohtmx/internal/service"
@@ -33,6 +39,10 @@ func (m *MockAuthService) Login(username, password, ip, userAgent string) (*serv
 	}, nil
 }
 
+func (m *MockAuthService) LoginFrom(username, password string, metadata auth.SessionMetadata) (*service.LoginResponse, error) {
+	return m.Login(username, password, metadata.IP, metadata.UserAgent)
+}
+
 func (m *MockAuthService) ValidateSession(sessionID string) (*auth.Session, *auth.UserData, error) {
 	return &auth.Session{
 			ID:        sessionID,
@@ -45,26 +55,66 @@ func (m *MockAuthService) ValidateSession(sessionID string) (*auth.Session, *aut
 		}, nil
 }
 
-func (m *MockAuthService) Logout(sessionID string) error {
+func (m *MockAuthService) ExtendSession(sessionID string) (*auth.Session, error) {
+	return &auth.Session{ID: sessionID, UserID: "1", ExpiresAt: time.Now().Add(time.Hour)}, nil
+}
+
+func (m *MockAuthService) Logout(sessionID string, reason auth.LogoutReason) error {
+	return nil
+}
+
+func (m *MockAuthService) LogoutAll(userID string, reason auth.LogoutReason) error {
 	return nil
 }
 
-func (m *MockAuthService) LogoutAll(userID string) error {
+func (m *MockAuthService) LogoutOthers(userID, keepSessionID string) error {
 	return nil
 }
 
+func (m *MockAuthService) CountSessions(userID string) (int64, error) {
+	return 0, nil
+}
+
 func (m *MockAuthService) Register(username, email, password, displayName string) (*models.User, error) {
 	return &models.User{}, nil
 }
 
-func (m *MockAuthService) RequestPasswordReset(email string) error {
+func (m *MockAuthService) RequestPasswordReset(email, binding string) error {
+	return nil
+}
+
+func (m *MockAuthService) ResetPassword(token, newPassword, binding string) error {
 	return nil
 }
 
-func (m *MockAuthService) ResetPassword(token, newPassword string) error {
+func (m *MockAuthService) ChangePassword(userID, currentPassword, newPassword string) error {
 	return nil
 }
 
+func (m *MockAuthService) RequestEmailChange(userID, newEmail string) error {
+	return nil
+}
+
+func (m *MockAuthService) ConfirmEmailChange(token string) (*models.User, error) {
+	return nil, nil
+}
+
+func (m *MockAuthService) ResendVerification(userID string) error {
+	return nil
+}
+
+func (m *MockAuthService) AcceptTerms(userID, version string) error {
+	return nil
+}
+
+func (m *MockAuthService) IsUsernameAvailable(username string) (bool, error) {
+	return true, nil
+}
+
+func (m *MockAuthService) IsEmailAvailable(email string) (bool, error) {
+	return true, nil
+}
+
 func NewMockAuthHandler() *handlers.AuthHandler {
 	mockAuthService := &MockAuthService{}
 	return handlers.NewAuthHandler(mockAuthService)
@@ -87,7 +137,7 @@ func TestSetupRouter(t *testing.T) {
 	// Setup
 	mockAuthHandler := NewMockAuthHandler()
 	mockAuthManager := NewMockAuthManager()
-	router := SetupRouter(mockAuthHandler, mockAuthManager, nil)
+	router := SetupRouter(mockAuthHandler, nil, nil, mockAuthManager, nil, nil)
 
 	// Test cases: only routes that exist in SetupRouter (no GET / in current router)
 	tests := []struct {
@@ -126,7 +176,7 @@ func TestSetupRouter(t *testing.T) {
 			}
 
 			// Check response body
-			var response map[string]string
+			var response map[string]any
 			err := json.Unmarshal(w.Body.Bytes(), &response)
 			if err != nil {
 				t.Fatalf("Failed to unmarshal response: %v", err)
@@ -134,20 +184,56 @@ func TestSetupRouter(t *testing.T) {
 
 			for key, expectedValue := range tt.expectedBody {
 				if actualValue := response[key]; actualValue != expectedValue {
-					t.Errorf("Expected %s to be %s, got %s", key, expectedValue, actualValue)
+					t.Errorf("Expected %s to be %s, got %v", key, expectedValue, actualValue)
 				}
 			}
 		})
 	}
 }
 
+func TestHealthBuildInfo(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := SetupRouter(NewMockAuthHandler(), nil, nil, NewMockAuthManager(), nil, nil)
+
+	health := func() map[string]any {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/health", nil)
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+		var body map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		return body
+	}
+
+	first := health()
+	if first["status"] != "ok" || first["version"] != Version || first["go_version"] != runtime.Version() {
+		t.Errorf("unexpected build info: %v", first)
+	}
+	if _, err := time.Parse(time.RFC3339, fmt.Sprint(first["started_at"])); err != nil {
+		t.Errorf("started_at should be RFC 3339: %v", err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	second := health()
+	before, ok1 := first["uptime_seconds"].(float64)
+	after, ok2 := second["uptime_seconds"].(float64)
+	if !ok1 || !ok2 || after <= before {
+		t.Errorf("uptime should increase, got %v then %v", first["uptime_seconds"], second["uptime_seconds"])
+	}
+}
+
 func TestRateLimiting(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	// Setup
 	mockAuthHandler := NewMockAuthHandler()
 	mockAuthManager := NewMockAuthManager()
-	router := SetupRouter(mockAuthHandler, mockAuthManager, nil)
+	router := SetupRouter(mockAuthHandler, nil, nil, mockAuthManager, nil, nil)
 
 	// Test auth routes rate limiting
 	t.Run("Auth routes rate limiting", func(t *testing.T) {
@@ -179,7 +265,7 @@ func TestProtectedRoutes(t *testing.T) {
 	// Setup
 	mockAuthHandler := NewMockAuthHandler()
 	mockAuthManager := NewMockAuthManager()
-	router := SetupRouter(mockAuthHandler, mockAuthManager, nil)
+	router := SetupRouter(mockAuthHandler, nil, nil, mockAuthManager, nil, nil)
 
 	tests := []struct {
 		name           string
@@ -222,3 +308,176 @@ func TestProtectedRoutes(t *testing.T) {
 		})
 	}
 }
+
+func TestVerifiedEmailGate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	loadConfig := func(yml string) {
+		t.Helper()
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "app.yml"), []byte(yml), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := config.LoadConfigFromPath(dir); err != nil {
+			t.Fatalf("failed to load config: %v", err)
+		}
+	}
+	loadConfig("login:\n  verified_email_gate:\n    enabled: true\n    exempt_admins: true\n")
+	t.Cleanup(func() { loadConfig("login:\n  verified_email_gate:\n    enabled: false\n") })
+
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(&models.User{}, &models.Session{})
+	authManager := auth.NewAuthManager(gormadapter.NewUserAdapter(db), gormadapter.NewSessionAdapter(db), auth.DefaultAuthConfig())
+	router := SetupRouter(NewMockAuthHandler(), nil, nil, authManager, nil, nil)
+
+	sessionFor := func(username, role string, verified bool) string {
+		t.Helper()
+		user := &models.User{Username: username, Email: username + "@example.com", PasswordHash: "hash", Active: true, Role: role, EmailVerified: verified}
+		if err := db.Create(user).Error; err != nil {
+			t.Fatal(err)
+		}
+		session, _, err := authManager.CreateSessionForUser(fmt.Sprint(user.ID), auth.SessionMetadata{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return session.ID
+	}
+	verified := sessionFor("verified", "user", true)
+	unverified := sessionFor("unverified", "user", false)
+	unverifiedAdmin := sessionFor("boss", "admin", false)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		sessionID      string
+		expectedStatus int
+	}{
+		{"Verified user reaches a protected route", "GET", "/api/protected", verified, http.StatusOK},
+		{"Unverified user is blocked", "GET", "/api/protected", unverified, http.StatusForbidden},
+		{"Unverified user can still log out", "POST", "/api/logout", unverified, http.StatusOK},
+		{"Unverified user can ask for a new link", "POST", "/api/account/verify-email", unverified, http.StatusAccepted},
+		{"Admins are exempt", "GET", "/api/admin/dashboard", unverifiedAdmin, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.sessionID)
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestOpenAPISpec(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(&models.User{}, &models.Session{})
+	authManager := auth.NewAuthManager(gormadapter.NewUserAdapter(db), gormadapter.NewSessionAdapter(db), auth.DefaultAuthConfig())
+	router := SetupRouter(NewMockAuthHandler(), nil, nil, authManager, nil, nil)
+
+	user := &models.User{Username: "client", Email: "client@example.com", PasswordHash: "hash", Active: true, Role: "user", EmailVerified: true}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	session, _, err := authManager.CreateSessionForUser(fmt.Sprint(user.ID), auth.SessionMetadata{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/openapi.json", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 without a session, got %d", w.Code)
+	}
+	var spec struct {
+		OpenAPI string                                                   `json:"openapi"`
+		Paths   map[string]map[string]struct{ Responses map[string]any } `json:"paths"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("spec is not valid JSON: %v", err)
+	}
+	if spec.OpenAPI == "" || len(spec.Paths) == 0 {
+		t.Fatalf("expected an OpenAPI document with paths, got %s", w.Body.String())
+	}
+
+	// Every documented operation is a registered route
+	routes := map[string]bool{}
+	for _, route := range router.Routes() {
+		routes[route.Method+" "+route.Path] = true
+	}
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			if key := strings.ToUpper(method) + " " + path; !routes[key] {
+				t.Errorf("documented operation %s is not routed", key)
+			}
+		}
+	}
+
+	// Representative inputs return a status the spec documents for the operation
+	tests := []struct {
+		method     string
+		path       string
+		body       string
+		auth       bool
+		wantStatus int
+	}{
+		{"POST", "/auth/login", `{"username":"client","password":"password123"}`, false, http.StatusOK},
+		{"POST", "/auth/login", `{}`, false, http.StatusBadRequest},
+		{"POST", "/auth/register", `{"username":"newuser","email":"new@example.com","password":"Str0ng!Pass","display_name":"New"}`, false, http.StatusOK},
+		{"POST", "/auth/register", `{"username":"x","email":"bad","password":"weak","display_name":"New"}`, false, http.StatusBadRequest},
+		{"POST", "/auth/password-reset-request", `{"email":"client@example.com"}`, false, http.StatusOK},
+		{"POST", "/auth/password-reset", `{}`, false, http.StatusBadRequest},
+		{"GET", "/auth/confirm-email", "", false, http.StatusBadRequest},
+		{"GET", "/auth/available?username=newuser", "", false, http.StatusOK},
+		{"GET", "/auth/available", "", false, http.StatusBadRequest},
+		{"POST", "/auth/login/verify", `{"challenge":"x","code":"123456"}`, false, http.StatusNotFound},
+		{"POST", "/auth/cancel-deletion", `{"token":"x"}`, false, http.StatusNotFound},
+		{"POST", "/auth/recover", `{"identifier":"x","code":"ABCD-EFGH-JKLM","new_password":"N3w!Passw0rd","confirm_password":"N3w!Passw0rd"}`, false, http.StatusNotFound},
+		{"POST", "/auth/passkey/login/begin", "", false, http.StatusNotFound},
+		{"GET", "/api/features", "", false, http.StatusOK},
+		{"GET", "/api/me", "", false, http.StatusUnauthorized},
+		{"GET", "/api/me", "", true, http.StatusOK},
+		{"GET", "/api/me/session", "", true, http.StatusOK},
+		{"GET", "/api/session", "", true, http.StatusOK},
+		{"GET", "/api/session/ping", "", true, http.StatusNoContent},
+		{"POST", "/api/session/extend", "", true, http.StatusOK},
+		{"POST", "/api/session/new-login/dismiss", "", true, http.StatusNotFound},
+		{"POST", "/api/sessions/revoke-others", "", true, http.StatusOK},
+		{"POST", "/api/account/verify-email", "", true, http.StatusAccepted},
+		{"POST", "/api/account/email", `{}`, true, http.StatusBadRequest},
+		{"POST", "/api/account/terms", "", true, http.StatusNotFound},
+		{"POST", "/api/account/delete", `{"password":"x"}`, true, http.StatusNotFound},
+		{"GET", "/api/passkeys", "", true, http.StatusNotFound},
+		{"POST", "/api/2fa/backup-codes", "", true, http.StatusNotFound},
+		{"POST", "/api/change-password", `{}`, true, http.StatusBadRequest},
+		{"POST", "/api/logout", "", true, http.StatusOK},
+	}
+	for i, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.RemoteAddr = fmt.Sprintf("198.51.100.%d:1234", i+1) // own IP: the auth rate limit has a burst of 3
+			if tt.auth {
+				req.Header.Set("Authorization", "Bearer "+session.ID)
+			}
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			operation, ok := spec.Paths[strings.SplitN(tt.path, "?", 2)[0]][strings.ToLower(tt.method)]
+			if !ok {
+				t.Fatalf("%s %s is not documented", tt.method, tt.path)
+			}
+			if _, ok := operation.Responses[fmt.Sprint(w.Code)]; !ok {
+				t.Errorf("status %d is not documented for %s %s", w.Code, tt.method, tt.path)
+			}
+		})
+	}
+}
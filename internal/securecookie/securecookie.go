@@ -0,0 +1,170 @@
+// Package securecookie stores small typed values in cookies that the client can't forge or read.
+//
+// Values are JSON-encoded, optionally encrypted with AES-GCM and always signed with HMAC-SHA256.
+// The cookie name and an expiry are bound into the signature, so a value can't be replayed under
+// another name or after it expires. Keys are derived from a single secret (security.cookie_secret),
+// separate from the JWT secret.
+package securecookie
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/basepath"
+)
+
+// MinSecretLength is the shortest secret New accepts.
+const MinSecretLength = 32
+
+// maxCookieLength keeps encoded values under the common 4096-byte browser limit (name and attributes included).
+const maxCookieLength = 3800
+
+var (
+	ErrSecretTooShort = errors.New("segredo de cookie deve ter pelo menos 32 bytes")
+	ErrInvalid        = errors.New("cookie inválido ou adulterado")
+	ErrExpired        = errors.New("cookie expirado")
+	ErrTooLong        = errors.New("valor grande demais para um cookie")
+)
+
+// SecureCookie encodes and decodes signed cookie values. Safe for concurrent use.
+type SecureCookie struct {
+	hashKey []byte
+	aead    cipher.AEAD // nil when encryption is off
+	now     func() time.Time
+}
+
+// New derives the signing (and, when encrypt is set, encryption) keys from secret.
+func New(secret string, encrypt bool) (*SecureCookie, error) {
+	if len(secret) < MinSecretLength {
+		return nil, ErrSecretTooShort
+	}
+	s := &SecureCookie{hashKey: deriveKey(secret, "hash"), now: time.Now}
+	if encrypt {
+		block, err := aes.NewCipher(deriveKey(secret, "encrypt"))
+		if err != nil {
+			return nil, err
+		}
+		if s.aead, err = cipher.NewGCM(block); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// deriveKey gives each purpose its own 32-byte key, so the MAC key never doubles as the cipher key.
+func deriveKey(secret, purpose string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("gohtmx-securecookie-" + purpose))
+	return mac.Sum(nil)
+}
+
+// Encode serializes value for the cookie name, valid for ttl.
+func (s *SecureCookie) Encode(name string, value any, ttl time.Duration) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	if s.aead != nil {
+		nonce := make([]byte, s.aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return "", err
+		}
+		data = s.aead.Seal(nonce, nonce, data, []byte(name))
+	}
+
+	// payload = expiry (unix seconds, big-endian) || data; the MAC covers name and payload
+	payload := binary.BigEndian.AppendUint64(nil, uint64(s.now().Add(ttl).Unix()))
+	payload = append(payload, data...)
+	encoded := base64.RawURLEncoding.EncodeToString(append(payload, s.sign(name, payload)...))
+	if len(encoded) > maxCookieLength {
+		return "", ErrTooLong
+	}
+	return encoded, nil
+}
+
+// Decode verifies the cookie value for name and unmarshals it into dst.
+// Returns ErrInvalid for tampered or malformed values and ErrExpired after the ttl given to Encode.
+func (s *SecureCookie) Decode(name, encoded string, dst any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(raw) < 8+sha256.Size {
+		return ErrInvalid
+	}
+	payload, sig := raw[:len(raw)-sha256.Size], raw[len(raw)-sha256.Size:]
+	if !hmac.Equal(sig, s.sign(name, payload)) {
+		return ErrInvalid
+	}
+	if s.now().Unix() > int64(binary.BigEndian.Uint64(payload[:8])) {
+		return ErrExpired
+	}
+
+	data := payload[8:]
+	if s.aead != nil {
+		nonceSize := s.aead.NonceSize()
+		if len(data) < nonceSize {
+			return ErrInvalid
+		}
+		if data, err = s.aead.Open(nil, data[:nonceSize], data[nonceSize:], []byte(name)); err != nil {
+			return ErrInvalid
+		}
+	}
+	if err := json.Unmarshal(data, dst); err != nil {
+		return ErrInvalid
+	}
+	return nil
+}
+
+func (s *SecureCookie) sign(name string, payload []byte) []byte {
+	mac := hmac.New(sha256.New, s.hashKey)
+	mac.Write([]byte(name))
+	mac.Write([]byte{0})
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// SetCookie encodes value and writes it as an HttpOnly, Secure, SameSite=Lax cookie scoped to the app's base path.
+func (s *SecureCookie) SetCookie(w http.ResponseWriter, name string, value any, ttl time.Duration) error {
+	encoded, err := s.Encode(name, value, ttl)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    encoded,
+		Path:     basepath.CookiePath(),
+		MaxAge:   int(ttl.Seconds()),
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// ReadCookie decodes the named cookie from r into dst. A missing cookie returns http.ErrNoCookie.
+func (s *SecureCookie) ReadCookie(r *http.Request, name string, dst any) error {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return err
+	}
+	return s.Decode(name, cookie.Value, dst)
+}
+
+// ClearCookie deletes the named cookie.
+func ClearCookie(w http.ResponseWriter, name string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Path:     basepath.CookiePath(),
+		MaxAge:   -1,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,125 @@
+package securecookie
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+type flash struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+func TestNew_RejectsShortSecret(t *testing.T) {
+	_, err := New("short", false)
+	assert.ErrorIs(t, err, ErrSecretTooShort)
+}
+
+func TestSecureCookie_RoundTrip(t *testing.T) {
+	for _, encrypt := range []bool{false, true} {
+		sc, err := New(testSecret, encrypt)
+		require.NoError(t, err)
+
+		in := flash{Kind: "success", Message: "Usuário criado"}
+		encoded, err := sc.Encode("flash", in, time.Minute)
+		require.NoError(t, err)
+
+		var out flash
+		require.NoError(t, sc.Decode("flash", encoded, &out))
+		assert.Equal(t, in, out)
+
+		if encrypt {
+			raw, _ := base64.RawURLEncoding.DecodeString(encoded)
+			assert.NotContains(t, string(raw), "Usuário criado", "encrypted payload must not be readable")
+		}
+
+		var token string
+		encoded, err = sc.Encode("csrf", "tok-123", time.Minute)
+		require.NoError(t, err)
+		require.NoError(t, sc.Decode("csrf", encoded, &token))
+		assert.Equal(t, "tok-123", token)
+	}
+}
+
+func TestSecureCookie_TamperDetection(t *testing.T) {
+	for _, encrypt := range []bool{false, true} {
+		sc, err := New(testSecret, encrypt)
+		require.NoError(t, err)
+		encoded, err := sc.Encode("flash", flash{Message: "oi"}, time.Minute)
+		require.NoError(t, err)
+
+		raw, _ := base64.RawURLEncoding.DecodeString(encoded)
+		for i := range raw {
+			tampered := append([]byte(nil), raw...)
+			tampered[i] ^= 0x01
+			var out flash
+			assert.ErrorIs(t, sc.Decode("flash", base64.RawURLEncoding.EncodeToString(tampered), &out), ErrInvalid, "byte %d", i)
+		}
+
+		var out flash
+		assert.ErrorIs(t, sc.Decode("other", encoded, &out), ErrInvalid, "value is bound to the cookie name")
+		assert.ErrorIs(t, sc.Decode("flash", "not base64!", &out), ErrInvalid)
+		assert.ErrorIs(t, sc.Decode("flash", "", &out), ErrInvalid)
+
+		other, err := New(strings.Repeat("x", MinSecretLength), encrypt)
+		require.NoError(t, err)
+		assert.ErrorIs(t, other.Decode("flash", encoded, &out), ErrInvalid, "another secret must not verify")
+	}
+}
+
+func TestSecureCookie_Expiry(t *testing.T) {
+	sc, err := New(testSecret, true)
+	require.NoError(t, err)
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	sc.now = func() time.Time { return now }
+
+	encoded, err := sc.Encode("flash", flash{Message: "oi"}, time.Minute)
+	require.NoError(t, err)
+
+	var out flash
+	now = now.Add(59 * time.Second)
+	require.NoError(t, sc.Decode("flash", encoded, &out))
+
+	now = now.Add(2 * time.Second)
+	assert.ErrorIs(t, sc.Decode("flash", encoded, &out), ErrExpired)
+}
+
+func TestSecureCookie_TooLong(t *testing.T) {
+	sc, err := New(testSecret, false)
+	require.NoError(t, err)
+	_, err = sc.Encode("big", strings.Repeat("a", 4096), time.Minute)
+	assert.ErrorIs(t, err, ErrTooLong)
+}
+
+func TestSecureCookie_HTTP(t *testing.T) {
+	sc, err := New(testSecret, false)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	require.NoError(t, sc.SetCookie(w, "flash", flash{Message: "oi"}, time.Minute))
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.True(t, cookies[0].HttpOnly)
+	assert.True(t, cookies[0].Secure)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookies[0])
+	var out flash
+	require.NoError(t, sc.ReadCookie(req, "flash", &out))
+	assert.Equal(t, "oi", out.Message)
+
+	assert.ErrorIs(t, sc.ReadCookie(httptest.NewRequest(http.MethodGet, "/", nil), "flash", &out), http.ErrNoCookie)
+
+	w = httptest.NewRecorder()
+	ClearCookie(w, "flash")
+	assert.Equal(t, -1, w.Result().Cookies()[0].MaxAge)
+}
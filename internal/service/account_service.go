@@ -0,0 +1,219 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+	"github.com/lucas-varjao/gohtmx/internal/validation"
+
+	"gorm.io/gorm"
+)
+
+// AccountExport is the personal data bundle of a user (data-subject access request).
+// It never carries password hashes, reset/confirmation tokens or session IDs.
+type AccountExport struct {
+	GeneratedAt   time.Time                  `json:"generated_at"`
+	Profile       ExportProfile              `json:"profile"`
+	Preferences   map[string]json.RawMessage `json:"preferences"`
+	Sessions      []ExportSession            `json:"sessions"`
+	LoginAttempts []ExportLoginAttempt       `json:"login_attempts"`
+	AuditEntries  []ExportAuditEntry         `json:"audit_entries"`
+}
+
+// ExportProfile holds the user's profile and account status fields.
+type ExportProfile struct {
+	ID            uint      `json:"id"`
+	Username      string    `json:"username"`
+	Email         string    `json:"email"`
+	PendingEmail  string    `json:"pending_email,omitempty"`
+	DisplayName   string    `json:"display_name"`
+	FirstName     string    `json:"first_name,omitempty"`
+	LastName      string    `json:"last_name,omitempty"`
+	AvatarURL     string    `json:"avatar_url,omitempty"`
+	Phone         string    `json:"phone,omitempty"`
+	Role          string    `json:"role"`
+	Active        bool      `json:"active"`
+	EmailVerified bool      `json:"email_verified"`
+	LastLogin     time.Time `json:"last_login"`
+	LastActive    time.Time `json:"last_active"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// ExportSession is session metadata; the session ID is a credential and is left out.
+type ExportSession struct {
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	IP        string    `json:"ip,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+}
+
+// ExportLoginAttempt is a login attempt made with the user's username or email.
+type ExportLoginAttempt struct {
+	CreatedAt time.Time `json:"created_at"`
+	IP        string    `json:"ip,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Success   bool      `json:"success"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+// ExportAuditEntry is an audit log entry where the user is the actor or the target.
+type ExportAuditEntry struct {
+	CreatedAt time.Time `json:"created_at"`
+	Action    string    `json:"action"`
+	ActorID   uint      `json:"actor_id"`
+	TargetID  uint      `json:"target_id,omitempty"`
+	IP        string    `json:"ip,omitempty"` // only for the user's own actions, never an admin's
+}
+
+// AccountServiceInterface defines account operations a user runs on their own data.
+type AccountServiceInterface interface {
+	Export(userID string) (*AccountExport, error)
+}
+
+// AccountService gathers a user's data across tables (profile, sessions, login attempts, audit log).
+type AccountService struct {
+	db *gorm.DB
+}
+
+// NewAccountService creates a new AccountService instance
+func NewAccountService(db *gorm.DB) *AccountService {
+	return &AccountService{db: db}
+}
+
+// Export collects the user's profile, preferences, sessions and login attempts.
+func (s *AccountService) Export(userID string) (*AccountExport, error) {
+	id, err := strconv.ParseUint(userID, 10, 64)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	var user models.User
+	if err := s.db.First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	export := &AccountExport{
+		GeneratedAt: time.Now().UTC(),
+		Profile: ExportProfile{
+			ID:            user.ID,
+			Username:      user.Username,
+			Email:         user.Email,
+			PendingEmail:  user.PendingEmail,
+			DisplayName:   user.DisplayName,
+			FirstName:     user.FirstName,
+			LastName:      user.LastName,
+			AvatarURL:     user.AvatarURL,
+			Phone:         user.Phone,
+			Role:          user.Role,
+			Active:        user.Active,
+			EmailVerified: user.EmailVerified,
+			LastLogin:     user.LastLogin,
+			LastActive:    user.LastActive,
+			CreatedAt:     user.CreatedAt,
+			UpdatedAt:     user.UpdatedAt,
+		},
+		Preferences:   decodePreferences(user.Preferences),
+		Sessions:      []ExportSession{},
+		LoginAttempts: []ExportLoginAttempt{},
+		AuditEntries:  []ExportAuditEntry{},
+	}
+
+	var sessions []models.Session
+	if err := s.db.Where("user_id = ?", user.ID).Order("created_at DESC").Find(&sessions).Error; err != nil {
+		logger.Error("Erro ao buscar sessões para exportação", "error", err, "user_id", user.ID)
+		return nil, err
+	}
+	for _, session := range sessions {
+		export.Sessions = append(export.Sessions, ExportSession{
+			CreatedAt: session.CreatedAt,
+			ExpiresAt: session.ExpiresAt,
+			IP:        session.IP,
+			UserAgent: session.UserAgent,
+		})
+	}
+
+	var attempts []models.LoginAttempt
+	if err := s.db.Where("LOWER(identifier) IN ?", []string{strings.ToLower(user.Username), strings.ToLower(user.Email)}).
+		Order("created_at DESC").Find(&attempts).Error; err != nil {
+		logger.Error("Erro ao buscar tentativas de login para exportação", "error", err, "user_id", user.ID)
+		return nil, err
+	}
+	for _, a := range attempts {
+		export.LoginAttempts = append(export.LoginAttempts, ExportLoginAttempt{
+			CreatedAt: a.CreatedAt,
+			IP:        a.IP,
+			UserAgent: a.UserAgent,
+			Success:   a.Success,
+			Reason:    a.Reason,
+		})
+	}
+
+	entries, err := NewAuditService(s.db).ForUser(user.ID)
+	if err != nil {
+		logger.Error("Erro ao buscar auditoria para exportação", "error", err, "user_id", user.ID)
+		return nil, err
+	}
+	for _, e := range entries {
+		entry := ExportAuditEntry{
+			CreatedAt: e.CreatedAt,
+			Action:    e.Action,
+			ActorID:   e.ActorID,
+			TargetID:  e.TargetID,
+		}
+		if e.ActorID == user.ID {
+			entry.IP = e.IP
+		}
+		export.AuditEntries = append(export.AuditEntries, entry)
+	}
+
+	logger.Info("Dados da conta exportados", "user_id", user.ID)
+	return export, nil
+}
+
+// Phone returns the user's phone number, "" when none is set.
+func (s *AccountService) Phone(userID string) (string, error) {
+	id, err := strconv.ParseUint(userID, 10, 64)
+	if err != nil {
+		return "", ErrUserNotFound
+	}
+	var user models.User
+	if err := s.db.Select("id", "phone").First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrUserNotFound
+		}
+		return "", err
+	}
+	return user.Phone, nil
+}
+
+// SetPhone saves the user's phone number after validation.NormalizePhone, returning the stored value;
+// "" removes it. A number that isn't E.164 returns a *ValidationError.
+func (s *AccountService) SetPhone(userID, phone string) (string, error) {
+	id, err := strconv.ParseUint(userID, 10, 64)
+	if err != nil {
+		return "", ErrUserNotFound
+	}
+	phone = validation.NormalizePhone(phone)
+	if err := validation.ValidatePhone(phone); err != nil {
+		return "", &ValidationError{Err: err, Fields: validation.FieldErrors{validation.FieldPhone: err.Error()}}
+	}
+
+	result := s.db.Model(&models.User{}).Where("id = ?", id).Update("phone", phone)
+	if result.Error != nil {
+		logger.Error("Erro ao salvar telefone", "error", result.Error, "user_id", id)
+		return "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return "", ErrUserNotFound
+	}
+	logger.Info("Telefone atualizado", "user_id", id, "removed", phone == "")
+	return phone, nil
+}
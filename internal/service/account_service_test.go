@@ -0,0 +1,95 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/lucas-varjao/gohtmx/internal/models"
+	"github.com/lucas-varjao/gohtmx/internal/validation"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountService_SetPhone(t *testing.T) {
+	_, _, _, _, _, db := setupTest(t)
+	accounts := NewAccountService(db)
+	user := createTestUser(t, db)
+	userID := idString(user.ID)
+
+	phone, err := accounts.SetPhone(userID, "+55 (11) 98765-4321")
+	require.NoError(t, err)
+	assert.Equal(t, "+5511987654321", phone, "stored normalized")
+	stored, err := accounts.Phone(userID)
+	require.NoError(t, err)
+	assert.Equal(t, "+5511987654321", stored)
+
+	_, err = accounts.SetPhone(userID, "call me maybe")
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, validation.ErrPhoneInvalid.Error(), validationErr.Fields[validation.FieldPhone])
+	stored, err = accounts.Phone(userID)
+	require.NoError(t, err)
+	assert.Equal(t, "+5511987654321", stored, "an invalid number keeps the previous one")
+
+	export, err := accounts.Export(userID)
+	require.NoError(t, err)
+	assert.Equal(t, "+5511987654321", export.Profile.Phone, "the owner's export includes it")
+
+	var model models.User
+	require.NoError(t, db.First(&model, user.ID).Error)
+	body, err := json.Marshal(model)
+	require.NoError(t, err)
+	assert.NotContains(t, string(body), "98765", "the user's JSON never carries the phone")
+
+	phone, err = accounts.SetPhone(userID, "")
+	require.NoError(t, err)
+	assert.Empty(t, phone)
+	stored, err = accounts.Phone(userID)
+	require.NoError(t, err)
+	assert.Empty(t, stored, "empty removes the phone")
+
+	_, err = accounts.SetPhone("999", "+5511987654321")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestAccountService_ExportIncludesPreferences(t *testing.T) {
+	_, _, _, _, _, db := setupTest(t)
+	accounts := NewAccountService(db)
+	user := createTestUser(t, db)
+
+	export, err := accounts.Export(idString(user.ID))
+	require.NoError(t, err)
+	assert.Empty(t, export.Preferences)
+
+	require.NoError(t, NewNotificationService(db).SetPreferences(user.ID, map[string]bool{}))
+	export, err = accounts.Export(idString(user.ID))
+	require.NoError(t, err)
+	body, err := json.Marshal(export)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"preferences":{"notifications":{`)
+	assert.Contains(t, string(body), `"account_locked":false`)
+}
+
+func TestAccountService_ExportKeepsAdminIPs(t *testing.T) {
+	_, _, _, _, _, db := setupTest(t)
+	accounts := NewAccountService(db)
+	user := createTestUser(t, db)
+	audit := NewAuditService(db)
+
+	require.NoError(t, audit.Record(&models.AuditLog{Action: AuditActionImpersonateStart, ActorID: 99, TargetID: user.ID, IP: "10.9.9.9"}))
+	require.NoError(t, audit.Record(&models.AuditLog{Action: AuditActionBackupCodesCreate, ActorID: user.ID, TargetID: user.ID, IP: "10.0.0.1"}))
+
+	export, err := accounts.Export(idString(user.ID))
+	require.NoError(t, err)
+	require.Len(t, export.AuditEntries, 2)
+	for _, entry := range export.AuditEntries {
+		switch entry.ActorID {
+		case user.ID:
+			assert.Equal(t, "10.0.0.1", entry.IP, "the user's own actions keep their IP")
+		default:
+			assert.Empty(t, entry.IP, "an admin's IP is never exported")
+		}
+	}
+}
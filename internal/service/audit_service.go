@@ -0,0 +1,107 @@
+package service
+
+import (
+	"strconv"
+
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Actions stored in models.AuditLog.Action.
+const (
+	AuditActionImpersonateStart = "impersonate.start"
+	AuditActionImpersonateStop  = "impersonate.stop"
+	// AuditActionVerificationBatch is an admin resending verification emails to unverified users
+	AuditActionVerificationBatch = "verification.resend_batch"
+	// AuditActionIPDeny is an admin adding an IP or CIDR range to the denylist (Details holds the entry)
+	AuditActionIPDeny = "ip.deny"
+	// AuditActionIPAllow is an admin removing an entry from the denylist
+	AuditActionIPAllow = "ip.allow"
+	// AuditActionInviteCreate is an admin creating a registration invite (Details holds its role and email)
+	AuditActionInviteCreate = "invite.create"
+	// AuditActionLogout is sessions ending through logout (Details holds the auth.LogoutReason and, when every
+	// session of the user ended at once, scope=all)
+	AuditActionLogout = "session.logout"
+	// AuditActionSessionCreate is a session opened by login or impersonation (the actor is the impersonating admin)
+	AuditActionSessionCreate = "session.create"
+	// AuditActionUserCreate is an admin creating a user account (Details holds its role)
+	AuditActionUserCreate = "user.create"
+	// AuditActionCaptchaBypass is a registration exempted from the CAPTCHA (Details holds how: via=ip or via=secret)
+	AuditActionCaptchaBypass = "captcha.bypass"
+	// AuditActionRecoveryCodeCreate is an admin creating an account recovery code for a user (Details holds its expiry)
+	AuditActionRecoveryCodeCreate = "recovery_code.create"
+	// AuditActionRecoveryCodeUse is a user setting a new password with a recovery code
+	AuditActionRecoveryCodeUse = "recovery_code.use"
+	// AuditActionBackupCodesCreate is a user generating a new set of login backup codes
+	AuditActionBackupCodesCreate = "backup_codes.create"
+)
+
+// AuditRecorder persists audit entries.
+type AuditRecorder interface {
+	Record(entry *models.AuditLog) error
+}
+
+// AuditService stores and queries the audit log.
+type AuditService struct {
+	db *gorm.DB
+}
+
+// NewAuditService creates a new AuditService instance
+func NewAuditService(db *gorm.DB) *AuditService {
+	return &AuditService{db: db}
+}
+
+// Record saves an audit entry. Failures are logged with the entry so the action is never lost silently.
+func (s *AuditService) Record(entry *models.AuditLog) error {
+	if err := s.db.Create(entry).Error; err != nil {
+		logger.Error("Erro ao registrar auditoria", "error", err, "action", entry.Action,
+			"actor_id", entry.ActorID, "target_id", entry.TargetID)
+		return err
+	}
+	return nil
+}
+
+// RecordLogouts records an AuditActionLogout entry each time authManager ends sessions. The user is the
+// target, and also the actor when they logged out themselves; other reasons (an admin, a password reset)
+// have no actor. Call it during setup, before serving requests.
+func (s *AuditService) RecordLogouts(authManager *auth.AuthManager) {
+	authManager.OnLogout(func(userID, sessionID string, reason auth.LogoutReason) {
+		target, _ := strconv.ParseUint(userID, 10, 64)
+		entry := &models.AuditLog{Action: AuditActionLogout, TargetID: uint(target), Details: "reason=" + string(reason)}
+		if reason == auth.LogoutReasonUser || reason == auth.LogoutReasonOtherDevices {
+			entry.ActorID = uint(target)
+		}
+		switch {
+		case reason == auth.LogoutReasonOtherDevices:
+			entry.Details += " scope=others"
+		case sessionID == "":
+			entry.Details += " scope=all"
+		}
+		_ = s.Record(entry)
+	})
+}
+
+// RecordSessions records an AuditActionSessionCreate entry each time authManager opens a session, so
+// SessionStatsService can count them after the sessions are gone. The user is the target, and the actor
+// is the user for logins or the admin for impersonations. Call it during setup, before serving requests.
+func (s *AuditService) RecordSessions(authManager *auth.AuthManager) {
+	authManager.OnSessionCreated(func(_ *auth.UserData, session *auth.Session) {
+		target, _ := strconv.ParseUint(session.UserID, 10, 64)
+		entry := &models.AuditLog{Action: AuditActionSessionCreate, ActorID: uint(target), TargetID: uint(target), IP: session.IP}
+		if session.ImpersonatedBy != "" {
+			actor, _ := strconv.ParseUint(session.ImpersonatedBy, 10, 64)
+			entry.ActorID = uint(actor)
+		}
+		_ = s.Record(entry)
+	})
+}
+
+// ForUser returns entries where the user is the actor or the target, newest first.
+func (s *AuditService) ForUser(userID uint) ([]models.AuditLog, error) {
+	var entries []models.AuditLog
+	err := s.db.Where("actor_id = ? OR target_id = ?", userID, userID).Order("created_at DESC").Find(&entries).Error
+	return entries, err
+}
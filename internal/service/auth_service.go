@@ -2,20 +2,26 @@
 package service
 
 import (
+	"cmp"
 	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
 	"errors"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/lucas-varjao/gohtmx/internal/auth"
 	gormadapter "github.com/lucas-varjao/gohtmx/internal/auth/adapter/gorm"
+	"github.com/lucas-varjao/gohtmx/internal/config"
 	"github.com/lucas-varjao/gohtmx/internal/email"
 	"github.com/lucas-varjao/gohtmx/internal/logger"
 	"github.com/lucas-varjao/gohtmx/internal/models"
+	"github.com/lucas-varjao/gohtmx/internal/validation"
 
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 var (
@@ -23,17 +29,50 @@ var (
 	ErrUserNotActive      = errors.New("usuário inativo")
 	ErrInvalidToken       = errors.New("token inválido")
 	ErrExpiredToken       = errors.New("token expirado")
+	// ErrPasswordReused means the new password matches the current one or one in the password history
+	ErrPasswordReused = errors.New("a nova senha não pode ser igual à senha atual nem a uma senha usada recentemente")
+	// ErrWrongPassword means the current password given to ChangePassword is wrong
+	ErrWrongPassword = errors.New("senha atual incorreta")
+	ErrEmailTaken    = errors.New("este email já está em uso")
+	ErrSameEmail     = errors.New("o novo email é igual ao atual")
+	// ErrAccountLocked means too many failed logins; it clears by itself after the lockout duration
+	ErrAccountLocked = errors.New("conta temporariamente bloqueada, tente novamente mais tarde")
+	// ErrEmailNotVerified means login requires a verified email (login.require_verified_email)
+	ErrEmailNotVerified = errors.New("confirme seu email antes de entrar")
+	// ErrAlreadyVerified means ResendVerification has nothing to verify
+	ErrAlreadyVerified = errors.New("seu email já está verificado")
+	// ErrResetContextMismatch means the reset link was bound to the device (or IP) that requested it and
+	// is being used from another one
+	ErrResetContextMismatch = errors.New("abra este link no mesmo dispositivo em que você pediu a redefinição de senha")
+	// ErrUsernameExists and ErrEmailExists mean Register found the username or email in use, also when a
+	// concurrent sign-up took it after the availability check
+	ErrUsernameExists = auth.ErrUsernameExists
+	ErrEmailExists    = auth.ErrEmailExists
 )
 
+// emailChangeTokenTTL is how long the link sent to the new address stays valid.
+const emailChangeTokenTTL = 24 * time.Hour
+
 // AuthServiceInterface defines the methods that an auth service must implement
 type AuthServiceInterface interface {
 	Login(username, password, ip, userAgent string) (*LoginResponse, error)
+	LoginFrom(username, password string, metadata auth.SessionMetadata) (*LoginResponse, error)
 	ValidateSession(sessionID string) (*auth.Session, *auth.UserData, error)
-	Logout(sessionID string) error
-	LogoutAll(userID string) error
+	ExtendSession(sessionID string) (*auth.Session, error)
+	Logout(sessionID string, reason auth.LogoutReason) error
+	LogoutAll(userID string, reason auth.LogoutReason) error
+	LogoutOthers(userID, keepSessionID string) error
+	CountSessions(userID string) (int64, error)
 	Register(username, email, password, displayName string) (*models.User, error)
-	RequestPasswordReset(email string) error
-	ResetPassword(token, newPassword string) error
+	RequestPasswordReset(email, binding string) error
+	ResetPassword(token, newPassword, binding string) error
+	ChangePassword(userID, currentPassword, newPassword string) error
+	RequestEmailChange(userID, newEmail string) error
+	ConfirmEmailChange(token string) (*models.User, error)
+	ResendVerification(userID string) error
+	AcceptTerms(userID, version string) error
+	IsUsernameAvailable(username string) (bool, error)
+	IsEmailAvailable(email string) (bool, error)
 }
 
 // AuthService handles authentication business logic
@@ -41,6 +80,27 @@ type AuthService struct {
 	authManager  *auth.AuthManager
 	userAdapter  *gormadapter.UserAdapter
 	emailService email.EmailServiceInterface
+	attempts     LoginAttemptRecorder // optional; nil disables login attempt records
+	// passwordHistory is how many replaced passwords are kept and blocked from reuse (config password.history_size)
+	passwordHistory int
+	// resetCooldown is the minimum time between reset emails to one account (config password.reset_cooldown)
+	resetCooldown time.Duration
+	// keepLockout leaves failed-login locks in place after a password reset or change
+	// (config login.keep_lockout_on_password_change)
+	keepLockout bool
+
+	// Lockout notices (see NotifyLockouts); lockoutNotified maps user ID → end of the lock already notified
+	lockoutQueue    *email.Queue
+	lockoutMu       sync.Mutex
+	lockoutNotified map[uint]time.Time
+	// passwordChangeQueue, when set, sends the confirmation after a password reset or change (see ConfirmPasswordChanges)
+	passwordChangeQueue *email.Queue
+	// notifications, when set, lets users opt out of optional emails such as the lockout notice
+	notifications email.Gate
+	// deletions, when set, answers logins of accounts scheduled for deletion with a cancel prompt
+	deletions *DeletionService
+	// usernames, when set, lets Register create accounts without a username
+	usernames *UsernameGenerator
 }
 
 // NewAuthService creates a new AuthService instance
@@ -48,57 +108,119 @@ func NewAuthService(
 	authManager *auth.AuthManager,
 	userAdapter *gormadapter.UserAdapter,
 	emailService email.EmailServiceInterface,
+	attempts LoginAttemptRecorder,
 ) *AuthService {
-	return &AuthService{
+	s := &AuthService{
 		authManager:  authManager,
 		userAdapter:  userAdapter,
 		emailService: emailService,
+		attempts:     attempts,
+	}
+	// The recorded wrong passwords are what locks accounts
+	if store, ok := attempts.(auth.LoginFailureStore); ok {
+		authManager.UseLoginFailures(store)
 	}
+	if cfg := config.GetConfig(); cfg != nil {
+		s.passwordHistory = cfg.Password.HistorySize
+		s.resetCooldown = cfg.Password.ResetCooldown
+		s.keepLockout = cfg.Login.KeepLockoutOnPasswordChange
+	}
+	return s
 }
 
-// LoginResponse represents the response from a successful login
+// LoginResponse represents the response from a successful login.
+// LoginStatus is "ok" or "must_change" (the client should send the user to change the password).
 type LoginResponse struct {
-	SessionID string        `json:"session_id"`
-	ExpiresAt time.Time     `json:"expires_at"`
-	User      auth.UserData `json:"user"`
+	SessionID   string           `json:"session_id"`
+	ExpiresAt   time.Time        `json:"expires_at"`
+	User        auth.UserData    `json:"user"`
+	LoginStatus auth.LoginStatus `json:"login_status"`
+	// FirstLogin is true on the user's first successful login, so the UI can show onboarding
+	FirstLogin bool `json:"first_login"`
 }
 
 // Login authenticates a user and creates a session
 func (s *AuthService) Login(username, password, ip, userAgent string) (*LoginResponse, error) {
-	metadata := auth.SessionMetadata{
+	return s.LoginFrom(username, password, auth.SessionMetadata{
 		UserAgent: userAgent,
 		IP:        ip,
-	}
+	})
+}
 
+// LoginFrom is Login with the full request metadata, e.g. the client's country for the new country
+// challenge. A login held back for that challenge returns a *LoginChallengeError and no session.
+func (s *AuthService) LoginFrom(username, password string, metadata auth.SessionMetadata) (*LoginResponse, error) {
+	ip := metadata.IP
 	session, user, err := s.authManager.Login(username, password, metadata)
 	if err != nil {
+		var challenge *LoginChallengeError
 		switch {
+		case errors.As(err, &challenge):
+			s.recordAttempt(username, metadata, AttemptReasonChallenged)
+			return nil, err
 		case errors.Is(err, auth.ErrInvalidCredentials):
 			logger.Warn("Tentativa de login com credenciais inválidas", "username", username, "ip", ip)
+			s.recordAttempt(username, metadata, AttemptReasonInvalidCredentials)
 
 			return nil, ErrInvalidCredentials
 		case errors.Is(err, auth.ErrUserNotActive):
 			logger.Warn("Tentativa de login com usuário inativo", "username", username, "ip", ip)
+			s.recordAttempt(username, metadata, AttemptReasonInactive)
+			// The password was right (it is checked first), so the owner may cancel a scheduled deletion
+			if s.deletions != nil {
+				if pending := s.deletions.Pending(username); pending != nil {
+					return nil, pending
+				}
+			}
 
 			return nil, ErrUserNotActive
 		case errors.Is(err, auth.ErrAccountLocked):
 			logger.Warn("Tentativa de login com conta bloqueada", "username", username, "ip", ip)
-			return nil, errors.New("conta temporariamente bloqueada, tente novamente mais tarde")
+			s.recordAttempt(username, metadata, AttemptReasonLocked)
+			return nil, ErrAccountLocked
+		case errors.Is(err, auth.ErrEmailNotVerified):
+			logger.Warn("Tentativa de login com email não verificado", "username", username, "ip", ip)
+			s.recordAttempt(username, metadata, AttemptReasonUnverified)
+			return nil, ErrEmailNotVerified
 		default:
 			logger.Error("Erro ao fazer login", "error", err, "username", username, "ip", ip)
+			s.recordAttempt(username, metadata, AttemptReasonError)
 			return nil, err
 		}
 	}
 
+	s.recordAttempt(username, metadata, AttemptReasonSuccess)
 	logger.Info("Login realizado com sucesso", "user_id", user.ID, "username", username, "ip", ip)
 
 	return &LoginResponse{
-		SessionID: session.ID,
-		ExpiresAt: session.ExpiresAt,
-		User:      *user,
+		SessionID:   session.ID,
+		ExpiresAt:   session.ExpiresAt,
+		User:        *user,
+		LoginStatus: s.authManager.LoginStatus(user),
+		FirstLogin:  user.FirstLogin,
 	}, nil
 }
 
+// recordAttempt stores a login attempt when a recorder is configured.
+func (s *AuthService) recordAttempt(identifier string, metadata auth.SessionMetadata, reason string) {
+	recordLoginAttempt(s.attempts, identifier, metadata, reason)
+}
+
+// recordLoginAttempt stores a login attempt in attempts; a nil recorder stores nothing.
+// A storage failure is logged by the recorder and never blocks the login itself.
+func recordLoginAttempt(attempts LoginAttemptRecorder, identifier string, metadata auth.SessionMetadata, reason string) {
+	if attempts == nil {
+		return
+	}
+	_ = attempts.Record(&models.LoginAttempt{
+		Identifier: identifier,
+		IP:         metadata.IP,
+		UserAgent:  metadata.UserAgent,
+		Success:    reason == AttemptReasonSuccess,
+		Reason:     reason,
+	})
+}
+
 // ValidateSession validates a session and returns user data
 func (s *AuthService) ValidateSession(sessionID string) (*auth.Session, *auth.UserData, error) {
 	session, user, err := s.authManager.ValidateSession(sessionID)
@@ -121,46 +243,96 @@ func (s *AuthService) ValidateSession(sessionID string) (*auth.Session, *auth.Us
 	return session, user, nil
 }
 
-// Logout invalidates a session
-func (s *AuthService) Logout(sessionID string) error {
-	if err := s.authManager.Logout(sessionID); err != nil {
+// ExtendSession pushes the session expiry forward (keep-alive), up to the absolute session lifetime
+func (s *AuthService) ExtendSession(sessionID string) (*auth.Session, error) {
+	session, err := s.authManager.ExtendSession(sessionID)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrSessionNotFound):
+			return nil, ErrInvalidToken
+		case errors.Is(err, auth.ErrSessionExpired):
+			return nil, ErrExpiredToken
+		case errors.Is(err, auth.ErrUserNotActive):
+			return nil, ErrUserNotActive
+		default:
+			logger.Error("Erro ao estender sessão", "error", err, "session_id", sessionID)
+			return nil, err
+		}
+	}
+	return session, nil
+}
+
+// Logout invalidates a session; reason says why ("" means auth.LogoutReasonUser)
+func (s *AuthService) Logout(sessionID string, reason auth.LogoutReason) error {
+	if err := s.authManager.Logout(sessionID, reason); err != nil {
 		logger.Error("Erro ao fazer logout no service", "error", err, "session_id", sessionID)
 		return err
 	}
 	return nil
 }
 
-// LogoutAll invalidates all sessions for a user
-func (s *AuthService) LogoutAll(userID string) error {
-	if err := s.authManager.LogoutAll(userID); err != nil {
+// LogoutAll invalidates all sessions for a user; reason says why ("" means auth.LogoutReasonUser)
+func (s *AuthService) LogoutAll(userID string, reason auth.LogoutReason) error {
+	if err := s.authManager.LogoutAll(userID, reason); err != nil {
 		logger.Error("Erro ao fazer logout de todas as sessões no service", "error", err, "user_id", userID)
 		return err
 	}
 	return nil
 }
 
+// LogoutOthers invalidates every session of a user but keepSessionID (the one making the request)
+func (s *AuthService) LogoutOthers(userID, keepSessionID string) error {
+	return s.authManager.LogoutOthers(userID, keepSessionID)
+}
+
+// CountSessions returns how many active sessions the user has
+func (s *AuthService) CountSessions(userID string) (int64, error) {
+	return s.authManager.CountUserSessions(userID)
+}
+
 // Register creates a new user account
 func (s *AuthService) Register(username, emailAddr, password, displayName string) (*models.User, error) {
+	generate := username == "" && s.usernames != nil
 	// Check if username already exists
-	if _, err := s.userAdapter.FindUserByIdentifier(username); err == nil {
-		logger.Warn("Tentativa de registro com username já existente", "username", username)
-		return nil, errors.New("username already exists")
+	if !generate {
+		if _, err := s.userAdapter.FindUserByIdentifier(username); err == nil {
+			logger.Warn("Tentativa de registro com username já existente", "username", username)
+			return nil, ErrUsernameExists
+		}
 	}
 
 	// Check if email already exists
 	if _, err := s.userAdapter.FindByEmail(emailAddr); err == nil {
 		logger.Warn("Tentativa de registro com email já existente", "email", emailAddr)
-		return nil, errors.New("email already exists")
+		return nil, ErrEmailExists
 	}
 
 	// Create user via adapter
-	userData, err := s.userAdapter.CreateUser(auth.CreateUserInput{
-		Identifier:  username,
-		Email:       emailAddr,
-		Password:    password,
-		DisplayName: displayName,
-	})
+	var userData *auth.UserData
+	var err error
+	for attempt := 1; ; attempt++ {
+		if generate {
+			if username, err = s.usernames.Generate(displayName, emailAddr); err != nil {
+				logger.Error("Erro ao gerar nome de usuário", "error", err, "email", emailAddr)
+				return nil, err
+			}
+		}
+		userData, err = s.userAdapter.CreateUser(auth.CreateUserInput{
+			Identifier:  username,
+			Email:       emailAddr,
+			Password:    password,
+			DisplayName: displayName,
+		})
+		// Another sign-up may have taken the generated name between Generate and the insert
+		if err == nil || !generate || attempt == generatedUsernameAttempts || !errors.Is(err, ErrUsernameExists) {
+			break
+		}
+	}
 	if err != nil {
+		// The checks above passed, but a concurrent sign-up got the username or email first
+		if errors.Is(err, ErrUsernameExists) || errors.Is(err, ErrEmailExists) {
+			return nil, err
+		}
 		logger.Error("Erro ao criar usuário", "error", err, "username", username, "email", emailAddr)
 		return nil, err
 	}
@@ -176,8 +348,34 @@ func (s *AuthService) Register(username, emailAddr, password, displayName string
 	return user, nil
 }
 
-// RequestPasswordReset initiates a password reset flow
-func (s *AuthService) RequestPasswordReset(emailAddr string) error {
+// IsUsernameAvailable reports whether no account uses the given username.
+func (s *AuthService) IsUsernameAvailable(username string) (bool, error) {
+	if _, err := s.userAdapter.FindUserByIdentifier(username); err != nil {
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			return true, nil
+		}
+		return false, err
+	}
+	return false, nil
+}
+
+// IsEmailAvailable reports whether no account uses the given email.
+func (s *AuthService) IsEmailAvailable(emailAddr string) (bool, error) {
+	if _, err := s.userAdapter.FindByEmail(emailAddr); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return true, nil
+		}
+		logger.Error("Erro ao verificar disponibilidade de email", "error", err, "email", emailAddr)
+		return false, err
+	}
+	return false, nil
+}
+
+// RequestPasswordReset initiates a password reset flow. A non-empty binding (the requester's IP or
+// device cookie) ties the token to that context: ResetPassword then requires the same binding.
+// Within the reset cooldown of the account's previous request nothing is sent, and like an unknown
+// email that still returns nil, so callers answer with the same neutral message.
+func (s *AuthService) RequestPasswordReset(emailAddr, binding string) error {
 	user, err := s.userAdapter.FindByEmail(emailAddr)
 	if err != nil {
 		// Don't reveal if email exists (return nil on purpose)
@@ -185,22 +383,15 @@ func (s *AuthService) RequestPasswordReset(emailAddr string) error {
 		return nil //nolint:nilerr // do not reveal whether email exists
 	}
 
-	// Generate reset token (32 bytes for 256-bit token)
-	const tokenByteSize = 32
-	tokenBytes := make([]byte, tokenByteSize)
-	_, err = s.generateSecureToken(tokenBytes)
-	if err != nil {
-		return err
+	now := time.Now()
+	if s.resetCooldown > 0 && now.Sub(user.LastResetRequestAt) < s.resetCooldown {
+		logger.Info("Solicitação de reset de senha ignorada: intervalo mínimo entre pedidos", "user_id", user.ID)
+		return nil
 	}
+	user.LastResetRequestAt = now
 
-	plaintextToken := hex.EncodeToString(tokenBytes)
-	hashedToken := s.hashToken(plaintextToken)
-	expiresAt := time.Now().Add(1 * time.Hour)
-
-	// Store hashed token
-	user.ResetToken = hashedToken
-	user.ResetTokenExpiry = expiresAt
-	if err := s.userAdapter.UpdateUser(user); err != nil {
+	plaintextToken, err := s.issueResetToken(user, binding)
+	if err != nil {
 		return err
 	}
 
@@ -224,8 +415,128 @@ func (s *AuthService) RequestPasswordReset(emailAddr string) error {
 	return nil
 }
 
-// ResetPassword resets a user's password using a reset token
-func (s *AuthService) ResetPassword(tokenFromUser, newPassword string) error {
+// issueResetToken stores a new password reset token (valid for 1 hour, replacing any previous one)
+// and returns it in plaintext for the email link; only its hash is kept, as is the hash of binding
+// (empty = usable from anywhere).
+func (s *AuthService) issueResetToken(user *models.User, binding string) (string, error) {
+	// 32 bytes for a 256-bit token
+	const tokenByteSize = 32
+	tokenBytes := make([]byte, tokenByteSize)
+	if _, err := s.generateSecureToken(tokenBytes); err != nil {
+		return "", err
+	}
+
+	plaintextToken := hex.EncodeToString(tokenBytes)
+	user.ResetToken = s.hashToken(plaintextToken)
+	user.ResetTokenExpiry = time.Now().Add(1 * time.Hour)
+	user.ResetTokenBinding = ""
+	if binding != "" {
+		user.ResetTokenBinding = s.hashToken(binding)
+	}
+	if err := s.userAdapter.UpdateUser(user); err != nil {
+		return "", err
+	}
+	return plaintextToken, nil
+}
+
+// NotifyLockouts makes every account lockout email the account owner a warning with a password reset
+// link (unless a reset is already pending), sent through queue. A user gets at most one notice per lock window, even when both the
+// username and the email get locked. Call it once during setup (config login.lockout_email).
+func (s *AuthService) NotifyLockouts(queue *email.Queue) {
+	s.lockoutQueue = queue
+	s.lockoutNotified = make(map[uint]time.Time)
+	s.authManager.OnAccountLocked(s.notifyAccountLocked)
+}
+
+// ConfirmPasswordChanges makes every successful ResetPassword and ChangePassword email the owner a
+// confirmation through queue, so a change they didn't make doesn't go unnoticed. Always sent: it is a
+// security email, not an optional one. Call it during setup, before serving requests.
+func (s *AuthService) ConfirmPasswordChanges(queue *email.Queue) {
+	s.passwordChangeQueue = queue
+}
+
+// notifyPasswordChanged queues the confirmation of a new password for user, when enabled.
+func (s *AuthService) notifyPasswordChanged(user *models.User) {
+	if s.passwordChangeQueue == nil {
+		return
+	}
+	to, username, displayName := user.Email, user.Username, cmp.Or(user.DisplayName, user.Username)
+	s.passwordChangeQueue.Enqueue(email.TypePasswordChanged, to, func(svc email.EmailServiceInterface) error {
+		return svc.SendPasswordChangedEmail(to, username, displayName)
+	})
+}
+
+// UseNotificationPreferences makes optional emails (the lockout notice) check gate first, so users who
+// turned them off don't get them. Call it during setup, before serving requests.
+func (s *AuthService) UseNotificationPreferences(gate email.Gate) {
+	s.notifications = gate
+}
+
+// UseDeletions makes the login of an account scheduled for deletion, with the right password, return a
+// *DeletionPendingError (which lets the user cancel) instead of ErrUserNotActive.
+// Call it during setup, before serving requests.
+func (s *AuthService) UseDeletions(deletions *DeletionService) {
+	s.deletions = deletions
+}
+
+// UseUsernameGenerator makes Register accept an empty username and generate one from the display name
+// or email (config registration.generate_username). Call it during setup, before serving requests.
+func (s *AuthService) UseUsernameGenerator(usernames *UsernameGenerator) {
+	s.usernames = usernames
+}
+
+// notifyAccountLocked runs on the failed login that caused the lock; the email itself is queued.
+func (s *AuthService) notifyAccountLocked(identifier string, until time.Time) {
+	data, err := s.userAdapter.FindUserByIdentifier(identifier)
+	if err != nil {
+		// Unknown identifier: nobody to warn
+		return
+	}
+	user, err := s.userAdapter.GetUserModel(data.ID)
+	if err != nil {
+		return
+	}
+	if s.notifications != nil && !s.notifications.ShouldSend(user.ID, email.TypeAccountLocked) {
+		logger.Info("Aviso de conta bloqueada desativado pelo usuário", "user_id", user.ID)
+		return
+	}
+
+	now := time.Now()
+	s.lockoutMu.Lock()
+	if s.lockoutNotified[user.ID].After(now) {
+		s.lockoutMu.Unlock()
+		return
+	}
+	for id, end := range s.lockoutNotified {
+		if !end.After(now) {
+			delete(s.lockoutNotified, id)
+		}
+	}
+	s.lockoutNotified[user.ID] = until
+	s.lockoutMu.Unlock()
+
+	// A reset the owner (or anyone) already asked for is left alone: issuing a new token would make the
+	// link they were sent stop working. The notice then goes without a link.
+	var token string
+	if user.ResetToken == "" || !user.ResetTokenExpiry.After(now) {
+		// The owner may read the warning anywhere, so the link isn't bound
+		token, err = s.issueResetToken(user, "")
+		if err != nil {
+			logger.Error("Erro ao gerar token para aviso de bloqueio", "error", err, "user_id", user.ID)
+			return
+		}
+	}
+	displayName := cmp.Or(user.DisplayName, user.Username)
+	to, username := user.Email, user.Username
+	s.lockoutQueue.Enqueue(email.TypeAccountLocked, to, func(svc email.EmailServiceInterface) error {
+		return svc.SendAccountLockedEmail(to, token, username, displayName)
+	})
+	logger.Info("Aviso de conta bloqueada enfileirado", "user_id", user.ID, "until", until)
+}
+
+// ResetPassword resets a user's password using a reset token. binding must match the one given to
+// RequestPasswordReset when the token was bound (ErrResetContextMismatch otherwise).
+func (s *AuthService) ResetPassword(tokenFromUser, newPassword, binding string) error {
 	hashedToken := s.hashToken(tokenFromUser)
 
 	matchedUser, err := s.userAdapter.FindByResetToken(hashedToken)
@@ -239,38 +550,320 @@ func (s *AuthService) ResetPassword(tokenFromUser, newPassword string) error {
 		return ErrExpiredToken
 	}
 
+	if matchedUser.ResetTokenBinding != "" &&
+		subtle.ConstantTimeCompare([]byte(matchedUser.ResetTokenBinding), []byte(s.hashToken(binding))) != 1 {
+		logger.Warn("Tentativa de reset de senha fora do contexto em que foi pedido", "user_id", matchedUser.ID)
+		return ErrResetContextMismatch
+	}
+
+	if err := s.replacePassword(matchedUser, newPassword); err != nil {
+		return err
+	}
+	logger.Info("Senha resetada com sucesso", "user_id", matchedUser.ID)
+	return nil
+}
+
+// RecoverPassword sets a new password for userID, whose owner proved who they are some other way than
+// the reset email (see RecoveryCodeService). Like ResetPassword it ends every session of the user.
+func (s *AuthService) RecoverPassword(userID, newPassword string) error {
+	user, err := s.userAdapter.GetUserModel(userID)
+	if err != nil {
+		logger.Error("Erro ao buscar usuário para recuperação de conta", "error", err, "user_id", userID)
+		return err
+	}
+	if err := s.replacePassword(user, newPassword); err != nil {
+		return err
+	}
+	logger.Info("Senha redefinida na recuperação de conta", "user_id", user.ID)
+	return nil
+}
+
+// replacePassword stores newPassword for a user who no longer knows the current one: any pending reset
+// token is dropped and every session ends. The request carried no username, so the password is only
+// checked against the user's username, email and display name here (a *ValidationError when it has them).
+func (s *AuthService) replacePassword(user *models.User, newPassword string) error {
+	requirements := validation.ValidatePasswordDetailed(newPassword, user.Username, user.Email, user.DisplayName)
+	if err := requirements.PersonalInfoErr(); err != nil {
+		return &ValidationError{Err: err}
+	}
+	if err := s.checkPasswordReuse(user, newPassword); err != nil {
+		return err
+	}
+
 	// Hash new password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
 	if err != nil {
-		logger.Error("Erro ao gerar hash da nova senha", "error", err, "user_id", matchedUser.ID)
+		logger.Error("Erro ao gerar hash da nova senha", "error", err, "user_id", user.ID)
 		return err
 	}
 
 	// Update password and clear reset token
-	matchedUser.PasswordHash = string(hashedPassword)
-	matchedUser.ResetToken = ""
-	matchedUser.ResetTokenExpiry = time.Time{}
+	previousHash := user.PasswordHash
+	now := time.Now()
+	user.PasswordHash = string(hashedPassword)
+	user.PasswordChangedAt = &now
+	user.MustChangePassword = false
+	user.ResetToken = ""
+	user.ResetTokenExpiry = time.Time{}
+	user.ResetTokenBinding = ""
 
 	// Also invalidate all existing sessions for security
-	userID := strconv.FormatUint(uint64(matchedUser.ID), 10)
-	_ = s.authManager.LogoutAll(userID)
+	userID := strconv.FormatUint(uint64(user.ID), 10)
+	_ = s.authManager.LogoutAll(userID, auth.LogoutReasonPasswordReset)
+
+	if err := s.userAdapter.UpdateUser(user); err != nil {
+		logger.Error("Erro ao atualizar senha do usuário", "error", err, "user_id", user.ID)
+		return err
+	}
+	s.rememberPassword(user.ID, previousHash)
+	s.clearLockout(user)
+	s.notifyPasswordChanged(user)
+	return nil
+}
 
-	if err := s.userAdapter.UpdateUser(matchedUser); err != nil {
-		logger.Error("Erro ao atualizar senha do usuário", "error", err, "user_id", matchedUser.ID)
+// ChangePassword sets a new password for a logged-in user after checking the current one.
+// Existing sessions are kept; the caller validates the new password's strength.
+func (s *AuthService) ChangePassword(userID, currentPassword, newPassword string) error {
+	user, err := s.userAdapter.GetUserModel(userID)
+	if err != nil {
+		logger.Error("Erro ao buscar usuário para troca de senha", "error", err, "user_id", userID)
 		return err
 	}
 
-	logger.Info("Senha resetada com sucesso", "user_id", matchedUser.ID)
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(currentPassword)) != nil {
+		logger.Warn("Tentativa de troca de senha com senha atual incorreta", "user_id", userID)
+		return ErrWrongPassword
+	}
+
+	if err := s.checkPasswordReuse(user, newPassword); err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		logger.Error("Erro ao gerar hash da nova senha", "error", err, "user_id", userID)
+		return err
+	}
+
+	previousHash := user.PasswordHash
+	now := time.Now()
+	user.PasswordHash = string(hashedPassword)
+	user.PasswordChangedAt = &now
+	user.MustChangePassword = false
+	if err := s.userAdapter.UpdateUser(user); err != nil {
+		logger.Error("Erro ao atualizar senha do usuário", "error", err, "user_id", userID)
+		return err
+	}
+	s.rememberPassword(user.ID, previousHash)
+	s.clearLockout(user)
+	s.notifyPasswordChanged(user)
+
+	logger.Info("Senha alterada com sucesso", "user_id", userID)
+	return nil
+}
+
+// clearLockout lifts a failed-login lock on user once its owner proved who they are by setting a new
+// password, so they can log in with it right away (unless login.keep_lockout_on_password_change).
+func (s *AuthService) clearLockout(user *models.User) {
+	if s.keepLockout {
+		return
+	}
+	s.authManager.ClearLockout(user.Username, user.Email)
+	s.lockoutMu.Lock()
+	delete(s.lockoutNotified, user.ID)
+	s.lockoutMu.Unlock()
+}
+
+// RequestEmailChange stores newEmail as a pending change and emails a confirmation link to it.
+// The current email stays in use until ConfirmEmailChange; a new request replaces any pending one.
+func (s *AuthService) RequestEmailChange(userID, newEmail string) error {
+	user, err := s.userAdapter.GetUserModel(userID)
+	if err != nil {
+		logger.Error("Erro ao buscar usuário para troca de email", "error", err, "user_id", userID)
+		return err
+	}
+
+	if strings.EqualFold(newEmail, user.Email) {
+		return ErrSameEmail
+	}
+	if available, err := s.IsEmailAvailable(newEmail); err != nil {
+		return err
+	} else if !available {
+		logger.Warn("Tentativa de troca para email já existente", "user_id", userID, "email", newEmail)
+		return ErrEmailTaken
+	}
+
+	const tokenByteSize = 32
+	tokenBytes := make([]byte, tokenByteSize)
+	if _, err := s.generateSecureToken(tokenBytes); err != nil {
+		return err
+	}
+	plaintextToken := hex.EncodeToString(tokenBytes)
+
+	user.PendingEmail = newEmail
+	user.EmailChangeToken = s.hashToken(plaintextToken)
+	user.EmailChangeExpiry = time.Now().Add(emailChangeTokenTTL)
+	if err := s.userAdapter.UpdateUser(user); err != nil {
+		return err
+	}
+
+	displayName := user.DisplayName
+	if displayName == "" {
+		displayName = user.Username
+	}
+	if err := s.emailService.SendEmailChangeConfirmation(newEmail, plaintextToken, user.Username, displayName); err != nil {
+		logger.Error("Erro ao enviar email de confirmação de troca de email", "error", err, "email", newEmail)
+	} else {
+		logger.Info("Email de confirmação de troca de email enviado", "user_id", user.ID, "email", newEmail)
+	}
+
+	return nil
+}
+
+// ResendVerification emails a new verification link to the user's current address, confirmed through
+// ConfirmEmailChange like the admin batch resend. It replaces any pending email change and returns
+// ErrAlreadyVerified when the email is verified already.
+func (s *AuthService) ResendVerification(userID string) error {
+	user, err := s.userAdapter.GetUserModel(userID)
+	if err != nil {
+		logger.Error("Erro ao buscar usuário para reenvio de verificação", "error", err, "user_id", userID)
+		return err
+	}
+	if user.EmailVerified {
+		return ErrAlreadyVerified
+	}
+
+	const tokenByteSize = 32
+	tokenBytes := make([]byte, tokenByteSize)
+	if _, err := s.generateSecureToken(tokenBytes); err != nil {
+		return err
+	}
+	plaintextToken := hex.EncodeToString(tokenBytes)
+
+	user.PendingEmail = user.Email
+	user.EmailChangeToken = s.hashToken(plaintextToken)
+	user.EmailChangeExpiry = time.Now().Add(emailChangeTokenTTL)
+	if err := s.userAdapter.UpdateUser(user); err != nil {
+		return err
+	}
+
+	displayName := cmp.Or(user.DisplayName, user.Username)
+	if err := s.emailService.SendEmailVerification(user.Email, plaintextToken, user.Username, displayName); err != nil {
+		logger.Error("Erro ao reenviar email de verificação", "error", err, "user_id", user.ID)
+		return err
+	}
+	logger.Info("Email de verificação reenviado", "user_id", user.ID)
+	return nil
+}
+
+// AcceptTerms records that userID accepted version of the terms of use (config terms.version), now.
+func (s *AuthService) AcceptTerms(userID, version string) error {
+	user, err := s.userAdapter.GetUserModel(userID)
+	if err != nil {
+		logger.Error("Erro ao buscar usuário para aceite dos termos", "error", err, "user_id", userID)
+		return err
+	}
+	now := time.Now()
+	user.TermsVersion = version
+	user.TermsAcceptedAt = &now
+	if err := s.userAdapter.UpdateUser(user); err != nil {
+		return err
+	}
+	logger.Info("Termos de uso aceitos", "user_id", user.ID, "version", version)
 	return nil
 }
 
+// ConfirmEmailChange applies the pending email change identified by token. The new address was proven
+// by the click, so the account stays (or becomes) verified. If another account took the address in
+// the meantime, the pending change is dropped and ErrEmailTaken is returned.
+// A pending "change" to the current address is an email verification (see VerificationService).
+func (s *AuthService) ConfirmEmailChange(tokenFromUser string) (*models.User, error) {
+	user, err := s.userAdapter.FindByEmailChangeToken(s.hashToken(tokenFromUser))
+	if err != nil || user == nil {
+		logger.Warn("Tentativa de confirmar troca de email com token inválido")
+		return nil, ErrInvalidToken
+	}
+	if time.Now().After(user.EmailChangeExpiry) {
+		logger.Warn("Tentativa de confirmar troca de email com token expirado", "user_id", user.ID)
+		return nil, ErrExpiredToken
+	}
+
+	newEmail := user.PendingEmail
+	user.PendingEmail = ""
+	user.EmailChangeToken = ""
+	user.EmailChangeExpiry = time.Time{}
+
+	if strings.EqualFold(newEmail, user.Email) {
+		user.EmailVerified = true
+		if err := s.userAdapter.UpdateUser(user); err != nil {
+			return nil, err
+		}
+		logger.Info("Email verificado", "user_id", user.ID, "email", user.Email)
+		return user, nil
+	}
+	if available, err := s.IsEmailAvailable(newEmail); err != nil {
+		return nil, err
+	} else if !available {
+		logger.Warn("Email pendente foi usado por outra conta antes da confirmação", "user_id", user.ID, "email", newEmail)
+		_ = s.userAdapter.UpdateUser(user)
+		return nil, ErrEmailTaken
+	}
+
+	oldEmail := user.Email
+	user.Email = newEmail
+	user.EmailVerified = true
+	if err := s.userAdapter.UpdateUser(user); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Email alterado com sucesso", "user_id", user.ID, "old_email", oldEmail, "new_email", newEmail)
+	return user, nil
+}
+
 // Helper methods
 
+// checkPasswordReuse returns ErrPasswordReused when newPassword matches the user's current password
+// or, when password history is enabled, one of the last passwordHistory passwords.
+func (s *AuthService) checkPasswordReuse(user *models.User, newPassword string) error {
+	hashes := []string{user.PasswordHash}
+	if s.passwordHistory > 0 {
+		previous, err := s.userAdapter.RecentPasswordHashes(user.ID, s.passwordHistory)
+		if err != nil {
+			return err
+		}
+		hashes = append(hashes, previous...)
+	}
+
+	for _, hash := range hashes {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(newPassword)) == nil {
+			logger.Warn("Tentativa de reutilizar senha", "user_id", user.ID)
+			return ErrPasswordReused
+		}
+	}
+	return nil
+}
+
+// rememberPassword stores the hash a password change just replaced in the user's password history, once
+// the change is saved. A failure is only logged: the new password is already in place.
+func (s *AuthService) rememberPassword(userID uint, previousHash string) {
+	if s.passwordHistory <= 0 {
+		return
+	}
+	if err := s.userAdapter.AddPasswordHistory(userID, previousHash, s.passwordHistory); err != nil {
+		logger.Error("Erro ao salvar histórico de senhas", "error", err, "user_id", userID)
+	}
+}
+
 func (s *AuthService) generateSecureToken(b []byte) (int, error) {
 	return auth.GenerateRandomBytes(b)
 }
 
 func (s *AuthService) hashToken(token string) string {
+	return hashToken(token)
+}
+
+// hashToken returns the SHA-256 (hex) stored in place of an emailed token.
+func hashToken(token string) string {
 	hash := sha256.Sum256([]byte(token))
 	return hex.EncodeToString(hash[:])
 }
@@ -2,6 +2,10 @@
 package service
 
 import (
+	"errors"
+	"slices"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 
@@ -22,7 +26,7 @@ func setupTest(t *testing.T) (*AuthService, *auth.AuthManager, *gormadapter.User
 	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	require.NoError(t, err)
 
-	err = db.AutoMigrate(&models.User{}, &models.Session{})
+	err = db.AutoMigrate(&models.User{}, &models.Session{}, &models.LoginAttempt{}, &models.PasswordHistory{}, &models.AuditLog{}, &models.DeniedIP{}, &models.Invite{}, &models.WebAuthnCredential{}, &models.BackupCode{}, &models.TrustedDevice{})
 	require.NoError(t, err)
 
 	userAdapter := gormadapter.NewUserAdapter(db)
@@ -30,7 +34,7 @@ func setupTest(t *testing.T) (*AuthService, *auth.AuthManager, *gormadapter.User
 	authConfig := auth.DefaultAuthConfig()
 	authManager := auth.NewAuthManager(userAdapter, sessionAdapter, authConfig)
 	mockEmailService := email.NewMockEmailService()
-	authService := NewAuthService(authManager, userAdapter, mockEmailService)
+	authService := NewAuthService(authManager, userAdapter, mockEmailService, NewLoginAttemptService(db))
 
 	return authService, authManager, userAdapter, sessionAdapter, mockEmailService, db
 }
@@ -76,6 +80,33 @@ func TestAuthService_Login_Success(t *testing.T) {
 	assert.Equal(t, user.Username, response.User.Identifier)
 }
 
+func TestAuthService_Login_FirstLogin(t *testing.T) {
+	authService, authManager, _, _, _, db := setupTest(t)
+	user := createTestUser(t, db)
+	var observed []string
+	authManager.OnFirstLogin(func(user *auth.UserData, _ *auth.Session) { observed = append(observed, user.Identifier) })
+
+	// A refused login with the right password is not a login: it neither stamps LastLogin nor uses up the first one
+	require.NoError(t, db.Model(user).Update("active", false).Error)
+	_, err := authService.Login("testuser", "password123", "127.0.0.1", "test-agent")
+	require.ErrorIs(t, err, ErrUserNotActive)
+	var stored models.User
+	require.NoError(t, db.First(&stored, user.ID).Error)
+	assert.True(t, stored.LastLogin.IsZero())
+	require.NoError(t, db.Model(user).Update("active", true).Error)
+
+	first, err := authService.Login("testuser", "password123", "127.0.0.1", "test-agent")
+	require.NoError(t, err)
+	assert.True(t, first.FirstLogin)
+	require.NoError(t, db.First(&stored, user.ID).Error)
+	assert.False(t, stored.LastLogin.IsZero())
+
+	again, err := authService.Login("test@example.com", "password123", "127.0.0.1", "test-agent")
+	require.NoError(t, err)
+	assert.False(t, again.FirstLogin)
+	assert.Equal(t, []string{"testuser"}, observed, "the event fires once, on the first login")
+}
+
 func TestAuthService_Login_InvalidCredentials(t *testing.T) {
 	authService, _, _, _, _, db := setupTest(t)
 	_ = createTestUser(t, db)
@@ -131,6 +162,37 @@ func TestAuthService_Login_AccountLocked(t *testing.T) {
 	assert.Contains(t, err.Error(), "bloqueada")
 }
 
+func TestAuthService_Login_LockoutFromRecordedAttempts(t *testing.T) {
+	authService, authManager, userAdapter, sessionAdapter, _, db := setupTest(t)
+	_ = createTestUser(t, db)
+	for range auth.DefaultAuthConfig().MaxFailedAttempts {
+		_, _ = authService.Login("testuser", "wrongpass", "127.0.0.1", "test-agent")
+	}
+
+	// Another instance (or the same one after a restart) sees the lock, under any letter case
+	restarted := NewAuthService(auth.NewAuthManager(userAdapter, sessionAdapter, auth.DefaultAuthConfig()),
+		userAdapter, email.NewMockEmailService(), NewLoginAttemptService(db))
+	_, err := restarted.Login("TestUser", "password123", "127.0.0.1", "test-agent")
+	require.ErrorIs(t, err, ErrAccountLocked)
+
+	// Failures older than the lockout window no longer count
+	require.NoError(t, db.Model(&models.LoginAttempt{}).Where("1 = 1").
+		Update("created_at", time.Now().Add(-auth.DefaultAuthConfig().LockoutDuration-time.Minute)).Error)
+	_, err = restarted.Login("testuser", "password123", "127.0.0.1", "test-agent")
+	require.NoError(t, err)
+
+	// Clearing keeps the attempts in the log
+	for range auth.DefaultAuthConfig().MaxFailedAttempts {
+		_, _ = authService.Login("testuser", "wrongpass", "127.0.0.1", "test-agent")
+	}
+	authManager.ClearLockout("testuser")
+	_, err = authService.Login("testuser", "password123", "127.0.0.1", "test-agent")
+	require.NoError(t, err)
+	var failures int64
+	require.NoError(t, db.Model(&models.LoginAttempt{}).Where("reason = ?", AttemptReasonInvalidCredentials).Count(&failures).Error)
+	assert.Equal(t, int64(2*auth.DefaultAuthConfig().MaxFailedAttempts), failures)
+}
+
 func TestAuthService_Login_InactiveUser(t *testing.T) {
 	authService, _, _, _, _, db := setupTest(t)
 	user := createTestUser(t, db)
@@ -179,7 +241,7 @@ func TestAuthService_Logout(t *testing.T) {
 	require.NoError(t, err)
 
 	// Logout
-	err = authService.Logout(loginResp.SessionID)
+	err = authService.Logout(loginResp.SessionID, auth.LogoutReasonUser)
 	require.NoError(t, err)
 
 	// Verify session is invalid
@@ -187,6 +249,96 @@ func TestAuthService_Logout(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestAuthService_CountSessions(t *testing.T) {
+	authService, _, _, _, _, db := setupTest(t)
+	user := createTestUser(t, db)
+	userID := strconv.FormatUint(uint64(user.ID), 10)
+
+	count, err := authService.CountSessions(userID)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	first, err := authService.Login("testuser", "password123", "127.0.0.1", "test-agent")
+	require.NoError(t, err)
+	_, err = authService.Login("testuser", "password123", "127.0.0.2", "other-agent")
+	require.NoError(t, err)
+	count, err = authService.CountSessions(userID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	// An expired session no longer counts, even before the cleanup job deletes it
+	require.NoError(t, db.Model(&models.Session{}).Where("id = ?", first.SessionID).
+		Update("expires_at", time.Now().Add(-time.Minute)).Error)
+	count, err = authService.CountSessions(userID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// Other users' sessions are not counted
+	count, err = authService.CountSessions(strconv.FormatUint(uint64(user.ID)+1, 10))
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestAuthService_LogoutOthers(t *testing.T) {
+	authService, authManager, _, _, _, db := setupTest(t)
+	NewAuditService(db).RecordLogouts(authManager)
+	user := createTestUser(t, db)
+	userID := strconv.FormatUint(uint64(user.ID), 10)
+	other := &models.User{Username: "other", Email: "other@example.com", PasswordHash: user.PasswordHash, Active: true, Role: "user"}
+	require.NoError(t, db.Create(other).Error)
+
+	var sessions []string
+	for _, ip := range []string{"127.0.0.1", "127.0.0.2", "127.0.0.3"} {
+		resp, err := authService.Login("testuser", "password123", ip, "test-agent")
+		require.NoError(t, err)
+		sessions = append(sessions, resp.SessionID)
+	}
+	otherResp, err := authService.Login("other", "password123", "127.0.0.4", "test-agent")
+	require.NoError(t, err)
+
+	require.NoError(t, authService.LogoutOthers(userID, sessions[1]))
+
+	var left []models.Session
+	require.NoError(t, db.Where("user_id = ?", user.ID).Find(&left).Error)
+	require.Len(t, left, 1)
+	assert.Equal(t, sessions[1], left[0].ID, "the current session stays")
+	_, _, err = authService.ValidateSession(otherResp.SessionID)
+	assert.NoError(t, err, "other users' sessions are not touched")
+
+	var entry models.AuditLog
+	require.NoError(t, db.Where("action = ?", AuditActionLogout).First(&entry).Error)
+	assert.Equal(t, "reason=other_devices scope=others", entry.Details)
+	assert.Equal(t, user.ID, entry.ActorID)
+}
+
+func TestAuthService_LogoutReasonIsAudited(t *testing.T) {
+	authService, authManager, _, _, _, db := setupTest(t)
+	NewAuditService(db).RecordLogouts(authManager)
+	user := createTestUser(t, db)
+	userID := strconv.FormatUint(uint64(user.ID), 10)
+
+	loginResp, err := authService.Login("testuser", "password123", "127.0.0.1", "test-agent")
+	require.NoError(t, err)
+	require.NoError(t, authService.Logout(loginResp.SessionID, ""))
+
+	_, err = authService.Login("testuser", "password123", "127.0.0.1", "test-agent")
+	require.NoError(t, err)
+	_, err = NewUserAdminService(db, authManager).SetActive(userID, false)
+	require.NoError(t, err)
+
+	var entries []models.AuditLog
+	require.NoError(t, db.Where("action = ?", AuditActionLogout).Order("id").Find(&entries).Error)
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, "reason=user", entries[0].Details, "no reason means the user logged out")
+	assert.Equal(t, user.ID, entries[0].ActorID)
+	assert.Equal(t, user.ID, entries[0].TargetID)
+
+	assert.Equal(t, "reason=deactivated scope=all", entries[1].Details)
+	assert.Zero(t, entries[1].ActorID, "the user didn't end these sessions")
+	assert.Equal(t, user.ID, entries[1].TargetID)
+}
+
 func TestAuthService_Register_Success(t *testing.T) {
 	authService, _, _, _, _, _ := setupTest(t)
 
@@ -218,11 +370,87 @@ func TestAuthService_Register_DuplicateUser(t *testing.T) {
 	assert.Contains(t, err.Error(), "email already exists")
 }
 
+func TestAuthService_Register_ConcurrentDuplicate(t *testing.T) {
+	authService, _, userAdapter, _, _, db := setupTest(t)
+	// One connection, so every goroutine sees the same in-memory database
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	race := func(t *testing.T, username string, emails [2]string) (failed int, errs []error) {
+		t.Helper()
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		errs = make([]error, 2)
+		for i := range errs {
+			wg.Go(func() {
+				<-start
+				_, errs[i] = authService.Register(username, emails[i], "password123", "Racer")
+			})
+		}
+		close(start)
+		wg.Wait()
+
+		failed = slices.IndexFunc(errs, func(err error) bool { return err != nil })
+		require.NotEqual(t, -1, failed, "one sign-up must fail")
+		require.NoError(t, errs[1-failed])
+		var count int64
+		require.NoError(t, db.Model(&models.User{}).Where("username = ?", username).Count(&count).Error)
+		assert.Equal(t, int64(1), count)
+		return failed, errs
+	}
+
+	t.Run("Only one of two identical sign-ups succeeds", func(t *testing.T) {
+		failed, errs := race(t, "racer", [2]string{"racer@example.com", "racer@example.com"})
+		// Both columns collide; either one may be reported
+		assert.True(t, errors.Is(errs[failed], ErrUsernameExists) || errors.Is(errs[failed], ErrEmailExists), errs[failed])
+	})
+
+	t.Run("Same username with another email", func(t *testing.T) {
+		failed, errs := race(t, "runner", [2]string{"runner1@example.com", "runner2@example.com"})
+		assert.ErrorIs(t, errs[failed], ErrUsernameExists)
+	})
+
+	// The insert that loses the race, past the availability checks
+	t.Run("Insert reports the column in use", func(t *testing.T) {
+		_, err := userAdapter.CreateUser(auth.CreateUserInput{Identifier: "racer", Email: "other@example.com", Password: "password123"})
+		assert.ErrorIs(t, err, ErrUsernameExists)
+		_, err = userAdapter.CreateUser(auth.CreateUserInput{Identifier: "other", Email: "racer@example.com", Password: "password123"})
+		assert.ErrorIs(t, err, ErrEmailExists)
+	})
+}
+
+func TestAuthService_IsUsernameAvailable(t *testing.T) {
+	authService, _, _, _, _, db := setupTest(t)
+	_ = createTestUser(t, db)
+
+	available, err := authService.IsUsernameAvailable("testuser")
+	require.NoError(t, err)
+	assert.False(t, available)
+
+	available, err = authService.IsUsernameAvailable("freeuser")
+	require.NoError(t, err)
+	assert.True(t, available)
+}
+
+func TestAuthService_IsEmailAvailable(t *testing.T) {
+	authService, _, _, _, _, db := setupTest(t)
+	_ = createTestUser(t, db)
+
+	available, err := authService.IsEmailAvailable("test@example.com")
+	require.NoError(t, err)
+	assert.False(t, available)
+
+	available, err = authService.IsEmailAvailable("free@example.com")
+	require.NoError(t, err)
+	assert.True(t, available)
+}
+
 func TestAuthService_RequestPasswordReset(t *testing.T) {
 	authService, _, _, _, mockEmailService, db := setupTest(t)
 	user := createTestUser(t, db)
 
-	err := authService.RequestPasswordReset(user.Email)
+	err := authService.RequestPasswordReset(user.Email, "")
 	require.NoError(t, err)
 
 	// Verify that reset token was set
@@ -241,11 +469,33 @@ func TestAuthService_RequestPasswordReset(t *testing.T) {
 	assert.NotEmpty(t, sentEmails[0].Token)
 }
 
+func TestAuthService_RequestPasswordReset_Cooldown(t *testing.T) {
+	authService, _, _, _, mockEmailService, db := setupTest(t)
+	authService.resetCooldown = 5 * time.Minute
+	user := createTestUser(t, db)
+
+	require.NoError(t, authService.RequestPasswordReset(user.Email, ""))
+	var first models.User
+	require.NoError(t, db.First(&first, user.ID).Error)
+
+	require.NoError(t, authService.RequestPasswordReset(user.Email, ""), "a request in the cooldown gets the same neutral answer")
+	assert.Len(t, mockEmailService.GetSentEmails(), 1, "no second email within the cooldown")
+	var second models.User
+	require.NoError(t, db.First(&second, user.ID).Error)
+	assert.Equal(t, first.ResetToken, second.ResetToken, "the first link keeps working")
+
+	// Once the cooldown has passed, a new email goes out
+	require.NoError(t, db.Model(&models.User{}).Where("id = ?", user.ID).
+		Update("last_reset_request_at", time.Now().Add(-6*time.Minute)).Error)
+	require.NoError(t, authService.RequestPasswordReset(user.Email, ""))
+	assert.Len(t, mockEmailService.GetSentEmails(), 2)
+}
+
 func TestAuthService_ResetPassword_ValidToken(t *testing.T) {
 	authService, _, _, _, mockEmailService, db := setupTest(t)
 	user := createTestUser(t, db)
 
-	err := authService.RequestPasswordReset(user.Email)
+	err := authService.RequestPasswordReset(user.Email, "")
 	require.NoError(t, err)
 
 	sentEmails := mockEmailService.GetSentEmails()
@@ -254,24 +504,50 @@ func TestAuthService_ResetPassword_ValidToken(t *testing.T) {
 	require.NotEmpty(t, plainToken)
 
 	newPassword := "NewSecurePass123!"
-	err = authService.ResetPassword(plainToken, newPassword)
+	before := time.Now()
+	err = authService.ResetPassword(plainToken, newPassword, "")
 	require.NoError(t, err)
 
 	var updated models.User
 	require.NoError(t, db.First(&updated, user.ID).Error)
 	assert.Empty(t, updated.ResetToken)
 	assert.True(t, updated.ResetTokenExpiry.IsZero())
+	require.NotNil(t, updated.PasswordChangedAt, "the reset records when the password changed")
+	assert.False(t, updated.PasswordChangedAt.Before(before.Truncate(time.Second)))
 	// Password changed: login with new password works
 	loginResp, err := authService.Login(user.Username, newPassword, "127.0.0.1", "test")
 	require.NoError(t, err)
 	assert.NotEmpty(t, loginResp.SessionID)
 }
 
+func TestAuthService_PasswordChangeConfirmation(t *testing.T) {
+	authService, _, _, _, mockEmailService, db := setupTest(t)
+	user := createTestUser(t, db)
+	userID := strconv.FormatUint(uint64(user.ID), 10)
+	queue := email.NewQueue(mockEmailService, 10)
+	authService.ConfirmPasswordChanges(queue)
+
+	require.NoError(t, authService.RequestPasswordReset(user.Email, ""))
+	resetToken := mockEmailService.GetSentEmails()[0].Token
+	require.Error(t, authService.ResetPassword("wrong-token", "NewSecurePass123!", ""))
+	require.NoError(t, authService.ResetPassword(resetToken, "NewSecurePass123!", ""))
+	require.NoError(t, authService.ChangePassword(userID, "NewSecurePass123!", "OtherSecurePass123!"))
+	require.NoError(t, queue.Close(t.Context()))
+
+	sent := mockEmailService.GetSentEmails()
+	require.Len(t, sent, 3, "the reset link, then one confirmation per successful change")
+	for _, confirmation := range sent[1:] {
+		assert.Equal(t, email.MockKindPasswordChanged, confirmation.Kind)
+		assert.Equal(t, user.Email, confirmation.To)
+		assert.Equal(t, user.Username, confirmation.Username)
+	}
+}
+
 func TestAuthService_ResetPassword_ExpiredToken(t *testing.T) {
 	authService, _, _, _, mockEmailService, db := setupTest(t)
 	user := createTestUser(t, db)
 
-	err := authService.RequestPasswordReset(user.Email)
+	err := authService.RequestPasswordReset(user.Email, "")
 	require.NoError(t, err)
 
 	sentEmails := mockEmailService.GetSentEmails()
@@ -282,13 +558,441 @@ func TestAuthService_ResetPassword_ExpiredToken(t *testing.T) {
 	require.NoError(t, db.Model(&models.User{}).Where("id = ?", user.ID).
 		Update("reset_token_expiry", time.Now().Add(-time.Hour)).Error)
 
-	err = authService.ResetPassword(plainToken, "NewSecurePass123!")
+	err = authService.ResetPassword(plainToken, "NewSecurePass123!", "")
 	assert.ErrorIs(t, err, ErrExpiredToken)
 }
 
 func TestAuthService_ResetPassword_InvalidToken(t *testing.T) {
 	authService, _, _, _, _, _ := setupTest(t)
 
-	err := authService.ResetPassword("nonexistent-token", "NewSecurePass123!")
+	err := authService.ResetPassword("nonexistent-token", "NewSecurePass123!", "")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestAuthService_ResetPassword_SamePassword(t *testing.T) {
+	authService, _, _, _, mockEmailService, db := setupTest(t)
+	user := createTestUser(t, db)
+
+	require.NoError(t, authService.RequestPasswordReset(user.Email, ""))
+	plainToken := mockEmailService.GetSentEmails()[0].Token
+
+	err := authService.ResetPassword(plainToken, "password123", "")
+	assert.ErrorIs(t, err, ErrPasswordReused)
+
+	// The token is not consumed by the rejected attempt
+	require.NoError(t, authService.ResetPassword(plainToken, "NewSecurePass123!", ""))
+}
+
+func TestAuthService_ResetPassword_PersonalInfo(t *testing.T) {
+	authService, _, _, _, mockEmailService, db := setupTest(t)
+	user := createTestUser(t, db)
+
+	require.NoError(t, authService.RequestPasswordReset(user.Email, ""))
+	plainToken := mockEmailService.GetSentEmails()[0].Token
+
+	// Strong on its own, but it contains the username the reset request didn't carry
+	err := authService.ResetPassword(plainToken, "Testuser#2024!", "")
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr), "got %v", err)
+
+	require.NoError(t, authService.ResetPassword(plainToken, "NewSecurePass123!", ""))
+}
+
+func TestAuthService_ResetPassword_BoundToken(t *testing.T) {
+	authService, _, _, _, mockEmailService, db := setupTest(t)
+	user := createTestUser(t, db)
+
+	require.NoError(t, authService.RequestPasswordReset(user.Email, "device-a"))
+	plainToken := mockEmailService.GetSentEmails()[0].Token
+
+	var stored models.User
+	require.NoError(t, db.First(&stored, user.ID).Error)
+	assert.NotEmpty(t, stored.ResetTokenBinding)
+	assert.NotEqual(t, "device-a", stored.ResetTokenBinding, "only the hash is stored")
+
+	for _, binding := range []string{"device-b", ""} {
+		err := authService.ResetPassword(plainToken, "NewSecurePass123!", binding)
+		assert.ErrorIs(t, err, ErrResetContextMismatch, "binding %q", binding)
+	}
+
+	// The mismatches don't consume the token
+	require.NoError(t, authService.ResetPassword(plainToken, "NewSecurePass123!", "device-a"))
+	require.NoError(t, db.First(&stored, user.ID).Error)
+	assert.Empty(t, stored.ResetTokenBinding)
+
+	// An unbound token works from anywhere
+	mockEmailService.ClearSentEmails()
+	require.NoError(t, authService.RequestPasswordReset(user.Email, ""))
+	require.NoError(t, authService.ResetPassword(mockEmailService.GetSentEmails()[0].Token, "OtherSecurePass123!", "device-b"))
+}
+
+func TestAuthService_ResetPassword_UnlocksAccount(t *testing.T) {
+	lock := func(authService *AuthService) {
+		t.Helper()
+		// Locked under both identifiers
+		for _, identifier := range []string{"testuser", "test@example.com"} {
+			for range auth.DefaultAuthConfig().MaxFailedAttempts {
+				_, _ = authService.Login(identifier, "wrongpass", "127.0.0.1", "test-agent")
+			}
+		}
+		_, err := authService.Login("testuser", "password123", "127.0.0.1", "test-agent")
+		require.ErrorIs(t, err, ErrAccountLocked)
+	}
+	reset := func(authService *AuthService, mockEmail *email.MockEmailService, user *models.User, password string) {
+		t.Helper()
+		mockEmail.ClearSentEmails()
+		require.NoError(t, authService.RequestPasswordReset(user.Email, ""))
+		require.NoError(t, authService.ResetPassword(mockEmail.GetSentEmails()[0].Token, password, ""))
+	}
+
+	t.Run("Reset", func(t *testing.T) {
+		authService, _, _, _, mockEmail, db := setupTest(t)
+		user := createTestUser(t, db)
+		lock(authService)
+
+		reset(authService, mockEmail, user, "NewSecurePass123!")
+		_, err := authService.Login("testuser", "NewSecurePass123!", "127.0.0.1", "test-agent")
+		require.NoError(t, err)
+		_, err = authService.Login("test@example.com", "NewSecurePass123!", "127.0.0.1", "test-agent")
+		require.NoError(t, err)
+	})
+
+	t.Run("Change", func(t *testing.T) {
+		authService, _, _, _, _, db := setupTest(t)
+		user := createTestUser(t, db)
+		lock(authService)
+
+		require.NoError(t, authService.ChangePassword(idString(user.ID), "password123", "NewSecurePass123!"))
+		_, err := authService.Login("testuser", "NewSecurePass123!", "127.0.0.1", "test-agent")
+		require.NoError(t, err)
+	})
+
+	t.Run("Kept when configured", func(t *testing.T) {
+		authService, _, _, _, mockEmail, db := setupTest(t)
+		authService.keepLockout = true
+		user := createTestUser(t, db)
+		lock(authService)
+
+		reset(authService, mockEmail, user, "NewSecurePass123!")
+		_, err := authService.Login("testuser", "NewSecurePass123!", "127.0.0.1", "test-agent")
+		assert.ErrorIs(t, err, ErrAccountLocked)
+	})
+}
+
+func TestAuthService_ChangePassword(t *testing.T) {
+	authService, _, _, _, _, db := setupTest(t)
+	user := createTestUser(t, db)
+	userID := strconv.FormatUint(uint64(user.ID), 10)
+
+	assert.ErrorIs(t, authService.ChangePassword(userID, "wrongpass", "NewSecurePass123!"), ErrWrongPassword)
+	assert.ErrorIs(t, authService.ChangePassword(userID, "password123", "password123"), ErrPasswordReused)
+
+	require.NoError(t, authService.ChangePassword(userID, "password123", "NewSecurePass123!"))
+	_, err := authService.Login(user.Username, "NewSecurePass123!", "127.0.0.1", "test")
+	require.NoError(t, err)
+
+	// Without history, an older password is allowed again
+	require.NoError(t, authService.ChangePassword(userID, "NewSecurePass123!", "password123"))
+	var count int64
+	db.Model(&models.PasswordHistory{}).Count(&count)
+	assert.Zero(t, count, "history is not stored when disabled")
+}
+
+func TestAuthService_ChangePassword_History(t *testing.T) {
+	authService, _, _, _, _, db := setupTest(t)
+	authService.passwordHistory = 2
+	user := createTestUser(t, db)
+	userID := strconv.FormatUint(uint64(user.ID), 10)
+
+	// password123 -> First1!pass -> Second2!pass -> Third3!pass
+	require.NoError(t, authService.ChangePassword(userID, "password123", "First1!pass"))
+	require.NoError(t, authService.ChangePassword(userID, "First1!pass", "Second2!pass"))
+	assert.ErrorIs(t, authService.ChangePassword(userID, "Second2!pass", "password123"), ErrPasswordReused, "older password still in history")
+	require.NoError(t, authService.ChangePassword(userID, "Second2!pass", "Third3!pass"))
+
+	assert.ErrorIs(t, authService.ChangePassword(userID, "Third3!pass", "First1!pass"), ErrPasswordReused)
+	assert.ErrorIs(t, authService.ChangePassword(userID, "Third3!pass", "Second2!pass"), ErrPasswordReused)
+
+	var count int64
+	db.Model(&models.PasswordHistory{}).Where("user_id = ?", user.ID).Count(&count)
+	assert.Equal(t, int64(2), count, "only the last history_size hashes are kept")
+
+	// password123 fell out of the history window
+	require.NoError(t, authService.ChangePassword(userID, "Third3!pass", "password123"))
+}
+
+func TestAuthService_ChangePassword_HistoryOnlyAfterUpdate(t *testing.T) {
+	authService, _, _, _, _, db := setupTest(t)
+	authService.passwordHistory = 2
+	user := createTestUser(t, db)
+	userID := strconv.FormatUint(uint64(user.ID), 10)
+
+	updateErr := errors.New("update failed")
+	require.NoError(t, db.Callback().Update().Before("gorm:update").Register("test:fail_user_update", func(tx *gorm.DB) {
+		if tx.Statement.Table == "users" {
+			_ = tx.AddError(updateErr)
+		}
+	}))
+	require.ErrorIs(t, authService.ChangePassword(userID, "password123", "NewSecurePass123!"), updateErr)
+
+	// The old password is still the current one, so it isn't history yet
+	var count int64
+	db.Model(&models.PasswordHistory{}).Where("user_id = ?", user.ID).Count(&count)
+	assert.Zero(t, count)
+}
+
+func TestAuthService_EmailChange_PendingThenConfirm(t *testing.T) {
+	authService, _, _, _, mockEmailService, db := setupTest(t)
+	user := createTestUser(t, db)
+	require.NoError(t, db.Model(user).Update("email_verified", true).Error)
+	userID := strconv.FormatUint(uint64(user.ID), 10)
+
+	require.NoError(t, authService.RequestEmailChange(userID, "new@example.com"))
+
+	sent := mockEmailService.GetSentEmails()
+	require.Len(t, sent, 1)
+	assert.Equal(t, email.MockKindEmailChange, sent[0].Kind)
+	assert.Equal(t, "new@example.com", sent[0].To, "confirmation goes to the new address")
+
+	// Until confirmed, the old email stays active
+	var pending models.User
+	require.NoError(t, db.First(&pending, user.ID).Error)
+	assert.Equal(t, "test@example.com", pending.Email)
+	assert.Equal(t, "new@example.com", pending.PendingEmail)
+	assert.NotEqual(t, sent[0].Token, pending.EmailChangeToken, "only the token hash is stored")
+	_, err := authService.Login("test@example.com", "password123", "127.0.0.1", "test")
+	require.NoError(t, err)
+
+	_, err = authService.ConfirmEmailChange("wrong-token-value")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	confirmed, err := authService.ConfirmEmailChange(sent[0].Token)
+	require.NoError(t, err)
+	assert.Equal(t, "new@example.com", confirmed.Email)
+
+	var updated models.User
+	require.NoError(t, db.First(&updated, user.ID).Error)
+	assert.Equal(t, "new@example.com", updated.Email)
+	assert.True(t, updated.EmailVerified)
+	assert.Empty(t, updated.PendingEmail)
+	assert.Empty(t, updated.EmailChangeToken)
+
+	// The link works only once
+	_, err = authService.ConfirmEmailChange(sent[0].Token)
 	assert.ErrorIs(t, err, ErrInvalidToken)
 }
+
+func TestAuthService_AcceptTerms(t *testing.T) {
+	authService, authManager, _, _, _, db := setupTest(t)
+	user := createTestUser(t, db)
+	response, err := authService.Login("testuser", "password123", "127.0.0.1", "test-agent")
+	require.NoError(t, err)
+	assert.Empty(t, response.User.TermsVersion)
+
+	require.NoError(t, authService.AcceptTerms(idString(user.ID), "2025-01"))
+
+	var stored models.User
+	require.NoError(t, db.First(&stored, user.ID).Error)
+	assert.Equal(t, "2025-01", stored.TermsVersion)
+	require.NotNil(t, stored.TermsAcceptedAt)
+	// The session sees the accepted version on the next request, which is what the terms gate compares
+	_, sessionUser, err := authManager.ValidateSession(response.SessionID)
+	require.NoError(t, err)
+	assert.Equal(t, "2025-01", sessionUser.TermsVersion)
+}
+
+func TestAuthService_ResendVerification(t *testing.T) {
+	authService, _, _, _, mockEmailService, db := setupTest(t)
+	user := createTestUser(t, db)
+	userID := strconv.FormatUint(uint64(user.ID), 10)
+
+	require.NoError(t, authService.ResendVerification(userID))
+	sent := mockEmailService.GetSentEmails()
+	require.Len(t, sent, 1)
+	assert.Equal(t, email.MockKindEmailVerification, sent[0].Kind)
+	assert.Equal(t, "test@example.com", sent[0].To)
+
+	confirmed, err := authService.ConfirmEmailChange(sent[0].Token)
+	require.NoError(t, err)
+	assert.True(t, confirmed.EmailVerified)
+	assert.Equal(t, "test@example.com", confirmed.Email)
+
+	assert.ErrorIs(t, authService.ResendVerification(userID), ErrAlreadyVerified)
+	assert.Len(t, mockEmailService.GetSentEmails(), 1)
+}
+
+func TestAuthService_EmailChange_Collisions(t *testing.T) {
+	authService, _, _, _, mockEmailService, db := setupTest(t)
+	user := createTestUser(t, db)
+	userID := strconv.FormatUint(uint64(user.ID), 10)
+	require.NoError(t, db.Create(&models.User{
+		Username: "other", Email: "taken@example.com", DisplayName: "Other", PasswordHash: "hash",
+	}).Error)
+
+	assert.ErrorIs(t, authService.RequestEmailChange(userID, "taken@example.com"), ErrEmailTaken)
+	assert.ErrorIs(t, authService.RequestEmailChange(userID, "TEST@example.com"), ErrSameEmail)
+	assert.Empty(t, mockEmailService.GetSentEmails())
+
+	// Address free at request time but taken before the link is clicked
+	require.NoError(t, authService.RequestEmailChange(userID, "race@example.com"))
+	token := mockEmailService.GetSentEmails()[0].Token
+	require.NoError(t, db.Create(&models.User{
+		Username: "racer", Email: "race@example.com", DisplayName: "Racer", PasswordHash: "hash",
+	}).Error)
+
+	_, err := authService.ConfirmEmailChange(token)
+	assert.ErrorIs(t, err, ErrEmailTaken)
+
+	var unchanged models.User
+	require.NoError(t, db.First(&unchanged, user.ID).Error)
+	assert.Equal(t, "test@example.com", unchanged.Email)
+	assert.Empty(t, unchanged.PendingEmail, "the stale pending change is dropped")
+
+	// Expired link
+	require.NoError(t, authService.RequestEmailChange(userID, "late@example.com"))
+	token = mockEmailService.GetSentEmails()[1].Token
+	require.NoError(t, db.Model(&models.User{}).Where("id = ?", user.ID).
+		Update("email_change_expiry", time.Now().Add(-time.Hour)).Error)
+	_, err = authService.ConfirmEmailChange(token)
+	assert.ErrorIs(t, err, ErrExpiredToken)
+}
+
+func TestAuthManager_LoginStatus(t *testing.T) {
+	_, _, userAdapter, sessionAdapter, _, db := setupTest(t)
+	authConfig := auth.DefaultAuthConfig()
+	authConfig.RequireVerifiedEmail = true
+
+	tests := []struct {
+		name       string
+		setup      func(user *models.User)
+		lockWith   string // identifier to lock before logging in with the username
+		wantStatus auth.LoginStatus
+		wantErr    error
+	}{
+		{"OK", func(u *models.User) {}, "", auth.LoginStatusOK, nil},
+		{"Inactive", func(u *models.User) { u.Active = false }, "", auth.LoginStatusInactive, ErrUserNotActive},
+		{"Locked", func(u *models.User) {}, "test@example.com", auth.LoginStatusLocked, ErrAccountLocked},
+		{"Unverified", func(u *models.User) { u.EmailVerified = false }, "", auth.LoginStatusUnverified, ErrEmailNotVerified},
+		{"Must change", func(u *models.User) { u.MustChangePassword = true }, "", auth.LoginStatusMustChange, nil},
+		{"Inactive wins over must change", func(u *models.User) {
+			u.Active = false
+			u.MustChangePassword = true
+		}, "", auth.LoginStatusInactive, ErrUserNotActive},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authManager := auth.NewAuthManager(userAdapter, sessionAdapter, authConfig)
+			authService := NewAuthService(authManager, userAdapter, email.NewMockEmailService(), NewLoginAttemptService(db))
+			// Lockouts come from the recorded attempts, so each case starts without any
+			require.NoError(t, db.Where("1 = 1").Delete(&models.LoginAttempt{}).Error)
+			require.NoError(t, db.Unscoped().Where("1 = 1").Delete(&models.User{}).Error)
+			user := createTestUser(t, db)
+			user.EmailVerified = true
+			tt.setup(user)
+			require.NoError(t, db.Save(user).Error)
+			if tt.lockWith != "" {
+				for range authConfig.MaxFailedAttempts {
+					_, _ = authService.Login(tt.lockWith, "wrongpass", "127.0.0.1", "test-agent")
+				}
+			}
+
+			data, err := userAdapter.FindUserByID(strconv.FormatUint(uint64(user.ID), 10))
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, authManager.LoginStatus(data))
+			assert.Equal(t, tt.wantErr == nil, tt.wantStatus.CanLogin())
+
+			response, err := authService.Login("testuser", "password123", "127.0.0.1", "test-agent")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, response.LoginStatus)
+		})
+	}
+}
+
+func TestAuthService_ChangePassword_ClearsMustChange(t *testing.T) {
+	authService, authManager, userAdapter, _, _, db := setupTest(t)
+	user := createTestUser(t, db)
+	user.MustChangePassword = true
+	require.NoError(t, db.Save(user).Error)
+	userID := strconv.FormatUint(uint64(user.ID), 10)
+
+	response, err := authService.Login("testuser", "password123", "127.0.0.1", "test-agent")
+	require.NoError(t, err)
+	assert.Equal(t, auth.LoginStatusMustChange, response.LoginStatus)
+
+	require.NoError(t, authService.ChangePassword(userID, "password123", "NewSecurePass123!"))
+	data, err := userAdapter.FindUserByID(userID)
+	require.NoError(t, err)
+	assert.Equal(t, auth.LoginStatusOK, authManager.LoginStatus(data))
+}
+
+func TestAuthService_LockoutNotification(t *testing.T) {
+	authService, _, _, _, mockEmail, db := setupTest(t)
+	user := createTestUser(t, db)
+	queue := email.NewQueue(mockEmail, 0)
+	authService.NotifyLockouts(queue)
+
+	attempts := auth.DefaultAuthConfig().MaxFailedAttempts
+	for range attempts {
+		_, _ = authService.Login("testuser", "wrongpass", "127.0.0.1", "test-agent")
+	}
+	// Still locked: no new lock, no new email
+	_, err := authService.Login("testuser", "wrongpass", "127.0.0.1", "test-agent")
+	require.ErrorIs(t, err, ErrAccountLocked)
+	// Locking the same account through its email is the same lock window
+	for range attempts {
+		_, _ = authService.Login("test@example.com", "wrongpass", "127.0.0.1", "test-agent")
+	}
+	// Unknown accounts get locked too, but there is nobody to warn
+	for range attempts {
+		_, _ = authService.Login("ghost", "wrongpass", "127.0.0.1", "test-agent")
+	}
+
+	require.NoError(t, queue.Close(t.Context()))
+	sent := mockEmail.GetSentEmails()
+	require.Len(t, sent, 1)
+	assert.Equal(t, email.MockKindAccountLocked, sent[0].Kind)
+	assert.Equal(t, user.Email, sent[0].To)
+	assert.Equal(t, user.Username, sent[0].Username)
+	require.NotEmpty(t, sent[0].Token)
+
+	// The link in the notice is a working password reset
+	require.NoError(t, authService.ResetPassword(sent[0].Token, "NewSecurePass123!", ""))
+}
+
+func TestAuthService_LockoutNotification_KeepsPendingReset(t *testing.T) {
+	authService, _, _, _, mockEmail, db := setupTest(t)
+	_ = createTestUser(t, db)
+	queue := email.NewQueue(mockEmail, 0)
+	authService.NotifyLockouts(queue)
+
+	require.NoError(t, authService.RequestPasswordReset("test@example.com", ""))
+	resetToken := mockEmail.GetSentEmails()[0].Token
+	require.NotEmpty(t, resetToken)
+
+	for range auth.DefaultAuthConfig().MaxFailedAttempts {
+		_, _ = authService.Login("testuser", "wrongpass", "127.0.0.1", "test-agent")
+	}
+	require.NoError(t, queue.Close(t.Context()))
+	sent := mockEmail.GetSentEmails()
+	require.Len(t, sent, 2)
+	assert.Equal(t, email.MockKindAccountLocked, sent[1].Kind)
+	assert.Empty(t, sent[1].Token, "no new token while a reset is pending")
+
+	// The link the owner asked for still works
+	require.NoError(t, authService.ResetPassword(resetToken, "NewSecurePass123!", ""))
+}
+
+func TestAuthService_LockoutNotification_Disabled(t *testing.T) {
+	authService, _, _, _, mockEmail, db := setupTest(t)
+	_ = createTestUser(t, db)
+
+	for range auth.DefaultAuthConfig().MaxFailedAttempts {
+		_, _ = authService.Login("testuser", "wrongpass", "127.0.0.1", "test-agent")
+	}
+	assert.Empty(t, mockEmail.GetSentEmails())
+}
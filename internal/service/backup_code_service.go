@@ -0,0 +1,87 @@
+package service
+
+import (
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// backupCodeCount is how many codes each Regenerate hands out.
+const backupCodeCount = 10
+
+// BackupCodeService keeps each user's set of one-time backup codes for the login verification step
+// (LoginChallengeService): when the code sent by email can't be received, one of these is typed
+// instead. Codes look like recovery codes (ABCD-EFGH-JKLM), only their hashes are stored, each works
+// once, and a new set replaces the previous one.
+type BackupCodeService struct {
+	db    *gorm.DB
+	audit AuditRecorder
+	now   func() time.Time
+}
+
+// NewBackupCodeService creates a new BackupCodeService instance; audit may be nil.
+func NewBackupCodeService(db *gorm.DB, audit AuditRecorder) *BackupCodeService {
+	return &BackupCodeService{db: db, audit: audit, now: time.Now}
+}
+
+// Regenerate creates a fresh set of backup codes for userID and returns them; they can't be shown again.
+// Every code of the previous set, used or not, stops working.
+func (s *BackupCodeService) Regenerate(userID, ip string) ([]string, error) {
+	id, err := ParseUserID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	codes := make([]string, backupCodeCount)
+	rows := make([]models.BackupCode, backupCodeCount)
+	for i := range codes {
+		if codes[i], err = newRecoveryCode(); err != nil {
+			logger.Error("Erro ao gerar código de backup", "error", err)
+			return nil, err
+		}
+		rows[i] = models.BackupCode{UserID: id, CodeHash: hashToken(normalizeRecoveryCode(codes[i]))}
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", id).Delete(&models.BackupCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&rows).Error
+	})
+	if err != nil {
+		logger.Error("Erro ao salvar códigos de backup", "error", err, "user_id", userID)
+		return nil, err
+	}
+	if s.audit != nil {
+		_ = s.audit.Record(&models.AuditLog{Action: AuditActionBackupCodesCreate, ActorID: id, TargetID: id, IP: ip})
+	}
+	logger.Info("Códigos de backup gerados", "user_id", userID)
+	return codes, nil
+}
+
+// Consume reports whether code is one of userID's unused backup codes, and marks it used when it is.
+// Two requests racing with the same code can't both succeed.
+func (s *BackupCodeService) Consume(userID, code string) (bool, error) {
+	id, err := ParseUserID(userID)
+	if err != nil {
+		return false, nil
+	}
+	normalized := normalizeRecoveryCode(code)
+	if len(normalized) != recoveryCodeLength {
+		return false, nil
+	}
+	result := s.db.Model(&models.BackupCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", id, hashToken(normalized)).
+		Update("used_at", s.now())
+	if result.Error != nil {
+		logger.Error("Erro ao usar código de backup", "error", result.Error, "user_id", userID)
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	logger.Info("Código de backup usado", "user_id", userID)
+	return true, nil
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestBackupCodeService_Regenerate(t *testing.T) {
+	_, _, _, _, _, db := setupTest(t)
+	user := createTestUser(t, db)
+	codes := NewBackupCodeService(db, NewAuditService(db))
+
+	issued, err := codes.Regenerate(idString(user.ID), "10.0.0.1")
+	require.NoError(t, err)
+	require.Len(t, issued, backupCodeCount)
+	seen := map[string]bool{}
+	for _, code := range issued {
+		assert.Regexp(t, regexp.MustCompile(`^[A-HJ-NP-Z2-9]{4}-[A-HJ-NP-Z2-9]{4}-[A-HJ-NP-Z2-9]{4}$`), code)
+		assert.False(t, seen[code], "codes are distinct")
+		seen[code] = true
+	}
+
+	// Only the hashes are stored
+	var stored []models.BackupCode
+	require.NoError(t, db.Where("user_id = ?", user.ID).Find(&stored).Error)
+	require.Len(t, stored, backupCodeCount)
+	for _, row := range stored {
+		assert.Len(t, row.CodeHash, 64)
+		assert.NotContains(t, row.CodeHash, strings.ReplaceAll(issued[0], "-", ""))
+	}
+
+	var entry models.AuditLog
+	require.NoError(t, db.Where("action = ?", AuditActionBackupCodesCreate).First(&entry).Error)
+	assert.Equal(t, user.ID, entry.ActorID)
+	assert.Equal(t, "10.0.0.1", entry.IP)
+
+	_, err = codes.Regenerate("nope", "")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestBackupCodeService_Consume_SingleUse(t *testing.T) {
+	_, _, _, _, _, db := setupTest(t)
+	user := createTestUser(t, db)
+	codes := NewBackupCodeService(db, nil)
+	issued, err := codes.Regenerate(idString(user.ID), "")
+	require.NoError(t, err)
+
+	// Typed in lowercase without dashes
+	ok, err := codes.Consume(idString(user.ID), strings.ToLower(strings.ReplaceAll(issued[0], "-", "")))
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = codes.Consume(idString(user.ID), issued[0])
+	require.NoError(t, err)
+	assert.False(t, ok, "a code works once")
+
+	ok, err = codes.Consume("424242", issued[1])
+	require.NoError(t, err)
+	assert.False(t, ok, "codes belong to their user")
+
+	ok, err = codes.Consume(idString(user.ID), "123456")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestBackupCodeService_Regenerate_InvalidatesPreviousSet(t *testing.T) {
+	_, _, _, _, _, db := setupTest(t)
+	user := createTestUser(t, db)
+	codes := NewBackupCodeService(db, nil)
+	old, err := codes.Regenerate(idString(user.ID), "")
+	require.NoError(t, err)
+	fresh, err := codes.Regenerate(idString(user.ID), "")
+	require.NoError(t, err)
+
+	ok, err := codes.Consume(idString(user.ID), old[0])
+	require.NoError(t, err)
+	assert.False(t, ok, "the previous set no longer works")
+	ok, err = codes.Consume(idString(user.ID), fresh[0])
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestLoginChallenge_BackupCodeInsteadOfEmailedCode(t *testing.T) {
+	authService, challenges, _, user := setupLoginChallenges(t)
+	codes := NewBackupCodeService(challenges.db, nil)
+	issued, err := codes.Regenerate(idString(user.ID), "")
+	require.NoError(t, err)
+
+	login := func() string {
+		t.Helper()
+		_, err := authService.LoginFrom("testuser", "password123", auth.SessionMetadata{Country: "US"})
+		var challenge *LoginChallengeError
+		require.True(t, errors.As(err, &challenge), "want a LoginChallengeError, got %v", err)
+		return challenge.ChallengeID
+	}
+
+	// Not accepted until the service is told to
+	id := login()
+	_, err = challenges.Verify(id, issued[0], auth.SessionMetadata{})
+	require.ErrorIs(t, err, ErrLoginCodeInvalid)
+
+	challenges.UseBackupCodes(codes)
+	response, err := challenges.Verify(id, issued[0], auth.SessionMetadata{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, response.SessionID)
+	assert.Equal(t, []string{"BR", "US"}, knownCountriesOf(t, challenges, user))
+
+	// The code is used up
+	require.NoError(t, challenges.db.Model(user).Update("known_countries", "BR").Error)
+	id = login()
+	_, err = challenges.Verify(id, issued[0], auth.SessionMetadata{})
+	assert.ErrorIs(t, err, ErrLoginCodeInvalid)
+}
+
+func TestLoginChallenge_BackupCodeCheckedOutsideLock(t *testing.T) {
+	authService, challenges, _, user := setupLoginChallenges(t)
+	codes := NewBackupCodeService(challenges.db, nil)
+	challenges.UseBackupCodes(codes)
+	issued, err := codes.Regenerate(idString(user.ID), "")
+	require.NoError(t, err)
+
+	// Other challenges can be verified while a backup code is looked up
+	var lockFree bool
+	require.NoError(t, challenges.db.Callback().Update().Before("gorm:update").Register("test:challenge_lock", func(tx *gorm.DB) {
+		if tx.Statement.Table == "backup_codes" && challenges.mu.TryLock() {
+			lockFree = true
+			challenges.mu.Unlock()
+		}
+	}))
+
+	_, err = authService.LoginFrom("testuser", "password123", auth.SessionMetadata{Country: "US"})
+	var challenge *LoginChallengeError
+	require.True(t, errors.As(err, &challenge), "want a LoginChallengeError, got %v", err)
+	_, err = challenges.Verify(challenge.ChallengeID, issued[0], auth.SessionMetadata{})
+	require.NoError(t, err)
+	assert.True(t, lockFree, "the backup code is consumed without holding the challenges lock")
+}
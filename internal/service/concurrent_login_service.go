@@ -0,0 +1,145 @@
+package service
+
+import (
+	"cmp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/config"
+	"github.com/lucas-varjao/gohtmx/internal/email"
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+	"github.com/lucas-varjao/gohtmx/internal/useragent"
+
+	"gorm.io/gorm"
+)
+
+// newLoginEmailInterval is the least time between two concurrent login emails to one user, so a leaked
+// password used over and over doesn't flood the owner's inbox (the banner is still updated).
+const newLoginEmailInterval = time.Hour
+
+// ConcurrentLoginService tells users about a login made while other sessions of their account
+// were open (config login.concurrent_login_notice): by email, and with a notice on those other sessions
+// (models.Session.NewLoginAt) that the page layout shows until it is dismissed.
+//
+// Open sessions from the same device as the new login (same user agent and IP) don't count, so logging
+// in again where one already was isn't reported; neither do sessions of an admin impersonating the user.
+// Every way of logging in that opens a session (password, passkey, login verification code) is covered
+// once Watch registers Notify with the AuthManager.
+type ConcurrentLoginService struct {
+	db     *gorm.DB
+	queue  *email.Queue
+	email  bool
+	banner bool
+	// notifications, when set, lets users turn the email off
+	notifications email.Gate
+	now           func() time.Time
+
+	mu      sync.Mutex
+	emailed map[uint]time.Time // user ID → when the last email was queued
+}
+
+// NewConcurrentLoginService creates a new ConcurrentLoginService instance; emails go through queue.
+func NewConcurrentLoginService(db *gorm.DB, queue *email.Queue, cfg config.ConcurrentLoginNoticeConfig) *ConcurrentLoginService {
+	return &ConcurrentLoginService{
+		db:      db,
+		queue:   queue,
+		email:   cfg.Email,
+		banner:  cfg.Banner,
+		now:     time.Now,
+		emailed: make(map[uint]time.Time),
+	}
+}
+
+// UseNotificationPreferences makes the email check gate first, so users who turned it off don't get it.
+// Call it during setup, before serving requests.
+func (s *ConcurrentLoginService) UseNotificationPreferences(gate email.Gate) {
+	s.notifications = gate
+}
+
+// Watch makes every session authManager opens go through Notify.
+// Call it during setup, before serving requests.
+func (s *ConcurrentLoginService) Watch(authManager *auth.AuthManager) {
+	authManager.OnSessionCreated(s.Notify)
+}
+
+// Notify looks for other open sessions of user when session was just opened by a login and, when there
+// are some from another device, marks them for the banner and emails the owner. Impersonation sessions
+// are skipped. Failures are logged and never fail the login.
+func (s *ConcurrentLoginService) Notify(user *auth.UserData, session *auth.Session) {
+	if session.ImpersonatedBy != "" {
+		return
+	}
+	userID, err := strconv.ParseUint(session.UserID, 10, 64)
+	if err != nil {
+		return
+	}
+	now := s.now()
+	var open []models.Session
+	if err := s.db.Select("id", "user_agent", "ip").
+		Where("user_id = ? AND id <> ? AND expires_at > ? AND impersonated_by = 0", userID, session.ID, now).
+		Find(&open).Error; err != nil {
+		logger.Error("Erro ao buscar sessões abertas no login", "error", err, "user_id", userID)
+		return
+	}
+	var elsewhere []string
+	for _, other := range open {
+		if other.UserAgent == session.UserAgent && other.IP == session.IP {
+			// Same device logging in again
+			continue
+		}
+		elsewhere = append(elsewhere, other.ID)
+	}
+	if len(elsewhere) == 0 {
+		return
+	}
+	logger.Info("Login com outras sessões da conta abertas", "user_id", userID, "open_sessions", len(elsewhere), "ip", session.IP)
+
+	device := useragent.Parse(session.UserAgent).Describe()
+	if s.banner {
+		from := device
+		if session.IP != "" {
+			from += ", IP " + session.IP
+		}
+		if err := s.db.Model(&models.Session{}).Where("id IN ?", elsewhere).
+			Updates(map[string]any{"new_login_at": now, "new_login_from": from}).Error; err != nil {
+			logger.Error("Erro ao marcar aviso de novo login nas sessões", "error", err, "user_id", userID)
+		}
+	}
+	if s.email {
+		s.sendEmail(uint(userID), user, device, session.IP)
+	}
+}
+
+// sendEmail queues the email unless the user turned it off or got one less than newLoginEmailInterval ago.
+func (s *ConcurrentLoginService) sendEmail(userID uint, user *auth.UserData, device, ip string) {
+	if s.notifications != nil && !s.notifications.ShouldSend(userID, email.TypeNewLogin) {
+		return
+	}
+	now := s.now()
+	s.mu.Lock()
+	if last, ok := s.emailed[userID]; ok && now.Sub(last) < newLoginEmailInterval {
+		s.mu.Unlock()
+		return
+	}
+	for id, last := range s.emailed {
+		if now.Sub(last) >= newLoginEmailInterval {
+			delete(s.emailed, id)
+		}
+	}
+	s.emailed[userID] = now
+	s.mu.Unlock()
+
+	to, username, displayName := user.Email, user.Identifier, cmp.Or(user.DisplayName, user.Identifier)
+	s.queue.Enqueue(email.TypeNewLogin, to, func(svc email.EmailServiceInterface) error {
+		return svc.SendNewLoginEmail(to, device, ip, username, displayName)
+	})
+}
+
+// Dismiss removes the new login notice from sessionID (nothing to do when it has none).
+func (s *ConcurrentLoginService) Dismiss(sessionID string) error {
+	return s.db.Model(&models.Session{}).Where("id = ?", sessionID).
+		Updates(map[string]any{"new_login_at": nil, "new_login_from": ""}).Error
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/config"
+	"github.com/lucas-varjao/gohtmx/internal/email"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	laptopAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:128.0) Gecko/20100101 Firefox/128.0"
+	phoneAgent  = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Mobile Safari/537.36"
+)
+
+func TestConcurrentLoginNotice(t *testing.T) {
+	authService, authManager, _, _, mockEmail, db := setupTest(t)
+	user := createTestUser(t, db)
+	queue := email.NewQueue(mockEmail, 10)
+	notices := NewConcurrentLoginService(db, queue, config.ConcurrentLoginNoticeConfig{Email: true, Banner: true})
+	notices.Watch(authManager)
+
+	first, err := authService.Login("testuser", "password123", "198.51.100.1", laptopAgent)
+	require.NoError(t, err)
+	// Logging in again on the same device isn't a concurrent login
+	_, err = authService.Login("testuser", "password123", "198.51.100.1", laptopAgent)
+	require.NoError(t, err)
+
+	var stored models.Session
+	require.NoError(t, db.First(&stored, "id = ?", first.SessionID).Error)
+	assert.Nil(t, stored.NewLoginAt, "a first login has no other session to warn")
+
+	second, err := authService.Login("testuser", "password123", "203.0.113.7", phoneAgent)
+	require.NoError(t, err)
+	// A third login within the hour marks the banner again but doesn't email again
+	_, err = authService.Login("testuser", "password123", "203.0.113.8", phoneAgent)
+	require.NoError(t, err)
+	require.NoError(t, queue.Close(t.Context()))
+
+	sent := mockEmail.GetSentEmails()
+	require.Len(t, sent, 1)
+	assert.Equal(t, email.MockKindNewLogin, sent[0].Kind)
+	assert.Equal(t, user.Email, sent[0].To)
+	assert.Equal(t, "Chrome 126 em Android 14", sent[0].Token)
+
+	require.NoError(t, db.First(&stored, "id = ?", first.SessionID).Error)
+	require.NotNil(t, stored.NewLoginAt)
+	assert.Equal(t, "Chrome 126 em Android 14, IP 203.0.113.8", stored.NewLoginFrom)
+	var own models.Session
+	require.NoError(t, db.First(&own, "id = ?", second.SessionID).Error)
+	assert.NotNil(t, own.NewLoginAt, "the second login is warned about the third")
+
+	require.NoError(t, notices.Dismiss(first.SessionID))
+	var dismissed models.Session
+	require.NoError(t, db.First(&dismissed, "id = ?", first.SessionID).Error)
+	assert.Nil(t, dismissed.NewLoginAt)
+	assert.Empty(t, dismissed.NewLoginFrom)
+}
+
+func TestConcurrentLoginNotice_EmailTurnedOff(t *testing.T) {
+	authService, authManager, _, _, mockEmail, db := setupTest(t)
+	user := createTestUser(t, db)
+	queue := email.NewQueue(mockEmail, 10)
+	notices := NewConcurrentLoginService(db, queue, config.ConcurrentLoginNoticeConfig{Email: true})
+	notifications := NewNotificationService(db)
+	require.NoError(t, notifications.SetPreferences(user.ID, map[string]bool{email.TypeAccountLocked: true}))
+	notices.UseNotificationPreferences(notifications)
+	notices.Watch(authManager)
+
+	first, err := authService.Login("testuser", "password123", "198.51.100.1", laptopAgent)
+	require.NoError(t, err)
+	_, err = authService.Login("testuser", "password123", "203.0.113.7", phoneAgent)
+	require.NoError(t, err)
+	require.NoError(t, queue.Close(t.Context()))
+
+	assert.Empty(t, mockEmail.GetSentEmails())
+	var stored models.Session
+	require.NoError(t, db.First(&stored, "id = ?", first.SessionID).Error)
+	assert.Nil(t, stored.NewLoginAt, "no banner unless configured")
+}
+
+func TestConcurrentLoginNotice_SessionsWithoutPassword(t *testing.T) {
+	authService, authManager, _, _, mockEmail, db := setupTest(t)
+	user := createTestUser(t, db)
+	userID := idString(user.ID)
+	queue := email.NewQueue(mockEmail, 10)
+	notices := NewConcurrentLoginService(db, queue, config.ConcurrentLoginNoticeConfig{Email: true, Banner: true})
+	notices.Watch(authManager)
+
+	first, err := authService.Login("testuser", "password123", "198.51.100.1", laptopAgent)
+	require.NoError(t, err)
+	// An admin impersonating the user isn't a login of the owner
+	_, _, err = authManager.CreateSessionForUser(userID, auth.SessionMetadata{IP: "10.0.0.1", UserAgent: phoneAgent, ImpersonatedBy: "99"})
+	require.NoError(t, err)
+	var stored models.Session
+	require.NoError(t, db.First(&stored, "id = ?", first.SessionID).Error)
+	assert.Nil(t, stored.NewLoginAt)
+
+	// A passkey or verification code login opens its session without a password
+	_, _, err = authManager.CreateSessionForUser(userID, auth.SessionMetadata{IP: "203.0.113.7", UserAgent: phoneAgent})
+	require.NoError(t, err)
+	require.NoError(t, queue.Close(t.Context()))
+
+	require.NoError(t, db.First(&stored, "id = ?", first.SessionID).Error)
+	require.NotNil(t, stored.NewLoginAt)
+	assert.Equal(t, "Chrome 126 em Android 14, IP 203.0.113.7", stored.NewLoginFrom)
+	require.Len(t, mockEmail.GetSentEmails(), 1)
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"cmp"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/email"
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// DeletionPendingError answers the login of an account scheduled for deletion, with the right password:
+// the user may still cancel, with CancelToken, until ScheduledAt.
+type DeletionPendingError struct {
+	ScheduledAt time.Time
+	CancelToken string
+}
+
+func (e *DeletionPendingError) Error() string {
+	return "sua conta está agendada para exclusão"
+}
+
+// DeletionService deletes accounts at their owner's request. With a grace period (config
+// account.deletion_grace_period) the account is first deactivated and marked with the date it goes away,
+// and the owner can cancel until then through the emailed link or the prompt shown on login;
+// jobs.RetentionJanitor deletes it once the date passes. Without one it is deleted right away.
+type DeletionService struct {
+	db           *gorm.DB
+	authManager  *auth.AuthManager
+	emailService email.EmailServiceInterface
+	grace        time.Duration
+	now          func() time.Time
+}
+
+// NewDeletionService creates a new DeletionService instance. grace <= 0 deletes accounts immediately.
+func NewDeletionService(db *gorm.DB, authManager *auth.AuthManager, emailService email.EmailServiceInterface, grace time.Duration) *DeletionService {
+	return &DeletionService{
+		db:           db,
+		authManager:  authManager,
+		emailService: emailService,
+		grace:        max(grace, 0),
+		now:          time.Now,
+	}
+}
+
+// Request deletes userID's account, confirmed with the current password (ErrWrongPassword otherwise).
+// With a grace period it returns when the account will be deleted; it is deactivated, its sessions end
+// and the owner gets the cancel link by email. Without one the account is gone and it returns the zero time.
+func (s *DeletionService) Request(userID, password string) (time.Time, error) {
+	user, err := s.find(userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		logger.Warn("Pedido de exclusão de conta com senha incorreta", "user_id", user.ID)
+		return time.Time{}, ErrWrongPassword
+	}
+	_ = s.authManager.LogoutAll(userID, auth.LogoutReasonDeactivated)
+
+	if s.grace == 0 {
+		err := WithTransaction(s.db, func(tx *gorm.DB) error {
+			return HardDeleteUsers(tx, user.ID)
+		})
+		if err != nil {
+			logger.Error("Erro ao excluir conta a pedido do usuário", "error", err, "user_id", user.ID)
+			return time.Time{}, err
+		}
+		logger.Info("Conta excluída a pedido do usuário", "user_id", user.ID, "username", user.Username)
+		return time.Time{}, nil
+	}
+
+	scheduledAt := s.now().Add(s.grace)
+	token, err := s.scheduleWithNewToken(user, scheduledAt)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if err := s.emailService.SendAccountDeletionScheduled(user.Email, token, user.Username, cmp.Or(user.DisplayName, user.Username)); err != nil {
+		// The account is scheduled either way; the owner can still cancel by logging in
+		logger.Error("Erro ao enviar email de exclusão agendada", "error", err, "user_id", user.ID)
+	}
+	logger.Info("Exclusão de conta agendada", "user_id", user.ID, "scheduled_at", scheduledAt)
+	return scheduledAt, nil
+}
+
+// Pending returns a *DeletionPendingError, with a fresh cancel token, when the account behind identifier
+// (username or email) is waiting to be deleted, and nil otherwise. Only call it once the password is
+// known to be right: the new token replaces the one emailed before.
+func (s *DeletionService) Pending(identifier string) error {
+	var user models.User
+	if err := s.db.Where("(username = ? OR email = ?) AND deletion_scheduled_at IS NOT NULL", identifier, identifier).
+		First(&user).Error; err != nil {
+		return nil
+	}
+	token, err := s.scheduleWithNewToken(&user, *user.DeletionScheduledAt)
+	if err != nil {
+		return nil
+	}
+	return &DeletionPendingError{ScheduledAt: *user.DeletionScheduledAt, CancelToken: token}
+}
+
+// Cancel reactivates the account whose deletion token is token. It returns ErrInvalidToken for an
+// unknown token and ErrExpiredToken once the scheduled date has passed.
+func (s *DeletionService) Cancel(token string) error {
+	if token == "" {
+		return ErrInvalidToken
+	}
+	var user models.User
+	if err := s.db.Where("deletion_cancel_token = ? AND deletion_scheduled_at IS NOT NULL", hashToken(token)).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Warn("Tentativa de cancelar exclusão de conta com token inválido")
+			return ErrInvalidToken
+		}
+		return err
+	}
+	if !s.now().Before(*user.DeletionScheduledAt) {
+		return ErrExpiredToken
+	}
+	if err := s.db.Model(&user).Updates(map[string]any{
+		"active":                true,
+		"deletion_scheduled_at": nil,
+		"deletion_cancel_token": "",
+	}).Error; err != nil {
+		logger.Error("Erro ao cancelar exclusão de conta", "error", err, "user_id", user.ID)
+		return err
+	}
+	logger.Info("Exclusão de conta cancelada", "user_id", user.ID)
+	return nil
+}
+
+// scheduleWithNewToken marks user inactive and due for deletion at scheduledAt, with a new cancel token
+// (its hash stored), and returns the token.
+func (s *DeletionService) scheduleWithNewToken(user *models.User, scheduledAt time.Time) (string, error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := auth.GenerateRandomBytes(tokenBytes); err != nil {
+		logger.Error("Erro ao gerar token de cancelamento de exclusão", "error", err)
+		return "", err
+	}
+	token := hex.EncodeToString(tokenBytes)
+	if err := s.db.Model(user).Updates(map[string]any{
+		"active":                false,
+		"deletion_scheduled_at": scheduledAt,
+		"deletion_cancel_token": hashToken(token),
+	}).Error; err != nil {
+		logger.Error("Erro ao agendar exclusão de conta", "error", err, "user_id", user.ID)
+		return "", err
+	}
+	return token, nil
+}
+
+func (s *DeletionService) find(userID string) (*models.User, error) {
+	id, err := strconv.ParseUint(userID, 10, 64)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	var user models.User
+	if err := s.db.First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
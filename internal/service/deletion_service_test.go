@@ -0,0 +1,113 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/email"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupDeletions(t *testing.T, grace time.Duration) (*AuthService, *DeletionService, *email.MockEmailService, *gorm.DB, *models.User) {
+	authService, authManager, _, _, mockEmail, db := setupTest(t)
+	deletions := NewDeletionService(db, authManager, mockEmail, grace)
+	authService.UseDeletions(deletions)
+	return authService, deletions, mockEmail, db, createTestUser(t, db)
+}
+
+func TestDeletion_RequestSchedulesAndDeactivates(t *testing.T) {
+	authService, deletions, mockEmail, db, user := setupDeletions(t, 30*24*time.Hour)
+	_, err := authService.Login("testuser", "password123", "", "")
+	require.NoError(t, err)
+
+	scheduledAt, err := deletions.Request(idString(user.ID), "password123")
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(30*24*time.Hour), scheduledAt, time.Minute)
+
+	var stored models.User
+	require.NoError(t, db.First(&stored, user.ID).Error)
+	assert.False(t, stored.Active)
+	require.NotNil(t, stored.DeletionScheduledAt)
+	var sessions int64
+	require.NoError(t, db.Model(&models.Session{}).Count(&sessions).Error)
+	assert.Zero(t, sessions, "the account's sessions end")
+
+	sent := mockEmail.GetSentEmails()
+	require.Len(t, sent, 1)
+	assert.Equal(t, email.MockKindAccountDeletion, sent[0].Kind)
+	assert.NotEqual(t, sent[0].Token, stored.DeletionCancelToken, "only the hash is stored")
+
+	// Logging in with the right password offers to cancel instead of "inactive"
+	_, err = authService.Login("testuser", "password123", "", "")
+	var pending *DeletionPendingError
+	require.True(t, errors.As(err, &pending), "want a DeletionPendingError, got %v", err)
+	assert.True(t, pending.ScheduledAt.Equal(*stored.DeletionScheduledAt))
+	assert.NotEmpty(t, pending.CancelToken)
+
+	_, err = authService.Login("testuser", "wrong", "", "")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestDeletion_WrongPassword(t *testing.T) {
+	_, deletions, mockEmail, db, user := setupDeletions(t, time.Hour)
+
+	_, err := deletions.Request(idString(user.ID), "wrong")
+	assert.ErrorIs(t, err, ErrWrongPassword)
+
+	var stored models.User
+	require.NoError(t, db.First(&stored, user.ID).Error)
+	assert.True(t, stored.Active)
+	assert.Nil(t, stored.DeletionScheduledAt)
+	assert.Empty(t, mockEmail.GetSentEmails())
+}
+
+func TestDeletion_CancelRestoresAccount(t *testing.T) {
+	authService, deletions, mockEmail, db, user := setupDeletions(t, time.Hour)
+	_, err := deletions.Request(idString(user.ID), "password123")
+	require.NoError(t, err)
+	emailed := mockEmail.GetSentEmails()[0].Token
+
+	// The login hands out a new token, which replaces the emailed one
+	_, err = authService.Login("testuser", "password123", "", "")
+	var pending *DeletionPendingError
+	require.True(t, errors.As(err, &pending))
+	assert.ErrorIs(t, deletions.Cancel(emailed), ErrInvalidToken)
+
+	require.NoError(t, deletions.Cancel(pending.CancelToken))
+	var stored models.User
+	require.NoError(t, db.First(&stored, user.ID).Error)
+	assert.True(t, stored.Active)
+	assert.Nil(t, stored.DeletionScheduledAt)
+	assert.Empty(t, stored.DeletionCancelToken)
+
+	_, err = authService.Login("testuser", "password123", "", "")
+	require.NoError(t, err)
+	assert.ErrorIs(t, deletions.Cancel(pending.CancelToken), ErrInvalidToken, "the token works once")
+}
+
+func TestDeletion_CancelAfterTheDate(t *testing.T) {
+	_, deletions, mockEmail, _, user := setupDeletions(t, time.Hour)
+	_, err := deletions.Request(idString(user.ID), "password123")
+	require.NoError(t, err)
+
+	deletions.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	assert.ErrorIs(t, deletions.Cancel(mockEmail.GetSentEmails()[0].Token), ErrExpiredToken)
+}
+
+func TestDeletion_WithoutGracePeriod(t *testing.T) {
+	_, deletions, mockEmail, db, user := setupDeletions(t, 0)
+
+	scheduledAt, err := deletions.Request(idString(user.ID), "password123")
+	require.NoError(t, err)
+	assert.True(t, scheduledAt.IsZero())
+
+	var count int64
+	require.NoError(t, db.Unscoped().Model(&models.User{}).Where("id = ?", user.ID).Count(&count).Error)
+	assert.Zero(t, count, "deleted for good right away")
+	assert.Empty(t, mockEmail.GetSentEmails())
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func seedImpersonationUser(t *testing.T, db *gorm.DB, username, role string) *models.User {
+	t.Helper()
+	user := &models.User{Username: username, Email: username + "@example.com", DisplayName: username,
+		PasswordHash: "hash", Active: true, Role: role}
+	require.NoError(t, db.Create(user).Error)
+	return user
+}
+
+func TestImpersonationService_StartStop(t *testing.T) {
+	_, authManager, _, _, _, db := setupTest(t)
+	admin := seedImpersonationUser(t, db, "boss", RoleAdmin)
+	target := seedImpersonationUser(t, db, "alice", RoleUser)
+	adminID := strconv.FormatUint(uint64(admin.ID), 10)
+	targetID := strconv.FormatUint(uint64(target.ID), 10)
+
+	adminSession, adminData, err := authManager.CreateSessionForUser(adminID, auth.SessionMetadata{IP: "10.0.0.1"})
+	require.NoError(t, err)
+
+	impersonation := NewImpersonationService(authManager, NewAuditService(db))
+	session, user, err := impersonation.Start(adminSession, adminData, targetID, auth.SessionMetadata{IP: "10.0.0.1"})
+	require.NoError(t, err)
+	assert.Equal(t, targetID, user.ID)
+	assert.Equal(t, adminID, session.ImpersonatedBy)
+	assert.WithinDuration(t, time.Now().Add(auth.DefaultAuthConfig().ImpersonationDuration), session.ExpiresAt, time.Minute)
+
+	// The admin session is replaced, and the new one validates as the target with the admin recorded
+	_, _, err = authManager.ValidateSession(adminSession.ID)
+	require.ErrorIs(t, err, auth.ErrSessionNotFound)
+	validated, validatedUser, err := authManager.ValidateSession(session.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", validatedUser.Identifier)
+	assert.Equal(t, adminID, validated.ImpersonatedBy)
+
+	restored, restoredUser, err := impersonation.Stop(validated, auth.SessionMetadata{IP: "10.0.0.1"})
+	require.NoError(t, err)
+	assert.Equal(t, adminID, restoredUser.ID)
+	assert.Empty(t, restored.ImpersonatedBy)
+	_, _, err = authManager.ValidateSession(session.ID)
+	require.ErrorIs(t, err, auth.ErrSessionNotFound, "impersonation session must be gone")
+
+	// A regular session has nothing to stop
+	_, _, err = impersonation.Stop(restored, auth.SessionMetadata{})
+	assert.ErrorIs(t, err, ErrNotImpersonating)
+
+	var entries []models.AuditLog
+	require.NoError(t, db.Order("id").Find(&entries).Error)
+	require.Len(t, entries, 2)
+	assert.Equal(t, AuditActionImpersonateStart, entries[0].Action)
+	assert.Equal(t, AuditActionImpersonateStop, entries[1].Action)
+	for _, e := range entries {
+		assert.Equal(t, admin.ID, e.ActorID)
+		assert.Equal(t, target.ID, e.TargetID)
+		assert.Equal(t, "10.0.0.1", e.IP)
+	}
+}
+
+func TestImpersonationService_Forbidden(t *testing.T) {
+	_, authManager, _, _, _, db := setupTest(t)
+	admin := seedImpersonationUser(t, db, "boss", RoleAdmin)
+	otherAdmin := seedImpersonationUser(t, db, "other", RoleAdmin)
+	regular := seedImpersonationUser(t, db, "alice", RoleUser)
+	adminID := strconv.FormatUint(uint64(admin.ID), 10)
+
+	adminSession, adminData, err := authManager.CreateSessionForUser(adminID, auth.SessionMetadata{})
+	require.NoError(t, err)
+	impersonation := NewImpersonationService(authManager, NewAuditService(db))
+
+	_, _, err = impersonation.Start(adminSession, adminData, strconv.FormatUint(uint64(otherAdmin.ID), 10), auth.SessionMetadata{})
+	assert.ErrorIs(t, err, ErrCannotImpersonateAdmin)
+	_, _, err = impersonation.Start(adminSession, adminData, adminID, auth.SessionMetadata{})
+	assert.ErrorIs(t, err, ErrCannotImpersonateAdmin, "impersonating yourself is impersonating an admin")
+	_, _, err = impersonation.Start(adminSession, adminData, "999", auth.SessionMetadata{})
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	// The admin keeps their session after a refused attempt
+	_, _, err = authManager.ValidateSession(adminSession.ID)
+	require.NoError(t, err)
+
+	// Only admins can start
+	regularSession, regularData, err := authManager.CreateSessionForUser(strconv.FormatUint(uint64(regular.ID), 10), auth.SessionMetadata{})
+	require.NoError(t, err)
+	_, _, err = impersonation.Start(regularSession, regularData, strconv.FormatUint(uint64(regular.ID), 10), auth.SessionMetadata{})
+	assert.ErrorIs(t, err, ErrImpersonatorNotAdmin)
+
+	var count int64
+	db.Model(&models.AuditLog{}).Count(&count)
+	assert.Zero(t, count)
+}
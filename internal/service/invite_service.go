@@ -0,0 +1,163 @@
+package service
+
+import (
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+	"github.com/lucas-varjao/gohtmx/internal/validation"
+
+	"gorm.io/gorm"
+)
+
+// DefaultInviteTTL is how long an invite link works when NewInviteService gets ttl <= 0.
+const DefaultInviteTTL = 7 * 24 * time.Hour
+
+// Reasons an invite can't be used. ErrInviteNotFound also covers a missing or malformed token.
+var (
+	ErrInviteNotFound      = errors.New("convite inválido")
+	ErrInviteExpired       = errors.New("este convite expirou")
+	ErrInviteUsed          = errors.New("este convite já foi usado")
+	ErrInviteEmailMismatch = errors.New("este convite é para outro email")
+)
+
+// InviteService issues and redeems registration invites. An invite is a single-use link that works
+// even with public registration disabled and decides the new user's role.
+type InviteService struct {
+	db    *gorm.DB
+	audit AuditRecorder
+	ttl   time.Duration
+}
+
+// NewInviteService creates a new InviteService instance; invites are valid for ttl (DefaultInviteTTL
+// when ttl <= 0) and their creation is audited when audit is not nil.
+func NewInviteService(db *gorm.DB, audit AuditRecorder, ttl time.Duration) *InviteService {
+	if ttl <= 0 {
+		ttl = DefaultInviteTTL
+	}
+	return &InviteService{db: db, audit: audit, ttl: ttl}
+}
+
+// Create stores a new invite and returns it with the plaintext token for the link; only the token's
+// hash is kept. A non-empty email restricts the invite to that address.
+func (s *InviteService) Create(emailAddr, role, adminID, ip string) (*models.Invite, string, error) {
+	emailAddr = strings.TrimSpace(emailAddr)
+	if emailAddr != "" {
+		if err := validation.ValidateEmail(emailAddr); err != nil {
+			return nil, "", &ValidationError{Err: err}
+		}
+	}
+
+	// 32 bytes for a 256-bit token
+	const tokenByteSize = 32
+	tokenBytes := make([]byte, tokenByteSize)
+	if _, err := auth.GenerateRandomBytes(tokenBytes); err != nil {
+		logger.Error("Erro ao gerar token de convite", "error", err)
+		return nil, "", err
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	actor, _ := strconv.ParseUint(adminID, 10, 64)
+	invite := &models.Invite{
+		TokenHash: hashToken(token),
+		Email:     emailAddr,
+		Role:      NormalizeRole(role),
+		CreatedBy: uint(actor),
+		ExpiresAt: time.Now().Add(s.ttl),
+	}
+	if err := s.db.Create(invite).Error; err != nil {
+		logger.Error("Erro ao criar convite", "error", err, "email", emailAddr)
+		return nil, "", err
+	}
+	if s.audit != nil {
+		details := "role=" + invite.Role
+		if emailAddr != "" {
+			details += " email=" + emailAddr
+		}
+		_ = s.audit.Record(&models.AuditLog{Action: AuditActionInviteCreate, ActorID: uint(actor), IP: ip, Details: details})
+	}
+	logger.Info("Convite criado", "invite_id", invite.ID, "role", invite.Role, "admin_id", adminID)
+	return invite, token, nil
+}
+
+// Lookup returns the invite behind a link token, or why it can't be used.
+func (s *InviteService) Lookup(token string) (*models.Invite, error) {
+	if token == "" {
+		return nil, ErrInviteNotFound
+	}
+	var invite models.Invite
+	if err := s.db.Where("token_hash = ?", hashToken(token)).First(&invite).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInviteNotFound
+		}
+		logger.Error("Erro ao buscar convite", "error", err)
+		return nil, err
+	}
+	switch {
+	case invite.UsedAt != nil:
+		return nil, ErrInviteUsed
+	case time.Now().After(invite.ExpiresAt):
+		return nil, ErrInviteExpired
+	}
+	return &invite, nil
+}
+
+// Redeem runs register with the invite behind token and gives the new user the invite's role.
+// The invite is claimed before register runs, so two sign-ups can't share it, and released again
+// when register fails. emailAddr must match the invite's email, if it has one.
+func (s *InviteService) Redeem(token, emailAddr string, register func() (*models.User, error)) (*models.User, error) {
+	invite, err := s.Lookup(token)
+	if err != nil {
+		return nil, err
+	}
+	if invite.Email != "" && !strings.EqualFold(invite.Email, strings.TrimSpace(emailAddr)) {
+		return nil, ErrInviteEmailMismatch
+	}
+
+	claim := s.db.Model(&models.Invite{}).Where("id = ? AND used_at IS NULL", invite.ID).Update("used_at", time.Now())
+	if claim.Error != nil {
+		logger.Error("Erro ao reservar convite", "error", claim.Error, "invite_id", invite.ID)
+		return nil, claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		return nil, ErrInviteUsed
+	}
+
+	user, err := register()
+	if err != nil {
+		if releaseErr := s.db.Model(&models.Invite{}).Where("id = ?", invite.ID).Update("used_at", nil).Error; releaseErr != nil {
+			logger.Error("Erro ao liberar convite", "error", releaseErr, "invite_id", invite.ID)
+		}
+		return nil, err
+	}
+
+	// The account exists at this point; a failure below only costs the invited role, so it's logged
+	err = WithTransaction(s.db, func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Invite{}).Where("id = ?", invite.ID).Update("used_by", user.ID).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).Where("id = ?", user.ID).Update("role", invite.Role).Error
+	})
+	if err != nil {
+		logger.Error("Erro ao aplicar convite ao usuário", "error", err, "invite_id", invite.ID, "user_id", user.ID)
+		return user, nil
+	}
+	user.Role = invite.Role
+	logger.Info("Convite usado", "invite_id", invite.ID, "user_id", user.ID, "role", invite.Role)
+	return user, nil
+}
+
+// List returns every invite, newest first.
+func (s *InviteService) List() ([]models.Invite, error) {
+	var invites []models.Invite
+	if err := s.db.Order("id DESC").Find(&invites).Error; err != nil {
+		logger.Error("Erro ao listar convites", "error", err)
+		return nil, err
+	}
+	return invites, nil
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInviteService_Create(t *testing.T) {
+	_, _, _, _, _, db := setupTest(t)
+	invites := NewInviteService(db, NewAuditService(db), 0)
+
+	invite, token, err := invites.Create(" new@example.com ", "superuser", "3", "10.0.0.1")
+	require.NoError(t, err)
+	assert.Len(t, token, 64)
+	assert.Equal(t, hashToken(token), invite.TokenHash, "only the token hash is stored")
+	assert.Equal(t, "new@example.com", invite.Email)
+	assert.Equal(t, RoleUser, invite.Role, "unknown roles fall back to user")
+	assert.WithinDuration(t, time.Now().Add(DefaultInviteTTL), invite.ExpiresAt, time.Minute)
+
+	_, _, err = invites.Create("not-an-email", RoleAdmin, "3", "10.0.0.1")
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+
+	var audits []models.AuditLog
+	require.NoError(t, db.Where("action = ?", AuditActionInviteCreate).Find(&audits).Error)
+	require.Len(t, audits, 1)
+	assert.Equal(t, "role=user email=new@example.com", audits[0].Details)
+}
+
+func TestInviteService_Lookup(t *testing.T) {
+	_, _, _, _, _, db := setupTest(t)
+	invites := NewInviteService(db, nil, time.Hour)
+
+	_, valid, err := invites.Create("", RoleAdmin, "1", "")
+	require.NoError(t, err)
+	expired, expiredToken, err := invites.Create("", RoleUser, "1", "")
+	require.NoError(t, err)
+	require.NoError(t, db.Model(expired).Update("expires_at", time.Now().Add(-time.Minute)).Error)
+	used, usedToken, err := invites.Create("", RoleUser, "1", "")
+	require.NoError(t, err)
+	require.NoError(t, db.Model(used).Update("used_at", time.Now()).Error)
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{"Valid", valid, nil},
+		{"Expired", expiredToken, ErrInviteExpired},
+		{"Used", usedToken, ErrInviteUsed},
+		{"Unknown", "deadbeef", ErrInviteNotFound},
+		{"Missing", "", ErrInviteNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invite, err := invites.Lookup(tt.token)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, RoleAdmin, invite.Role)
+		})
+	}
+}
+
+func TestInviteService_Redeem(t *testing.T) {
+	authService, _, _, _, _, db := setupTest(t)
+	invites := NewInviteService(db, nil, 0)
+	register := func(username, email string) func() (*models.User, error) {
+		return func() (*models.User, error) {
+			return authService.Register(username, email, "Test123!@#", "Invited")
+		}
+	}
+
+	t.Run("Assigns the invite role and marks it used", func(t *testing.T) {
+		invite, token, err := invites.Create("", RoleAdmin, "1", "")
+		require.NoError(t, err)
+
+		user, err := invites.Redeem(token, "invited@example.com", register("invited", "invited@example.com"))
+		require.NoError(t, err)
+		assert.Equal(t, RoleAdmin, user.Role)
+
+		var stored models.User
+		require.NoError(t, db.First(&stored, user.ID).Error)
+		assert.Equal(t, RoleAdmin, stored.Role)
+		var redeemed models.Invite
+		require.NoError(t, db.First(&redeemed, invite.ID).Error)
+		assert.NotNil(t, redeemed.UsedAt)
+		assert.Equal(t, user.ID, redeemed.UsedBy)
+
+		_, err = invites.Redeem(token, "other@example.com", register("other", "other@example.com"))
+		assert.ErrorIs(t, err, ErrInviteUsed, "an invite registers one user only")
+	})
+
+	t.Run("Email must match", func(t *testing.T) {
+		_, token, err := invites.Create("Only@Example.com", RoleUser, "1", "")
+		require.NoError(t, err)
+
+		called := false
+		_, err = invites.Redeem(token, "someone@example.com", func() (*models.User, error) { called = true; return nil, nil })
+		assert.ErrorIs(t, err, ErrInviteEmailMismatch)
+		assert.False(t, called)
+
+		_, err = invites.Redeem(token, "only@example.com", register("only", "only@example.com"))
+		assert.NoError(t, err, "the email comparison ignores case")
+	})
+
+	t.Run("Failed registration releases the invite", func(t *testing.T) {
+		_, token, err := invites.Create("", RoleUser, "1", "")
+		require.NoError(t, err)
+
+		_, err = invites.Redeem(token, "x@example.com", func() (*models.User, error) { return nil, errors.New("username already exists") })
+		require.Error(t, err)
+
+		_, err = invites.Lookup(token)
+		assert.NoError(t, err, "the invite can be used again")
+	})
+
+	t.Run("Expired and missing invites don't register", func(t *testing.T) {
+		expired, token, err := invites.Create("", RoleUser, "1", "")
+		require.NoError(t, err)
+		require.NoError(t, db.Model(expired).Update("expires_at", time.Now().Add(-time.Second)).Error)
+
+		called := false
+		noRegister := func() (*models.User, error) { called = true; return nil, nil }
+		_, err = invites.Redeem(token, "", noRegister)
+		assert.ErrorIs(t, err, ErrInviteExpired)
+		_, err = invites.Redeem("", "", noRegister)
+		assert.ErrorIs(t, err, ErrInviteNotFound)
+		assert.False(t, called)
+	})
+}
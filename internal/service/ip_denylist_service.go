@@ -0,0 +1,166 @@
+package service
+
+import (
+	"errors"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidIPOrCIDR is returned when a denylist entry is neither an IP address nor a CIDR range.
+var ErrInvalidIPOrCIDR = errors.New("IP ou faixa CIDR inválida")
+
+// maxDenyReasonLen is the column limit of models.DeniedIP.Reason.
+const maxDenyReasonLen = 255
+
+// ErrDeniedIPNotFound is returned when removing a denylist entry that doesn't exist.
+var ErrDeniedIPNotFound = errors.New("entrada não encontrada na lista de bloqueio")
+
+// ErrDenyAll is returned for a range covering every address (0.0.0.0/0, ::/0), which would lock everyone out.
+var ErrDenyAll = errors.New("essa faixa bloquearia todos os IPs")
+
+// ErrDenySelf is returned when an entry would block the IP the admin's own request comes from.
+var ErrDenySelf = errors.New("esse bloqueio incluiria o seu próprio IP")
+
+// DenylistEnforcer applies the stored entries to requests (middleware.IPDenylist).
+type DenylistEnforcer interface {
+	SetDynamic(entries []string) error
+}
+
+// IPDenylistService stores the IPs and CIDR ranges admins have blocked.
+type IPDenylistService struct {
+	db       *gorm.DB
+	audit    AuditRecorder
+	enforcer DenylistEnforcer
+}
+
+// NewIPDenylistService creates a new IPDenylistService instance; changes are audited when audit is not nil.
+func NewIPDenylistService(db *gorm.DB, audit AuditRecorder) *IPDenylistService {
+	return &IPDenylistService{db: db, audit: audit}
+}
+
+// NormalizeIPOrCIDR returns the canonical form of an IP ("::ffff:1.2.3.4" becomes "1.2.3.4") or of a CIDR
+// range (host bits cleared, "10.0.0.7/8" becomes "10.0.0.0/8").
+func NormalizeIPOrCIDR(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return "", ErrInvalidIPOrCIDR
+		}
+		return prefix.Masked().String(), nil
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return "", ErrInvalidIPOrCIDR
+	}
+	return addr.Unmap().WithZone("").String(), nil
+}
+
+// Add blocks an IP or CIDR range. Adding an entry that already exists returns it unchanged. ip is the
+// admin's own address: an entry containing it (ErrDenySelf), like a catch-all range (ErrDenyAll), is
+// refused with a *ValidationError, so an admin can't lock themselves or everyone out.
+func (s *IPDenylistService) Add(value, reason, adminID, ip string) (*models.DeniedIP, error) {
+	normalized, err := NormalizeIPOrCIDR(value)
+	if err != nil {
+		return nil, &ValidationError{Err: err}
+	}
+	if err := checkDenyEntry(normalized, ip); err != nil {
+		return nil, &ValidationError{Err: err}
+	}
+
+	var existing models.DeniedIP
+	err = s.db.Where("value = ?", normalized).First(&existing).Error
+	if err == nil {
+		return &existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		logger.Error("Erro ao consultar lista de IPs bloqueados", "error", err, "value", normalized)
+		return nil, err
+	}
+
+	actor, _ := strconv.ParseUint(adminID, 10, 64)
+	entry := &models.DeniedIP{Value: normalized, Reason: truncate(strings.TrimSpace(reason), maxDenyReasonLen), CreatedBy: uint(actor)}
+	if err := s.db.Create(entry).Error; err != nil {
+		logger.Error("Erro ao bloquear IP", "error", err, "value", normalized)
+		return nil, err
+	}
+	if s.audit != nil {
+		_ = s.audit.Record(&models.AuditLog{Action: AuditActionIPDeny, ActorID: uint(actor), IP: ip, Details: normalized})
+	}
+	logger.Info("IP bloqueado", "value", normalized, "admin_id", adminID)
+	return entry, s.sync()
+}
+
+// checkDenyEntry refuses a normalized entry that covers every address or contains ip (when ip is valid).
+func checkDenyEntry(normalized, ip string) error {
+	prefix, err := netip.ParsePrefix(normalized)
+	if err != nil {
+		// A single address
+		addr, _ := netip.ParseAddr(normalized)
+		prefix = netip.PrefixFrom(addr, addr.BitLen())
+	} else if prefix.Bits() == 0 {
+		return ErrDenyAll
+	}
+	if self, err := netip.ParseAddr(ip); err == nil && prefix.Contains(self.Unmap().WithZone("")) {
+		return ErrDenySelf
+	}
+	return nil
+}
+
+// Remove deletes an entry by id, unblocking it immediately when an enforcer is set.
+func (s *IPDenylistService) Remove(id, adminID, ip string) error {
+	var entry models.DeniedIP
+	if err := s.db.Where("id = ?", id).First(&entry).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrDeniedIPNotFound
+		}
+		logger.Error("Erro ao consultar lista de IPs bloqueados", "error", err, "id", id)
+		return err
+	}
+	if err := s.db.Delete(&entry).Error; err != nil {
+		logger.Error("Erro ao desbloquear IP", "error", err, "value", entry.Value)
+		return err
+	}
+
+	actor, _ := strconv.ParseUint(adminID, 10, 64)
+	if s.audit != nil {
+		_ = s.audit.Record(&models.AuditLog{Action: AuditActionIPAllow, ActorID: uint(actor), IP: ip, Details: entry.Value})
+	}
+	logger.Info("IP desbloqueado", "value", entry.Value, "admin_id", adminID)
+	return s.sync()
+}
+
+// EnforceWith loads the stored entries into enforcer now and again after every Add or Remove.
+func (s *IPDenylistService) EnforceWith(enforcer DenylistEnforcer) error {
+	s.enforcer = enforcer
+	return s.sync()
+}
+
+// sync pushes every stored entry to the enforcer, if any.
+func (s *IPDenylistService) sync() error {
+	if s.enforcer == nil {
+		return nil
+	}
+	var values []string
+	if err := s.db.Model(&models.DeniedIP{}).Order("id ASC").Pluck("value", &values).Error; err != nil {
+		logger.Error("Erro ao carregar lista de IPs bloqueados", "error", err)
+		return err
+	}
+	return s.enforcer.SetDynamic(values)
+}
+
+// List returns every entry, oldest first.
+func (s *IPDenylistService) List() ([]models.DeniedIP, error) {
+	var entries []models.DeniedIP
+	if err := s.db.Order("id ASC").Find(&entries).Error; err != nil {
+		logger.Error("Erro ao listar IPs bloqueados", "error", err)
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeIPOrCIDR(t *testing.T) {
+	tests := []struct {
+		in, want string
+		wantErr  bool
+	}{
+		{" 10.0.0.1 ", "10.0.0.1", false},
+		{"::ffff:10.0.0.1", "10.0.0.1", false},
+		{"2001:DB8::1", "2001:db8::1", false},
+		{"10.1.2.3/8", "10.0.0.0/8", false},
+		{"2001:db8::1/32", "2001:db8::/32", false},
+		{"10.0.0.256", "", true},
+		{"10.0.0.0/33", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeIPOrCIDR(tt.in)
+		if tt.wantErr {
+			assert.ErrorIs(t, err, ErrInvalidIPOrCIDR, tt.in)
+			continue
+		}
+		require.NoError(t, err, tt.in)
+		assert.Equal(t, tt.want, got)
+	}
+}
+
+func TestIPDenylistService_Add(t *testing.T) {
+	_, _, _, _, _, db := setupTest(t)
+	denylist := NewIPDenylistService(db, NewAuditService(db))
+
+	entry, err := denylist.Add("::ffff:203.0.113.9", "brute force", "7", "10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, "203.0.113.9", entry.Value)
+	assert.Equal(t, uint(7), entry.CreatedBy)
+
+	again, err := denylist.Add("203.0.113.9", "other", "8", "10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, entry.ID, again.ID, "adding an existing entry is a no-op")
+	assert.Equal(t, "brute force", again.Reason)
+
+	_, err = denylist.Add("not-an-ip", "", "7", "10.0.0.1")
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+
+	// Nobody can lock everyone out, or themselves
+	for _, value := range []string{"0.0.0.0/0", "::/0", "10.1.2.3/0"} {
+		_, err = denylist.Add(value, "", "7", "10.0.0.1")
+		require.ErrorAs(t, err, &validationErr, value)
+		assert.ErrorIs(t, err, ErrDenyAll, value)
+	}
+	for _, value := range []string{"10.0.0.1", "10.0.0.0/8", "::ffff:10.0.0.1"} {
+		_, err = denylist.Add(value, "", "7", "::ffff:10.0.0.1")
+		require.ErrorAs(t, err, &validationErr, value)
+		assert.ErrorIs(t, err, ErrDenySelf, value)
+	}
+
+	entries, err := denylist.List()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	var audits []models.AuditLog
+	require.NoError(t, db.Where("action = ?", AuditActionIPDeny).Find(&audits).Error)
+	require.Len(t, audits, 1)
+	assert.Equal(t, uint(7), audits[0].ActorID)
+	assert.Equal(t, "203.0.113.9", audits[0].Details)
+}
+
+// fakeEnforcer records the last entries pushed by IPDenylistService.
+type fakeEnforcer struct{ entries []string }
+
+func (f *fakeEnforcer) SetDynamic(entries []string) error {
+	f.entries = entries
+	return nil
+}
+
+func TestIPDenylistService_EnforceWith(t *testing.T) {
+	_, _, _, _, _, db := setupTest(t)
+	denylist := NewIPDenylistService(db, NewAuditService(db))
+	_, err := denylist.Add("192.0.2.1", "", "1", "")
+	require.NoError(t, err)
+
+	enforcer := &fakeEnforcer{}
+	require.NoError(t, denylist.EnforceWith(enforcer))
+	assert.Equal(t, []string{"192.0.2.1"}, enforcer.entries, "stored entries are loaded at startup")
+
+	entry, err := denylist.Add("198.51.100.0/24", "", "1", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"192.0.2.1", "198.51.100.0/24"}, enforcer.entries)
+
+	require.NoError(t, denylist.Remove(strconv.FormatUint(uint64(entry.ID), 10), "1", ""))
+	assert.Equal(t, []string{"192.0.2.1"}, enforcer.entries)
+	assert.ErrorIs(t, denylist.Remove("999", "1", ""), ErrDeniedIPNotFound)
+
+	var removed int64
+	db.Model(&models.AuditLog{}).Where("action = ?", AuditActionIPAllow).Count(&removed)
+	assert.Equal(t, int64(1), removed)
+}
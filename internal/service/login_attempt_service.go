@@ -0,0 +1,182 @@
+package service
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Reasons stored in models.LoginAttempt.Reason.
+const (
+	AttemptReasonSuccess            = "success"
+	AttemptReasonInvalidCredentials = "invalid_credentials"
+	AttemptReasonInactive           = "inactive"
+	AttemptReasonLocked             = "locked"
+	AttemptReasonUnverified         = "unverified"
+	AttemptReasonError              = "error"
+	AttemptReasonChallenged         = "challenged"     // right password, held back for the new country code
+	AttemptReasonPasskeyCloned      = "passkey_cloned" // the passkey's sign counter went backwards
+)
+
+// LoginAttemptRecorder persists login attempts. AuthService and PasskeyService only need this half of
+// LoginAttemptService.
+type LoginAttemptRecorder interface {
+	Record(attempt *models.LoginAttempt) error
+}
+
+// LoginAttemptFilter narrows the admin login attempt listing. PerPage <= 0 returns every match.
+type LoginAttemptFilter struct {
+	Identifier string // exact match, case-insensitive
+	IP         string
+	Success    *bool
+	Page       int
+	PerPage    int
+}
+
+// LoginAttemptPage is one page of the login attempt listing.
+type LoginAttemptPage struct {
+	Attempts []models.LoginAttempt
+	Total    int64
+	Page     int
+	PerPage  int
+}
+
+// LoginAttemptService stores and queries login attempts.
+type LoginAttemptService struct {
+	db *gorm.DB
+}
+
+// NewLoginAttemptService creates a new LoginAttemptService instance
+func NewLoginAttemptService(db *gorm.DB) *LoginAttemptService {
+	return &LoginAttemptService{db: db}
+}
+
+// Column limits of models.LoginAttempt; longer client-supplied values are truncated.
+const (
+	maxAttemptIdentifierLen = 255
+	maxAttemptUserAgentLen  = 500
+)
+
+// Record inserts an attempt; CreatedAt defaults to now.
+func (s *LoginAttemptService) Record(attempt *models.LoginAttempt) error {
+	if attempt.CreatedAt.IsZero() {
+		attempt.CreatedAt = time.Now()
+	}
+	attempt.Identifier = truncate(attempt.Identifier, maxAttemptIdentifierLen)
+	attempt.UserAgent = truncate(attempt.UserAgent, maxAttemptUserAgentLen)
+	if err := s.db.Create(attempt).Error; err != nil {
+		logger.Error("Erro ao registrar tentativa de login", "error", err, "identifier", attempt.Identifier)
+		return err
+	}
+	return nil
+}
+
+// List returns attempts matching filter, newest first.
+func (s *LoginAttemptService) List(filter LoginAttemptFilter) (*LoginAttemptPage, error) {
+	query := s.db.Model(&models.LoginAttempt{})
+	if identifier := strings.ToLower(strings.TrimSpace(filter.Identifier)); identifier != "" {
+		query = query.Where("LOWER(identifier) = ?", identifier)
+	}
+	if ip := strings.TrimSpace(filter.IP); ip != "" {
+		query = query.Where("ip = ?", ip)
+	}
+	if filter.Success != nil {
+		query = query.Where("success = ?", *filter.Success)
+	}
+
+	page := &LoginAttemptPage{Page: filter.Page, PerPage: filter.PerPage}
+	if err := query.Count(&page.Total).Error; err != nil {
+		logger.Error("Erro ao contar tentativas de login", "error", err)
+		return nil, err
+	}
+
+	query = query.Order("created_at DESC").Order("id DESC")
+	if filter.PerPage > 0 {
+		if page.Page < 1 {
+			page.Page = 1
+		}
+		query = query.Offset((page.Page - 1) * filter.PerPage).Limit(filter.PerPage)
+	}
+	if err := query.Find(&page.Attempts).Error; err != nil {
+		logger.Error("Erro ao listar tentativas de login", "error", err)
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// FailureBursts are the IPs and identifiers (lowercased) with at least the threshold of failed logins in a
+// time window, mapped to their failure count.
+type FailureBursts struct {
+	IPs         map[string]int64
+	Identifiers map[string]int64
+}
+
+// Bursts finds the IPs and accounts with at least threshold failed attempts since the given time.
+func (s *LoginAttemptService) Bursts(since time.Time, threshold int) (*FailureBursts, error) {
+	bursts := &FailureBursts{IPs: map[string]int64{}, Identifiers: map[string]int64{}}
+	for column, counts := range map[string]map[string]int64{"ip": bursts.IPs, "LOWER(identifier)": bursts.Identifiers} {
+		var rows []struct {
+			Value    string
+			Failures int64
+		}
+		err := s.db.Model(&models.LoginAttempt{}).
+			Select(column+" AS value, COUNT(*) AS failures").
+			Where("success = ? AND created_at >= ? AND "+column+" <> ''", false, since).
+			Group(column).
+			Having("COUNT(*) >= ?", threshold).
+			Scan(&rows).Error
+		if err != nil {
+			logger.Error("Erro ao agrupar falhas de login", "error", err, "column", column)
+			return nil, err
+		}
+		for _, row := range rows {
+			counts[row.Value] = row.Failures
+		}
+	}
+	return bursts, nil
+}
+
+// LoginFailures counts the uncleared wrong-password attempts of identifier since the given time, and
+// returns the oldest one's time (auth.LoginFailureStore). Attempts refused for another reason (locked,
+// inactive, ...) don't count, so retrying against a locked account doesn't extend the lock.
+func (s *LoginAttemptService) LoginFailures(identifier string, since time.Time) (int, time.Time, error) {
+	var failures int64
+	if err := s.failures(identifier).Where("created_at >= ?", since).Count(&failures).Error; err != nil || failures == 0 {
+		return 0, time.Time{}, err
+	}
+	var oldest models.LoginAttempt
+	if err := s.failures(identifier).Where("created_at >= ?", since).Order("created_at").First(&oldest).Error; err != nil {
+		return 0, time.Time{}, err
+	}
+	return int(failures), oldest.CreatedAt, nil
+}
+
+// ClearLoginFailures stops counting the wrong-password attempts of identifier toward the lockout
+// (auth.LoginFailureStore); the attempts stay in the log.
+func (s *LoginAttemptService) ClearLoginFailures(identifier string) error {
+	return s.failures(identifier).Update("cleared", true).Error
+}
+
+// failures selects the uncleared wrong-password attempts of identifier (case-insensitive).
+func (s *LoginAttemptService) failures(identifier string) *gorm.DB {
+	return s.db.Model(&models.LoginAttempt{}).
+		Where("LOWER(identifier) = ? AND success = ? AND reason = ? AND cleared = ?",
+			strings.ToLower(truncate(identifier, maxAttemptIdentifierLen)), false, AttemptReasonInvalidCredentials, false)
+}
+
+// truncate cuts s to at most n bytes without splitting a UTF-8 sequence.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
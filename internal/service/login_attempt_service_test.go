@@ -0,0 +1,109 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthService_Login_RecordsAttempts(t *testing.T) {
+	authService, _, _, _, _, db := setupTest(t)
+	_ = createTestUser(t, db)
+
+	_, err := authService.Login("testuser", "wrongpass", "10.0.0.1", "bad-agent")
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = authService.Login("testuser", "password123", "10.0.0.2", "good-agent")
+	require.NoError(t, err)
+
+	var attempts []models.LoginAttempt
+	require.NoError(t, db.Order("id").Find(&attempts).Error)
+	require.Len(t, attempts, 2)
+
+	failed := attempts[0]
+	assert.Equal(t, "testuser", failed.Identifier)
+	assert.Equal(t, "10.0.0.1", failed.IP)
+	assert.Equal(t, "bad-agent", failed.UserAgent)
+	assert.False(t, failed.Success)
+	assert.Equal(t, AttemptReasonInvalidCredentials, failed.Reason)
+	assert.WithinDuration(t, time.Now(), failed.CreatedAt, time.Minute)
+
+	ok := attempts[1]
+	assert.Equal(t, "testuser", ok.Identifier)
+	assert.Equal(t, "10.0.0.2", ok.IP)
+	assert.True(t, ok.Success)
+	assert.Equal(t, AttemptReasonSuccess, ok.Reason)
+}
+
+func TestLoginAttemptService_List(t *testing.T) {
+	_, _, _, _, _, db := setupTest(t)
+	attempts := NewLoginAttemptService(db)
+	base := time.Now().Add(-time.Hour)
+
+	for i, a := range []models.LoginAttempt{
+		{Identifier: "alice", IP: "10.0.0.1", Success: false, Reason: AttemptReasonInvalidCredentials},
+		{Identifier: "Alice", IP: "10.0.0.1", Success: true, Reason: AttemptReasonSuccess},
+		{Identifier: "bob", IP: "10.0.0.2", Success: false, Reason: AttemptReasonInvalidCredentials},
+		{Identifier: "bob", IP: "10.0.0.1", UserAgent: strings.Repeat("x", 600), Success: false, Reason: AttemptReasonLocked},
+	} {
+		a.CreatedAt = base.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, attempts.Record(&a))
+	}
+
+	failed := false
+	tests := []struct {
+		name   string
+		filter LoginAttemptFilter
+		total  int64
+	}{
+		{"All", LoginAttemptFilter{}, 4},
+		{"Identifier is case-insensitive", LoginAttemptFilter{Identifier: "ALICE"}, 2},
+		{"By IP", LoginAttemptFilter{IP: "10.0.0.1"}, 3},
+		{"Failures for account", LoginAttemptFilter{Identifier: "bob", Success: &failed}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := attempts.List(tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.total, page.Total)
+			assert.Len(t, page.Attempts, int(tt.total))
+		})
+	}
+
+	page, err := attempts.List(LoginAttemptFilter{PerPage: 3, Page: 2})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), page.Total)
+	require.Len(t, page.Attempts, 1)
+	assert.Equal(t, "alice", page.Attempts[0].Identifier, "oldest attempt comes last")
+
+	page, err = attempts.List(LoginAttemptFilter{PerPage: 1})
+	require.NoError(t, err)
+	assert.Len(t, page.Attempts[0].UserAgent, maxAttemptUserAgentLen, "long user agents are truncated")
+}
+
+func TestLoginAttemptService_Bursts(t *testing.T) {
+	_, _, _, _, _, db := setupTest(t)
+	attempts := NewLoginAttemptService(db)
+	now := time.Now()
+
+	record := func(identifier, ip string, success bool, age time.Duration) {
+		require.NoError(t, attempts.Record(&models.LoginAttempt{Identifier: identifier, IP: ip, Success: success, CreatedAt: now.Add(-age)}))
+	}
+	for i := range 3 {
+		record("alice", "10.0.0.1", false, time.Duration(i)*time.Minute)
+		record("Bob", "10.0.0.2", false, time.Minute)
+	}
+	record("bob", "10.0.0.3", false, time.Minute)
+	record("carol", "10.0.0.1", true, time.Minute)  // successes don't count
+	record("carol", "10.0.0.4", false, 2*time.Hour) // outside the window
+	record("carol", "10.0.0.4", false, 2*time.Hour+time.Second)
+
+	bursts, err := attempts.Bursts(now.Add(-time.Hour), 3)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"10.0.0.1": 3, "10.0.0.2": 3}, bursts.IPs)
+	assert.Equal(t, map[string]int64{"alice": 3, "bob": 4}, bursts.Identifiers, "accounts are grouped case-insensitively")
+}
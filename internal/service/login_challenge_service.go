@@ -0,0 +1,336 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/email"
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// DefaultLoginCodeTTL is how long the emailed login code can be used when config
+// login.new_country_challenge.code_ttl is unset.
+const DefaultLoginCodeTTL = 10 * time.Minute
+
+// maxPendingLoginChallenges caps the challenged logins kept in memory. Only a right password creates
+// one, but each also sends an email, so the cap keeps a leaked password from flooding either.
+const maxPendingLoginChallenges = 10000
+
+// maxLoginCodeAttempts is how many wrong codes a challenge takes before it is dropped and the user has
+// to log in again (and get a new code).
+const maxLoginCodeAttempts = 5
+
+// maxKnownCountries keeps models.User.KnownCountries within its column; the oldest countries go first.
+const maxKnownCountries = 80
+
+var (
+	ErrLoginChallengeNotFound = errors.New("o código expirou ou já foi usado; entre novamente")
+	ErrLoginCodeInvalid       = errors.New("código incorreto")
+	ErrLoginChallengeBusy     = errors.New("muitos logins aguardando confirmação; tente novamente em instantes")
+)
+
+// LoginChallengeError ends a password login that must be confirmed with the code emailed to the user
+// before a session is opened; ChallengeID goes to LoginChallengeService.Verify with the code.
+type LoginChallengeError struct {
+	ChallengeID string
+}
+
+func (e *LoginChallengeError) Error() string {
+	return "confirme o login com o código enviado para o seu email"
+}
+
+// loginChallenge is a login held back until the user types the emailed code. Only the code's hash is kept.
+type loginChallenge struct {
+	userID   string
+	country  string
+	codeHash [sha256.Size]byte
+	attempts int
+	expires  time.Time
+}
+
+// LoginChallengeService asks for a step-up verification when a user logs in from a country they never
+// logged in from (config login.new_country_challenge): the right password is not enough, a 6-digit code
+// is emailed to the account and the session is only opened once it is typed. The countries a user has
+// logged in from are kept in models.User.KnownCountries; the first login with a country just records it.
+//
+// It plugs into the password login as the auth.AuthManager OnCredentialsVerified hook (Check). The
+// country comes in auth.SessionMetadata.Country; a login without one (no proxy header, an anonymous
+// proxy or Tor) can't be told apart from a new country, so it is challenged too and never recorded.
+// Challenges live in memory, so with several instances the code must reach the instance that sent it.
+// With UseBackupCodes, one of the user's backup codes is accepted in place of the emailed code, and with
+// UseTrustedDevices a device the user chose to remember skips the check.
+type LoginChallengeService struct {
+	db           *gorm.DB
+	authManager  *auth.AuthManager
+	emailService email.EmailServiceInterface
+	backupCodes  *BackupCodeService
+	devices      *TrustedDeviceService
+	ttl          time.Duration
+	now          func() time.Time
+
+	mu      sync.Mutex
+	pending map[string]loginChallenge
+}
+
+// NewLoginChallengeService creates a new LoginChallengeService instance. ttl <= 0 uses DefaultLoginCodeTTL.
+func NewLoginChallengeService(db *gorm.DB, authManager *auth.AuthManager, emailService email.EmailServiceInterface, ttl time.Duration) *LoginChallengeService {
+	if ttl <= 0 {
+		ttl = DefaultLoginCodeTTL
+	}
+	return &LoginChallengeService{
+		db:           db,
+		authManager:  authManager,
+		emailService: emailService,
+		ttl:          ttl,
+		now:          time.Now,
+		pending:      make(map[string]loginChallenge),
+	}
+}
+
+// UseBackupCodes lets Verify accept an unused backup code of the user instead of the emailed code, for
+// when the email can't be received. Call it during setup, before serving requests.
+func (s *LoginChallengeService) UseBackupCodes(codes *BackupCodeService) {
+	s.backupCodes = codes
+}
+
+// UseTrustedDevices lets Check pass logins carrying a remembered device's token
+// (auth.SessionMetadata.DeviceToken) without the emailed code. Call it during setup, before serving requests.
+func (s *LoginChallengeService) UseTrustedDevices(devices *TrustedDeviceService) {
+	s.devices = devices
+}
+
+// Check decides whether a password login from metadata.Country needs the emailed code. It returns nil
+// to let the login go on (the user's first country, one they logged in from before, or a remembered
+// device) and a *LoginChallengeError once the code is sent. An empty country is not known, so it is
+// always challenged unless the device is remembered. Register it with authManager.OnCredentialsVerified(s.Check).
+func (s *LoginChallengeService) Check(user *auth.UserData, metadata auth.SessionMetadata) error {
+	country := metadata.Country
+	known, err := s.knownCountries(user.ID)
+	if err != nil {
+		return err
+	}
+	if country != "" && slices.Contains(known, country) {
+		return nil
+	}
+	if country != "" && len(known) == 0 {
+		// Nothing to compare with yet: the first country is trusted
+		s.rememberCountry(user.ID, known, country)
+		return nil
+	}
+
+	if s.devices != nil && s.devices.Trusted(user.ID, metadata.DeviceToken) {
+		// The country is not recorded: the device is trusted, not where it is
+		logger.Info("Login de país novo liberado por dispositivo lembrado", "user_id", user.ID, "country", country, "ip", metadata.IP)
+		return nil
+	}
+
+	id, err := s.issue(user, country)
+	if err != nil {
+		return err
+	}
+	logger.Info("Login de país novo aguardando código", "user_id", user.ID, "country", country, "ip", metadata.IP)
+	return &LoginChallengeError{ChallengeID: id}
+}
+
+// issue stores a challenge for user and emails its code. The code is sent before the challenge is
+// returned, so a failed email fails the login instead of leaving the user waiting for it.
+func (s *LoginChallengeService) issue(user *auth.UserData, country string) (string, error) {
+	idBytes := make([]byte, 32)
+	if _, err := auth.GenerateRandomBytes(idBytes); err != nil {
+		logger.Error("Erro ao gerar ID do desafio de login", "error", err)
+		return "", err
+	}
+	id := hex.EncodeToString(idBytes)
+	n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+	if err != nil {
+		logger.Error("Erro ao gerar código de login", "error", err)
+		return "", err
+	}
+	code := fmt.Sprintf("%06d", n.Int64())
+
+	s.mu.Lock()
+	now := s.now()
+	if len(s.pending) >= maxPendingLoginChallenges {
+		for key, challenge := range s.pending {
+			if !now.Before(challenge.expires) {
+				delete(s.pending, key)
+			}
+		}
+		if len(s.pending) >= maxPendingLoginChallenges {
+			s.mu.Unlock()
+			logger.Warn("Limite de desafios de login pendentes atingido", "pending", len(s.pending))
+			return "", ErrLoginChallengeBusy
+		}
+	}
+	s.pending[id] = loginChallenge{userID: user.ID, country: country, codeHash: sha256.Sum256([]byte(code)), expires: now.Add(s.ttl)}
+	s.mu.Unlock()
+
+	if err := s.emailService.SendLoginCode(user.Email, code, user.Identifier, user.DisplayName); err != nil {
+		logger.Error("Erro ao enviar código de login", "error", err, "user_id", user.ID)
+		s.mu.Lock()
+		delete(s.pending, id)
+		s.mu.Unlock()
+		return "", err
+	}
+	return id, nil
+}
+
+// take checks code against the challenge behind id and removes it when it is right, expired, or out of
+// attempts. It returns the challenge on success. A code that isn't the emailed one may still be one of
+// the user's backup codes (see UseBackupCodes), which is then used up; that lookup runs without s.mu, so
+// other challenges aren't held up by the database.
+func (s *LoginChallengeService) take(id, code string) (loginChallenge, error) {
+	s.mu.Lock()
+	challenge, ok := s.pending[id]
+	if !ok {
+		s.mu.Unlock()
+		return loginChallenge{}, ErrLoginChallengeNotFound
+	}
+	if !s.now().Before(challenge.expires) {
+		delete(s.pending, id)
+		s.mu.Unlock()
+		return loginChallenge{}, ErrLoginChallengeNotFound
+	}
+	hash := sha256.Sum256([]byte(strings.TrimSpace(code)))
+	if subtle.ConstantTimeCompare(hash[:], challenge.codeHash[:]) == 1 {
+		delete(s.pending, id)
+		s.mu.Unlock()
+		return challenge, nil
+	}
+	s.mu.Unlock()
+
+	backupCode := s.consumeBackupCode(challenge.userID, code)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Another request may have finished or dropped the challenge meanwhile
+	challenge, ok = s.pending[id]
+	if !ok {
+		return loginChallenge{}, ErrLoginChallengeNotFound
+	}
+	if backupCode {
+		delete(s.pending, id)
+		return challenge, nil
+	}
+	challenge.attempts++
+	if challenge.attempts >= maxLoginCodeAttempts {
+		delete(s.pending, id)
+		logger.Warn("Desafio de login descartado após códigos errados", "user_id", challenge.userID)
+		return loginChallenge{}, ErrLoginChallengeNotFound
+	}
+	s.pending[id] = challenge
+	return loginChallenge{}, ErrLoginCodeInvalid
+}
+
+// consumeBackupCode reports whether code is an unused backup code of userID, using it up. Emailed codes
+// are 6 digits, so only longer codes reach the database.
+func (s *LoginChallengeService) consumeBackupCode(userID, code string) bool {
+	if s.backupCodes == nil || len(strings.TrimSpace(code)) <= 6 {
+		return false
+	}
+	ok, err := s.backupCodes.Consume(userID, code)
+	if err != nil {
+		return false
+	}
+	if ok {
+		logger.Info("Login de país novo confirmado com código de backup", "user_id", userID)
+	}
+	return ok
+}
+
+// Verify finishes a challenged login: with the right code it adds the country to the user's known
+// ones and opens the session the password login held back. A wrong code can be retried a few times;
+// after that, or once the code expires, it returns ErrLoginChallengeNotFound.
+func (s *LoginChallengeService) Verify(challengeID, code string, metadata auth.SessionMetadata) (*LoginResponse, error) {
+	challenge, err := s.take(challengeID, code)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.authManager.GetUserAdapter().FindUserByID(challenge.userID)
+	if err != nil {
+		return nil, err
+	}
+	// The account may have changed while the code was in the inbox
+	status := s.authManager.LoginStatus(user)
+	switch status {
+	case auth.LoginStatusInactive:
+		return nil, ErrUserNotActive
+	case auth.LoginStatusLocked:
+		return nil, ErrAccountLocked
+	case auth.LoginStatusUnverified:
+		return nil, ErrEmailNotVerified
+	}
+
+	known, err := s.knownCountries(challenge.userID)
+	if err != nil {
+		return nil, err
+	}
+	s.rememberCountry(challenge.userID, known, challenge.country)
+
+	session, user, err := s.authManager.CreateSessionForUser(challenge.userID, metadata)
+	if err != nil {
+		if errors.Is(err, auth.ErrUserNotActive) {
+			return nil, ErrUserNotActive
+		}
+		return nil, err
+	}
+	if err := s.db.Model(&models.User{}).Where("id = ?", challenge.userID).Update("last_login", s.now()).Error; err != nil {
+		logger.Error("Erro ao atualizar último login", "error", err, "user_id", challenge.userID)
+	}
+	logger.Info("Login de país novo confirmado com código", "user_id", challenge.userID, "country", challenge.country, "ip", metadata.IP)
+
+	return &LoginResponse{
+		SessionID:   session.ID,
+		ExpiresAt:   session.ExpiresAt,
+		User:        *user,
+		LoginStatus: status,
+	}, nil
+}
+
+// knownCountries returns the countries userID has logged in from, oldest first.
+func (s *LoginChallengeService) knownCountries(userID string) ([]string, error) {
+	id, err := strconv.ParseUint(userID, 10, 64)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	var user models.User
+	if err := s.db.Select("id", "known_countries").First(&user, id).Error; err != nil {
+		logger.Error("Erro ao buscar países conhecidos do usuário", "error", err, "user_id", userID)
+		return nil, err
+	}
+	if user.KnownCountries == "" {
+		return nil, nil
+	}
+	return strings.Split(user.KnownCountries, ","), nil
+}
+
+// rememberCountry adds country to userID's known countries; an empty (unknown) country is never added.
+// A storage failure only means the next login from there is challenged again, so it is logged and not
+// returned.
+func (s *LoginChallengeService) rememberCountry(userID string, known []string, country string) {
+	if country == "" || slices.Contains(known, country) {
+		return
+	}
+	known = append(known, country)
+	if len(known) > maxKnownCountries {
+		known = known[len(known)-maxKnownCountries:]
+	}
+	if err := s.db.Model(&models.User{}).Where("id = ?", userID).Update("known_countries", strings.Join(known, ",")).Error; err != nil {
+		logger.Error("Erro ao salvar país conhecido do usuário", "error", err, "user_id", userID, "country", country)
+	}
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/email"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupLoginChallenges(t *testing.T) (*AuthService, *LoginChallengeService, *email.MockEmailService, *models.User) {
+	authService, authManager, _, _, mockEmail, db := setupTest(t)
+	challenges := NewLoginChallengeService(db, authManager, mockEmail, 0)
+	authManager.OnCredentialsVerified(challenges.Check)
+	user := createTestUser(t, db)
+	require.NoError(t, db.Model(user).Update("known_countries", "BR").Error)
+	return authService, challenges, mockEmail, user
+}
+
+func knownCountriesOf(t *testing.T, s *LoginChallengeService, user *models.User) []string {
+	known, err := s.knownCountries(idString(user.ID))
+	require.NoError(t, err)
+	return known
+}
+
+func TestLoginChallenge_KnownCountryProceeds(t *testing.T) {
+	authService, challenges, mockEmail, user := setupLoginChallenges(t)
+
+	response, err := authService.LoginFrom("testuser", "password123", auth.SessionMetadata{IP: "203.0.113.1", Country: "BR"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, response.SessionID)
+	assert.Empty(t, mockEmail.GetSentEmails())
+	assert.Equal(t, []string{"BR"}, knownCountriesOf(t, challenges, user))
+}
+
+func TestLoginChallenge_UnknownCountryRequiresCode(t *testing.T) {
+	authService, challenges, mockEmail, user := setupLoginChallenges(t)
+
+	// No country header (or "XX"/Tor from the proxy): it can't be a known country
+	_, err := authService.LoginFrom("testuser", "password123", auth.SessionMetadata{IP: "203.0.113.1"})
+	var challenge *LoginChallengeError
+	require.True(t, errors.As(err, &challenge), "want a LoginChallengeError, got %v", err)
+	sent := mockEmail.GetSentEmails()
+	require.Len(t, sent, 1)
+
+	_, err = challenges.Verify(challenge.ChallengeID, sent[0].Token, auth.SessionMetadata{IP: "203.0.113.1"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"BR"}, knownCountriesOf(t, challenges, user), "an unknown country is never recorded")
+
+	// Not even as the first country
+	require.NoError(t, challenges.db.Model(user).Update("known_countries", "").Error)
+	_, err = authService.LoginFrom("testuser", "password123", auth.SessionMetadata{})
+	require.True(t, errors.As(err, &challenge), "want a LoginChallengeError, got %v", err)
+	assert.Empty(t, knownCountriesOf(t, challenges, user))
+}
+
+func TestLoginChallenge_FirstCountryIsRecorded(t *testing.T) {
+	authService, challenges, _, user := setupLoginChallenges(t)
+	require.NoError(t, challenges.db.Model(user).Update("known_countries", "").Error)
+
+	_, err := authService.LoginFrom("testuser", "password123", auth.SessionMetadata{Country: "PT"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"PT"}, knownCountriesOf(t, challenges, user))
+}
+
+func TestLoginChallenge_NewCountryRequiresCode(t *testing.T) {
+	authService, challenges, mockEmail, user := setupLoginChallenges(t)
+
+	response, err := authService.LoginFrom("testuser", "password123", auth.SessionMetadata{IP: "198.51.100.7", Country: "US"})
+	var challenge *LoginChallengeError
+	require.True(t, errors.As(err, &challenge), "want a LoginChallengeError, got %v", err)
+	assert.Nil(t, response)
+	assert.NotEmpty(t, challenge.ChallengeID)
+
+	var sessions int64
+	require.NoError(t, challenges.db.Model(&models.Session{}).Count(&sessions).Error)
+	assert.Zero(t, sessions, "no session before the code is confirmed")
+
+	sent := mockEmail.GetSentEmails()
+	require.Len(t, sent, 1)
+	assert.Equal(t, email.MockKindLoginCode, sent[0].Kind)
+	assert.Equal(t, "test@example.com", sent[0].To)
+	assert.Len(t, sent[0].Token, 6)
+
+	_, err = challenges.Verify(challenge.ChallengeID, "wrong!", auth.SessionMetadata{})
+	assert.ErrorIs(t, err, ErrLoginCodeInvalid)
+	assert.Equal(t, []string{"BR"}, knownCountriesOf(t, challenges, user))
+
+	response, err = challenges.Verify(challenge.ChallengeID, sent[0].Token, auth.SessionMetadata{IP: "198.51.100.7"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, response.SessionID)
+	assert.Equal(t, "testuser", response.User.Identifier)
+	assert.Equal(t, []string{"BR", "US"}, knownCountriesOf(t, challenges, user))
+
+	// The code works once, and the country is now known
+	_, err = challenges.Verify(challenge.ChallengeID, sent[0].Token, auth.SessionMetadata{})
+	assert.ErrorIs(t, err, ErrLoginChallengeNotFound)
+	_, err = authService.LoginFrom("testuser", "password123", auth.SessionMetadata{Country: "US"})
+	assert.NoError(t, err)
+}
+
+func TestLoginChallenge_WrongPasswordSendsNothing(t *testing.T) {
+	authService, _, mockEmail, _ := setupLoginChallenges(t)
+
+	_, err := authService.LoginFrom("testuser", "wrong", auth.SessionMetadata{Country: "US"})
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	assert.Empty(t, mockEmail.GetSentEmails())
+}
+
+func TestLoginChallenge_ExpiresAndRunsOutOfAttempts(t *testing.T) {
+	authService, challenges, mockEmail, _ := setupLoginChallenges(t)
+	now := time.Now()
+	challenges.now = func() time.Time { return now }
+
+	login := func() (string, string) {
+		t.Helper()
+		mockEmail.ClearSentEmails()
+		_, err := authService.LoginFrom("testuser", "password123", auth.SessionMetadata{Country: "US"})
+		var challenge *LoginChallengeError
+		require.True(t, errors.As(err, &challenge))
+		return challenge.ChallengeID, mockEmail.GetSentEmails()[0].Token
+	}
+
+	id, code := login()
+	now = now.Add(DefaultLoginCodeTTL)
+	_, err := challenges.Verify(id, code, auth.SessionMetadata{})
+	assert.ErrorIs(t, err, ErrLoginChallengeNotFound)
+
+	id, code = login()
+	for range maxLoginCodeAttempts - 1 {
+		_, err = challenges.Verify(id, "000000x", auth.SessionMetadata{})
+		assert.ErrorIs(t, err, ErrLoginCodeInvalid)
+	}
+	_, err = challenges.Verify(id, "000000x", auth.SessionMetadata{})
+	assert.ErrorIs(t, err, ErrLoginChallengeNotFound)
+	_, err = challenges.Verify(id, code, auth.SessionMetadata{})
+	assert.ErrorIs(t, err, ErrLoginChallengeNotFound, "the right code is refused once the attempts ran out")
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"encoding/json"
+
+	"github.com/lucas-varjao/gohtmx/internal/email"
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// preferencesKeyNotifications is the key of the notification settings in models.User.Preferences.
+const preferencesKeyNotifications = "notifications"
+
+// NotificationService reads and stores which optional emails (email.OptionalTypes) each user wants.
+// Settings live in the user's preferences JSON; a type that isn't there is enabled.
+type NotificationService struct {
+	db *gorm.DB
+}
+
+// NewNotificationService creates a new NotificationService instance
+func NewNotificationService(db *gorm.DB) *NotificationService {
+	return &NotificationService{db: db}
+}
+
+// ShouldSend reports whether userID wants emails of emailType. Types that can't be turned off always
+// send, and so does everything when the preferences can't be read: a missed security notice is worse
+// than an unwanted one.
+func (s *NotificationService) ShouldSend(userID uint, emailType string) bool {
+	if !email.IsOptional(emailType) {
+		return true
+	}
+	enabled, err := s.Preferences(userID)
+	if err != nil {
+		return true
+	}
+	return enabled[emailType]
+}
+
+// Preferences returns every optional email type with whether userID receives it.
+func (s *NotificationService) Preferences(userID uint) (map[string]bool, error) {
+	var user models.User
+	if err := s.db.Select("id", "preferences").First(&user, userID).Error; err != nil {
+		logger.Error("Erro ao buscar preferências de notificação", "error", err, "user_id", userID)
+		return nil, err
+	}
+
+	enabled := make(map[string]bool, len(email.OptionalTypes))
+	for _, t := range email.OptionalTypes {
+		enabled[t] = true
+	}
+	var stored map[string]bool
+	if raw := decodePreferences(user.Preferences)[preferencesKeyNotifications]; raw != nil {
+		if err := json.Unmarshal(raw, &stored); err != nil {
+			logger.Warn("Preferências de notificação inválidas; usando o padrão", "error", err, "user_id", userID)
+		}
+	}
+	for t, on := range stored {
+		if email.IsOptional(t) {
+			enabled[t] = on
+		}
+	}
+	return enabled, nil
+}
+
+// SetPreferences stores which optional email types userID receives; types missing from enabled are
+// turned off and types that can't be turned off are ignored. Other preferences are kept as they are.
+func (s *NotificationService) SetPreferences(userID uint, enabled map[string]bool) error {
+	var user models.User
+	if err := s.db.Select("id", "preferences").First(&user, userID).Error; err != nil {
+		return err
+	}
+
+	notifications := make(map[string]bool, len(email.OptionalTypes))
+	for _, t := range email.OptionalTypes {
+		notifications[t] = enabled[t]
+	}
+	encoded, err := json.Marshal(notifications)
+	if err != nil {
+		return err
+	}
+	prefs := decodePreferences(user.Preferences)
+	prefs[preferencesKeyNotifications] = encoded
+	raw, err := json.Marshal(prefs)
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.Model(&models.User{}).Where("id = ?", userID).Update("preferences", string(raw)).Error; err != nil {
+		logger.Error("Erro ao salvar preferências de notificação", "error", err, "user_id", userID)
+		return err
+	}
+	logger.Info("Preferências de notificação atualizadas", "user_id", userID, "notifications", notifications)
+	return nil
+}
+
+// decodePreferences splits the preferences JSON into its top-level keys; empty or invalid JSON is no preferences.
+func decodePreferences(raw string) map[string]json.RawMessage {
+	prefs := map[string]json.RawMessage{}
+	if raw != "" {
+		if err := json.Unmarshal([]byte(raw), &prefs); err != nil || prefs == nil {
+			return map[string]json.RawMessage{}
+		}
+	}
+	return prefs
+}
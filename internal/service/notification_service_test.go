@@ -0,0 +1,75 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/email"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotificationService_Preferences(t *testing.T) {
+	_, _, _, _, _, db := setupTest(t)
+	user := createTestUser(t, db)
+	notifications := NewNotificationService(db)
+
+	enabled, err := notifications.Preferences(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{email.TypeAccountLocked: true, email.TypeAccountDeactivated: true, email.TypeNewLogin: true}, enabled,
+		"every optional email is on until the user turns it off")
+
+	require.NoError(t, db.Model(user).Update("preferences", `{"theme":"dark"}`).Error)
+	require.NoError(t, notifications.SetPreferences(user.ID, map[string]bool{
+		email.TypeAccountDeactivated: true,
+		email.TypePasswordReset:      false,
+	}))
+
+	enabled, err = notifications.Preferences(user.ID)
+	require.NoError(t, err)
+	assert.False(t, enabled[email.TypeAccountLocked], "types missing from the form are turned off")
+	assert.True(t, enabled[email.TypeAccountDeactivated])
+	assert.NotContains(t, enabled, email.TypePasswordReset)
+
+	var stored models.User
+	require.NoError(t, db.First(&stored, user.ID).Error)
+	assert.Contains(t, stored.Preferences, `"theme":"dark"`, "other preferences are kept")
+}
+
+func TestNotificationService_ShouldSend(t *testing.T) {
+	_, _, _, _, _, db := setupTest(t)
+	user := createTestUser(t, db)
+	notifications := NewNotificationService(db)
+
+	// Stored by hand: SetPreferences would drop the password_reset entry
+	require.NoError(t, db.Model(user).Update("preferences",
+		`{"notifications":{"account_locked":false,"password_reset":false,"email_verification":false}}`).Error)
+
+	assert.False(t, notifications.ShouldSend(user.ID, email.TypeAccountLocked))
+	assert.True(t, notifications.ShouldSend(user.ID, email.TypeAccountDeactivated))
+	assert.True(t, notifications.ShouldSend(user.ID, email.TypePasswordReset), "password reset can't be turned off")
+	assert.True(t, notifications.ShouldSend(user.ID, email.TypeEmailVerification))
+	assert.True(t, notifications.ShouldSend(9999, email.TypeAccountLocked), "unreadable preferences still send")
+}
+
+func TestAuthService_NotificationPreferences(t *testing.T) {
+	authService, _, _, _, mockEmail, db := setupTest(t)
+	user := createTestUser(t, db)
+	queue := email.NewQueue(mockEmail, 0)
+	authService.NotifyLockouts(queue)
+	notifications := NewNotificationService(db)
+	authService.UseNotificationPreferences(notifications)
+	require.NoError(t, notifications.SetPreferences(user.ID, map[string]bool{}))
+
+	for range auth.DefaultAuthConfig().MaxFailedAttempts {
+		_, _ = authService.Login("testuser", "wrongpass", "127.0.0.1", "test-agent")
+	}
+	require.NoError(t, authService.RequestPasswordReset(user.Email, ""))
+
+	require.NoError(t, queue.Close(t.Context()))
+	sent := mockEmail.GetSentEmails()
+	require.Len(t, sent, 1, "the lockout notice is skipped")
+	assert.Equal(t, email.MockKindPasswordReset, sent[0].Kind, "the reset email always sends")
+}
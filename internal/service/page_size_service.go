@@ -0,0 +1,96 @@
+package service
+
+import (
+	"cmp"
+	"encoding/json"
+	"strconv"
+
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// preferencesKeyPerPage is the key of the preferred list page size in models.User.Preferences.
+const preferencesKeyPerPage = "per_page"
+
+// PageSizeService picks the page size of the admin lists: the ?per_page= of the request, else the one
+// the user last asked for (kept in the preferences JSON), else the configured default.
+type PageSizeService struct {
+	db          *gorm.DB
+	defaultSize int // config admin.per_page; 0 leaves each list its own default
+}
+
+// NewPageSizeService creates a new PageSizeService instance. defaultSize <= 0 keeps the built-in
+// default of each list.
+func NewPageSizeService(db *gorm.DB, defaultSize int) *PageSizeService {
+	return &PageSizeService{db: db, defaultSize: max(defaultSize, 0)}
+}
+
+// Resolve returns the page size for a list whose built-in default is fallback, capped at limit.
+// requested is the request's ?per_page= (<= 0 when absent or invalid); when set it is also stored as
+// userID's preference. userID "" (anonymous) has no preference. A nil service only applies requested
+// and fallback.
+func (s *PageSizeService) Resolve(userID string, requested, fallback, limit int) int {
+	if requested > 0 {
+		size := min(requested, limit)
+		if s != nil && userID != "" && s.stored(userID) != size {
+			// Not being able to remember it doesn't fail the list
+			_ = s.store(userID, size)
+		}
+		return size
+	}
+	if s == nil {
+		return min(fallback, limit)
+	}
+	if userID != "" {
+		if size := s.stored(userID); size > 0 {
+			return min(size, limit)
+		}
+	}
+	return min(cmp.Or(s.defaultSize, fallback), limit)
+}
+
+// stored returns userID's preferred page size, or 0 when unset or unreadable.
+func (s *PageSizeService) stored(userID string) int {
+	id, err := strconv.ParseUint(userID, 10, 64)
+	if err != nil {
+		return 0
+	}
+	var user models.User
+	if err := s.db.Select("id", "preferences").First(&user, id).Error; err != nil {
+		return 0
+	}
+	var size int
+	if raw := decodePreferences(user.Preferences)[preferencesKeyPerPage]; raw != nil {
+		if err := json.Unmarshal(raw, &size); err != nil {
+			logger.Warn("Tamanho de página preferido inválido; usando o padrão", "error", err, "user_id", userID)
+			return 0
+		}
+	}
+	return max(size, 0)
+}
+
+// store saves size as userID's preferred page size, keeping the other preferences as they are.
+func (s *PageSizeService) store(userID string, size int) error {
+	id, err := strconv.ParseUint(userID, 10, 64)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	var user models.User
+	if err := s.db.Select("id", "preferences").First(&user, id).Error; err != nil {
+		return err
+	}
+
+	prefs := decodePreferences(user.Preferences)
+	prefs[preferencesKeyPerPage] = json.RawMessage(strconv.Itoa(size))
+	raw, err := json.Marshal(prefs)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Model(&models.User{}).Where("id = ?", id).Update("preferences", string(raw)).Error; err != nil {
+		logger.Error("Erro ao salvar o tamanho de página preferido", "error", err, "user_id", userID)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageSizeService_Resolve(t *testing.T) {
+	_, _, _, _, _, db := setupTest(t)
+	user := createTestUser(t, db)
+	userID := idString(user.ID)
+	require.NoError(t, db.Model(user).Update("preferences", `{"theme":"dark"}`).Error)
+
+	t.Run("Configured default, then the list's own", func(t *testing.T) {
+		assert.Equal(t, 30, NewPageSizeService(db, 30).Resolve(userID, 0, 20, 100))
+		assert.Equal(t, 20, NewPageSizeService(db, 0).Resolve(userID, 0, 20, 100))
+		assert.Equal(t, 20, (*PageSizeService)(nil).Resolve(userID, 0, 20, 100))
+	})
+
+	pageSizes := NewPageSizeService(db, 30)
+
+	t.Run("A requested size is used and remembered", func(t *testing.T) {
+		assert.Equal(t, 50, pageSizes.Resolve(userID, 50, 20, 100))
+		assert.Equal(t, 50, pageSizes.Resolve(userID, 0, 20, 100))
+
+		var stored models.User
+		require.NoError(t, db.First(&stored, user.ID).Error)
+		assert.Contains(t, stored.Preferences, `"per_page":50`)
+		assert.Contains(t, stored.Preferences, `"theme":"dark"`, "other preferences are kept")
+	})
+
+	t.Run("A request overrides the preference", func(t *testing.T) {
+		assert.Equal(t, 10, pageSizes.Resolve(userID, 10, 20, 100))
+		assert.Equal(t, 10, pageSizes.Resolve(userID, 0, 20, 100))
+	})
+
+	t.Run("Capped at the list's limit", func(t *testing.T) {
+		assert.Equal(t, 100, pageSizes.Resolve(userID, 500, 20, 100))
+		assert.Equal(t, 40, pageSizes.Resolve(userID, 0, 20, 40))
+	})
+
+	t.Run("Anonymous requests aren't remembered", func(t *testing.T) {
+		assert.Equal(t, 15, pageSizes.Resolve("", 15, 20, 100))
+		assert.Equal(t, 30, pageSizes.Resolve("", 0, 20, 100))
+	})
+}
@@ -0,0 +1,423 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/config"
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"gorm.io/gorm"
+)
+
+// DefaultPasskeyChallengeTTL is how long a begun passkey registration or login can be finished when
+// config webauthn.challenge_ttl is unset.
+const DefaultPasskeyChallengeTTL = 5 * time.Minute
+
+// maxPendingPasskeyCeremonies caps the begun but unfinished ceremonies kept in memory. Beginning a login
+// needs no account, so without a cap a client could grow the store until the TTL catches up.
+const maxPendingPasskeyCeremonies = 10000
+
+// maxPasskeyNameLength matches the size of models.WebAuthnCredential.Name.
+const maxPasskeyNameLength = 100
+
+var (
+	ErrPasskeyCeremonyNotFound = errors.New("a operação com a passkey expirou ou já foi concluída; tente novamente")
+	ErrPasskeyInvalid          = errors.New("passkey não reconhecida")
+	ErrPasskeyNotFound         = errors.New("passkey não encontrada")
+	ErrPasskeyBusy             = errors.New("muitas operações com passkey em andamento; tente novamente em instantes")
+	ErrPasskeyCloned           = errors.New("esta passkey foi bloqueada por suspeita de cópia; entre com a senha e cadastre outra")
+)
+
+// passkeyCeremonies is the WebAuthn relying party (*webauthn.WebAuthn); tests use a stubbed authenticator.
+type passkeyCeremonies interface {
+	BeginRegistration(user webauthn.User, opts ...webauthn.RegistrationOption) (*protocol.CredentialCreation, *webauthn.SessionData, error)
+	FinishRegistration(user webauthn.User, session webauthn.SessionData, r *http.Request) (*webauthn.Credential, error)
+	BeginDiscoverableLogin(opts ...webauthn.LoginOption) (*protocol.CredentialAssertion, *webauthn.SessionData, error)
+	FinishPasskeyLogin(handler webauthn.DiscoverableUserHandler, session webauthn.SessionData, r *http.Request) (webauthn.User, *webauthn.Credential, error)
+}
+
+// passkeyCeremony is the server half of a begun registration or login: the challenge the authenticator
+// must sign. It is used once; userID binds a registration to the account that began it.
+type passkeyCeremony struct {
+	userID  string // empty for logins
+	session webauthn.SessionData
+	expires time.Time
+}
+
+// PasskeyService registers passkeys (WebAuthn credentials) for logged-in users and logs users in with
+// them. Logins use discoverable credentials, so the user picks a passkey without typing a username;
+// a successful login opens a normal session, exactly like the password login it sits beside.
+//
+// Challenges live in memory between begin and finish, keyed by a random ceremony ID, and are dropped
+// on first use or after the TTL. With several instances, the finish request must reach the instance
+// that began the ceremony.
+type PasskeyService struct {
+	db          *gorm.DB
+	authManager *auth.AuthManager
+	rp          passkeyCeremonies
+	attempts    LoginAttemptRecorder // optional; nil disables login attempt records
+	ttl         time.Duration
+	now         func() time.Time
+
+	mu      sync.Mutex
+	pending map[string]passkeyCeremony
+}
+
+// NewPasskeyService creates a new PasskeyService for the relying party in cfg; an empty
+// rp_display_name uses appName.
+func NewPasskeyService(db *gorm.DB, authManager *auth.AuthManager, cfg config.WebAuthnConfig, appName string) (*PasskeyService, error) {
+	ttl := cfg.ChallengeTTL
+	if ttl <= 0 {
+		ttl = DefaultPasskeyChallengeTTL
+	}
+	displayName := cfg.RPDisplayName
+	if displayName == "" {
+		displayName = appName
+	}
+	timeout := webauthn.TimeoutConfig{Enforce: true, Timeout: ttl, TimeoutUVD: ttl}
+	rp, err := webauthn.New(&webauthn.Config{
+		RPID:          cfg.RPID,
+		RPDisplayName: displayName,
+		RPOrigins:     cfg.RPOrigins,
+		Timeouts:      webauthn.TimeoutsConfig{Login: timeout, Registration: timeout},
+	})
+	if err != nil {
+		logger.Error("Configuração de WebAuthn inválida", "error", err, "rp_id", cfg.RPID)
+		return nil, err
+	}
+	return newPasskeyService(db, authManager, rp, ttl), nil
+}
+
+func newPasskeyService(db *gorm.DB, authManager *auth.AuthManager, rp passkeyCeremonies, ttl time.Duration) *PasskeyService {
+	return &PasskeyService{
+		db:          db,
+		authManager: authManager,
+		rp:          rp,
+		ttl:         ttl,
+		now:         time.Now,
+		pending:     make(map[string]passkeyCeremony),
+	}
+}
+
+// passkeyUser adapts an account and its passkeys to webauthn.User. The user handle stored on the
+// authenticator is the decimal user ID, which FinishLogin maps back to the account.
+type passkeyUser struct {
+	user        models.User
+	credentials []webauthn.Credential
+}
+
+func (u *passkeyUser) WebAuthnID() []byte {
+	return []byte(strconv.FormatUint(uint64(u.user.ID), 10))
+}
+
+func (u *passkeyUser) WebAuthnName() string {
+	return u.user.Username
+}
+
+func (u *passkeyUser) WebAuthnDisplayName() string {
+	if u.user.DisplayName != "" {
+		return u.user.DisplayName
+	}
+	return u.user.Username
+}
+
+func (u *passkeyUser) WebAuthnCredentials() []webauthn.Credential {
+	return u.credentials
+}
+
+// loadUser returns the account with its stored passkeys.
+func (s *PasskeyService) loadUser(userID string) (*passkeyUser, error) {
+	id, err := strconv.ParseUint(userID, 10, 64)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	u := &passkeyUser{}
+	if err := s.db.First(&u.user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		logger.Error("Erro ao buscar usuário para passkey", "error", err, "user_id", userID)
+		return nil, err
+	}
+	var rows []models.WebAuthnCredential
+	if err := s.db.Where("user_id = ?", u.user.ID).Find(&rows).Error; err != nil {
+		logger.Error("Erro ao buscar passkeys do usuário", "error", err, "user_id", userID)
+		return nil, err
+	}
+	for _, row := range rows {
+		var credential webauthn.Credential
+		if err := json.Unmarshal([]byte(row.Data), &credential); err != nil {
+			logger.Error("Passkey armazenada ilegível", "error", err, "credential_id", row.ID)
+			continue
+		}
+		u.credentials = append(u.credentials, credential)
+	}
+	return u, nil
+}
+
+// begin stores the ceremony's challenge and returns the ID the finish request must send back.
+func (s *PasskeyService) begin(userID string, session *webauthn.SessionData) (string, error) {
+	idBytes := make([]byte, 32)
+	if _, err := auth.GenerateRandomBytes(idBytes); err != nil {
+		logger.Error("Erro ao gerar ID de cerimônia de passkey", "error", err)
+		return "", err
+	}
+	id := hex.EncodeToString(idBytes)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if len(s.pending) >= maxPendingPasskeyCeremonies {
+		for key, ceremony := range s.pending {
+			if !now.Before(ceremony.expires) {
+				delete(s.pending, key)
+			}
+		}
+		if len(s.pending) >= maxPendingPasskeyCeremonies {
+			logger.Warn("Limite de cerimônias de passkey pendentes atingido", "pending", len(s.pending))
+			return "", ErrPasskeyBusy
+		}
+	}
+	s.pending[id] = passkeyCeremony{userID: userID, session: *session, expires: now.Add(s.ttl)}
+	return id, nil
+}
+
+// take removes and returns the ceremony behind id: a challenge can be answered once, whatever the outcome.
+func (s *PasskeyService) take(id, userID string) (webauthn.SessionData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ceremony, ok := s.pending[id]
+	if !ok {
+		return webauthn.SessionData{}, ErrPasskeyCeremonyNotFound
+	}
+	delete(s.pending, id)
+	if !s.now().Before(ceremony.expires) || ceremony.userID != userID {
+		return webauthn.SessionData{}, ErrPasskeyCeremonyNotFound
+	}
+	return ceremony.session, nil
+}
+
+// BeginRegistration starts adding a passkey to userID's account. It returns the options for
+// navigator.credentials.create() and the ceremony ID for FinishRegistration. Passkeys the user
+// already has are excluded, so an authenticator can't be registered twice.
+func (s *PasskeyService) BeginRegistration(userID string) (*protocol.CredentialCreation, string, error) {
+	user, err := s.loadUser(userID)
+	if err != nil {
+		return nil, "", err
+	}
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(user.credentials))
+	for i := range user.credentials {
+		exclusions = append(exclusions, user.credentials[i].Descriptor())
+	}
+	options, session, err := s.rp.BeginRegistration(user,
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+		webauthn.WithExclusions(exclusions))
+	if err != nil {
+		logger.Error("Erro ao iniciar cadastro de passkey", "error", err, "user_id", userID)
+		return nil, "", err
+	}
+	id, err := s.begin(userID, session)
+	if err != nil {
+		return nil, "", err
+	}
+	return options, id, nil
+}
+
+// FinishRegistration verifies the authenticator's response in r to the ceremony begun by the same
+// user and stores the new passkey under name (defaults to "Passkey").
+func (s *PasskeyService) FinishRegistration(userID, ceremonyID, name string, r *http.Request) (*models.WebAuthnCredential, error) {
+	session, err := s.take(ceremonyID, userID)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.loadUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	credential, err := s.rp.FinishRegistration(user, session, r)
+	if err != nil {
+		logger.Warn("Cadastro de passkey rejeitado", "error", err, "user_id", userID)
+		return nil, ErrPasskeyInvalid
+	}
+	data, err := json.Marshal(credential)
+	if err != nil {
+		logger.Error("Erro ao serializar passkey", "error", err, "user_id", userID)
+		return nil, err
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = "Passkey"
+	}
+	if runes := []rune(name); len(runes) > maxPasskeyNameLength {
+		name = string(runes[:maxPasskeyNameLength])
+	}
+	row := &models.WebAuthnCredential{
+		UserID:       user.user.ID,
+		CredentialID: base64.RawURLEncoding.EncodeToString(credential.ID),
+		Name:         name,
+		Data:         string(data),
+	}
+	if err := s.db.Create(row).Error; err != nil {
+		logger.Error("Erro ao salvar passkey", "error", err, "user_id", userID)
+		return nil, err
+	}
+	logger.Info("Passkey cadastrada", "user_id", userID, "credential_id", row.ID)
+	return row, nil
+}
+
+// BeginLogin starts a passwordless login. It returns the options for navigator.credentials.get()
+// and the ceremony ID for FinishLogin; no account is named, the authenticator offers its passkeys.
+func (s *PasskeyService) BeginLogin() (*protocol.CredentialAssertion, string, error) {
+	options, session, err := s.rp.BeginDiscoverableLogin()
+	if err != nil {
+		logger.Error("Erro ao iniciar login com passkey", "error", err)
+		return nil, "", err
+	}
+	id, err := s.begin("", session)
+	if err != nil {
+		return nil, "", err
+	}
+	return options, id, nil
+}
+
+// UseLoginAttempts records every passkey login, successful or not, like the password login does, so
+// they show up in the admin login attempts and the account export. Call it during setup, before
+// serving requests.
+func (s *PasskeyService) UseLoginAttempts(attempts LoginAttemptRecorder) {
+	s.attempts = attempts
+}
+
+// FinishLogin verifies the signed challenge in r and opens a session for the passkey's owner. The
+// account must be allowed to log in (AuthManager.LoginStatus), as with a password. A passkey whose sign
+// counter went backwards may have been copied: it is flagged and refused from then on (ErrPasskeyCloned).
+func (s *PasskeyService) FinishLogin(ceremonyID string, r *http.Request, metadata auth.SessionMetadata) (*LoginResponse, error) {
+	session, err := s.take(ceremonyID, "")
+	if err != nil {
+		return nil, err
+	}
+	found, credential, err := s.rp.FinishPasskeyLogin(func(_, userHandle []byte) (webauthn.User, error) {
+		return s.loadUser(string(userHandle))
+	}, session, r)
+	if err != nil {
+		logger.Warn("Login com passkey rejeitado", "error", err, "ip", metadata.IP)
+		recordLoginAttempt(s.attempts, "", metadata, AttemptReasonInvalidCredentials)
+		return nil, ErrPasskeyInvalid
+	}
+	owner, ok := found.(*passkeyUser)
+	if !ok {
+		recordLoginAttempt(s.attempts, "", metadata, AttemptReasonInvalidCredentials)
+		return nil, ErrPasskeyInvalid
+	}
+	userID := strconv.FormatUint(uint64(owner.user.ID), 10)
+	username := owner.user.Username
+	s.touchCredential(owner.user.ID, credential)
+	if credential.Authenticator.CloneWarning {
+		logger.Warn("Login com passkey recusado: o contador de assinaturas voltou atrás", "user_id", userID,
+			"credential_id", base64.RawURLEncoding.EncodeToString(credential.ID), "ip", metadata.IP)
+		recordLoginAttempt(s.attempts, username, metadata, AttemptReasonPasskeyCloned)
+		return nil, ErrPasskeyCloned
+	}
+
+	user, err := s.authManager.GetUserAdapter().FindUserByID(userID)
+	if err != nil {
+		recordLoginAttempt(s.attempts, username, metadata, AttemptReasonError)
+		return nil, err
+	}
+	status := s.authManager.LoginStatus(user)
+	switch status {
+	case auth.LoginStatusInactive:
+		logger.Warn("Login com passkey de usuário inativo", "user_id", userID, "ip", metadata.IP)
+		recordLoginAttempt(s.attempts, username, metadata, AttemptReasonInactive)
+		return nil, ErrUserNotActive
+	case auth.LoginStatusLocked:
+		logger.Warn("Login com passkey de conta bloqueada", "user_id", userID, "ip", metadata.IP)
+		recordLoginAttempt(s.attempts, username, metadata, AttemptReasonLocked)
+		return nil, ErrAccountLocked
+	case auth.LoginStatusUnverified:
+		logger.Warn("Login com passkey de email não verificado", "user_id", userID, "ip", metadata.IP)
+		recordLoginAttempt(s.attempts, username, metadata, AttemptReasonUnverified)
+		return nil, ErrEmailNotVerified
+	}
+
+	authSession, user, err := s.authManager.CreateSessionForUser(userID, metadata)
+	if err != nil {
+		if errors.Is(err, auth.ErrUserNotActive) {
+			recordLoginAttempt(s.attempts, username, metadata, AttemptReasonInactive)
+			return nil, ErrUserNotActive
+		}
+		recordLoginAttempt(s.attempts, username, metadata, AttemptReasonError)
+		return nil, err
+	}
+	recordLoginAttempt(s.attempts, username, metadata, AttemptReasonSuccess)
+	firstLogin := owner.user.LastLogin.IsZero()
+	if err := s.db.Model(&models.User{}).Where("id = ?", owner.user.ID).Update("last_login", s.now()).Error; err != nil {
+		logger.Error("Erro ao atualizar último login", "error", err, "user_id", userID)
+	}
+	logger.Info("Login com passkey realizado com sucesso", "user_id", userID, "ip", metadata.IP)
+
+	return &LoginResponse{
+		SessionID:   authSession.ID,
+		ExpiresAt:   authSession.ExpiresAt,
+		User:        *user,
+		LoginStatus: status,
+		FirstLogin:  firstLogin,
+	}, nil
+}
+
+// touchCredential stores the credential's new sign counter and flags after a login, and when it was used.
+// A clone warning is stored too, so the passkey stays refused, but doesn't count as a use.
+func (s *PasskeyService) touchCredential(userID uint, credential *webauthn.Credential) {
+	data, err := json.Marshal(credential)
+	if err != nil {
+		logger.Error("Erro ao serializar passkey", "error", err, "user_id", userID)
+		return
+	}
+	updates := map[string]any{"data": string(data)}
+	if !credential.Authenticator.CloneWarning {
+		now := s.now()
+		updates["last_used_at"] = &now
+	}
+	err = s.db.Model(&models.WebAuthnCredential{}).
+		Where("user_id = ? AND credential_id = ?", userID, base64.RawURLEncoding.EncodeToString(credential.ID)).
+		Updates(updates).Error
+	if err != nil {
+		logger.Error("Erro ao atualizar passkey após login", "error", err, "user_id", userID)
+	}
+}
+
+// List returns the passkeys of userID, oldest first.
+func (s *PasskeyService) List(userID string) ([]models.WebAuthnCredential, error) {
+	var credentials []models.WebAuthnCredential
+	if err := s.db.Where("user_id = ?", userID).Order("id").Find(&credentials).Error; err != nil {
+		logger.Error("Erro ao listar passkeys", "error", err, "user_id", userID)
+		return nil, err
+	}
+	return credentials, nil
+}
+
+// Delete removes one of userID's passkeys; ErrPasskeyNotFound when it doesn't exist or belongs to someone else.
+func (s *PasskeyService) Delete(userID, credentialID string) error {
+	result := s.db.Where("id = ? AND user_id = ?", credentialID, userID).Delete(&models.WebAuthnCredential{})
+	if result.Error != nil {
+		logger.Error("Erro ao remover passkey", "error", result.Error, "user_id", userID, "credential_id", credentialID)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrPasskeyNotFound
+	}
+	logger.Info("Passkey removida", "user_id", userID, "credential_id", credentialID)
+	return nil
+}
@@ -0,0 +1,296 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubAuthenticator stands in for the relying party and the user's authenticator: every begin issues
+// a new challenge, and a finish succeeds only when it gets back the session of a challenge it issued.
+type stubAuthenticator struct {
+	credentialID []byte
+	userHandle   []byte // what the authenticator answers on login; empty uses the registered user
+	reject       bool
+	issued       map[string]bool
+	signCount    uint32
+}
+
+func newStubAuthenticator() *stubAuthenticator {
+	return &stubAuthenticator{credentialID: []byte("credential-1"), issued: map[string]bool{}}
+}
+
+func (a *stubAuthenticator) challenge() *webauthn.SessionData {
+	challenge := fmt.Sprintf("challenge-%d", len(a.issued)+1)
+	a.issued[challenge] = true
+	return &webauthn.SessionData{Challenge: challenge}
+}
+
+func (a *stubAuthenticator) check(session webauthn.SessionData) error {
+	if a.reject || !a.issued[session.Challenge] {
+		return errors.New("assinatura inválida")
+	}
+	return nil
+}
+
+func (a *stubAuthenticator) BeginRegistration(user webauthn.User, _ ...webauthn.RegistrationOption) (*protocol.CredentialCreation, *webauthn.SessionData, error) {
+	if a.userHandle == nil {
+		a.userHandle = user.WebAuthnID()
+	}
+	return &protocol.CredentialCreation{}, a.challenge(), nil
+}
+
+func (a *stubAuthenticator) FinishRegistration(_ webauthn.User, session webauthn.SessionData, _ *http.Request) (*webauthn.Credential, error) {
+	if err := a.check(session); err != nil {
+		return nil, err
+	}
+	return &webauthn.Credential{ID: a.credentialID, PublicKey: []byte("public-key")}, nil
+}
+
+func (a *stubAuthenticator) BeginDiscoverableLogin(_ ...webauthn.LoginOption) (*protocol.CredentialAssertion, *webauthn.SessionData, error) {
+	return &protocol.CredentialAssertion{}, a.challenge(), nil
+}
+
+func (a *stubAuthenticator) FinishPasskeyLogin(handler webauthn.DiscoverableUserHandler, session webauthn.SessionData, _ *http.Request) (webauthn.User, *webauthn.Credential, error) {
+	if err := a.check(session); err != nil {
+		return nil, nil, err
+	}
+	user, err := handler(a.credentialID, a.userHandle)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, credential := range user.WebAuthnCredentials() {
+		if string(credential.ID) == string(a.credentialID) {
+			a.signCount++
+			credential.Authenticator.UpdateCounter(a.signCount)
+			return user, &credential, nil
+		}
+	}
+	return nil, nil, errors.New("credencial desconhecida")
+}
+
+func setupPasskeyTest(t *testing.T) (*PasskeyService, *stubAuthenticator, *auth.AuthManager, *models.User) {
+	_, authManager, _, _, _, db := setupTest(t)
+	user := createTestUser(t, db)
+	authenticator := newStubAuthenticator()
+	return newPasskeyService(db, authManager, authenticator, time.Minute), authenticator, authManager, user
+}
+
+// registerPasskey runs a full registration ceremony for user.
+func registerPasskey(t *testing.T, passkeys *PasskeyService, user *models.User) *models.WebAuthnCredential {
+	_, ceremony, err := passkeys.BeginRegistration(idString(user.ID))
+	require.NoError(t, err)
+	credential, err := passkeys.FinishRegistration(idString(user.ID), ceremony, "Notebook", nil)
+	require.NoError(t, err)
+	return credential
+}
+
+func TestPasskeyService_Registration(t *testing.T) {
+	t.Run("Stores the passkey for the user who began", func(t *testing.T) {
+		passkeys, _, _, user := setupPasskeyTest(t)
+
+		credential := registerPasskey(t, passkeys, user)
+		assert.Equal(t, user.ID, credential.UserID)
+		assert.Equal(t, "Notebook", credential.Name)
+		assert.Equal(t, "Y3JlZGVudGlhbC0x", credential.CredentialID) // base64url("credential-1")
+
+		list, err := passkeys.List(idString(user.ID))
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+	})
+
+	t.Run("A challenge can be answered only once", func(t *testing.T) {
+		passkeys, _, _, user := setupPasskeyTest(t)
+		_, ceremony, err := passkeys.BeginRegistration(idString(user.ID))
+		require.NoError(t, err)
+		_, err = passkeys.FinishRegistration(idString(user.ID), ceremony, "", nil)
+		require.NoError(t, err)
+
+		_, err = passkeys.FinishRegistration(idString(user.ID), ceremony, "", nil)
+		assert.ErrorIs(t, err, ErrPasskeyCeremonyNotFound)
+	})
+
+	t.Run("Another user can't finish the ceremony", func(t *testing.T) {
+		passkeys, _, _, user := setupPasskeyTest(t)
+		_, ceremony, err := passkeys.BeginRegistration(idString(user.ID))
+		require.NoError(t, err)
+
+		_, err = passkeys.FinishRegistration(idString(user.ID+1), ceremony, "", nil)
+		assert.ErrorIs(t, err, ErrPasskeyCeremonyNotFound)
+		// The attempt used the challenge up
+		_, err = passkeys.FinishRegistration(idString(user.ID), ceremony, "", nil)
+		assert.ErrorIs(t, err, ErrPasskeyCeremonyNotFound)
+	})
+
+	t.Run("An expired ceremony is rejected", func(t *testing.T) {
+		passkeys, _, _, user := setupPasskeyTest(t)
+		_, ceremony, err := passkeys.BeginRegistration(idString(user.ID))
+		require.NoError(t, err)
+
+		passkeys.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+		_, err = passkeys.FinishRegistration(idString(user.ID), ceremony, "", nil)
+		assert.ErrorIs(t, err, ErrPasskeyCeremonyNotFound)
+	})
+
+	t.Run("A rejected response stores nothing", func(t *testing.T) {
+		passkeys, authenticator, _, user := setupPasskeyTest(t)
+		_, ceremony, err := passkeys.BeginRegistration(idString(user.ID))
+		require.NoError(t, err)
+
+		authenticator.reject = true
+		_, err = passkeys.FinishRegistration(idString(user.ID), ceremony, "", nil)
+		assert.ErrorIs(t, err, ErrPasskeyInvalid)
+		list, err := passkeys.List(idString(user.ID))
+		require.NoError(t, err)
+		assert.Empty(t, list)
+	})
+}
+
+func TestPasskeyService_Login(t *testing.T) {
+	t.Run("Issues a session for the passkey's owner", func(t *testing.T) {
+		passkeys, _, authManager, user := setupPasskeyTest(t)
+		registerPasskey(t, passkeys, user)
+
+		_, ceremony, err := passkeys.BeginLogin()
+		require.NoError(t, err)
+		response, err := passkeys.FinishLogin(ceremony, nil, auth.SessionMetadata{IP: "192.0.2.1"})
+		require.NoError(t, err)
+		assert.Equal(t, idString(user.ID), response.User.ID)
+		assert.Equal(t, auth.LoginStatusOK, response.LoginStatus)
+		assert.True(t, response.FirstLogin)
+
+		_, sessionUser, err := authManager.ValidateSession(response.SessionID)
+		require.NoError(t, err)
+		assert.Equal(t, idString(user.ID), sessionUser.ID)
+
+		list, err := passkeys.List(idString(user.ID))
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.NotNil(t, list[0].LastUsedAt)
+		assert.Contains(t, list[0].Data, `"signCount":1`)
+	})
+
+	t.Run("Unknown and reused ceremonies are rejected", func(t *testing.T) {
+		passkeys, _, _, user := setupPasskeyTest(t)
+		registerPasskey(t, passkeys, user)
+
+		_, err := passkeys.FinishLogin("unknown", nil, auth.SessionMetadata{})
+		assert.ErrorIs(t, err, ErrPasskeyCeremonyNotFound)
+
+		_, ceremony, err := passkeys.BeginLogin()
+		require.NoError(t, err)
+		_, err = passkeys.FinishLogin(ceremony, nil, auth.SessionMetadata{})
+		require.NoError(t, err)
+		_, err = passkeys.FinishLogin(ceremony, nil, auth.SessionMetadata{})
+		assert.ErrorIs(t, err, ErrPasskeyCeremonyNotFound)
+	})
+
+	t.Run("A registration ceremony can't be used to log in", func(t *testing.T) {
+		passkeys, _, _, user := setupPasskeyTest(t)
+		registerPasskey(t, passkeys, user)
+		_, ceremony, err := passkeys.BeginRegistration(idString(user.ID))
+		require.NoError(t, err)
+
+		_, err = passkeys.FinishLogin(ceremony, nil, auth.SessionMetadata{})
+		assert.ErrorIs(t, err, ErrPasskeyCeremonyNotFound)
+	})
+
+	t.Run("A passkey that was never registered is rejected", func(t *testing.T) {
+		passkeys, _, _, user := setupPasskeyTest(t)
+		_, _, err := passkeys.BeginRegistration(idString(user.ID)) // only to learn the user handle
+		require.NoError(t, err)
+
+		_, ceremony, err := passkeys.BeginLogin()
+		require.NoError(t, err)
+		_, err = passkeys.FinishLogin(ceremony, nil, auth.SessionMetadata{})
+		assert.ErrorIs(t, err, ErrPasskeyInvalid)
+	})
+
+	t.Run("A passkey whose counter goes backwards is refused from then on", func(t *testing.T) {
+		passkeys, authenticator, _, user := setupPasskeyTest(t)
+		registerPasskey(t, passkeys, user)
+		login := func() error {
+			t.Helper()
+			_, ceremony, err := passkeys.BeginLogin()
+			require.NoError(t, err)
+			_, err = passkeys.FinishLogin(ceremony, nil, auth.SessionMetadata{})
+			return err
+		}
+		require.NoError(t, login())
+
+		// A copy of the authenticator signs with a counter the server already saw
+		authenticator.signCount = 0
+		assert.ErrorIs(t, login(), ErrPasskeyCloned)
+		authenticator.signCount = 10
+		assert.ErrorIs(t, login(), ErrPasskeyCloned, "the passkey stays flagged")
+
+		list, err := passkeys.List(idString(user.ID))
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.Contains(t, list[0].Data, `"cloneWarning":true`)
+	})
+
+	t.Run("Every login is recorded as a login attempt", func(t *testing.T) {
+		passkeys, authenticator, _, user := setupPasskeyTest(t)
+		attempts := NewLoginAttemptService(passkeys.db)
+		passkeys.UseLoginAttempts(attempts)
+		registerPasskey(t, passkeys, user)
+
+		_, ceremony, err := passkeys.BeginLogin()
+		require.NoError(t, err)
+		_, err = passkeys.FinishLogin(ceremony, nil, auth.SessionMetadata{IP: "192.0.2.1"})
+		require.NoError(t, err)
+
+		authenticator.reject = true
+		_, ceremony, err = passkeys.BeginLogin()
+		require.NoError(t, err)
+		_, err = passkeys.FinishLogin(ceremony, nil, auth.SessionMetadata{IP: "192.0.2.2"})
+		require.ErrorIs(t, err, ErrPasskeyInvalid)
+
+		authenticator.reject = false
+		require.NoError(t, passkeys.db.Model(user).Update("active", false).Error)
+		_, ceremony, err = passkeys.BeginLogin()
+		require.NoError(t, err)
+		_, err = passkeys.FinishLogin(ceremony, nil, auth.SessionMetadata{IP: "192.0.2.3"})
+		require.ErrorIs(t, err, ErrUserNotActive)
+
+		var recorded []models.LoginAttempt
+		require.NoError(t, passkeys.db.Order("id").Find(&recorded).Error)
+		require.Len(t, recorded, 3)
+		assert.Equal(t, []string{"testuser", "", "testuser"}, []string{recorded[0].Identifier, recorded[1].Identifier, recorded[2].Identifier})
+		assert.True(t, recorded[0].Success)
+		assert.Equal(t, AttemptReasonInvalidCredentials, recorded[1].Reason)
+		assert.Equal(t, "192.0.2.2", recorded[1].IP)
+		assert.Equal(t, AttemptReasonInactive, recorded[2].Reason)
+	})
+
+	t.Run("Inactive users can't log in", func(t *testing.T) {
+		passkeys, _, _, user := setupPasskeyTest(t)
+		registerPasskey(t, passkeys, user)
+		require.NoError(t, passkeys.db.Model(user).Update("active", false).Error)
+
+		_, ceremony, err := passkeys.BeginLogin()
+		require.NoError(t, err)
+		_, err = passkeys.FinishLogin(ceremony, nil, auth.SessionMetadata{})
+		assert.ErrorIs(t, err, ErrUserNotActive)
+	})
+}
+
+func TestPasskeyService_Delete(t *testing.T) {
+	passkeys, _, _, user := setupPasskeyTest(t)
+	credential := registerPasskey(t, passkeys, user)
+
+	assert.ErrorIs(t, passkeys.Delete(idString(user.ID+1), idString(credential.ID)), ErrPasskeyNotFound)
+	require.NoError(t, passkeys.Delete(idString(user.ID), idString(credential.ID)))
+	assert.ErrorIs(t, passkeys.Delete(idString(user.ID), idString(credential.ID)), ErrPasskeyNotFound)
+}
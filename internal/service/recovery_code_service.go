@@ -0,0 +1,211 @@
+package service
+
+import (
+	"crypto/subtle"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// DefaultRecoveryCodeTTL is how long a recovery code works when NewRecoveryCodeService gets ttl <= 0.
+const DefaultRecoveryCodeTTL = time.Hour
+
+const (
+	// recoveryCodeAlphabet has 32 symbols without the look-alikes I, O, 0 and 1, so a code read over the
+	// phone survives; each symbol carries 5 bits
+	recoveryCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	// recoveryCodeLength symbols give 60 bits, far beyond what the attempt cap and the rate limit let through
+	recoveryCodeLength = 12
+	// recoveryCodeGroup is the size of the dash-separated groups the code is shown in (ABCD-EFGH-JKLM)
+	recoveryCodeGroup = 4
+	// maxRecoveryCodeAttempts is how many wrong codes discard the account's code; the admin then makes a new one
+	maxRecoveryCodeAttempts = 5
+)
+
+// ErrRecoveryCodeInvalid answers every refused recovery alike (unknown account, wrong, expired or used
+// code), so /recover doesn't tell which accounts have a code.
+var ErrRecoveryCodeInvalid = errors.New("código de recuperação inválido ou expirado")
+
+// IssuedRecoveryCode is a new recovery code, shown once to the admin who created it.
+type IssuedRecoveryCode struct {
+	Code      string    `json:"code"`
+	Username  string    `json:"username"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// RecoveryCodeService lets users who lost access to their email set a new password without the reset
+// email: an admin creates a one-time code for the account (Issue) and hands it over by another channel,
+// and the user types it at /recover with the new password (Redeem).
+//
+// Only the code's hash is kept, it expires after the configured TTL (config account.recovery_code_ttl),
+// works once and is discarded after a few wrong attempts. Both steps go to the audit log.
+type RecoveryCodeService struct {
+	db          *gorm.DB
+	authService *AuthService
+	audit       AuditRecorder
+	ttl         time.Duration
+	now         func() time.Time
+}
+
+// NewRecoveryCodeService creates a new RecoveryCodeService instance; authService sets the new password.
+// Codes are valid for ttl (DefaultRecoveryCodeTTL when ttl <= 0); audit may be nil.
+func NewRecoveryCodeService(db *gorm.DB, authService *AuthService, audit AuditRecorder, ttl time.Duration) *RecoveryCodeService {
+	if ttl <= 0 {
+		ttl = DefaultRecoveryCodeTTL
+	}
+	return &RecoveryCodeService{db: db, authService: authService, audit: audit, ttl: ttl, now: time.Now}
+}
+
+// Issue creates a recovery code for userID on behalf of adminID, replacing the user's previous one.
+func (s *RecoveryCodeService) Issue(userID, adminID, ip string) (*IssuedRecoveryCode, error) {
+	user, err := s.find(userID)
+	if err != nil {
+		return nil, err
+	}
+	code, err := newRecoveryCode()
+	if err != nil {
+		logger.Error("Erro ao gerar código de recuperação", "error", err)
+		return nil, err
+	}
+	expiresAt := s.now().Add(s.ttl)
+	if err := s.db.Model(user).Updates(map[string]any{
+		"recovery_code_hash":     hashToken(normalizeRecoveryCode(code)),
+		"recovery_code_expiry":   expiresAt,
+		"recovery_code_attempts": 0,
+	}).Error; err != nil {
+		logger.Error("Erro ao salvar código de recuperação", "error", err, "user_id", user.ID)
+		return nil, err
+	}
+
+	actor, _ := strconv.ParseUint(adminID, 10, 64)
+	if s.audit != nil {
+		_ = s.audit.Record(&models.AuditLog{Action: AuditActionRecoveryCodeCreate, ActorID: uint(actor), TargetID: user.ID, IP: ip,
+			Details: "expires_at=" + expiresAt.UTC().Format(time.RFC3339)})
+	}
+	logger.Info("Código de recuperação de conta criado", "user_id", user.ID, "admin_id", adminID, "expires_at", expiresAt)
+	return &IssuedRecoveryCode{Code: code, Username: user.Username, ExpiresAt: expiresAt}, nil
+}
+
+// Redeem sets newPassword for the account behind identifier (username or email) when code is its
+// current recovery code, and uses the code up. Every session of the account ends. The code is kept when
+// the password is refused (ErrPasswordReused, or a *ValidationError when it contains the user's personal
+// info), so the user can try another one.
+func (s *RecoveryCodeService) Redeem(identifier, code, newPassword, ip string) error {
+	identifier = strings.TrimSpace(identifier)
+	if identifier == "" {
+		return ErrRecoveryCodeInvalid
+	}
+	var user models.User
+	if err := s.db.Where("(username = ? OR email = ?) AND recovery_code_hash <> ''", identifier, identifier).
+		First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Warn("Tentativa de recuperação de conta sem código pendente", "ip", ip)
+			return ErrRecoveryCodeInvalid
+		}
+		logger.Error("Erro ao buscar conta para recuperação", "error", err)
+		return err
+	}
+	if !s.now().Before(user.RecoveryCodeExpiry) {
+		s.discard(user.ID)
+		logger.Warn("Tentativa de recuperação de conta com código expirado", "user_id", user.ID, "ip", ip)
+		return ErrRecoveryCodeInvalid
+	}
+	hash := hashToken(normalizeRecoveryCode(code))
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(user.RecoveryCodeHash)) != 1 {
+		s.countWrongCode(&user, ip)
+		return ErrRecoveryCodeInvalid
+	}
+
+	// Claim the code first, so two requests can't both use it, and give it back if the password is refused
+	claim := s.db.Model(&models.User{}).Where("id = ? AND recovery_code_hash = ?", user.ID, hash).
+		Updates(map[string]any{"recovery_code_hash": "", "recovery_code_expiry": time.Time{}, "recovery_code_attempts": 0})
+	if claim.Error != nil {
+		logger.Error("Erro ao reservar código de recuperação", "error", claim.Error, "user_id", user.ID)
+		return claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		return ErrRecoveryCodeInvalid
+	}
+	if err := s.authService.RecoverPassword(strconv.FormatUint(uint64(user.ID), 10), newPassword); err != nil {
+		if releaseErr := s.db.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]any{
+			"recovery_code_hash":     user.RecoveryCodeHash,
+			"recovery_code_expiry":   user.RecoveryCodeExpiry,
+			"recovery_code_attempts": user.RecoveryCodeAttempts,
+		}).Error; releaseErr != nil {
+			logger.Error("Erro ao liberar código de recuperação", "error", releaseErr, "user_id", user.ID)
+		}
+		return err
+	}
+
+	if s.audit != nil {
+		_ = s.audit.Record(&models.AuditLog{Action: AuditActionRecoveryCodeUse, ActorID: user.ID, TargetID: user.ID, IP: ip})
+	}
+	logger.Info("Conta recuperada com código de recuperação", "user_id", user.ID, "ip", ip)
+	return nil
+}
+
+// countWrongCode records a wrong code for user and discards the code once maxRecoveryCodeAttempts is reached.
+func (s *RecoveryCodeService) countWrongCode(user *models.User, ip string) {
+	if user.RecoveryCodeAttempts+1 >= maxRecoveryCodeAttempts {
+		s.discard(user.ID)
+		logger.Warn("Código de recuperação descartado após códigos errados", "user_id", user.ID, "ip", ip)
+		return
+	}
+	if err := s.db.Model(&models.User{}).Where("id = ?", user.ID).
+		Update("recovery_code_attempts", gorm.Expr("recovery_code_attempts + 1")).Error; err != nil {
+		logger.Error("Erro ao contar código de recuperação errado", "error", err, "user_id", user.ID)
+	}
+	logger.Warn("Código de recuperação incorreto", "user_id", user.ID, "ip", ip)
+}
+
+// discard removes the recovery code of userID.
+func (s *RecoveryCodeService) discard(userID uint) {
+	if err := s.db.Model(&models.User{}).Where("id = ?", userID).
+		Updates(map[string]any{"recovery_code_hash": "", "recovery_code_expiry": time.Time{}, "recovery_code_attempts": 0}).Error; err != nil {
+		logger.Error("Erro ao descartar código de recuperação", "error", err, "user_id", userID)
+	}
+}
+
+func (s *RecoveryCodeService) find(userID string) (*models.User, error) {
+	id, err := strconv.ParseUint(userID, 10, 64)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	var user models.User
+	if err := s.db.First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// newRecoveryCode returns a random code in groups of recoveryCodeGroup (ABCD-EFGH-JKLM).
+func newRecoveryCode() (string, error) {
+	random := make([]byte, recoveryCodeLength)
+	if _, err := auth.GenerateRandomBytes(random); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for i, r := range random {
+		if i > 0 && i%recoveryCodeGroup == 0 {
+			b.WriteByte('-')
+		}
+		// 256 is a multiple of the 32 symbols, so every symbol is equally likely
+		b.WriteByte(recoveryCodeAlphabet[int(r)%len(recoveryCodeAlphabet)])
+	}
+	return b.String(), nil
+}
+
+// normalizeRecoveryCode drops the dashes and spaces a typed code may have and uppercases it.
+func normalizeRecoveryCode(code string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupRecoveryCodeTest(t *testing.T) (*RecoveryCodeService, *AuthService, *models.User, *gorm.DB) {
+	t.Helper()
+	authService, _, _, _, _, db := setupTest(t)
+	user := createTestUser(t, db)
+	return NewRecoveryCodeService(db, authService, NewAuditService(db), 0), authService, user, db
+}
+
+func TestRecoveryCodeService_Issue(t *testing.T) {
+	recovery, _, user, db := setupRecoveryCodeTest(t)
+	userID := strconv.FormatUint(uint64(user.ID), 10)
+
+	issued, err := recovery.Issue(userID, "99", "10.0.0.1")
+	require.NoError(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^[A-HJ-NP-Z2-9]{4}-[A-HJ-NP-Z2-9]{4}-[A-HJ-NP-Z2-9]{4}$`), issued.Code)
+	assert.Equal(t, "testuser", issued.Username)
+	assert.WithinDuration(t, time.Now().Add(DefaultRecoveryCodeTTL), issued.ExpiresAt, time.Minute)
+
+	// Only the hash is stored
+	var stored models.User
+	require.NoError(t, db.First(&stored, user.ID).Error)
+	assert.NotEmpty(t, stored.RecoveryCodeHash)
+	assert.NotContains(t, stored.RecoveryCodeHash, strings.ReplaceAll(issued.Code, "-", ""))
+
+	// A new code replaces the previous one
+	again, err := recovery.Issue(userID, "99", "10.0.0.1")
+	require.NoError(t, err)
+	assert.NotEqual(t, issued.Code, again.Code)
+	assert.ErrorIs(t, recovery.Redeem("testuser", issued.Code, "NewSecurePass123!", ""), ErrRecoveryCodeInvalid)
+
+	_, err = recovery.Issue("424242", "99", "")
+	require.ErrorIs(t, err, ErrUserNotFound)
+
+	var entry models.AuditLog
+	require.NoError(t, db.Where("action = ?", AuditActionRecoveryCodeCreate).First(&entry).Error)
+	assert.Equal(t, uint(99), entry.ActorID)
+	assert.Equal(t, user.ID, entry.TargetID)
+	assert.Equal(t, "10.0.0.1", entry.IP)
+}
+
+func TestRecoveryCodeService_Redeem_SingleUse(t *testing.T) {
+	recovery, authService, user, db := setupRecoveryCodeTest(t)
+	issued, err := recovery.Issue(strconv.FormatUint(uint64(user.ID), 10), "99", "")
+	require.NoError(t, err)
+
+	// A reused password, or one with the user's personal info, keeps the code, so the user can pick another one
+	require.ErrorIs(t, recovery.Redeem("testuser", issued.Code, "password123", ""), ErrPasswordReused)
+	var validationErr *ValidationError
+	require.ErrorAs(t, recovery.Redeem("testuser", issued.Code, "Testuser#2024!", ""), &validationErr)
+
+	// Typed in lowercase without dashes, by email
+	typed := strings.ToLower(strings.ReplaceAll(issued.Code, "-", ""))
+	require.NoError(t, recovery.Redeem("test@example.com", typed, "NewSecurePass123!", "10.0.0.2"))
+	_, err = authService.Login("testuser", "NewSecurePass123!", "127.0.0.1", "test")
+	require.NoError(t, err)
+
+	// The code works once
+	assert.ErrorIs(t, recovery.Redeem("testuser", issued.Code, "OtherSecurePass123!", ""), ErrRecoveryCodeInvalid)
+
+	var entry models.AuditLog
+	require.NoError(t, db.Where("action = ?", AuditActionRecoveryCodeUse).First(&entry).Error)
+	assert.Equal(t, user.ID, entry.ActorID)
+	assert.Equal(t, "10.0.0.2", entry.IP)
+}
+
+func TestRecoveryCodeService_Redeem_Expired(t *testing.T) {
+	recovery, _, user, db := setupRecoveryCodeTest(t)
+	issued, err := recovery.Issue(strconv.FormatUint(uint64(user.ID), 10), "99", "")
+	require.NoError(t, err)
+
+	recovery.now = func() time.Time { return time.Now().Add(DefaultRecoveryCodeTTL + time.Second) }
+	require.ErrorIs(t, recovery.Redeem("testuser", issued.Code, "NewSecurePass123!", ""), ErrRecoveryCodeInvalid)
+
+	var stored models.User
+	require.NoError(t, db.First(&stored, user.ID).Error)
+	assert.Empty(t, stored.RecoveryCodeHash, "expired code must be discarded")
+}
+
+func TestRecoveryCodeService_Redeem_WrongCodes(t *testing.T) {
+	recovery, _, user, _ := setupRecoveryCodeTest(t)
+	issued, err := recovery.Issue(strconv.FormatUint(uint64(user.ID), 10), "99", "")
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, recovery.Redeem("nobody", issued.Code, "NewSecurePass123!", ""), ErrRecoveryCodeInvalid)
+	for range maxRecoveryCodeAttempts {
+		assert.ErrorIs(t, recovery.Redeem("testuser", "AAAA-AAAA-AAAA", "NewSecurePass123!", ""), ErrRecoveryCodeInvalid)
+	}
+	// The right code no longer works once the attempts run out
+	assert.ErrorIs(t, recovery.Redeem("testuser", issued.Code, "NewSecurePass123!", ""), ErrRecoveryCodeInvalid)
+}
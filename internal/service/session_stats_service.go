@@ -0,0 +1,121 @@
+package service
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// UserSessionActivity counts one user's sessions in a time window.
+type UserSessionActivity struct {
+	UserID    uint   `json:"user_id"`
+	Username  string `json:"username"`
+	Created   int64  `json:"created"`   // sessions opened in the window (AuditActionSessionCreate)
+	Destroyed int64  `json:"destroyed"` // logouts in the window; ending every session at once counts as one
+	Active    int64  `json:"active"`    // sessions not expired yet
+	// Flagged marks a user with at least the threshold of sessions created or active, e.g. a stolen
+	// password being used from many places
+	Flagged bool `json:"flagged"`
+}
+
+// SessionStatsService counts sessions per user from the audit log (AuditService.RecordSessions and
+// RecordLogouts) and the sessions table.
+type SessionStatsService struct {
+	db *gorm.DB
+}
+
+// NewSessionStatsService creates a new SessionStatsService instance
+func NewSessionStatsService(db *gorm.DB) *SessionStatsService {
+	return &SessionStatsService{db: db}
+}
+
+// Activity returns up to limit users with sessions created or destroyed since the given time or still
+// active, flagged ones first and then by sessions created (most first).
+func (s *SessionStatsService) Activity(since time.Time, threshold, limit int) ([]UserSessionActivity, error) {
+	byUser := map[uint]*UserSessionActivity{}
+	get := func(id uint) *UserSessionActivity {
+		if byUser[id] == nil {
+			byUser[id] = &UserSessionActivity{UserID: id}
+		}
+		return byUser[id]
+	}
+
+	var events []struct {
+		TargetID uint
+		Action   string
+		Count    int64
+	}
+	err := s.db.Model(&models.AuditLog{}).
+		Select("target_id, action, COUNT(*) AS count").
+		Where("action IN ? AND created_at >= ? AND target_id <> 0", []string{AuditActionSessionCreate, AuditActionLogout}, since).
+		Group("target_id, action").
+		Scan(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range events {
+		if e.Action == AuditActionSessionCreate {
+			get(e.TargetID).Created = e.Count
+		} else {
+			get(e.TargetID).Destroyed = e.Count
+		}
+	}
+
+	var active []struct {
+		UserID uint
+		Count  int64
+	}
+	err = s.db.Model(&models.Session{}).
+		Select("user_id, COUNT(*) AS count").
+		Where("expires_at > ?", time.Now()).
+		Group("user_id").
+		Scan(&active).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range active {
+		get(a.UserID).Active = a.Count
+	}
+
+	activity := make([]UserSessionActivity, 0, len(byUser))
+	for _, a := range byUser {
+		a.Flagged = a.Created >= int64(threshold) || a.Active >= int64(threshold)
+		activity = append(activity, *a)
+	}
+	slices.SortFunc(activity, func(a, b UserSessionActivity) int {
+		if a.Flagged != b.Flagged {
+			if a.Flagged {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Or(cmp.Compare(b.Created, a.Created), cmp.Compare(b.Active, a.Active), cmp.Compare(a.UserID, b.UserID))
+	})
+	if limit > 0 && len(activity) > limit {
+		activity = activity[:limit]
+	}
+
+	if len(activity) == 0 {
+		return activity, nil
+	}
+	ids := make([]uint, len(activity))
+	for i, a := range activity {
+		ids[i] = a.UserID
+	}
+	var users []models.User
+	if err := s.db.Select("id", "username").Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	usernames := make(map[uint]string, len(users))
+	for _, u := range users {
+		usernames[u.ID] = u.Username
+	}
+	for i := range activity {
+		activity[i].Username = usernames[activity[i].UserID]
+	}
+	return activity, nil
+}
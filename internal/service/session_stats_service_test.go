@@ -0,0 +1,55 @@
+package service
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionStatsService_Activity(t *testing.T) {
+	_, authManager, _, _, _, db := setupTest(t)
+	audit := NewAuditService(db)
+	audit.RecordSessions(authManager)
+	audit.RecordLogouts(authManager)
+	stats := NewSessionStatsService(db)
+
+	user := createTestUser(t, db)
+	other := &models.User{Username: "other", Email: "other@example.com", PasswordHash: user.PasswordHash, Active: true, Role: RoleUser}
+	require.NoError(t, db.Create(other).Error)
+	const threshold = 5
+
+	for range threshold + 1 {
+		_, _, err := authManager.Login(user.Username, "password123", auth.SessionMetadata{IP: "10.0.0.1"})
+		require.NoError(t, err)
+	}
+	session, _, err := authManager.Login(other.Username, "password123", auth.SessionMetadata{})
+	require.NoError(t, err)
+	require.NoError(t, authManager.Logout(session.ID, auth.LogoutReasonUser))
+
+	activity, err := stats.Activity(time.Now().Add(-time.Hour), threshold, 10)
+	require.NoError(t, err)
+	require.Len(t, activity, 2)
+
+	assert.Equal(t, UserSessionActivity{UserID: user.ID, Username: "testuser", Created: threshold + 1, Active: threshold + 1, Flagged: true}, activity[0],
+		"a user with many new sessions is flagged and listed first")
+	assert.Equal(t, UserSessionActivity{UserID: other.ID, Username: "other", Created: 1, Destroyed: 1}, activity[1])
+
+	activity, err = stats.Activity(time.Now().Add(time.Hour), threshold, 1)
+	require.NoError(t, err)
+	require.Len(t, activity, 1, "limit caps the list")
+	assert.Equal(t, user.ID, activity[0].UserID)
+	assert.Zero(t, activity[0].Created, "sessions created before the window aren't counted")
+	assert.True(t, activity[0].Flagged, "active sessions over the threshold still flag the user")
+
+	var impersonation []struct{ ActorID, TargetID uint }
+	_, _, err = authManager.CreateSessionForUser(strconv.FormatUint(uint64(other.ID), 10), auth.SessionMetadata{ImpersonatedBy: strconv.FormatUint(uint64(user.ID), 10)})
+	require.NoError(t, err)
+	require.NoError(t, db.Table("audit_logs").Select("actor_id, target_id").Where("action = ?", AuditActionSessionCreate).Order("id DESC").Limit(1).Scan(&impersonation).Error)
+	assert.Equal(t, []struct{ ActorID, TargetID uint }{{user.ID, other.ID}}, impersonation, "the impersonating admin is the actor")
+}
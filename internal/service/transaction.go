@@ -0,0 +1,38 @@
+package service
+
+import (
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+
+	"gorm.io/gorm"
+)
+
+// WithTransaction runs fn inside a database transaction: it commits when fn returns nil and rolls back
+// when fn returns an error or panics (the panic is re-raised after the rollback). Every write of a
+// multi-step operation must go through tx, or it won't be part of the transaction.
+func WithTransaction(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	tx := db.Begin()
+	if tx.Error != nil {
+		logger.Error("Erro ao iniciar transação", "error", tx.Error)
+		return tx.Error
+	}
+
+	committed := false
+	defer func() {
+		if committed {
+			return
+		}
+		if rbErr := tx.Rollback().Error; rbErr != nil {
+			logger.Error("Erro ao desfazer transação", "error", rbErr)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		logger.Error("Erro ao confirmar transação", "error", err)
+		return err
+	}
+	committed = true
+	return nil
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestWithTransaction(t *testing.T) {
+	_, _, _, _, _, db := setupTest(t)
+	countRows := func() (users, audits int64) {
+		require.NoError(t, db.Model(&models.User{}).Count(&users).Error)
+		require.NoError(t, db.Model(&models.AuditLog{}).Count(&audits).Error)
+		return users, audits
+	}
+	twoWrites := func(tx *gorm.DB, name string) error {
+		user := &models.User{Username: name, Email: name + "@example.com", PasswordHash: "x", Active: true}
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.AuditLog{Action: AuditActionUserCreate, TargetID: user.ID}).Error
+	}
+
+	t.Run("Commits when fn succeeds", func(t *testing.T) {
+		err := WithTransaction(db, func(tx *gorm.DB) error { return twoWrites(tx, "committed") })
+		require.NoError(t, err)
+		users, audits := countRows()
+		assert.Equal(t, int64(1), users)
+		assert.Equal(t, int64(1), audits)
+	})
+
+	t.Run("A mid-transaction error rolls back every write", func(t *testing.T) {
+		errBoom := errors.New("boom")
+		err := WithTransaction(db, func(tx *gorm.DB) error {
+			if err := twoWrites(tx, "rolledback"); err != nil {
+				return err
+			}
+			return errBoom
+		})
+		require.ErrorIs(t, err, errBoom)
+		users, audits := countRows()
+		assert.Equal(t, int64(1), users)
+		assert.Equal(t, int64(1), audits)
+	})
+
+	t.Run("A panic rolls back and is re-raised", func(t *testing.T) {
+		assert.Panics(t, func() {
+			_ = WithTransaction(db, func(tx *gorm.DB) error {
+				if err := twoWrites(tx, "panicked"); err != nil {
+					return err
+				}
+				panic("boom")
+			})
+		})
+		users, audits := countRows()
+		assert.Equal(t, int64(1), users)
+		assert.Equal(t, int64(1), audits)
+	})
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"encoding/hex"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// TrustedDeviceService remembers the browsers where a user passed the login verification step
+// (LoginChallengeService) and asked to be remembered, so logins from them skip the step for a while
+// (config login.new_country_challenge.remember_device). Each device gets a random token kept in a
+// signed cookie; only its hash is stored, per user. Ending every session of the user with LogoutAll
+// (a password reset, an admin revoking or deactivating the account) forgets all their devices (see Watch).
+type TrustedDeviceService struct {
+	db  *gorm.DB
+	ttl time.Duration
+	now func() time.Time
+}
+
+// NewTrustedDeviceService creates a new TrustedDeviceService instance; devices are remembered for ttl.
+func NewTrustedDeviceService(db *gorm.DB, ttl time.Duration) *TrustedDeviceService {
+	return &TrustedDeviceService{db: db, ttl: ttl, now: time.Now}
+}
+
+// TTL returns how long a device stays remembered, for the cookie's lifetime.
+func (s *TrustedDeviceService) TTL() time.Duration {
+	return s.ttl
+}
+
+// Remember trusts a new device of userID and returns the token for its cookie; it can't be read back.
+// The user's expired devices are dropped on the way.
+func (s *TrustedDeviceService) Remember(userID string) (string, error) {
+	id, err := ParseUserID(userID)
+	if err != nil {
+		return "", ErrUserNotFound
+	}
+	tokenBytes := make([]byte, 32)
+	if _, err := auth.GenerateRandomBytes(tokenBytes); err != nil {
+		logger.Error("Erro ao gerar token do dispositivo", "error", err)
+		return "", err
+	}
+	token := hex.EncodeToString(tokenBytes)
+	now := s.now()
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND expires_at < ?", id, now).Delete(&models.TrustedDevice{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.TrustedDevice{UserID: id, TokenHash: hashToken(token), ExpiresAt: now.Add(s.ttl)}).Error
+	})
+	if err != nil {
+		logger.Error("Erro ao salvar dispositivo confiável", "error", err, "user_id", userID)
+		return "", err
+	}
+	logger.Info("Dispositivo lembrado", "user_id", userID)
+	return token, nil
+}
+
+// Trusted reports whether token is one of userID's remembered devices and hasn't expired. An empty or
+// unknown token, or another user's, is not trusted.
+func (s *TrustedDeviceService) Trusted(userID, token string) bool {
+	if token == "" {
+		return false
+	}
+	id, err := ParseUserID(userID)
+	if err != nil {
+		return false
+	}
+	var count int64
+	err = s.db.Model(&models.TrustedDevice{}).
+		Where("user_id = ? AND token_hash = ? AND expires_at > ?", id, hashToken(token), s.now()).
+		Count(&count).Error
+	if err != nil {
+		logger.Error("Erro ao consultar dispositivo confiável", "error", err, "user_id", userID)
+		return false
+	}
+	return count > 0
+}
+
+// Revoke forgets every remembered device of userID.
+func (s *TrustedDeviceService) Revoke(userID string) error {
+	id, err := ParseUserID(userID)
+	if err != nil {
+		return nil
+	}
+	if err := s.db.Where("user_id = ?", id).Delete(&models.TrustedDevice{}).Error; err != nil {
+		logger.Error("Erro ao remover dispositivos confiáveis", "error", err, "user_id", userID)
+		return err
+	}
+	return nil
+}
+
+// Watch makes authManager's LogoutAll forget the user's remembered devices too.
+// Call it during setup, before serving requests.
+func (s *TrustedDeviceService) Watch(authManager *auth.AuthManager) {
+	authManager.OnLogoutAll(func(userID string, _ auth.LogoutReason) {
+		_ = s.Revoke(userID)
+	})
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrustedDeviceService_Remember(t *testing.T) {
+	_, _, _, _, _, db := setupTest(t)
+	user := createTestUser(t, db)
+	devices := NewTrustedDeviceService(db, 24*time.Hour)
+	now := time.Now()
+	devices.now = func() time.Time { return now }
+
+	token, err := devices.Remember(idString(user.ID))
+	require.NoError(t, err)
+	assert.Len(t, token, 64)
+	assert.True(t, devices.Trusted(idString(user.ID), token))
+	assert.False(t, devices.Trusted(idString(user.ID), ""), "no cookie")
+	assert.False(t, devices.Trusted(idString(user.ID), "unknown"))
+	assert.False(t, devices.Trusted(idString(user.ID+1), token), "another user's device")
+
+	// Only the hash is stored
+	var stored models.TrustedDevice
+	require.NoError(t, db.Where("user_id = ?", user.ID).First(&stored).Error)
+	assert.Equal(t, hashToken(token), stored.TokenHash)
+	assert.WithinDuration(t, now.Add(24*time.Hour), stored.ExpiresAt, time.Second)
+
+	// Past its lifetime the device is no longer trusted, and the next Remember drops it
+	now = now.Add(25 * time.Hour)
+	assert.False(t, devices.Trusted(idString(user.ID), token))
+	_, err = devices.Remember(idString(user.ID))
+	require.NoError(t, err)
+	var count int64
+	db.Model(&models.TrustedDevice{}).Where("user_id = ?", user.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestLoginChallenge_TrustedDeviceSkipsCode(t *testing.T) {
+	authService, challenges, mockEmail, user := setupLoginChallenges(t)
+	devices := NewTrustedDeviceService(challenges.db, 24*time.Hour)
+	devices.Watch(challenges.authManager)
+	challenges.UseTrustedDevices(devices)
+	token, err := devices.Remember(idString(user.ID))
+	require.NoError(t, err)
+
+	response, err := authService.LoginFrom("testuser", "password123", auth.SessionMetadata{IP: "203.0.113.1", Country: "US", DeviceToken: token})
+	require.NoError(t, err)
+	assert.NotEmpty(t, response.SessionID)
+	assert.Empty(t, mockEmail.GetSentEmails())
+	assert.Equal(t, []string{"BR"}, knownCountriesOf(t, challenges, user), "the device is trusted, not the country")
+
+	// Without the cookie the same login still needs the code
+	_, err = authService.LoginFrom("testuser", "password123", auth.SessionMetadata{IP: "203.0.113.1", Country: "US"})
+	var challenge *LoginChallengeError
+	assert.True(t, errors.As(err, &challenge))
+
+	t.Run("LogoutAll forgets the device", func(t *testing.T) {
+		require.NoError(t, authService.LogoutAll(idString(user.ID), auth.LogoutReasonPasswordReset))
+		assert.False(t, devices.Trusted(idString(user.ID), token))
+
+		_, err := authService.LoginFrom("testuser", "password123", auth.SessionMetadata{IP: "203.0.113.1", Country: "US", DeviceToken: token})
+		var challenge *LoginChallengeError
+		assert.True(t, errors.As(err, &challenge), "a revoked device is challenged again")
+	})
+}
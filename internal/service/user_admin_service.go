@@ -0,0 +1,383 @@
+package service
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+	"github.com/lucas-varjao/gohtmx/internal/validation"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// Supported user roles.
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+var (
+	ErrUserNotFound = errors.New("usuário não encontrado")
+	ErrUserExists   = errors.New("usuário ou email já existe")
+	// ErrVersionConflict means the user changed since the version the caller last read
+	ErrVersionConflict = errors.New("usuário foi alterado por outra pessoa")
+	// ErrAccountTooNewForAdmin means the account is younger than RequireAccountAgeForAdmin allows for a promotion
+	ErrAccountTooNewForAdmin = errors.New("conta recente demais para ser promovida a admin")
+)
+
+// AnyVersion skips the optimistic-locking check in Update.
+const AnyVersion uint = 0
+
+// ValidationError wraps an input validation failure so handlers can tell it apart from
+// storage errors (400 / inline message instead of 500). Error() is the original message.
+// Fields, when set, holds one message per invalid form field (validation.RegistrationFields).
+type ValidationError struct {
+	Err    error
+	Fields validation.FieldErrors
+}
+
+func (e *ValidationError) Error() string { return e.Err.Error() }
+
+func (e *ValidationError) Unwrap() error { return e.Err }
+
+// UserFilter narrows the admin user listing. PerPage <= 0 disables pagination (returns every match).
+type UserFilter struct {
+	Query   string // matches username, email or display name (case-insensitive)
+	Role    string
+	Active  *bool
+	Page    int
+	PerPage int
+}
+
+// UserPage is one page of the admin user listing.
+type UserPage struct {
+	Users   []models.User
+	Total   int64
+	Page    int
+	PerPage int
+}
+
+// NewUserInput contains the fields an admin provides to create a user.
+// ActorID and IP identify the admin for the audit log entry.
+type NewUserInput struct {
+	Username    string
+	Email       string
+	DisplayName string
+	Password    string
+	Role        string
+	Active      bool
+	Phone       string // optional, normalized with validation.NormalizePhone
+	ActorID     string
+	IP          string
+}
+
+// UserUpdate holds the admin-editable fields; nil fields are left unchanged.
+type UserUpdate struct {
+	Role               *string
+	Active             *bool
+	DisplayName        *string
+	MustChangePassword *bool
+	Phone              *string // "" removes the phone
+}
+
+// UserAdminServiceInterface defines the user management operations shared by the HTML and JSON admin handlers.
+type UserAdminServiceInterface interface {
+	List(filter UserFilter) (*UserPage, error)
+	Get(id string) (*models.User, error)
+	Create(input NewUserInput) (*models.User, error)
+	UpdateRole(id, role string) (*models.User, error)
+	SetActive(id string, active bool) (*models.User, error)
+	UpdateDisplayName(id, displayName string) (*models.User, error)
+	Update(id string, changes UserUpdate, expectedVersion uint) (*models.User, error)
+	Delete(id string) error
+	LoginStatus(user *models.User) auth.LoginStatus
+}
+
+// UserAdminService implements user management for admins.
+type UserAdminService struct {
+	db                 *gorm.DB
+	authManager        *auth.AuthManager
+	logoutOnDeactivate bool
+	logoutOnRoleChange bool
+	minAdminAge        time.Duration
+}
+
+// NewUserAdminService creates a new UserAdminService instance.
+// Deactivating a user ends all their sessions right away (see KeepSessionsOnDeactivate).
+func NewUserAdminService(db *gorm.DB, authManager *auth.AuthManager) *UserAdminService {
+	return &UserAdminService{db: db, authManager: authManager, logoutOnDeactivate: true}
+}
+
+// KeepSessionsOnDeactivate leaves a deactivated user's sessions in place; they are refused on
+// the next request instead, when session validation re-checks Active.
+// Call it during setup, before serving requests.
+func (s *UserAdminService) KeepSessionsOnDeactivate() {
+	s.logoutOnDeactivate = false
+}
+
+// LogoutOnRoleChange ends all of a user's sessions when an admin changes their role, so they sign in
+// again under the new role instead of carrying on in sessions opened with the old one.
+// Call it during setup, before serving requests.
+func (s *UserAdminService) LogoutOnRoleChange() {
+	s.logoutOnRoleChange = true
+}
+
+// RequireAccountAgeForAdmin refuses to change the role of an account created less than age ago to admin,
+// so a freshly created account can't be made admin right away; Create refuses new admins for the same
+// reason. Demotions are not affected.
+// Call it during setup, before serving requests.
+func (s *UserAdminService) RequireAccountAgeForAdmin(age time.Duration) {
+	s.minAdminAge = age
+}
+
+// NormalizeRole ensures only supported roles are persisted.
+func NormalizeRole(role string) string {
+	if role != RoleAdmin && role != RoleUser {
+		return RoleUser
+	}
+	return role
+}
+
+// List returns users matching filter, newest first.
+func (s *UserAdminService) List(filter UserFilter) (*UserPage, error) {
+	query := s.db.Model(&models.User{})
+	if q := strings.ToLower(strings.TrimSpace(filter.Query)); q != "" {
+		like := "%" + q + "%"
+		query = query.Where("LOWER(username) LIKE ? OR LOWER(email) LIKE ? OR LOWER(display_name) LIKE ?", like, like, like)
+	}
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+	if filter.Active != nil {
+		query = query.Where("active = ?", *filter.Active)
+	}
+
+	page := &UserPage{Page: filter.Page, PerPage: filter.PerPage}
+	if err := query.Count(&page.Total).Error; err != nil {
+		logger.Error("Erro ao contar usuários", "error", err)
+		return nil, err
+	}
+
+	query = query.Order("created_at DESC")
+	if filter.PerPage > 0 {
+		if page.Page < 1 {
+			page.Page = 1
+		}
+		query = query.Offset((page.Page - 1) * filter.PerPage).Limit(filter.PerPage)
+	}
+	if err := query.Find(&page.Users).Error; err != nil {
+		logger.Error("Erro ao listar usuários", "error", err)
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// Get returns the user with the given ID.
+func (s *UserAdminService) Get(id string) (*models.User, error) {
+	uid, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	var user models.User
+	if err := s.db.First(&user, uid).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Create validates input and creates a user with a hashed password.
+func (s *UserAdminService) Create(input NewUserInput) (*models.User, error) {
+	phone := validation.NormalizePhone(input.Phone)
+	err := validation.ValidateRegistrationRequest(input.Username, input.Email, input.Password, input.DisplayName)
+	phoneErr := validation.ValidatePhone(phone)
+	if err != nil || phoneErr != nil {
+		fields := validation.ValidateRegistrationFields(input.Username, input.Email, input.Password, input.DisplayName)
+		if phoneErr != nil {
+			fields[validation.FieldPhone] = phoneErr.Error()
+		}
+		return nil, &ValidationError{Err: cmp.Or(err, phoneErr), Fields: fields}
+	}
+	if NormalizeRole(input.Role) == RoleAdmin && s.minAdminAge > 0 {
+		return nil, &ValidationError{Err: fmt.Errorf("%w: crie a conta como usuário e promova-a depois de %s", ErrAccountTooNewForAdmin, s.minAdminAge)}
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	if err != nil {
+		logger.Error("Erro ao gerar hash da senha", "error", err)
+		return nil, err
+	}
+
+	now := time.Now()
+	user := models.User{
+		Username:          input.Username,
+		Email:             input.Email,
+		DisplayName:       input.DisplayName,
+		PasswordHash:      string(hashedPassword),
+		PasswordChangedAt: &now,
+		Phone:             phone,
+		Role:              NormalizeRole(input.Role),
+		Active:            input.Active,
+		Version:           1,
+	}
+	actor, _ := strconv.ParseUint(input.ActorID, 10, 64)
+	// The user, its active flag and the audit entry are saved together or not at all
+	err = WithTransaction(s.db, func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			logger.Warn("Erro ao criar usuário pelo admin", "error", err, "username", input.Username)
+			return ErrUserExists
+		}
+		// Active=false is a zero value and would be replaced by the column default on insert
+		if !input.Active {
+			if err := tx.Model(&user).Update("active", false).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(&models.AuditLog{
+			Action:   AuditActionUserCreate,
+			ActorID:  uint(actor),
+			TargetID: user.ID,
+			IP:       input.IP,
+			Details:  "role=" + user.Role,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Usuário criado pelo admin", "user_id", user.ID, "username", user.Username)
+	return &user, nil
+}
+
+// UpdateRole changes a user's role (unsupported roles become "user").
+func (s *UserAdminService) UpdateRole(id, role string) (*models.User, error) {
+	return s.Update(id, UserUpdate{Role: &role}, AnyVersion)
+}
+
+// SetActive activates or deactivates a user.
+func (s *UserAdminService) SetActive(id string, active bool) (*models.User, error) {
+	return s.Update(id, UserUpdate{Active: &active}, AnyVersion)
+}
+
+// UpdateDisplayName validates and saves a new display name (surrounding spaces are trimmed).
+func (s *UserAdminService) UpdateDisplayName(id, displayName string) (*models.User, error) {
+	return s.Update(id, UserUpdate{DisplayName: &displayName}, AnyVersion)
+}
+
+// Update applies changes in a single statement and bumps the user's version.
+// When expectedVersion is not AnyVersion, the update only happens if the stored version still
+// matches, otherwise ErrVersionConflict is returned.
+func (s *UserAdminService) Update(id string, changes UserUpdate, expectedVersion uint) (*models.User, error) {
+	user, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if expectedVersion != AnyVersion && user.Version != expectedVersion {
+		return nil, ErrVersionConflict
+	}
+
+	fields := map[string]any{}
+	if changes.DisplayName != nil {
+		displayName := strings.TrimSpace(*changes.DisplayName)
+		if err := validation.ValidateDisplayName(displayName); err != nil {
+			return nil, &ValidationError{Err: err}
+		}
+		fields["display_name"] = displayName
+	}
+	if changes.Role != nil {
+		role := NormalizeRole(*changes.Role)
+		if role == RoleAdmin && user.Role != RoleAdmin && s.minAdminAge > 0 && time.Since(user.CreatedAt) < s.minAdminAge {
+			return nil, &ValidationError{Err: fmt.Errorf("%w: ela precisa existir há pelo menos %s", ErrAccountTooNewForAdmin, s.minAdminAge)}
+		}
+		fields["role"] = role
+	}
+	if changes.Active != nil {
+		fields["active"] = *changes.Active
+	}
+	if changes.MustChangePassword != nil {
+		fields["must_change_password"] = *changes.MustChangePassword
+	}
+	if changes.Phone != nil {
+		phone := validation.NormalizePhone(*changes.Phone)
+		if err := validation.ValidatePhone(phone); err != nil {
+			return nil, &ValidationError{Err: err, Fields: validation.FieldErrors{validation.FieldPhone: err.Error()}}
+		}
+		fields["phone"] = phone
+	}
+	if len(fields) == 0 {
+		return user, nil
+	}
+	fields["version"] = gorm.Expr("version + 1")
+
+	result := s.db.Model(&models.User{}).Where("id = ? AND version = ?", user.ID, user.Version).Updates(fields)
+	if result.Error != nil {
+		logger.Error("Erro ao atualizar usuário", "error", result.Error, "user_id", user.ID)
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		// Someone else updated the row between our read and write
+		return nil, ErrVersionConflict
+	}
+	if changes.Active != nil && !*changes.Active && user.Active && s.logoutOnDeactivate {
+		_ = s.authManager.LogoutAll(strconv.FormatUint(uint64(user.ID), 10), auth.LogoutReasonDeactivated)
+	} else if changes.Role != nil && NormalizeRole(*changes.Role) != user.Role && s.logoutOnRoleChange {
+		_ = s.authManager.LogoutAll(strconv.FormatUint(uint64(user.ID), 10), auth.LogoutReasonRoleChanged)
+	}
+
+	return s.Get(id)
+}
+
+// Delete permanently removes a user (hard delete) after ending all their sessions.
+func (s *UserAdminService) Delete(id string) error {
+	user, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	_ = s.authManager.LogoutAll(strconv.FormatUint(uint64(user.ID), 10), auth.LogoutReasonAdminRevoked)
+	err = WithTransaction(s.db, func(tx *gorm.DB) error {
+		return HardDeleteUsers(tx, user.ID)
+	})
+	if err != nil {
+		logger.Error("Erro ao excluir usuário", "error", err, "user_id", user.ID)
+		return err
+	}
+
+	logger.Info("Usuário excluído pelo admin", "user_id", user.ID, "username", user.Username)
+	return nil
+}
+
+// userOwnedModels are the tables whose rows belong to a single user (user_id) and go with the account.
+var userOwnedModels = []any{&models.WebAuthnCredential{}, &models.BackupCode{}, &models.PasswordHistory{}, &models.TrustedDevice{}}
+
+// HardDeleteUsers permanently removes the users ids along with the rows they own (passkeys, backup codes,
+// password history, trusted devices), which no foreign key cascades. Run it inside WithTransaction, after ending the
+// users' sessions with LogoutAll.
+func HardDeleteUsers(tx *gorm.DB, ids ...uint) error {
+	for _, model := range userOwnedModels {
+		if err := tx.Where("user_id IN ?", ids).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Unscoped().Delete(&models.User{}, ids).Error
+}
+
+// LoginStatus tells whether user can log in right now (see auth.AuthManager.LoginStatus).
+func (s *UserAdminService) LoginStatus(user *models.User) auth.LoginStatus {
+	return s.authManager.LoginStatus(&auth.UserData{
+		Identifier:         user.Username,
+		Email:              user.Email,
+		Active:             user.Active,
+		EmailVerified:      user.EmailVerified,
+		MustChangePassword: user.MustChangePassword,
+	})
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/models"
+	"github.com/lucas-varjao/gohtmx/internal/validation"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserAdminService_Create(t *testing.T) {
+	_, authManager, _, _, _, db := setupTest(t)
+	users := NewUserAdminService(db, authManager)
+
+	t.Run("Inactive user stays inactive", func(t *testing.T) {
+		u, err := users.Create(NewUserInput{
+			Username: "inactive", Email: "inactive@example.com", DisplayName: "Inactive",
+			Password: "Test123!@#", Role: "superuser", Active: false, ActorID: "42", IP: "10.0.0.1",
+		})
+		require.NoError(t, err)
+
+		stored, err := users.Get(idString(u.ID))
+		require.NoError(t, err)
+		assert.False(t, stored.Active)
+		assert.Equal(t, RoleUser, stored.Role, "unknown roles fall back to user")
+
+		var entry models.AuditLog
+		require.NoError(t, db.Where("action = ?", AuditActionUserCreate).First(&entry).Error)
+		assert.Equal(t, uint(42), entry.ActorID)
+		assert.Equal(t, u.ID, entry.TargetID)
+		assert.Equal(t, "10.0.0.1", entry.IP)
+		assert.Equal(t, "role=user", entry.Details)
+	})
+
+	t.Run("No new admins under a minimum account age", func(t *testing.T) {
+		users := NewUserAdminService(db, authManager)
+		users.RequireAccountAgeForAdmin(72 * time.Hour)
+		_, err := users.Create(NewUserInput{
+			Username: "newadmin", Email: "newadmin@example.com", DisplayName: "New Admin",
+			Password: "Test123!@#", Role: RoleAdmin, Active: true,
+		})
+		var validationErr *ValidationError
+		require.True(t, errors.As(err, &validationErr))
+		require.ErrorIs(t, err, ErrAccountTooNewForAdmin)
+		var count int64
+		require.NoError(t, db.Model(&models.User{}).Where("username = ?", "newadmin").Count(&count).Error)
+		assert.Zero(t, count)
+
+		u, err := users.Create(NewUserInput{
+			Username: "newuser", Email: "newuser@example.com", DisplayName: "New User",
+			Password: "Test123!@#", Role: RoleUser, Active: true,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, RoleUser, u.Role)
+	})
+
+	t.Run("Validation error is typed", func(t *testing.T) {
+		_, err := users.Create(NewUserInput{Username: "x", Email: "bad", DisplayName: "", Password: "123"})
+		var validationErr *ValidationError
+		require.True(t, errors.As(err, &validationErr))
+		assert.Len(t, validationErr.Fields, 4, "every invalid field gets its own message")
+		assert.Equal(t, validation.ErrEmailInvalid.Error(), validationErr.Fields[validation.FieldEmail])
+	})
+
+	t.Run("Phone is optional but validated", func(t *testing.T) {
+		u, err := users.Create(NewUserInput{
+			Username: "withphone", Email: "withphone@example.com", DisplayName: "Phone",
+			Password: "Test123!@#", Active: true, Phone: "0044 20 7946 0958",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "+442079460958", u.Phone)
+
+		_, err = users.Create(NewUserInput{
+			Username: "badphone", Email: "badphone@example.com", DisplayName: "Phone",
+			Password: "Test123!@#", Active: true, Phone: "12345",
+		})
+		var validationErr *ValidationError
+		require.True(t, errors.As(err, &validationErr))
+		assert.Equal(t, validation.FieldErrors{validation.FieldPhone: validation.ErrPhoneInvalid.Error()}, validationErr.Fields)
+
+		noPhone := ""
+		updated, err := users.Update(idString(u.ID), UserUpdate{Phone: &noPhone}, AnyVersion)
+		require.NoError(t, err)
+		assert.Empty(t, updated.Phone)
+	})
+
+	t.Run("Duplicate user", func(t *testing.T) {
+		auditEntries := func() int64 {
+			var n int64
+			require.NoError(t, db.Model(&models.AuditLog{}).Where("action = ?", AuditActionUserCreate).Count(&n).Error)
+			return n
+		}
+		before := auditEntries()
+		_, err := users.Create(NewUserInput{
+			Username: "inactive", Email: "other@example.com", DisplayName: "Dup", Password: "Test123!@#",
+		})
+		assert.ErrorIs(t, err, ErrUserExists)
+		assert.Equal(t, before, auditEntries(), "a failed creation leaves no audit entry")
+	})
+}
+
+func TestUserAdminService_List(t *testing.T) {
+	_, authManager, _, _, _, db := setupTest(t)
+	users := NewUserAdminService(db, authManager)
+	createTestUser(t, db)
+	_, err := users.Create(NewUserInput{
+		Username: "maria", Email: "maria@example.com", DisplayName: "Maria Silva", Password: "Test123!@#", Role: RoleAdmin, Active: true,
+	})
+	require.NoError(t, err)
+
+	page, err := users.List(UserFilter{Query: "SILVA"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), page.Total)
+
+	page, err = users.List(UserFilter{Role: RoleUser})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), page.Total)
+	assert.Equal(t, "testuser", page.Users[0].Username)
+
+	page, err = users.List(UserFilter{PerPage: 1, Page: 2})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), page.Total)
+	assert.Len(t, page.Users, 1)
+
+	_, err = users.Get("not-a-number")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestUserAdminService_SetActive_EndsSessions(t *testing.T) {
+	authService, authManager, _, _, _, db := setupTest(t)
+	users := NewUserAdminService(db, authManager)
+	user := createTestUser(t, db)
+	sessionCount := func() int64 {
+		var n int64
+		require.NoError(t, db.Model(&models.Session{}).Where("user_id = ?", user.ID).Count(&n).Error)
+		return n
+	}
+
+	t.Run("Deactivation logs the user out everywhere", func(t *testing.T) {
+		for range 2 {
+			_, err := authService.Login("testuser", "password123", "127.0.0.1", "test-agent")
+			require.NoError(t, err)
+		}
+		require.Equal(t, int64(2), sessionCount())
+
+		_, err := users.SetActive(idString(user.ID), false)
+		require.NoError(t, err)
+		assert.Zero(t, sessionCount())
+	})
+
+	t.Run("Soft mode keeps sessions until the next request", func(t *testing.T) {
+		_, err := users.SetActive(idString(user.ID), true)
+		require.NoError(t, err)
+		loginResp, err := authService.Login("testuser", "password123", "127.0.0.1", "test-agent")
+		require.NoError(t, err)
+
+		users.KeepSessionsOnDeactivate()
+		_, err = users.SetActive(idString(user.ID), false)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), sessionCount())
+		_, _, err = authService.ValidateSession(loginResp.SessionID)
+		assert.Error(t, err)
+	})
+}
+
+func TestUserAdminService_UpdateRole_Sessions(t *testing.T) {
+	authService, authManager, _, _, _, db := setupTest(t)
+	NewAuditService(db).RecordLogouts(authManager)
+	user := createTestUser(t, db)
+	users := NewUserAdminService(db, authManager)
+	_, err := users.UpdateRole(idString(user.ID), RoleAdmin)
+	require.NoError(t, err)
+
+	t.Run("A demoted user loses admin right away", func(t *testing.T) {
+		loginResp, err := authService.Login("testuser", "password123", "127.0.0.1", "test-agent")
+		require.NoError(t, err)
+		_, userData, err := authService.ValidateSession(loginResp.SessionID)
+		require.NoError(t, err)
+		require.Equal(t, RoleAdmin, userData.Role)
+
+		_, err = users.UpdateRole(idString(user.ID), RoleUser)
+		require.NoError(t, err)
+		_, userData, err = authService.ValidateSession(loginResp.SessionID)
+		require.NoError(t, err, "by default the session survives")
+		assert.Equal(t, RoleUser, userData.Role, "with the new role already")
+	})
+
+	t.Run("Forced re-auth ends the sessions and is audited", func(t *testing.T) {
+		users.LogoutOnRoleChange()
+		_, err := users.UpdateRole(idString(user.ID), RoleAdmin)
+		require.NoError(t, err)
+		loginResp, err := authService.Login("testuser", "password123", "127.0.0.1", "test-agent")
+		require.NoError(t, err)
+
+		_, err = users.UpdateRole(idString(user.ID), RoleUser)
+		require.NoError(t, err)
+		_, _, err = authService.ValidateSession(loginResp.SessionID)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+
+		var entry models.AuditLog
+		require.NoError(t, db.Where("action = ?", AuditActionLogout).Last(&entry).Error)
+		assert.Equal(t, "reason=role_changed scope=all", entry.Details)
+		assert.Equal(t, user.ID, entry.TargetID)
+
+		// Saving the same role again is not a change
+		loginResp, err = authService.Login("testuser", "password123", "127.0.0.1", "test-agent")
+		require.NoError(t, err)
+		_, err = users.UpdateRole(idString(user.ID), RoleUser)
+		require.NoError(t, err)
+		_, _, err = authService.ValidateSession(loginResp.SessionID)
+		assert.NoError(t, err)
+	})
+}
+
+func idString(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
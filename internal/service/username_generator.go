@@ -0,0 +1,107 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/validation"
+)
+
+const (
+	// fallbackUsername is the base when neither the display name nor the email give a usable one
+	fallbackUsername = "usuario"
+	// usernameBaseMaxLen keeps room for the numeric suffix under validation's 50 characters
+	usernameBaseMaxLen = 40
+	// usernameSuffixes is how many numbered candidates (base2, base3...) are tried after the base
+	usernameSuffixes = 99
+	// generatedUsernameAttempts is how many generated names Register tries when sign-ups race for one
+	generatedUsernameAttempts = 3
+)
+
+// ErrNoUsernameAvailable means every candidate username derived from the display name and email is taken.
+var ErrNoUsernameAvailable = errors.New("não foi possível gerar um nome de usuário disponível")
+
+// usernameAccents maps accented letters to the plain ones kept in usernames ("João" → "joao").
+var usernameAccents = strings.NewReplacer(
+	"à", "a", "á", "a", "â", "a", "ã", "a", "ä", "a", "å", "a",
+	"ç", "c",
+	"è", "e", "é", "e", "ê", "e", "ë", "e",
+	"ì", "i", "í", "i", "î", "i", "ï", "i",
+	"ñ", "n",
+	"ò", "o", "ó", "o", "ô", "o", "õ", "o", "ö", "o",
+	"ù", "u", "ú", "u", "û", "u", "ü", "u",
+	"ý", "y", "ÿ", "y",
+)
+
+// UsernameGenerator derives a free username for sign-ups that don't ask for one: the display name (or the
+// email's local part) as a slug ("Maria da Silva" → "maria.da.silva"), with a number appended while the
+// name is taken ("maria.da.silva2"). Every generated name passes validation.ValidateUsername.
+//
+// Generate only checks the names against the stored users; two sign-ups racing for the same name are
+// settled by the unique username column, and AuthService.Register retries with a new one.
+type UsernameGenerator struct {
+	users auth.UserAdapter
+}
+
+// NewUsernameGenerator creates a UsernameGenerator that checks candidates with users.FindUserByIdentifier.
+func NewUsernameGenerator(users auth.UserAdapter) *UsernameGenerator {
+	return &UsernameGenerator{users: users}
+}
+
+// Generate returns the first candidate derived from displayName and email that no user has.
+func (g *UsernameGenerator) Generate(displayName, email string) (string, error) {
+	base := UsernameSlug(displayName, email)
+	for n := 1; n <= usernameSuffixes+1; n++ {
+		candidate := base
+		if n > 1 {
+			candidate += strconv.Itoa(n)
+		}
+		_, err := g.users.FindUserByIdentifier(candidate)
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return "", ErrNoUsernameAvailable
+}
+
+// UsernameSlug is the base username for displayName, or for email's local part when the display name
+// gives less than 3 usable characters: lowercase, accents removed, other characters turned into dots.
+func UsernameSlug(displayName, email string) string {
+	local, _, _ := strings.Cut(email, "@")
+	local, _, _ = strings.Cut(local, "+")
+	for _, source := range []string{displayName, local} {
+		slug := slugify(source)
+		if validation.ValidateUsername(slug) == nil {
+			return slug
+		}
+	}
+	return fallbackUsername
+}
+
+// slugify keeps the ASCII letters and digits of s (accents removed), joined by single dots.
+func slugify(s string) string {
+	s = usernameAccents.Replace(strings.ToLower(strings.TrimSpace(s)))
+	var b strings.Builder
+	dot := false
+	for _, r := range s {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			if dot && b.Len() > 0 {
+				b.WriteByte('.')
+			}
+			dot = false
+			b.WriteRune(r)
+			continue
+		}
+		dot = true
+	}
+	slug := b.String()
+	if len(slug) > usernameBaseMaxLen {
+		slug = strings.TrimRight(slug[:usernameBaseMaxLen], ".")
+	}
+	return slug
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+	gormadapter "github.com/lucas-varjao/gohtmx/internal/auth/adapter/gorm"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+	"github.com/lucas-varjao/gohtmx/internal/validation"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsernameSlug(t *testing.T) {
+	tests := []struct {
+		name        string
+		displayName string
+		email       string
+		want        string
+	}{
+		{"Display name", "Maria da Silva", "m@example.com", "maria.da.silva"},
+		{"Accents", "João Conceição", "j@example.com", "joao.conceicao"},
+		{"Symbols collapse", "  Ana -- Lú (admin)!  ", "a@example.com", "ana.lu.admin"},
+		{"Too short uses the email", "Jo", "jo.santos+news@example.com", "jo.santos"},
+		{"No letters uses the email", "😀 ✨", "carla_m@example.com", "carla.m"},
+		{"Nothing usable", "李", "x@example.com", fallbackUsername},
+		{"Long name is cut", strings.Repeat("abcdefghij ", 6), "a@example.com", "abcdefghij.abcdefghij.abcdefghij.abcdefg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := UsernameSlug(tt.displayName, tt.email)
+			assert.Equal(t, tt.want, got)
+			assert.NoError(t, validation.ValidateUsername(got))
+		})
+	}
+}
+
+func TestUsernameGenerator_Collisions(t *testing.T) {
+	_, _, userAdapter, _, _, db := setupTest(t)
+	for _, username := range []string{"maria.silva", "maria.silva2"} {
+		require.NoError(t, db.Create(&models.User{Username: username, Email: username + "@example.com", PasswordHash: "x"}).Error)
+	}
+	generator := NewUsernameGenerator(userAdapter)
+
+	got, err := generator.Generate("Maria Silva", "maria@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "maria.silva3", got)
+	assert.NoError(t, validation.ValidateUsername(got))
+
+	got, err = generator.Generate("Pedro", "pedro@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "pedro", got, "a free name gets no suffix")
+}
+
+// racingUsers reports the first lookup as free, like a sign-up that checked a name just before another
+// one saved it.
+type racingUsers struct {
+	*gormadapter.UserAdapter
+	raced bool
+}
+
+func (r *racingUsers) FindUserByIdentifier(identifier string) (*auth.UserData, error) {
+	if !r.raced {
+		r.raced = true
+		return nil, auth.ErrInvalidCredentials
+	}
+	return r.UserAdapter.FindUserByIdentifier(identifier)
+}
+
+func TestRegister_GeneratedUsername(t *testing.T) {
+	authService, _, userAdapter, _, _, db := setupTest(t)
+	require.NoError(t, db.Create(&models.User{Username: "maria.silva", Email: "other@example.com", PasswordHash: "x"}).Error)
+	authService.UseUsernameGenerator(NewUsernameGenerator(&racingUsers{UserAdapter: userAdapter}))
+
+	user, err := authService.Register("", "maria@example.com", "Password123!", "Maria Silva")
+	require.NoError(t, err)
+	assert.Equal(t, "maria.silva2", user.Username, "the name taken by the racing sign-up is skipped")
+
+	user, err = authService.Register("chosen", "chosen@example.com", "Password123!", "Maria Silva")
+	require.NoError(t, err)
+	assert.Equal(t, "chosen", user.Username, "a given username is kept")
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"cmp"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/email"
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// DefaultVerificationBatchCap limits a batch when NewVerificationService gets maxBatch <= 0.
+const DefaultVerificationBatchCap = 500
+
+// VerificationBatch reports what a ResendToUnverified run did (or, in a dry run, would do).
+type VerificationBatch struct {
+	DryRun bool `json:"dry_run"`
+	// Eligible is every user the batch could target; Targeted is the part that fits in the cap
+	Eligible int64 `json:"eligible"`
+	Targeted int   `json:"targeted"`
+	Enqueued int   `json:"enqueued"`
+}
+
+// VerificationService emails unverified users a link that confirms their current address.
+//
+// The link is the email change confirmation (AuthService.ConfirmEmailChange) for a pending change to
+// the same address, so it reuses that token, TTL and endpoint. The token is only stored when its email
+// goes out, so an email the queue drops or fails to send leaves the user as they were. Users with a
+// pending change or verification that hasn't expired, or with an email still in the queue, are skipped:
+// a batch never clobbers an email change, and running it again after hitting the cap moves on to the
+// next users.
+type VerificationService struct {
+	db       *gorm.DB
+	queue    *email.Queue
+	audit    AuditRecorder
+	maxBatch int
+
+	mu     sync.Mutex
+	queued map[uint]struct{} // users whose email is waiting in the queue
+}
+
+// NewVerificationService creates a new VerificationService that sends through queue (a throttled
+// queue keeps bulk sends under the SMTP provider's limits) and caps each batch at maxBatch users.
+func NewVerificationService(db *gorm.DB, queue *email.Queue, audit AuditRecorder, maxBatch int) *VerificationService {
+	if maxBatch <= 0 {
+		maxBatch = DefaultVerificationBatchCap
+	}
+	return &VerificationService{db: db, queue: queue, audit: audit, maxBatch: maxBatch, queued: make(map[uint]struct{})}
+}
+
+// eligible selects active, unverified users without an unexpired pending email change or a queued email.
+func (s *VerificationService) eligible(now time.Time) *gorm.DB {
+	query := s.db.Model(&models.User{}).
+		Where("email_verified = ? AND active = ?", false, true).
+		Where("email_change_token = '' OR email_change_token IS NULL OR email_change_expiry < ?", now)
+	s.mu.Lock()
+	queued := slices.Collect(maps.Keys(s.queued))
+	s.mu.Unlock()
+	if len(queued) > 0 {
+		query = query.Where("id NOT IN ?", queued)
+	}
+	return query
+}
+
+// ResendToUnverified queues a verification email for up to limit eligible users (oldest accounts
+// first); limit <= 0 or above the cap means the cap. A dry run only counts. Real runs are audited
+// with adminID as the actor.
+func (s *VerificationService) ResendToUnverified(adminID string, limit int, dryRun bool, ip string) (*VerificationBatch, error) {
+	if limit <= 0 || limit > s.maxBatch {
+		limit = s.maxBatch
+	}
+	now := time.Now()
+	batch := &VerificationBatch{DryRun: dryRun}
+	if err := s.eligible(now).Count(&batch.Eligible).Error; err != nil {
+		logger.Error("Erro ao contar usuários não verificados", "error", err)
+		return nil, err
+	}
+	batch.Targeted = int(min(batch.Eligible, int64(limit)))
+	if dryRun || batch.Targeted == 0 {
+		return batch, nil
+	}
+
+	var users []models.User
+	if err := s.eligible(now).Order("id ASC").Limit(batch.Targeted).Find(&users).Error; err != nil {
+		logger.Error("Erro ao listar usuários não verificados", "error", err)
+		return nil, err
+	}
+	batch.Targeted = len(users)
+	for i := range users {
+		ok, err := s.enqueue(&users[i], now)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			// Queue full or closed: the rest would be dropped too
+			break
+		}
+		batch.Enqueued++
+	}
+
+	s.record(adminID, ip, batch)
+	logger.Info("Reenvio de verificação em lote", "admin_id", adminID, "eligible", batch.Eligible,
+		"targeted", batch.Targeted, "enqueued", batch.Enqueued)
+	return batch, nil
+}
+
+// enqueue queues the verification email for user. The token is stored right before the email is sent
+// and cleared again when sending fails; an email the queue drops never stores one.
+func (s *VerificationService) enqueue(user *models.User, now time.Time) (bool, error) {
+	const tokenByteSize = 32
+	tokenBytes := make([]byte, tokenByteSize)
+	if _, err := auth.GenerateRandomBytes(tokenBytes); err != nil {
+		return false, err
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	id, to, username, displayName := user.ID, user.Email, user.Username, cmp.Or(user.DisplayName, user.Username)
+	s.mu.Lock()
+	s.queued[id] = struct{}{}
+	s.mu.Unlock()
+	queued := s.queue.Enqueue(email.TypeEmailVerification, to, func(svc email.EmailServiceInterface) error {
+		defer s.dequeue(id)
+		if err := s.db.Model(&models.User{}).Where("id = ?", id).Updates(map[string]any{
+			"pending_email":       to,
+			"email_change_token":  hashToken(token),
+			"email_change_expiry": now.Add(emailChangeTokenTTL),
+		}).Error; err != nil {
+			logger.Error("Erro ao salvar token de verificação", "error", err, "user_id", id)
+			return err
+		}
+		if err := svc.SendEmailVerification(to, token, username, displayName); err != nil {
+			s.clearToken(id)
+			return err
+		}
+		return nil
+	})
+	if !queued {
+		s.dequeue(id)
+	}
+	return queued, nil
+}
+
+// dequeue makes userID eligible for later batches again (unless a token was stored for them).
+func (s *VerificationService) dequeue(userID uint) {
+	s.mu.Lock()
+	delete(s.queued, userID)
+	s.mu.Unlock()
+}
+
+// clearToken removes the verification token of an email that couldn't be sent.
+func (s *VerificationService) clearToken(userID uint) {
+	if err := s.db.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]any{
+		"pending_email": "", "email_change_token": "", "email_change_expiry": time.Time{},
+	}).Error; err != nil {
+		logger.Error("Erro ao limpar token de verificação", "error", err, "user_id", userID)
+	}
+}
+
+func (s *VerificationService) record(adminID, ip string, batch *VerificationBatch) {
+	if s.audit == nil {
+		return
+	}
+	actor, _ := strconv.ParseUint(adminID, 10, 64)
+	_ = s.audit.Record(&models.AuditLog{
+		Action:  AuditActionVerificationBatch,
+		ActorID: uint(actor),
+		IP:      ip,
+		Details: fmt.Sprintf("eligible=%d targeted=%d enqueued=%d", batch.Eligible, batch.Targeted, batch.Enqueued),
+	})
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/email"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+	"gorm.io/gorm"
+)
+
+func seedVerificationUser(t *testing.T, db *gorm.DB, username string, verified, active bool) *models.User {
+	t.Helper()
+	user := &models.User{Username: username, Email: username + "@example.com", DisplayName: username,
+		PasswordHash: "hash", Role: RoleUser}
+	require.NoError(t, db.Create(user).Error)
+	// false is a zero value and would be replaced by the column defaults on insert
+	require.NoError(t, db.Model(user).Updates(map[string]any{"email_verified": verified, "active": active}).Error)
+	return user
+}
+
+func TestVerificationService_ResendToUnverified(t *testing.T) {
+	authService, _, _, _, _, db := setupTest(t)
+	admin := seedVerificationUser(t, db, "boss", true, true)
+	adminID := strconv.FormatUint(uint64(admin.ID), 10)
+	first := seedVerificationUser(t, db, "first", false, true)
+	second := seedVerificationUser(t, db, "second", false, true)
+	third := seedVerificationUser(t, db, "third", false, true)
+	seedVerificationUser(t, db, "verified", true, true)
+	seedVerificationUser(t, db, "inactive", false, false)
+	changing := seedVerificationUser(t, db, "changing", false, true)
+	require.NoError(t, db.Model(changing).Updates(map[string]any{
+		"pending_email": "new@example.com", "email_change_token": "hash", "email_change_expiry": time.Now().Add(time.Hour),
+	}).Error)
+
+	mock := email.NewMockEmailService()
+	queue := email.NewQueue(mock, 10)
+	verification := NewVerificationService(db, queue, NewAuditService(db), 2)
+
+	t.Run("Dry run only counts", func(t *testing.T) {
+		batch, err := verification.ResendToUnverified(adminID, 0, true, "10.0.0.1")
+		require.NoError(t, err)
+		assert.Equal(t, &VerificationBatch{DryRun: true, Eligible: 3, Targeted: 2}, batch)
+
+		var pending int64
+		db.Model(&models.User{}).Where("pending_email = email").Count(&pending)
+		assert.Zero(t, pending)
+	})
+
+	t.Run("Batch respects the cap and continues on the next run", func(t *testing.T) {
+		batch, err := verification.ResendToUnverified(adminID, 10, false, "10.0.0.1")
+		require.NoError(t, err)
+		assert.Equal(t, &VerificationBatch{Eligible: 3, Targeted: 2, Enqueued: 2}, batch)
+
+		batch, err = verification.ResendToUnverified(adminID, 0, false, "10.0.0.1")
+		require.NoError(t, err)
+		assert.Equal(t, &VerificationBatch{Eligible: 1, Targeted: 1, Enqueued: 1}, batch)
+		require.NoError(t, queue.Close(t.Context()))
+
+		sent := mock.GetSentEmails()
+		require.Len(t, sent, 3)
+		for i, user := range []*models.User{first, second, third} {
+			assert.Equal(t, email.MockKindEmailVerification, sent[i].Kind)
+			assert.Equal(t, user.Email, sent[i].To)
+		}
+
+		var entries []models.AuditLog
+		require.NoError(t, db.Where("action = ?", AuditActionVerificationBatch).Order("id").Find(&entries).Error)
+		require.Len(t, entries, 2, "dry runs are not audited")
+		assert.Equal(t, admin.ID, entries[0].ActorID)
+		assert.Equal(t, "eligible=3 targeted=2 enqueued=2", entries[0].Details)
+
+		// The link verifies the current address
+		confirmed, err := authService.ConfirmEmailChange(sent[0].Token)
+		require.NoError(t, err)
+		assert.Equal(t, first.Email, confirmed.Email)
+		assert.True(t, confirmed.EmailVerified)
+	})
+
+	t.Run("Pending email change is left alone", func(t *testing.T) {
+		var stored models.User
+		require.NoError(t, db.First(&stored, changing.ID).Error)
+		assert.Equal(t, "new@example.com", stored.PendingEmail)
+		assert.Equal(t, "hash", stored.EmailChangeToken)
+	})
+}
+
+func TestVerificationService_UnsentEmailsKeepNoToken(t *testing.T) {
+	_, _, _, _, _, db := setupTest(t)
+	admin := seedVerificationUser(t, db, "boss", true, true)
+	adminID := strconv.FormatUint(uint64(admin.ID), 10)
+	failed := seedVerificationUser(t, db, "failed", false, true)
+	dropped := seedVerificationUser(t, db, "dropped", false, true)
+
+	t.Run("Failed send", func(t *testing.T) {
+		mock := email.NewMockEmailService()
+		mock.SetSendEmailError(errors.New("smtp down"))
+		queue := email.NewQueue(mock, 10)
+		batch, err := NewVerificationService(db, queue, nil, 1).ResendToUnverified(adminID, 0, false, "")
+		require.NoError(t, err)
+		assert.Equal(t, 1, batch.Enqueued)
+		require.NoError(t, queue.Close(t.Context()))
+
+		var stored models.User
+		require.NoError(t, db.First(&stored, failed.ID).Error)
+		assert.Empty(t, stored.EmailChangeToken, "the user stays eligible for the next batch")
+		assert.Empty(t, stored.PendingEmail)
+	})
+
+	t.Run("Dropped when the queue closes", func(t *testing.T) {
+		mock := email.NewMockEmailService()
+		// One email now, the next only after 10 minutes
+		queue := email.NewThrottledQueue(mock, 10, rate.Every(10*time.Minute))
+		verification := NewVerificationService(db, queue, nil, 10)
+		batch, err := verification.ResendToUnverified(adminID, 0, false, "")
+		require.NoError(t, err)
+		assert.Equal(t, 2, batch.Enqueued)
+
+		// Emails still in the queue aren't targeted again
+		again, err := verification.ResendToUnverified(adminID, 0, true, "")
+		require.NoError(t, err)
+		assert.Zero(t, again.Eligible)
+		require.NoError(t, queue.Close(t.Context()))
+
+		// Only the emails that went out (at most the first) stored a token
+		var withToken int64
+		require.NoError(t, db.Model(&models.User{}).Where("email_change_token <> ''").Count(&withToken).Error)
+		assert.Equal(t, int64(len(mock.GetSentEmails())), withToken)
+		var stored models.User
+		require.NoError(t, db.First(&stored, dropped.ID).Error)
+		assert.Empty(t, stored.EmailChangeToken, "the dropped email stored no token")
+	})
+}
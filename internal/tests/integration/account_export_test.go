@@ -0,0 +1,93 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountExport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r, db, authManager := setupIntegrationTest(t)
+	userSession := createUserWithSession(t, db, authManager, "alice", "user")
+	adminSession := createUserWithSession(t, db, authManager, "boss", "admin")
+
+	var alice models.User
+	require.NoError(t, db.Where("username = ?", "alice").First(&alice).Error)
+	require.NoError(t, db.Model(&alice).Updates(map[string]any{
+		"reset_token":        "reset-secret",
+		"reset_token_expiry": time.Now().Add(time.Hour),
+		"email_change_token": "email-change-secret",
+	}).Error)
+	require.NoError(t, db.Create(&models.LoginAttempt{Identifier: "alice", IP: "10.0.0.9", Success: true}).Error)
+	require.NoError(t, db.Create(&models.LoginAttempt{Identifier: "ALICE@example.com", IP: "10.0.0.9", Reason: "invalid_credentials"}).Error)
+	require.NoError(t, db.Create(&models.LoginAttempt{Identifier: "boss", IP: "10.0.0.8", Success: true}).Error)
+
+	w := doJSON(r, http.MethodGet, "/api/account/export", userSession, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment;")
+	assert.Contains(t, w.Header().Get("Content-Disposition"), ".json")
+
+	body := w.Body.String()
+	var export map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &export))
+	for _, section := range []string{"generated_at", "profile", "sessions", "login_attempts"} {
+		assert.Contains(t, export, section)
+	}
+	profile := export["profile"].(map[string]any)
+	assert.Equal(t, "alice", profile["username"])
+	assert.Len(t, export["sessions"], 1)
+	// Attempts by username and (case-insensitive) email; boss's attempts stay out
+	assert.Len(t, export["login_attempts"], 2)
+
+	for _, secret := range []string{alice.PasswordHash, userSession, "reset-secret", "email-change-secret", "password_hash"} {
+		assert.NotContains(t, body, secret)
+	}
+
+	// Admins can export any user; regular users cannot use the admin route
+	path := "/api/admin/users/" + strconv.FormatUint(uint64(alice.ID), 10) + "/export"
+	w = doJSON(r, http.MethodGet, path, adminSession, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &export))
+	assert.Equal(t, "alice", export["profile"].(map[string]any)["username"])
+	assert.NotContains(t, w.Body.String(), "reset-secret")
+
+	w = doJSON(r, http.MethodGet, path, userSession, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doJSON(r, http.MethodGet, "/api/admin/users/9999/export", adminSession, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doJSON(r, http.MethodGet, "/api/account/export", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestImpersonationSession_BlocksAccountSensitiveRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r, db, authManager := setupIntegrationTest(t)
+	createUserWithSession(t, db, authManager, "alice", "user")
+	createUserWithSession(t, db, authManager, "boss", "admin")
+
+	var alice, boss models.User
+	require.NoError(t, db.Where("username = ?", "alice").First(&alice).Error)
+	require.NoError(t, db.Where("username = ?", "boss").First(&boss).Error)
+	session, _, err := authManager.CreateSessionForUser(strconv.FormatUint(uint64(alice.ID), 10),
+		auth.SessionMetadata{ImpersonatedBy: strconv.FormatUint(uint64(boss.ID), 10)})
+	require.NoError(t, err)
+
+	w := doJSON(r, http.MethodGet, "/api/me", session.ID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doJSON(r, http.MethodGet, "/api/account/export", session.ID, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doJSON(r, http.MethodPost, "/api/change-password", session.ID, map[string]any{
+		"current_password": "Test123!@#", "new_password": "Other123!@#", "confirm_password": "Other123!@#",
+	})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -0,0 +1,238 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// createUserWithSession seeds a user with the given role and returns a valid session ID for it.
+func createUserWithSession(t *testing.T, db *gorm.DB, authManager *auth.AuthManager, username, role string) string {
+	t.Helper()
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("Test123!@#"), bcrypt.MinCost)
+	require.NoError(t, err)
+	require.NoError(t, db.Create(&models.User{
+		Username:     username,
+		Email:        username + "@example.com",
+		DisplayName:  username,
+		PasswordHash: string(hashedPassword),
+		Active:       true,
+		Role:         role,
+	}).Error)
+
+	session, _, err := authManager.Login(username, "Test123!@#", auth.SessionMetadata{IP: "127.0.0.1"})
+	require.NoError(t, err)
+	return session.ID
+}
+
+func doJSON(r *gin.Engine, method, path, sessionID string, body any) *httptest.ResponseRecorder {
+	return doJSONWithHeaders(r, method, path, sessionID, body, nil)
+}
+
+func doJSONWithHeaders(r *gin.Engine, method, path, sessionID string, body any, headers map[string]string) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		_ = json.NewEncoder(&buf).Encode(body)
+	}
+	req, _ := http.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	if sessionID != "" {
+		req.Header.Set("Authorization", "Bearer "+sessionID)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAdminUsersAPI_AuthEnforcement(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r, db, authManager := setupIntegrationTest(t)
+	userSession := createUserWithSession(t, db, authManager, "regular", "user")
+
+	w := doJSON(r, http.MethodGet, "/api/admin/users", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = doJSON(r, http.MethodGet, "/api/admin/users", userSession, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = doJSON(r, http.MethodDelete, "/api/admin/users/1", userSession, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestAdminUsersAPI_CRUDFlow(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r, db, authManager := setupIntegrationTest(t)
+	adminSession := createUserWithSession(t, db, authManager, "boss", "admin")
+
+	// 1. Create
+	w := doJSON(r, http.MethodPost, "/api/admin/users", adminSession, map[string]any{
+		"username":     "newbie",
+		"email":        "newbie@example.com",
+		"display_name": "Newbie",
+		"password":     "Test123!@#",
+		"role":         "user",
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "newbie", created["username"])
+	assert.Equal(t, true, created["active"])
+	assert.NotContains(t, created, "password_hash")
+	id := strconv.Itoa(int(created["id"].(float64)))
+
+	// Duplicate and invalid payloads
+	w = doJSON(r, http.MethodPost, "/api/admin/users", adminSession, map[string]any{
+		"username": "newbie", "email": "other@example.com", "display_name": "X", "password": "Test123!@#",
+	})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = doJSON(r, http.MethodPost, "/api/admin/users", adminSession, map[string]any{
+		"username": "bad user", "email": "bad@example.com", "display_name": "X", "password": "Test123!@#",
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 2. List with filter and pagination
+	w = doJSON(r, http.MethodGet, "/api/admin/users?q=newb&per_page=1", adminSession, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var list map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, float64(1), list["total"])
+	assert.Len(t, list["users"], 1)
+
+	w = doJSON(r, http.MethodGet, "/api/admin/users?per_page=1&page=2", adminSession, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, float64(2), list["total"])
+	assert.Len(t, list["users"], 1)
+
+	// 3. Update role, active and display name (conditional on the current ETag)
+	w = doJSON(r, http.MethodGet, "/api/admin/users/"+id, adminSession, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	w = doJSONWithHeaders(r, http.MethodPatch, "/api/admin/users/"+id, adminSession, map[string]any{
+		"role": "admin", "active": false, "display_name": "Promoted",
+	}, map[string]string{"If-Match": etag})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var updated map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &updated))
+	assert.Equal(t, "admin", updated["role"])
+	assert.Equal(t, false, updated["active"])
+	assert.Equal(t, "Promoted", updated["display_name"])
+
+	w = doJSONWithHeaders(r, http.MethodPatch, "/api/admin/users/"+id, adminSession,
+		map[string]any{"display_name": ""}, map[string]string{"If-Match": "*"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 4. Get
+	w = doJSON(r, http.MethodGet, "/api/admin/users/"+id, adminSession, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// 5. Delete
+	w = doJSON(r, http.MethodDelete, "/api/admin/users/"+id, adminSession, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = doJSON(r, http.MethodGet, "/api/admin/users/"+id, adminSession, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAdminUsersAPI_ConditionalUpdate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r, db, authManager := setupIntegrationTest(t)
+	adminSession := createUserWithSession(t, db, authManager, "boss", "admin")
+	createUserWithSession(t, db, authManager, "target", "user")
+
+	var target models.User
+	require.NoError(t, db.Where("username = ?", "target").First(&target).Error)
+	path := "/api/admin/users/" + strconv.FormatUint(uint64(target.ID), 10)
+
+	w := doJSON(r, http.MethodGet, path, adminSession, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	staleETag := w.Header().Get("ETag")
+	assert.Equal(t, `"v1"`, staleETag)
+
+	// Unchanged resource revalidates with 304
+	w = doJSONWithHeaders(r, http.MethodGet, path, adminSession, nil, map[string]string{"If-None-Match": staleETag})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	// Missing If-Match is rejected
+	w = doJSON(r, http.MethodPatch, path, adminSession, map[string]any{"role": "admin"})
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+
+	// Successful conditional update returns the new ETag
+	w = doJSONWithHeaders(r, http.MethodPatch, path, adminSession, map[string]any{"display_name": "First"},
+		map[string]string{"If-Match": staleETag})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	freshETag := w.Header().Get("ETag")
+	assert.Equal(t, `"v2"`, freshETag)
+
+	// A second client still holding the old ETag gets 412 and nothing changes
+	w = doJSONWithHeaders(r, http.MethodPatch, path, adminSession, map[string]any{"display_name": "Second"},
+		map[string]string{"If-Match": staleETag})
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	require.NoError(t, db.First(&target, target.ID).Error)
+	assert.Equal(t, "First", target.DisplayName)
+	assert.Equal(t, uint(2), target.Version)
+
+	// Garbage ETag is a failed precondition too
+	w = doJSONWithHeaders(r, http.MethodPatch, path, adminSession, map[string]any{"role": "admin"},
+		map[string]string{"If-Match": `"abc"`})
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+}
+
+func TestAdminUsersAPI_ListStyles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r, db, authManager := setupIntegrationTest(t)
+	adminSession := createUserWithSession(t, db, authManager, "boss", "admin")
+	createUserWithSession(t, db, authManager, "regular", "user")
+
+	w := doJSON(r, http.MethodGet, "/api/admin/users?per_page=1", adminSession, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var plain struct {
+		Users   []map[string]any `json:"users"`
+		Total   int64            `json:"total"`
+		Page    int              `json:"page"`
+		PerPage int              `json:"per_page"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &plain))
+	require.Len(t, plain.Users, 1)
+
+	w = doJSONWithHeaders(r, http.MethodGet, "/api/admin/users?per_page=1", adminSession, nil,
+		map[string]string{"Accept": `application/json; profile="envelope"`})
+	require.Equal(t, http.StatusOK, w.Code)
+	var envelope struct {
+		Data []map[string]any `json:"data"`
+		Meta struct {
+			Total   int64 `json:"total"`
+			Page    int   `json:"page"`
+			PerPage int   `json:"per_page"`
+		} `json:"meta"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &envelope))
+	assert.Equal(t, plain.Users, envelope.Data)
+	assert.Equal(t, plain.Total, envelope.Meta.Total)
+	assert.Equal(t, plain.Page, envelope.Meta.Page)
+	assert.Equal(t, plain.PerPage, envelope.Meta.PerPage)
+
+	w = doJSON(r, http.MethodGet, "/api/admin/users?per_page=1&style=array", adminSession, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var array []map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &array))
+	assert.Equal(t, plain.Users, array)
+	assert.Equal(t, "2", w.Header().Get("X-Total-Count"))
+	assert.Equal(t, "1", w.Header().Get("X-Per-Page"))
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/lucas-varjao/gohtmx/internal/auth"
 	gormadapter "github.com/lucas-varjao/gohtmx/internal/auth/adapter/gorm"
+	"github.com/lucas-varjao/gohtmx/internal/config"
 	"github.com/lucas-varjao/gohtmx/internal/email"
 	"github.com/lucas-varjao/gohtmx/internal/handlers"
 	"github.com/lucas-varjao/gohtmx/internal/models"
@@ -25,10 +26,17 @@ import (
 )
 
 func setupIntegrationTest(t *testing.T) (*gin.Engine, *gorm.DB, *auth.AuthManager) {
+	return setupIntegrationTestWithConfig(t, auth.DefaultAuthConfig(), nil)
+}
+
+// setupIntegrationTestWithConfig is setupIntegrationTest with explicit auth settings and app config
+// (nil cfg uses the loaded app config, like NewAuthHandler).
+func setupIntegrationTestWithConfig(t *testing.T, authConfig *auth.AuthConfig, cfg *config.Config) (*gin.Engine, *gorm.DB, *auth.AuthManager) {
 	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	require.NoError(t, err)
 
-	err = db.AutoMigrate(&models.User{}, &models.Session{})
+	err = db.AutoMigrate(&models.User{}, &models.Session{}, &models.LoginAttempt{}, &models.PasswordHistory{}, &models.AuditLog{},
+		&models.WebAuthnCredential{}, &models.BackupCode{}, &models.TrustedDevice{})
 	require.NoError(t, err)
 
 	// Setup adapters
@@ -36,16 +44,20 @@ func setupIntegrationTest(t *testing.T) (*gin.Engine, *gorm.DB, *auth.AuthManage
 	sessionAdapter := gormadapter.NewSessionAdapter(db)
 
 	// Setup auth manager
-	authConfig := auth.DefaultAuthConfig()
 	authManager := auth.NewAuthManager(userAdapter, sessionAdapter, authConfig)
 
 	// Setup services
 	emailService := email.NewMockEmailService()
-	authService := service.NewAuthService(authManager, userAdapter, emailService)
+	authService := service.NewAuthService(authManager, userAdapter, emailService, service.NewLoginAttemptService(db))
 	authHandler := handlers.NewAuthHandler(authService)
+	if cfg != nil {
+		authHandler = handlers.NewAuthHandlerWithConfig(authService, cfg)
+	}
 
 	// Setup router
-	r := router.SetupRouter(authHandler, authManager, nil)
+	adminUserHandler := handlers.NewAdminUserHandler(service.NewUserAdminService(db, authManager))
+	accountHandler := handlers.NewAccountHandler(service.NewAccountService(db))
+	r := router.SetupRouter(authHandler, adminUserHandler, accountHandler, authManager, nil, nil)
 	return r, db, authManager
 }
 
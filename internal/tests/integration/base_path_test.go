@@ -0,0 +1,55 @@
+package integration
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/lucas-varjao/gohtmx/internal/basepath"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBasePath_PrefixedRoutesAndRedirects(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	basepath.Set("/myapp")
+	t.Cleanup(func() { basepath.Set("") })
+
+	r, db, authManager := setupIntegrationTest(t)
+	createUserWithSession(t, db, authManager, "alice", "user")
+	h := basepath.Handler(r)
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	// Routes resolve under the prefix and nowhere else
+	w := serve(httptest.NewRequest(http.MethodGet, "/myapp/ping", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = serve(httptest.NewRequest(http.MethodGet, "/ping", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Unauthenticated HTMX request: login redirect and next= both account for the prefix
+	req := httptest.NewRequest(http.MethodGet, "/myapp/api/me", nil)
+	req.Header.Set("HX-Request", "true")
+	req.Header.Set("HX-Current-URL", "http://localhost/myapp/admin/users?page=2")
+	w = serve(req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "/myapp/login?next="+url.QueryEscape("/admin/users?page=2"), w.Header().Get("HX-Redirect"))
+
+	// HTMX login sends the browser to a prefixed page and scopes the cookie to the prefix
+	form := url.Values{"username": {"alice"}, "password": {"Test123!@#"}, "next": {"/admin/users"}}
+	req = httptest.NewRequest(http.MethodPost, "/myapp/auth/login", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("HX-Request", "true")
+	w = serve(req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "/myapp/admin/users", w.Header().Get("HX-Redirect"))
+	assert.Contains(t, w.Header().Get("Set-Cookie"), "Path=/myapp")
+}
@@ -0,0 +1,155 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/config"
+	"github.com/lucas-varjao/gohtmx/internal/middleware"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// expiresInHeader parses the X-Session-Expires-In response header.
+func expiresInHeader(t *testing.T, header http.Header) time.Duration {
+	t.Helper()
+	seconds, err := strconv.Atoi(header.Get(middleware.SessionExpiresInHeader))
+	require.NoError(t, err, "missing or invalid %s", middleware.SessionExpiresInHeader)
+	return time.Duration(seconds) * time.Second
+}
+
+func TestSessionExpiry_HeaderAndExtend(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	authConfig := auth.DefaultAuthConfig()
+	authConfig.SessionDuration = time.Hour
+	authConfig.RefreshThreshold = 30 * time.Minute
+	authConfig.MaxSessionLifetime = 2 * time.Hour
+	cfg := &config.Config{Session: config.SessionConfig{WarnBefore: 5 * time.Minute}}
+	r, db, authManager := setupIntegrationTestWithConfig(t, authConfig, cfg)
+	sessionID := createUserWithSession(t, db, authManager, "alice", "user")
+
+	// Every authenticated response tells the client how long the session has left
+	w := doJSON(r, http.MethodGet, "/api/me", sessionID, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.InDelta(t, time.Hour.Seconds(), expiresInHeader(t, w.Header()).Seconds(), 2)
+
+	w = doJSON(r, http.MethodGet, "/api/session", sessionID, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var status map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.Equal(t, float64(300), status["warn_before"])
+	assert.InDelta(t, time.Hour.Seconds(), status["expires_in"], 2)
+
+	// Idle for a while: 10 minutes left, 50 minutes of lifetime left
+	now := time.Now()
+	require.NoError(t, db.Model(&models.Session{}).Where("id = ?", sessionID).Updates(map[string]any{
+		"created_at": now.Add(-70 * time.Minute),
+		"expires_at": now.Add(10 * time.Minute),
+	}).Error)
+	w = doJSON(r, http.MethodGet, "/api/session", sessionID, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.InDelta(t, (50 * time.Minute).Seconds(), expiresInHeader(t, w.Header()).Seconds(), 2,
+		"activity refreshes the session only up to created_at + MaxSessionLifetime")
+
+	// Extending can't go past the cap either
+	w = doJSON(r, http.MethodPost, "/api/session/extend", sessionID, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.InDelta(t, (50 * time.Minute).Seconds(), expiresInHeader(t, w.Header()).Seconds(), 2)
+
+	// Well inside the lifetime, extending gives a full SessionDuration
+	require.NoError(t, db.Model(&models.Session{}).Where("id = ?", sessionID).Updates(map[string]any{
+		"created_at": now.Add(-10 * time.Minute),
+		"expires_at": now.Add(5 * time.Minute),
+	}).Error)
+	w = doJSON(r, http.MethodPost, "/api/session/extend", sessionID, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.InDelta(t, time.Hour.Seconds(), expiresInHeader(t, w.Header()).Seconds(), 2)
+
+	var stored models.Session
+	require.NoError(t, db.First(&stored, "id = ?", sessionID).Error)
+	assert.WithinDuration(t, now.Add(time.Hour), stored.ExpiresAt, 2*time.Second)
+
+	w = doJSON(r, http.MethodPost, "/api/session/extend", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestSessionPing_RespectsAbsoluteCap(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	authConfig := auth.DefaultAuthConfig()
+	authConfig.SessionDuration = time.Hour
+	authConfig.RefreshThreshold = 30 * time.Minute
+	authConfig.MaxSessionLifetime = 2 * time.Hour
+	r, db, authManager := setupIntegrationTestWithConfig(t, authConfig, &config.Config{})
+	sessionID := createUserWithSession(t, db, authManager, "alice", "user")
+
+	setSession := func(createdAgo, expiresIn time.Duration) {
+		t.Helper()
+		now := time.Now()
+		require.NoError(t, db.Model(&models.Session{}).Where("id = ?", sessionID).Updates(map[string]any{
+			"created_at": now.Add(-createdAgo),
+			"expires_at": now.Add(expiresIn),
+		}).Error)
+	}
+	storedExpiry := func() time.Time {
+		t.Helper()
+		var stored models.Session
+		require.NoError(t, db.First(&stored, "id = ?", sessionID).Error)
+		return stored.ExpiresAt
+	}
+
+	// Close to expiry: the ping slides it to a full SessionDuration
+	setSession(10*time.Minute, 5*time.Minute)
+	w := doJSON(r, http.MethodGet, "/api/session/ping", sessionID, nil)
+	require.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.InDelta(t, time.Hour.Seconds(), expiresInHeader(t, w.Header()).Seconds(), 2)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), storedExpiry(), 2*time.Second)
+
+	// Near the end of the lifetime: extended only up to created_at + MaxSessionLifetime
+	setSession(110*time.Minute, 5*time.Minute)
+	w = doJSON(r, http.MethodGet, "/api/session/ping", sessionID, nil)
+	require.Equal(t, http.StatusNoContent, w.Code)
+	assert.WithinDuration(t, time.Now().Add(10*time.Minute), storedExpiry(), 2*time.Second)
+
+	// Plenty of time left: nothing to write
+	setSession(10*time.Minute, 50*time.Minute)
+	before := storedExpiry()
+	w = doJSON(r, http.MethodGet, "/api/session/ping", sessionID, nil)
+	require.Equal(t, http.StatusNoContent, w.Code)
+	assert.True(t, before.Equal(storedExpiry()))
+
+	// Past the cap the session is gone, not revived
+	setSession(3*time.Hour, -time.Minute)
+	w = doJSON(r, http.MethodGet, "/api/session/ping", sessionID, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestValidateSession_AbsoluteCapRejectsActiveSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	authConfig := auth.DefaultAuthConfig()
+	authConfig.MaxSessionLifetime = 2 * time.Hour
+	r, db, authManager := setupIntegrationTestWithConfig(t, authConfig, &config.Config{})
+	sessionID := createUserWithSession(t, db, authManager, "alice", "user")
+
+	// Recently refreshed (expiry well ahead) but created before the cap, e.g. issued when the cap was longer
+	now := time.Now()
+	require.NoError(t, db.Model(&models.Session{}).Where("id = ?", sessionID).Updates(map[string]any{
+		"created_at": now.Add(-2*time.Hour - time.Minute),
+		"expires_at": now.Add(20 * 24 * time.Hour),
+	}).Error)
+
+	w := doJSON(r, http.MethodGet, "/api/me", sessionID, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	var remaining int64
+	db.Model(&models.Session{}).Where("id = ?", sessionID).Count(&remaining)
+	assert.Zero(t, remaining, "the capped session is deleted")
+
+	assert.Equal(t, auth.DefaultMaxSessionLifetime, auth.DefaultAuthConfig().MaxSessionLifetime, "sessions are capped by default")
+}
@@ -0,0 +1,57 @@
+// Package tracing wires optional OpenTelemetry tracing: an OTLP exporter, a per-request span
+// middleware and spans around GORM queries. Without an exporter the global tracer provider stays
+// the OpenTelemetry no-op, so nothing is recorded.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lucas-varjao/gohtmx/internal/config"
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the spans created by this app.
+const instrumentationName = "github.com/lucas-varjao/gohtmx"
+
+// defaultServiceName is reported when tracing.service_name is empty.
+const defaultServiceName = "gohtmx"
+
+// Setup installs an OTLP/HTTP exporter as the global tracer provider when cfg.Endpoint is set and
+// returns a function that flushes pending spans on shutdown. With no endpoint it does nothing.
+func Setup(ctx context.Context, cfg config.TracingConfig) (func(context.Context) error, error) {
+	if cfg.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("falha ao criar o exportador OTLP: %w", err)
+	}
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	logger.Info("Tracing OpenTelemetry habilitado", "endpoint", cfg.Endpoint, "service", serviceName)
+	return provider.Shutdown, nil
+}
+
+// tracer returns the app tracer from the current global provider.
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
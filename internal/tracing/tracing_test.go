@@ -0,0 +1,164 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+	gormadapter "github.com/lucas-varjao/gohtmx/internal/auth/adapter/gorm"
+	"github.com/lucas-varjao/gohtmx/internal/middleware"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// useInMemoryExporter installs a synchronous in-memory exporter as the global provider for one test.
+func useInMemoryExporter(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		_ = provider.Shutdown(t.Context())
+	})
+	return exporter
+}
+
+func spanAttr(span tracetest.SpanStub, key string) attribute.Value {
+	for _, kv := range span.Attributes {
+		if string(kv.Key) == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestMiddleware_SpanPerRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	exporter := useInMemoryExporter(t)
+
+	r := gin.New()
+	r.Use(Middleware())
+	var handlerSpan trace.SpanContext
+	r.GET("/users/:id", func(c *gin.Context) {
+		handlerSpan = trace.SpanContextFromContext(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+	r.GET("/boom", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set("X-Request-ID", "req-123")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/boom", nil))
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+
+	ok := spans[0]
+	assert.Equal(t, "GET /users/:id", ok.Name)
+	assert.Equal(t, trace.SpanKindServer, ok.SpanKind)
+	assert.Equal(t, "/users/:id", spanAttr(ok, "http.route").AsString())
+	assert.Equal(t, int64(http.StatusOK), spanAttr(ok, "http.response.status_code").AsInt64())
+	assert.Equal(t, "req-123", spanAttr(ok, "http.request.id").AsString())
+	assert.Equal(t, ok.SpanContext.SpanID(), handlerSpan.SpanID(), "handlers see the request span in their context")
+
+	failed := spans[1]
+	assert.Equal(t, "GET /boom", failed.Name)
+	assert.Equal(t, codes.Error, failed.Status.Code)
+	assert.NotEqual(t, ok.SpanContext.TraceID(), failed.SpanContext.TraceID())
+}
+
+func TestMiddleware_ContinuesIncomingTrace(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	exporter := useInMemoryExporter(t)
+	previous := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(previous) })
+
+	r := gin.New()
+	r.Use(Middleware())
+	r.GET("/ping", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent.SpanID().String())
+}
+
+func TestInstrumentDB_QuerySpansJoinContext(t *testing.T) {
+	exporter := useInMemoryExporter(t)
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	type widget struct {
+		ID   uint
+		Name string
+	}
+	require.NoError(t, db.AutoMigrate(&widget{}))
+	require.NoError(t, InstrumentDB(db))
+
+	ctx, parent := otel.Tracer("test").Start(t.Context(), "request")
+	require.NoError(t, db.WithContext(ctx).Create(&widget{Name: "a"}).Error)
+	var found widget
+	require.NoError(t, db.WithContext(ctx).First(&found).Error)
+	parent.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 3)
+	assert.Equal(t, "gorm.create widgets", spans[0].Name)
+	assert.Equal(t, "gorm.query widgets", spans[1].Name)
+	for _, span := range spans[:2] {
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent.SpanID())
+		assert.Contains(t, spanAttr(span, "db.query.text").AsString(), "widgets")
+	}
+}
+
+func TestInstrumentDB_SessionLookupJoinsRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	exporter := useInMemoryExporter(t)
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Session{}))
+	authManager := auth.NewAuthManager(gormadapter.NewUserAdapter(db), gormadapter.NewSessionAdapter(db), nil)
+	user, err := authManager.GetUserAdapter().CreateUser(auth.CreateUserInput{Identifier: "traced", Email: "traced@example.com", Password: "password123"})
+	require.NoError(t, err)
+	session, _, err := authManager.CreateSessionForUser(user.ID, auth.SessionMetadata{})
+	require.NoError(t, err)
+	require.NoError(t, InstrumentDB(db))
+
+	r := gin.New()
+	r.Use(Middleware())
+	r.GET("/me", middleware.AuthMiddleware(authManager), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+session.ID)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	spans := exporter.GetSpans()
+	require.NotEmpty(t, spans)
+	request := spans[len(spans)-1]
+	require.Equal(t, "GET /me", request.Name)
+	queries := 0
+	for _, span := range spans[:len(spans)-1] {
+		assert.Equal(t, request.SpanContext.SpanID(), span.Parent.SpanID(), "%s is a child of the request span", span.Name)
+		queries++
+	}
+	assert.Positive(t, queries, "the session lookup is traced")
+}
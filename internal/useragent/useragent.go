@@ -0,0 +1,193 @@
+// Package useragent turns User-Agent headers into friendly browser, OS and device names.
+package useragent
+
+import "strings"
+
+// Device kinds reported in Agent.Device.
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+)
+
+// Agent is what a User-Agent header tells about the client. Names are empty when unknown.
+type Agent struct {
+	// Browser is the browser's name (Chrome, Firefox, Safari, Edge, ...) or the client's, for bots and tools
+	Browser string `json:"browser"`
+	// BrowserVersion is the major version ("126")
+	BrowserVersion string `json:"browser_version"`
+	// OS is the operating system with its version when the header has one ("Android 14", "iOS 17.4")
+	OS string `json:"os"`
+	// Device is one of DeviceDesktop, DeviceMobile, DeviceTablet or DeviceBot
+	Device string `json:"device"`
+}
+
+// Describe names the browser and system for messages shown to the user ("Chrome 126 em Android 14").
+func (a Agent) Describe() string {
+	browser := a.Browser
+	if browser != "" && a.BrowserVersion != "" {
+		browser += " " + a.BrowserVersion
+	}
+	switch {
+	case browser != "" && a.OS != "":
+		return browser + " em " + a.OS
+	case browser != "" || a.OS != "":
+		return browser + a.OS
+	}
+	return "dispositivo desconhecido"
+}
+
+// browsers are matched in order: Chromium-based browsers also send "Chrome/" and almost all send "Safari/",
+// so the more specific tokens come first. Safari's own version is in "Version/".
+var browsers = []struct{ token, name string }{
+	{"Edg/", "Edge"},
+	{"EdgA/", "Edge"},
+	{"EdgiOS/", "Edge"},
+	{"OPR/", "Opera"},
+	{"OPiOS/", "Opera"},
+	{"SamsungBrowser/", "Samsung Internet"},
+	{"YaBrowser/", "Yandex Browser"},
+	{"Vivaldi/", "Vivaldi"},
+	{"Firefox/", "Firefox"},
+	{"FxiOS/", "Firefox"},
+	{"CriOS/", "Chrome"},
+	{"Chrome/", "Chrome"},
+	{"Version/", "Safari"},
+}
+
+// tools are non-browser clients, reported as bots.
+var tools = []struct{ token, name string }{
+	{"curl/", "curl"},
+	{"Wget/", "Wget"},
+	{"Go-http-client/", "Go"},
+	{"python-requests/", "Python Requests"},
+	{"PostmanRuntime/", "Postman"},
+	{"okhttp/", "OkHttp"},
+}
+
+// Parse reads header, as sent in User-Agent. An empty or unrecognised header gives empty names and
+// DeviceDesktop.
+func Parse(header string) Agent {
+	agent := Agent{OS: parseOS(header), Device: DeviceDesktop}
+
+	for _, tool := range tools {
+		if version, ok := versionAfter(header, tool.token); ok {
+			agent.Browser, agent.BrowserVersion, agent.Device = tool.name, version, DeviceBot
+			return agent
+		}
+	}
+	lower := strings.ToLower(header)
+	if strings.Contains(lower, "bot") || strings.Contains(lower, "crawler") || strings.Contains(lower, "spider") {
+		agent.Browser, agent.Device = botName(header), DeviceBot
+		return agent
+	}
+
+	for _, browser := range browsers {
+		if version, ok := versionAfter(header, browser.token); ok {
+			if browser.name == "Safari" && !strings.Contains(header, "Safari/") {
+				continue
+			}
+			agent.Browser, agent.BrowserVersion = browser.name, version
+			break
+		}
+	}
+
+	switch {
+	case strings.Contains(header, "iPad") || strings.Contains(header, "Tablet") ||
+		(strings.Contains(header, "Android") && !strings.Contains(header, "Mobile")):
+		agent.Device = DeviceTablet
+	case strings.Contains(header, "Mobile") || strings.Contains(header, "iPhone"):
+		agent.Device = DeviceMobile
+	}
+	return agent
+}
+
+// parseOS names the operating system in header ("" when there is none).
+func parseOS(header string) string {
+	switch {
+	case strings.Contains(header, "Windows"):
+		switch fullVersionAfter(header, "Windows NT ") {
+		case "6.3":
+			return "Windows 8.1"
+		case "6.2":
+			return "Windows 8"
+		case "6.1":
+			return "Windows 7"
+		default:
+			// Windows 10 and 11 both send NT 10.0
+			return "Windows"
+		}
+	case strings.Contains(header, "iPhone") || strings.Contains(header, "iPod"):
+		return withVersion("iOS", iOSVersion(header))
+	case strings.Contains(header, "iPad"):
+		return withVersion("iPadOS", iOSVersion(header))
+	case strings.Contains(header, "Android"):
+		return withVersion("Android", fullVersionAfter(header, "Android "))
+	case strings.Contains(header, "CrOS"):
+		return "ChromeOS"
+	case strings.Contains(header, "Mac OS X") || strings.Contains(header, "Macintosh"):
+		// Browsers froze the reported version at 10.15, so it says nothing about the real one
+		return "macOS"
+	case strings.Contains(header, "Linux"):
+		return "Linux"
+	}
+	return ""
+}
+
+// iOSVersion reads "OS 17_4 like Mac OS X" as "17.4".
+func iOSVersion(header string) string {
+	i := strings.Index(header, " OS ")
+	if i < 0 {
+		return ""
+	}
+	rest := header[i+len(" OS "):]
+	end := strings.IndexFunc(rest, func(r rune) bool { return (r < '0' || r > '9') && r != '_' })
+	if end >= 0 {
+		rest = rest[:end]
+	}
+	return strings.ReplaceAll(strings.TrimRight(rest, "_"), "_", ".")
+}
+
+func withVersion(name, version string) string {
+	if version == "" {
+		return name
+	}
+	return name + " " + version
+}
+
+// versionAfter returns the major version following token in header, and whether token is there.
+func versionAfter(header, token string) (string, bool) {
+	if !strings.Contains(header, token) {
+		return "", false
+	}
+	version, _, _ := strings.Cut(fullVersionAfter(header, token), ".")
+	return version, true
+}
+
+// fullVersionAfter returns the dotted version number following token in header ("" when none).
+func fullVersionAfter(header, token string) string {
+	i := strings.Index(header, token)
+	if i < 0 {
+		return ""
+	}
+	rest := header[i+len(token):]
+	end := strings.IndexFunc(rest, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if end >= 0 {
+		rest = rest[:end]
+	}
+	return strings.TrimRight(rest, ".")
+}
+
+// botName picks the crawler's name: the product token that mentions bot, crawler or spider
+// ("Googlebot/2.1" gives "Googlebot").
+func botName(header string) string {
+	for field := range strings.FieldsFuncSeq(header, func(r rune) bool { return r == ' ' || r == ';' || r == '(' || r == ')' || r == '+' }) {
+		lower := strings.ToLower(field)
+		if strings.Contains(lower, "bot") || strings.Contains(lower, "crawler") || strings.Contains(lower, "spider") {
+			name, _, _ := strings.Cut(field, "/")
+			return name
+		}
+	}
+	return ""
+}
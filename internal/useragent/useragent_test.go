@@ -0,0 +1,92 @@
+package useragent
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   Agent
+	}{
+		{
+			"Chrome on Windows",
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.6478.127 Safari/537.36",
+			Agent{Browser: "Chrome", BrowserVersion: "126", OS: "Windows", Device: DeviceDesktop},
+		},
+		{
+			"Edge on Windows",
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36 Edg/126.0.2592.87",
+			Agent{Browser: "Edge", BrowserVersion: "126", OS: "Windows", Device: DeviceDesktop},
+		},
+		{
+			"Safari on macOS",
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Safari/605.1.15",
+			Agent{Browser: "Safari", BrowserVersion: "17", OS: "macOS", Device: DeviceDesktop},
+		},
+		{
+			"Firefox on Linux",
+			"Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:127.0) Gecko/20100101 Firefox/127.0",
+			Agent{Browser: "Firefox", BrowserVersion: "127", OS: "Linux", Device: DeviceDesktop},
+		},
+		{
+			"Safari on iPhone",
+			"Mozilla/5.0 (iPhone; CPU iPhone OS 17_4_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4.1 Mobile/15E148 Safari/604.1",
+			Agent{Browser: "Safari", BrowserVersion: "17", OS: "iOS 17.4.1", Device: DeviceMobile},
+		},
+		{
+			"Chrome on iPad",
+			"Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/126.0.6478.54 Mobile/15E148 Safari/604.1",
+			Agent{Browser: "Chrome", BrowserVersion: "126", OS: "iPadOS 16.6", Device: DeviceTablet},
+		},
+		{
+			"Samsung Internet on Android",
+			"Mozilla/5.0 (Linux; Android 14; SM-S918B) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/25.0 Chrome/121.0.0.0 Mobile Safari/537.36",
+			Agent{Browser: "Samsung Internet", BrowserVersion: "25", OS: "Android 14", Device: DeviceMobile},
+		},
+		{
+			"Chrome on an Android tablet",
+			"Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36",
+			Agent{Browser: "Chrome", BrowserVersion: "126", OS: "Android 13", Device: DeviceTablet},
+		},
+		{
+			"Googlebot",
+			"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			Agent{Browser: "Googlebot", Device: DeviceBot},
+		},
+		{
+			"curl",
+			"curl/8.7.1",
+			Agent{Browser: "curl", BrowserVersion: "8", Device: DeviceBot},
+		},
+		{
+			"Empty",
+			"",
+			Agent{Device: DeviceDesktop},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.header); got != tt.want {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAgent_Describe(t *testing.T) {
+	tests := []struct {
+		agent Agent
+		want  string
+	}{
+		{Agent{Browser: "Chrome", BrowserVersion: "126", OS: "Android 14"}, "Chrome 126 em Android 14"},
+		{Agent{Browser: "Firefox", OS: "Linux"}, "Firefox em Linux"},
+		{Agent{Browser: "curl", BrowserVersion: "8"}, "curl 8"},
+		{Agent{OS: "Windows"}, "Windows"},
+		{Agent{}, "dispositivo desconhecido"},
+	}
+	for _, tt := range tests {
+		if got := tt.agent.Describe(); got != tt.want {
+			t.Errorf("Describe(%+v) = %q, want %q", tt.agent, got, tt.want)
+		}
+	}
+}
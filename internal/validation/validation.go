@@ -5,8 +5,10 @@ package validation
 import (
 	"errors"
 	"fmt"
+	"net/url"
 	"regexp"
 	"strings"
+	"sync/atomic"
 	"unicode"
 )
 
@@ -24,10 +26,21 @@ var (
 	ErrPasswordNoSpecial    = errors.New("senha deve conter pelo menos um caractere especial")
 	ErrPasswordCommonWord   = errors.New("senha não pode ser uma palavra comum ou fácil de adivinhar")
 	ErrPasswordContainsUser = errors.New("senha não pode conter o nome de usuário")
+	// ErrPasswordContainsEmail means the password is the email's local part (the part before the @)
+	ErrPasswordContainsEmail = errors.New("senha não pode ser igual ao seu email")
+	// ErrPasswordContainsName means the password is the display name
+	ErrPasswordContainsName = errors.New("senha não pode ser igual ao seu nome")
 	ErrRefreshTokenInvalid  = errors.New("token de atualização inválido")
 	ErrResetTokenInvalid    = errors.New("token de redefinição de senha inválido")
 	ErrDisplayNameInvalid   = errors.New("nome de exibição inválido")
 	ErrDisplayNameTooLong   = errors.New("nome de exibição não pode ter mais de 100 caracteres")
+	ErrRedirectNotLocal     = errors.New("destino de redirecionamento deve ser um caminho local")
+	ErrPhoneInvalid         = errors.New("telefone inválido: use o formato internacional, como +5511987654321")
+
+	// ErrEmailDomainNotAllowed means the email's domain is not in registration.allowed_email_domains
+	ErrEmailDomainNotAllowed = errors.New("cadastro permitido apenas com email dos domínios autorizados")
+	// ErrTermsNotAccepted means registration requires accepting the terms of use (config terms.version)
+	ErrTermsNotAccepted = errors.New("aceite os termos de uso para criar a conta")
 )
 
 // Validation limits (avoid magic numbers for mnd)
@@ -90,49 +103,133 @@ func ValidateEmail(email string) error {
 	return nil
 }
 
-// ValidatePassword ensures the password meets complexity requirements
-func ValidatePassword(password, username string) error {
-	if len(password) < minPasswordLen {
-		return ErrPasswordTooShort
-	}
-	if err := validatePasswordChars(password); err != nil {
-		return err
+// ValidateEmailDomain returns ErrEmailDomainNotAllowed unless email's domain is one of allowed, compared
+// without case. An empty allowed list accepts every domain. The email format is checked by ValidateEmail.
+func ValidateEmailDomain(email string, allowed []string) error {
+	if len(allowed) == 0 {
+		return nil
 	}
-	if isCommonPassword(password) {
-		return ErrPasswordCommonWord
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ErrEmailDomainNotAllowed
 	}
-	if username != "" && len(username) >= minUsernameLen &&
-		strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
-		return ErrPasswordContainsUser
+	domain := email[at+1:]
+	for _, d := range allowed {
+		if strings.EqualFold(domain, strings.TrimPrefix(strings.TrimSpace(d), "@")) {
+			return nil
+		}
 	}
+	return ErrEmailDomainNotAllowed
+}
 
-	return nil
+// personalInfoAllowed is set at startup from config password.allow_personal_info.
+var personalInfoAllowed atomic.Bool
+
+// AllowPersonalInfo turns off (or back on) the NotContainsEmail and NotContainsName requirements.
+func AllowPersonalInfo(allowed bool) {
+	personalInfoAllowed.Store(allowed)
 }
 
-func validatePasswordChars(password string) error {
-	var hasUpper, hasLower, hasNumber, hasSpecial bool
-	for _, char := range password {
-		hasUpper = hasUpper || unicode.IsUpper(char)
-		hasLower = hasLower || unicode.IsLower(char)
-		hasNumber = hasNumber || unicode.IsNumber(char)
-		hasSpecial = hasSpecial || (unicode.IsPunct(char) || unicode.IsSymbol(char))
-	}
-	if !hasUpper {
-		return ErrPasswordNoUppercase
+// ValidatePassword ensures the password meets complexity requirements, returning the first unmet one.
+// email and displayName belong to the password's owner; "" skips their requirement.
+func ValidatePassword(password, username, email, displayName string) error {
+	return ValidatePasswordDetailed(password, username, email, displayName).Err()
+}
+
+// PasswordRequirements tells which password requirements a password meets, one field per rule, so
+// forms and API clients can show the whole checklist instead of only the first failure. The first five
+// match the live checklist of the registration form.
+type PasswordRequirements struct {
+	Length              bool `json:"length"`                // at least 8 characters
+	Upper               bool `json:"upper"`                 // an uppercase letter
+	Lower               bool `json:"lower"`                 // a lowercase letter
+	Number              bool `json:"number"`                // a digit
+	Special             bool `json:"special"`               // a punctuation mark or symbol
+	NotCommon           bool `json:"not_common"`            // no common or easy to guess password in it
+	NotContainsUsername bool `json:"not_contains_username"` // doesn't contain the username
+	NotContainsEmail    bool `json:"not_contains_email"`    // isn't the email's local part
+	NotContainsName     bool `json:"not_contains_name"`     // isn't the display name
+}
+
+// ValidatePasswordDetailed checks every password requirement, without stopping at the first unmet one.
+// An empty username, or one too short to be a valid username, always meets NotContainsUsername. The
+// email's local part and the display name are compared ignoring case; an empty one, or
+// AllowPersonalInfo(true), meets its requirement.
+func ValidatePasswordDetailed(password, username, email, displayName string) PasswordRequirements {
+	local, _, _ := strings.Cut(email, "@")
+	req := PasswordRequirements{
+		Length:              len(password) >= minPasswordLen,
+		NotCommon:           !isCommonPassword(password),
+		NotContainsUsername: len(username) < minUsernameLen || !strings.Contains(strings.ToLower(password), strings.ToLower(username)),
+		NotContainsEmail:    !isPersonalInfo(password, local),
+		NotContainsName:     !isPersonalInfo(password, displayName),
 	}
-	if !hasLower {
-		return ErrPasswordNoLowercase
+	for _, char := range password {
+		req.Upper = req.Upper || unicode.IsUpper(char)
+		req.Lower = req.Lower || unicode.IsLower(char)
+		req.Number = req.Number || unicode.IsNumber(char)
+		req.Special = req.Special || (unicode.IsPunct(char) || unicode.IsSymbol(char))
 	}
-	if !hasNumber {
-		return ErrPasswordNoNumber
+	return req
+}
+
+// Unmet returns the error of each unmet requirement, in the order ValidatePassword checks them.
+func (r PasswordRequirements) Unmet() []error {
+	var errs []error
+	for _, rule := range []struct {
+		met bool
+		err error
+	}{
+		{r.Length, ErrPasswordTooShort},
+		{r.Upper, ErrPasswordNoUppercase},
+		{r.Lower, ErrPasswordNoLowercase},
+		{r.Number, ErrPasswordNoNumber},
+		{r.Special, ErrPasswordNoSpecial},
+		{r.NotCommon, ErrPasswordCommonWord},
+		{r.NotContainsUsername, ErrPasswordContainsUser},
+		{r.NotContainsEmail, ErrPasswordContainsEmail},
+		{r.NotContainsName, ErrPasswordContainsName},
+	} {
+		if !rule.met {
+			errs = append(errs, rule.err)
+		}
 	}
-	if !hasSpecial {
-		return ErrPasswordNoSpecial
+	return errs
+}
+
+// Met reports whether the password meets every requirement.
+func (r PasswordRequirements) Met() bool {
+	return len(r.Unmet()) == 0
+}
+
+// Err returns the first unmet requirement's error, or nil when all are met.
+func (r PasswordRequirements) Err() error {
+	if unmet := r.Unmet(); len(unmet) > 0 {
+		return unmet[0]
 	}
+	return nil
+}
 
+// PersonalInfoErr returns the error of the first unmet personal info requirement (username, email, display
+// name), or nil, for a password whose other requirements were already checked without knowing its owner.
+func (r PasswordRequirements) PersonalInfoErr() error {
+	switch {
+	case !r.NotContainsUsername:
+		return ErrPasswordContainsUser
+	case !r.NotContainsEmail:
+		return ErrPasswordContainsEmail
+	case !r.NotContainsName:
+		return ErrPasswordContainsName
+	}
 	return nil
 }
 
+// isPersonalInfo reports whether password is info (the email's local part or the display name), ignoring case.
+func isPersonalInfo(password, info string) bool {
+	info = strings.TrimSpace(info)
+	return info != "" && !personalInfoAllowed.Load() && strings.EqualFold(password, info)
+}
+
 func isCommonPassword(password string) bool {
 	lower := strings.ToLower(password)
 	for commonPass := range commonPasswords {
@@ -157,6 +254,38 @@ func ValidateDisplayName(name string) error {
 	return nil
 }
 
+// phoneRegex is the E.164 format: "+", a country code not starting with 0 and at most 15 digits in all
+var phoneRegex = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// NormalizePhone strips the punctuation people type in phone numbers (spaces, dots, dashes and
+// parentheses) and turns the "00" international prefix into "+", so "+55 (11) 98765-4321" becomes
+// "+5511987654321". The result still has to pass ValidatePhone.
+func NormalizePhone(phone string) string {
+	phone = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '.', '-', '(', ')':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(phone))
+	if rest, ok := strings.CutPrefix(phone, "00"); ok {
+		phone = "+" + rest
+	}
+	return phone
+}
+
+// ValidatePhone accepts an empty phone (it is optional) or an E.164 number; normalize input with
+// NormalizePhone first.
+func ValidatePhone(phone string) error {
+	if phone == "" {
+		return nil
+	}
+	if !phoneRegex.MatchString(phone) {
+		return ErrPhoneInvalid
+	}
+	return nil
+}
+
 // ValidateRefreshToken performs basic validation on refresh tokens
 func ValidateRefreshToken(token string) error {
 	if token == "" || len(token) < 10 {
@@ -200,7 +329,7 @@ func ValidateRegistrationRequest(username, email, password, displayName string)
 		return err
 	}
 
-	if err := ValidatePassword(password, username); err != nil {
+	if err := ValidatePassword(password, username, email, displayName); err != nil {
 		return err
 	}
 
@@ -211,6 +340,60 @@ func ValidateRegistrationRequest(username, email, password, displayName string)
 	return nil
 }
 
+// Registration form field names (match the form inputs and the "<field>-error" slots).
+const (
+	FieldUsername    = "username"
+	FieldEmail       = "email"
+	FieldPassword    = "password"
+	FieldDisplayName = "display_name"
+)
+
+// FieldPhone is the optional phone input of the profile and admin new-user forms.
+const FieldPhone = "phone"
+
+// FieldTerms is the "I accept the terms of use" checkbox of the registration form, shown when config terms.version is set.
+const FieldTerms = "terms"
+
+// RegistrationFields lists the registration form fields in display order.
+var RegistrationFields = []string{FieldUsername, FieldEmail, FieldDisplayName, FieldPassword}
+
+// FieldErrors maps a form field name to its validation message.
+type FieldErrors map[string]string
+
+// HasErrors reports whether any field failed validation.
+func (fe FieldErrors) HasErrors() bool {
+	return len(fe) > 0
+}
+
+// First returns the message of the first failing field following the given order.
+func (fe FieldErrors) First(order []string) string {
+	for _, field := range order {
+		if msg, ok := fe[field]; ok {
+			return msg
+		}
+	}
+	return ""
+}
+
+// ValidateRegistrationFields validates every registration field and returns all failures keyed by field.
+// Unlike ValidateRegistrationRequest, it does not stop at the first error so forms can highlight each field.
+func ValidateRegistrationFields(username, email, password, displayName string) FieldErrors {
+	errs := FieldErrors{}
+	if err := ValidateUsername(username); err != nil {
+		errs[FieldUsername] = err.Error()
+	}
+	if err := ValidateEmail(email); err != nil {
+		errs[FieldEmail] = err.Error()
+	}
+	if err := ValidatePassword(password, username, email, displayName); err != nil {
+		errs[FieldPassword] = err.Error()
+	}
+	if err := ValidateDisplayName(displayName); err != nil {
+		errs[FieldDisplayName] = err.Error()
+	}
+	return errs
+}
+
 // ValidatePasswordReset validates a password reset request
 func ValidatePasswordReset(token, newPassword, confirmPassword string) error {
 	if err := ValidateResetToken(token); err != nil {
@@ -221,10 +404,51 @@ func ValidatePasswordReset(token, newPassword, confirmPassword string) error {
 		return errors.New("as senhas não coincidem")
 	}
 
-	// For password reset, we don't have username, so use an empty string
-	if err := ValidatePassword(newPassword, ""); err != nil {
+	// The user is only known from the token; AuthService.ResetPassword checks their personal info
+	if err := ValidatePassword(newPassword, "", "", ""); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// ValidatePasswordChange validates a change password request (new password must match confirmation and be strong)
+func ValidatePasswordChange(newPassword, confirmPassword, username, email, displayName string) error {
+	if newPassword != confirmPassword {
+		return errors.New("as senhas não coincidem")
+	}
+
+	return ValidatePassword(newPassword, username, email, displayName)
+}
+
+// ValidateRedirectPath ensures a user-supplied redirect target (e.g. ?next=) is a local path.
+// Rejects absolute URLs, protocol-relative URLs ("//host"), backslash tricks and control characters
+// so the value can't be used as an open redirect.
+func ValidateRedirectPath(target string) error {
+	if target == "" || !strings.HasPrefix(target, "/") {
+		return ErrRedirectNotLocal
+	}
+	// "//host" and "/\host" are treated as protocol-relative by browsers
+	if strings.HasPrefix(target, "//") || strings.Contains(target, "\\") {
+		return ErrRedirectNotLocal
+	}
+	for _, r := range target {
+		if unicode.IsControl(r) {
+			return ErrRedirectNotLocal
+		}
+	}
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.User != nil {
+		return ErrRedirectNotLocal
+	}
+
+	return nil
+}
+
+// SafeRedirectPath returns target when it is a valid local path, otherwise fallback.
+func SafeRedirectPath(target, fallback string) string {
+	if ValidateRedirectPath(target) != nil {
+		return fallback
+	}
+	return target
+}
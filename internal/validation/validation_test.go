@@ -59,6 +59,67 @@ func TestValidateEmail(t *testing.T) {
 	}
 }
 
+func TestValidatePhone(t *testing.T) {
+	tests := []struct {
+		name    string
+		phone   string
+		want    string // normalized
+		wantErr error
+	}{
+		{"Empty is allowed", "", "", nil},
+		{"E.164", "+5511987654321", "+5511987654321", nil},
+		{"Formatted Brazilian number", "+55 (11) 98765-4321", "+5511987654321", nil},
+		{"US number with dots", "+1.415.555.2671", "+14155552671", nil},
+		{"00 international prefix", "0044 20 7946 0958", "+442079460958", nil},
+		{"Shortest allowed", "+6831234", "+6831234", nil},
+		{"Missing country code", "11987654321", "11987654321", ErrPhoneInvalid},
+		{"Country code starting with 0", "+0511987654321", "+0511987654321", ErrPhoneInvalid},
+		{"Too long", "+1234567890123456", "+1234567890123456", ErrPhoneInvalid},
+		{"Too short", "+12345", "+12345", ErrPhoneInvalid},
+		{"Letters", "+55 11 CALL-NOW", "+5511CALLNOW", ErrPhoneInvalid},
+		{"Extension", "+14155552671 x12", "+14155552671x12", ErrPhoneInvalid},
+		{"Garbage", "not a phone", "notaphone", ErrPhoneInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			phone := NormalizePhone(tt.phone)
+			if phone != tt.want {
+				t.Errorf("NormalizePhone() = %q, want %q", phone, tt.want)
+			}
+			if err := ValidatePhone(phone); err != tt.wantErr {
+				t.Errorf("ValidatePhone() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateEmailDomain(t *testing.T) {
+	allowed := []string{"corp.example", " @Partner.example "}
+	tests := []struct {
+		name    string
+		email   string
+		allowed []string
+		wantErr error
+	}{
+		{"No restriction", "someone@gmail.com", nil, nil},
+		{"Allowed domain", "ana@corp.example", allowed, nil},
+		{"Case-insensitive", "ana@CORP.Example", allowed, nil},
+		{"Entry with @ and spaces", "bob@partner.example", allowed, nil},
+		{"Disallowed domain", "ana@gmail.com", allowed, ErrEmailDomainNotAllowed},
+		{"Subdomain is another domain", "ana@mail.corp.example", allowed, ErrEmailDomainNotAllowed},
+		{"Lookalike suffix", "ana@evilcorp.example", allowed, ErrEmailDomainNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateEmailDomain(tt.email, tt.allowed); err != tt.wantErr {
+				t.Errorf("ValidateEmailDomain() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestValidatePassword(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -79,7 +140,7 @@ func TestValidatePassword(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := ValidatePassword(tt.password, tt.username)
+			err := ValidatePassword(tt.password, tt.username, "", "")
 			if err != tt.wantErr {
 				t.Errorf("ValidatePassword() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -87,6 +148,101 @@ func TestValidatePassword(t *testing.T) {
 	}
 }
 
+func TestValidatePasswordDetailed(t *testing.T) {
+	all := PasswordRequirements{Length: true, Upper: true, Lower: true, Number: true, Special: true, NotCommon: true, NotContainsUsername: true, NotContainsEmail: true, NotContainsName: true}
+	tests := []struct {
+		name     string
+		password string
+		username string
+		want     PasswordRequirements
+	}{
+		{"Every requirement met", "C0mpl3x!P@ssw0rd", "alice", all},
+		{"Empty password", "", "", PasswordRequirements{NotCommon: true, NotContainsUsername: true, NotContainsEmail: true, NotContainsName: true}},
+		{"Only lowercase letters", "abcdefgh", "", PasswordRequirements{Length: true, Lower: true, NotCommon: true, NotContainsUsername: true, NotContainsEmail: true, NotContainsName: true}},
+		{"Short, no special", "Ab1", "", PasswordRequirements{Upper: true, Lower: true, Number: true, NotCommon: true, NotContainsUsername: true, NotContainsEmail: true, NotContainsName: true}},
+		{"Common and with the username", "Password123!maria", "Maria",
+			PasswordRequirements{Length: true, Upper: true, Lower: true, Number: true, Special: true, NotContainsEmail: true, NotContainsName: true}},
+		{"Username too short to count", "Test1234!ab", "ab", all},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ValidatePasswordDetailed(tt.password, tt.username, "", "")
+			if got != tt.want {
+				t.Errorf("ValidatePasswordDetailed() = %+v, want %+v", got, tt.want)
+			}
+			if got.Met() != (tt.want == all) {
+				t.Errorf("Met() = %v", got.Met())
+			}
+			if err := ValidatePassword(tt.password, tt.username, "", ""); err != got.Err() {
+				t.Errorf("ValidatePassword() = %v, but first unmet is %v", err, got.Err())
+			}
+		})
+	}
+
+	unmet := ValidatePasswordDetailed("admin", "", "", "").Unmet()
+	want := []error{ErrPasswordTooShort, ErrPasswordNoUppercase, ErrPasswordNoNumber, ErrPasswordNoSpecial, ErrPasswordCommonWord}
+	if len(unmet) != len(want) {
+		t.Fatalf("Unmet() = %v, want %v", unmet, want)
+	}
+	for i := range want {
+		if unmet[i] != want[i] {
+			t.Errorf("Unmet()[%d] = %v, want %v", i, unmet[i], want[i])
+		}
+	}
+}
+
+func TestValidatePassword_PersonalInfo(t *testing.T) {
+	tests := []struct {
+		name        string
+		password    string
+		email       string
+		displayName string
+		wantErr     error
+	}{
+		{"Email local part", "Maria.Silva1!", "maria.silva1!@example.com", "Ana", ErrPasswordContainsEmail},
+		{"Display name", "João da Silva 1!", "joao@example.com", "  joão da silva 1!  ", ErrPasswordContainsName},
+		{"Only part of the email", "Maria.Silva1!x", "maria.silva1!@example.com", "Ana", nil},
+		{"No email or name given", "Maria.Silva1!", "", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidatePassword(tt.password, "", tt.email, tt.displayName); err != tt.wantErr {
+				t.Errorf("ValidatePassword() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("Registration", func(t *testing.T) {
+		if err := ValidateRegistrationRequest("maria", "silva-2024@example.com", "Silva-2024", "Maria"); err != ErrPasswordContainsEmail {
+			t.Errorf("ValidateRegistrationRequest() error = %v, want %v", err, ErrPasswordContainsEmail)
+		}
+		fields := ValidateRegistrationFields("msilva", "maria@example.com", "Maria Silva 1!", "maria silva 1!")
+		if fields[FieldPassword] != ErrPasswordContainsName.Error() {
+			t.Errorf("ValidateRegistrationFields() password = %q, want %q", fields[FieldPassword], ErrPasswordContainsName)
+		}
+	})
+
+	t.Run("Only the personal info requirements", func(t *testing.T) {
+		// Short, but PersonalInfoErr leaves length and character classes to ValidatePassword
+		if err := ValidatePasswordDetailed("maria1", "", "", "Maria1").PersonalInfoErr(); err != ErrPasswordContainsName {
+			t.Errorf("PersonalInfoErr() = %v, want %v", err, ErrPasswordContainsName)
+		}
+		if err := ValidatePasswordDetailed("abc", "", "", "Maria").PersonalInfoErr(); err != nil {
+			t.Errorf("PersonalInfoErr() = %v, want nil", err)
+		}
+	})
+
+	t.Run("Allowed by config", func(t *testing.T) {
+		AllowPersonalInfo(true)
+		defer AllowPersonalInfo(false)
+		if err := ValidatePassword("Maria.Silva1!", "", "maria.silva1!@example.com", "Maria.Silva1!"); err != nil {
+			t.Errorf("ValidatePassword() error = %v, want nil", err)
+		}
+	})
+}
+
 func TestValidateLoginRequest(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -260,3 +416,146 @@ func TestValidatePasswordReset(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateRegistrationFields(t *testing.T) {
+	tests := []struct {
+		name        string
+		username    string
+		email       string
+		password    string
+		displayName string
+		want        FieldErrors
+	}{
+		{
+			name:        "All valid",
+			username:    "john_doe",
+			email:       "john@example.com",
+			password:    "Secure123!",
+			displayName: "John Doe",
+			want:        FieldErrors{},
+		},
+		{
+			name:        "Username rule maps to username",
+			username:    "jo",
+			email:       "john@example.com",
+			password:    "Secure123!",
+			displayName: "John Doe",
+			want:        FieldErrors{FieldUsername: ErrUsernameTooShort.Error()},
+		},
+		{
+			name:        "Email rule maps to email",
+			username:    "john_doe",
+			email:       "not-an-email",
+			password:    "Secure123!",
+			displayName: "John Doe",
+			want:        FieldErrors{FieldEmail: ErrEmailInvalid.Error()},
+		},
+		{
+			name:        "Password rule maps to password",
+			username:    "john_doe",
+			email:       "john@example.com",
+			password:    "secure123!",
+			displayName: "John Doe",
+			want:        FieldErrors{FieldPassword: ErrPasswordNoUppercase.Error()},
+		},
+		{
+			name:        "Password containing username maps to password",
+			username:    "john_doe",
+			email:       "john@example.com",
+			password:    "John_doe123!",
+			displayName: "John Doe",
+			want:        FieldErrors{FieldPassword: ErrPasswordContainsUser.Error()},
+		},
+		{
+			name:        "Display name rule maps to display_name",
+			username:    "john_doe",
+			email:       "john@example.com",
+			password:    "Secure123!",
+			displayName: "",
+			want:        FieldErrors{FieldDisplayName: ErrDisplayNameInvalid.Error()},
+		},
+		{
+			name:        "Every field invalid",
+			username:    "",
+			email:       "",
+			password:    "short",
+			displayName: strings.Repeat("a", 101),
+			want: FieldErrors{
+				FieldUsername:    ErrUsernameInvalid.Error(),
+				FieldEmail:       ErrEmailInvalid.Error(),
+				FieldPassword:    ErrPasswordTooShort.Error(),
+				FieldDisplayName: ErrDisplayNameTooLong.Error(),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ValidateRegistrationFields(tt.username, tt.email, tt.password, tt.displayName)
+			if len(got) != len(tt.want) {
+				t.Fatalf("ValidateRegistrationFields() = %v, want %v", got, tt.want)
+			}
+			for field, msg := range tt.want {
+				if got[field] != msg {
+					t.Errorf("field %q = %q, want %q", field, got[field], msg)
+				}
+			}
+			if got.HasErrors() != (len(tt.want) > 0) {
+				t.Errorf("HasErrors() = %v, want %v", got.HasErrors(), len(tt.want) > 0)
+			}
+		})
+	}
+}
+
+func TestFieldErrorsFirst(t *testing.T) {
+	errs := FieldErrors{
+		FieldPassword: "password message",
+		FieldEmail:    "email message",
+	}
+	if got := errs.First(RegistrationFields); got != "email message" {
+		t.Errorf("First() = %q, want %q", got, "email message")
+	}
+	if got := (FieldErrors{}).First(RegistrationFields); got != "" {
+		t.Errorf("First() on empty = %q, want empty", got)
+	}
+}
+
+func TestValidateRedirectPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		wantErr bool
+	}{
+		{"Root", "/", false},
+		{"Admin page", "/admin/users", false},
+		{"With query", "/admin/users?page=2&q=ana", false},
+		{"With fragment", "/profile#sessions", false},
+		{"Empty", "", true},
+		{"Relative path", "admin", true},
+		{"Absolute http URL", "http://evil.example.com/", true},
+		{"Absolute https URL", "https://evil.example.com/admin", true},
+		{"Protocol-relative", "//evil.example.com", true},
+		{"Backslash protocol-relative", "/\\evil.example.com", true},
+		{"Javascript scheme", "javascript:alert(1)", true},
+		{"Control character", "/admin\n", true},
+		{"Tab smuggling", "/\t/evil.example.com", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRedirectPath(tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRedirectPath(%q) error = %v, wantErr %v", tt.target, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSafeRedirectPath(t *testing.T) {
+	if got := SafeRedirectPath("/admin", "/"); got != "/admin" {
+		t.Errorf("SafeRedirectPath() = %q, want /admin", got)
+	}
+	if got := SafeRedirectPath("//evil.example.com", "/"); got != "/" {
+		t.Errorf("SafeRedirectPath() = %q, want fallback /", got)
+	}
+}
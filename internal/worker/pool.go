@@ -0,0 +1,181 @@
+// Package worker runs background tasks on a bounded set of goroutines, so features that do work off
+// the request path (e.g. the email queue) share one implementation of queueing, draining on shutdown
+// and panic recovery.
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+)
+
+// DefaultQueueSize is the buffer used by NewPool when queueSize <= 0.
+const DefaultQueueSize = 100
+
+// Reasons Submit refuses a task, and the error a panicking task reports to Hooks.OnDone.
+var (
+	ErrQueueFull = errors.New("fila de tarefas cheia")
+	ErrClosed    = errors.New("pool de tarefas encerrado")
+	ErrPanic     = errors.New("pânico na tarefa")
+)
+
+// Task is a unit of background work. ctx is cancelled when Shutdown stops waiting, so long tasks should watch it.
+type Task func(ctx context.Context) error
+
+// Hooks observe the pool, e.g. for metrics. Each one is optional; they run on the worker goroutine, so
+// they must not block.
+type Hooks struct {
+	// OnDone is called after every task with how long it ran and its error (wrapping ErrPanic after a panic)
+	OnDone func(name string, elapsed time.Duration, err error)
+	// OnDrop is called for every task that never runs: refused by Submit or still queued when Shutdown gave up
+	OnDrop func(name string)
+}
+
+type job struct {
+	name string
+	fn   Task
+}
+
+// Pool runs submitted tasks on a fixed number of workers. Tasks wait in a bounded queue; a panic in one
+// task is recovered and logged without taking down the worker or the process.
+type Pool struct {
+	// Hooks must be set before the first Submit
+	Hooks Hooks
+
+	name     string
+	tasks    chan job
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+	inFlight atomic.Int64
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewPool starts workers goroutines (at least one) that run tasks queued with Submit, buffering up to
+// queueSize tasks (DefaultQueueSize when <= 0). name only labels the logs.
+func NewPool(name string, workers, queueSize int) *Pool {
+	if workers <= 0 {
+		workers = 1
+	}
+	if queueSize <= 0 {
+		queueSize = DefaultQueueSize
+	}
+	p := &Pool{
+		name:  name,
+		tasks: make(chan job, queueSize),
+		done:  make(chan struct{}),
+	}
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.work()
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(p.done)
+	}()
+	return p
+}
+
+// Submit queues fn without blocking. It returns ErrQueueFull when every worker is busy and the queue is
+// full, or ErrClosed after Shutdown; the task is dropped in both cases. name only labels logs and hooks.
+func (p *Pool) Submit(name string, fn Task) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		p.drop(name)
+		return ErrClosed
+	}
+	select {
+	case p.tasks <- job{name: name, fn: fn}:
+		return nil
+	default:
+		p.drop(name)
+		return ErrQueueFull
+	}
+}
+
+// InFlight returns how many tasks are running right now.
+func (p *Pool) InFlight() int {
+	return int(p.inFlight.Load())
+}
+
+// Pending returns how many tasks are queued and not started yet.
+func (p *Pool) Pending() int {
+	return len(p.tasks)
+}
+
+// Shutdown stops accepting tasks and waits for the queued and running ones to finish. When ctx ends
+// first, it cancels the tasks' context, drops the tasks that haven't started and returns ctx.Err()
+// without waiting for running tasks any longer. Calling it again waits the same way.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.tasks)
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-p.done:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		logger.Warn("Pool de tarefas encerrado antes de terminar", "pool", p.name, "running", p.InFlight(), "pending", p.Pending())
+		return ctx.Err()
+	}
+}
+
+func (p *Pool) work() {
+	for j := range p.tasks {
+		if p.ctx.Err() != nil {
+			// Shutdown gave up: drain without running
+			p.drop(j.name)
+			continue
+		}
+		p.run(j)
+	}
+}
+
+func (p *Pool) run(j job) {
+	p.inFlight.Add(1)
+	defer p.inFlight.Add(-1)
+
+	start := time.Now()
+	err := p.call(j)
+	if p.Hooks.OnDone != nil {
+		p.Hooks.OnDone(j.name, time.Since(start), err)
+	}
+}
+
+// call runs the task, turning a panic into an ErrPanic error.
+func (p *Pool) call(j job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("Pânico em tarefa em segundo plano", "pool", p.name, "task", j.name, "panic", r, "stack", string(debug.Stack()))
+			err = fmt.Errorf("%w: %v", ErrPanic, r)
+		}
+	}()
+	return j.fn(p.ctx)
+}
+
+func (p *Pool) drop(name string) {
+	if p.Hooks.OnDrop != nil {
+		p.Hooks.OnDrop(name)
+	}
+}
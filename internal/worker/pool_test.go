@@ -0,0 +1,125 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPool_BoundsConcurrency(t *testing.T) {
+	const workers = 3
+	pool := NewPool("test", workers, 20)
+
+	var running, peak atomic.Int64
+	release := make(chan struct{})
+	for range 10 {
+		require.NoError(t, pool.Submit("slow", func(context.Context) error {
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			<-release
+			running.Add(-1)
+			return nil
+		}))
+	}
+
+	require.Eventually(t, func() bool { return pool.InFlight() == workers }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, 7, pool.Pending())
+	close(release)
+	require.NoError(t, pool.Shutdown(context.Background()))
+	assert.EqualValues(t, workers, peak.Load(), "never more tasks at once than workers")
+}
+
+func TestPool_QueueFull(t *testing.T) {
+	pool := NewPool("test", 1, 1)
+	var dropped []string
+	pool.Hooks.OnDrop = func(name string) { dropped = append(dropped, name) }
+
+	release := make(chan struct{})
+	require.NoError(t, pool.Submit("running", func(context.Context) error { <-release; return nil }))
+	require.Eventually(t, func() bool { return pool.InFlight() == 1 }, time.Second, 5*time.Millisecond)
+	require.NoError(t, pool.Submit("queued", func(context.Context) error { return nil }))
+
+	assert.ErrorIs(t, pool.Submit("extra", func(context.Context) error { return nil }), ErrQueueFull)
+	assert.Equal(t, []string{"extra"}, dropped)
+	close(release)
+	require.NoError(t, pool.Shutdown(context.Background()))
+}
+
+func TestPool_DrainsOnShutdown(t *testing.T) {
+	pool := NewPool("test", 2, 10)
+	var done atomic.Int64
+	for range 6 {
+		require.NoError(t, pool.Submit("task", func(context.Context) error {
+			time.Sleep(10 * time.Millisecond)
+			done.Add(1)
+			return nil
+		}))
+	}
+
+	require.NoError(t, pool.Shutdown(context.Background()))
+	assert.EqualValues(t, 6, done.Load(), "queued tasks run before Shutdown returns")
+	assert.ErrorIs(t, pool.Submit("late", func(context.Context) error { return nil }), ErrClosed)
+	assert.NoError(t, pool.Shutdown(context.Background()), "Shutdown can be called again")
+}
+
+func TestPool_ShutdownTimeout(t *testing.T) {
+	pool := NewPool("test", 1, 10)
+	var dropped atomic.Int64
+	pool.Hooks.OnDrop = func(string) { dropped.Add(1) }
+
+	cancelled := make(chan struct{})
+	require.NoError(t, pool.Submit("blocking", func(ctx context.Context) error {
+		<-ctx.Done()
+		close(cancelled)
+		return ctx.Err()
+	}))
+	ran := false
+	require.NoError(t, pool.Submit("queued", func(context.Context) error { ran = true; return nil }))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, pool.Shutdown(ctx), context.DeadlineExceeded)
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("running task's context was not cancelled")
+	}
+	require.NoError(t, pool.Shutdown(context.Background()))
+	assert.False(t, ran, "tasks that hadn't started are dropped")
+	assert.EqualValues(t, 1, dropped.Load())
+}
+
+func TestPool_RecoversPanics(t *testing.T) {
+	pool := NewPool("test", 1, 10)
+	var mu sync.Mutex
+	results := map[string]error{}
+	pool.Hooks.OnDone = func(name string, elapsed time.Duration, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		results[name] = err
+	}
+
+	failure := errors.New("boom")
+	require.NoError(t, pool.Submit("panics", func(context.Context) error { panic("kaboom") }))
+	require.NoError(t, pool.Submit("fails", func(context.Context) error { return failure }))
+	require.NoError(t, pool.Submit("ok", func(context.Context) error { return nil }))
+	require.NoError(t, pool.Shutdown(context.Background()))
+
+	require.Len(t, results, 3, "the worker keeps running after a panic")
+	assert.ErrorIs(t, results["panics"], ErrPanic)
+	assert.ErrorContains(t, results["panics"], "kaboom")
+	assert.ErrorIs(t, results["fails"], failure)
+	assert.NoError(t, results["ok"])
+}
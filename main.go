@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"net/http"
 	"os"
 	"os/signal"
@@ -13,44 +14,124 @@ import (
 	"github.com/lucas-varjao/gohtmx/internal/config"
 	"github.com/lucas-varjao/gohtmx/internal/email"
 	"github.com/lucas-varjao/gohtmx/internal/handlers"
+	"github.com/lucas-varjao/gohtmx/internal/jobs"
 	"github.com/lucas-varjao/gohtmx/internal/logger"
 	"github.com/lucas-varjao/gohtmx/internal/models"
+	"github.com/lucas-varjao/gohtmx/internal/securecookie"
 	"github.com/lucas-varjao/gohtmx/internal/service"
+	"github.com/lucas-varjao/gohtmx/internal/tracing"
 
-	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/time/rate"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
+// defaultBulkEmailRate is the bulk send pace (emails per second) when email.bulk_rate_per_second is unset.
+const defaultBulkEmailRate = 2.0
+
 // gracefulShutdownTimeout limits how long we wait for in-flight requests to finish.
 const gracefulShutdownTimeout = 5 * time.Second
 
+// migratedModels are the tables AutoMigrate manages; --check compares the database against them.
+var migratedModels = []any{
+	&models.User{}, &models.Session{}, &models.LoginAttempt{}, &models.PasswordHistory{}, &models.AuditLog{}, &models.DeniedIP{}, &models.Invite{}, &models.WebAuthnCredential{}, &models.BackupCode{}, &models.TrustedDevice{},
+}
+
 func main() {
+	check := flag.Bool("check", false, "validate the config and database, then exit without starting the server")
+	flag.Parse()
+
 	cfg := loadConfigOrExit()
 	initLoggerFromConfig(cfg)
+	if *check {
+		if err := runCheck(os.Stdout, cfg, openDatabase); err != nil {
+			os.Exit(1)
+		}
+		return
+	}
 	logger.Info("Iniciando servidor", "port", cfg.Server.Port)
+	// Effective values after env overrides, without secrets, so a deploy can be checked from the log
+	logger.Info("Configuração em vigor", "config", cfg.Redacted())
+
+	shutdownTracing := setupTracing(cfg)
 
 	db := connectDatabase(cfg.Database.DSN)
+	if cfg.Tracing.Endpoint != "" {
+		if err := tracing.InstrumentDB(db); err != nil {
+			logger.Error("Falha ao instrumentar o banco de dados", "error", err)
+			os.Exit(1)
+		}
+	}
 	migrateDatabase(db)
-	ensureAdminUser(db)
+	seedUsers(db, cfg.Seed.Users)
 
-	authManager, authService := initAuthStack(db, cfg)
+	emailService := email.NewEmailService(cfg)
+	emailQueue := email.NewQueue(emailService, email.DefaultQueueSize)
+	bulkEmailQueue := newBulkEmailQueue(emailService, cfg.Email)
+	authManager, authService, deletions, newLogins := initAuthStack(db, cfg, emailService, emailQueue)
 
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(authService)
+	authHandler := handlers.NewAuthHandlerWithConfig(authService, cfg)
+	authHandler.UseDeletions(deletions)
+	if newLogins != nil {
+		authHandler.UseNewLoginNotices(newLogins)
+	}
+	// Logins from a country the user never logged in from wait for a code sent by email, or one of the
+	// user's backup codes
+	if cfg.Features().NewCountryChallenge {
+		challenges := service.NewLoginChallengeService(db, authManager, emailService, cfg.Login.NewCountryChallenge.CodeTTL)
+		backupCodes := service.NewBackupCodeService(db, service.NewAuditService(db))
+		challenges.UseBackupCodes(backupCodes)
+		authManager.OnCredentialsVerified(challenges.Check)
+		authHandler.UseLoginChallenges(challenges)
+		authHandler.UseBackupCodes(backupCodes)
+		// A device the user chose to remember skips the code until it expires or LogoutAll forgets it
+		if ttl := cfg.Login.NewCountryChallenge.RememberDevice; ttl > 0 {
+			jar, err := securecookie.New(cfg.Security.CookieSecret, false)
+			if err != nil {
+				logger.Error("Erro ao configurar cookie de dispositivo lembrado", "error", err)
+				os.Exit(1)
+			}
+			devices := service.NewTrustedDeviceService(db, ttl)
+			devices.Watch(authManager)
+			challenges.UseTrustedDevices(devices)
+			authHandler.UseTrustedDevices(devices, jar)
+		}
+	}
+	// Users who lost access to their email set a new password with a code an admin creates for them
+	recovery := service.NewRecoveryCodeService(db, authService, service.NewAuditService(db), cfg.Account.RecoveryCodeTTL)
+	authHandler.UseRecoveryCodes(recovery)
 
 	// Build server instance
-	server, err := buildServer(authHandler, authManager, db)
+	server, err := buildServer(authHandler, authManager, db, recovery, bulkEmailQueue, emailService.Degraded())
 	if err != nil {
 		logger.Error("Erro ao criar servidor", "error", err)
 		os.Exit(1)
 	}
 
-	if err := runServerWithGracefulShutdown(server, cfg.Server.Port); err != nil {
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	startBackgroundJobs(jobsCtx, db, cfg)
+
+	err = runServerWithGracefulShutdown(server, cfg.Server.Port)
+	stopJobs()
+	closeEmailQueues(emailQueue, bulkEmailQueue)
+	shutdownTracing()
+	if err != nil {
 		os.Exit(1)
 	}
 }
 
+// closeEmailQueues sends what is left in the queues, giving up after gracefulShutdownTimeout.
+func closeEmailQueues(queues ...*email.Queue) {
+	ctx, cancel := context.WithTimeout(context.Background(), gracefulShutdownTimeout)
+	defer cancel()
+	for _, queue := range queues {
+		if err := queue.Close(ctx); err != nil {
+			logger.Error("Erro ao esvaziar a fila de emails", "error", err)
+		}
+	}
+}
+
 // loadConfigOrExit loads config and initializes a fallback logger on failure.
 func loadConfigOrExit() *config.Config {
 	cfg, err := config.LoadConfig()
@@ -76,55 +157,138 @@ func initLoggerFromConfig(cfg *config.Config) {
 	logger.Init(logLevel, logFormat)
 }
 
+// setupTracing installs the OTLP exporter when configured and returns a func that flushes pending spans.
+func setupTracing(cfg *config.Config) func() {
+	shutdown, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		logger.Error("Falha ao configurar o tracing", "error", err)
+		os.Exit(1)
+	}
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), gracefulShutdownTimeout)
+		defer cancel()
+		if err := shutdown(ctx); err != nil {
+			logger.Error("Erro ao enviar spans pendentes", "error", err)
+		}
+	}
+}
+
+// openDatabase opens the Postgres connection pool.
+func openDatabase(dsn string) (*gorm.DB, error) {
+	return gorm.Open(postgres.Open(dsn), &gorm.Config{})
+}
+
 // connectDatabase connects to Postgres and logs success or exits on failure.
 func connectDatabase(dsn string) *gorm.DB {
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := openDatabase(dsn)
 	if err != nil {
-		logger.Error("Falha ao conectar ao banco de dados", "error", err, "dsn", dsn)
+		logger.Error("Falha ao conectar ao banco de dados", "error", err, "dsn", config.RedactDSN(dsn))
 		os.Exit(1)
 	}
-	logger.Info("Conectado ao banco de dados", "dsn", dsn)
+	logger.Info("Conectado ao banco de dados", "dsn", config.RedactDSN(dsn))
 	return db
 }
 
 // migrateDatabase runs schema migrations needed for the app.
 func migrateDatabase(db *gorm.DB) {
-	if err := db.AutoMigrate(&models.User{}, &models.Session{}); err != nil {
+	if err := db.AutoMigrate(migratedModels...); err != nil {
 		logger.Error("Falha ao executar migrações", "error", err)
 		os.Exit(1)
 	}
+	// gorm.Model doesn't index created_at; the signups chart groups by it
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_users_created_at ON users (created_at)").Error; err != nil {
+		logger.Error("Falha ao criar índice de created_at", "error", err)
+		os.Exit(1)
+	}
 	logger.Info("Migrações executadas com sucesso")
 }
 
-// ensureAdminUser seeds a default admin user when missing.
-func ensureAdminUser(db *gorm.DB) {
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte("admin"), bcrypt.DefaultCost)
-	if err != nil {
-		logger.Error("Falha ao gerar hash da senha do admin", "error", err)
+// initAuthStack wires adapters, auth manager, and service dependencies. Logins of accounts waiting to be
+// deleted offer to cancel through the returned DeletionService. The ConcurrentLoginService that notifies
+// users of logins made while other sessions were open is nil unless config login.concurrent_login_notice
+// turns it on.
+// Emails that must not hold up the request (e.g. lockout notices) go through emailQueue.
+func initAuthStack(db *gorm.DB, cfg *config.Config, emailService email.EmailServiceInterface, emailQueue *email.Queue) (*auth.AuthManager, *service.AuthService, *service.DeletionService, *service.ConcurrentLoginService) {
+	userAdapter := gormadapter.NewUserAdapter(db)
+	sessionAdapter := gormadapter.NewSessionAdapter(db)
+	authConfig := auth.DefaultAuthConfig()
+	authConfig.RequireVerifiedEmail = cfg.Login.RequireVerifiedEmail
+	if cfg.Session.MaxLifetime > 0 {
+		authConfig.MaxSessionLifetime = cfg.Session.MaxLifetime
 	}
+	if idle := cfg.Session.IdleTimeout; idle > 0 {
+		// Activity in the second half of the window slides the expiry forward
+		authConfig.SessionDuration = idle
+		authConfig.RefreshThreshold = idle / 2
+	}
+	authManager := auth.NewAuthManager(userAdapter, sessionAdapter, authConfig)
+	authService := service.NewAuthService(authManager, userAdapter, emailService, service.NewLoginAttemptService(db))
+	if cfg.Login.LockoutEmail {
+		authService.NotifyLockouts(emailQueue)
+	}
+	if cfg.Password.SendsChangeConfirmation() {
+		authService.ConfirmPasswordChanges(emailQueue)
+	}
+	notifications := service.NewNotificationService(db)
+	authService.UseNotificationPreferences(notifications)
+	deletions := service.NewDeletionService(db, authManager, emailService, cfg.Account.DeletionGracePeriod)
+	authService.UseDeletions(deletions)
+	if cfg.Registration.GenerateUsername {
+		authService.UseUsernameGenerator(service.NewUsernameGenerator(userAdapter))
+	}
+	var newLogins *service.ConcurrentLoginService
+	if notice := cfg.Login.ConcurrentLoginNotice; notice.Email || notice.Banner {
+		newLogins = service.NewConcurrentLoginService(db, emailQueue, notice)
+		newLogins.UseNotificationPreferences(notifications)
+		newLogins.Watch(authManager)
+	}
+	return authManager, authService, deletions, newLogins
+}
 
-	result := db.Where(models.User{Username: "admin"}).FirstOrCreate(&models.User{
-		Username:     "admin",
-		Email:        "onyx.views5004@eagereverest.com",
-		DisplayName:  "Administrator",
-		PasswordHash: string(passwordHash),
-		Role:         "admin",
-	})
-	if result.Error != nil {
-		logger.Error("Falha ao criar usuário admin", "error", result.Error)
+// newBulkEmailQueue creates the throttled queue for admin bulk sends, sized to hold a whole batch.
+func newBulkEmailQueue(emailService email.EmailServiceInterface, cfg config.EmailConfig) *email.Queue {
+	perSecond := cfg.BulkRatePerSecond
+	if perSecond <= 0 {
+		perSecond = defaultBulkEmailRate
 	}
-	logger.Info("Usuário admin verificado", "rows_affected", result.RowsAffected)
+	size := cfg.VerificationBatchCap
+	if size <= 0 {
+		size = service.DefaultVerificationBatchCap
+	}
+	return email.NewThrottledQueue(emailService, size, rate.Limit(perSecond))
 }
 
-// initAuthStack wires adapters, auth manager, and service dependencies.
-func initAuthStack(db *gorm.DB, cfg *config.Config) (*auth.AuthManager, service.AuthServiceInterface) {
-	userAdapter := gormadapter.NewUserAdapter(db)
-	sessionAdapter := gormadapter.NewSessionAdapter(db)
-	authConfig := auth.DefaultAuthConfig()
-	authManager := auth.NewAuthManager(userAdapter, sessionAdapter, authConfig)
-	emailService := email.NewEmailService(cfg)
-	authService := service.NewAuthService(authManager, userAdapter, emailService)
-	return authManager, authService
+// startBackgroundJobs schedules the periodic jobs; they stop when ctx is cancelled.
+func startBackgroundJobs(ctx context.Context, db *gorm.DB, cfg *config.Config) {
+	janitor := &jobs.RetentionJanitor{
+		DB:                  db,
+		SessionsAfterExpiry: cfg.Jobs.Retention.Sessions,
+		LoginAttempts:       cfg.Jobs.LoginAttemptRetention(),
+		AuditLogs:           cfg.Jobs.Retention.AuditLogs,
+	}
+	jobs.Every(ctx, "retention_janitor", cfg.Jobs.Interval, janitor.Job())
+
+	if cfg.Password.MaxAge > 0 {
+		expirer := &jobs.PasswordExpirer{DB: db, MaxAge: cfg.Password.MaxAge}
+		jobs.Every(ctx, "password_expiry", cfg.Jobs.Interval, expirer.Job())
+		logger.Info("Expiração de senhas habilitada", "max_age", cfg.Password.MaxAge)
+	}
+
+	inactivity := cfg.Jobs.Inactivity
+	if !inactivity.Enabled || inactivity.Threshold <= 0 {
+		return
+	}
+	deactivator := &jobs.InactivityDeactivator{
+		DB:                 db,
+		Threshold:          inactivity.Threshold,
+		ProtectedUsernames: protectedSeedUsernames(cfg.Seed.Users),
+	}
+	if inactivity.NotifyEmail {
+		deactivator.Email = email.NewEmailService(cfg)
+		deactivator.Notifications = service.NewNotificationService(db)
+	}
+	jobs.Every(ctx, "inactivity_deactivation", cfg.Jobs.Interval, deactivator.Job())
+	logger.Info("Desativação automática por inatividade habilitada", "threshold", inactivity.Threshold)
 }
 
 // runServerWithGracefulShutdown blocks until shutdown or a server error.
@@ -133,8 +297,16 @@ func runServerWithGracefulShutdown(server *http.Server, port int) error {
 
 	// Start server in a goroutine.
 	go func() {
-		logger.Info("Servidor iniciado", "port", port)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if server.TLSConfig != nil {
+			// Certificates are already loaded into TLSConfig by buildServer
+			logger.Info("Servidor iniciado", "port", port, "tls", true)
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			logger.Info("Servidor iniciado", "port", port)
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			serverErr <- err
 		}
 	}()
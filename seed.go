@@ -0,0 +1,118 @@
+package main
+
+import (
+	"cmp"
+	"encoding/base64"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/config"
+	"github.com/lucas-varjao/gohtmx/internal/logger"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+	"github.com/lucas-varjao/gohtmx/internal/service"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// seedAdminUsername is the admin account created on startup when seed.users is empty.
+const seedAdminUsername = "admin"
+
+// randomPasswordBytes is the entropy of generated seed passwords (24 base64 characters).
+const randomPasswordBytes = 18
+
+// defaultSeedUsers is the single admin seeded when seed.users is empty.
+func defaultSeedUsers() []config.SeedUser {
+	return []config.SeedUser{{
+		Username:    seedAdminUsername,
+		Email:       "onyx.views5004@eagereverest.com",
+		DisplayName: "Administrator",
+		Role:        service.RoleAdmin,
+		Password:    "admin",
+	}}
+}
+
+// seedUsers creates the users (or the default admin when users is empty) that don't exist yet, matched by
+// username or email. Existing accounts are never changed, so running it on every startup is safe.
+// It returns how many users were created; failures are logged and don't stop the remaining users.
+func seedUsers(db *gorm.DB, users []config.SeedUser) int {
+	if len(users) == 0 {
+		users = defaultSeedUsers()
+	}
+
+	created := 0
+	for _, seed := range users {
+		if seed.Username == "" || seed.Email == "" {
+			logger.Error("Usuário inicial sem username ou email ignorado", "username", seed.Username, "email", seed.Email)
+			continue
+		}
+		var existing int64
+		if err := db.Model(&models.User{}).Where("username = ? OR email = ?", seed.Username, seed.Email).Count(&existing).Error; err != nil {
+			logger.Error("Falha ao verificar usuário inicial", "error", err, "username", seed.Username)
+			continue
+		}
+		if existing > 0 {
+			logger.Debug("Usuário inicial já existe", "username", seed.Username)
+			continue
+		}
+		if err := createSeedUser(db, seed); err != nil {
+			logger.Error("Falha ao criar usuário inicial", "error", err, "username", seed.Username)
+			continue
+		}
+		created++
+	}
+	logger.Info("Usuários iniciais verificados", "configured", len(users), "created", created)
+	return created
+}
+
+func createSeedUser(db *gorm.DB, seed config.SeedUser) error {
+	password, generated := seed.Password, false
+	if password == "" {
+		raw := make([]byte, randomPasswordBytes)
+		if _, err := auth.GenerateRandomBytes(raw); err != nil {
+			return err
+		}
+		password, generated = base64.RawURLEncoding.EncodeToString(raw), true
+	}
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	user := &models.User{
+		Username:           seed.Username,
+		Email:              seed.Email,
+		DisplayName:        cmp.Or(seed.DisplayName, seed.Username),
+		PasswordHash:       string(passwordHash),
+		PasswordChangedAt:  &now,
+		Role:               service.NormalizeRole(seed.Role),
+		Active:             true,
+		MustChangePassword: generated,
+	}
+	if err := db.Create(user).Error; err != nil {
+		return err
+	}
+
+	if generated {
+		// The only time the password is available; it has to be changed at first login
+		logger.Warn("Usuário inicial criado com senha aleatória", "username", user.Username, "password", password)
+	} else {
+		logger.Info("Usuário inicial criado", "username", user.Username, "role", user.Role)
+	}
+	return nil
+}
+
+// protectedSeedUsernames lists the seeded admins, which the inactivity job never deactivates.
+func protectedSeedUsernames(users []config.SeedUser) []string {
+	if len(users) == 0 {
+		users = defaultSeedUsers()
+	}
+	var usernames []string
+	for _, seed := range users {
+		if service.NormalizeRole(seed.Role) == service.RoleAdmin {
+			usernames = append(usernames, seed.Username)
+		}
+	}
+	return usernames
+}
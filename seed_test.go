@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/lucas-varjao/gohtmx/internal/config"
+	"github.com/lucas-varjao/gohtmx/internal/models"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestSeedUsers(t *testing.T) {
+	db := setupTestDB(t)
+	seeds := []config.SeedUser{
+		{Username: "maria", Email: "maria@example.com", DisplayName: "Maria", Role: "admin", Password: "Secret123!"},
+		{Username: "joao", Email: "joao@example.com", Role: "superuser"},
+		{Username: "", Email: "nobody@example.com"},
+	}
+
+	if created := seedUsers(db, seeds); created != 2 {
+		t.Fatalf("first run: expected 2 users created, got %d", created)
+	}
+
+	var maria, joao models.User
+	if err := db.Where("username = ?", "maria").First(&maria).Error; err != nil {
+		t.Fatalf("maria not seeded: %v", err)
+	}
+	if maria.Role != "admin" || maria.MustChangePassword || !maria.Active {
+		t.Errorf("unexpected maria: role=%q must_change=%v active=%v", maria.Role, maria.MustChangePassword, maria.Active)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(maria.PasswordHash), []byte("Secret123!")) != nil {
+		t.Error("configured password should be used")
+	}
+	if err := db.Where("username = ?", "joao").First(&joao).Error; err != nil {
+		t.Fatalf("joao not seeded: %v", err)
+	}
+	if joao.Role != "user" || joao.DisplayName != "joao" || !joao.MustChangePassword {
+		t.Errorf("random-password user must change it at first login: role=%q display=%q must_change=%v",
+			joao.Role, joao.DisplayName, joao.MustChangePassword)
+	}
+
+	// Second run: nothing new, and changes made since then are kept
+	if err := db.Model(&maria).Update("role", "user").Error; err != nil {
+		t.Fatal(err)
+	}
+	seeds[0].Email = "maria.new@example.com"
+	seeds = append(seeds, config.SeedUser{Username: "maria2", Email: "joao@example.com"})
+	if created := seedUsers(db, seeds); created != 0 {
+		t.Errorf("second run: expected no users created, got %d", created)
+	}
+	var count int64
+	db.Model(&models.User{}).Count(&count)
+	if count != 2 {
+		t.Errorf("expected 2 users after second run, got %d", count)
+	}
+	if err := db.First(&maria, maria.ID).Error; err != nil || maria.Role != "user" || maria.Email != "maria@example.com" {
+		t.Errorf("existing user must not be overwritten, got role=%q email=%q", maria.Role, maria.Email)
+	}
+}
+
+func TestSeedUsers_DefaultAdmin(t *testing.T) {
+	db := setupTestDB(t)
+
+	if created := seedUsers(db, nil); created != 1 {
+		t.Fatalf("expected the default admin to be created, got %d", created)
+	}
+	var admin models.User
+	if err := db.Where("username = ?", seedAdminUsername).First(&admin).Error; err != nil || admin.Role != "admin" {
+		t.Fatalf("default admin not seeded: %v (role %q)", err, admin.Role)
+	}
+	if created := seedUsers(db, nil); created != 0 {
+		t.Errorf("default admin must be seeded only once, got %d", created)
+	}
+	if got := protectedSeedUsernames(nil); len(got) != 1 || got[0] != seedAdminUsername {
+		t.Errorf("expected the default admin to be protected, got %v", got)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"net/http"
@@ -10,11 +11,17 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/render"
 	"github.com/lucas-varjao/gohtmx/internal/auth"
+	"github.com/lucas-varjao/gohtmx/internal/avatar"
+	"github.com/lucas-varjao/gohtmx/internal/basepath"
 	"github.com/lucas-varjao/gohtmx/internal/config"
+	"github.com/lucas-varjao/gohtmx/internal/email"
 	"github.com/lucas-varjao/gohtmx/internal/handlers"
 	"github.com/lucas-varjao/gohtmx/internal/logger"
 	"github.com/lucas-varjao/gohtmx/internal/middleware"
 	"github.com/lucas-varjao/gohtmx/internal/router"
+	"github.com/lucas-varjao/gohtmx/internal/service"
+	"github.com/lucas-varjao/gohtmx/internal/validation"
+	brand "github.com/lucas-varjao/gohtmx/templates/render"
 
 	"gorm.io/gorm"
 )
@@ -54,7 +61,10 @@ func (t *TemplRender) Instance(name string, data any) render.Render {
 
 // buildServer creates and configures a new HTTP server instance.
 // Returns the server instance ready to be started, or an error if configuration fails.
-func buildServer(authHandler *handlers.AuthHandler, authManager *auth.AuthManager, db *gorm.DB) (*http.Server, error) {
+// recovery creates the account recovery codes admins hand to users who lost access to their email.
+// bulkEmail sends the admin's bulk emails (verification batch); nil leaves that route out.
+// emailDegraded is why the email service fell back to logging (email.EmailService.Degraded), reported by GET /health/ready.
+func buildServer(authHandler *handlers.AuthHandler, authManager *auth.AuthManager, db *gorm.DB, recovery *service.RecoveryCodeService, bulkEmail *email.Queue, emailDegraded error) (*http.Server, error) {
 	cfg := config.GetConfig()
 	if cfg == nil {
 		return nil, fmt.Errorf("config not loaded")
@@ -63,15 +73,79 @@ func buildServer(authHandler *handlers.AuthHandler, authManager *auth.AuthManage
 	// Custom recovery: render HTML error page or JSON depending on Accept header
 	recoveryFn := func(c *gin.Context, err any) {
 		logger.Error("panic recovered", "error", err)
-		if wantsHTML(c) {
+		if middleware.WantsHTML(c) {
 			renderErrorPage(c, http.StatusInternalServerError)
 		} else {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
 		}
 	}
 
+	// GET /health reports the same version as the footer
+	router.Version = AppVersion
+
+	// Every link and redirect is built with basepath.URL, so set the prefix before anything renders
+	basepath.Set(cfg.Server.BasePath)
+	middleware.SetSessionCookiePartitioned(cfg.Session.CookiePartitioned)
+	middleware.SetAdminReadOnly(cfg.Features().AdminReadOnly)
+	validation.AllowPersonalInfo(cfg.Password.AllowPersonalInfo)
+	avatar.AllowHosts(cfg.Avatar.Hosts...)
+	start, end, err := cfg.Maintenance.Window()
+	if err != nil {
+		return nil, err
+	}
+	middleware.SetMaintenanceWindow(middleware.MaintenanceWindow{Start: start, End: end, AnnounceBefore: cfg.Maintenance.AnnounceBefore})
+	router.MaintenancePage = func(c *gin.Context) { renderErrorPage(c, http.StatusServiceUnavailable) }
+
+	// User management shared by the HTML admin pages and the JSON admin API
+	users := service.NewUserAdminService(db, authManager)
+	if cfg.Session.KeepOnDeactivate {
+		users.KeepSessionsOnDeactivate()
+	}
+	if cfg.Session.LogoutOnRoleChange {
+		users.LogoutOnRoleChange()
+	}
+	if cfg.Admin.MinAccountAgeForAdmin > 0 {
+		users.RequireAccountAgeForAdmin(cfg.Admin.MinAccountAgeForAdmin)
+	}
+	loginAttempts := service.NewLoginAttemptService(db)
+	accounts := service.NewAccountService(db)
+	audit := service.NewAuditService(db)
+	// Every logout lands in the audit log with its reason (user, admin, password reset, ...)
+	audit.RecordLogouts(authManager)
+	// ...and every new session, so admins can spot a user piling up sessions (see adminSessionStatsJSON)
+	audit.RecordSessions(authManager)
+	impersonation := service.NewImpersonationService(authManager, audit)
+	// Blocked IPs: fixed ones from config plus the admin-managed ones, reloaded on every edit
+	ipDenylist, err := middleware.NewIPDenylist(cfg.Security.IPDenylist)
+	if err != nil {
+		return nil, err
+	}
+	// Invite links register users (with the invite's role) even when registration is disabled
+	invites := service.NewInviteService(db, audit, cfg.Registration.InviteTTL)
+	authHandler.UseInvites(invites)
+	authHandler.UseAudit(audit)
+	// Passkeys (WebAuthn) log users in without a password; the password login stays available
+	if cfg.WebAuthn.Enabled {
+		passkeys, err := service.NewPasskeyService(db, authManager, cfg.WebAuthn, cmp.Or(cfg.App.Name, brand.DefaultBrand.Name))
+		if err != nil {
+			return nil, err
+		}
+		passkeys.UseLoginAttempts(loginAttempts)
+		authHandler.UsePasskeys(passkeys)
+	}
+	denylist := service.NewIPDenylistService(db, audit)
+	if err := denylist.EnforceWith(ipDenylist); err != nil {
+		return nil, err
+	}
+
+	// Admin lists default to the page size each admin last asked for
+	pageSizes := service.NewPageSizeService(db, cfg.Admin.PerPage)
+	adminUserHandler := handlers.NewAdminUserHandler(users)
+	adminUserHandler.UsePageSizes(pageSizes)
+
 	// Setup router with all routes (auth, API, etc.)
-	r := router.SetupRouter(authHandler, authManager, recoveryFn)
+	r := router.SetupRouter(authHandler, adminUserHandler, handlers.NewAccountHandler(accounts),
+		authManager, ipDenylist, recoveryFn)
 
 	// Define HTML renderer for template engine (TEMPL support)
 	r.HTMLRender = &TemplRender{}
@@ -79,6 +153,9 @@ func buildServer(authHandler *handlers.AuthHandler, authManager *auth.AuthManage
 	// Handle static files (keep gowebly static route)
 	r.Static("/static", "./static")
 
+	// Readiness: unlike /health it checks the dependencies (see readinessHandler)
+	r.GET("/health/ready", func(c *gin.Context) { readinessHandler(c, db, emailDegraded) })
+
 	// Handle index page view (receives authManager to show user when logged in)
 	r.GET("/", func(c *gin.Context) { indexViewHandler(c, authManager) })
 
@@ -86,8 +163,42 @@ func buildServer(authHandler *handlers.AuthHandler, authManager *auth.AuthManage
 	r.POST("/logout", func(c *gin.Context) { logoutViewHandler(c, authManager) })
 
 	// Handle authentication views (pass authManager for navbar/footer).
-	r.GET("/login", func(c *gin.Context) { loginViewHandler(c, authManager) })
-	r.GET("/register", func(c *gin.Context) { registerViewHandler(c, authManager) })
+	r.GET("/login", func(c *gin.Context) { loginViewHandler(c, authManager, authHandler.LoginCaptcha(c, false)) })
+	r.GET("/register", func(c *gin.Context) { registerViewHandler(c, authManager, invites, authHandler.RegisterCaptcha(false)) })
+	// Asks for the emailed code of a login from a new country (config login.new_country_challenge)
+	if cfg.Features().NewCountryChallenge {
+		r.GET(handlers.LoginChallengePath, func(c *gin.Context) {
+			loginChallengeViewHandler(c, authManager, cfg.Login.NewCountryChallenge.RememberDevice)
+		})
+	}
+
+	// Where the account deletion email links to, while the deletion can still be cancelled (config account)
+	if cfg.Account.DeletionGracePeriod > 0 {
+		r.GET(handlers.CancelDeletionPath, func(c *gin.Context) { cancelDeletionViewHandler(c, authManager) })
+	}
+
+	// Where users who lost access to their email type the recovery code an admin created for them
+	r.GET(handlers.RecoverPath, func(c *gin.Context) { recoverViewHandler(c, authManager) })
+
+	// Where protected routes send users with an unverified email (config login.verified_email_gate)
+	r.GET(middleware.VerifyEmailPath, func(c *gin.Context) { verifyEmailViewHandler(c, authManager) })
+	// Where protected routes send users who haven't accepted the current terms of use (config terms.version)
+	r.GET(middleware.TermsPath, func(c *gin.Context) { termsViewHandler(c, authManager, cfg.Terms) })
+
+	// Profile of the logged-in user (notification preferences)
+	notifications := service.NewNotificationService(db)
+	profileGroup := r.Group("/profile")
+	profileGroup.Use(middleware.WebAuthMiddleware(authManager))
+	if gate := router.VerifiedEmailGate(); gate != nil {
+		profileGroup.Use(gate)
+	}
+	if gate := router.TermsGate(); gate != nil {
+		profileGroup.Use(gate)
+	}
+	profileGroup.GET("", func(c *gin.Context) { profileView(c, notifications, accounts, authManager) })
+	profileGroup.POST("/notifications", func(c *gin.Context) { profileNotificationsPost(c, notifications) })
+	profileGroup.POST("/phone", func(c *gin.Context) { profilePhonePost(c, accounts) })
+	profileGroup.POST("/delete", middleware.ForbidImpersonationMiddleware(), authHandler.RequestAccountDeletion)
 
 	// Handle API endpoints (keep gowebly example route)
 	r.GET("/api/hello-world", showContentAPIHandler)
@@ -95,18 +206,56 @@ func buildServer(authHandler *handlers.AuthHandler, authManager *auth.AuthManage
 	// Admin area (HTML); requires valid session + admin role
 	adminGroup := r.Group("/admin")
 	adminGroup.Use(middleware.AdminWebMiddleware(authManager, func(c *gin.Context) { renderErrorPage(c, http.StatusForbidden) }))
+	if gate := router.VerifiedEmailGate(); gate != nil {
+		adminGroup.Use(gate)
+	}
+	if gate := router.TermsGate(); gate != nil {
+		adminGroup.Use(gate)
+	}
+	adminGroup.Use(middleware.AdminReadOnlyMiddleware(adminReadOnlyBlocked))
 	adminGroup.GET("", func(c *gin.Context) { adminDashboardView(c, db, authManager) })
 	adminGroup.GET("/", func(c *gin.Context) { adminDashboardView(c, db, authManager) })
-	adminGroup.GET("/users", func(c *gin.Context) { adminUsersView(c, db, authManager) })
+	adminGroup.GET("/stats/signups", func(c *gin.Context) { adminSignupStatsJSON(c, db) })
+	sessionStats := service.NewSessionStatsService(db)
+	adminGroup.GET("/stats/sessions", func(c *gin.Context) { adminSessionStatsJSON(c, sessionStats) })
+	adminGroup.GET("/users", func(c *gin.Context) { adminUsersView(c, users, authManager) })
 	adminGroup.GET("/users/new", func(c *gin.Context) { adminUsersNewView(c, authManager) })
-	adminGroup.POST("/users", func(c *gin.Context) { adminUsersCreatePost(c, db) })
-	adminGroup.POST("/users/:id/role", func(c *gin.Context) { adminUserRolePost(c, db) })
-	adminGroup.POST("/users/:id/active", func(c *gin.Context) { adminUserActivePost(c, db) })
-	adminGroup.POST("/users/:id/delete", func(c *gin.Context) { adminUserDeletePost(c, db, authManager) })
+	adminGroup.GET("/users/available", authHandler.CheckAvailabilityAdmin)
+	adminGroup.GET("/emails/preview", func(c *gin.Context) { adminEmailPreview(c, cfg.Email) })
+	adminGroup.GET("/invites", func(c *gin.Context) { adminInvitesView(c, invites, authManager) })
+	adminGroup.POST("/invites", func(c *gin.Context) { adminInvitesCreatePost(c, invites, authManager) })
+	adminGroup.POST("/users", func(c *gin.Context) { adminUsersCreatePost(c, users) })
+	adminGroup.POST("/users/:id/role", func(c *gin.Context) { adminUserRolePost(c, users) })
+	adminGroup.POST("/users/:id/active", func(c *gin.Context) { adminUserActivePost(c, users) })
+	adminGroup.GET("/users/:id/display-name", func(c *gin.Context) { adminDisplayNameCell(c, users) })
+	adminGroup.GET("/users/:id/display-name/edit", func(c *gin.Context) { adminDisplayNameEditView(c, users) })
+	adminGroup.POST("/users/:id/display-name", func(c *gin.Context) { adminDisplayNamePost(c, users) })
+	adminGroup.POST("/users/:id/delete", func(c *gin.Context) { adminUserDeletePost(c, users) })
+	adminGroup.GET("/security/attempts", func(c *gin.Context) { adminLoginAttemptsView(c, loginAttempts, denylist, pageSizes, authManager) })
+	adminGroup.GET("/security/denylist", func(c *gin.Context) { adminDenylistView(c, denylist, cfg.Security.IPDenylist, authManager) })
+	adminGroup.POST("/security/denylist", func(c *gin.Context) { adminDenyIPPost(c, denylist) })
+	adminGroup.POST("/security/denylist/:id/delete", func(c *gin.Context) { adminDenylistDeletePost(c, denylist) })
+	// Old address of the attempts page, kept for bookmarks
+	adminGroup.GET("/login-attempts", func(c *gin.Context) {
+		target := "/admin/security/attempts"
+		if c.Request.URL.RawQuery != "" {
+			target += "?" + c.Request.URL.RawQuery
+		}
+		c.Redirect(http.StatusMovedPermanently, basepath.URL(target))
+	})
+	adminGroup.POST("/users/:id/impersonate", func(c *gin.Context) { adminImpersonatePost(c, impersonation) })
+	adminGroup.POST("/users/:id/recovery-code", func(c *gin.Context) { adminRecoveryCodePost(c, recovery) })
+	if bulkEmail != nil {
+		verification := service.NewVerificationService(db, bulkEmail, audit, cfg.Email.VerificationBatchCap)
+		adminGroup.POST("/users/resend-verification-unverified", func(c *gin.Context) { adminResendVerificationPost(c, verification) })
+	}
+
+	// Leaving impersonation runs on the impersonated (non-admin) session, so it lives outside /admin
+	r.POST("/impersonate/stop", func(c *gin.Context) { impersonateStopPost(c, authManager, impersonation) })
 
-	// 503 maintenance page (for testing and future maintenance mode)
+	// 503 maintenance page, as shown during the maintenance window (config maintenance)
 	r.GET("/maintenance", func(c *gin.Context) {
-		if wantsHTML(c) {
+		if middleware.WantsHTML(c) {
 			renderErrorPage(c, http.StatusServiceUnavailable)
 		} else {
 			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
@@ -115,7 +264,7 @@ func buildServer(authHandler *handlers.AuthHandler, authManager *auth.AuthManage
 
 	// 404 for unmatched routes (after all other routes)
 	r.NoRoute(func(c *gin.Context) {
-		if wantsHTML(c) {
+		if middleware.WantsHTML(c) {
 			renderErrorPage(c, http.StatusNotFound)
 		} else {
 			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "not found"})
@@ -128,17 +277,54 @@ func buildServer(authHandler *handlers.AuthHandler, authManager *auth.AuthManage
 		port = 7000 // Default gowebly port
 	}
 
-	// Create a new server instance with options from environment variables.
+	// Create a new server instance with timeouts from config.
 	// For more information, see https://blog.cloudflare.com/the-complete-guide-to-golang-net-http-timeouts/
-	// Note: The ReadTimeout and WriteTimeout settings may interfere with SSE (Server-Sent Event) or WS (WebSocket) connections.
-	// For SSE or WS, these timeouts can cause the connection to reset after 10 or 5 seconds due to the ReadTimeout and WriteTimeout settings.
-	// If you plan to use SSE or WS, consider commenting out or removing the ReadTimeout and WriteTimeout key-value pairs.
+	// ReadTimeout and WriteTimeout would reset SSE (Server-Sent Event) or WS (WebSocket) connections after a few
+	// seconds, over HTTP/1.1 and HTTP/2 alike. Register such routes with middleware.NoTimeoutMiddleware() instead
+	// of loosening the timeouts globally.
+	timeouts := serverTimeouts(cfg.Server)
 	server := &http.Server{
-		Addr:         fmt.Sprintf(":%d", port),
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		Handler:      r,
+		Addr:              fmt.Sprintf(":%d", port),
+		ReadHeaderTimeout: timeouts.ReadHeaderTimeout,
+		ReadTimeout:       timeouts.ReadTimeout,
+		WriteTimeout:      timeouts.WriteTimeout,
+		IdleTimeout:       timeouts.IdleTimeout,
+		Handler:           basepath.Handler(r),
+		Protocols:         serverProtocols(cfg.Server.H2C),
 	}
 
+	// Optional in-process TLS; a bad certificate or setting fails startup here instead of at the first request
+	tlsConfig, err := buildTLSConfig(cfg.Server.TLS)
+	if err != nil {
+		return nil, err
+	}
+	server.TLSConfig = tlsConfig
+
 	return server, nil
 }
+
+// Default http.Server timeouts, used for any value left at zero in config.
+const (
+	defaultReadHeaderTimeout = 5 * time.Second
+	defaultReadTimeout       = 5 * time.Second
+	defaultWriteTimeout      = 10 * time.Second
+	defaultIdleTimeout       = 120 * time.Second
+)
+
+// serverTimeouts fills unset timeouts with the defaults.
+func serverTimeouts(cfg config.ServerConfig) config.ServerConfig {
+	cfg.ReadHeaderTimeout = cmp.Or(cfg.ReadHeaderTimeout, defaultReadHeaderTimeout)
+	cfg.ReadTimeout = cmp.Or(cfg.ReadTimeout, defaultReadTimeout)
+	cfg.WriteTimeout = cmp.Or(cfg.WriteTimeout, defaultWriteTimeout)
+	cfg.IdleTimeout = cmp.Or(cfg.IdleTimeout, defaultIdleTimeout)
+	return cfg
+}
+
+// serverProtocols enables HTTP/1.1 and HTTP/2 (negotiated over TLS), plus cleartext HTTP/2 when h2c is set.
+func serverProtocols(h2c bool) *http.Protocols {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(h2c)
+	return protocols
+}
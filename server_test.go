@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/config"
+)
+
+func TestServerProtocols_H2C(t *testing.T) {
+	tests := []struct {
+		name    string
+		h2c     bool
+		wantErr bool
+	}{
+		{"Enabled", true, false},
+		{"Disabled", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(r.Proto))
+			}))
+			srv.Config.Protocols = serverProtocols(tt.h2c)
+			srv.Start()
+			defer srv.Close()
+
+			// Prior-knowledge h2c client: speaks HTTP/2 over plain TCP without an upgrade
+			h2cProtocols := new(http.Protocols)
+			h2cProtocols.SetUnencryptedHTTP2(true)
+			h2cClient := &http.Client{Transport: &http.Transport{Protocols: h2cProtocols}}
+
+			resp, err := h2cClient.Get(srv.URL)
+			if tt.wantErr {
+				if err == nil {
+					resp.Body.Close()
+					t.Fatal("expected h2c request to fail when disabled")
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("h2c request failed: %v", err)
+				}
+				resp.Body.Close()
+				if resp.ProtoMajor != 2 {
+					t.Errorf("expected HTTP/2, got %s", resp.Proto)
+				}
+			}
+
+			// HTTP/1.1 keeps working either way
+			resp, err = http.Get(srv.URL)
+			if err != nil {
+				t.Fatalf("HTTP/1.1 request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.ProtoMajor != 1 {
+				t.Errorf("expected HTTP/1.1, got %s", resp.Proto)
+			}
+		})
+	}
+}
+
+func TestServerTimeouts(t *testing.T) {
+	got := serverTimeouts(config.ServerConfig{WriteTimeout: time.Minute})
+	if got.WriteTimeout != time.Minute {
+		t.Errorf("expected configured write timeout to be kept, got %v", got.WriteTimeout)
+	}
+	if got.ReadHeaderTimeout != defaultReadHeaderTimeout || got.ReadTimeout != defaultReadTimeout || got.IdleTimeout != defaultIdleTimeout {
+		t.Errorf("expected defaults for unset timeouts, got %+v", got)
+	}
+}
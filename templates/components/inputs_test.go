@@ -0,0 +1,97 @@
+package components
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/a-h/templ"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func renderHTML(t *testing.T, component templ.Component) string {
+	t.Helper()
+	var b strings.Builder
+	require.NoError(t, component.Render(context.Background(), &b))
+	return b.String()
+}
+
+func TestInputs(t *testing.T) {
+	props := InputProps{
+		Name:        "username",
+		Label:       "Nome de Usuário",
+		Icon:        "<svg data-icon></svg>",
+		Value:       "maria",
+		Placeholder: "nome de usuário",
+		Required:    true,
+		Attrs:       templ.Attributes{"minlength": "3"},
+	}
+
+	t.Run("Without error", func(t *testing.T) {
+		html := renderHTML(t, TextInput(props))
+		for _, want := range []string{
+			`<label class="label" for="input-username">`,
+			`<svg data-icon></svg>`,
+			`id="input-username"`,
+			`type="text"`,
+			`name="username"`,
+			`value="maria"`,
+			`placeholder="nome de usuário"`,
+			`aria-describedby="username-error"`,
+			` required`,
+			`minlength="3"`,
+			`<div id="username-error" class="field-error text-error text-xs mt-1" aria-live="polite"></div>`,
+		} {
+			assert.Contains(t, html, want)
+		}
+		assert.NotContains(t, html, "input-error")
+		assert.NotContains(t, html, "aria-invalid")
+		assert.NotContains(t, html, "hx-swap-oob", "the slot is swapped into, not swapped itself")
+	})
+
+	t.Run("With error", func(t *testing.T) {
+		props := props
+		props.Error = "nome de usuário já existe"
+		html := renderHTML(t, TextInput(props))
+		assert.Contains(t, html, `class="input input-bordered w-full input-error"`)
+		assert.Contains(t, html, `aria-invalid="true"`)
+		assert.Contains(t, html, `<div id="username-error" class="field-error text-error text-xs mt-1" aria-live="polite">nome de usuário já existe</div>`)
+	})
+
+	t.Run("Email with children", func(t *testing.T) {
+		// Children go between the input and the error slot, as the availability hint does in AccountFields
+		ctx := templ.WithChildren(context.Background(), AvailabilityStatus("email", false, "", ""))
+		var b strings.Builder
+		require.NoError(t, EmailInput(InputProps{Name: "email", Label: "Email"}).Render(ctx, &b))
+		html := b.String()
+		assert.Contains(t, html, `type="email"`)
+		assert.NotContains(t, html, "placeholder=")
+		assert.NotContains(t, html, " required")
+		assert.NotContains(t, html, "readonly")
+		hint, slot := strings.Index(html, `id="email-availability"`), strings.Index(html, `id="email-error"`)
+		require.True(t, hint > 0 && slot > 0)
+		assert.Less(t, strings.Index(html, "<input"), hint)
+		assert.Less(t, hint, slot)
+	})
+
+	t.Run("Password never echoes the value", func(t *testing.T) {
+		html := renderHTML(t, PasswordInput(InputProps{Name: "password", Label: "Senha", Value: "hunter2"}))
+		assert.Contains(t, html, `type="password"`)
+		assert.NotContains(t, html, "hunter2")
+		assert.Contains(t, html, `id="password-error"`)
+	})
+
+	t.Run("Select", func(t *testing.T) {
+		options := []SelectOption{{Value: "user", Label: "Usuário"}, {Value: "admin", Label: "Admin"}}
+		html := renderHTML(t, SelectInput(InputProps{Name: "role", Label: "Role", Value: "admin"}, options))
+		assert.Contains(t, html, `<select id="input-role" name="role" class="select select-bordered w-full"`)
+		assert.Contains(t, html, `<option value="user">Usuário</option>`)
+		assert.Contains(t, html, `<option value="admin" selected>Admin</option>`)
+		assert.Contains(t, html, `<div id="role-error"`)
+
+		html = renderHTML(t, SelectInput(InputProps{Name: "role", Label: "Role", Error: "role inválida"}, options))
+		assert.Contains(t, html, "select-error")
+		assert.Contains(t, html, ">role inválida</div>")
+	})
+}
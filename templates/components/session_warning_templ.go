@@ -0,0 +1,78 @@
+// Code generated by templ - DO NOT EDIT.
+
+// templ: version: v0.3.977
+package components
+
+//lint:file-ignore SA4006 This context is only used if a nested component is present.
+
+import "github.com/a-h/templ"
+import templruntime "github.com/a-h/templ/runtime"
+
+import "github.com/lucas-varjao/gohtmx/internal/basepath"
+
+// SessionExpiryWarning warns a logged-in user before an idle logout. It reads the expiry once from
+// GET /api/session, keeps it current from the X-Session-Expires-In header of HTMX responses, and
+// warn_before seconds ahead offers to extend the session (POST /api/session/extend).
+// While the user is active (keys, clicks, scroll) it pings GET /api/session/ping at most once a
+// minute, so reading or filling a long form counts as activity.
+// When the session runs out it sends the user to the login page. The prompt is off when warn_before is 0.
+func SessionExpiryWarning() templ.Component {
+	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
+		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
+		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
+			return templ_7745c5c3_CtxErr
+		}
+		templ_7745c5c3_Buffer, templ_7745c5c3_IsBuffer := templruntime.GetBuffer(templ_7745c5c3_W)
+		if !templ_7745c5c3_IsBuffer {
+			defer func() {
+				templ_7745c5c3_BufErr := templruntime.ReleaseBuffer(templ_7745c5c3_Buffer)
+				if templ_7745c5c3_Err == nil {
+					templ_7745c5c3_Err = templ_7745c5c3_BufErr
+				}
+			}()
+		}
+		ctx = templ.InitializeContext(ctx)
+		templ_7745c5c3_Var1 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var1 == nil {
+			templ_7745c5c3_Var1 = templ.NopComponent
+		}
+		ctx = templ.ClearChildren(ctx)
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div x-data=\"{\n\t\t\texpiresAt: 0, warnBefore: 0, now: Date.now(), active: false,\n\t\t\tget remaining() { return Math.max(0, Math.round((this.expiresAt - this.now) / 1000)) },\n\t\t\tget warning() { return this.warnBefore > 0 && this.expiresAt > 0 && this.remaining <= this.warnBefore },\n\t\t\tsetExpiresIn(seconds) { this.expiresAt = Date.now() + seconds * 1000; this.now = Date.now() },\n\t\t\tload(url, method) {\n\t\t\t\treturn fetch(url, { method, credentials: 'same-origin' })\n\t\t\t\t\t.then(r => r.ok ? r.json() : null)\n\t\t\t\t\t.then(d => { if (d) { this.warnBefore = d.warn_before; this.setExpiresIn(d.expires_in) } })\n\t\t\t},\n\t\t\tping(url) {\n\t\t\t\tfetch(url, { credentials: 'same-origin' }).then(r => {\n\t\t\t\t\tconst v = r.headers.get('X-Session-Expires-In');\n\t\t\t\t\tif (r.status === 204 && v !== null) this.setExpiresIn(Number(v))\n\t\t\t\t})\n\t\t\t},\n\t\t}\" x-init=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var2 string
+		templ_7745c5c3_Var2, templ_7745c5c3_Err = templ.JoinStringErrs("load('" + basepath.URL("/api/session") + "', 'GET');" +
+			" document.body.addEventListener('htmx:afterRequest', e => { const v = e.detail.xhr.getResponseHeader('X-Session-Expires-In'); if (v !== null) setExpiresIn(Number(v)) });" +
+			" setInterval(() => { now = Date.now(); if (expiresAt > 0 && remaining === 0) window.location.href = '" + basepath.URL("/login") + "' }, 1000);" +
+			" ['keydown', 'mousedown', 'scroll', 'touchstart'].forEach(ev => document.addEventListener(ev, () => { active = true }, { passive: true }));" +
+			" setInterval(() => { if (active) { active = false; ping('" + basepath.URL("/api/session/ping") + "') } }, 60000)")
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `templates/components/session_warning.templ`, Line: 34, Col: 117}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var2))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "\"><div x-show=\"warning\" x-cloak class=\"toast toast-end z-50\" role=\"alertdialog\" aria-live=\"assertive\"><div class=\"alert alert-warning shadow-lg flex flex-col items-start gap-2\"><span>Sua sessão expira em <strong x-text=\"Math.ceil(remaining / 60) + ' min'\"></strong> por inatividade.</span> <button type=\"button\" class=\"btn btn-sm\" @click=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var3 string
+		templ_7745c5c3_Var3, templ_7745c5c3_Err = templ.JoinStringErrs("load('" + basepath.URL("/api/session/extend") + "', 'POST')")
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `templates/components/session_warning.templ`, Line: 39, Col: 115}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var3))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "\">Continuar conectado</button></div></div></div>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		return nil
+	})
+}
+
+var _ = templruntime.GeneratedTemplate
@@ -11,13 +11,16 @@ import templruntime "github.com/a-h/templ/runtime"
 import (
 	"html/template"
 
+	"github.com/lucas-varjao/gohtmx/internal/basepath"
 	"github.com/lucas-varjao/gohtmx/templates/components"
 )
 
 // NewUserForm renders the new-user form (usado no modal da listagem e na página dedicada).
 // inModal: quando true, não renderiza o link Cancelar (o modal usa form method="dialog" para fechar).
+// accountFields are the inputs shared with the registration form (components.AccountFields), so admins get
+// the same password checklist and per-field error slots.
 // errorIcon is trusted HTML from lucide-go. errorMessage exibido acima do form quando não vazio.
-func NewUserForm(errorMessage string, errorIcon template.HTML, inModal bool) templ.Component {
+func NewUserForm(errorMessage string, errorIcon template.HTML, inModal bool, accountFields templ.Component) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -52,17 +55,83 @@ func NewUserForm(errorMessage string, errorIcon template.HTML, inModal bool) tem
 				return templ_7745c5c3_Err
 			}
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "<form method=\"POST\" action=\"/admin/users\" hx-post=\"/admin/users\" hx-target=\"#new-user-error\" hx-swap=\"innerHTML\" class=\"space-y-4\"><div id=\"new-user-error\"></div><div class=\"form-control\"><label class=\"label\"><span class=\"label-text\">Usuário</span></label> <input type=\"text\" name=\"username\" placeholder=\"nome de usuário\" class=\"input input-bordered w-full\" required minlength=\"3\"></div><div class=\"form-control\"><label class=\"label\"><span class=\"label-text\">Email</span></label> <input type=\"email\" name=\"email\" placeholder=\"email@exemplo.com\" class=\"input input-bordered w-full\" required></div><div class=\"form-control\"><label class=\"label\"><span class=\"label-text\">Nome de exibição</span></label> <input type=\"text\" name=\"display_name\" placeholder=\"Nome exibido\" class=\"input input-bordered w-full\" required></div><div class=\"form-control\"><label class=\"label\"><span class=\"label-text\">Senha</span></label> <input type=\"password\" name=\"password\" placeholder=\"mín. 8 caracteres\" class=\"input input-bordered w-full\" required minlength=\"8\"></div><div class=\"form-control\"><label class=\"label\"><span class=\"label-text\">Role</span></label> <select name=\"role\" class=\"select select-bordered w-full\"><option value=\"user\" selected>user</option> <option value=\"admin\">admin</option></select></div><div class=\"form-control\"><label class=\"label cursor-pointer justify-start gap-2\"><input type=\"checkbox\" name=\"active\" value=\"true\" checked class=\"checkbox checkbox-sm\"> <span class=\"label-text\">Conta ativa</span></label></div><div class=\"flex gap-2 items-center justify-center\"><button type=\"submit\" class=\"btn btn-primary\">Criar usuário</button> ")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "<form method=\"POST\" action=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var2 templ.SafeURL
+		templ_7745c5c3_Var2, templ_7745c5c3_Err = templ.JoinURLErrs(basepath.URL("/admin/users"))
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `templates/pages/admin/users_new.templ`, Line: 23, Col: 39}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var2))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "\" hx-post=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var3 string
+		templ_7745c5c3_Var3, templ_7745c5c3_Err = templ.JoinStringErrs(basepath.URL("/admin/users"))
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `templates/pages/admin/users_new.templ`, Line: 24, Col: 40}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var3))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "\" hx-target=\"#new-user-error\" hx-swap=\"innerHTML\" class=\"space-y-4\" x-data=\"{ password: '', confirmPassword: '', passwordsMatch: true, passwordReady: false }\"><div id=\"new-user-error\"></div>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = accountFields.Render(ctx, templ_7745c5c3_Buffer)
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "<div class=\"form-control\"><label class=\"label\"><span class=\"label-text\">Telefone (opcional)</span></label> <input type=\"tel\" name=\"phone\" placeholder=\"+55 11 98765-4321\" maxlength=\"25\" class=\"input input-bordered w-full\">")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = components.FieldError("phone", "", false).Render(ctx, templ_7745c5c3_Buffer)
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, "</div>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = components.SelectInput(components.InputProps{Name: "role", Label: "Role", Value: "user"}, []components.SelectOption{
+			{Value: "user", Label: "user"},
+			{Value: "admin", Label: "admin"},
+		}).Render(ctx, templ_7745c5c3_Buffer)
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 8, "<div class=\"form-control\"><label class=\"label cursor-pointer justify-start gap-2\"><input type=\"checkbox\" name=\"active\" value=\"true\" checked class=\"checkbox checkbox-sm\"> <span class=\"label-text\">Conta ativa</span></label></div><div class=\"flex gap-2 items-center justify-center\"><button type=\"submit\" class=\"btn btn-primary\" :disabled=\"!passwordReady\">Criar usuário</button> ")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
 		if !inModal {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "<a href=\"/admin/users\" class=\"btn btn-ghost\">Cancelar</a>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, "<a href=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var4 templ.SafeURL
+			templ_7745c5c3_Var4, templ_7745c5c3_Err = templ.JoinURLErrs(basepath.URL("/admin/users"))
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `templates/pages/admin/users_new.templ`, Line: 58, Col: 42}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var4))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 10, "\" class=\"btn btn-ghost\">Cancelar</a>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "</div></form>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 11, "</div></form>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -71,8 +140,8 @@ func NewUserForm(errorMessage string, errorIcon template.HTML, inModal bool) tem
 }
 
 // UsersNewPage renders the new-user form inside the dashboard content area (página dedicada /admin/users/new).
-// errorIcon is trusted HTML from lucide-go.
-func UsersNewPage(errorMessage string, errorIcon template.HTML) templ.Component {
+// errorIcon is trusted HTML from lucide-go; accountFields as in NewUserForm.
+func UsersNewPage(errorMessage string, errorIcon template.HTML, accountFields templ.Component) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -88,20 +157,20 @@ func UsersNewPage(errorMessage string, errorIcon template.HTML) templ.Component
 			}()
 		}
 		ctx = templ.InitializeContext(ctx)
-		templ_7745c5c3_Var2 := templ.GetChildren(ctx)
-		if templ_7745c5c3_Var2 == nil {
-			templ_7745c5c3_Var2 = templ.NopComponent
+		templ_7745c5c3_Var5 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var5 == nil {
+			templ_7745c5c3_Var5 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "<div class=\"p-4 sm:p-6 page-content\"><div class=\"max-w-lg w-full\"><h1 class=\"text-2xl font-semibold text-base-content\">Novo usuário</h1><p class=\"text-base-content/70 text-sm mt-0.5\">Preencha os dados para criar uma conta.</p><div class=\"card bg-base-100 border border-base-content/10 mt-4\"><div class=\"card-body\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 12, "<div class=\"p-4 sm:p-6 page-content\"><div class=\"max-w-lg w-full\"><h1 class=\"text-2xl font-semibold text-base-content\">Novo usuário</h1><p class=\"text-base-content/70 text-sm mt-0.5\">Preencha os dados para criar uma conta.</p><div class=\"card bg-base-100 border border-base-content/10 mt-4\"><div class=\"card-body\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = NewUserForm(errorMessage, errorIcon, false).Render(ctx, templ_7745c5c3_Buffer)
+		templ_7745c5c3_Err = NewUserForm(errorMessage, errorIcon, false, accountFields).Render(ctx, templ_7745c5c3_Buffer)
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, "</div></div></div></div>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 13, "</div></div></div></div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -9,9 +9,68 @@ type UserView struct {
 	Username    string
 	Email       string
 	DisplayName string
+	AvatarURL   string // "" when avatars are disabled
 	Role        string
 	Active      bool
+	LoginStatus string // auth.LoginStatus value, shown as a badge
 	LastLogin   string
+	PasswordAge string // e.g. "12 dias", counted from the account creation when the change date is unknown
+}
+
+// LoginAttemptView holds display-only fields of a login attempt record.
+type LoginAttemptView struct {
+	Identifier string
+	IP         string
+	UserAgent  string
+	Success    bool
+	Reason     string // human-readable label
+	CreatedAt  string
+	Burst      bool // a recent failure from an IP or account with a burst of failures
+	IPDenied   bool // the IP is already on the denylist
+}
+
+// FailureBurstView is an IP or account with many recent failed logins, listed above the attempts table.
+type FailureBurstView struct {
+	IP         string // set for IP bursts
+	Identifier string // set for account bursts
+	Failures   int64
+	IPDenied   bool
+	FilterURL  string // the attempts page filtered by this IP or account
+}
+
+// DeniedIPView holds display-only fields of a denylist entry.
+type DeniedIPView struct {
+	ID        string // empty for Static entries
+	Value     string
+	Reason    string
+	CreatedAt string
+	Static    bool // from config (security.ip_denylist), not removable at runtime
+}
+
+// InviteView holds display-only fields of a registration invite.
+type InviteView struct {
+	Email     string // "" when any email can use it
+	Role      string
+	Status    string // "pending", "used" or "expired"
+	ExpiresAt string
+	CreatedAt string
+}
+
+// LoginAttemptsFilter holds the current filter values of the login attempts page.
+type LoginAttemptsFilter struct {
+	Identifier string
+	IP         string
+	Outcome    string // "", "success" or "failure"
+}
+
+// Pagination describes the current page of a paginated admin list.
+// PrevURL/NextURL are empty when there is no previous/next page.
+type Pagination struct {
+	Page       int
+	TotalPages int
+	Total      int64
+	PrevURL    string
+	NextURL    string
 }
 
 // DashboardStats holds aggregated user statistics for the admin dashboard.
@@ -39,6 +98,59 @@ func BoolToTitle(active bool) string {
 	return "Clique para ativar"
 }
 
+// loginStatusBadges maps auth.LoginStatus values to the badge label and DaisyUI class.
+var loginStatusBadges = map[string][2]string{
+	"ok":          {"Liberado", "badge-success"},
+	"inactive":    {"Inativo", "badge-ghost"},
+	"locked":      {"Bloqueado", "badge-error"},
+	"unverified":  {"Email não verificado", "badge-warning"},
+	"must_change": {"Trocar senha", "badge-info"},
+}
+
+// loginStatusLabel returns the badge text for a login status.
+func loginStatusLabel(status string) string {
+	if badge, ok := loginStatusBadges[status]; ok {
+		return badge[0]
+	}
+	return status
+}
+
+// loginStatusClass returns the badge classes for a login status.
+func loginStatusClass(status string) string {
+	if badge, ok := loginStatusBadges[status]; ok {
+		return "badge badge-sm " + badge[1]
+	}
+	return "badge badge-sm"
+}
+
+// inviteStatusBadges maps InviteView.Status to the badge label and DaisyUI class.
+var inviteStatusBadges = map[string][2]string{
+	"pending": {"Pendente", "badge-info"},
+	"used":    {"Usado", "badge-success"},
+	"expired": {"Expirado", "badge-ghost"},
+}
+
+// inviteStatusLabel returns the badge text for an invite status.
+func inviteStatusLabel(status string) string {
+	if badge, ok := inviteStatusBadges[status]; ok {
+		return badge[0]
+	}
+	return status
+}
+
+// inviteStatusClass returns the badge classes for an invite status.
+func inviteStatusClass(status string) string {
+	if badge, ok := inviteStatusBadges[status]; ok {
+		return "badge badge-sm " + badge[1]
+	}
+	return "badge badge-sm"
+}
+
+// int64ToString converts an int64 to string for use in templates.
+func int64ToString(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
+
 // intToString converts an int to string for use in templates.
 func intToString(n int) string {
 	return strconv.Itoa(n)
@@ -0,0 +1,9 @@
+package pages
+
+// NotificationToggle is one optional email on the profile page, with whether the user receives it.
+type NotificationToggle struct {
+	Type        string // email.Type* value, sent as the checkbox value
+	Label       string
+	Description string
+	Enabled     bool
+}
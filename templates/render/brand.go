@@ -0,0 +1,57 @@
+package render
+
+import (
+	"cmp"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Brand is the app's name and look (config app), so the starter can be rebranded without editing templates.
+type Brand struct {
+	Name         string
+	Tagline      string // shown in the home page title
+	LogoURL      string // "" shows Name as text in the navbar
+	FaviconURL   string // "" uses the bundled favicon set under /static
+	PrimaryColor string // browser UI color (theme-color meta)
+}
+
+// DefaultBrand fills the Brand fields that config leaves empty.
+var DefaultBrand = Brand{
+	Name:         "GoHTMX",
+	Tagline:      "Stack demo",
+	PrimaryColor: "#070F26",
+}
+
+// Title returns the document title of a page: "page - Name", or "Name — Tagline" for the home page (page == "").
+func (b Brand) Title(page string) string {
+	if page == "" {
+		return b.Name + " — " + b.Tagline
+	}
+	return page + " - " + b.Name
+}
+
+// ImageSources returns the img-src sources of the Content-Security-Policy a page showing b needs: 'self',
+// data: and the origin of LogoURL and FaviconURL when they point to another host.
+func (b Brand) ImageSources() string {
+	sources := []string{"'self'", "data:"}
+	for _, raw := range []string{b.LogoURL, b.FaviconURL} {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			continue
+		}
+		if origin := u.Scheme + "://" + u.Host; !slices.Contains(sources, origin) {
+			sources = append(sources, origin)
+		}
+	}
+	return strings.Join(sources, " ")
+}
+
+func (b Brand) withDefaults() Brand {
+	b.Name = cmp.Or(b.Name, DefaultBrand.Name)
+	b.Tagline = cmp.Or(b.Tagline, DefaultBrand.Tagline)
+	b.LogoURL = cmp.Or(b.LogoURL, DefaultBrand.LogoURL)
+	b.FaviconURL = cmp.Or(b.FaviconURL, DefaultBrand.FaviconURL)
+	b.PrimaryColor = cmp.Or(b.PrimaryColor, DefaultBrand.PrimaryColor)
+	return b
+}
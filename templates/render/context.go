@@ -0,0 +1,89 @@
+// Package render carries the values every page needs (current user, locale, request ID, branding, footer data)
+// in the request context, so templates read them with From(ctx) instead of taking them as arguments.
+package render
+
+import (
+	"context"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/config"
+)
+
+// DefaultLocale is the locale pages render in when the context doesn't set one.
+const DefaultLocale = "pt-BR"
+
+// User is the logged-in user as shown in the navbar.
+type User struct {
+	DisplayName   string
+	AvatarURL     string // "" when avatars are disabled
+	Impersonating bool   // an admin is acting as this user
+	// NewLogin is a login from another device made while this session was open; nil for none
+	NewLogin *NewLogin
+}
+
+// NewLogin is the concurrent login notice shown until the user dismisses it (config login.concurrent_login_notice.banner).
+type NewLogin struct {
+	At   time.Time
+	From string // device and IP
+}
+
+// Maintenance is an upcoming maintenance window announced on every page.
+type Maintenance struct {
+	Start, End time.Time // in the zone of config maintenance.timezone
+}
+
+// Context holds the cross-cutting values of one request's render. Page-specific data stays in the
+// template arguments.
+type Context struct {
+	RequestID string
+	Locale    string
+	User      *User // nil when logged out
+	// Features are the config feature flags, so pages only show what the server accepts (e.g. the
+	// navbar hides the register link when Features.Registration is false)
+	Features      config.Features
+	AdminReadOnly bool         // config admin.read_only; the admin area shows a read-only notice
+	Maintenance   *Maintenance // nil unless a maintenance window starts soon (config maintenance.announce_before)
+	AppVersion    string
+	Year          int   // shown in the footer
+	Brand         Brand // empty fields fall back to DefaultBrand in From
+}
+
+// LoggedIn reports whether the request has a logged-in user.
+func (rc Context) LoggedIn() bool {
+	return rc.User != nil
+}
+
+// DisplayName returns the logged-in user's display name, or "" when logged out.
+func (rc Context) DisplayName() string {
+	if rc.User == nil {
+		return ""
+	}
+	return rc.User.DisplayName
+}
+
+// AvatarURL returns the logged-in user's avatar URL, or "" when logged out or avatars are disabled.
+func (rc Context) AvatarURL() string {
+	if rc.User == nil {
+		return ""
+	}
+	return rc.User.AvatarURL
+}
+
+type contextKey struct{}
+
+// WithContext returns a copy of ctx carrying rc.
+func WithContext(ctx context.Context, rc Context) context.Context {
+	return context.WithValue(ctx, contextKey{}, rc)
+}
+
+// From returns the Context stored in ctx by WithContext. Without one it returns an empty Context
+// (logged out, registration closed) in DefaultLocale with DefaultBrand; an empty Locale or Brand field
+// also falls back to its default.
+func From(ctx context.Context) Context {
+	rc, _ := ctx.Value(contextKey{}).(Context)
+	if rc.Locale == "" {
+		rc.Locale = DefaultLocale
+	}
+	rc.Brand = rc.Brand.withDefaults()
+	return rc
+}
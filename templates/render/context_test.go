@@ -0,0 +1,76 @@
+package render
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lucas-varjao/gohtmx/internal/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrom(t *testing.T) {
+	t.Run("Empty context", func(t *testing.T) {
+		rc := From(context.Background())
+		assert.Equal(t, DefaultLocale, rc.Locale)
+		assert.False(t, rc.LoggedIn())
+		assert.Empty(t, rc.DisplayName())
+		assert.Empty(t, rc.AvatarURL())
+		assert.False(t, rc.Features.Registration)
+		assert.Equal(t, DefaultBrand, rc.Brand)
+	})
+
+	t.Run("Round trip", func(t *testing.T) {
+		want := Context{
+			RequestID:  "req-1",
+			Locale:     "en-US",
+			User:       &User{DisplayName: "Ana", AvatarURL: "https://example.com/a.png", Impersonating: true},
+			Features:   config.Features{Registration: true, Passkeys: true},
+			AppVersion: "1.2.3",
+			Year:       2026,
+			Brand:      Brand{Name: "Acme", Tagline: "Tools", LogoURL: "/logo.svg", FaviconURL: "/icon.png", PrimaryColor: "#123456"},
+		}
+		rc := From(WithContext(context.Background(), want))
+		assert.Equal(t, want, rc)
+		assert.True(t, rc.LoggedIn())
+		assert.Equal(t, "Ana", rc.DisplayName())
+		assert.Equal(t, "https://example.com/a.png", rc.AvatarURL())
+	})
+
+	t.Run("Missing locale falls back", func(t *testing.T) {
+		rc := From(WithContext(context.Background(), Context{RequestID: "req-2"}))
+		assert.Equal(t, DefaultLocale, rc.Locale)
+		assert.Equal(t, "req-2", rc.RequestID)
+	})
+
+	t.Run("Missing brand fields fall back", func(t *testing.T) {
+		rc := From(WithContext(context.Background(), Context{Brand: Brand{Name: "Acme"}}))
+		assert.Equal(t, "Acme", rc.Brand.Name)
+		assert.Equal(t, DefaultBrand.Tagline, rc.Brand.Tagline)
+		assert.Equal(t, DefaultBrand.PrimaryColor, rc.Brand.PrimaryColor)
+		assert.Empty(t, rc.Brand.LogoURL)
+	})
+
+	t.Run("Inner context wins", func(t *testing.T) {
+		outer := WithContext(context.Background(), Context{RequestID: "outer"})
+		inner := WithContext(outer, Context{RequestID: "inner"})
+		assert.Equal(t, "inner", From(inner).RequestID)
+		assert.Equal(t, "outer", From(outer).RequestID)
+	})
+}
+
+func TestBrand_Title(t *testing.T) {
+	brand := Brand{Name: "Acme", Tagline: "Tools"}
+	assert.Equal(t, "Acme — Tools", brand.Title(""))
+	assert.Equal(t, "Entrar - Acme", brand.Title("Entrar"))
+}
+
+func TestBrand_ImageSources(t *testing.T) {
+	assert.Equal(t, "'self' data:", DefaultBrand.ImageSources())
+	assert.Equal(t, "'self' data:", Brand{LogoURL: "/logo.svg", FaviconURL: "/icon.png"}.ImageSources(), "same origin")
+
+	brand := Brand{LogoURL: "https://cdn.example.com/logo.svg", FaviconURL: "https://cdn.example.com/icon.png"}
+	assert.Equal(t, "'self' data: https://cdn.example.com", brand.ImageSources())
+	brand.FaviconURL = "http://static.example.com:8080/icon.png"
+	assert.Equal(t, "'self' data: https://cdn.example.com http://static.example.com:8080", brand.ImageSources())
+}
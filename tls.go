@@ -0,0 +1,90 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/lucas-varjao/gohtmx/internal/config"
+)
+
+// defaultCipherSuites are the TLS 1.2 suites used when none are configured: forward secrecy and AEAD only.
+var defaultCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+}
+
+// buildTLSConfig loads the certificate and builds the server TLS settings.
+// Returns nil when TLS is disabled, so the server keeps serving plain HTTP.
+func buildTLSConfig(cfg config.TLSConfig) (*tls.Config, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return nil, errors.New("TLS habilitado, mas cert_file e key_file são obrigatórios")
+	}
+
+	minVersion, err := parseTLSVersion(cfg.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	suites, err := parseCipherSuites(cfg.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+	if minVersion < tls.VersionTLS13 && !slices.ContainsFunc(suites, isHTTP2RequiredSuite) {
+		// Without one of these, HTTP/2 clients abort the handshake (RFC 7540, section 9.2.2)
+		return nil, errors.New("cipher_suites precisa incluir uma suite ECDHE AES_128_GCM_SHA256 para HTTP/2")
+	}
+
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao carregar certificado TLS (%s, %s): %w", cfg.CertFile, cfg.KeyFile, err)
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   minVersion,
+		CipherSuites: suites,
+	}, nil
+}
+
+func parseTLSVersion(v string) (uint16, error) {
+	switch v {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("versão mínima de TLS inválida: %q (use 1.2 ou 1.3)", v)
+	}
+}
+
+// parseCipherSuites maps crypto/tls suite names to IDs. Only suites Go considers secure are accepted.
+func parseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return defaultCipherSuites, nil
+	}
+	known := map[string]uint16{}
+	for _, s := range tls.CipherSuites() {
+		known[s.Name] = s.ID
+	}
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("cipher suite TLS desconhecida ou insegura: %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func isHTTP2RequiredSuite(id uint16) bool {
+	return id == tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 || id == tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256
+}
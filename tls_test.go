@@ -0,0 +1,135 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lucas-varjao/gohtmx/internal/config"
+)
+
+// writeSelfSignedCert writes a localhost certificate and key to dir and returns their paths.
+func writeSelfSignedCert(t *testing.T, dir string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("failed to write cert: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	return certFile, keyFile
+}
+
+func TestBuildTLSConfig(t *testing.T) {
+	certFile, keyFile := writeSelfSignedCert(t, t.TempDir())
+
+	tests := []struct {
+		name    string
+		cfg     config.TLSConfig
+		wantNil bool
+		wantErr bool
+	}{
+		{"Disabled", config.TLSConfig{}, true, false},
+		{"Defaults", config.TLSConfig{Enabled: true, CertFile: certFile, KeyFile: keyFile}, false, false},
+		{"Missing paths", config.TLSConfig{Enabled: true}, true, true},
+		{"Missing cert file", config.TLSConfig{Enabled: true, CertFile: "/nope.pem", KeyFile: keyFile}, true, true},
+		{"Bad min version", config.TLSConfig{Enabled: true, CertFile: certFile, KeyFile: keyFile, MinVersion: "1.0"}, true, true},
+		{"Insecure suite", config.TLSConfig{Enabled: true, CertFile: certFile, KeyFile: keyFile,
+			CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}}, true, true},
+		{"Custom suite", config.TLSConfig{Enabled: true, CertFile: certFile, KeyFile: keyFile,
+			CipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"}}, false, false},
+		{"Suites without HTTP/2 suite", config.TLSConfig{Enabled: true, CertFile: certFile, KeyFile: keyFile,
+			CipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384"}}, true, true},
+		{"TLS 1.3 ignores suites", config.TLSConfig{Enabled: true, CertFile: certFile, KeyFile: keyFile, MinVersion: "1.3",
+			CipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384"}}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildTLSConfig(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("buildTLSConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (got == nil) != tt.wantNil {
+				t.Fatalf("buildTLSConfig() = %v, wantNil %v", got, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestTLSServer_RefusesBelowMinVersion(t *testing.T) {
+	certFile, keyFile := writeSelfSignedCert(t, t.TempDir())
+	tlsConfig, err := buildTLSConfig(config.TLSConfig{Enabled: true, CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("buildTLSConfig() error = %v", err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	server := &http.Server{
+		Handler:   http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusNoContent) }),
+		TLSConfig: tlsConfig,
+	}
+	go func() { _ = server.ServeTLS(ln, "", "") }()
+	t.Cleanup(func() { _ = server.Close() })
+
+	get := func(minVersion, maxVersion uint16) (*http.Response, error) {
+		client := &http.Client{Timeout: 5 * time.Second, Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: true, //nolint:gosec // self-signed test certificate
+			MinVersion:         minVersion,
+			MaxVersion:         maxVersion,
+		}}}
+		return client.Get("https://" + ln.Addr().String())
+	}
+
+	if resp, err := get(tls.VersionTLS10, tls.VersionTLS11); err == nil {
+		resp.Body.Close()
+		t.Fatal("expected TLS 1.1 handshake to be refused")
+	}
+
+	resp, err := get(tls.VersionTLS12, tls.VersionTLS12)
+	if err != nil {
+		t.Fatalf("TLS 1.2 request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("expected status 204, got %d", resp.StatusCode)
+	}
+}